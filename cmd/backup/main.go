@@ -0,0 +1,170 @@
+// cmd/backup/main.go
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/database"
+	"github.com/chynybekuuludastan/website_optimizer/internal/database/migration"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/backup"
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found")
+	}
+
+	// Define command-line flags
+	exportCmd := flag.Bool("export", false, "Export analyses and their data to an archive")
+	restoreCmd := flag.Bool("restore", false, "Restore an archive into the database")
+	file := flag.String("file", "", "Path of the archive")
+	driver := flag.String("driver", getEnv("DB_DRIVER", "postgres"), "Database driver: postgres or sqlite")
+	dsn := flag.String("dsn", "", "PostgreSQL connection string or SQLite file (default POSTGRES_URI or SQLITE_PATH)")
+	orgs := flag.String("orgs", "", "Comma-separated IDs of the organizations to export (default all)")
+	from := flag.String("from", "", "Export analyses created on or after this date (YYYY-MM-DD)")
+	to := flag.String("to", "", "Export analyses created before this date (YYYY-MM-DD)")
+
+	// Parse command-line flags
+	flag.Parse()
+
+	// Check that exactly one command and an archive were specified
+	if *exportCmd == *restoreCmd || *file == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	db, err := openDatabase(*driver, *dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect to the database: %v", err)
+	}
+
+	ctx := context.Background()
+
+	switch {
+	case *exportCmd:
+		filter, err := parseFilter(*orgs, *from, *to)
+		if err != nil {
+			log.Fatalf("Invalid filter: %v", err)
+		}
+
+		out, err := os.Create(*file)
+		if err != nil {
+			log.Fatalf("Failed to create archive: %v", err)
+		}
+
+		log.Println("Exporting...")
+		manifest, err := backup.Export(ctx, db, filter, out)
+		if err == nil {
+			err = out.Close()
+		}
+		if err != nil {
+			out.Close()
+			os.Remove(*file)
+			log.Fatalf("Export failed: %v", err)
+		}
+		printTables(manifest)
+		log.Printf("Exported %d artifacts to %s", len(manifest.Artifacts), *file)
+
+	case *restoreCmd:
+		// Bring the schema up to date before importing
+		if *driver == "postgres" {
+			log.Println("Running migrations...")
+			if err := migration.NewMigrator(db).Migrate(); err != nil {
+				log.Fatalf("Migration failed: %v", err)
+			}
+		}
+
+		in, err := os.Open(*file)
+		if err != nil {
+			log.Fatalf("Failed to open archive: %v", err)
+		}
+		defer in.Close()
+
+		log.Println("Restoring...")
+		manifest, err := backup.Restore(ctx, db, in)
+		if err != nil {
+			log.Fatalf("Restore failed: %v", err)
+		}
+		printTables(manifest)
+		log.Printf("Restored %d artifacts from %s", len(manifest.Artifacts), *file)
+	}
+}
+
+// openDatabase connects to the database of an instance. SQLite databases
+// get their schema when opened.
+func openDatabase(driver, dsn string) (*gorm.DB, error) {
+	switch driver {
+	case "sqlite":
+		if dsn == "" {
+			dsn = getEnv("SQLITE_PATH", "data/website_optimizer.db")
+		}
+		client, err := database.OpenSQLite(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return client.DB, nil
+	case "postgres":
+		if dsn == "" {
+			dsn = os.Getenv("POSTGRES_URI")
+		}
+		return gorm.Open(postgres.Open(dsn), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Warn),
+		})
+	}
+	log.Fatalf("Unknown database driver %q", driver)
+	return nil, nil
+}
+
+func parseFilter(orgs, from, to string) (backup.Filter, error) {
+	var filter backup.Filter
+	for _, org := range strings.Split(orgs, ",") {
+		if org = strings.TrimSpace(org); org == "" {
+			continue
+		}
+		id, err := uuid.Parse(org)
+		if err != nil {
+			return filter, err
+		}
+		filter.UserIDs = append(filter.UserIDs, id)
+	}
+	for _, bound := range []struct {
+		value string
+		dest  **time.Time
+	}{{from, &filter.From}, {to, &filter.To}} {
+		if bound.value == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", bound.value)
+		if err != nil {
+			return filter, err
+		}
+		*bound.dest = &date
+	}
+	return filter, nil
+}
+
+func printTables(manifest *backup.Manifest) {
+	for _, table := range manifest.Tables {
+		if table.Rows > 0 {
+			log.Printf("  %-26s %d rows", table.Name, table.Rows)
+		}
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return fallback
+}
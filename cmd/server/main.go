@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/gofiber/fiber/v2"
@@ -13,9 +14,12 @@ import (
 	"github.com/joho/godotenv"
 
 	"github.com/chynybekuuludastan/website_optimizer/internal/api"
+	"github.com/chynybekuuludastan/website_optimizer/internal/api/middleware"
 	"github.com/chynybekuuludastan/website_optimizer/internal/api/swagger"
+	"github.com/chynybekuuludastan/website_optimizer/internal/cache"
 	"github.com/chynybekuuludastan/website_optimizer/internal/config"
 	"github.com/chynybekuuludastan/website_optimizer/internal/database"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/chaos"
 )
 
 // @title Website Analyzer API
@@ -46,19 +50,69 @@ func main() {
 	// Initialize configuration
 	cfg := config.NewConfig()
 
-	// Connect to PostgreSQL
-	db, err := database.InitPostgreSQL(cfg.PostgresURI)
-	if err != nil {
-		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	// Connect to the database: PostgreSQL, or an embedded SQLite file in
+	// the self-contained mode
+	var (
+		db  *database.DatabaseClient
+		err error
+	)
+	switch cfg.DatabaseDriver {
+	case "sqlite":
+		db, err = database.InitSQLite(cfg.SQLitePath)
+		if err != nil {
+			log.Fatalf("Failed to open SQLite database: %v", err)
+		}
+	case "postgres":
+		db, err = database.InitPostgreSQL(cfg.PostgresURI)
+		if err != nil {
+			log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+		}
+	default:
+		log.Fatalf("Invalid DB_DRIVER %q, expected postgres or sqlite", cfg.DatabaseDriver)
 	}
 	defer db.Close()
 
-	// Connect to Redis
-	redisClient, err := database.InitRedis(cfg.RedisURI)
-	if err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
+	// Connect the cache. Locks, counters and acknowledgements use Redis
+	// directly: the memory driver runs them on an embedded Redis server and
+	// the none driver keeps them in process or skips them.
+	var (
+		cacheStore  cache.Cache
+		redisClient *database.RedisClient
+	)
+	switch cfg.CacheDriver {
+	case cache.DriverRedis:
+		redisClient, err = database.InitRedis(cfg.RedisURI)
+		if err != nil {
+			log.Fatalf("Failed to connect to Redis: %v", err)
+		}
+		defer redisClient.Close()
+		cacheStore = cache.NewRedis(redisClient.Client)
+	case cache.DriverMemory:
+		redisClient, err = database.InitMemoryRedis()
+		if err != nil {
+			log.Fatalf("Failed to start in-memory cache: %v", err)
+		}
+		defer redisClient.Close()
+		cacheStore = cache.NewMemory()
+	case cache.DriverNone:
+		cacheStore = cache.NewNoop()
+	default:
+		log.Fatal(cache.ValidateDriver(cfg.CacheDriver))
+	}
+	defer cacheStore.Close()
+
+	// Fault injection for resilience testing
+	if cfg.ChaosEnabled {
+		faults, err := chaos.Parse(cfg.ChaosFaults)
+		if err != nil {
+			log.Fatalf("Invalid CHAOS_FAULTS: %v", err)
+		}
+		chaos.SetGlobal(faults)
+		if redisClient != nil {
+			redisClient.EnableFaultInjection()
+		}
+		log.Printf("Warning: fault injection is enabled (%d configured faults)", len(faults))
 	}
-	defer redisClient.Close()
 
 	// Initialize Fiber app
 	app := fiber.New(fiber.Config{
@@ -78,16 +132,23 @@ func main() {
 	app.Use(recover.New())
 	app.Use(logger.New())
 	app.Use(cors.New(cors.Config{
+		// Summary widgets answer CORS for registered origins only
+		Next: func(c *fiber.Ctx) bool {
+			return strings.HasSuffix(c.Path(), "/summary-widget")
+		},
 		AllowOrigins: "*",
 		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
 		AllowMethods: "GET, POST, PUT, DELETE, PATCH",
 	}))
+	if cfg.ChaosEnabled {
+		app.Use(middleware.Chaos())
+	}
 
 	// Setup Swagger
 	swagger.SetupSwagger(app)
 
 	// Setup routes
-	api.SetupRoutes(app, db, redisClient, cfg)
+	api.SetupRoutes(app, db, cacheStore, redisClient, cfg)
 
 	// Start server
 	go func() {
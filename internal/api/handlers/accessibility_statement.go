@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/billing"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/conformance"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/llm"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/report"
+)
+
+// AccessibilityStatementRequest is the body of an accessibility statement
+// request
+type AccessibilityStatementRequest struct {
+	// Organization is the name the statement is published under; defaults
+	// to the host of the analyzed site
+	Organization string `json:"organization,omitempty"`
+	// ContactEmail is where users report accessibility barriers
+	ContactEmail string `json:"contact_email,omitempty"`
+	// RemediationDays override the days by which issues of each severity
+	// are planned to be fixed
+	RemediationDays conformance.RemediationDays `json:"remediation_days"`
+	// Polish asks an LLM to improve the wording of the templated text
+	Polish       bool   `json:"polish"`
+	Language     string `json:"language,omitempty"`
+	ProviderName string `json:"provider,omitempty"`
+}
+
+// accessibilityStatementResult is a statement with the outcome of polishing
+type accessibilityStatementResult struct {
+	conformance.Statement
+	PolishError string `json:"polish_error,omitempty"`
+}
+
+// AccessibilityStatementHandler drafts accessibility conformance statements
+type AccessibilityStatementHandler struct {
+	LLMService   *llm.Service
+	AnalysisRepo repository.AnalysisRepository
+	WebsiteRepo  repository.WebsiteRepository
+	MetricsRepo  repository.MetricsRepository
+	IssueRepo    repository.IssueRepository
+	UsageRepo    repository.UsageRepository
+	Quota        *billing.Quota
+}
+
+// NewAccessibilityStatementHandler creates a new accessibility statement handler
+func NewAccessibilityStatementHandler(
+	llmService *llm.Service,
+	repoFactory *repository.Factory,
+	quota *billing.Quota,
+) *AccessibilityStatementHandler {
+	return &AccessibilityStatementHandler{
+		LLMService:   llmService,
+		AnalysisRepo: repoFactory.AnalysisRepository,
+		WebsiteRepo:  repoFactory.WebsiteRepository,
+		MetricsRepo:  repoFactory.MetricsRepository,
+		IssueRepo:    repoFactory.IssueRepository,
+		UsageRepo:    repoFactory.UsageRepository,
+		Quota:        quota,
+	}
+}
+
+// GenerateAccessibilityStatement drafts the accessibility statement of an
+// analyzed site
+// @Summary Generate an accessibility statement
+// @Description Drafts an accessibility conformance statement from the accessibility results of an analysis: a WCAG 2.1 Level AA summary of the success criteria covered by automated testing with their passes and failures, the known issues, and a remediation plan with target dates by severity (30, 60 and 90 days unless overridden). The text is templated; with polish=true an LLM improves its wording while keeping the facts, falling back to the templated text on failure. Returns JSON, or an HTML page or PDF document for a compliance page
+// @Tags accessibility
+// @Accept json
+// @Produce json,html,application/pdf
+// @Param id path string true "Analysis ID" format="uuid"
+// @Param format query string false "Response format: json, html or pdf" default(json)
+// @Param request body handlers.AccessibilityStatementRequest false "Statement options"
+// @Success 200 {object} map[string]interface{} "Accessibility statement"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 402 {object} map[string]interface{} "Monthly LLM generation quota of the plan exhausted"
+// @Failure 404 {object} map[string]interface{} "Analysis not found"
+// @Failure 409 {object} map[string]interface{} "The analysis has no accessibility results"
+// @Security BearerAuth
+// @Router /analysis/{id}/accessibility-statement [post]
+func (h *AccessibilityStatementHandler) GenerateAccessibilityStatement(c *fiber.Ctx) error {
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+
+	format := c.Query("format", "json")
+	if format != "json" && format != report.FormatHTML && format != report.FormatPDF {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Format must be json, html or pdf",
+		})
+	}
+
+	req := new(AccessibilityStatementRequest)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "Invalid request body: " + err.Error(),
+			})
+		}
+	}
+	if req.RemediationDays.High < 0 || req.RemediationDays.Medium < 0 || req.RemediationDays.Low < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Remediation days must not be negative",
+		})
+	}
+
+	var analysis models.Analysis
+	if err := h.AnalysisRepo.FindByID(analysisID, &analysis); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Analysis not found",
+		})
+	}
+	var website models.Website
+	if err := h.WebsiteRepo.FindByID(analysis.WebsiteID, &website); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch website data",
+		})
+	}
+
+	metric, err := h.MetricsRepo.FindByName(analysisID, string(analyzer.AccessibilityType)+"_score")
+	if err != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"success": false,
+			"error":   "The analysis has no accessibility results",
+		})
+	}
+	var value struct {
+		Score float64 `json:"score"`
+	}
+	json.Unmarshal(metric.Value, &value)
+
+	issues, err := h.IssueRepo.FindByCategory(analysisID, string(analyzer.AccessibilityType))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch accessibility issues",
+		})
+	}
+
+	input := conformance.Input{
+		Organization: req.Organization,
+		ContactEmail: strings.TrimSpace(req.ContactEmail),
+		URL:          website.URL,
+		AnalysisID:   analysisID.String(),
+		AnalyzedAt:   analysis.CompletedAt,
+		Score:        value.Score,
+		Date:         time.Now(),
+		Remediation:  req.RemediationDays,
+	}
+	if input.AnalyzedAt.IsZero() {
+		input.AnalyzedAt = analysis.CreatedAt
+	}
+	for _, issue := range issues {
+		input.Issues = append(input.Issues, conformance.Issue{
+			Type:     issue.Type,
+			Severity: issue.Severity,
+			Title:    issue.Title,
+			Location: issue.Location,
+		})
+	}
+
+	result := accessibilityStatementResult{Statement: conformance.Build(input)}
+
+	if req.Polish {
+		if !enforceQuota(c, h.Quota, billing.ResourceLLMGenerations) {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(c.Context(), 2*time.Minute)
+		defer cancel()
+		h.polishStatement(ctx, c.Locals("userID").(uuid.UUID), analysisID, req, &result)
+	}
+
+	switch format {
+	case report.FormatHTML:
+		document, err := conformance.RenderHTML(result.Statement)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"success": false,
+				"error":   err.Error(),
+			})
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.Send(document)
+	case report.FormatPDF:
+		c.Set(fiber.HeaderContentType, "application/pdf")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`inline; filename="accessibility-statement-%s.pdf"`, analysisID))
+		return c.Send(conformance.RenderPDF(result.Statement))
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// polishStatement asks the LLM to improve the wording of the statement.
+// Failures keep the templated text and are reported in the result.
+func (h *AccessibilityStatementHandler) polishStatement(ctx context.Context, userID, analysisID uuid.UUID, req *AccessibilityStatementRequest, result *accessibilityStatementResult) {
+	providerName := req.ProviderName
+	if providerName == "" {
+		providers := h.LLMService.GetAvailableProviders()
+		if len(providers) == 0 {
+			result.PolishError = "No LLM providers available"
+			return
+		}
+		providerName = providers[0]
+	}
+
+	statementRequest := &llm.StatementRequest{
+		URL:          result.URL,
+		Organization: result.Organization,
+		Language:     req.Language,
+	}
+	var prompt strings.Builder
+	for _, section := range result.Sections {
+		statementRequest.Sections = append(statementRequest.Sections, llm.StatementSection{
+			Heading: section.Heading,
+			Text:    section.Text,
+		})
+		prompt.WriteString(section.Heading)
+		prompt.WriteString(section.Text)
+	}
+
+	polished, err := h.LLMService.PolishStatement(ctx, statementRequest, providerName)
+	if err != nil {
+		result.PolishError = "Failed to polish statement: " + err.Error()
+		return
+	}
+
+	var completion strings.Builder
+	for i, section := range polished.Sections {
+		result.Sections[i] = conformance.Section{Heading: section.Heading, Text: section.Text}
+		completion.WriteString(section.Heading)
+		completion.WriteString(section.Text)
+	}
+	result.PolishedBy = polished.ProviderUsed
+
+	if polished.CachedResult {
+		return
+	}
+	if h.Quota != nil {
+		if err := h.Quota.Record(context.Background(), userID, billing.ResourceLLMGenerations); err != nil {
+			fmt.Println("Failed to record LLM generation:", err)
+		}
+	}
+	meterLLMUsage(h.UsageRepo, analysisID, userID, polished.ProviderUsed, prompt.String(), completion.String())
+}
@@ -3,9 +3,12 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"math"
+	"log"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,16 +17,39 @@ import (
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 
+	"github.com/chynybekuuludastan/website_optimizer/internal/cache"
 	"github.com/chynybekuuludastan/website_optimizer/internal/config"
 	"github.com/chynybekuuludastan/website_optimizer/internal/database"
 	"github.com/chynybekuuludastan/website_optimizer/internal/models"
 	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analytics"
 	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/backlinks"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/billing"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/captcha"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/chaos"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/maintenance"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/outbox"
 	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/queue"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/signing"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+	ws "github.com/chynybekuuludastan/website_optimizer/internal/websocket"
 )
 
 type AnalysisRequest struct {
-	URL string `json:"url" validate:"required,url"`
+	URL      string `json:"url" validate:"required,url"`
+	Priority string `json:"priority,omitempty" validate:"omitempty,oneof=low normal high"`
+	// Preset is the key of an analysis preset, see GET /presets
+	Preset string `json:"preset,omitempty"`
+	// Mode "checklist" runs the launch checklist instead of scored analyzers
+	Mode string `json:"mode,omitempty" validate:"omitempty,oneof=standard checklist"`
+	// Sandbox returns synthetic results instantly without fetching the page
+	// or consuming quota; also enabled by the sandbox=true query parameter
+	Sandbox bool `json:"sandbox,omitempty"`
+	// Optional headers, user agent and cookies sent when fetching the page,
+	// and user journeys run on the page afterwards
+	parser.RequestOverrides
 }
 
 type AnalysisHandler struct {
@@ -32,14 +58,43 @@ type AnalysisHandler struct {
 	MetricsRepo        repository.MetricsRepository
 	IssueRepo          repository.IssueRepository
 	RecommendationRepo repository.RecommendationRepository
-	RedisClient        *database.RedisClient
+	EventRepo          repository.AnalysisEventRepository
+	UsageRepo          repository.UsageRepository
+	SnapshotRepo       repository.SnapshotRepository
+	PresetRepo         repository.PresetRepository
+	EventStreamRepo    repository.EventStreamRepository
+	MonitoredSiteRepo  repository.MonitoredSiteRepository
+	UserRepo           repository.UserRepository
+	BacklinkRepo       repository.BacklinkRepository
+	PageEntityRepo     repository.PageEntityRepository
+	FeedbackRepo       repository.IssueFeedbackRepository
+	DocLinkRepo        repository.IssueDocLinkRepository
+	CustomRuleRepo     repository.CustomRuleRepository
+	DeploymentRepo     repository.DeploymentRepository
+	KeywordRepo        repository.KeywordRepository
+	BacklinkProvider   backlinks.Provider
+	AnalyticsWriter    analytics.Writer
+	Maintenance        *maintenance.Store
+	Cache              cache.Cache
+	RedisClient        *database.RedisClient // in-flight locks of analyses; nil without Redis
+	Hub                *ws.Hub
+	Scheduler          *queue.Scheduler
+	Outbox             *outbox.Dispatcher // woken when side effects are queued; nil before it runs
+	Quota              *billing.Quota
+	Captcha            *captcha.Verifier // checks anonymous quick scans
+	Signer             *signing.Signer   // signs shared scan results; nil when not configured
 	Config             *config.Config
 	cancelFunctions    sync.Map
+	quickScanSlots     chan struct{}
+	quickScanClaims    sync.Map // claim tokens being claimed when Redis is unavailable
 }
 
 func NewAnalysisHandler(
 	repoFactory *repository.Factory,
+	cacheStore cache.Cache,
 	redisClient *database.RedisClient,
+	hub *ws.Hub,
+	quota *billing.Quota,
 	cfg *config.Config,
 ) *AnalysisHandler {
 	return &AnalysisHandler{
@@ -48,27 +103,65 @@ func NewAnalysisHandler(
 		MetricsRepo:        repoFactory.MetricsRepository,
 		IssueRepo:          repoFactory.IssueRepository,
 		RecommendationRepo: repoFactory.RecommendationRepository,
+		EventRepo:          repoFactory.AnalysisEventRepository,
+		UsageRepo:          repoFactory.UsageRepository,
+		SnapshotRepo:       repoFactory.SnapshotRepository,
+		PresetRepo:         repoFactory.PresetRepository,
+		EventStreamRepo:    repoFactory.EventStreamRepository,
+		MonitoredSiteRepo:  repoFactory.MonitoredSiteRepository,
+		UserRepo:           repoFactory.UserRepository,
+		BacklinkRepo:       repoFactory.BacklinkRepository,
+		PageEntityRepo:     repoFactory.PageEntityRepository,
+		FeedbackRepo:       repoFactory.IssueFeedbackRepository,
+		DocLinkRepo:        repoFactory.IssueDocLinkRepository,
+		CustomRuleRepo:     repoFactory.CustomRuleRepository,
+		DeploymentRepo:     repoFactory.DeploymentRepository,
+		KeywordRepo:        repoFactory.KeywordRepository,
+		BacklinkProvider:   newBacklinkProvider(cfg.BacklinkProvider, cfg.BacklinkAPIURL, cfg.BacklinkAPIKey),
+		AnalyticsWriter:    newAnalyticsWriter(cfg),
+		Maintenance:        maintenance.NewStore(redisClient.Conn()),
+		Cache:              cacheStore,
 		RedisClient:        redisClient,
+		Hub:                hub,
+		Scheduler:          newAnalysisScheduler(cfg),
+		Quota:              quota,
+		Captcha:            captcha.NewVerifier(cfg.CaptchaSecret, cfg.CaptchaVerifyURL),
 		Config:             cfg,
 		cancelFunctions:    sync.Map{},
+		quickScanSlots:     make(chan struct{}, max(cfg.QuickScanConcurrency, 1)),
 	}
 }
 
 // @Summary Create a new website analysis
-// @Description Starts an analysis of the provided website URL
+// @Description Starts an analysis of the provided website URL. If the same URL is already being analyzed, the request is attached to that analysis instead. Analyses are queued by priority (low/normal/high); high priority requires a paid plan or the admin role and low-priority analyses may be paused while higher-priority ones run. In sandbox mode the analysis completes immediately with realistic synthetic results that are deterministic per URL; nothing is fetched and no quota is used. Optional journeys (visit, click, fill, submit and wait steps) run in a headless browser after the page is parsed and are scored in the journeys category
 // @Tags analysis
 // @Accept json
 // @Produce json
 // @Param analysis body AnalysisRequest true "Analysis Request"
+// @Param sandbox query bool false "Return synthetic results without fetching the page"
+// @Success 200 {object} map[string]interface{} "Attached to an in-flight analysis"
 // @Success 201 {object} map[string]interface{} "Analysis created successfully"
 // @Failure 400 {object} map[string]interface{} "Invalid request"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 402 {object} map[string]interface{} "Monthly analysis quota of the plan exhausted"
+// @Failure 403 {object} map[string]interface{} "Priority not allowed for plan"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Failure 503 {object} map[string]interface{} "Maintenance mode, new analyses are not accepted"
 // @Security BearerAuth
 // @Router /analysis [post]
 func (h *AnalysisHandler) CreateAnalysis(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
+	if mode := h.Maintenance.Get(c.Context()); mode.Enabled {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(mode.RetryAfter().Seconds())))
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"success":     false,
+			"error":       mode.UserMessage(),
+			"maintenance": true,
+			"ends_at":     mode.EndsAt,
+		})
+	}
+
 	req := new(AnalysisRequest)
 	if err := c.BodyParser(req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -77,46 +170,187 @@ func (h *AnalysisHandler) CreateAnalysis(c *fiber.Ctx) error {
 		})
 	}
 
+	// Variants of the same page must share one website record and history
+	normalizedURL, err := urlnorm.Normalize(req.URL)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid URL: " + err.Error(),
+		})
+	}
+	req.URL = normalizedURL
+
+	if err := req.RequestOverrides.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	overrides := req.RequestOverrides
+
+	presetKey := req.Preset
+	if presetKey == "" {
+		presetKey = c.Query("preset")
+	}
+	switch req.Mode {
+	case "", analysisModeStandard:
+	case analysisModeChecklist:
+		if presetKey != "" && presetKey != analyzer.LaunchChecklistKey {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "Checklist mode cannot be combined with a preset",
+			})
+		}
+		presetKey = analyzer.LaunchChecklistKey
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Unknown mode: " + req.Mode,
+		})
+	}
+	var preset *analyzer.Preset
+	if presetKey != "" {
+		preset, err = ResolvePreset(h.PresetRepo, userID, presetKey)
+		if errors.Is(err, errPresetNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "Unknown preset: " + presetKey,
+			})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"success": false,
+				"error":   "Failed to load preset: " + err.Error(),
+			})
+		}
+	}
+	if req.Sandbox || c.QueryBool("sandbox") {
+		return h.createSandboxAnalysis(c, userID, req.URL, preset)
+	}
+
+	priority, err := queue.ParsePriority(req.Priority)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	role, _ := c.Locals("role").(string)
+	if priority > h.maxPriority(userID, role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"success": false,
+			"error":   "Your plan does not allow " + priority.String() + " priority analyses",
+		})
+	}
+
+	if !enforceQuota(c, h.Quota, billing.ResourceAnalyses) {
+		return nil
+	}
+
+	analysis, deduplicated, err := h.startAnalysis(userID, req.URL, overrides, preset, priority, chaos.FromContext(c.UserContext()))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	if deduplicated {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"success": true,
+			"data": fiber.Map{
+				"analysis_id":  analysis.ID,
+				"status":       "running",
+				"deduplicated": true,
+				"room":         ws.AnalysisRoom(analysis.ID.String()),
+			},
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"analysis_id":    analysis.ID,
+			"status":         analysis.Status,
+			"priority":       analysis.Priority,
+			"queue_position": h.Scheduler.Position(analysis.ID.String()),
+			"queue":          h.queueStatus(analysis.ID),
+		},
+	})
+}
+
+// errMaintenance is returned by startAnalysis while maintenance mode is enabled
+var errMaintenance = errors.New("new analyses are paused for maintenance")
+
+// startAnalysis creates and queues an analysis of a normalized URL. When the
+// same variant of the URL is already being analyzed, the user is attached to
+// that analysis instead and deduplicated is true. faults are the chaos faults
+// injected into the run, nil outside resilience tests.
+func (h *AnalysisHandler) startAnalysis(userID uuid.UUID, pageURL string, overrides parser.RequestOverrides, preset *analyzer.Preset, priority queue.Priority, faults chaos.Faults) (*models.Analysis, bool, error) {
+	if h.Maintenance.Get(context.Background()).Enabled {
+		return nil, false, errMaintenance
+	}
+	variant := analysisVariant(overrides, preset)
+
+	// Attach to an in-flight analysis of the same URL instead of crawling it twice
+	analysisID, claimed := h.claimAnalysis(pageURL, variant, uuid.New())
+	if !claimed {
+		h.attachWatcher(analysisID, userID)
+		h.recordEvent(analysisID, models.AnalysisEventAttached, "", "Duplicate request attached to in-flight analysis", 0, map[string]interface{}{
+			"user_id": userID,
+		})
+		return &models.Analysis{ID: analysisID, Status: "running"}, true, nil
+	}
+
 	// Создаем или получаем веб-сайт
-	website, err := h.WebsiteRepo.FindByURL(req.URL)
+	website, err := h.WebsiteRepo.FindByURL(pageURL)
 	if err != nil {
 		// Веб-сайт не найден, создаем новый
 		website = &models.Website{
-			URL: req.URL,
+			URL: pageURL,
 		}
 		if err := h.WebsiteRepo.Create(website); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"success": false,
-				"error":   "Failed to create website record: " + err.Error(),
-			})
+			h.releaseAnalysis(pageURL, variant, analysisID)
+			return nil, false, fmt.Errorf("Failed to create website record: %w", err)
 		}
 	}
 
 	// Создаем анализ
 	analysis := models.Analysis{
+		ID:        analysisID,
 		WebsiteID: website.ID,
 		UserID:    userID,
 		Status:    "pending",
+		Priority:  priority.String(),
 		StartedAt: time.Now(),
 	}
+	// Keep a record of how the page was analyzed
+	metadata := make(map[string]interface{})
+	if !overrides.IsEmpty() {
+		metadata["request_overrides"] = overrides.Summary()
+	}
+	if preset != nil {
+		metadata["preset"] = preset.Key
+	}
+	if len(metadata) > 0 {
+		encoded, _ := json.Marshal(metadata)
+		analysis.Metadata = datatypes.JSON(encoded)
+	}
 
 	if err := h.AnalysisRepo.Create(&analysis); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"success": false,
-			"error":   "Failed to create analysis record: " + err.Error(),
-		})
+		h.releaseAnalysis(pageURL, variant, analysisID)
+		return nil, false, fmt.Errorf("Failed to create analysis record: %w", err)
 	}
 
-	// Запускаем анализ в фоновом режиме
-	go h.runAnalysis(analysis.ID, req.URL)
+	h.recordEvent(analysis.ID, models.AnalysisEventQueued, "", "Analysis queued for "+pageURL, 0, nil)
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"success": true,
-		"data": fiber.Map{
-			"analysis_id": analysis.ID,
-			"status":      analysis.Status,
-		},
+	// Запускаем анализ в фоновом режиме
+	// Analyses of the same site share the per-domain limit
+	domain, _ := urlnorm.Hostname(pageURL)
+	h.Scheduler.Submit(analysis.ID.String(), domain, priority, func(ticket *queue.Ticket) {
+		h.runAnalysis(ticket, analysis.ID, userID, pageURL, overrides, preset, faults)
 	})
+
+	return &analysis, false, nil
 }
 
 // GetAnalysisMetrics returns all metrics for a specific analysis
@@ -126,6 +360,7 @@ func (h *AnalysisHandler) CreateAnalysis(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param id path string true "Analysis ID"
+// @Param locale query string false "Locale of the number formatting metadata, e.g. de-CH"
 // @Success 200 {object} map[string]interface{} "Analysis metrics"
 // @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
@@ -156,14 +391,15 @@ func (h *AnalysisHandler) GetAnalysisMetrics(c *fiber.Ctx) error {
 	cacheKey := "analysis_metrics:" + analysisID.String()
 
 	// Try to get from cache if Redis is available
-	if h.RedisClient != nil {
+	if h.Cache != nil {
 		var cachedMetrics []map[string]interface{}
-		err := h.RedisClient.Get(cacheKey, &cachedMetrics)
+		err := h.Cache.Get(cacheKey, &cachedMetrics)
 		if err == nil && cachedMetrics != nil {
 			return c.JSON(fiber.Map{
-				"success": true,
-				"data":    cachedMetrics,
-				"cached":  true,
+				"success":    true,
+				"data":       cachedMetrics,
+				"formatting": responseFormat(c, h.UserRepo),
+				"cached":     true,
 			})
 		}
 	}
@@ -196,11 +432,15 @@ func (h *AnalysisHandler) GetAnalysisMetrics(c *fiber.Ctx) error {
 	}
 
 	// Cache the result if Redis is available
-	if h.RedisClient != nil {
-		h.RedisClient.Set(cacheKey, formattedMetrics, 30*time.Minute) // Cache for 30 minutes
+	if h.Cache != nil {
+		h.Cache.Set(cacheKey, formattedMetrics, 30*time.Minute) // Cache for 30 minutes
 	}
 
-	return c.JSON(formattedMetrics)
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       formattedMetrics,
+		"formatting": responseFormat(c, h.UserRepo),
+	})
 }
 
 // GetAnalysisMetricsByCategory returns metrics for a specific category
@@ -211,6 +451,7 @@ func (h *AnalysisHandler) GetAnalysisMetrics(c *fiber.Ctx) error {
 // @Produce json
 // @Param id path string true "Analysis ID"
 // @Param category path string true "Metric category"
+// @Param locale query string false "Locale of the number formatting metadata, e.g. de-CH"
 // @Success 200 {object} map[string]interface{} "Metrics for the specified category"
 // @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
@@ -243,14 +484,15 @@ func (h *AnalysisHandler) GetAnalysisMetricsByCategory(c *fiber.Ctx) error {
 	cacheKey := fmt.Sprintf("analysis_metrics:%s:%s", analysisID.String(), category)
 
 	// Try to get from cache if Redis is available
-	if h.RedisClient != nil {
+	if h.Cache != nil {
 		var cachedMetrics []map[string]interface{}
-		err := h.RedisClient.Get(cacheKey, &cachedMetrics)
+		err := h.Cache.Get(cacheKey, &cachedMetrics)
 		if err == nil && cachedMetrics != nil {
 			return c.JSON(fiber.Map{
-				"success": true,
-				"data":    cachedMetrics,
-				"cached":  true,
+				"success":    true,
+				"data":       cachedMetrics,
+				"formatting": responseFormat(c, h.UserRepo),
+				"cached":     true,
 			})
 		}
 	}
@@ -290,20 +532,25 @@ func (h *AnalysisHandler) GetAnalysisMetricsByCategory(c *fiber.Ctx) error {
 	}
 
 	// Cache the result if Redis is available
-	if h.RedisClient != nil {
-		h.RedisClient.Set(cacheKey, formattedMetrics, 30*time.Minute) // Cache for 30 minutes
+	if h.Cache != nil {
+		h.Cache.Set(cacheKey, formattedMetrics, 30*time.Minute) // Cache for 30 minutes
 	}
 
-	return c.JSON(formattedMetrics)
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       formattedMetrics,
+		"formatting": responseFormat(c, h.UserRepo),
+	})
 }
 
 // GetAnalysisIssues returns all issues found during analysis
 // @Summary Get issues for an analysis
-// @Description Returns all issues found during analysis
+// @Description Returns all issues found during analysis. Issues with documentation carry a docs link explaining the fix on the platform the site is built with (e.g. WordPress, Shopify or Next.js), or generic documentation when the platform has none
 // @Tags analysis
 // @Accept json
 // @Produce json
 // @Param id path string true "Analysis ID"
+// @Param locale query string false "Locale of the number formatting metadata, e.g. de-CH"
 // @Success 200 {object} map[string]interface{} "Analysis issues"
 // @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
@@ -334,14 +581,15 @@ func (h *AnalysisHandler) GetAnalysisIssues(c *fiber.Ctx) error {
 	cacheKey := "analysis_issues:" + analysisID.String()
 
 	// Try to get from cache if Redis is available
-	if h.RedisClient != nil {
+	if h.Cache != nil {
 		var cachedIssues []models.Issue
-		err := h.RedisClient.Get(cacheKey, &cachedIssues)
+		err := h.Cache.Get(cacheKey, &cachedIssues)
 		if err == nil && cachedIssues != nil {
 			return c.JSON(fiber.Map{
-				"success": true,
-				"data":    cachedIssues,
-				"cached":  true,
+				"success":    true,
+				"data":       h.withDocs(analysisID, cachedIssues),
+				"formatting": responseFormat(c, h.UserRepo),
+				"cached":     true,
 			})
 		}
 	}
@@ -356,41 +604,90 @@ func (h *AnalysisHandler) GetAnalysisIssues(c *fiber.Ctx) error {
 	}
 
 	// Cache the result if Redis is available
-	if h.RedisClient != nil {
-		h.RedisClient.Set(cacheKey, issues, 30*time.Minute) // Cache for 30 minutes
+	if h.Cache != nil {
+		h.Cache.Set(cacheKey, issues, 30*time.Minute) // Cache for 30 minutes
 	}
 
-	return c.JSON(issues)
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       h.withDocs(analysisID, issues),
+		"formatting": responseFormat(c, h.UserRepo),
+	})
 }
 
-func (a *AnalysisHandler) runAnalysis(analysisID uuid.UUID, url string) {
+func (a *AnalysisHandler) runAnalysis(ticket *queue.Ticket, analysisID, userID uuid.UUID, url string, overrides parser.RequestOverrides, preset *analyzer.Preset, faults chaos.Faults) {
+	defer a.releaseAnalysis(url, analysisVariant(overrides, preset), analysisID)
+
 	if err := a.AnalysisRepo.UpdateStatus(analysisID, "running"); err != nil {
 		a.updateAnalysisFailed(analysisID, "Error updating status: "+err.Error())
 		return
 	}
+	analysisStart := time.Now()
+	profile := newAnalysisProfile()
+
+	// Fan progress out to WebSocket viewers and database checkpoints. The
+	// final state is delivered on every return path.
+	progress := a.newProgressDispatcher(analysisID)
+	defer a.closeProgress(progress, analysisID)
+
+	a.recordEvent(analysisID, models.AnalysisEventStarted, "", "Analysis started", 0, nil)
 
 	timeout := a.Config.AnalysisTimeout
-	if timeout <= 0 || timeout > 300 {
-		timeout = 300
+	if preset != nil && preset.Budgets.TimeoutSeconds > 0 {
+		timeout = time.Duration(preset.Budgets.TimeoutSeconds) * time.Second
+	}
+	if timeout <= 0 || timeout > maxAnalysisTimeout {
+		timeout = maxAnalysisTimeout
 	}
 
-	ctx, cancel := context.WithTimeout(
-		context.Background(),
-		time.Duration(timeout)*time.Second,
-	)
+	// Faults injected by the request that started the analysis, if any
+	ctx, cancel := context.WithTimeout(chaos.WithFaults(context.Background(), faults), timeout)
 	defer cancel()
 
 	a.cancelFunctions.Store(analysisID.String(), cancel)
 	defer a.cancelFunctions.Delete(analysisID.String())
 
-	websiteData, err := parser.ParseWebsite(url, parser.ParseOptions{
-		Timeout: timeout,
+	a.recordEvent(analysisID, models.AnalysisEventParseStarted, "", "Parsing "+url, 0, nil)
+	parseStart := time.Now()
+	progress.Publish(analyzer.ProgressUpdate{
+		AnalyzerType: "parser",
+		Progress:     0,
+		Message:      "Parsing website",
+		Timestamp:    parseStart,
 	})
 
+	parseOpts := parser.ParseOptions{
+		Timeout: timeout,
+	}
+	if preset != nil {
+		parseOpts = parser.DefaultParseOptions()
+		parseOpts.Timeout = timeout
+		preset.ApplyParseOptions(&parseOpts)
+	}
+	overrides.Apply(&parseOpts, url)
+
+	websiteData, err := parser.ParseWebsite(url, parseOpts)
+
+	// Scores of a challenge page would describe the protection, not the site
+	if websiteData != nil && websiteData.Challenge != nil {
+		a.saveSnapshots(analysisID, websiteData)
+		a.updateAnalysisBlocked(analysisID, websiteData.Challenge, parseOpts)
+		return
+	}
 	if err != nil {
 		a.updateAnalysisFailed(analysisID, "Parsing error: "+err.Error())
 		return
 	}
+	profile.setParse(time.Since(parseStart), websiteData)
+	progress.Publish(analyzer.ProgressUpdate{
+		AnalyzerType: "parser",
+		Progress:     100,
+		Message:      "Website parsed",
+		Details:      map[string]interface{}{"duration_ms": time.Since(parseStart).Milliseconds()},
+		Timestamp:    time.Now(),
+	})
+	a.recordEvent(analysisID, models.AnalysisEventParseCompleted, "", "Website parsed", time.Since(parseStart), nil)
+	a.saveSnapshots(analysisID, websiteData)
 
 	// Check if context is done (cancelled or timed out)
 	select {
@@ -402,36 +699,88 @@ func (a *AnalysisHandler) runAnalysis(analysisID uuid.UUID, url string) {
 	}
 
 	// Update website information
-	website := &models.Website{
-		ID:          analysisID,
-		Title:       websiteData.Title,
-		Description: websiteData.Description,
+	var analysis models.Analysis
+	if err := a.AnalysisRepo.FindByID(analysisID, &analysis); err != nil {
+		a.updateAnalysisFailed(analysisID, "Error loading analysis: "+err.Error())
+		return
+	}
+	var website models.Website
+	if err := a.WebsiteRepo.FindByID(analysis.WebsiteID, &website); err != nil {
+		a.updateAnalysisFailed(analysisID, "Error loading website: "+err.Error())
+		return
 	}
-	if err := a.WebsiteRepo.Update(website); err != nil {
+	website.Title = websiteData.Title
+	website.Description = websiteData.Description
+	if err := a.WebsiteRepo.Update(&website); err != nil {
 		a.updateAnalysisFailed(analysisID, "Error updating website info: "+err.Error())
 		return
 	}
+	if websiteData.FinalURL != "" {
+		// Follow redirects such as http to https so later lookups hit this record
+		if err := a.WebsiteRepo.UpdateCanonicalURL(website.ID, websiteData.FinalURL); err != nil {
+			log.Printf("Failed to update canonical URL of website %s: %v", website.ID, err)
+		}
+	}
+	a.savePageEntities(analysisID, website.ID, websiteData)
+
+	// Scheduled runs of unchanged content reuse the previous results
+	contentHash := websiteData.RawHTML
+	if contentHash == "" {
+		contentHash = websiteData.HTML
+	}
+	if contentHash != "" {
+		contentHash = parser.ContentHash(contentHash)
+		if err := a.AnalysisRepo.SetMetadataKey(analysisID, "content_hash", contentHash); err != nil {
+			log.Printf("Failed to store content hash of analysis %s: %v", analysisID, err)
+		}
+	}
+	site := a.monitoredSite(analysisID)
+	if site != nil && overrides.IsEmpty() {
+		presetKey := ""
+		if preset != nil {
+			presetKey = preset.Key
+		}
+		if a.reuseUnchangedResults(&analysis, userID, url, contentHash, presetKey, analysisStart) {
+			return
+		}
+	}
+
+	// Let waiting higher-priority analyses run before the analyzers start
+	if err := a.yieldToHigherPriority(ctx, ticket, analysisID); err != nil {
+		a.updateAnalysisFailed(analysisID, "Analysis cancelled or timed out while paused: "+err.Error())
+		return
+	}
+
+	// Results are comparable across analyses only with the same analyzer behavior
+	if err := a.AnalysisRepo.SetMetadataKey(analysisID, "scoring_version", analyzer.ScoringVersion()); err != nil {
+		log.Printf("Failed to store scoring version of analysis %s: %v", analysisID, err)
+	}
 
 	// Create analyzer manager with progress tracking - register only essential analyzers
 	manager := analyzer.NewAnalyzerManager()
 
-	// Register only critical analyzers to reduce processing time
-	manager.RegisterCriticalAnalyzers()
+	if preset != nil {
+		manager.RegisterAnalyzers(preset.Analyzers)
+	} else {
+		// Register only critical analyzers to reduce processing time
+		manager.RegisterCriticalAnalyzers()
+	}
+	// Journeys supplied with the analysis are scored in their own category
+	if len(overrides.Journeys) > 0 {
+		manager.RegisterAnalyzers([]analyzer.AnalyzerType{analyzer.JourneysType})
+	}
+	// The organization's own checks run on every page it analyzes
+	if rules := a.customRules(userID); len(rules) > 0 {
+		manager.RegisterAnalyzer(analyzer.CustomRulesType, analyzer.NewCustomRulesAnalyzer(rules))
+	}
 
-	// Register progress callback with rate limiting
-	progressChan := make(chan analyzer.ProgressUpdate, 20) // Buffered channel
-	lastProgressTime := time.Now()
 	manager.SetProgressCallback(func(update analyzer.ProgressUpdate) {
-		// Rate limit progress updates to reduce WebSocket traffic
-		now := time.Now()
-		if math.Mod(update.Progress, 10) == 0 || update.Progress == 100 || now.Sub(lastProgressTime) > time.Second {
-			select {
-			case progressChan <- update:
-				lastProgressTime = now
-			default:
-				// Skip update if channel is full (non-blocking)
-			}
+		a.recordAnalyzerEvent(analysisID, update)
+		if ms, ok := update.Details["duration_ms"].(int64); ok && update.AnalyzerType != "manager" {
+			profile.setAnalyzer(update.AnalyzerType, ms)
 		}
+
+		progress.Publish(update)
 	})
 
 	// Run the analyzers with timeout
@@ -442,6 +791,7 @@ func (a *AnalysisHandler) runAnalysis(analysisID uuid.UUID, url string) {
 	case <-ctx.Done():
 		if ctx.Err() == context.Canceled {
 			a.AnalysisRepo.UpdateStatus(analysisID, "cancelled")
+			a.recordEvent(analysisID, models.AnalysisEventCancelled, "", "Analysis cancelled", time.Since(analysisStart), nil)
 			return
 		} else if ctx.Err() == context.DeadlineExceeded {
 			// Analysis timed out
@@ -457,31 +807,40 @@ func (a *AnalysisHandler) runAnalysis(analysisID uuid.UUID, url string) {
 		return
 	}
 
+	// Analyzers retried after transient failures, to tell flaky upstreams apart
+	if retries := manager.GetRetries(); len(retries) > 0 {
+		if err := a.AnalysisRepo.SetMetadataKey(analysisID, "analyzer_retries", retries); err != nil {
+			log.Printf("Failed to store analyzer retries of analysis %s: %v", analysisID, err)
+		}
+	}
+
+	// In test and CI environments results must match the published schemas
+	if a.Config != nil && a.Config.SchemaValidation {
+		if violations := analyzer.ValidateResults(results, manager.GetAllIssues()); len(violations) > 0 {
+			log.Printf("Analysis %s results violate the schemas: %s", analysisID, strings.Join(violations, "; "))
+			a.updateAnalysisFailed(analysisID, "Result schema violations: "+strings.Join(violations, "; "))
+			return
+		}
+	}
+
+	// Persisted records are forwarded to the user's event streams
+	var savedMetrics []models.AnalysisMetric
+	var savedIssues []models.Issue
+	streams := a.activeStreams(userID)
+
 	// Split database operations into separate transactions to avoid long locks
 	// First transaction: save metrics
 	err = a.AnalysisRepo.Transaction(func(tx *gorm.DB) error {
 		totalMetrics := 0
 		for analyzerType, result := range results {
-			// Only save essential metrics (score and basic info)
-			essentialData := map[string]interface{}{
-				"score": result["score"],
-				"type":  string(analyzerType),
-			}
-
-			metricData, err := json.Marshal(essentialData)
+			metric, err := scoreMetric(analysisID, analyzerType, result)
 			if err != nil {
-				return fmt.Errorf("error serializing results: %w", err)
-			}
-
-			metric := models.AnalysisMetric{
-				AnalysisID: analysisID,
-				Category:   string(analyzerType),
-				Name:       string(analyzerType) + "_score",
-				Value:      datatypes.JSON(metricData),
+				return err
 			}
 			if err := tx.Create(&metric).Error; err != nil {
 				return fmt.Errorf("error saving metric: %w", err)
 			}
+			savedMetrics = append(savedMetrics, metric)
 			totalMetrics++
 
 		}
@@ -495,45 +854,18 @@ func (a *AnalysisHandler) runAnalysis(analysisID uuid.UUID, url string) {
 
 	err = a.AnalysisRepo.Transaction(func(tx *gorm.DB) error {
 		allIssues := manager.GetAllIssues()
+		locator := pageElementLocator(websiteData, url)
+		overrides := a.severityOverrides()
 
 		for analyzerType, issues := range allIssues {
-			// Limit to 10 most important issues per category
-			maxIssues := 10
-			if len(issues) > maxIssues {
-				// Sort issues by severity (high first)
-				sort.Slice(issues, func(i, j int) bool {
-					sevI, _ := issues[i]["severity"].(string)
-					sevJ, _ := issues[j]["severity"].(string)
-					return getSeverityValue(sevI) > getSeverityValue(sevJ)
-				})
-				issues = issues[:maxIssues]
-			}
-
-			// Save each issue
-			for _, issue := range issues {
-				severity := issue["severity"].(string)
-				description := issue["description"].(string)
-
-				issueRecord := models.Issue{
-					AnalysisID:  analysisID,
-					Category:    string(analyzerType),
-					Severity:    severity,
-					Title:       description,
-					Description: description,
-				}
-
-				if location, ok := issue["url"].(string); ok {
-					issueRecord.Location = location
-				} else if count, ok := issue["count"].(int); ok {
-					issueRecord.Location = fmt.Sprintf("Count: %d", count)
-				}
-
+			for _, issueRecord := range issueRecords(analysisID, analyzerType, issues, locator, overrides) {
 				if err := tx.Create(&issueRecord).Error; err != nil {
 					return fmt.Errorf("error saving issue: %w", err)
 				}
+				savedIssues = append(savedIssues, issueRecord)
 			}
 		}
-		return nil
+		return repository.EnqueueOutbox(tx, streamDeliveries(streams, analysisID, url)...)
 	})
 
 	if err != nil {
@@ -561,21 +893,10 @@ func (a *AnalysisHandler) runAnalysis(analysisID uuid.UUID, url string) {
 				}
 				totalRecs++
 
-				// Determine priority
-				priority := "medium" // Default value
-				switch analyzerType {
-				case analyzer.SEOType, analyzer.SecurityType:
-					priority = "high"
-				case analyzer.PerformanceType, analyzer.AccessibilityType:
-					priority = "medium"
-				case analyzer.StructureType, analyzer.MobileType, analyzer.ContentType:
-					priority = "low"
-				}
-
 				recommendation := models.Recommendation{
 					AnalysisID:  analysisID,
 					Category:    string(analyzerType),
-					Priority:    priority,
+					Priority:    recommendationPriority(analyzerType),
 					Title:       rec,
 					Description: rec,
 				}
@@ -598,6 +919,12 @@ func (a *AnalysisHandler) runAnalysis(analysisID uuid.UUID, url string) {
 		a.updateAnalysisFailed(analysisID, "Error saving recommendations: "+err.Error())
 		return
 	}
+	a.recordEvent(analysisID, models.AnalysisEventReportGenerated, "", "Metrics, issues and recommendations saved", 0, nil)
+	a.Outbox.Notify()
+	go a.generateOGImage(analysisID, url, websiteData)
+
+	a.saveProfile(analysisID, profile, time.Since(analysisStart))
+	a.meterAnalysis(analysisID, userID, websiteData, results)
 
 	// Update analysis to completed status
 	if err := a.AnalysisRepo.UpdateStatus(analysisID, "completed"); err != nil {
@@ -614,6 +941,173 @@ func (a *AnalysisHandler) runAnalysis(analysisID uuid.UUID, url string) {
 			scoreCount++
 		}
 	}
+
+	overallScore := 0.0
+	if scoreCount > 0 {
+		overallScore = totalScore / float64(scoreCount)
+	}
+	a.saveOverallScore(analysisID, overallScore)
+	go a.exportAnalytics(analysisID, userID, url, overallScore, results, savedMetrics, savedIssues)
+	budgetViolations := 0
+	if budgets := budgetPreset(preset, site); budgets != nil && budgets.Budgets.HasLimits() {
+		budgetViolations = a.checkBudgets(analysisID, budgets, websiteData, overallScore, results)
+	}
+	if checklist, ok := results[analyzer.ChecklistType]; ok {
+		a.saveChecklist(analysisID, checklist)
+	}
+	if infrastructure, ok := results[analyzer.InfrastructureType]; ok {
+		a.saveInfrastructure(analysisID, infrastructure)
+	}
+	if domain, ok := results[analyzer.DomainType]; ok {
+		a.saveSubdomains(analysisID, domain)
+	}
+	if security, ok := results[analyzer.SecurityType]; ok {
+		a.saveCSP(analysisID, security)
+	}
+	if site != nil {
+		highIssues := 0
+		for _, issue := range savedIssues {
+			if issue.Severity == "high" {
+				highIssues++
+			}
+		}
+		a.evaluateSiteAlerts(site, analysisID, analyzer.AlertValues(websiteData.LoadTime, overallScore, results, highIssues, budgetViolations))
+	}
+	a.recordCompletion(analysisID, userID, overallScore, time.Since(analysisStart), map[string]interface{}{
+		"overall_score": overallScore,
+	})
+	// Post-deploy analyses are compared with the last run before the release
+	go a.recordDeployImpact(analysisID)
+
+	// Sites without a sitemap get one proposed from a crawl of their pages
+	go a.proposeSitemap(analysisID, websiteData)
+	// Backlink profile of the domain for the backlink trend
+	go a.captureBacklinks(analysisID, websiteData)
+}
+
+// recordCompletion records the completed event of an analysis and queues
+// acknowledged analysis_completed messages to its owner and watchers in the
+// same transaction, so they are redelivered if they are offline and sent
+// even if the server stops right after
+func (a *AnalysisHandler) recordCompletion(analysisID, userID uuid.UUID, overallScore float64, duration time.Duration, details map[string]interface{}) {
+	event := newAnalysisEvent(analysisID, models.AnalysisEventCompleted, "", "Analysis completed", duration, details)
+
+	var notifications []*models.OutboxMessage
+	if a.Hub != nil {
+		recipients := []uuid.UUID{userID}
+		for _, watcherID := range a.watchers(analysisID) {
+			if watcherID != userID {
+				recipients = append(recipients, watcherID)
+			}
+		}
+		for _, recipient := range recipients {
+			msg, err := ws.NewMessage(ws.MessageTypeAnalysisCompleted, ws.AnalysisRoom(analysisID.String()), fiber.Map{
+				"analysis_id":   analysisID,
+				"status":        "completed",
+				"overall_score": overallScore,
+			})
+			if err != nil {
+				continue
+			}
+			notification, err := notificationMessage(event.ID.String(), recipient, msg)
+			if err != nil {
+				continue
+			}
+			notifications = append(notifications, notification)
+		}
+	}
+
+	a.recordEventWithEffects([]models.AnalysisEvent{event}, notifications)
+}
+
+// scoreMetric builds the metric that stores the score of an analyzer
+func scoreMetric(analysisID uuid.UUID, analyzerType analyzer.AnalyzerType, result map[string]interface{}) (models.AnalysisMetric, error) {
+	// Only save essential metrics (score and basic info)
+	essentialData := map[string]interface{}{
+		"score": result["score"],
+		"type":  string(analyzerType),
+	}
+
+	metricData, err := json.Marshal(essentialData)
+	if err != nil {
+		return models.AnalysisMetric{}, fmt.Errorf("error serializing results: %w", err)
+	}
+
+	return models.AnalysisMetric{
+		AnalysisID: analysisID,
+		Category:   string(analyzerType),
+		Name:       string(analyzerType) + "_score",
+		Value:      datatypes.JSON(metricData),
+	}, nil
+}
+
+// issueRecords converts the issues reported by an analyzer into records,
+// keeping the 10 most important ones. Issues about an element, given by a
+// "selector" or the "url" of a resource, are tied to it through the locator.
+// Severities are replaced by the deployment's overrides before ranking.
+func issueRecords(analysisID uuid.UUID, analyzerType analyzer.AnalyzerType, issues []map[string]interface{}, locator *parser.ElementLocator, overrides severityOverrides) []models.Issue {
+	severityOf := func(issue map[string]interface{}) string {
+		severity, _ := issue["severity"].(string)
+		issueType, _ := issue["type"].(string)
+		return overrides.severity(string(analyzerType), issueType, severity)
+	}
+
+	maxIssues := 10
+	if len(issues) > maxIssues {
+		// Sort issues by severity (high first)
+		sort.Slice(issues, func(i, j int) bool {
+			return getSeverityValue(severityOf(issues[i])) > getSeverityValue(severityOf(issues[j]))
+		})
+		issues = issues[:maxIssues]
+	}
+
+	records := make([]models.Issue, 0, len(issues))
+	for _, issue := range issues {
+		severity := severityOf(issue)
+		description := issue["description"].(string)
+		issueType, _ := issue["type"].(string)
+
+		issueRecord := models.Issue{
+			AnalysisID:  analysisID,
+			Category:    string(analyzerType),
+			Severity:    severity,
+			Type:        issueType,
+			Title:       description,
+			Description: description,
+		}
+
+		if location, ok := issue["url"].(string); ok {
+			issueRecord.Location = location
+		} else if count, ok := issue["count"].(int); ok {
+			issueRecord.Location = fmt.Sprintf("Count: %d", count)
+		}
+
+		var element parser.ElementRef
+		var found bool
+		if selector, ok := issue["selector"].(string); ok {
+			element, found = locator.BySelector(selector)
+		} else if location, ok := issue["url"].(string); ok {
+			element, found = locator.ByURL(location)
+		}
+		if found {
+			issueRecord.ElementSelector = element.Selector
+			issueRecord.ElementHash = element.ContentHash
+		}
+		records = append(records, issueRecord)
+	}
+	return records
+}
+
+// recommendationPriority returns the priority of recommendations of an analyzer
+func recommendationPriority(analyzerType analyzer.AnalyzerType) string {
+	switch analyzerType {
+	case analyzer.SEOType, analyzer.SecurityType:
+		return "high"
+	case analyzer.StructureType, analyzer.MobileType, analyzer.ContentType:
+		return "low"
+	default:
+		return "medium"
+	}
 }
 
 // Helper function to get numeric value for severity to sort issues
@@ -631,6 +1125,8 @@ func getSeverityValue(severity string) int {
 }
 
 func (a *AnalysisHandler) updateAnalysisFailed(analysisID uuid.UUID, errorMsg string) {
+	a.recordEvent(analysisID, models.AnalysisEventError, "", errorMsg, 0, nil)
+
 	metadata := datatypes.JSON([]byte(`{"error": "` + errorMsg + `"}`))
 
 	a.AnalysisRepo.Transaction(func(tx *gorm.DB) error {
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/config"
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analytics"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+)
+
+// analyticsExportTimeout bounds the export of one analysis, including retries
+const analyticsExportTimeout = 2 * time.Minute
+
+// newAnalyticsWriter creates the configured analytics writer. It returns nil
+// when the export is disabled or misconfigured.
+func newAnalyticsWriter(cfg *config.Config) analytics.Writer {
+	if cfg.AnalyticsWriter == "" {
+		return nil
+	}
+	writer, err := analytics.NewWriter(cfg.AnalyticsWriter, analytics.Config{
+		BaseURL:     cfg.AnalyticsURL,
+		Database:    cfg.AnalyticsDatabase,
+		TablePrefix: cfg.AnalyticsTablePrefix,
+		User:        cfg.AnalyticsUser,
+		Token:       cfg.AnalyticsToken,
+	})
+	if err != nil {
+		log.Printf("Analytics export disabled: %v", err)
+		return nil
+	}
+	return writer
+}
+
+// exportAnalytics writes the flattened facts of a completed analysis to the
+// analytics store. Category scores and metric values are taken from the
+// analyzer results; for reused analyses, which have none, scores are taken
+// from the stored score metrics.
+func (a *AnalysisHandler) exportAnalytics(analysisID, userID uuid.UUID, pageURL string, overallScore float64, results map[analyzer.AnalyzerType]map[string]interface{}, metrics []models.AnalysisMetric, issues []models.Issue) {
+	if a.AnalyticsWriter == nil {
+		return
+	}
+
+	facts := analyticsFacts(analysisID, userID, pageURL, overallScore, results, metrics, issues)
+
+	ctx, cancel := context.WithTimeout(context.Background(), analyticsExportTimeout)
+	defer cancel()
+	if err := analytics.Export(ctx, a.AnalyticsWriter, facts); err != nil {
+		log.Printf("Failed to export analysis %s to %s: %v", analysisID, a.AnalyticsWriter.Name(), err)
+	}
+}
+
+// analyticsFacts flattens a completed analysis into analytics facts
+func analyticsFacts(analysisID, userID uuid.UUID, pageURL string, overallScore float64, results map[analyzer.AnalyzerType]map[string]interface{}, metrics []models.AnalysisMetric, issues []models.Issue) *analytics.Facts {
+	host, _ := urlnorm.Hostname(pageURL)
+	completedAt := time.Now().UTC()
+
+	facts := &analytics.Facts{
+		Analysis: analytics.AnalysisFact{
+			AnalysisID:   analysisID,
+			UserID:       userID,
+			URL:          pageURL,
+			Host:         host,
+			CompletedAt:  completedAt,
+			OverallScore: overallScore,
+			Reused:       results == nil,
+			IssueCount:   len(issues),
+		},
+	}
+
+	issuesByCategory := make(map[string]int)
+	for _, issue := range issues {
+		issuesByCategory[issue.Category]++
+		switch issue.Severity {
+		case "critical":
+			facts.Analysis.CriticalIssues++
+		case "high":
+			facts.Analysis.HighIssues++
+		case "medium":
+			facts.Analysis.MediumIssues++
+		case "low":
+			facts.Analysis.LowIssues++
+		}
+	}
+
+	scores := make(map[string]float64)
+	if results != nil {
+		for analyzerType, result := range results {
+			category := string(analyzerType)
+			if score, ok := result["score"].(float64); ok {
+				scores[category] = score
+			}
+			facts.Metrics = append(facts.Metrics, analytics.FlattenMetrics(analytics.MetricFact{
+				AnalysisID:  analysisID,
+				Host:        host,
+				CompletedAt: completedAt,
+				Category:    category,
+			}, result)...)
+		}
+	} else {
+		for _, metric := range metrics {
+			var value struct {
+				Score *float64 `json:"score"`
+			}
+			if err := json.Unmarshal(metric.Value, &value); err == nil && value.Score != nil {
+				scores[metric.Category] = *value.Score
+			}
+		}
+	}
+
+	for category, score := range scores {
+		facts.Scores = append(facts.Scores, analytics.ScoreFact{
+			AnalysisID:  analysisID,
+			Host:        host,
+			CompletedAt: completedAt,
+			Category:    category,
+			Score:       score,
+			IssueCount:  issuesByCategory[category],
+		})
+	}
+	sort.Slice(facts.Scores, func(i, j int) bool { return facts.Scores[i].Category < facts.Scores[j].Category })
+
+	return facts
+}
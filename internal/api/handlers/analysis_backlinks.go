@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/backlinks"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+)
+
+// newBacklinkProvider creates the configured backlink data provider. It
+// returns nil when backlink snapshots are disabled or misconfigured.
+func newBacklinkProvider(name, baseURL, apiKey string) backlinks.Provider {
+	if name == "" {
+		return nil
+	}
+	provider, err := backlinks.NewProvider(name, backlinks.Config{BaseURL: baseURL, APIKey: apiKey})
+	if err != nil {
+		log.Printf("Backlink snapshots disabled: %v", err)
+		return nil
+	}
+	return provider
+}
+
+// captureBacklinks stores a snapshot of the backlink profile of the analyzed
+// domain. A recent snapshot of the same domain is reused instead of querying
+// the provider again. A sharp drop of referring domains since the previous
+// snapshot is stored with the snapshot and recorded as an event.
+func (a *AnalysisHandler) captureBacklinks(analysisID uuid.UUID, data *parser.WebsiteData) {
+	if a.BacklinkProvider == nil || a.BacklinkRepo == nil || data == nil {
+		return
+	}
+
+	pageURL := data.FinalURL
+	if pageURL == "" {
+		pageURL = data.URL
+	}
+	host, err := urlnorm.Hostname(pageURL)
+	if err != nil {
+		return
+	}
+	domain := backlinks.Domain(host)
+
+	previous, err := a.BacklinkRepo.FindLatestByDomain(domain)
+	if err == nil && time.Since(previous.CreatedAt) < a.Config.BacklinkRefreshInterval {
+		snapshot := &models.BacklinkSnapshot{
+			AnalysisID:       analysisID,
+			Domain:           domain,
+			Provider:         previous.Provider,
+			ReferringDomains: previous.ReferringDomains,
+			Backlinks:        previous.Backlinks,
+			TopBacklinks:     previous.TopBacklinks,
+		}
+		if err := a.BacklinkRepo.Create(snapshot); err != nil {
+			log.Printf("Failed to save backlink snapshot for analysis %s: %v", analysisID, err)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backlinks.DefaultTimeout*2)
+	defer cancel()
+
+	start := time.Now()
+	profile, err := a.BacklinkProvider.Profile(ctx, domain, backlinks.DefaultTopLimit)
+	if err != nil {
+		log.Printf("Failed to fetch backlinks of %s for analysis %s: %v", domain, analysisID, err)
+		return
+	}
+
+	topBacklinks, err := json.Marshal(profile.TopBacklinks)
+	if err != nil {
+		log.Printf("Failed to encode backlinks for analysis %s: %v", analysisID, err)
+		return
+	}
+	snapshot := &models.BacklinkSnapshot{
+		AnalysisID:       analysisID,
+		Domain:           domain,
+		Provider:         a.BacklinkProvider.Name(),
+		ReferringDomains: profile.ReferringDomains,
+		Backlinks:        profile.Backlinks,
+		TopBacklinks:     datatypes.JSON(topBacklinks),
+	}
+
+	var loss *backlinks.Loss
+	if previous != nil {
+		loss = backlinks.DetectLoss(backlinkProfile(previous), profile, a.Config.BacklinkLossThreshold)
+	}
+	if loss != nil {
+		if encoded, err := json.Marshal(loss); err == nil {
+			snapshot.Loss = datatypes.JSON(encoded)
+		}
+	}
+
+	if err := a.BacklinkRepo.Create(snapshot); err != nil {
+		log.Printf("Failed to save backlink snapshot for analysis %s: %v", analysisID, err)
+		return
+	}
+
+	if loss != nil {
+		message := fmt.Sprintf("%s lost %d of %d referring domains (%.0f%%) since %s",
+			domain, loss.ReferringDomainsLost, loss.ReferringDomainsBefore, loss.LostRatio*100,
+			previous.CreatedAt.Format("2006-01-02"))
+		a.recordEvent(analysisID, models.AnalysisEventBacklinkLoss, "", message, time.Since(start), map[string]interface{}{
+			"domain":               domain,
+			"previous_snapshot_id": previous.ID,
+			"loss":                 loss,
+		})
+	}
+}
+
+// backlinkProfile converts a stored snapshot back to a profile
+func backlinkProfile(snapshot *models.BacklinkSnapshot) *backlinks.Profile {
+	profile := &backlinks.Profile{
+		Domain:           snapshot.Domain,
+		ReferringDomains: snapshot.ReferringDomains,
+		Backlinks:        snapshot.Backlinks,
+	}
+	if len(snapshot.TopBacklinks) > 0 {
+		if err := json.Unmarshal(snapshot.TopBacklinks, &profile.TopBacklinks); err != nil {
+			log.Printf("Invalid top backlinks in snapshot %s: %v", snapshot.ID, err)
+		}
+	}
+	return profile
+}
+
+// GetAnalysisBacklinks returns the backlink snapshot taken for an analysis
+// @Summary Get backlink snapshot
+// @Description Returns the referring-domain and backlink counts and the top backlinks of the analyzed domain as reported by the configured backlink data provider when the analysis ran. "loss" is set when the domain lost at least BACKLINK_LOSS_THRESHOLD of its referring domains since the previous snapshot
+// @Tags analysis
+// @Produce json
+// @Param id path string true "Analysis ID"
+// @Success 200 {object} map[string]interface{} "Backlink snapshot"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "No backlink snapshot for this analysis"
+// @Security BearerAuth
+// @Router /analysis/{id}/backlinks [get]
+func (h *AnalysisHandler) GetAnalysisBacklinks(c *fiber.Ctx) error {
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+
+	snapshot, err := h.BacklinkRepo.FindByAnalysisID(analysisID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "No backlink snapshot for this analysis",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    snapshot,
+	})
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// blockedSuggestion is a way to let the scanner past an anti-bot check
+type blockedSuggestion struct {
+	Option      string   `json:"option"`
+	Description string   `json:"description"`
+	Values      []string `json:"values,omitempty"`
+}
+
+// blockedSuggestions lists what the site owner can do about a challenge
+func (a *AnalysisHandler) blockedSuggestions(challenge *parser.Challenge, opts parser.ParseOptions) []blockedSuggestion {
+	allowlist := blockedSuggestion{
+		Option:      "allowlist_scanner",
+		Description: "Allowlist the scanner in the WAF or bot protection settings of the site",
+	}
+	if len(a.Config.ScannerEgressIPs) > 0 {
+		allowlist.Description = "Allowlist the scanner IP addresses in the WAF or bot protection settings of the site"
+		allowlist.Values = a.Config.ScannerEgressIPs
+	}
+	suggestions := []blockedSuggestion{allowlist}
+
+	if challenge.Kind == parser.ChallengeKindInterstitial && !opts.UseHeadlessBrowser {
+		suggestions = append(suggestions, blockedSuggestion{
+			Option:      "headless_browser",
+			Description: "Run the analysis again with a preset that renders the page in the headless browser, which can pass JavaScript challenges",
+		})
+	}
+	suggestions = append(suggestions, blockedSuggestion{
+		Option:      "request_overrides",
+		Description: "Run the analysis again with request overrides: a session cookie of a browser that passed the check (e.g. cf_clearance) or a header the WAF trusts",
+	})
+	return suggestions
+}
+
+// updateAnalysisBlocked finishes an analysis whose page was an anti-bot
+// check instead of the site. No scores are produced, as they would
+// describe the challenge page.
+func (a *AnalysisHandler) updateAnalysisBlocked(analysisID uuid.UUID, challenge *parser.Challenge, opts parser.ParseOptions) {
+	provider := challenge.Provider
+	if provider == "" {
+		provider = "unknown protection"
+	}
+	message := fmt.Sprintf("Blocked by anti-bot protection (%s %s)", provider, challenge.Kind)
+	a.recordEvent(analysisID, models.AnalysisEventBlocked, "", message, 0, map[string]interface{}{
+		"challenge": challenge,
+	})
+
+	for key, value := range map[string]interface{}{
+		"error":       message,
+		"blocked_by":  challenge,
+		"suggestions": a.blockedSuggestions(challenge, opts),
+	} {
+		if err := a.AnalysisRepo.SetMetadataKey(analysisID, key, value); err != nil {
+			log.Printf("Failed to save %s of blocked analysis %s: %v", key, analysisID, err)
+		}
+	}
+	if err := a.AnalysisRepo.UpdateStatus(analysisID, "blocked"); err != nil {
+		log.Printf("Failed to mark analysis %s as blocked: %v", analysisID, err)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+)
+
+// Analysis modes accepted by CreateAnalysis
+const (
+	analysisModeStandard  = "standard"
+	analysisModeChecklist = "checklist"
+)
+
+// saveChecklist stores the launch checklist outcome under the "checklist" key
+// of the analysis metadata
+func (a *AnalysisHandler) saveChecklist(analysisID uuid.UUID, result map[string]interface{}) {
+	checklist := map[string]interface{}{
+		"passed":       result["passed"],
+		"passed_count": result["passed_count"],
+		"total":        result["total"],
+		"items":        result["items"],
+	}
+	if err := a.AnalysisRepo.SetMetadataKey(analysisID, "checklist", checklist); err != nil {
+		log.Printf("Failed to save checklist for analysis %s: %v", analysisID, err)
+	}
+}
+
+// GetAnalysisChecklist returns the pass/fail launch checklist of an analysis
+// @Summary Get launch checklist
+// @Description Returns the go-live checklist of an analysis created with mode "checklist": one pass/fail entry per criterion and an overall sign-off result
+// @Tags analysis
+// @Produce json
+// @Param id path string true "Analysis ID"
+// @Success 200 {object} map[string]interface{} "Checklist"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Analysis or checklist not found"
+// @Security BearerAuth
+// @Router /analysis/{id}/checklist [get]
+func (h *AnalysisHandler) GetAnalysisChecklist(c *fiber.Ctx) error {
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+
+	var analysis models.Analysis
+	if err := h.AnalysisRepo.FindByID(analysisID, &analysis); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Analysis not found",
+		})
+	}
+
+	checklist := metadataValue(analysis.Metadata, "checklist")
+	if checklist == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "No checklist for this analysis",
+			"status":  analysis.Status,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    checklist,
+	})
+}
+
+// metadataValue returns a top-level key of the analysis metadata, or nil
+func metadataValue(metadata datatypes.JSON, key string) json.RawMessage {
+	if metadata == nil {
+		return nil
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(metadata, &values); err != nil {
+		return nil
+	}
+	if value, ok := values[key]; ok && string(value) != "null" {
+		return value
+	}
+	return nil
+}
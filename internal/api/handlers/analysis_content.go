@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// GetAnalysisContent returns the main text content of the analyzed page
+// @Summary Get extracted page content
+// @Description Returns the main content of the analyzed page with navigation, footers and other boilerplate removed, along with its word count, detected language and estimated read time
+// @Tags analysis
+// @Produce json
+// @Param id path string true "Analysis ID"
+// @Success 200 {object} map[string]interface{} "Extracted content"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Snapshot not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /analysis/{id}/content [get]
+func (h *AnalysisHandler) GetAnalysisContent(c *fiber.Ctx) error {
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+
+	cacheKey := "analysis_content:" + analysisID.String()
+	if h.Cache != nil {
+		var cached parser.MainContent
+		if err := h.Cache.Get(cacheKey, &cached); err == nil {
+			return c.JSON(fiber.Map{
+				"success": true,
+				"data":    cached,
+				"cached":  true,
+			})
+		}
+	}
+
+	// The rendered DOM contains script-inserted content, so prefer it
+	snapshot, err := h.SnapshotRepo.Find(analysisID, models.SnapshotKindDOM)
+	if err != nil {
+		snapshot, err = h.SnapshotRepo.Find(analysisID, models.SnapshotKindHTML)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "No snapshot stored for this analysis",
+		})
+	}
+
+	html, err := repository.DecompressSnapshot(snapshot)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to decompress snapshot",
+		})
+	}
+
+	content := parser.ExtractMainContent(string(html))
+
+	if h.Cache != nil {
+		h.Cache.Set(cacheKey, content, 30*time.Minute)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    content,
+	})
+}
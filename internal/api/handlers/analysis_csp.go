@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+)
+
+// saveCSP stores the Content Security Policy recommended by the security
+// analyzer under the "csp_policy" key of the analysis metadata
+func (a *AnalysisHandler) saveCSP(analysisID uuid.UUID, result map[string]interface{}) {
+	policy, ok := result["csp_policy"]
+	if !ok {
+		return
+	}
+	if err := a.AnalysisRepo.SetMetadataKey(analysisID, "csp_policy", policy); err != nil {
+		log.Printf("Failed to save CSP for analysis %s: %v", analysisID, err)
+	}
+}
+
+// GetAnalysisCSP returns the Content Security Policy recommended for the page
+// @Summary Get recommended Content Security Policy
+// @Description Returns a CSP built from the origins the page actually loaded scripts, styles, images, fonts and connections from, in report-only form with a copyable header, and warns about inline scripts, event handlers and styles the policy would block. With format=header only the header line is returned as plain text.
+// @Tags analysis
+// @Produce json,plain
+// @Param id path string true "Analysis ID"
+// @Param format query string false "Response format: json (default) or header"
+// @Success 200 {object} map[string]interface{} "Recommended policy"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Analysis or policy not found"
+// @Security BearerAuth
+// @Router /analysis/{id}/csp [get]
+func (h *AnalysisHandler) GetAnalysisCSP(c *fiber.Ctx) error {
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+
+	var analysis models.Analysis
+	if err := h.AnalysisRepo.FindByID(analysisID, &analysis); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Analysis not found",
+		})
+	}
+
+	var policy analyzer.CSPPolicy
+	raw := metadataValue(analysis.Metadata, "csp_policy")
+	if raw == nil || json.Unmarshal(raw, &policy) != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "No Content Security Policy for this analysis",
+			"status":  analysis.Status,
+		})
+	}
+
+	if c.Query("format") == "header" {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return c.SendString(policy.Header + "\n")
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    policy,
+	})
+}
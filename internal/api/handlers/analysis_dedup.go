@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+)
+
+const (
+	// Lock held by the analysis currently crawling a URL
+	keyPrefixInflightAnalysis = "analysis:inflight:"
+	// Set of users attached to an in-flight analysis
+	keyPrefixAnalysisWatchers = "analysis:watchers:"
+
+	// maxAnalysisTimeout caps the configured and preset analysis timeouts
+	maxAnalysisTimeout = 5 * time.Minute
+
+	// inflightLockTTL bounds how long a crashed analysis can block new ones.
+	// It exceeds the maximum analysis timeout.
+	inflightLockTTL = 6 * time.Minute
+)
+
+// inflightKey returns the lock key for a URL. Analyses with request
+// overrides fetch a different variant of the page and get their own key.
+func inflightKey(url, variant string) string {
+	key, err := urlnorm.Key(url)
+	if err != nil {
+		key = strings.ToLower(strings.TrimSpace(url))
+	}
+	if variant != "" {
+		key += "@" + variant
+	}
+	return keyPrefixInflightAnalysis + key
+}
+
+// analysisVariant identifies what is fetched and run for a URL, so that only
+// requests with the same overrides and preset share an analysis
+func analysisVariant(overrides parser.RequestOverrides, preset *analyzer.Preset) string {
+	variant := overrides.Fingerprint()
+	if preset != nil {
+		variant += "+" + preset.Key
+	}
+	return variant
+}
+
+// claimAnalysis takes the in-flight lock for a URL on behalf of a new analysis.
+// If another analysis of the same URL is still pending or running, its ID is
+// returned instead and the caller should attach to it.
+func (h *AnalysisHandler) claimAnalysis(url, variant string, analysisID uuid.UUID) (uuid.UUID, bool) {
+	if h.RedisClient == nil {
+		return analysisID, true
+	}
+
+	key := inflightKey(url, variant)
+	for attempt := 0; attempt < 2; attempt++ {
+		acquired, current, err := h.RedisClient.AcquireLock(key, analysisID.String(), inflightLockTTL)
+		if err != nil {
+			// Without Redis there is no coalescing, run the analysis on its own
+			log.Printf("Failed to acquire analysis lock for %s: %v", url, err)
+			return analysisID, true
+		}
+		if acquired {
+			return analysisID, true
+		}
+
+		existingID, err := uuid.Parse(current)
+		if err == nil {
+			var existing models.Analysis
+			err := h.AnalysisRepo.FindByID(existingID, &existing)
+			// The owner claims the lock before it creates the analysis record.
+			// A lock without a record is live until the lock itself expires.
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return existingID, false
+			}
+			if err == nil && (existing.Status == "pending" || existing.Status == "running" || existing.Status == "paused") {
+				return existingID, false
+			}
+		}
+
+		// The lock is stale, drop it and retry
+		h.RedisClient.ReleaseLock(key, current)
+	}
+
+	return analysisID, true
+}
+
+// releaseAnalysis releases the in-flight lock held by an analysis
+func (a *AnalysisHandler) releaseAnalysis(url, variant string, analysisID uuid.UUID) {
+	if a.RedisClient == nil {
+		return
+	}
+	if err := a.RedisClient.ReleaseLock(inflightKey(url, variant), analysisID.String()); err != nil {
+		log.Printf("Failed to release analysis lock for %s: %v", url, err)
+	}
+}
+
+// attachWatcher registers a user that requested an analysis already in flight
+func (h *AnalysisHandler) attachWatcher(analysisID, userID uuid.UUID) {
+	if h.RedisClient == nil {
+		return
+	}
+
+	ctx := context.Background()
+	key := keyPrefixAnalysisWatchers + analysisID.String()
+
+	pipe := h.RedisClient.Client.TxPipeline()
+	pipe.SAdd(ctx, key, userID.String())
+	pipe.Expire(ctx, key, inflightLockTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to attach user %s to analysis %s: %v", userID, analysisID, err)
+	}
+}
+
+// watchers returns the users attached to an analysis and forgets them
+func (a *AnalysisHandler) watchers(analysisID uuid.UUID) []uuid.UUID {
+	if a.RedisClient == nil {
+		return nil
+	}
+
+	ctx := context.Background()
+	key := keyPrefixAnalysisWatchers + analysisID.String()
+
+	members, err := a.RedisClient.Client.SMembers(ctx, key).Result()
+	if err != nil {
+		log.Printf("Failed to load watchers of analysis %s: %v", analysisID, err)
+		return nil
+	}
+	a.RedisClient.Client.Del(ctx, key)
+
+	result := make([]uuid.UUID, 0, len(members))
+	for _, member := range members {
+		if id, err := uuid.Parse(member); err == nil {
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+)
+
+// GetAnalyzerDependencies returns the analyzer dependency graph in effect
+// @Summary Get analyzer dependency graph
+// @Description Returns the dependency graph new analyses run with, where it was loaded from and why the dependencies file was last rejected, if it was
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Dependency graph"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Security BearerAuth
+// @Router /admin/analyzers/dependencies [get]
+func (h *AnalysisHandler) GetAnalyzerDependencies(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    analyzer.CurrentDependencyGraph(h.Config.AnalyzerDependenciesFile),
+	})
+}
+
+// GetAnalyzerConcurrency returns the analyzers running on this instance
+// @Summary Get analyzer concurrency
+// @Description Returns the capacity shared by the analyzers of all analyses on this instance, the capacity in effect under the current memory and CPU pressure, the weight of each analyzer, the running analyzers and the number waiting for capacity
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Analyzer concurrency"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Security BearerAuth
+// @Router /admin/analyzers/concurrency [get]
+func (h *AnalysisHandler) GetAnalyzerConcurrency(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    analyzer.CurrentThrottle(h.Config).Stats(),
+	})
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+)
+
+// saveSubdomains stores the subdomain inventory under the "subdomains" key
+// of the analysis metadata
+func (a *AnalysisHandler) saveSubdomains(analysisID uuid.UUID, result map[string]interface{}) {
+	inventory, ok := result["inventory"]
+	if !ok {
+		return
+	}
+	if err := a.AnalysisRepo.SetMetadataKey(analysisID, "subdomains", inventory); err != nil {
+		log.Printf("Failed to save subdomains for analysis %s: %v", analysisID, err)
+	}
+}
+
+// GetAnalysisSubdomains returns the subdomain inventory of an analysis
+// @Summary Get subdomain inventory
+// @Description Returns the subdomains of the analyzed domain found in the site's certificate, in DNS and in the page's links and resources, with their DNS addresses, liveness, HTTPS and certificate validity, certificate expiry, and any default server page or takeover signature they serve. Requires the domain analyzer
+// @Tags analysis
+// @Produce json
+// @Param id path string true "Analysis ID"
+// @Success 200 {object} map[string]interface{} "Subdomain inventory"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Analysis or subdomain inventory not found"
+// @Security BearerAuth
+// @Router /analysis/{id}/subdomains [get]
+func (h *AnalysisHandler) GetAnalysisSubdomains(c *fiber.Ctx) error {
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+
+	var analysis models.Analysis
+	if err := h.AnalysisRepo.FindByID(analysisID, &analysis); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Analysis not found",
+		})
+	}
+
+	subdomains := metadataValue(analysis.Metadata, "subdomains")
+	if subdomains == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "No subdomain inventory for this analysis",
+			"status":  analysis.Status,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    subdomains,
+	})
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+)
+
+// Limits of the parsed entities stored per analysis. Pages above them are
+// generated listings whose full inventory is of little use.
+const (
+	maxStoredLinks  = 5000
+	maxStoredImages = 2000
+)
+
+// savePageEntities stores the links, images and technologies of a parsed page
+// so they can be queried across analyses
+func (a *AnalysisHandler) savePageEntities(analysisID, websiteID uuid.UUID, data *parser.WebsiteData) {
+	if a.PageEntityRepo == nil || data == nil {
+		return
+	}
+
+	entities := pageEntities(analysisID, websiteID, data)
+	if err := a.PageEntityRepo.SaveForAnalysis(analysisID, entities); err != nil {
+		log.Printf("Failed to save page entities for analysis %s: %v", analysisID, err)
+	}
+}
+
+// pageEntities converts parsed page data to entity rows
+func pageEntities(analysisID, websiteID uuid.UUID, data *parser.WebsiteData) repository.PageEntities {
+	var entities repository.PageEntities
+
+	for _, link := range data.Links {
+		if len(entities.Links) >= maxStoredLinks {
+			break
+		}
+		if link.URL == "" {
+			continue
+		}
+		host, _ := urlnorm.Hostname(link.URL)
+		entities.Links = append(entities.Links, models.PageLink{
+			AnalysisID: analysisID,
+			WebsiteID:  websiteID,
+			URL:        truncateRunes(link.URL, 2048),
+			TargetHost: truncateRunes(host, 255),
+			Text:       strings.TrimSpace(link.Text),
+			IsInternal: link.IsInternal,
+			NoFollow:   link.NoFollow,
+			StatusCode: link.StatusCode,
+		})
+	}
+
+	for _, image := range data.Images {
+		if len(entities.Images) >= maxStoredImages {
+			break
+		}
+		if image.URL == "" {
+			continue
+		}
+		entities.Images = append(entities.Images, models.PageImage{
+			AnalysisID: analysisID,
+			WebsiteID:  websiteID,
+			URL:        truncateRunes(image.URL, 2048),
+			Alt:        image.Alt,
+			Width:      truncateRunes(image.Width, 20),
+			Height:     truncateRunes(image.Height, 20),
+			FileSize:   image.FileSize,
+		})
+	}
+
+	// A technology is stored once per analysis, with its highest confidence
+	seen := make(map[string]int)
+	for _, tech := range data.Technologies {
+		name := truncateRunes(strings.TrimSpace(tech.Name), 100)
+		if name == "" {
+			continue
+		}
+		if i, ok := seen[name]; ok {
+			if tech.Confidence > entities.Technologies[i].Confidence {
+				entities.Technologies[i].Confidence = tech.Confidence
+			}
+			continue
+		}
+		seen[name] = len(entities.Technologies)
+		entities.Technologies = append(entities.Technologies, models.PageTechnology{
+			AnalysisID: analysisID,
+			WebsiteID:  websiteID,
+			Name:       name,
+			Category:   truncateRunes(tech.Category, 100),
+			Version:    truncateRunes(tech.Version, 50),
+			Confidence: tech.Confidence,
+		})
+	}
+
+	return entities
+}
+
+// truncateRunes shortens a string to at most max characters
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
@@ -0,0 +1,417 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/importer"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+)
+
+// maxImportBytes limits the size of an uploaded export
+const maxImportBytes = 4 << 20
+
+// ImportedAnalysisSummary describes an analysis created by an import
+type ImportedAnalysisSummary struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	AuditedAt time.Time `json:"audited_at"`
+	Score     *float64  `json:"score,omitempty"`
+	Issues    int       `json:"issues"`
+}
+
+// AnalysisIssueChange is an issue type found by only one of two analyses
+type AnalysisIssueChange struct {
+	Category string `json:"category"`
+	Type     string `json:"type"`
+	Severity string `json:"severity"`
+	Title    string `json:"title"`
+}
+
+// AnalysisScoreChange compares the score of one category
+type AnalysisScoreChange struct {
+	Category string   `json:"category"`
+	Base     *float64 `json:"base"`
+	Target   *float64 `json:"target"`
+	Change   *float64 `json:"change,omitempty"`
+}
+
+// ImportAnalyses stores the audits of an export from another tool as analyses
+// @Summary Import audits from another tool
+// @Description Imports a Lighthouse JSON report, a Screaming Frog "Internal" CSV export or an Ahrefs Site Audit page export. Every page becomes a completed analysis dated when it was audited, with metrics and issues mapped to the categories and issue types of this platform, so history is kept and can be compared with new analyses. The same file is imported only once; imports do not count against the analysis quota
+// @Tags analysis
+// @Accept multipart/form-data
+// @Produce json
+// @Param tool formData string true "Tool that produced the export (lighthouse, screaming_frog, ahrefs)"
+// @Param file formData file true "Export file"
+// @Param audited_at formData string false "When the audit ran, for exports that do not record it (RFC 3339 or YYYY-MM-DD); defaults to now"
+// @Success 201 {object} map[string]interface{} "Imported analyses"
+// @Failure 400 {object} map[string]interface{} "Invalid export"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 409 {object} map[string]interface{} "Export already imported"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /analysis/import [post]
+func (h *AnalysisHandler) ImportAnalyses(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	tool := c.FormValue("tool")
+
+	auditedAt, err := parseUsageDate(c.FormValue("audited_at"), time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid audited_at, expected RFC 3339 or YYYY-MM-DD",
+		})
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "An export file is required",
+		})
+	}
+	if fileHeader.Size > maxImportBytes {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "The export must not exceed 4 MB",
+		})
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to read the export: " + err.Error(),
+		})
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxImportBytes))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to read the export: " + err.Error(),
+		})
+	}
+
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	exists, err := h.AnalysisRepo.ImportExists(userID, digest)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to check previous imports: " + err.Error(),
+		})
+	}
+	if exists {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"success": false,
+			"error":   "This export has already been imported",
+		})
+	}
+
+	parsed, err := importer.Parse(tool, data, auditedAt)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	imported, summaries := h.importedAnalyses(userID, parsed, fileHeader.Filename, digest)
+	if len(imported) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "The export contains no valid page URLs",
+		})
+	}
+	if err := h.AnalysisRepo.CreateImported(imported); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to store imported analyses: " + err.Error(),
+		})
+	}
+	log.Printf("User %s imported %d analyses from %s", userID, len(imported), parsed.Tool)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"tool":     parsed.Tool,
+			"imported": len(summaries),
+			"skipped":  len(parsed.Pages) - len(summaries),
+			"analyses": summaries,
+		},
+	})
+}
+
+// importedAnalyses converts the pages of an export into analysis records.
+// Issues are capped and overridden like those of analyses run here, so that
+// both compare fairly. Pages with invalid URLs are skipped.
+func (h *AnalysisHandler) importedAnalyses(userID uuid.UUID, parsed *importer.Import, filename, digest string) ([]repository.ImportedAnalysis, []ImportedAnalysisSummary) {
+	overrides := h.severityOverrides()
+	now := time.Now()
+
+	var imported []repository.ImportedAnalysis
+	var summaries []ImportedAnalysisSummary
+	for _, page := range parsed.Pages {
+		pageURL, err := urlnorm.Normalize(page.URL)
+		if err != nil {
+			continue
+		}
+
+		analysisID := uuid.New()
+		metadata := map[string]interface{}{
+			"import": map[string]interface{}{
+				"tool":        parsed.Tool,
+				"file":        filename,
+				"digest":      digest,
+				"imported_at": now,
+			},
+		}
+		if page.Score != nil {
+			metadata["overall_score"] = *page.Score
+		}
+		encoded, _ := json.Marshal(metadata)
+
+		item := repository.ImportedAnalysis{
+			URL: pageURL,
+			Analysis: &models.Analysis{
+				ID:          analysisID,
+				UserID:      userID,
+				Status:      "completed",
+				Priority:    "low",
+				StartedAt:   page.AuditedAt,
+				CompletedAt: page.AuditedAt,
+				CreatedAt:   page.AuditedAt,
+				Metadata:    datatypes.JSON(encoded),
+			},
+		}
+		for _, metric := range page.Metrics {
+			value, err := json.Marshal(metric.Value)
+			if err != nil {
+				continue
+			}
+			item.Metrics = append(item.Metrics, models.AnalysisMetric{
+				AnalysisID: analysisID,
+				Category:   metric.Category,
+				Name:       metric.Name,
+				Value:      datatypes.JSON(value),
+			})
+		}
+		for category, issues := range page.Issues {
+			item.Issues = append(item.Issues, issueRecords(analysisID, analyzer.AnalyzerType(category), issues, nil, overrides)...)
+		}
+
+		imported = append(imported, item)
+		summaries = append(summaries, ImportedAnalysisSummary{
+			ID:        analysisID,
+			URL:       pageURL,
+			AuditedAt: page.AuditedAt,
+			Score:     page.Score,
+			Issues:    len(item.Issues),
+		})
+	}
+	return imported, summaries
+}
+
+// CompareAnalyses compares the scores and issues of two analyses
+// @Summary Compare two analyses
+// @Description Compares an analysis with a later one, for example an imported audit with a new analysis of the same page: score changes per category and the issue types that were introduced or resolved
+// @Tags analysis
+// @Produce json
+// @Param id path string true "Base analysis ID"
+// @Param otherID path string true "Analysis ID to compare with"
+// @Success 200 {object} map[string]interface{} "Comparison"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Analysis not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /analysis/{id}/compare/{otherID} [get]
+func (h *AnalysisHandler) CompareAnalyses(c *fiber.Ctx) error {
+	baseID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+	targetID, err := uuid.Parse(c.Params("otherID"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+
+	var base, target models.Analysis
+	if err := h.AnalysisRepo.FindByID(baseID, &base); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Analysis not found",
+		})
+	}
+	if err := h.AnalysisRepo.FindByID(targetID, &target); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Analysis to compare with not found",
+		})
+	}
+
+	baseScores, baseIssues, err := h.analysisResults(baseID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load analysis results: " + err.Error(),
+		})
+	}
+	targetScores, targetIssues, err := h.analysisResults(targetID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load analysis results: " + err.Error(),
+		})
+	}
+
+	scores := categoryChanges(baseScores, targetScores)
+	newIssues := issueChanges(targetIssues, baseIssues)
+	resolvedIssues := issueChanges(baseIssues, targetIssues)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"base":             comparedAnalysis(&base),
+			"target":           comparedAnalysis(&target),
+			"overall":          scoreChange("overall", overallScore(&base), overallScore(&target)),
+			"categories":       scores,
+			"new_issues":       newIssues,
+			"resolved_issues":  resolvedIssues,
+			"unchanged_issues": len(issueKeys(targetIssues)) - len(newIssues),
+		},
+	})
+}
+
+// analysisResults loads the category scores and issues of an analysis
+func (h *AnalysisHandler) analysisResults(analysisID uuid.UUID) (map[string]*float64, []models.Issue, error) {
+	metrics, err := h.MetricsRepo.FindByAnalysisID(analysisID)
+	if err != nil {
+		return nil, nil, err
+	}
+	scores := make(map[string]*float64)
+	for _, metric := range metrics {
+		var value struct {
+			Score *float64 `json:"score"`
+		}
+		if json.Unmarshal(metric.Value, &value) == nil && value.Score != nil {
+			scores[metric.Category] = value.Score
+		}
+	}
+
+	issues, err := h.IssueRepo.FindByAnalysisID(analysisID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return scores, issues, nil
+}
+
+// comparedAnalysis describes one side of a comparison
+func comparedAnalysis(analysis *models.Analysis) fiber.Map {
+	source := "analysis"
+	var imported struct {
+		Tool string `json:"tool"`
+	}
+	if raw := metadataValue(analysis.Metadata, "import"); raw != nil && json.Unmarshal(raw, &imported) == nil {
+		source = "import:" + imported.Tool
+	}
+	return fiber.Map{
+		"id":         analysis.ID,
+		"website_id": analysis.WebsiteID,
+		"created_at": analysis.CreatedAt,
+		"source":     source,
+	}
+}
+
+// overallScore returns the overall score stored in the analysis metadata
+func overallScore(analysis *models.Analysis) *float64 {
+	var score float64
+	raw := metadataValue(analysis.Metadata, "overall_score")
+	if raw == nil || json.Unmarshal(raw, &score) != nil {
+		return nil
+	}
+	return &score
+}
+
+// scoreChange compares two optional scores
+func scoreChange(category string, base, target *float64) AnalysisScoreChange {
+	change := AnalysisScoreChange{Category: category, Base: base, Target: target}
+	if base != nil && target != nil {
+		delta := *target - *base
+		change.Change = &delta
+	}
+	return change
+}
+
+// categoryChanges compares the scores of every category scored by either
+// analysis, ordered by category
+func categoryChanges(baseScores, targetScores map[string]*float64) []AnalysisScoreChange {
+	categories := make([]string, 0, len(baseScores)+len(targetScores))
+	for category := range baseScores {
+		categories = append(categories, category)
+	}
+	for category := range targetScores {
+		if _, ok := baseScores[category]; !ok {
+			categories = append(categories, category)
+		}
+	}
+	sort.Strings(categories)
+
+	changes := make([]AnalysisScoreChange, 0, len(categories))
+	for _, category := range categories {
+		changes = append(changes, scoreChange(category, baseScores[category], targetScores[category]))
+	}
+	return changes
+}
+
+// issueKeys returns the category and type of each issue type
+func issueKeys(issues []models.Issue) map[string]bool {
+	keys := make(map[string]bool, len(issues))
+	for _, issue := range issues {
+		keys[issue.Category+"/"+issue.Type] = true
+	}
+	return keys
+}
+
+// issueChanges returns the issue types of a that are not in b, most severe
+// first
+func issueChanges(a, b []models.Issue) []AnalysisIssueChange {
+	known := issueKeys(b)
+	changes := []AnalysisIssueChange{}
+	for _, issue := range a {
+		key := issue.Category + "/" + issue.Type
+		if known[key] {
+			continue
+		}
+		known[key] = true
+		changes = append(changes, AnalysisIssueChange{
+			Category: issue.Category,
+			Type:     issue.Type,
+			Severity: issue.Severity,
+			Title:    issue.Title,
+		})
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return getSeverityValue(changes[i].Severity) > getSeverityValue(changes[j].Severity)
+	})
+	return changes
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+)
+
+// saveInfrastructure stores the detected serving stack under the
+// "infrastructure" key of the analysis metadata
+func (a *AnalysisHandler) saveInfrastructure(analysisID uuid.UUID, result map[string]interface{}) {
+	if err := a.AnalysisRepo.SetMetadataKey(analysisID, "infrastructure", result["infrastructure"]); err != nil {
+		log.Printf("Failed to save infrastructure for analysis %s: %v", analysisID, err)
+	}
+}
+
+// GetAnalysisInfrastructure returns the hosting and CDN diagnostics of an analysis
+// @Summary Get infrastructure diagnostics
+// @Description Returns the serving stack detected during the analysis: CDN provider and the evidence for it, server software, protocol, server IP with its geolocation and ASN, IPv4/IPv6 reachability with the latency difference, and the audience the page targets
+// @Tags analysis
+// @Produce json
+// @Param id path string true "Analysis ID"
+// @Param locale query string false "Locale of the number formatting metadata, e.g. de-CH"
+// @Success 200 {object} map[string]interface{} "Infrastructure metadata"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Analysis or infrastructure data not found"
+// @Security BearerAuth
+// @Router /analysis/{id}/infrastructure [get]
+func (h *AnalysisHandler) GetAnalysisInfrastructure(c *fiber.Ctx) error {
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+
+	var analysis models.Analysis
+	if err := h.AnalysisRepo.FindByID(analysisID, &analysis); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Analysis not found",
+		})
+	}
+
+	infrastructure := metadataValue(analysis.Metadata, "infrastructure")
+	if infrastructure == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "No infrastructure data for this analysis",
+			"status":  analysis.Status,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       infrastructure,
+		"formatting": responseFormat(c, h.UserRepo),
+	})
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// maxElementHTML limits the markup of a located element in responses
+const maxElementHTML = 2000
+
+// pageElementLocator returns a locator over the markup that is stored as the
+// snapshot of a page: the rendered DOM when available, otherwise the HTML
+func pageElementLocator(data *parser.WebsiteData, pageURL string) *parser.ElementLocator {
+	if data == nil {
+		return nil
+	}
+	markup := data.RenderedDOM
+	if markup == "" {
+		markup = data.RawHTML
+	}
+	if markup == "" {
+		markup = data.HTML
+	}
+	if data.FinalURL != "" {
+		pageURL = data.FinalURL
+	}
+	return parser.NewElementLocator(markup, pageURL)
+}
+
+// GetAnalysisIssue returns one issue and locates its element in a stored snapshot
+// @Summary Get an issue with its page element
+// @Description Returns an issue of an analysis. Issues about a page element carry a stable element ID (content hash and selector); the element is located in the stored DOM snapshot of the analysis, or of the analysis given by "in", so the frontend can highlight it. The match is "exact", "moved" when the element was found by its content elsewhere, or "selector" when only its position matched. docs links to documentation on fixing the issue on the platform the site is built with
+// @Tags analysis
+// @Produce json
+// @Param id path string true "Analysis ID"
+// @Param issueID path string true "Issue ID"
+// @Param in query string false "ID of another analysis of the page whose snapshot to search"
+// @Success 200 {object} map[string]interface{} "Issue with element"
+// @Failure 400 {object} map[string]interface{} "Invalid ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Issue not found"
+// @Security BearerAuth
+// @Router /analysis/{id}/issues/{issueID} [get]
+func (h *AnalysisHandler) GetAnalysisIssue(c *fiber.Ctx) error {
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+	issueID, err := uuid.Parse(c.Params("issueID"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid issue ID",
+		})
+	}
+	snapshotOf := analysisID
+	if in := c.Query("in"); in != "" {
+		if snapshotOf, err = uuid.Parse(in); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "Invalid analysis ID in \"in\"",
+			})
+		}
+	}
+
+	var issue models.Issue
+	if err := h.IssueRepo.FindByID(issueID, &issue); err != nil || issue.AnalysisID != analysisID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Issue not found",
+		})
+	}
+
+	data := fiber.Map{"issue": issue}
+	if docs := issueDocResolver(h.DocLinkRepo).Resolve(issue.Category, issue.Type, analysisPlatforms(h.PageEntityRepo, analysisID)); docs != nil {
+		data["docs"] = docs
+	}
+	if issue.ElementSelector != "" {
+		ref := parser.ElementRef{Selector: issue.ElementSelector, ContentHash: issue.ElementHash}
+		element := fiber.Map{
+			"id":           ref.ID(),
+			"selector":     ref.Selector,
+			"content_hash": ref.ContentHash,
+			"analysis_id":  snapshotOf,
+			"found":        false,
+		}
+		if locator, kind := h.snapshotLocator(snapshotOf); locator != nil {
+			element["snapshot"] = kind
+			if sel, match, ok := locator.Resolve(ref); ok {
+				html, _ := goquery.OuterHtml(sel)
+				if len(html) > maxElementHTML {
+					html = html[:maxElementHTML]
+				}
+				element["found"] = true
+				element["match"] = match
+				element["current_selector"] = parser.ElementSelector(sel)
+				element["html"] = html
+			}
+		}
+		data["element"] = element
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    data,
+	})
+}
+
+// snapshotLocator returns a locator over the stored DOM snapshot of an
+// analysis, or its HTML snapshot, and the kind of snapshot used
+func (h *AnalysisHandler) snapshotLocator(analysisID uuid.UUID) (*parser.ElementLocator, string) {
+	if h.SnapshotRepo == nil {
+		return nil, ""
+	}
+	for _, kind := range []string{models.SnapshotKindDOM, models.SnapshotKindHTML} {
+		snapshot, err := h.SnapshotRepo.Find(analysisID, kind)
+		if err != nil {
+			continue
+		}
+		content, err := repository.DecompressSnapshot(snapshot)
+		if err != nil {
+			continue
+		}
+		return parser.NewElementLocator(string(content), ""), kind
+	}
+	return nil, ""
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/ogimage"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+)
+
+// generateOGImage renders a preview image for a page without og:image,
+// stores it as a snapshot and recommends it together with the meta tags
+// that reference it
+func (a *AnalysisHandler) generateOGImage(analysisID uuid.UUID, pageURL string, data *parser.WebsiteData) {
+	if !a.Config.OGImageGeneration || a.SnapshotRepo == nil || data == nil {
+		return
+	}
+	if strings.TrimSpace(data.MetaTags["og:image"]) != "" {
+		return
+	}
+
+	domain, _ := urlnorm.Hostname(pageURL)
+	domain = strings.TrimPrefix(domain, "www.")
+	card := ogimage.Card{
+		Title:       firstNonEmpty(data.MetaTags["og:title"], data.Title, firstString(data.H1), domain),
+		Description: firstNonEmpty(data.MetaTags["og:description"], data.Description),
+		Domain:      domain,
+		Accent:      data.MetaTags["theme-color"],
+		Screenshot:  data.Screenshots[parser.DesktopDevice.Name],
+	}
+	if card.Screenshot == nil {
+		for _, screenshot := range data.Screenshots {
+			card.Screenshot = screenshot
+			break
+		}
+	}
+
+	image, err := ogimage.Render(context.Background(), card, ogimage.Options{})
+	if err != nil {
+		log.Printf("Failed to generate og:image for analysis %s: %v", analysisID, err)
+		return
+	}
+	if err := a.SnapshotRepo.Save(analysisID, models.SnapshotKindOGImage, string(image)); err != nil {
+		log.Printf("Failed to save og:image for analysis %s: %v", analysisID, err)
+		return
+	}
+
+	imageURL := ogimage.SuggestedURL(pageURL)
+	recommendation := models.Recommendation{
+		AnalysisID: analysisID,
+		Category:   "seo",
+		Priority:   "medium",
+		Title:      "Добавьте изображение для предпросмотра (og:image)",
+		Description: fmt.Sprintf("У страницы нет og:image, поэтому соцсети и мессенджеры показывают ссылку без картинки. "+
+			"Мы подготовили изображение %dx%d: скачайте его (GET /api/analysis/%s/og-image), разместите по адресу %s и добавьте теги в <head>.",
+			ogimage.Width, ogimage.Height, analysisID, imageURL),
+		CodeSnippet: ogimage.MetaTags(imageURL, card.Title),
+	}
+	if err := a.RecommendationRepo.Create(&recommendation); err != nil {
+		log.Printf("Failed to save og:image recommendation for analysis %s: %v", analysisID, err)
+	}
+}
+
+// firstNonEmpty returns the first value that is not blank
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// firstString returns the first element of a list, if any
+func firstString(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// GetAnalysisOGImage returns the preview image generated for a page without og:image
+// @Summary Get generated Open Graph image
+// @Description Returns the 1200x630 PNG preview image generated for a page that has no og:image: the page title and domain next to a thumbnail of the page
+// @Tags analysis
+// @Produce png
+// @Param id path string true "Analysis ID"
+// @Success 200 {file} binary "PNG image"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "No image generated for this analysis"
+// @Security BearerAuth
+// @Router /analysis/{id}/og-image [get]
+func (h *AnalysisHandler) GetAnalysisOGImage(c *fiber.Ctx) error {
+	return h.serveSnapshot(c, models.SnapshotKindOGImage, "image/png")
+}
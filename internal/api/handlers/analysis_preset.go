@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// checkBudgets stores the budget outcome of an analysis run with a preset,
+// records an event for each violated budget and returns the violation count
+func (a *AnalysisHandler) checkBudgets(
+	analysisID uuid.UUID,
+	preset *analyzer.Preset,
+	data *parser.WebsiteData,
+	overallScore float64,
+	results map[analyzer.AnalyzerType]map[string]interface{},
+) int {
+	violations := preset.CheckBudgets(data.LoadTime, overallScore, results)
+
+	outcome := map[string]interface{}{
+		"preset":     preset.Key,
+		"passed":     len(violations) == 0,
+		"violations": violations,
+	}
+	if err := a.AnalysisRepo.SetMetadataKey(analysisID, "budgets", outcome); err != nil {
+		log.Printf("Failed to save budget outcome for analysis %s: %v", analysisID, err)
+	}
+
+	for _, violation := range violations {
+		a.recordEvent(analysisID, models.AnalysisEventBudgetExceeded, violation.Analyzer, "Budget "+violation.Budget+" exceeded", 0, map[string]interface{}{
+			"preset": preset.Key,
+			"limit":  violation.Limit,
+			"actual": violation.Actual,
+		})
+	}
+	return len(violations)
+}
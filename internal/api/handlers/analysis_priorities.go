@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/issuedocs"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/priority"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+)
+
+// maxCategoryWeight bounds the category weights a client can set
+const maxCategoryWeight = 10
+
+// PriorityItem is a ranked issue with the documentation on fixing it
+type PriorityItem struct {
+	priority.Item
+	Docs *issuedocs.Link `json:"docs,omitempty"`
+}
+
+// GetAnalysisPriorities returns the issues of an analysis ranked by what to
+// fix first
+// @Summary Get fix priorities
+// @Description Ranks the issues of an analysis into a single list of what to fix first. The score of an issue is its severity, weighted by its category, by the search traffic of the page (estimated from the positions of the owner's tracked keywords, for SEO, content, performance and mobile issues) and by the budgets the analysis missed, divided by the estimated effort of the fix. Each item lists the factors of its score and a justification
+// @Tags analysis
+// @Produce json
+// @Param id path string true "Analysis ID"
+// @Param weights query string false "Category weights as category:weight pairs, e.g. security:2,seo:1.5; a weight of 0 leaves the category out"
+// @Param limit query int false "Maximum number of items"
+// @Success 200 {object} map[string]interface{} "Ranked fix list"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Analysis not found"
+// @Failure 409 {object} map[string]interface{} "Analysis not completed"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /analysis/{id}/priorities [get]
+func (h *AnalysisHandler) GetAnalysisPriorities(c *fiber.Ctx) error {
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+
+	weights, err := parseCategoryWeights(c.Query("weights"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	limit := c.QueryInt("limit", 0)
+	if limit < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Limit must not be negative",
+		})
+	}
+
+	var analysis models.Analysis
+	if err := h.AnalysisRepo.FindByID(analysisID, &analysis); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Analysis not found",
+		})
+	}
+	if analysis.Status != "completed" {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"success": false,
+			"error":   "Analysis is not completed",
+			"status":  analysis.Status,
+		})
+	}
+	var website models.Website
+	if err := h.WebsiteRepo.FindByID(analysis.WebsiteID, &website); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch website data",
+		})
+	}
+
+	issues, err := h.IssueRepo.FindByAnalysisID(analysisID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch issues",
+		})
+	}
+
+	input := priority.Input{
+		CategoryWeights: weights,
+		Traffic:         h.pageTraffic(analysis.UserID, website.URL),
+		Violations:      budgetViolations(analysis),
+	}
+	byID := make(map[string]models.Issue, len(issues))
+	for _, issue := range issues {
+		byID[issue.ID.String()] = issue
+		input.Issues = append(input.Issues, priority.Issue{
+			ID:       issue.ID.String(),
+			Category: issue.Category,
+			Severity: issue.Severity,
+			Type:     issue.Type,
+			Title:    issue.Title,
+			Location: issue.Location,
+		})
+	}
+
+	ranked := priority.Rank(input)
+	total := len(ranked)
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	resolver := issueDocResolver(h.DocLinkRepo)
+	platforms := analysisPlatforms(h.PageEntityRepo, analysisID)
+	items := make([]PriorityItem, 0, len(ranked))
+	for _, item := range ranked {
+		issue := byID[item.Issue.ID]
+		items = append(items, PriorityItem{
+			Item: item,
+			Docs: resolver.Resolve(issue.Category, issue.Type, platforms),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"analysis_id":       analysisID,
+			"url":               website.URL,
+			"total":             total,
+			"items":             items,
+			"category_weights":  weights,
+			"traffic":           input.Traffic,
+			"budget_violations": input.Violations,
+		},
+	})
+}
+
+// parseCategoryWeights parses category:weight pairs
+func parseCategoryWeights(value string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		category, raw, ok := strings.Cut(pair, ":")
+		category = strings.TrimSpace(category)
+		if !ok || category == "" {
+			return nil, fmt.Errorf("invalid category weight %q, expected category:weight", pair)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil || weight < 0 || weight > maxCategoryWeight {
+			return nil, fmt.Errorf("weight of %s must be a number from 0 to %d", category, maxCategoryWeight)
+		}
+		weights[category] = weight
+	}
+	return weights, nil
+}
+
+// pageTraffic estimates the search traffic of a page from the positions of
+// the tracked keywords of the analysis owner that rank it
+func (h *AnalysisHandler) pageTraffic(userID uuid.UUID, pageURL string) priority.Traffic {
+	if h.KeywordRepo == nil {
+		return priority.Traffic{}
+	}
+	keywords, err := h.KeywordRepo.FindByUserID(userID)
+	if err != nil {
+		log.Printf("Failed to load tracked keywords of user %s: %v", userID, err)
+		return priority.Traffic{}
+	}
+
+	pageKey, _ := urlnorm.Key(pageURL)
+	var positions []int
+	for _, keyword := range keywords {
+		if !keyword.Active || keyword.Position == nil {
+			continue
+		}
+		if key, _ := urlnorm.Key(keyword.URL); key == pageKey {
+			positions = append(positions, *keyword.Position)
+		}
+	}
+	return priority.TrafficFromPositions(positions)
+}
+
+// budgetViolations returns the budgets an analysis did not meet, stored
+// under the "budgets" key of its metadata
+func budgetViolations(analysis models.Analysis) []analyzer.BudgetViolation {
+	raw := metadataValue(analysis.Metadata, "budgets")
+	if raw == nil {
+		return nil
+	}
+	var outcome struct {
+		Violations []analyzer.BudgetViolation `json:"violations"`
+	}
+	if err := json.Unmarshal(raw, &outcome); err != nil {
+		return nil
+	}
+	return outcome.Violations
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// analysisProfile records where the wall time of an analysis was spent. It is
+// stored under the "profile" key of the analysis metadata.
+type analysisProfile struct {
+	TotalMs     int64            `json:"total_ms"`
+	ParseMs     int64            `json:"parse_ms"`
+	ParsePhases map[string]int64 `json:"parse_phases"`
+	Analyzers   map[string]int64 `json:"analyzers"`
+
+	mu sync.Mutex
+}
+
+// newAnalysisProfile creates an empty profile
+func newAnalysisProfile() *analysisProfile {
+	return &analysisProfile{
+		ParsePhases: make(map[string]int64),
+		Analyzers:   make(map[string]int64),
+	}
+}
+
+// setParse records the parse duration and its phase breakdown
+func (p *analysisProfile) setParse(duration time.Duration, data *parser.WebsiteData) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.ParseMs = duration.Milliseconds()
+	if data == nil {
+		return
+	}
+	for phase, phaseDuration := range data.PhaseTimings {
+		p.ParsePhases[phase] = phaseDuration.Milliseconds()
+	}
+}
+
+// setAnalyzer records the wall time of an analyzer. It is called concurrently
+// from the analyzer manager's progress callback.
+func (p *analysisProfile) setAnalyzer(analyzerType string, durationMs int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.Analyzers[analyzerType] = durationMs
+}
+
+// saveProfile stores the profile in the analysis metadata
+func (a *AnalysisHandler) saveProfile(analysisID uuid.UUID, profile *analysisProfile, total time.Duration) {
+	profile.mu.Lock()
+	profile.TotalMs = total.Milliseconds()
+	profile.mu.Unlock()
+
+	if err := a.AnalysisRepo.SetMetadataKey(analysisID, "profile", profile); err != nil {
+		log.Printf("Failed to save profile for analysis %s: %v", analysisID, err)
+	}
+}
+
+// GetSlowAnalysisDiagnostics aggregates which analyzers and targets are consistently slow
+// @Summary Get slow analysis diagnostics
+// @Description Aggregates per-analyzer wall time and the slowest analysis targets from stored analysis profiles
+// @Tags admin
+// @Produce json
+// @Param days query int false "Look-back window in days" default(7)
+// @Param threshold_ms query int false "Duration above which a run counts as slow" default(30000)
+// @Param min_runs query int false "Minimum number of analyses per target" default(2)
+// @Param limit query int false "Maximum number of targets" default(20)
+// @Success 200 {object} map[string]interface{} "Slow analyzer and target statistics"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /admin/analysis/slow [get]
+func (h *AnalysisHandler) GetSlowAnalysisDiagnostics(c *fiber.Ctx) error {
+	days := c.QueryInt("days", 7)
+	if days <= 0 {
+		days = 7
+	}
+	thresholdMs := int64(c.QueryInt("threshold_ms", 30000))
+	minRuns := c.QueryInt("min_runs", 2)
+	if minRuns < 1 {
+		minRuns = 1
+	}
+	limit := c.QueryInt("limit", 20)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+
+	analyzers, err := h.AnalysisRepo.AnalyzerDurationStats(since)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to aggregate analyzer durations: " + err.Error(),
+		})
+	}
+
+	targets, err := h.AnalysisRepo.SlowTargetStats(since, thresholdMs, minRuns, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to aggregate slow targets: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"since":        since,
+			"threshold_ms": thresholdMs,
+			"analyzers":    analyzers,
+			"targets":      targets,
+		},
+	})
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+	ws "github.com/chynybekuuludastan/website_optimizer/internal/websocket"
+)
+
+// checkpointInterval is the minimum time between progress checkpoints written
+// to the database, except for analyzer completions and the final state
+const checkpointInterval = 2 * time.Second
+
+// newProgressDispatcher creates the dispatcher feeding an analysis' progress
+// to WebSocket viewers and database checkpoints
+func (a *AnalysisHandler) newProgressDispatcher(analysisID uuid.UUID) *analyzer.ProgressDispatcher {
+	dispatcher := analyzer.NewProgressDispatcher()
+	if a.Hub != nil {
+		dispatcher.Subscribe(a.broadcastProgress(analysisID))
+	}
+	dispatcher.Subscribe(a.checkpointProgress(analysisID))
+	return dispatcher
+}
+
+// closeProgress publishes the terminal status of the analysis and waits until
+// every consumer received it
+func (a *AnalysisHandler) closeProgress(dispatcher *analyzer.ProgressDispatcher, analysisID uuid.UUID) {
+	status := "unknown"
+	var analysis models.Analysis
+	if err := a.AnalysisRepo.FindByID(analysisID, &analysis); err == nil {
+		status = analysis.Status
+	}
+
+	dispatcher.Close(analyzer.ProgressUpdate{
+		AnalyzerType: "manager",
+		Progress:     100,
+		Message:      "Analysis " + status,
+		Details: map[string]interface{}{
+			"status": status,
+			"final":  true,
+		},
+		Timestamp: time.Now(),
+	})
+
+	if coalesced := dispatcher.Coalesced(); coalesced > 0 {
+		log.Printf("Coalesced %d progress updates for analysis %s", coalesced, analysisID)
+	}
+}
+
+// broadcastProgress sends progress updates to everyone in the analysis room
+func (a *AnalysisHandler) broadcastProgress(analysisID uuid.UUID) analyzer.ProgressSink {
+	room := ws.AnalysisRoom(analysisID.String())
+
+	return func(update analyzer.ProgressUpdate) {
+		msg, err := ws.NewMessage(ws.MessageTypeAnalysisProgress, room, progressPayload(analysisID, update))
+		if err != nil {
+			return
+		}
+		a.Hub.BroadcastToRoom(room, msg, nil)
+	}
+}
+
+// checkpointProgress stores the latest progress in the analysis metadata so it
+// survives reconnects and can be served over REST
+func (a *AnalysisHandler) checkpointProgress(analysisID uuid.UUID) analyzer.ProgressSink {
+	var lastCheckpoint time.Time
+	analyzers := make(map[string]float64)
+	overall := 0.0
+
+	return func(update analyzer.ProgressUpdate) {
+		if update.AnalyzerType != "manager" {
+			analyzers[update.AnalyzerType] = update.Progress
+		} else {
+			overall = update.Progress
+		}
+
+		final, _ := update.Details["final"].(bool)
+		if !final && update.Progress < 100 && time.Since(lastCheckpoint) < checkpointInterval {
+			return
+		}
+		lastCheckpoint = time.Now()
+
+		checkpoint := progressPayload(analysisID, update)
+		checkpoint["analyzers"] = analyzers
+		checkpoint["overall_progress"] = overall
+		if err := a.AnalysisRepo.SetMetadataKey(analysisID, "progress", checkpoint); err != nil {
+			log.Printf("Failed to checkpoint progress for analysis %s: %v", analysisID, err)
+		}
+	}
+}
+
+// progressPayload converts a progress update to its wire format
+func progressPayload(analysisID uuid.UUID, update analyzer.ProgressUpdate) fiber.Map {
+	payload := fiber.Map{
+		"analysis_id": analysisID,
+		"analyzer":    update.AnalyzerType,
+		"progress":    update.Progress,
+		"message":     update.Message,
+		"timestamp":   update.Timestamp,
+	}
+	if len(update.Details) > 0 {
+		payload["details"] = update.Details
+	}
+	return payload
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/config"
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/queue"
+)
+
+// Reasons a queued analysis is waiting, as reported by the API
+const (
+	waitReasonWorkers = "workers_busy"
+	waitReasonDomain  = "domain_limit"
+)
+
+// newAnalysisScheduler creates the scheduler of analysis runs. Runs are keyed
+// by the target domain so a single site is not crawled by many analyses at once.
+func newAnalysisScheduler(cfg *config.Config) *queue.Scheduler {
+	scheduler := queue.NewScheduler(cfg.AnalysisMaxConcurrent, cfg.AnalysisPreemption)
+	scheduler.SetMaxPerKey(cfg.AnalysisMaxPerDomain)
+	return scheduler
+}
+
+// queueStatus describes why a queued analysis has not started yet, or nil if
+// it is not waiting for the scheduler
+func (a *AnalysisHandler) queueStatus(analysisID uuid.UUID) fiber.Map {
+	id := analysisID.String()
+	var reason, message string
+	switch a.Scheduler.WaitReason(id) {
+	case queue.WaitReasonKeyLimit:
+		reason = waitReasonDomain
+		message = "Waiting for other analyses of the same domain to finish"
+	case queue.WaitReasonWorkerLimit:
+		reason = waitReasonWorkers
+		message = "Waiting for a free analysis worker"
+	default:
+		return nil
+	}
+
+	return fiber.Map{
+		"position": a.Scheduler.Position(id),
+		"reason":   reason,
+		"message":  message,
+	}
+}
+
+// maxPriority returns the highest analysis priority a user may request.
+// Administrators may always request high priority, other users up to the
+// priority of their subscription plan.
+func (a *AnalysisHandler) maxPriority(userID uuid.UUID, role string) queue.Priority {
+	if role == "admin" {
+		return queue.PriorityHigh
+	}
+	if a.Quota == nil {
+		return queue.PriorityNormal
+	}
+
+	priority, err := queue.ParsePriority(a.Quota.Plan(userID).MaxPriority)
+	if err != nil {
+		return queue.PriorityNormal
+	}
+	return priority
+}
+
+// yieldToHigherPriority pauses a low-priority analysis while higher-priority
+// analyses are waiting for a worker
+func (a *AnalysisHandler) yieldToHigherPriority(ctx context.Context, ticket *queue.Ticket, analysisID uuid.UUID) error {
+	paused, err := ticket.Yield(ctx, func() {
+		a.AnalysisRepo.UpdateStatus(analysisID, "paused")
+		a.recordEvent(analysisID, models.AnalysisEventPaused, "", "Paused for higher-priority analyses", 0, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	if paused {
+		a.AnalysisRepo.UpdateStatus(analysisID, "running")
+		a.recordEvent(analysisID, models.AnalysisEventResumed, "", "Analysis resumed", 0, nil)
+	}
+	return nil
+}
+
+// GetQueueStats returns the state of the analysis scheduler
+// @Summary Get analysis queue statistics
+// @Description Returns running, queued and paused analyses per priority, the per-domain limit, running analyses per domain and the number of analyses held back by it
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Queue statistics"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Security BearerAuth
+// @Router /admin/analysis/queue [get]
+func (h *AnalysisHandler) GetQueueStats(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    h.Scheduler.Stats(),
+	})
+}
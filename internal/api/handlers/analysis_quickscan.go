@@ -0,0 +1,463 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/billing"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/captcha"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+)
+
+const (
+	// keyPrefixQuickScan stores a quick scan under its claim token
+	keyPrefixQuickScan = "quick_scan:"
+	// keyPrefixQuickScanRate counts quick scans per client IP and scanned
+	// host per hour
+	keyPrefixQuickScanRate = "quick_scan:rate:"
+	// keyPrefixQuickScanClaim locks a claim token while it is claimed
+	keyPrefixQuickScanClaim = "quick_scan:claim:"
+
+	// quickScanTopIssues is the number of issues shown before a scan is claimed
+	quickScanTopIssues = 5
+	// quickScanBusyRetry is suggested to clients when every scan slot is taken
+	quickScanBusyRetry = 10 * time.Second
+)
+
+// quickScanAnalyzers only need the fetched HTML of the page
+var quickScanAnalyzers = []analyzer.AnalyzerType{
+	analyzer.SEOType,
+	analyzer.ContentType,
+	analyzer.SecurityType,
+	analyzer.StructureType,
+	analyzer.MobileType,
+}
+
+var errQuickScanBlocked = errors.New("the site answered with a bot protection challenge")
+
+// QuickScanRequest names the page to scan and carries the CAPTCHA token
+// solved by the visitor
+type QuickScanRequest struct {
+	URL          string `json:"url" validate:"required,url"`
+	CaptchaToken string `json:"captcha_token"`
+}
+
+// QuickScanIssue is an issue shown in a quick scan
+type QuickScanIssue struct {
+	Category string `json:"category"`
+	Severity string `json:"severity"`
+	Title    string `json:"title"`
+}
+
+// QuickScanResult is the preview of a page shown to anonymous visitors. The
+// full issues and recommendations are saved when the scan is claimed.
+type QuickScanResult struct {
+	Token        string             `json:"token"`
+	URL          string             `json:"url"`
+	Title        string             `json:"title"`
+	Description  string             `json:"description"`
+	OverallScore float64            `json:"overall_score"`
+	Scores       map[string]float64 `json:"scores"`
+	IssueCounts  map[string]int     `json:"issue_counts"` // by severity
+	TopIssues    []QuickScanIssue   `json:"top_issues"`
+	CreatedAt    time.Time          `json:"created_at"`
+	ExpiresAt    time.Time          `json:"expires_at"`
+}
+
+// quickScan is a quick scan as stored until it is claimed or expires
+type quickScan struct {
+	QuickScanResult
+	Issues          map[analyzer.AnalyzerType][]map[string]interface{} `json:"issues"`
+	Recommendations map[analyzer.AnalyzerType][]string                 `json:"recommendations"`
+}
+
+// QuickScan runs an anonymous preview analysis of a page
+// @Summary Scan a page anonymously
+// @Description Fetches a single page and runs the lightweight analyzers (SEO, content, security, structure and mobile) without JavaScript rendering, link checks or LLM calls. Requires a CAPTCHA token when the deployment configures a CAPTCHA secret and is rate limited per client IP and per scanned host. In production the endpoint is unavailable without a CAPTCHA secret and Redis. The result is kept for a limited time under its token; a signed-in user claims it with POST /scan/{token}/claim to save it as an analysis
+// @Tags scan
+// @Accept json
+// @Produce json
+// @Param scan body QuickScanRequest true "Page to scan"
+// @Success 201 {object} map[string]interface{} "Scan result with its claim token"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 403 {object} map[string]interface{} "CAPTCHA verification failed"
+// @Failure 422 {object} map[string]interface{} "The page could not be scanned"
+// @Failure 429 {object} map[string]interface{} "Too many scans"
+// @Failure 503 {object} map[string]interface{} "All scan slots are busy, maintenance mode or quick scans not configured"
+// @Router /scan [post]
+func (h *AnalysisHandler) QuickScan(c *fiber.Ctx) error {
+	if mode := h.Maintenance.Get(c.Context()); mode.Enabled {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(mode.RetryAfter().Seconds())))
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"success":     false,
+			"error":       mode.UserMessage(),
+			"maintenance": true,
+			"ends_at":     mode.EndsAt,
+		})
+	}
+
+	// Production deployments must not expose an unprotected scanner
+	if h.Config.Environment == "production" && (!h.Captcha.Enabled() || h.RedisClient == nil) {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"success": false,
+			"error":   "Quick scans are not available",
+		})
+	}
+
+	req := new(QuickScanRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+	pageURL, err := urlnorm.Normalize(req.URL)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid URL: " + err.Error(),
+		})
+	}
+
+	if !h.allowQuickScan(c, "ip:"+c.IP(), h.Config.QuickScanRateLimit, "Too many scans from your network") {
+		return nil
+	}
+	if err := h.Captcha.Verify(c.Context(), req.CaptchaToken, c.IP()); err != nil {
+		if !errors.Is(err, captcha.ErrInvalidToken) {
+			log.Printf("Failed to verify captcha: %v", err)
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"success": false,
+			"error":   "CAPTCHA verification failed",
+		})
+	}
+	if err := checkPublicURL(c.Context(), pageURL); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	host, _ := urlnorm.Hostname(pageURL)
+	if !h.allowQuickScan(c, "host:"+host, h.Config.QuickScanHostRateLimit, "Too many scans of this site") {
+		return nil
+	}
+
+	// Scans hold a slot of the instance so bursts cannot pile up fetches
+	select {
+	case h.quickScanSlots <- struct{}{}:
+		defer func() { <-h.quickScanSlots }()
+	default:
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(quickScanBusyRetry.Seconds())))
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"success": false,
+			"error":   "All scan slots are busy, try again shortly",
+		})
+	}
+
+	scan, err := h.runQuickScan(pageURL)
+	if err != nil {
+		status := fiber.StatusUnprocessableEntity
+		if errors.Is(err, parser.ErrPrivateAddress) {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to scan page: " + err.Error(),
+		})
+	}
+
+	token := make([]byte, 24)
+	if _, err := rand.Read(token); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to generate claim token",
+		})
+	}
+	scan.Token = hex.EncodeToString(token)
+	scan.ExpiresAt = scan.CreatedAt.Add(h.Config.QuickScanTTL)
+	if err := h.Cache.Set(keyPrefixQuickScan+scan.Token, scan, h.Config.QuickScanTTL); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to store scan: " + err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data":    scan.QuickScanResult,
+	})
+}
+
+// GetQuickScan returns an unclaimed quick scan
+// @Summary Get a quick scan
+// @Description Returns the result of an anonymous quick scan until it is claimed or expires. When the server has a signing key, the result comes with a "signature": a JWS embedding the result, which anyone the scan is shared with can check at /signatures/verify
+// @Tags scan
+// @Produce json
+// @Param token path string true "Claim token"
+// @Success 200 {object} map[string]interface{} "Scan result"
+// @Failure 404 {object} map[string]interface{} "Scan not found, expired or claimed"
+// @Router /scan/{token} [get]
+func (h *AnalysisHandler) GetQuickScan(c *fiber.Ctx) error {
+	var scan quickScan
+	if err := h.Cache.Get(keyPrefixQuickScan+c.Params("token"), &scan); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Scan not found, expired or already claimed",
+		})
+	}
+
+	response := fiber.Map{
+		"success": true,
+		"data":    scan.QuickScanResult,
+	}
+	if jws := signData(h.Signer, scan.QuickScanResult, "quick_scan"); jws != "" {
+		response["signature"] = jws
+	}
+	return c.JSON(response)
+}
+
+// ClaimQuickScan saves a quick scan as a completed analysis of the user
+// @Summary Claim a quick scan
+// @Description Saves an anonymous quick scan as a completed analysis of the signed-in user, with all its issues and recommendations. A scan can be claimed once; the analysis counts against the quota of the plan
+// @Tags scan
+// @Produce json
+// @Param token path string true "Claim token"
+// @Success 201 {object} map[string]interface{} "Analysis created from the scan"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 402 {object} map[string]interface{} "Monthly analysis quota of the plan exhausted"
+// @Failure 404 {object} map[string]interface{} "Scan not found, expired or claimed"
+// @Failure 409 {object} map[string]interface{} "Scan is being claimed"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /scan/{token}/claim [post]
+func (h *AnalysisHandler) ClaimQuickScan(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	token := c.Params("token")
+
+	if !enforceQuota(c, h.Quota, billing.ResourceAnalyses) {
+		return nil
+	}
+
+	// Concurrent claims of the same token must not create two analyses
+	if h.RedisClient != nil {
+		lockKey := keyPrefixQuickScanClaim + token
+		acquired, _, err := h.RedisClient.AcquireLock(lockKey, userID.String(), time.Minute)
+		if err == nil && !acquired {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"success": false,
+				"error":   "Scan is being claimed",
+			})
+		}
+		if err == nil {
+			defer h.RedisClient.ReleaseLock(lockKey, userID.String())
+		}
+	} else {
+		if _, claiming := h.quickScanClaims.LoadOrStore(token, userID); claiming {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"success": false,
+				"error":   "Scan is being claimed",
+			})
+		}
+		defer h.quickScanClaims.Delete(token)
+	}
+
+	var scan quickScan
+	if err := h.Cache.Get(keyPrefixQuickScan+token, &scan); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Scan not found, expired or already claimed",
+		})
+	}
+
+	encoded, err := json.Marshal(map[string]interface{}{
+		"quick_scan":      true,
+		"overall_score":   scan.OverallScore,
+		"scoring_version": analyzer.ScoringVersion(),
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to encode metadata: " + err.Error(),
+		})
+	}
+	analysis := models.Analysis{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Status:      "completed",
+		Priority:    "normal",
+		StartedAt:   scan.CreatedAt,
+		CompletedAt: time.Now(),
+		Metadata:    datatypes.JSON(encoded),
+	}
+
+	results := &analyzer.SandboxResult{
+		Results:         make(map[analyzer.AnalyzerType]map[string]interface{}, len(scan.Scores)),
+		Issues:          scan.Issues,
+		Recommendations: scan.Recommendations,
+	}
+	for category, score := range scan.Scores {
+		results.Results[analyzer.AnalyzerType(category)] = map[string]interface{}{"score": score}
+	}
+	if err := h.saveCompletedAnalysis(&analysis, scan.URL, results); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	if err := h.Cache.Delete(keyPrefixQuickScan + token); err != nil {
+		log.Printf("Failed to delete claimed quick scan: %v", err)
+	}
+
+	h.recordEvent(analysis.ID, models.AnalysisEventCompleted, "", "Analysis created from a quick scan", 0, map[string]interface{}{
+		"overall_score": scan.OverallScore,
+		"quick_scan":    true,
+	})
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"analysis_id":   analysis.ID,
+			"status":        analysis.Status,
+			"url":           scan.URL,
+			"overall_score": scan.OverallScore,
+		},
+	})
+}
+
+// runQuickScan fetches a single page through the public-only transport and
+// runs the quick scan analyzers on it
+func (h *AnalysisHandler) runQuickScan(pageURL string) (*quickScan, error) {
+	timeout := h.Config.QuickScanTimeout
+	if timeout <= 0 {
+		timeout = 20 * time.Second
+	}
+
+	opts := parser.DefaultParseOptions()
+	opts.Timeout = timeout
+	opts.MaxRetries = 0
+	opts.UserAgent = parser.DesktopDevice.UserAgent
+	opts.Transport = parser.PublicTransport()
+	opts.SkipResourceChecks = true
+
+	start := time.Now()
+	data, err := parser.ParseWebsite(pageURL, opts)
+	if data != nil && data.Challenge != nil {
+		return nil, errQuickScanBlocked
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	manager := analyzer.NewAnalyzerManager()
+	manager.RegisterAnalyzers(quickScanAnalyzers)
+	results, err := manager.RunAllAnalyzers(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	scan := &quickScan{
+		QuickScanResult: QuickScanResult{
+			URL:         pageURL,
+			Title:       data.Title,
+			Description: data.Description,
+			Scores:      make(map[string]float64, len(results)),
+			IssueCounts: map[string]int{"high": 0, "medium": 0, "low": 0},
+			CreatedAt:   start,
+		},
+		Issues:          manager.GetAllIssues(),
+		Recommendations: manager.GetAllRecommendations(),
+	}
+
+	var totalScore float64
+	for analyzerType, result := range results {
+		if score, ok := result["score"].(float64); ok {
+			scan.Scores[string(analyzerType)] = score
+			totalScore += score
+		}
+	}
+	if len(scan.Scores) > 0 {
+		scan.OverallScore = totalScore / float64(len(scan.Scores))
+	}
+
+	var issues []QuickScanIssue
+	for analyzerType, list := range scan.Issues {
+		for _, issue := range list {
+			severity, _ := issue["severity"].(string)
+			description, _ := issue["description"].(string)
+			scan.IssueCounts[severity]++
+			issues = append(issues, QuickScanIssue{Category: string(analyzerType), Severity: severity, Title: description})
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if getSeverityValue(issues[i].Severity) != getSeverityValue(issues[j].Severity) {
+			return getSeverityValue(issues[i].Severity) > getSeverityValue(issues[j].Severity)
+		}
+		if issues[i].Category != issues[j].Category {
+			return issues[i].Category < issues[j].Category
+		}
+		return issues[i].Title < issues[j].Title
+	})
+	if len(issues) > quickScanTopIssues {
+		issues = issues[:quickScanTopIssues]
+	}
+	scan.TopIssues = issues
+
+	return scan, nil
+}
+
+// allowQuickScan counts a quick scan against an hourly limit of a client IP
+// or scanned host and responds with 429 when it is exceeded. Outside
+// production scans are allowed when Redis is unavailable; in production they
+// are rejected with 503 since they could not be counted.
+func (h *AnalysisHandler) allowQuickScan(c *fiber.Ctx, subject string, limit int, message string) bool {
+	if limit <= 0 || h.RedisClient == nil {
+		return true
+	}
+
+	ctx := context.Background()
+	window := time.Now().Truncate(time.Hour)
+	key := fmt.Sprintf("%s%s:%d", keyPrefixQuickScanRate, subject, window.Unix())
+	pipe := h.RedisClient.Client.TxPipeline()
+	count := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, time.Hour)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to count quick scans of %s: %v", subject, err)
+		if h.Config.Environment != "production" {
+			return true
+		}
+		c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"success": false,
+			"error":   "Quick scans are temporarily unavailable",
+		})
+		return false
+	}
+	if count.Val() <= int64(limit) {
+		return true
+	}
+
+	retryAfter := int(time.Until(window.Add(time.Hour)).Seconds()) + 1
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+	c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"success": false,
+		"error":   fmt.Sprintf("%s, at most %d scans per hour are allowed", message, limit),
+	})
+	return false
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// saveRecording stores the HAR and screencast of a recorded page load
+func (a *AnalysisHandler) saveRecording(analysisID uuid.UUID, recording *parser.SessionRecording) {
+	har, err := recording.HARJSON()
+	if err != nil {
+		log.Printf("Failed to encode HAR for analysis %s: %v", analysisID, err)
+	} else if err := a.SnapshotRepo.Save(analysisID, models.SnapshotKindHAR, string(har)); err != nil {
+		log.Printf("Failed to save HAR for analysis %s: %v", analysisID, err)
+	}
+
+	if len(recording.Frames) == 0 {
+		return
+	}
+	screencast, err := recording.ScreencastZip()
+	if err != nil {
+		log.Printf("Failed to pack screencast for analysis %s: %v", analysisID, err)
+		return
+	}
+	if err := a.SnapshotRepo.Save(analysisID, models.SnapshotKindScreencast, string(screencast)); err != nil {
+		log.Printf("Failed to save screencast for analysis %s: %v", analysisID, err)
+	}
+}
+
+// GetAnalysisHAR downloads the HAR of a recorded page load
+// @Summary Download HAR of the page load
+// @Description Returns the HTTP Archive of every request the headless browser made while loading the page. Only available for analyses created with overrides.record set. Cookie and Authorization header values are redacted
+// @Tags analysis
+// @Produce json
+// @Param id path string true "Analysis ID"
+// @Success 200 {file} binary "HAR file"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Analysis was not recorded"
+// @Security BearerAuth
+// @Router /analysis/{id}/recording/har [get]
+func (h *AnalysisHandler) GetAnalysisHAR(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="analysis-%s.har"`, c.Params("id")))
+	return h.serveSnapshot(c, models.SnapshotKindHAR, fiber.MIMEApplicationJSON)
+}
+
+// GetAnalysisScreencast downloads the screencast of a recorded page load
+// @Summary Download screencast of the page load
+// @Description Returns a ZIP archive with the JPEG frames of the viewport while the page loaded, a manifest.json of frame offsets and an index.html that plays them back. Only available for analyses created with overrides.record set
+// @Tags analysis
+// @Produce application/zip
+// @Param id path string true "Analysis ID"
+// @Success 200 {file} binary "ZIP archive"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Analysis was not recorded"
+// @Security BearerAuth
+// @Router /analysis/{id}/recording/screencast [get]
+func (h *AnalysisHandler) GetAnalysisScreencast(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="analysis-%s-screencast.zip"`, c.Params("id")))
+	return h.serveSnapshot(c, models.SnapshotKindScreencast, "application/zip")
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// Page sources accepted by RerunAnalysisCategory
+const (
+	rerunSourceSnapshot = "snapshot"
+	rerunSourceLive     = "live"
+)
+
+// RerunAnalysisCategory runs a single analyzer of a finished analysis again
+// @Summary Re-run one category of an analysis
+// @Description Runs only the analyzer of the given category against the stored HTML snapshot of the page, or a fresh fetch with source=live or when no snapshot exists, and replaces that category's metrics, issues and recommendations. The replaced results are kept as a numbered version
+// @Tags analysis
+// @Produce json
+// @Param id path string true "Analysis ID"
+// @Param category query string true "Analyzer category, e.g. seo"
+// @Param source query string false "Page source: snapshot (default) or live"
+// @Success 200 {object} map[string]interface{} "New results of the category"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID, category or source"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Analysis not found"
+// @Failure 409 {object} map[string]interface{} "Analysis is still running"
+// @Failure 422 {object} map[string]interface{} "Analyzer failed"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /analysis/{id}/rerun [post]
+func (h *AnalysisHandler) RerunAnalysisCategory(c *fiber.Ctx) error {
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+
+	category := analyzer.AnalyzerType(c.Query("category"))
+	if !isAnalyzerType(category) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   fmt.Sprintf("Unknown category %q", category),
+		})
+	}
+
+	source := c.Query("source", rerunSourceSnapshot)
+	if source != rerunSourceSnapshot && source != rerunSourceLive {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "source must be snapshot or live",
+		})
+	}
+
+	var analysis models.Analysis
+	if err := h.AnalysisRepo.FindByID(analysisID, &analysis); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Analysis not found",
+		})
+	}
+	if analysis.Status == "pending" || analysis.Status == "running" {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"success": false,
+			"error":   "Analysis is still running",
+		})
+	}
+
+	var website models.Website
+	if err := h.WebsiteRepo.FindByID(analysis.WebsiteID, &website); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load website",
+		})
+	}
+
+	timeout := h.Config.AnalysisTimeout
+	if timeout <= 0 || timeout > maxAnalysisTimeout {
+		timeout = maxAnalysisTimeout
+	}
+	parseOpts := parser.DefaultParseOptions()
+	parseOpts.Timeout = timeout
+	if raw := metadataValue(analysis.Metadata, "preset"); raw != nil {
+		var key string
+		if json.Unmarshal(raw, &key) == nil && key != "" {
+			if preset, err := ResolvePreset(h.PresetRepo, analysis.UserID, key); err == nil {
+				preset.ApplyParseOptions(&parseOpts)
+			}
+		}
+	}
+
+	start := time.Now()
+	websiteData, source, err := h.rerunPageData(analysisID, website.URL, source, parseOpts)
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load page: " + err.Error(),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	manager := analyzer.NewAnalyzerManager()
+	manager.RegisterAnalyzers([]analyzer.AnalyzerType{category})
+	result, err := manager.RunAnalyzer(ctx, category, websiteData, nil)
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"success": false,
+			"error":   "Analyzer failed: " + err.Error(),
+		})
+	}
+
+	metric, err := scoreMetric(analysisID, category, result)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	metrics := []models.AnalysisMetric{metric}
+	issues := issueRecords(analysisID, category, manager.GetAnalyzerIssues(category), pageElementLocator(websiteData, website.URL), h.severityOverrides())
+
+	var recommendations []models.Recommendation
+	seen := make(map[string]struct{})
+	for _, rec := range manager.GetAnalyzerRecommendations(category) {
+		if _, ok := seen[rec]; ok {
+			continue
+		}
+		seen[rec] = struct{}{}
+		recommendations = append(recommendations, models.Recommendation{
+			AnalysisID:  analysisID,
+			Category:    string(category),
+			Priority:    recommendationPriority(category),
+			Title:       rec,
+			Description: rec,
+		})
+	}
+
+	version, err := h.AnalysisRepo.ReplaceCategoryResults(analysisID, string(category), metrics, issues, recommendations)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to save results: " + err.Error(),
+		})
+	}
+
+	switch category {
+	case analyzer.ChecklistType:
+		h.saveChecklist(analysisID, result)
+	case analyzer.InfrastructureType:
+		h.saveInfrastructure(analysisID, result)
+	case analyzer.DomainType:
+		h.saveSubdomains(analysisID, result)
+	case analyzer.SecurityType:
+		h.saveCSP(analysisID, result)
+	}
+	h.invalidateResultCache(analysisID, string(category))
+	h.recordEvent(analysisID, models.AnalysisEventCategoryRerun, string(category), fmt.Sprintf("Category %s analyzed again", category), time.Since(start), map[string]interface{}{
+		"source":           source,
+		"replaced_version": version,
+	})
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"category":         category,
+			"source":           source,
+			"score":            result["score"],
+			"replaced_version": version,
+			"metrics":          metrics,
+			"issues":           issues,
+			"recommendations":  recommendations,
+		},
+	})
+}
+
+// GetAnalysisResultVersions returns the results replaced by category re-runs
+// @Summary Get replaced results of an analysis
+// @Description Returns the metrics, issues and recommendations that re-runs of single categories replaced, newest version first
+// @Tags analysis
+// @Produce json
+// @Param id path string true "Analysis ID"
+// @Param category query string false "Only versions of this category"
+// @Success 200 {object} map[string]interface{} "Result versions"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /analysis/{id}/versions [get]
+func (h *AnalysisHandler) GetAnalysisResultVersions(c *fiber.Ctx) error {
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+
+	versions, err := h.AnalysisRepo.FindResultVersions(analysisID, c.Query("category"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load result versions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    versions,
+	})
+}
+
+// rerunPageData returns the page to analyze again: the stored HTML snapshot
+// when one exists and a live fetch was not asked for, otherwise a fresh fetch.
+// The source actually used is returned.
+func (h *AnalysisHandler) rerunPageData(analysisID uuid.UUID, pageURL, source string, opts parser.ParseOptions) (*parser.WebsiteData, string, error) {
+	if source == rerunSourceSnapshot && h.SnapshotRepo != nil {
+		if snapshot, err := h.SnapshotRepo.Find(analysisID, models.SnapshotKindHTML); err == nil {
+			html, err := repository.DecompressSnapshot(snapshot)
+			if err != nil {
+				return nil, source, err
+			}
+			data, err := parser.ParseSnapshot(pageURL, string(html), opts)
+			return data, rerunSourceSnapshot, err
+		}
+	}
+
+	data, err := parser.ParseWebsite(pageURL, opts)
+	return data, rerunSourceLive, err
+}
+
+// invalidateResultCache drops cached metrics and issues of an analysis
+func (h *AnalysisHandler) invalidateResultCache(analysisID uuid.UUID, category string) {
+	if h.Cache == nil {
+		return
+	}
+	for _, key := range []string{
+		"analysis_metrics:" + analysisID.String(),
+		fmt.Sprintf("analysis_metrics:%s:%s", analysisID.String(), category),
+		"analysis_issues:" + analysisID.String(),
+	} {
+		if err := h.Cache.Delete(key); err != nil {
+			log.Printf("Failed to invalidate %s: %v", key, err)
+		}
+	}
+}
+
+// isAnalyzerType reports whether t names a known analyzer
+func isAnalyzerType(t analyzer.AnalyzerType) bool {
+	for _, known := range analyzer.AllAnalyzerTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+)
+
+// reuseUnchangedResults completes a scheduled analysis with a copy of the
+// results of an earlier analysis of identical content. It returns false when
+// no reusable analysis exists and the analyzers have to run.
+func (a *AnalysisHandler) reuseUnchangedResults(analysis *models.Analysis, userID uuid.UUID, pageURL, contentHash, presetKey string, analysisStart time.Time) bool {
+	maxAge := a.Config.AnalysisReuseMaxAge
+	if maxAge <= 0 || contentHash == "" {
+		return false
+	}
+
+	previous, err := a.AnalysisRepo.FindReusable(analysis.WebsiteID, contentHash, presetKey, analyzer.ScoringVersion(), time.Now().Add(-maxAge), analysis.ID)
+	if err != nil {
+		return false
+	}
+
+	metrics, issues, err := a.AnalysisRepo.CloneResults(previous.ID, analysis.ID)
+	if err != nil {
+		log.Printf("Failed to reuse results of analysis %s: %v", previous.ID, err)
+		return false
+	}
+
+	// Carry over stored reports such as the checklist and infrastructure
+	// diagnostics that the new analysis does not have yet
+	var previousMetadata map[string]json.RawMessage
+	if err := json.Unmarshal(previous.Metadata, &previousMetadata); err == nil {
+		var current models.Analysis
+		if err := a.AnalysisRepo.FindByID(analysis.ID, &current); err == nil {
+			for key, value := range previousMetadata {
+				if key == "unchanged" || key == "cloned_from" || metadataValue(current.Metadata, key) != nil {
+					continue
+				}
+				if err := a.AnalysisRepo.SetMetadataKey(analysis.ID, key, value); err != nil {
+					log.Printf("Failed to copy %s to analysis %s: %v", key, analysis.ID, err)
+				}
+			}
+		}
+	}
+	if err := a.AnalysisRepo.SetMetadataKey(analysis.ID, "unchanged", true); err != nil {
+		log.Printf("Failed to mark analysis %s as unchanged: %v", analysis.ID, err)
+	}
+	if err := a.AnalysisRepo.SetMetadataKey(analysis.ID, "cloned_from", previous.ID); err != nil {
+		log.Printf("Failed to record source of analysis %s: %v", analysis.ID, err)
+	}
+
+	a.recordEventWithEffects([]models.AnalysisEvent{
+		newAnalysisEvent(analysis.ID, models.AnalysisEventReportGenerated, "", "Content unchanged, results copied from analysis "+previous.ID.String(), 0, nil),
+	}, streamDeliveries(a.activeStreams(userID), analysis.ID, pageURL))
+
+	if err := a.AnalysisRepo.UpdateStatus(analysis.ID, "completed"); err != nil {
+		a.updateAnalysisFailed(analysis.ID, "Error updating completion status: "+err.Error())
+		return true
+	}
+
+	overallScore := clonedOverallScore(metrics)
+	a.saveOverallScore(analysis.ID, overallScore)
+	go a.exportAnalytics(analysis.ID, userID, pageURL, overallScore, nil, metrics, issues)
+	a.recordCompletion(analysis.ID, userID, overallScore, time.Since(analysisStart), map[string]interface{}{
+		"overall_score": overallScore,
+		"unchanged":     true,
+		"cloned_from":   previous.ID,
+	})
+	go a.recordDeployImpact(analysis.ID)
+	return true
+}
+
+// clonedOverallScore averages the analyzer scores stored in score metrics
+func clonedOverallScore(metrics []models.AnalysisMetric) float64 {
+	var total float64
+	var count int
+	for _, metric := range metrics {
+		var value struct {
+			Score *float64 `json:"score"`
+		}
+		if err := json.Unmarshal(metric.Value, &value); err != nil || value.Score == nil {
+			continue
+		}
+		total += *value.Score
+		count++
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / float64(count)
+}
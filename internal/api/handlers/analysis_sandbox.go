@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+)
+
+// createSandboxAnalysis completes an analysis instantly with synthetic
+// results instead of fetching the page. Sandbox analyses do not count
+// against the quota and are marked with the "sandbox" metadata key.
+func (h *AnalysisHandler) createSandboxAnalysis(c *fiber.Ctx, userID uuid.UUID, pageURL string, preset *analyzer.Preset) error {
+	var types []analyzer.AnalyzerType
+	if preset != nil {
+		types = preset.Analyzers
+	}
+	synthetic := analyzer.SyntheticResults(pageURL, types)
+	if len(synthetic.Results) == 0 {
+		synthetic = analyzer.SyntheticResults(pageURL, nil)
+	}
+
+	var totalScore float64
+	for _, result := range synthetic.Results {
+		totalScore += result["score"].(float64)
+	}
+	overallScore := totalScore / float64(len(synthetic.Results))
+
+	metadata := map[string]interface{}{
+		"sandbox":         true,
+		"overall_score":   overallScore,
+		"scoring_version": analyzer.ScoringVersion(),
+	}
+	if preset != nil {
+		metadata["preset"] = preset.Key
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to encode metadata: " + err.Error(),
+		})
+	}
+
+	now := time.Now()
+	analysis := models.Analysis{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Status:      "completed",
+		Priority:    "normal",
+		StartedAt:   now,
+		CompletedAt: now,
+		Metadata:    datatypes.JSON(encoded),
+	}
+
+	if err := h.saveCompletedAnalysis(&analysis, pageURL, synthetic); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	h.recordEvent(analysis.ID, models.AnalysisEventCompleted, "", "Sandbox analysis completed with synthetic results", 0, map[string]interface{}{
+		"overall_score": overallScore,
+		"sandbox":       true,
+	})
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"analysis_id":   analysis.ID,
+			"status":        analysis.Status,
+			"priority":      analysis.Priority,
+			"overall_score": overallScore,
+			"sandbox":       true,
+		},
+	})
+}
+
+// saveCompletedAnalysis stores a finished sandbox analysis of a page with its
+// scores, issues and recommendations in one transaction. Each sandbox analysis
+// gets a sandbox website of its own, so its synthetic results never become
+// the latest results of the real site.
+func (h *AnalysisHandler) saveCompletedAnalysis(analysis *models.Analysis, pageURL string, results *analyzer.SandboxResult) error {
+	return h.AnalysisRepo.Transaction(func(tx *gorm.DB) error {
+		website := models.Website{URL: pageURL, Sandbox: true}
+		if err := tx.Create(&website).Error; err != nil {
+			return fmt.Errorf("failed to create website record: %w", err)
+		}
+		analysis.WebsiteID = website.ID
+		if err := tx.Create(analysis).Error; err != nil {
+			return fmt.Errorf("failed to create analysis record: %w", err)
+		}
+
+		for analyzerType, result := range results.Results {
+			metric, err := scoreMetric(analysis.ID, analyzerType, result)
+			if err != nil {
+				return err
+			}
+			if err := tx.Create(&metric).Error; err != nil {
+				return fmt.Errorf("error saving metric: %w", err)
+			}
+			for _, issue := range issueRecords(analysis.ID, analyzerType, results.Issues[analyzerType], nil, nil) {
+				if err := tx.Create(&issue).Error; err != nil {
+					return fmt.Errorf("error saving issue: %w", err)
+				}
+			}
+			for _, rec := range results.Recommendations[analyzerType] {
+				recommendation := models.Recommendation{
+					AnalysisID:  analysis.ID,
+					Category:    string(analyzerType),
+					Priority:    recommendationPriority(analyzerType),
+					Title:       rec,
+					Description: rec,
+				}
+				if err := tx.Create(&recommendation).Error; err != nil {
+					return fmt.Errorf("error saving recommendation: %w", err)
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// sitemapFetchTimeout limits each request of the sitemap crawl
+const sitemapFetchTimeout = 10 * time.Second
+
+// proposeSitemap crawls the site of an analysis when it has no sitemap and
+// stores a proposed sitemap.xml of the indexable pages found. A summary of
+// the crawl goes to the "generated_sitemap" metadata key.
+func (a *AnalysisHandler) proposeSitemap(analysisID uuid.UUID, data *parser.WebsiteData) {
+	maxPages := a.Config.SitemapCrawlMaxPages
+	if maxPages <= 0 || a.SnapshotRepo == nil || data == nil {
+		return
+	}
+
+	timeout := a.Config.AnalysisTimeout
+	if timeout <= 0 || timeout > maxAnalysisTimeout {
+		timeout = maxAnalysisTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	pageURL := data.FinalURL
+	if pageURL == "" {
+		pageURL = data.URL
+	}
+	client := &http.Client{Timeout: sitemapFetchTimeout}
+	if _, ok := parser.FindSitemap(ctx, client, pageURL); ok {
+		return
+	}
+
+	start := time.Now()
+	crawl := parser.CrawlForSitemap(ctx, client, data, maxPages)
+	if len(crawl.URLs) == 0 {
+		return
+	}
+
+	sitemap, err := crawl.XML()
+	if err != nil {
+		log.Printf("Failed to render sitemap for analysis %s: %v", analysisID, err)
+		return
+	}
+	if err := a.SnapshotRepo.Save(analysisID, models.SnapshotKindSitemap, string(sitemap)); err != nil {
+		log.Printf("Failed to save generated sitemap for analysis %s: %v", analysisID, err)
+		return
+	}
+
+	summary := map[string]interface{}{
+		"urls":          len(crawl.URLs),
+		"crawled":       crawl.Crawled,
+		"noindex":       crawl.Noindex,
+		"canonicalized": crawl.Canonicalized,
+		"failed":        crawl.Failed,
+		"truncated":     crawl.Truncated,
+	}
+	if err := a.AnalysisRepo.SetMetadataKey(analysisID, "generated_sitemap", summary); err != nil {
+		log.Printf("Failed to save sitemap summary for analysis %s: %v", analysisID, err)
+	}
+	a.recordEvent(analysisID, models.AnalysisEventReportGenerated, "", fmt.Sprintf("No sitemap found, proposed sitemap with %d URLs generated", len(crawl.URLs)), time.Since(start), nil)
+}
+
+// GetGeneratedSitemap returns the sitemap.xml proposed for a site without one
+// @Summary Download the proposed sitemap.xml
+// @Description When the analyzed site has neither a sitemap in robots.txt nor /sitemap.xml, its internal links are crawled after the analysis and a sitemap.xml of the indexable pages is proposed. Pages with a noindex directive are left out and pages declaring another canonical URL are replaced by it. The crawl summary is in the "generated_sitemap" metadata key of the analysis
+// @Tags analysis
+// @Produce xml
+// @Param id path string true "Analysis ID"
+// @Success 200 {string} string "Proposed sitemap.xml"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "No sitemap was generated for this analysis"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /analysis/{id}/generated-sitemap.xml [get]
+func (h *AnalysisHandler) GetGeneratedSitemap(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="sitemap.xml"`)
+	return h.serveSnapshot(c, models.SnapshotKindSitemap, fiber.MIMEApplicationXMLCharsetUTF8)
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// saveSnapshots stores the fetched HTML and, when the headless browser was
+// used, the rendered DOM and the screenshots of an analysis. The element
+// boxes for screenshot overlays go to the "screenshot_overlays" metadata key.
+func (a *AnalysisHandler) saveSnapshots(analysisID uuid.UUID, data *parser.WebsiteData) {
+	if a.SnapshotRepo == nil || data == nil {
+		return
+	}
+
+	rawHTML := data.RawHTML
+	if rawHTML == "" && data.RenderedDOM == "" {
+		// Older parse paths only keep the processed HTML
+		rawHTML = data.HTML
+	}
+
+	if rawHTML != "" {
+		if err := a.SnapshotRepo.Save(analysisID, models.SnapshotKindHTML, rawHTML); err != nil {
+			log.Printf("Failed to save HTML snapshot for analysis %s: %v", analysisID, err)
+		}
+	}
+	if data.RenderedDOM != "" {
+		if err := a.SnapshotRepo.Save(analysisID, models.SnapshotKindDOM, data.RenderedDOM); err != nil {
+			log.Printf("Failed to save DOM snapshot for analysis %s: %v", analysisID, err)
+		}
+	}
+	for device, screenshot := range data.Screenshots {
+		if len(screenshot) == 0 {
+			continue
+		}
+		if err := a.SnapshotRepo.Save(analysisID, models.SnapshotKindScreenshotPrefix+device, string(screenshot)); err != nil {
+			log.Printf("Failed to save %s screenshot for analysis %s: %v", device, analysisID, err)
+		}
+	}
+	if len(data.ScreenshotOverlays) > 0 {
+		if err := a.AnalysisRepo.SetMetadataKey(analysisID, "screenshot_overlays", data.ScreenshotOverlays); err != nil {
+			log.Printf("Failed to save screenshot overlays for analysis %s: %v", analysisID, err)
+		}
+	}
+	if data.Recording != nil {
+		a.saveRecording(analysisID, data.Recording)
+	}
+}
+
+// GetAnalysisHTML returns the raw HTML fetched during an analysis
+// @Summary Get raw HTML snapshot
+// @Description Returns the HTML document exactly as fetched during the analysis. The response is sent gzip-encoded when the client accepts it
+// @Tags analysis
+// @Produce html
+// @Param id path string true "Analysis ID"
+// @Success 200 {string} string "Raw HTML"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Snapshot not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /analysis/{id}/html [get]
+func (h *AnalysisHandler) GetAnalysisHTML(c *fiber.Ctx) error {
+	return h.serveSnapshot(c, models.SnapshotKindHTML, fiber.MIMETextHTMLCharsetUTF8)
+}
+
+// GetAnalysisDOM returns the rendered DOM captured during an analysis
+// @Summary Get rendered DOM snapshot
+// @Description Returns the DOM serialized after JavaScript execution. Only available when the analysis used the headless browser. The response is sent gzip-encoded when the client accepts it
+// @Tags analysis
+// @Produce html
+// @Param id path string true "Analysis ID"
+// @Success 200 {string} string "Rendered DOM"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Snapshot not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /analysis/{id}/dom [get]
+func (h *AnalysisHandler) GetAnalysisDOM(c *fiber.Ctx) error {
+	return h.serveSnapshot(c, models.SnapshotKindDOM, fiber.MIMETextHTMLCharsetUTF8)
+}
+
+// GetAnalysisScreenshots lists the screenshots of an analysis with overlay boxes
+// @Summary List screenshots with issue overlays
+// @Description Returns the devices for which screenshots were captured, the image URL of each and the bounding boxes of elements with missing alt text, insufficient contrast or small tap targets. Boxes are in CSS pixels of the full page; multiply by device_scale_factor to get screenshot pixels
+// @Tags analysis
+// @Produce json
+// @Param id path string true "Analysis ID"
+// @Success 200 {object} map[string]interface{} "Screenshots with overlays"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Analysis not found or no screenshots"
+// @Security BearerAuth
+// @Router /analysis/{id}/screenshots [get]
+func (h *AnalysisHandler) GetAnalysisScreenshots(c *fiber.Ctx) error {
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+
+	var analysis models.Analysis
+	if err := h.AnalysisRepo.FindByID(analysisID, &analysis); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Analysis not found",
+		})
+	}
+
+	var overlays map[string]parser.ScreenshotOverlay
+	if raw := metadataValue(analysis.Metadata, "screenshot_overlays"); raw != nil {
+		if err := json.Unmarshal(raw, &overlays); err != nil {
+			log.Printf("Failed to decode screenshot overlays of analysis %s: %v", analysisID, err)
+		}
+	}
+	if len(overlays) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "No screenshots for this analysis",
+		})
+	}
+
+	devices := make([]string, 0, len(overlays))
+	for device := range overlays {
+		devices = append(devices, device)
+	}
+	sort.Strings(devices)
+
+	screenshots := make([]fiber.Map, 0, len(devices))
+	for _, device := range devices {
+		screenshots = append(screenshots, fiber.Map{
+			"device":    device,
+			"image_url": fmt.Sprintf("/api/analysis/%s/screenshots/%s", analysisID, url.PathEscape(device)),
+			"overlay":   overlays[device],
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    screenshots,
+	})
+}
+
+// GetAnalysisScreenshot returns the screenshot of one device
+// @Summary Get screenshot
+// @Description Returns the full-page JPEG screenshot captured for a device. The response is sent gzip-encoded when the client accepts it
+// @Tags analysis
+// @Produce jpeg
+// @Param id path string true "Analysis ID"
+// @Param device path string true "Device name, e.g. desktop or mobile"
+// @Success 200 {file} binary "Screenshot"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Screenshot not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /analysis/{id}/screenshots/{device} [get]
+func (h *AnalysisHandler) GetAnalysisScreenshot(c *fiber.Ctx) error {
+	return h.serveSnapshot(c, models.SnapshotKindScreenshotPrefix+c.Params("device"), "image/jpeg")
+}
+
+// serveSnapshot writes a stored snapshot, passing the compressed bytes
+// through when the client accepts gzip
+func (h *AnalysisHandler) serveSnapshot(c *fiber.Ctx, kind, contentType string) error {
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+
+	snapshot, err := h.SnapshotRepo.Find(analysisID, kind)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "No " + kind + " snapshot stored for this analysis",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set("X-Content-SHA256", snapshot.SHA256)
+	c.Set("X-Content-Length", strconv.FormatInt(snapshot.Size, 10))
+
+	if strings.Contains(c.Get(fiber.HeaderAcceptEncoding), "gzip") {
+		c.Set(fiber.HeaderContentEncoding, "gzip")
+		return c.Send(snapshot.Content)
+	}
+
+	content, err := repository.DecompressSnapshot(snapshot)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to decompress snapshot",
+		})
+	}
+	return c.Send(content)
+}
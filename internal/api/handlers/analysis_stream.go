@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/stream"
+)
+
+// streamDeliveryTimeout bounds the delivery of one analysis to one stream,
+// including retries
+const streamDeliveryTimeout = 2 * time.Minute
+
+// activeStreams returns the event streams the findings of a user's analyses
+// are forwarded to
+func (a *AnalysisHandler) activeStreams(userID uuid.UUID) []models.EventStream {
+	if a.EventStreamRepo == nil || userID == uuid.Nil {
+		return nil
+	}
+	streams, err := a.EventStreamRepo.FindActiveByUserID(userID)
+	if err != nil {
+		log.Printf("Failed to load event streams for user %s: %v", userID, err)
+		return nil
+	}
+	return streams
+}
+
+// deliverToStream sends events to one stream and records the outcome
+func deliverToStream(ctx context.Context, repo repository.EventStreamRepository, s *models.EventStream, events []stream.Event) error {
+	sink, err := stream.NewSink(stream.SinkConfig{Type: s.Sink, URL: s.URL, Topic: s.Topic, Secret: s.Secret})
+	if err == nil {
+		deliverCtx, cancel := context.WithTimeout(ctx, streamDeliveryTimeout)
+		err = stream.Deliver(deliverCtx, sink, events)
+		cancel()
+	}
+	if err != nil {
+		log.Printf("Failed to deliver %d events to stream %s: %v", len(events), s.ID, err)
+	}
+	if recordErr := repo.RecordDelivery(s.ID, err); recordErr != nil {
+		log.Printf("Failed to record delivery for stream %s: %v", s.ID, recordErr)
+	}
+	return err
+}
+
+// streamEvents converts persisted metric and issue records to stream events
+func streamEvents(analysisID uuid.UUID, pageURL string, metrics []models.AnalysisMetric, issues []models.Issue) []stream.Event {
+	events := make([]stream.Event, 0, len(metrics)+len(issues))
+
+	for _, metric := range metrics {
+		data, _ := json.Marshal(map[string]interface{}{
+			"name":  metric.Name,
+			"value": json.RawMessage(metric.Value),
+		})
+		events = append(events, stream.Event{
+			ID:         metric.ID,
+			Type:       stream.EventMetric,
+			AnalysisID: analysisID,
+			URL:        pageURL,
+			Category:   metric.Category,
+			Timestamp:  metric.CreatedAt,
+			Data:       data,
+		})
+	}
+
+	for _, issue := range issues {
+		data, _ := json.Marshal(map[string]interface{}{
+			"severity":    issue.Severity,
+			"title":       issue.Title,
+			"description": issue.Description,
+			"location":    issue.Location,
+		})
+		events = append(events, stream.Event{
+			ID:         issue.ID,
+			Type:       stream.EventIssue,
+			AnalysisID: analysisID,
+			URL:        pageURL,
+			Category:   issue.Category,
+			Timestamp:  issue.CreatedAt,
+			Data:       data,
+		})
+	}
+
+	return events
+}
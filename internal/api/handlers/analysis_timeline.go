@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+)
+
+// recordEvent persists a lifecycle event of an analysis. Failures are only
+// logged so that the timeline never interrupts the analysis itself.
+func (a *AnalysisHandler) recordEvent(analysisID uuid.UUID, eventType, analyzerName, message string, duration time.Duration, details map[string]interface{}) {
+	if a.EventRepo == nil {
+		return
+	}
+
+	event := newAnalysisEvent(analysisID, eventType, analyzerName, message, duration, details)
+	if err := a.EventRepo.Create(&event); err != nil {
+		log.Printf("Failed to record %s event for analysis %s: %v", eventType, analysisID, err)
+	}
+}
+
+// newAnalysisEvent builds a lifecycle event of an analysis. The ID is set
+// so side effects queued with the event can refer to it.
+func newAnalysisEvent(analysisID uuid.UUID, eventType, analyzerName, message string, duration time.Duration, details map[string]interface{}) models.AnalysisEvent {
+	event := models.AnalysisEvent{
+		ID:         uuid.New(),
+		AnalysisID: analysisID,
+		EventType:  eventType,
+		Analyzer:   analyzerName,
+		Message:    message,
+		DurationMs: duration.Milliseconds(),
+	}
+
+	if len(details) > 0 {
+		data, err := json.Marshal(details)
+		if err == nil {
+			event.Details = datatypes.JSON(data)
+		}
+	}
+	return event
+}
+
+// recordEventWithEffects persists lifecycle events of an analysis together
+// with the side effects they cause, so the side effects are delivered even
+// if the server stops right after. Failures are only logged.
+func (a *AnalysisHandler) recordEventWithEffects(events []models.AnalysisEvent, effects []*models.OutboxMessage) {
+	err := a.AnalysisRepo.Transaction(func(tx *gorm.DB) error {
+		if a.EventRepo != nil {
+			for i := range events {
+				if err := tx.Create(&events[i]).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return repository.EnqueueOutbox(tx, effects...)
+	})
+	if err != nil {
+		log.Printf("Failed to record events and %d side effects: %v", len(effects), err)
+		return
+	}
+	if len(effects) > 0 {
+		a.Outbox.Notify()
+	}
+}
+
+// GetAnalysisTimeline returns the recorded lifecycle events of an analysis
+// @Summary Get analysis timeline
+// @Description Returns the lifecycle events of an analysis in chronological order with the time spent in each analyzer. While the analysis is queued, queue tells why it has not started
+// @Tags analysis
+// @Accept json
+// @Produce json
+// @Param id path string true "Analysis ID"
+// @Param locale query string false "Locale of the formatted durations, e.g. de-CH"
+// @Success 200 {object} map[string]interface{} "Analysis timeline"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Analysis not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /analysis/{id}/timeline [get]
+func (h *AnalysisHandler) GetAnalysisTimeline(c *fiber.Ctx) error {
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+
+	var analysis models.Analysis
+	if err := h.AnalysisRepo.FindByID(analysisID, &analysis); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Analysis not found",
+		})
+	}
+
+	events, err := h.EventRepo.FindByAnalysisID(analysisID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch timeline",
+		})
+	}
+
+	format := responseFormat(c, h.UserRepo)
+	timeline := make([]fiber.Map, 0, len(events))
+	analyzerDurations := make(map[string]int64)
+	var totalMs int64
+
+	for _, event := range events {
+		offsetMs := event.CreatedAt.Sub(events[0].CreatedAt).Milliseconds()
+		totalMs = offsetMs
+
+		entry := fiber.Map{
+			"id":          event.ID,
+			"event_type":  event.EventType,
+			"analyzer":    event.Analyzer,
+			"message":     event.Message,
+			"duration_ms": event.DurationMs,
+			"duration":    format.Duration(event.DurationMs),
+			"offset_ms":   offsetMs,
+			"created_at":  event.CreatedAt,
+		}
+
+		var details map[string]interface{}
+		if len(event.Details) > 0 && json.Unmarshal(event.Details, &details) == nil {
+			entry["details"] = details
+		}
+
+		if event.Analyzer != "" && (event.EventType == models.AnalysisEventAnalyzerCompleted ||
+			event.EventType == models.AnalysisEventAnalyzerFailed) {
+			analyzerDurations[event.Analyzer] = event.DurationMs
+		}
+
+		timeline = append(timeline, entry)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"analysis_id":        analysisID,
+			"status":             analysis.Status,
+			"events":             timeline,
+			"total_duration_ms":  totalMs,
+			"total_duration":     format.Duration(totalMs),
+			"analyzer_durations": analyzerDurations,
+			"queue":              h.queueStatus(analysisID),
+		},
+		"formatting": format,
+	})
+}
+
+// recordAnalyzerEvent persists the completion or failure of a single analyzer
+func (a *AnalysisHandler) recordAnalyzerEvent(analysisID uuid.UUID, update analyzer.ProgressUpdate) {
+	if update.AnalyzerType == "manager" || update.Progress < 100 {
+		return
+	}
+
+	var duration time.Duration
+	if ms, ok := update.Details["duration_ms"].(int64); ok {
+		duration = time.Duration(ms) * time.Millisecond
+	}
+
+	eventType := models.AnalysisEventAnalyzerCompleted
+	var details map[string]interface{}
+	if errMsg, ok := update.Details["error"].(string); ok {
+		eventType = models.AnalysisEventAnalyzerFailed
+		details = map[string]interface{}{"error": errMsg}
+	}
+
+	a.recordEvent(analysisID, eventType, update.AnalyzerType, update.Message, duration, details)
+}
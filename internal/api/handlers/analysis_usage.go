@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/config"
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/llm/tokens"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// defaultProviderModels maps provider names to the model used for pricing
+// when a response only reports the provider
+var defaultProviderModels = map[string]string{
+	"gemini": "gemini-1.5-flash",
+	"openai": "gpt-3.5-turbo",
+}
+
+// crawlCost prices the crawl-side resources of an analysis
+func crawlCost(cfg *config.Config, usage *models.AnalysisUsage) float64 {
+	gb := float64(usage.BytesFetched) / (1 << 30)
+	headlessSeconds := float64(usage.HeadlessMs) / 1000
+
+	return gb*cfg.UsagePricePerGB +
+		headlessSeconds*cfg.UsagePricePerHeadlessSecond +
+		float64(usage.LighthouseCalls)*cfg.UsagePricePerLighthouseCall
+}
+
+// llmCost prices LLM tokens using the model table of the token tracker
+func llmCost(modelOrProvider string, promptTokens, completionTokens int) float64 {
+	info, ok := tokens.Models[modelOrProvider]
+	if !ok {
+		info, ok = tokens.Models[defaultProviderModels[modelOrProvider]]
+	}
+	if !ok {
+		return 0
+	}
+
+	return float64(promptTokens)/info.TokensPerPromptDollar +
+		float64(completionTokens)/info.TokensPerOutputDollar
+}
+
+// meterAnalysis stores the resources consumed while crawling and analyzing a
+// website. Failures are only logged so that metering never fails an analysis.
+func (a *AnalysisHandler) meterAnalysis(analysisID, userID uuid.UUID, data *parser.WebsiteData, results map[analyzer.AnalyzerType]map[string]interface{}) {
+	if a.UsageRepo == nil || data == nil {
+		return
+	}
+
+	usage := &models.AnalysisUsage{
+		AnalysisID:   analysisID,
+		UserID:       userID,
+		BytesFetched: int64(len(data.HTML)),
+		HeadlessMs:   data.PhaseTimings[parser.PhaseJSRender].Milliseconds(),
+	}
+	for _, screenshot := range data.Screenshots {
+		usage.BytesFetched += int64(len(screenshot))
+	}
+	if _, ok := results[analyzer.LighthouseType]; ok {
+		usage.LighthouseCalls = 1
+	}
+	usage.Cost = crawlCost(a.Config, usage)
+
+	if err := a.UsageRepo.Upsert(usage); err != nil {
+		log.Printf("Failed to meter analysis %s: %v", analysisID, err)
+	}
+}
+
+// meterLLMUsage adds the estimated tokens of a content generation to the
+// usage of its analysis
+func meterLLMUsage(repo repository.UsageRepository, analysisID, userID uuid.UUID, provider, prompt, completion string) {
+	if repo == nil {
+		return
+	}
+
+	promptTokens, completionTokens := tokens.CalculateContextSize(prompt, completion)
+	cost := llmCost(provider, promptTokens, completionTokens)
+
+	if err := repo.AddLLMUsage(analysisID, userID, int64(promptTokens), int64(completionTokens), cost); err != nil {
+		log.Printf("Failed to meter LLM usage for analysis %s: %v", analysisID, err)
+	}
+}
@@ -7,8 +7,8 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/chynybekuuludastan/website_optimizer/internal/api/middleware"
+	"github.com/chynybekuuludastan/website_optimizer/internal/cache"
 	"github.com/chynybekuuludastan/website_optimizer/internal/config"
-	"github.com/chynybekuuludastan/website_optimizer/internal/database"
 	"github.com/chynybekuuludastan/website_optimizer/internal/models"
 	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
 	"github.com/chynybekuuludastan/website_optimizer/internal/utils/password"
@@ -16,17 +16,17 @@ import (
 
 // AuthHandler handles authentication-related requests
 type AuthHandler struct {
-	UserRepo    repository.UserRepository
-	RedisClient *database.RedisClient
-	Config      *config.Config
+	UserRepo repository.UserRepository
+	Cache    cache.Cache
+	Config   *config.Config
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(repo repository.UserRepository, redisClient *database.RedisClient, cfg *config.Config) *AuthHandler {
+func NewAuthHandler(repo repository.UserRepository, cacheStore cache.Cache, cfg *config.Config) *AuthHandler {
 	return &AuthHandler{
-		UserRepo:    repo,
-		RedisClient: redisClient,
-		Config:      cfg,
+		UserRepo: repo,
+		Cache:    cacheStore,
+		Config:   cfg,
 	}
 }
 
@@ -191,7 +191,7 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 
 	// Store token in Redis for blacklisting on logout
 	tokenKey := "token:" + token
-	h.RedisClient.Set(tokenKey, true, h.Config.JWTExpiration)
+	h.Cache.Set(tokenKey, true, h.Config.JWTExpiration)
 
 	return c.JSON(fiber.Map{
 		"success": true,
@@ -225,6 +225,7 @@ func (h *AuthHandler) GetMe(c *fiber.Ctx) error {
 			"username":   user.Username,
 			"email":      user.Email,
 			"role":       user.Role.Name,
+			"locale":     user.Locale,
 			"created_at": user.CreatedAt,
 		},
 	})
@@ -256,7 +257,7 @@ func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
 
 	// Store token in Redis
 	tokenKey := "token:" + token
-	h.RedisClient.Set(tokenKey, true, h.Config.JWTExpiration)
+	h.Cache.Set(tokenKey, true, h.Config.JWTExpiration)
 
 	return c.JSON(fiber.Map{
 		"success": true,
@@ -292,7 +293,7 @@ func (h *AuthHandler) Logout(c *fiber.Ctx) error {
 
 	// Add token to blacklist in Redis
 	tokenKey := "token:" + token
-	h.RedisClient.Set(tokenKey, false, h.Config.JWTExpiration)
+	h.Cache.Set(tokenKey, false, h.Config.JWTExpiration)
 
 	return c.JSON(fiber.Map{
 		"success": true,
@@ -0,0 +1,410 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/config"
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/billing"
+)
+
+// CheckoutRequest selects the plan to subscribe to
+type CheckoutRequest struct {
+	Plan string `json:"plan" validate:"required,oneof=pro agency"`
+}
+
+type BillingHandler struct {
+	SubscriptionRepo repository.SubscriptionRepository
+	UserRepo         repository.UserRepository
+	Stripe           *billing.StripeClient
+	Plans            *billing.Plans
+	Quota            *billing.Quota
+	Config           *config.Config
+}
+
+// NewBillingHandler creates a new billing handler
+func NewBillingHandler(
+	repoFactory *repository.Factory,
+	stripe *billing.StripeClient,
+	plans *billing.Plans,
+	quota *billing.Quota,
+	cfg *config.Config,
+) *BillingHandler {
+	return &BillingHandler{
+		SubscriptionRepo: repoFactory.SubscriptionRepository,
+		UserRepo:         repoFactory.UserRepository,
+		Stripe:           stripe,
+		Plans:            plans,
+		Quota:            quota,
+		Config:           cfg,
+	}
+}
+
+// billingStatuses are the Stripe subscription states that keep a paid plan active
+var billingStatuses = map[string]bool{
+	"active":   true,
+	"trialing": true,
+	"past_due": true,
+}
+
+// EffectivePlan returns the plan a user is entitled to. Users without a
+// subscription, or whose subscription lapsed, are on the free plan.
+func EffectivePlan(repo repository.SubscriptionRepository, userID uuid.UUID) string {
+	subscription, err := repo.FindByUserID(userID)
+	if err != nil || !billingStatuses[subscription.Status] {
+		return billing.PlanFree
+	}
+	return subscription.Plan
+}
+
+// enforceQuota writes a 402 response and returns false when the user has
+// exhausted a plan limit. Admins are not subject to plan limits.
+func enforceQuota(c *fiber.Ctx, quota *billing.Quota, resource billing.Resource) bool {
+	if quota == nil {
+		return true
+	}
+	if role, _ := c.Locals("role").(string); role == "admin" {
+		return true
+	}
+
+	userID := c.Locals("userID").(uuid.UUID)
+	status, err := quota.Check(c.Context(), userID, resource)
+	if errors.Is(err, billing.ErrQuotaExceeded) {
+		c.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{
+			"success": false,
+			"error":   "Your " + status.Plan + " plan limit for " + string(resource) + " has been reached",
+			"quota":   status,
+		})
+		return false
+	}
+	if err != nil {
+		// Do not block users because usage could not be counted
+		log.Printf("Failed to check %s quota for user %s: %v", resource, userID, err)
+	}
+	return true
+}
+
+// @Summary List billing plans
+// @Description Returns the available subscription plans and their limits
+// @Tags billing
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Plans"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Security BearerAuth
+// @Router /billing/plans [get]
+func (h *BillingHandler) ListPlans(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    h.Plans.List(),
+	})
+}
+
+// @Summary Get current subscription
+// @Description Returns the current plan, subscription state and quota usage of the authenticated user
+// @Tags billing
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Subscription"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /billing/subscription [get]
+func (h *BillingHandler) GetSubscription(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	subscription, err := h.SubscriptionRepo.FindByUserID(userID)
+	if err != nil {
+		subscription = &models.Subscription{
+			UserID: userID,
+			Plan:   billing.PlanFree,
+			Status: "active",
+		}
+	}
+
+	quotas, err := h.Quota.Statuses(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load quota usage: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"subscription":   subscription,
+			"effective_plan": EffectivePlan(h.SubscriptionRepo, userID),
+			"quotas":         quotas,
+		},
+	})
+}
+
+// @Summary Start a plan checkout
+// @Description Creates a Stripe checkout session for the selected paid plan and returns its URL
+// @Tags billing
+// @Accept json
+// @Produce json
+// @Param checkout body CheckoutRequest true "Plan selection"
+// @Success 200 {object} map[string]interface{} "Checkout session"
+// @Failure 400 {object} map[string]interface{} "Invalid plan"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 502 {object} map[string]interface{} "Stripe error"
+// @Failure 503 {object} map[string]interface{} "Billing not configured"
+// @Security BearerAuth
+// @Router /billing/checkout [post]
+func (h *BillingHandler) CreateCheckout(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	req := new(CheckoutRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+
+	plan, err := h.Plans.Get(req.Plan)
+	if err != nil || plan.StripePriceID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Plan is not available for checkout",
+		})
+	}
+
+	if !h.Stripe.Configured() {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"success": false,
+			"error":   billing.ErrNotConfigured.Error(),
+		})
+	}
+
+	params := billing.CheckoutParams{
+		PriceID:    plan.StripePriceID,
+		UserID:     userID.String(),
+		Plan:       plan.Name,
+		SuccessURL: h.Config.BillingSuccessURL,
+		CancelURL:  h.Config.BillingCancelURL,
+	}
+	if existing, err := h.SubscriptionRepo.FindByUserID(userID); err == nil {
+		params.CustomerID = existing.StripeCustomerID
+	}
+	if params.CustomerID == "" {
+		var user models.User
+		if err := h.UserRepo.FindByID(userID, &user); err == nil {
+			params.Email = user.Email
+		}
+	}
+
+	session, err := h.Stripe.CreateCheckoutSession(c.Context(), params)
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to create checkout session: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"session_id":   session.ID,
+			"checkout_url": session.URL,
+		},
+	})
+}
+
+// @Summary Complete a plan checkout
+// @Description Confirms a finished Stripe checkout session and activates the purchased plan. Webhooks apply the same change asynchronously
+// @Tags billing
+// @Produce json
+// @Param session_id query string true "Stripe checkout session ID"
+// @Success 200 {object} map[string]interface{} "Updated subscription"
+// @Failure 400 {object} map[string]interface{} "Checkout not completed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Session belongs to another user"
+// @Failure 502 {object} map[string]interface{} "Stripe error"
+// @Security BearerAuth
+// @Router /billing/callback [get]
+func (h *BillingHandler) CheckoutCallback(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	sessionID := c.Query("session_id")
+	if sessionID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "session_id is required",
+		})
+	}
+
+	session, err := h.Stripe.GetCheckoutSession(c.Context(), sessionID)
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch checkout session: " + err.Error(),
+		})
+	}
+
+	if session.ClientReferenceID != userID.String() {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"success": false,
+			"error":   "Checkout session belongs to another user",
+		})
+	}
+	if session.Status != "complete" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Checkout has not been completed",
+			"status":  session.Status,
+		})
+	}
+
+	subscription, err := h.applyCheckout(c.Context(), session)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to activate plan: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    subscription,
+	})
+}
+
+// @Summary Stripe webhook
+// @Description Receives Stripe payment and subscription events. Requests are authenticated with the Stripe-Signature header
+// @Tags billing
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Event processed"
+// @Failure 400 {object} map[string]interface{} "Invalid signature or payload"
+// @Failure 500 {object} map[string]interface{} "Event processing failed"
+// @Router /billing/webhook [post]
+func (h *BillingHandler) StripeWebhook(c *fiber.Ctx) error {
+	event, err := h.Stripe.ParseWebhook(c.Body(), c.Get("Stripe-Signature"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	if err := h.handleEvent(c.Context(), event); err != nil {
+		log.Printf("Failed to process stripe event %s (%s): %v", event.ID, event.Type, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to process event",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"received": true,
+	})
+}
+
+// handleEvent applies a Stripe event to the local subscription state
+func (h *BillingHandler) handleEvent(ctx context.Context, event *billing.Event) error {
+	switch event.Type {
+	case "checkout.session.completed":
+		var session billing.CheckoutSession
+		if err := json.Unmarshal(event.Data.Object, &session); err != nil {
+			return err
+		}
+		_, err := h.applyCheckout(ctx, &session)
+		return err
+
+	case "customer.subscription.created", "customer.subscription.updated", "customer.subscription.deleted":
+		var stripeSub billing.Subscription
+		if err := json.Unmarshal(event.Data.Object, &stripeSub); err != nil {
+			return err
+		}
+		return h.applySubscription(&stripeSub)
+
+	case "invoice.payment_failed", "invoice.paid":
+		var invoice billing.Invoice
+		if err := json.Unmarshal(event.Data.Object, &invoice); err != nil {
+			return err
+		}
+		subscription, err := h.SubscriptionRepo.FindByStripeCustomerID(invoice.Customer)
+		if err != nil {
+			// Invoice for a customer we do not track
+			return nil
+		}
+		subscription.Status = "active"
+		if event.Type == "invoice.payment_failed" {
+			subscription.Status = "past_due"
+		}
+		return h.SubscriptionRepo.Save(subscription)
+	}
+
+	return nil
+}
+
+// applyCheckout activates the plan bought in a completed checkout session
+func (h *BillingHandler) applyCheckout(ctx context.Context, session *billing.CheckoutSession) (*models.Subscription, error) {
+	userID, err := uuid.Parse(session.ClientReferenceID)
+	if err != nil {
+		return nil, errors.New("checkout session has no user reference")
+	}
+
+	plan, err := h.Plans.Get(session.Metadata["plan"])
+	if err != nil {
+		return nil, err
+	}
+
+	subscription := &models.Subscription{
+		UserID:               userID,
+		Plan:                 plan.Name,
+		Status:               "active",
+		StripeCustomerID:     session.Customer,
+		StripeSubscriptionID: session.Subscription,
+	}
+
+	if session.Subscription != "" {
+		if stripeSub, err := h.Stripe.GetSubscription(ctx, session.Subscription); err == nil {
+			subscription.Status = stripeSub.Status
+			subscription.CurrentPeriodEnd = time.Unix(stripeSub.CurrentPeriodEnd, 0)
+		}
+	}
+
+	if err := h.SubscriptionRepo.Save(subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// applySubscription mirrors a Stripe subscription into the local record
+func (h *BillingHandler) applySubscription(stripeSub *billing.Subscription) error {
+	subscription, err := h.SubscriptionRepo.FindByStripeSubscriptionID(stripeSub.ID)
+	if err != nil {
+		userID, parseErr := uuid.Parse(stripeSub.Metadata["user_id"])
+		if parseErr != nil {
+			// Subscription created outside of our checkout flow
+			return nil
+		}
+		subscription = &models.Subscription{UserID: userID}
+	}
+
+	subscription.Status = stripeSub.Status
+	subscription.StripeCustomerID = stripeSub.Customer
+	subscription.StripeSubscriptionID = stripeSub.ID
+	if stripeSub.CurrentPeriodEnd > 0 {
+		subscription.CurrentPeriodEnd = time.Unix(stripeSub.CurrentPeriodEnd, 0)
+	}
+	if plan, ok := h.Plans.ByPriceID(stripeSub.PriceID()); ok {
+		subscription.Plan = plan.Name
+	}
+	if stripeSub.Status == "canceled" {
+		subscription.Plan = billing.PlanFree
+	}
+
+	return h.SubscriptionRepo.Save(subscription)
+}
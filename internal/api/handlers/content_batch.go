@@ -0,0 +1,457 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/billing"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/chaos"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/llm"
+)
+
+const (
+	defaultContentBatchPages = 5
+	maxContentBatchPages     = 20
+)
+
+// Content batch page statuses
+const (
+	contentBatchPending    = "pending"
+	contentBatchProcessing = "processing"
+	contentBatchCompleted  = "completed"
+	contentBatchFailed     = "failed"
+	contentBatchSkipped    = "skipped"
+)
+
+// ContentBatchRequest represents a request for content improvements of the
+// lowest-scoring pages of a domain
+type ContentBatchRequest struct {
+	Pages          int      `json:"pages" example:"5"` // lowest-scoring pages to improve, at most 20
+	TargetAudience string   `json:"target_audience"`
+	Language       string   `json:"language"`
+	ProviderName   string   `json:"provider"`
+	Model          string   `json:"model,omitempty"`
+	Temperature    *float64 `json:"temperature,omitempty"`
+	MaxTokens      int      `json:"max_tokens,omitempty"`
+}
+
+// ContentBatchPage is the status of one page of a content batch
+type ContentBatchPage struct {
+	WebsiteID  uuid.UUID `json:"website_id"`
+	URL        string    `json:"url"`
+	AnalysisID uuid.UUID `json:"analysis_id"`
+	Score      float64   `json:"score"`
+	Status     string    `json:"status"` // pending, processing, completed, failed, skipped
+	Error      string    `json:"error,omitempty"`
+	Model      string    `json:"model,omitempty"`
+}
+
+// GenerateDomainContentImprovements starts content improvements for the
+// lowest-scoring pages of a domain
+// @Summary Improve the content of a domain's weakest pages
+// @Description Selects the pages of a domain (project) whose latest completed analysis has the lowest overall score and generates content improvements for each of them as a background batch. Poll /content-batches/{id} for aggregate progress and per-page status, and download the combined package from /content-batches/{id}/export. Each page counts as one LLM generation of the plan
+// @Tags content-improvements
+// @Accept json
+// @Produce json
+// @Param id path string true "Domain ID" format="uuid"
+// @Param request body handlers.ContentBatchRequest true "Batch parameters"
+// @Success 202 {object} map[string]interface{} "Batch started"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 402 {object} map[string]interface{} "Monthly LLM generation quota of the plan exhausted"
+// @Failure 404 {object} map[string]interface{} "Domain not found or without analyzed pages"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /domains/{id}/content-improvements [post]
+func (h *ContentImprovementHandler) GenerateDomainContentImprovements(c *fiber.Ctx) error {
+	domainID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid domain ID",
+		})
+	}
+
+	var domain models.Domain
+	if err := h.DomainRepo.FindByID(domainID, &domain); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Domain not found",
+		})
+	}
+
+	req := new(ContentBatchRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+	if req.Pages == 0 {
+		req.Pages = defaultContentBatchPages
+	}
+	if req.Pages < 0 || req.Pages > maxContentBatchPages {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   fmt.Sprintf("pages must be between 1 and %d", maxContentBatchPages),
+		})
+	}
+
+	userID := c.Locals("userID").(uuid.UUID)
+	providerName, params, err := h.generationParams(userID, req.ProviderName, llm.GenerationParams{
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	if !enforceQuota(c, h.Quota, billing.ResourceLLMGenerations) {
+		return nil
+	}
+
+	scores, err := h.DomainRepo.LowestScoringPages(domainID, req.Pages)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to select domain pages: " + err.Error(),
+		})
+	}
+	if len(scores) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Domain has no pages with a completed analysis",
+		})
+	}
+
+	pages := make([]ContentBatchPage, len(scores))
+	for i, score := range scores {
+		pages[i] = ContentBatchPage{
+			WebsiteID:  score.WebsiteID,
+			URL:        score.URL,
+			AnalysisID: score.AnalysisID,
+			Score:      score.Score,
+			Status:     contentBatchPending,
+		}
+	}
+
+	options, _ := json.Marshal(req)
+	pagesJSON, _ := json.Marshal(pages)
+	batch := models.ContentBatch{
+		UserID:   userID,
+		DomainID: domainID,
+		Status:   contentBatchProcessing,
+		Options:  options,
+		Pages:    pagesJSON,
+	}
+	if err := h.ContentBatchRepo.Create(&batch); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to create content batch: " + err.Error(),
+		})
+	}
+
+	role, _ := c.Locals("role").(string)
+	faults := chaos.FromContext(c.UserContext())
+	go h.runContentBatch(batch.ID, userID, role == "admin", pages, req, providerName, params, faults)
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"success": true,
+		"message": "Content improvement batch started",
+		"data":    batch,
+	})
+}
+
+// GetContentBatch returns the progress of a content batch
+// @Summary Get a content batch
+// @Description Returns the aggregate progress and the status of each page of a content improvement batch
+// @Tags content-improvements
+// @Produce json
+// @Param id path string true "Batch ID" format="uuid"
+// @Success 200 {object} map[string]interface{} "Content batch"
+// @Failure 400 {object} map[string]interface{} "Invalid batch ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Content batch not found"
+// @Security BearerAuth
+// @Router /content-batches/{id} [get]
+func (h *ContentImprovementHandler) GetContentBatch(c *fiber.Ctx) error {
+	batch, status, message := h.findContentBatch(c)
+	if batch == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    batch,
+	})
+}
+
+// ExportContentBatch downloads the improvements of a content batch
+// @Summary Export a content batch
+// @Description Downloads a ZIP package with a manifest of the batch and, for each completed page, its improvements as JSON and the generated HTML
+// @Tags content-improvements
+// @Produce application/zip
+// @Param id path string true "Batch ID" format="uuid"
+// @Success 200 {file} file "ZIP package"
+// @Failure 400 {object} map[string]interface{} "Invalid batch ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Content batch not found"
+// @Failure 409 {object} map[string]interface{} "Batch is still processing"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /content-batches/{id}/export [get]
+func (h *ContentImprovementHandler) ExportContentBatch(c *fiber.Ctx) error {
+	batch, status, message := h.findContentBatch(c)
+	if batch == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+	if batch.CompletedAt == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"success": false,
+			"error":   "Content batch is still processing",
+		})
+	}
+
+	var pages []ContentBatchPage
+	if err := json.Unmarshal(batch.Pages, &pages); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to read batch pages: " + err.Error(),
+		})
+	}
+
+	improvements := make(map[uuid.UUID][]models.ContentImprovement)
+	for _, page := range pages {
+		if page.Status != contentBatchCompleted {
+			continue
+		}
+		pageImprovements, err := h.ContentImproveRepo.FindByAnalysisID(page.AnalysisID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"success": false,
+				"error":   "Failed to load content improvements: " + err.Error(),
+			})
+		}
+		// Keep the improvements of this batch, not earlier generations
+		for _, improvement := range pageImprovements {
+			if !improvement.CreatedAt.Before(batch.CreatedAt) {
+				improvements[page.AnalysisID] = append(improvements[page.AnalysisID], improvement)
+			}
+		}
+	}
+
+	data, err := contentBatchPackage(batch, pages, improvements)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to build export package: " + err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="content-batch-%s.zip"`, batch.ID))
+	return c.Send(data)
+}
+
+// findContentBatch loads the batch of the route that belongs to the user
+func (h *ContentImprovementHandler) findContentBatch(c *fiber.Ctx) (*models.ContentBatch, int, string) {
+	batchID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.StatusBadRequest, "Invalid batch ID"
+	}
+
+	batch, err := h.ContentBatchRepo.FindForUser(c.Locals("userID").(uuid.UUID), batchID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.StatusNotFound, "Content batch not found"
+	}
+	if err != nil {
+		return nil, fiber.StatusInternalServerError, "Failed to load content batch: " + err.Error()
+	}
+	return batch, fiber.StatusOK, ""
+}
+
+// runContentBatch generates the improvements of the pages of a batch one
+// after another, storing the status of each page and the aggregate progress
+func (h *ContentImprovementHandler) runContentBatch(
+	batchID uuid.UUID,
+	userID uuid.UUID,
+	unlimited bool,
+	pages []ContentBatchPage,
+	req *ContentBatchRequest,
+	providerName string,
+	params llm.GenerationParams,
+	faults chaos.Faults,
+) {
+	save := func(done int) {
+		data, _ := json.Marshal(pages)
+		progress := float64(done) / float64(len(pages)) * 100
+		if err := h.ContentBatchRepo.UpdateProgress(batchID, progress, data); err != nil {
+			log.Printf("Failed to update content batch %s: %v", batchID, err)
+		}
+	}
+
+	for i := range pages {
+		page := &pages[i]
+		page.Status = contentBatchProcessing
+		save(i)
+
+		page.Status, page.Model, page.Error = h.improveBatchPage(page.AnalysisID, userID, unlimited, req, providerName, params, faults)
+	}
+
+	completed := 0
+	for _, page := range pages {
+		if page.Status == contentBatchCompleted {
+			completed++
+		}
+	}
+	status := "partial"
+	switch completed {
+	case len(pages):
+		status = contentBatchCompleted
+	case 0:
+		status = contentBatchFailed
+	}
+
+	data, _ := json.Marshal(pages)
+	if err := h.ContentBatchRepo.Complete(batchID, status, data); err != nil {
+		log.Printf("Failed to complete content batch %s: %v", batchID, err)
+	}
+}
+
+// improveBatchPage generates the improvements of one page of a batch and
+// returns its status, the model that generated them and any error
+func (h *ContentImprovementHandler) improveBatchPage(
+	analysisID uuid.UUID,
+	userID uuid.UUID,
+	unlimited bool,
+	req *ContentBatchRequest,
+	providerName string,
+	params llm.GenerationParams,
+	faults chaos.Faults,
+) (string, string, string) {
+	if _, busy := h.activeRequests.LoadOrStore(analysisID.String(), true); busy {
+		return contentBatchSkipped, "", "Content improvement generation already in progress"
+	}
+	defer h.activeRequests.Delete(analysisID.String())
+
+	if h.Quota != nil && !unlimited {
+		if _, err := h.Quota.Check(context.Background(), userID, billing.ResourceLLMGenerations); errors.Is(err, billing.ErrQuotaExceeded) {
+			return contentBatchSkipped, "", "Monthly LLM generation limit of the plan reached"
+		}
+		if err := h.Quota.Record(context.Background(), userID, billing.ResourceLLMGenerations); err != nil {
+			log.Printf("Failed to record LLM generation: %v", err)
+		}
+	}
+
+	var analysis models.Analysis
+	if err := h.AnalysisRepo.FindByID(analysisID, &analysis); err != nil {
+		return contentBatchFailed, "", "Analysis not found"
+	}
+
+	request, _, message := h.contentRequestFor(&analysis, req.Language, req.TargetAudience)
+	if request == nil {
+		return contentBatchFailed, "", message
+	}
+
+	response, err := h.generateContentWithProgressTracking(analysisID, analysis.UserID, request, providerName, params, faults)
+	if err != nil {
+		return contentBatchFailed, "", err.Error()
+	}
+	return contentBatchCompleted, llmModelLabel(response.ProviderUsed, response.Model), ""
+}
+
+var packageNameRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// contentBatchPackage builds the ZIP export of a batch: manifest.json with
+// the batch and its pages, and a folder per completed page with its
+// improvements and generated HTML
+func contentBatchPackage(batch *models.ContentBatch, pages []ContentBatchPage, improvements map[uuid.UUID][]models.ContentImprovement) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	writeFile := func(name string, content []byte) error {
+		w, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(content)
+		return err
+	}
+
+	manifest, err := json.MarshalIndent(fiber.Map{
+		"batch_id":     batch.ID,
+		"domain_id":    batch.DomainID,
+		"status":       batch.Status,
+		"created_at":   batch.CreatedAt,
+		"completed_at": batch.CompletedAt,
+		"pages":        pages,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile("manifest.json", manifest); err != nil {
+		return nil, err
+	}
+
+	for i, page := range pages {
+		pageImprovements := improvements[page.AnalysisID]
+		if len(pageImprovements) == 0 {
+			continue
+		}
+
+		name := strings.Trim(packageNameRegex.ReplaceAllString(strings.ToLower(page.URL), "-"), "-")
+		dir := fmt.Sprintf("pages/%02d-%s/", i+1, strings.TrimPrefix(strings.TrimPrefix(name, "https-"), "http-"))
+
+		elements := make(map[string]string, len(pageImprovements))
+		for _, improvement := range pageImprovements {
+			if improvement.ElementType == "html" {
+				if err := writeFile(dir+"index.html", []byte(improvement.ImprovedContent)); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			elements[improvement.ElementType] = improvement.ImprovedContent
+		}
+
+		data, err := json.MarshalIndent(fiber.Map{
+			"url":          page.URL,
+			"analysis_id":  page.AnalysisID,
+			"score":        page.Score,
+			"model":        page.Model,
+			"improvements": elements,
+		}, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := writeFile(dir+"improvements.json", data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
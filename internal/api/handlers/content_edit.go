@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/cache"
+	"github.com/chynybekuuludastan/website_optimizer/internal/config"
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/collab"
+	ws "github.com/chynybekuuludastan/website_optimizer/internal/websocket"
+)
+
+// EditPayload is an edit sent by a reviewer: operations made on a revision
+// of the improved content
+type EditPayload struct {
+	Revision int         `json:"revision"`
+	Ops      []collab.Op `json:"ops"`
+}
+
+// EditState is the improved content being edited
+type EditState struct {
+	ImprovementID uuid.UUID `json:"improvement_id"`
+	Content       string    `json:"content"`
+	Revision      int       `json:"revision"`
+}
+
+// editSession is the shared document of an improvement with reviewers in
+// its room. Sessions live on the instance the reviewers are connected to.
+type editSession struct {
+	improvementID uuid.UUID
+	analysisID    uuid.UUID
+	doc           *collab.Document
+	// mu orders applying edits and relaying them, so every reviewer
+	// receives the edits in revision order
+	mu            sync.Mutex
+	savedRevision int
+	editors       map[uuid.UUID]bool
+}
+
+// ContentEditHandler lets reviewers edit an improvement together before
+// approving it and saves the edited content as versions
+type ContentEditHandler struct {
+	Hub                *ws.Hub
+	ContentImproveRepo repository.ContentImprovementRepository
+	AnalysisRepo       repository.AnalysisRepository
+	Cache              cache.Cache
+	Config             *config.Config
+	sessions           map[uuid.UUID]*editSession
+	mu                 sync.Mutex
+}
+
+// NewContentEditHandler creates a new content edit handler and routes the
+// edit messages of improvement rooms to it
+func NewContentEditHandler(hub *ws.Hub, repoFactory *repository.Factory, cacheStore cache.Cache, cfg *config.Config) *ContentEditHandler {
+	h := &ContentEditHandler{
+		Hub:                hub,
+		ContentImproveRepo: repoFactory.ContentImprovementRepository,
+		AnalysisRepo:       repoFactory.AnalysisRepository,
+		Cache:              cacheStore,
+		Config:             cfg,
+		sessions:           make(map[uuid.UUID]*editSession),
+	}
+
+	hub.Handle(ws.MessageTypeEditSync, h.handleSync)
+	hub.Handle(ws.MessageTypeEdit, h.handleEdit)
+
+	return h
+}
+
+// GetImprovementVersions lists the saved versions of an improvement
+// @Summary List improvement versions
+// @Description Returns the versions of an improvement saved while reviewers edited it together, oldest first. Version 1 is the generated content. Reviewers join the WebSocket room improvement:<id>, request the content with {"type":"edit_sync"} and send edits as {"type":"edit","data":{"revision":n,"ops":[{"type":"insert","position":0,"text":"..."},{"type":"delete","position":0,"length":3}]}}; positions count Unicode code points
+// @Tags content-improvements
+// @Produce json
+// @Param id path string true "Improvement ID" format="uuid"
+// @Success 200 {object} map[string]interface{} "Improvement versions"
+// @Failure 400 {object} map[string]interface{} "Invalid improvement ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Improvement not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /content-improvements/{id}/versions [get]
+func (h *ContentEditHandler) GetImprovementVersions(c *fiber.Ctx) error {
+	improvementID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid improvement ID",
+		})
+	}
+
+	userID, _ := c.Locals("userID").(uuid.UUID)
+	role, _ := c.Locals("role").(string)
+	improvement, err := h.editableImprovement(improvementID, userID, role)
+	if err != nil {
+		status := fiber.StatusNotFound
+		if errors.Is(err, errEditForbidden) {
+			status = fiber.StatusForbidden
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	versions, err := h.ContentImproveRepo.FindVersions(improvementID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch versions: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"improvement_id": improvement.ID,
+			"element_type":   improvement.ElementType,
+			"content":        improvement.ImprovedContent,
+			"versions":       versions,
+		},
+	})
+}
+
+var errEditForbidden = errors.New("only the owner of the analysis can edit its improvements")
+
+// editableImprovement loads an improvement the user may edit
+func (h *ContentEditHandler) editableImprovement(improvementID, userID uuid.UUID, role string) (*models.ContentImprovement, error) {
+	var improvement models.ContentImprovement
+	if err := h.ContentImproveRepo.FindByID(improvementID, &improvement); err != nil {
+		return nil, errors.New("improvement not found")
+	}
+
+	var analysis models.Analysis
+	if err := h.AnalysisRepo.FindByID(improvement.AnalysisID, &analysis); err != nil {
+		return nil, errors.New("analysis not found")
+	}
+	if role != "admin" && analysis.UserID != userID {
+		return nil, errEditForbidden
+	}
+	return &improvement, nil
+}
+
+// AuthorizeRoom allows the owner of the analysis and administrators to join
+// the room of an improvement
+func (h *ContentEditHandler) AuthorizeRoom(client *ws.Client, improvementID uuid.UUID) error {
+	_, err := h.editableImprovement(improvementID, client.UserID, client.Role)
+	return err
+}
+
+// session returns the editing session of an improvement, starting it from
+// the stored content
+func (h *ContentEditHandler) session(improvementID uuid.UUID) (*editSession, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if session, ok := h.sessions[improvementID]; ok {
+		return session, nil
+	}
+
+	var improvement models.ContentImprovement
+	if err := h.ContentImproveRepo.FindByID(improvementID, &improvement); err != nil {
+		return nil, errors.New("improvement not found")
+	}
+	session := &editSession{
+		improvementID: improvementID,
+		analysisID:    improvement.AnalysisID,
+		doc:           collab.NewDocument(improvement.ImprovedContent, h.Config.ContentEditMaxLength),
+		editors:       make(map[uuid.UUID]bool),
+	}
+	h.sessions[improvementID] = session
+	return session, nil
+}
+
+// roomSession returns the session of the improvement room of a message
+func (h *ContentEditHandler) roomSession(client *ws.Client, msg *ws.Message) (*editSession, bool) {
+	id, err := uuid.Parse(improvementRoomID(msg.Room))
+	if err != nil {
+		client.SendError(msg.Room, "Edits are only accepted in improvement rooms")
+		return nil, false
+	}
+	session, err := h.session(id)
+	if err != nil {
+		client.SendError(msg.Room, err.Error())
+		return nil, false
+	}
+	return session, true
+}
+
+// handleSync sends the current content to a reviewer
+func (h *ContentEditHandler) handleSync(client *ws.Client, msg *ws.Message) {
+	session, ok := h.roomSession(client, msg)
+	if !ok {
+		return
+	}
+	h.sendState(client, msg.Room, session)
+}
+
+func (h *ContentEditHandler) sendState(client *ws.Client, room string, session *editSession) {
+	content, revision := session.doc.State()
+	state := EditState{ImprovementID: session.improvementID, Content: content, Revision: revision}
+	if reply, err := ws.NewMessage(ws.MessageTypeEditState, room, state); err == nil {
+		client.Send(reply)
+	}
+}
+
+// handleEdit merges an edit into the content, confirms it to its author and
+// relays it to the other reviewers
+func (h *ContentEditHandler) handleEdit(client *ws.Client, msg *ws.Message) {
+	session, ok := h.roomSession(client, msg)
+	if !ok {
+		return
+	}
+
+	var payload EditPayload
+	if err := json.Unmarshal(msg.Data, &payload); err != nil || len(payload.Ops) == 0 {
+		client.SendError(msg.Room, "Invalid edit payload")
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	applied, revision, err := session.doc.Apply(payload.Revision, payload.Ops)
+	if err != nil {
+		client.SendError(msg.Room, err.Error())
+		if errors.Is(err, collab.ErrStaleRevision) {
+			h.sendState(client, msg.Room, session)
+		}
+		return
+	}
+	if len(applied) > 0 {
+		session.editors[client.UserID] = true
+	}
+
+	if ack, err := ws.NewMessage(ws.MessageTypeEditAck, msg.Room, fiber.Map{"revision": revision}); err == nil {
+		client.Send(ack)
+	}
+	if len(applied) == 0 {
+		return
+	}
+	edit, err := ws.NewMessage(ws.MessageTypeEdit, msg.Room, fiber.Map{
+		"revision": revision,
+		"ops":      applied,
+		"user_id":  client.UserID,
+		"username": client.Username,
+	})
+	if err == nil {
+		h.Hub.BroadcastToRoom(msg.Room, edit, client)
+	}
+}
+
+// RunSnapshots saves edited content as versions at the configured interval
+// until the context is cancelled
+func (h *ContentEditHandler) RunSnapshots(ctx context.Context) {
+	if h.Config.ContentEditSnapshotInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(h.Config.ContentEditSnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			h.saveSessions()
+			return
+		case <-ticker.C:
+			h.saveSessions()
+		}
+	}
+}
+
+// saveSessions saves the sessions edited since their last version and ends
+// the saved sessions nobody is editing anymore
+func (h *ContentEditHandler) saveSessions() {
+	h.mu.Lock()
+	sessions := make([]*editSession, 0, len(h.sessions))
+	for _, session := range h.sessions {
+		sessions = append(sessions, session)
+	}
+	h.mu.Unlock()
+
+	for _, session := range sessions {
+		room := ws.ImprovementRoom(session.improvementID.String())
+		if err := h.saveSession(session, room); err != nil {
+			log.Printf("Failed to save edits of improvement %s: %v", session.improvementID, err)
+			continue
+		}
+
+		if len(h.Hub.Presence(room)) > 0 {
+			continue
+		}
+		h.mu.Lock()
+		session.mu.Lock()
+		_, revision := session.doc.State()
+		if revision == session.savedRevision {
+			delete(h.sessions, session.improvementID)
+		}
+		session.mu.Unlock()
+		h.mu.Unlock()
+	}
+}
+
+// saveSession saves the content of a session as a new version if it was
+// edited since the last one
+func (h *ContentEditHandler) saveSession(session *editSession, room string) error {
+	session.mu.Lock()
+	content, revision := session.doc.State()
+	if revision == session.savedRevision {
+		session.mu.Unlock()
+		return nil
+	}
+	editors := make([]uuid.UUID, 0, len(session.editors))
+	for editor := range session.editors {
+		editors = append(editors, editor)
+	}
+	session.mu.Unlock()
+
+	version, err := h.ContentImproveRepo.SaveEditedVersion(session.improvementID, content, revision, editors)
+	if err != nil {
+		return err
+	}
+
+	session.mu.Lock()
+	session.savedRevision = revision
+	for _, editor := range editors {
+		delete(session.editors, editor)
+	}
+	session.mu.Unlock()
+
+	if h.Cache != nil {
+		h.Cache.Delete("content_improvements:" + session.analysisID.String())
+	}
+	if msg, err := ws.NewMessage(ws.MessageTypeEditVersion, room, version); err == nil {
+		h.Hub.BroadcastToRoom(room, msg, nil)
+	}
+	return nil
+}
+
+// improvementRoomID returns the improvement ID of an improvement room
+func improvementRoomID(room string) string {
+	if id, ok := strings.CutPrefix(room, "improvement:"); ok {
+		return id
+	}
+	return ""
+}
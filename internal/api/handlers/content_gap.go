@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/cache"
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/billing"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/llm"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+)
+
+const (
+	// maxGapCompetitors limits how many competitor pages one request compares
+	maxGapCompetitors = 5
+	// competitorFetchTimeout bounds the fetch of a single competitor page
+	competitorFetchTimeout = 20 * time.Second
+	// contentGapCacheTTL is how long a gap report is reused
+	contentGapCacheTTL = time.Hour
+)
+
+// ContentGapRequest is the body of a content gap analysis request
+type ContentGapRequest struct {
+	Keyword     string   `json:"keyword" validate:"required"`
+	Competitors []string `json:"competitors" validate:"required,min=1,max=5"`
+	// Outline requests an LLM-generated outline covering the missing topics
+	Outline      bool   `json:"outline"`
+	Language     string `json:"language,omitempty"`
+	ProviderName string `json:"provider,omitempty"`
+}
+
+// contentGapResult is the gap report with the optional outline suggestion
+type contentGapResult struct {
+	analyzer.ContentGapReport
+	Outline      *llm.OutlineResponse `json:"outline,omitempty"`
+	OutlineError string               `json:"outline_error,omitempty"`
+}
+
+type ContentGapHandler struct {
+	LLMService   *llm.Service
+	AnalysisRepo repository.AnalysisRepository
+	WebsiteRepo  repository.WebsiteRepository
+	SnapshotRepo repository.SnapshotRepository
+	UsageRepo    repository.UsageRepository
+	Quota        *billing.Quota
+	Cache        cache.Cache
+}
+
+// NewContentGapHandler creates a new content gap handler
+func NewContentGapHandler(
+	llmService *llm.Service,
+	repoFactory *repository.Factory,
+	cacheStore cache.Cache,
+	quota *billing.Quota,
+) *ContentGapHandler {
+	return &ContentGapHandler{
+		LLMService:   llmService,
+		AnalysisRepo: repoFactory.AnalysisRepository,
+		WebsiteRepo:  repoFactory.WebsiteRepository,
+		SnapshotRepo: repoFactory.SnapshotRepository,
+		UsageRepo:    repoFactory.UsageRepository,
+		Quota:        quota,
+		Cache:        cacheStore,
+	}
+}
+
+// AnalyzeContentGap compares the analyzed page with competitor pages
+// @Summary Analyze content gaps against competitors
+// @Description Extracts headings and topical phrases from the analyzed page and from up to 5 competitor pages targeting the same keyword, and reports topics and sections the competitors cover that the page lacks. With outline=true an LLM suggests an outline covering the gaps
+// @Tags content-improvements
+// @Accept json
+// @Produce json
+// @Param id path string true "Analysis ID" format="uuid"
+// @Param request body handlers.ContentGapRequest true "Keyword and competitor URLs"
+// @Success 200 {object} map[string]interface{} "Content gap report"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 402 {object} map[string]interface{} "Monthly LLM generation quota of the plan exhausted"
+// @Failure 404 {object} map[string]interface{} "Analysis not found"
+// @Failure 502 {object} map[string]interface{} "The page could not be fetched"
+// @Security BearerAuth
+// @Router /analysis/{id}/content-gap [post]
+func (h *ContentGapHandler) AnalyzeContentGap(c *fiber.Ctx) error {
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+
+	req := new(ContentGapRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+	req.Keyword = strings.TrimSpace(req.Keyword)
+	if req.Keyword == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Keyword is required",
+		})
+	}
+
+	var analysis models.Analysis
+	if err := h.AnalysisRepo.FindByID(analysisID, &analysis); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Analysis not found",
+		})
+	}
+	var website models.Website
+	if err := h.WebsiteRepo.FindByID(analysis.WebsiteID, &website); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch website data",
+		})
+	}
+
+	competitors, err := normalizeCompetitors(req.Competitors, website.URL)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	for _, competitorURL := range competitors {
+		if err := checkPublicURL(c.Context(), competitorURL); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   competitorURL + ": " + err.Error(),
+			})
+		}
+	}
+
+	cacheKey := contentGapCacheKey(analysisID, req, competitors)
+	if h.Cache != nil {
+		var cached contentGapResult
+		if err := h.Cache.Get(cacheKey, &cached); err == nil {
+			return c.JSON(fiber.Map{
+				"success": true,
+				"data":    cached,
+				"cached":  true,
+			})
+		}
+	}
+
+	if req.Outline && !enforceQuota(c, h.Quota, billing.ResourceLLMGenerations) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 2*time.Minute)
+	defer cancel()
+
+	page, err := h.pageTopics(ctx, analysisID, website.URL)
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load the analyzed page: " + err.Error(),
+		})
+	}
+
+	// Fetch competitor pages concurrently
+	fetched := make([]*parser.PageTopics, len(competitors))
+	fetchErrors := make([]error, len(competitors))
+	var wg sync.WaitGroup
+	for i, competitorURL := range competitors {
+		wg.Add(1)
+		go func(i int, competitorURL string) {
+			defer wg.Done()
+			fetched[i], fetchErrors[i] = parser.FetchTopics(ctx, competitorURL, competitorFetchTimeout)
+		}(i, competitorURL)
+	}
+	wg.Wait()
+
+	var loaded []*parser.PageTopics
+	failed := make(map[string]string)
+	for i, competitorURL := range competitors {
+		if fetchErrors[i] != nil {
+			failed[competitorURL] = fetchErrors[i].Error()
+			continue
+		}
+		loaded = append(loaded, fetched[i])
+	}
+	if len(loaded) == 0 {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"success": false,
+			"error":   "None of the competitor pages could be fetched",
+			"details": failed,
+		})
+	}
+
+	result := contentGapResult{
+		ContentGapReport: analyzer.FindContentGaps(req.Keyword, page, loaded, failed),
+	}
+
+	if req.Outline {
+		h.suggestOutline(ctx, c.Locals("userID").(uuid.UUID), analysisID, page, req, &result)
+	}
+
+	if h.Cache != nil && result.OutlineError == "" {
+		h.Cache.Set(cacheKey, result, contentGapCacheTTL)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// pageTopics extracts the topics of the analyzed page from its stored
+// snapshot, falling back to fetching the live page
+func (h *ContentGapHandler) pageTopics(ctx context.Context, analysisID uuid.UUID, pageURL string) (*parser.PageTopics, error) {
+	snapshot, err := h.SnapshotRepo.Find(analysisID, models.SnapshotKindDOM)
+	if err != nil {
+		snapshot, err = h.SnapshotRepo.Find(analysisID, models.SnapshotKindHTML)
+	}
+	if err == nil {
+		if html, err := repository.DecompressSnapshot(snapshot); err == nil {
+			topics := parser.ExtractTopics(string(html))
+			topics.URL = pageURL
+			return &topics, nil
+		}
+	}
+
+	return parser.FetchTopics(ctx, pageURL, competitorFetchTimeout)
+}
+
+// suggestOutline asks the LLM for an outline covering the gaps. Failures are
+// reported in the result instead of failing the gap report.
+func (h *ContentGapHandler) suggestOutline(ctx context.Context, userID, analysisID uuid.UUID, page *parser.PageTopics, req *ContentGapRequest, result *contentGapResult) {
+	if len(result.MissingTopics) == 0 && len(result.MissingHeadings) == 0 {
+		result.OutlineError = "No content gaps to build an outline for"
+		return
+	}
+
+	providerName := req.ProviderName
+	if providerName == "" {
+		providers := h.LLMService.GetAvailableProviders()
+		if len(providers) == 0 {
+			result.OutlineError = "No LLM providers available"
+			return
+		}
+		providerName = providers[0]
+	}
+
+	outlineRequest := &llm.OutlineRequest{
+		URL:      page.URL,
+		Keyword:  req.Keyword,
+		Title:    page.Title,
+		Language: req.Language,
+	}
+	for _, heading := range page.Headings {
+		outlineRequest.Headings = append(outlineRequest.Headings, heading.Text)
+	}
+	for _, topic := range result.MissingTopics {
+		outlineRequest.MissingTopics = append(outlineRequest.MissingTopics, topic.Phrase)
+	}
+	for _, heading := range result.MissingHeadings {
+		outlineRequest.CompetitorHeadings = append(outlineRequest.CompetitorHeadings, heading.Text)
+	}
+
+	outline, err := h.LLMService.GenerateOutline(ctx, outlineRequest, providerName)
+	if err != nil {
+		result.OutlineError = "Failed to generate outline: " + err.Error()
+		return
+	}
+	result.Outline = outline
+
+	if outline.CachedResult {
+		return
+	}
+	if h.Quota != nil {
+		if err := h.Quota.Record(context.Background(), userID, billing.ResourceLLMGenerations); err != nil {
+			fmt.Println("Failed to record LLM generation:", err)
+		}
+	}
+
+	var completion strings.Builder
+	completion.WriteString(outline.Title)
+	for _, section := range outline.Sections {
+		completion.WriteString(section.Heading)
+		completion.WriteString(strings.Join(section.Points, " "))
+	}
+	meterLLMUsage(h.UsageRepo, analysisID, userID, outline.ProviderUsed,
+		strings.Join(outlineRequest.MissingTopics, " ")+strings.Join(outlineRequest.CompetitorHeadings, " "),
+		completion.String())
+}
+
+// normalizeCompetitors validates and deduplicates competitor URLs, dropping
+// the analyzed page itself
+func normalizeCompetitors(urls []string, pageURL string) ([]string, error) {
+	pageKey, _ := urlnorm.Key(pageURL)
+
+	seen := make(map[string]bool)
+	var competitors []string
+	for _, raw := range urls {
+		normalized, err := urlnorm.Normalize(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid competitor URL %q: %v", raw, err)
+		}
+		key, _ := urlnorm.Key(normalized)
+		if key == pageKey || seen[key] {
+			continue
+		}
+		seen[key] = true
+		competitors = append(competitors, normalized)
+	}
+
+	if len(competitors) == 0 {
+		return nil, fmt.Errorf("at least one competitor URL other than the analyzed page is required")
+	}
+	if len(competitors) > maxGapCompetitors {
+		return nil, fmt.Errorf("at most %d competitor URLs are supported", maxGapCompetitors)
+	}
+	return competitors, nil
+}
+
+// contentGapCacheKey identifies a gap report by analysis and request
+func contentGapCacheKey(analysisID uuid.UUID, req *ContentGapRequest, competitors []string) string {
+	sorted := append([]string(nil), competitors...)
+	sort.Strings(sorted)
+
+	parts := []string{strings.ToLower(req.Keyword), strings.Join(sorted, ","), req.Language, req.ProviderName}
+	if req.Outline {
+		parts = append(parts, "outline")
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return "content_gap:" + analysisID.String() + ":" + hex.EncodeToString(sum[:12])
+}
@@ -10,9 +10,12 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
-	"github.com/chynybekuuludastan/website_optimizer/internal/database"
+	"github.com/chynybekuuludastan/website_optimizer/internal/cache"
 	"github.com/chynybekuuludastan/website_optimizer/internal/models"
 	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/billing"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/chaos"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/glossary"
 	"github.com/chynybekuuludastan/website_optimizer/internal/service/llm"
 )
 
@@ -22,7 +25,13 @@ type ContentImprovementHandler struct {
 	MetricsRepo        repository.MetricsRepository
 	ContentImproveRepo repository.ContentImprovementRepository
 	WebsiteRepo        repository.WebsiteRepository
-	RedisClient        *database.RedisClient // Add Redis client
+	UserRepo           repository.UserRepository
+	DomainRepo         repository.DomainRepository
+	ContentBatchRepo   repository.ContentBatchRepository
+	GlossaryRepo       repository.GlossaryRepository
+	UsageRepo          repository.UsageRepository
+	Quota              *billing.Quota
+	Cache              cache.Cache
 	activeRequests     sync.Map
 }
 
@@ -30,7 +39,8 @@ type ContentImprovementHandler struct {
 func NewContentImprovementHandler(
 	llmService *llm.Service,
 	repoFactory *repository.Factory,
-	redisClient *database.RedisClient,
+	cacheStore cache.Cache,
+	quota *billing.Quota,
 ) *ContentImprovementHandler {
 	return &ContentImprovementHandler{
 		LLMService:         llmService,
@@ -38,7 +48,13 @@ func NewContentImprovementHandler(
 		MetricsRepo:        repoFactory.MetricsRepository,
 		ContentImproveRepo: repoFactory.ContentImprovementRepository,
 		WebsiteRepo:        repoFactory.WebsiteRepository,
-		RedisClient:        redisClient,
+		UserRepo:           repoFactory.UserRepository,
+		DomainRepo:         repoFactory.DomainRepository,
+		ContentBatchRepo:   repoFactory.ContentBatchRepository,
+		GlossaryRepo:       repoFactory.GlossaryRepository,
+		UsageRepo:          repoFactory.UsageRepository,
+		Quota:              quota,
+		Cache:              cacheStore,
 		activeRequests:     sync.Map{},
 	}
 }
@@ -48,6 +64,11 @@ type ContentImprovementRequest struct {
 	TargetAudience string `json:"target_audience"`
 	Language       string `json:"language"`
 	ProviderName   string `json:"provider"`
+	// Model, Temperature and MaxTokens tune the generation; unset values
+	// fall back to the organization's LLM defaults, then to the provider's
+	Model       string   `json:"model,omitempty" example:"gemini-1.5-pro"`
+	Temperature *float64 `json:"temperature,omitempty" example:"0.4"`
+	MaxTokens   int      `json:"max_tokens,omitempty" example:"4096"`
 }
 
 type SuccessResponse struct {
@@ -82,10 +103,13 @@ type CodeSnippetRequest struct {
 	Language       string   `json:"language,omitempty"`
 	ProviderName   string   `json:"provider"`
 	SnippetTypes   []string `json:"snippet_types,omitempty"` // Types of snippets to generate (e.g., html, css, js)
+	Model          string   `json:"model,omitempty"`
+	Temperature    *float64 `json:"temperature,omitempty"`
+	MaxTokens      int      `json:"max_tokens,omitempty"`
 }
 
 // @Summary Request new content improvement
-// @Description Generate new content improvements using LLM for a specific analysis
+// @Description Generate new content improvements using LLM for a specific analysis. The model, temperature and max_tokens are validated against the capabilities of the provider; unset values fall back to the organization's LLM defaults
 // @Tags content-improvements
 // @Accept json
 // @Produce json
@@ -94,6 +118,7 @@ type CodeSnippetRequest struct {
 // @Success 202 {object} handlers.SuccessResponse "Content improvement generation initiated"
 // @Failure 400 {object} handlers.ErrorResponse "Invalid request"
 // @Failure 401 {object} handlers.ErrorResponse "Unauthorized"
+// @Failure 402 {object} handlers.ErrorResponse "Monthly LLM generation quota of the plan exhausted"
 // @Failure 403 {object} handlers.ErrorResponse "Forbidden"
 // @Failure 404 {object} handlers.ErrorResponse "Analysis not found"
 // @Failure 500 {object} handlers.ErrorResponse "Server error"
@@ -144,22 +169,68 @@ func (h *ContentImprovementHandler) RequestContentImprovement(c *fiber.Ctx) erro
 		})
 	}
 
+	// Resolve the model and parameters against the provider's capabilities
+	providerName, params, err := h.generationParams(analysis.UserID, req.ProviderName, llm.GenerationParams{
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	contentRequest, status, message := h.contentRequestFor(&analysis, req.Language, req.TargetAudience)
+	if contentRequest == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	if !enforceQuota(c, h.Quota, billing.ResourceLLMGenerations) {
+		return nil
+	}
+	if h.Quota != nil {
+		if err := h.Quota.Record(c.Context(), c.Locals("userID").(uuid.UUID), billing.ResourceLLMGenerations); err != nil {
+			fmt.Println("Failed to record LLM generation:", err)
+		}
+	}
+
+	// Mark this analysis ID as having an active request
+	h.activeRequests.Store(analysisID.String(), true)
+
+	// Start content generation in the background with enhanced progress tracking
+	go func() {
+		defer h.activeRequests.Delete(analysisID.String())
+		h.generateContentWithProgressTracking(analysisID, analysis.UserID, contentRequest, providerName, params, chaos.FromContext(c.UserContext()))
+	}()
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"success": true,
+		"message": "Content improvement generation started",
+		"data": fiber.Map{
+			"analysis_id": analysisID,
+			"status":      "processing",
+		},
+	})
+}
+
+// contentRequestFor builds the content request of a completed analysis from
+// the page text saved in its metadata
+func (h *ContentImprovementHandler) contentRequestFor(analysis *models.Analysis, language, targetAudience string) (*llm.ContentRequest, int, string) {
 	// Get website data
 	var website models.Website
 	if err := h.WebsiteRepo.FindByID(analysis.WebsiteID, &website); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"success": false,
-			"error":   "Failed to fetch website data",
-		})
+		return nil, fiber.StatusInternalServerError, "Failed to fetch website data"
 	}
 
 	// Get metrics data for analysis results
-	metrics, err := h.MetricsRepo.FindByAnalysisID(analysisID)
+	metrics, err := h.MetricsRepo.FindByAnalysisID(analysis.ID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"success": false,
-			"error":   "Failed to fetch analysis metrics",
-		})
+		return nil, fiber.StatusInternalServerError, "Failed to fetch analysis metrics"
 	}
 
 	// Extract text content from website or analysis metadata
@@ -200,43 +271,29 @@ func (h *ContentImprovementHandler) RequestContentImprovement(c *fiber.Ctx) erro
 	analysisResults := llm.ExtractAnalysisResults(metrics)
 
 	// Create a ContentRequest
-	contentRequest := &llm.ContentRequest{
+	return &llm.ContentRequest{
 		URL:             website.URL,
 		Title:           title,
 		CTAText:         ctaText,
 		Content:         content,
 		AnalysisResults: analysisResults,
-		Language:        req.Language,
-		TargetAudience:  req.TargetAudience,
-	}
-
-	// Mark this analysis ID as having an active request
-	h.activeRequests.Store(analysisID.String(), true)
-
-	// Start content generation in the background with enhanced progress tracking
-	go func() {
-		defer h.activeRequests.Delete(analysisID.String())
-		h.generateContentWithProgressTracking(analysisID, contentRequest, req.ProviderName)
-	}()
-
-	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
-		"success": true,
-		"message": "Content improvement generation started",
-		"data": fiber.Map{
-			"analysis_id": analysisID,
-			"status":      "processing",
-		},
-	})
+		Language:        language,
+		TargetAudience:  targetAudience,
+	}, fiber.StatusOK, ""
 }
 
 // generateContentWithProgressTracking handles content generation with WebSocket progress updates
+// and saves the improvements. It returns the generated content.
 func (h *ContentImprovementHandler) generateContentWithProgressTracking(
 	analysisID uuid.UUID,
+	userID uuid.UUID,
 	request *llm.ContentRequest,
 	providerName string,
-) {
+	params llm.GenerationParams,
+	faults chaos.Faults,
+) (*llm.ContentResponse, error) {
 	// Set timeout for generation
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx, cancel := context.WithTimeout(llm.WithParams(chaos.WithFaults(context.Background(), faults), params), 2*time.Minute)
 	defer cancel()
 
 	// Create progress callback for live updates
@@ -254,7 +311,7 @@ func (h *ContentImprovementHandler) generateContentWithProgressTracking(
 			providerName = providers[0]
 		} else {
 			fmt.Println("No LLM providers available")
-			return
+			return nil, llm.ErrInvalidProvider
 		}
 	}
 
@@ -262,11 +319,12 @@ func (h *ContentImprovementHandler) generateContentWithProgressTracking(
 	response, err := h.LLMService.GenerateContentWithProgress(ctx, request, providerName, progressCallback)
 	if err != nil {
 		// Send failure notification
-		return
+		return nil, err
 	}
 
-	// Generate HTML
-	html, err := h.LLMService.GenerateHTML(ctx, request, response, providerName)
+	// Generate HTML with the provider that served the content, which may be
+	// a fallback of the requested one
+	html, err := h.LLMService.GenerateHTML(ctx, request, response, response.ProviderUsed)
 	if err != nil {
 		// Continue without HTML, send warning
 		fmt.Println("Failed to generate HTML content:", err)
@@ -274,6 +332,15 @@ func (h *ContentImprovementHandler) generateContentWithProgressTracking(
 		response.HTML = html
 	}
 
+	// Correct the content against the organization's terminology before saving
+	corrections := h.enforceGlossary(userID, request.Language, response)
+	h.recordGlossaryCorrections(analysisID, "glossary_corrections", corrections)
+
+	model := llmModelLabel(response.ProviderUsed, response.Model)
+	meterLLMUsage(h.UsageRepo, analysisID, userID, firstNonEmpty(response.Model, response.ProviderUsed),
+		request.Title+request.CTAText+request.Content,
+		response.Title+response.CTAText+response.Content+response.HTML)
+
 	// Prepare database records
 	improvements := []models.ContentImprovement{
 		{
@@ -281,21 +348,21 @@ func (h *ContentImprovementHandler) generateContentWithProgressTracking(
 			ElementType:     "heading",
 			OriginalContent: request.Title,
 			ImprovedContent: response.Title,
-			LLMModel:        response.ProviderUsed,
+			LLMModel:        model,
 		},
 		{
 			AnalysisID:      analysisID,
 			ElementType:     "cta",
 			OriginalContent: request.CTAText,
 			ImprovedContent: response.CTAText,
-			LLMModel:        response.ProviderUsed,
+			LLMModel:        model,
 		},
 		{
 			AnalysisID:      analysisID,
 			ElementType:     "content",
 			OriginalContent: request.Content,
 			ImprovedContent: response.Content,
-			LLMModel:        response.ProviderUsed,
+			LLMModel:        model,
 		},
 	}
 
@@ -306,7 +373,7 @@ func (h *ContentImprovementHandler) generateContentWithProgressTracking(
 			ElementType:     "html",
 			OriginalContent: "",
 			ImprovedContent: response.HTML,
-			LLMModel:        response.ProviderUsed,
+			LLMModel:        model,
 		})
 	}
 
@@ -314,12 +381,13 @@ func (h *ContentImprovementHandler) generateContentWithProgressTracking(
 	err = h.ContentImproveRepo.CreateBatch(improvements)
 	if err != nil {
 		fmt.Println("Failed to save content improvements:", err)
-		return
+		return nil, err
 	}
+	return response, nil
 }
 
 // @Summary Get content improvements for an analysis
-// @Description Retrieve all content improvements generated for a specific analysis
+// @Description Retrieve all content improvements generated for a specific analysis, with the glossary_corrections made to enforce the organization's terminology
 // @Tags content-improvements
 // @Accept json
 // @Produce json
@@ -346,9 +414,9 @@ func (h *ContentImprovementHandler) GetContentImprovements(c *fiber.Ctx) error {
 	cacheKey := "content_improvements:" + analysisID.String()
 
 	// Try to get from cache if Redis is available
-	if h.RedisClient != nil {
+	if h.Cache != nil {
 		var cachedResponse map[string]interface{}
-		err := h.RedisClient.Get(cacheKey, &cachedResponse)
+		err := h.Cache.Get(cacheKey, &cachedResponse)
 		if err == nil && cachedResponse != nil {
 			return c.JSON(fiber.Map{
 				"success": true,
@@ -394,8 +462,8 @@ func (h *ContentImprovementHandler) GetContentImprovements(c *fiber.Ctx) error {
 		}
 
 		// Cache this response briefly
-		if h.RedisClient != nil {
-			h.RedisClient.Set(cacheKey, noImprovementsResponse, 1*time.Minute) // Short cache - only 1 minute
+		if h.Cache != nil {
+			h.Cache.Set(cacheKey, noImprovementsResponse, 1*time.Minute) // Short cache - only 1 minute
 		}
 
 		return c.JSON(fiber.Map{
@@ -406,20 +474,29 @@ func (h *ContentImprovementHandler) GetContentImprovements(c *fiber.Ctx) error {
 
 	// Format response by element type
 	response := map[string]string{}
+	ids := map[string]uuid.UUID{} // rooms in which reviewers edit the improvements
 	for _, improvement := range improvements {
 		response[improvement.ElementType] = improvement.ImprovedContent
+		ids[improvement.ElementType] = improvement.ID
 	}
 
 	responseData := fiber.Map{
-		"improvements": response,
-		"status":       generationStatus,
-		"model":        improvements[0].LLMModel,
-		"created_at":   improvements[0].CreatedAt,
+		"improvements":    response,
+		"improvement_ids": ids,
+		"status":          generationStatus,
+		"model":           improvements[0].LLMModel,
+		"created_at":      improvements[0].CreatedAt,
+	}
+	var analysis models.Analysis
+	if err := h.AnalysisRepo.FindByID(analysisID, &analysis); err == nil {
+		if corrections := metadataValue(analysis.Metadata, "glossary_corrections"); corrections != nil {
+			responseData["glossary_corrections"] = corrections
+		}
 	}
 
 	// Cache the response for a longer time since content is completed
-	if h.RedisClient != nil && generationStatus == "completed" {
-		h.RedisClient.Set(cacheKey, responseData, 1*time.Hour) // Cache for 1 hour
+	if h.Cache != nil && generationStatus == "completed" {
+		h.Cache.Set(cacheKey, responseData, 1*time.Hour) // Cache for 1 hour
 	}
 
 	return c.JSON(fiber.Map{
@@ -535,9 +612,9 @@ func (h *ContentImprovementHandler) GetCodeSnippets(c *fiber.Ctx) error {
 	cacheKey := "code_snippets:" + analysisID.String()
 
 	// Try to get from cache if Redis is available
-	if h.RedisClient != nil {
+	if h.Cache != nil {
 		var cachedResponse map[string]interface{}
-		err := h.RedisClient.Get(cacheKey, &cachedResponse)
+		err := h.Cache.Get(cacheKey, &cachedResponse)
 		if err == nil && cachedResponse != nil {
 			return c.JSON(fiber.Map{
 				"success": true,
@@ -600,8 +677,8 @@ func (h *ContentImprovementHandler) GetCodeSnippets(c *fiber.Ctx) error {
 		}
 
 		// Cache this response briefly
-		if h.RedisClient != nil {
-			h.RedisClient.Set(cacheKey, noSnippetsResponse, 1*time.Minute) // Short cache - only 1 minute
+		if h.Cache != nil {
+			h.Cache.Set(cacheKey, noSnippetsResponse, 1*time.Minute) // Short cache - only 1 minute
 		}
 
 		return c.JSON(fiber.Map{
@@ -631,10 +708,16 @@ func (h *ContentImprovementHandler) GetCodeSnippets(c *fiber.Ctx) error {
 		"model":      model,
 		"created_at": createdAt,
 	}
+	var analysis models.Analysis
+	if err := h.AnalysisRepo.FindByID(analysisID, &analysis); err == nil {
+		if corrections := metadataValue(analysis.Metadata, "snippet_glossary_corrections"); corrections != nil {
+			responseData["glossary_corrections"] = corrections
+		}
+	}
 
 	// Cache the response
-	if h.RedisClient != nil {
-		h.RedisClient.Set(cacheKey, responseData, 1*time.Hour) // Cache for 1 hour
+	if h.Cache != nil {
+		h.Cache.Set(cacheKey, responseData, 1*time.Hour) // Cache for 1 hour
 	}
 
 	return c.JSON(fiber.Map{
@@ -645,7 +728,7 @@ func (h *ContentImprovementHandler) GetCodeSnippets(c *fiber.Ctx) error {
 
 // GenerateCodeSnippets generates code snippets based on analysis results
 // @Summary Generate code snippets
-// @Description Generate code snippets based on analysis results
+// @Description Generate code snippets based on analysis results. The model, temperature and max_tokens are validated against the capabilities of the provider; unset values fall back to the organization's LLM defaults
 // @Tags code-snippets
 // @Accept json
 // @Produce json
@@ -654,6 +737,7 @@ func (h *ContentImprovementHandler) GetCodeSnippets(c *fiber.Ctx) error {
 // @Success 202 {object} handlers.SuccessResponse "Code snippet generation initiated"
 // @Failure 400 {object} handlers.ErrorResponse "Invalid request"
 // @Failure 401 {object} handlers.ErrorResponse "Unauthorized"
+// @Failure 402 {object} handlers.ErrorResponse "Monthly LLM generation quota of the plan exhausted"
 // @Failure 403 {object} handlers.ErrorResponse "Forbidden"
 // @Failure 404 {object} handlers.ErrorResponse "Analysis not found"
 // @Failure 500 {object} handlers.ErrorResponse "Server error"
@@ -705,6 +789,19 @@ func (h *ContentImprovementHandler) GenerateCodeSnippets(c *fiber.Ctx) error {
 		})
 	}
 
+	// Resolve the model and parameters against the provider's capabilities
+	providerName, params, err := h.generationParams(analysis.UserID, req.ProviderName, llm.GenerationParams{
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
 	// Get website data
 	var website models.Website
 	if err := h.WebsiteRepo.FindByID(analysis.WebsiteID, &website); err != nil {
@@ -737,13 +834,22 @@ func (h *ContentImprovementHandler) GenerateCodeSnippets(c *fiber.Ctx) error {
 		TargetAudience:  req.TargetAudience,
 	}
 
+	if !enforceQuota(c, h.Quota, billing.ResourceLLMGenerations) {
+		return nil
+	}
+	if h.Quota != nil {
+		if err := h.Quota.Record(c.Context(), c.Locals("userID").(uuid.UUID), billing.ResourceLLMGenerations); err != nil {
+			fmt.Println("Failed to record LLM generation:", err)
+		}
+	}
+
 	// Mark this analysis ID as having an active request
 	h.activeRequests.Store(analysisID.String(), true)
 
 	// Start code generation in the background
 	go func() {
 		defer h.activeRequests.Delete(analysisID.String())
-		h.generateCodeSnippets(analysisID, contentRequest, req.ProviderName, req.SnippetTypes)
+		h.generateCodeSnippets(analysisID, analysis.UserID, contentRequest, providerName, params, req.SnippetTypes, chaos.FromContext(c.UserContext()))
 	}()
 
 	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
@@ -759,12 +865,15 @@ func (h *ContentImprovementHandler) GenerateCodeSnippets(c *fiber.Ctx) error {
 // generateCodeSnippets handles the background generation of code snippets
 func (h *ContentImprovementHandler) generateCodeSnippets(
 	analysisID uuid.UUID,
+	userID uuid.UUID,
 	request *llm.ContentRequest,
 	providerName string,
+	params llm.GenerationParams,
 	snippetTypes []string,
+	faults chaos.Faults,
 ) {
 	// Set timeout for generation
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx, cancel := context.WithTimeout(llm.WithParams(chaos.WithFaults(context.Background(), faults), params), 2*time.Minute)
 	defer cancel()
 
 	// Validate provider
@@ -785,13 +894,25 @@ func (h *ContentImprovementHandler) generateCodeSnippets(
 	}
 
 	// Generate each requested snippet type
+	var corrections []glossary.Replacement
+	defer func() { h.recordGlossaryCorrections(analysisID, "snippet_glossary_corrections", corrections) }()
 	for _, snippetType := range snippetTypes {
 		// Generate the snippet
-		snippet, err := h.generateSnippet(ctx, request, providerName, snippetType)
+		snippet, servedBy, err := h.generateSnippet(ctx, request, providerName, snippetType)
 		if err != nil {
 			fmt.Printf("Error generating %s snippet: %v\n", snippetType, err)
 			continue
 		}
+		meterLLMUsage(h.UsageRepo, analysisID, userID, firstNonEmpty(servedBy.Model, servedBy.ProviderUsed), request.Title+request.Content, snippet)
+
+		// Only generated markup carries prose the glossary applies to
+		if snippetType == "html" {
+			generated := &llm.ContentResponse{HTML: snippet}
+			if replacements := h.enforceGlossary(userID, request.Language, generated); replacements != nil {
+				snippet = generated.HTML
+				corrections = glossary.Merge(append(corrections, replacements...))
+			}
+		}
 
 		// Save the snippet
 		improvement := models.ContentImprovement{
@@ -799,7 +920,7 @@ func (h *ContentImprovementHandler) generateCodeSnippets(
 			ElementType:     snippetType,
 			OriginalContent: request.Content,
 			ImprovedContent: snippet,
-			LLMModel:        providerName,
+			LLMModel:        llmModelLabel(servedBy.ProviderUsed, servedBy.Model),
 		}
 
 		if err := h.ContentImproveRepo.Create(&improvement); err != nil {
@@ -813,22 +934,22 @@ func (h *ContentImprovementHandler) generateSnippet(
 	request *llm.ContentRequest,
 	providerName string,
 	snippetType string,
-) (string, error) {
+) (string, *llm.ContentResponse, error) {
 	// For HTML, we can use the existing HTML generation
 	if snippetType == "html" {
 		// First generate the content if we don't have it yet
 		contentResponse, err := h.LLMService.GenerateContent(ctx, request, providerName)
 		if err != nil {
-			return "", fmt.Errorf("failed to generate content: %w", err)
+			return "", nil, fmt.Errorf("failed to generate content: %w", err)
 		}
 
-		// Then generate HTML
-		html, err := h.LLMService.GenerateHTML(ctx, request, contentResponse, providerName)
+		// Then generate HTML with the provider that served the content
+		html, err := h.LLMService.GenerateHTML(ctx, request, contentResponse, contentResponse.ProviderUsed)
 		if err != nil {
-			return "", fmt.Errorf("failed to generate HTML: %w", err)
+			return "", nil, fmt.Errorf("failed to generate HTML: %w", err)
 		}
 
-		return html, nil
+		return html, contentResponse, nil
 	}
 
 	// For other snippet types, create a custom prompt based on the snippet type
@@ -837,5 +958,5 @@ func (h *ContentImprovementHandler) generateSnippet(
 	// 1. Use a specialized prompt for each snippet type
 	// 2. Implement proper handling in the LLM service
 
-	return prompt, nil
+	return prompt, &llm.ContentResponse{ProviderUsed: providerName, Model: llm.ParamsFromContext(ctx).Model}, nil
 }
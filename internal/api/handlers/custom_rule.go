@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+)
+
+// CustomRuleRequest is the body of a custom rule create or update request
+type CustomRuleRequest struct {
+	Code     string `json:"code" validate:"required"` // issue type of violations, e.g. cookie_banner
+	Name     string `json:"name" validate:"required"`
+	Kind     string `json:"kind" validate:"required"` // selector_required, selector_forbidden, pattern_required, pattern_forbidden
+	Selector string `json:"selector"`
+	Pattern  string `json:"pattern"` // e.g. /TODO|lorem ipsum/i
+	Severity string `json:"severity" validate:"required"`
+	Message  string `json:"message" validate:"required"`
+	Active   *bool  `json:"active"`
+}
+
+// CustomRuleHandler manages the checks organizations run on their pages
+type CustomRuleHandler struct {
+	CustomRuleRepo repository.CustomRuleRepository
+}
+
+// NewCustomRuleHandler creates a new custom rule handler
+func NewCustomRuleHandler(repoFactory *repository.Factory) *CustomRuleHandler {
+	return &CustomRuleHandler{
+		CustomRuleRepo: repoFactory.CustomRuleRepository,
+	}
+}
+
+// ListCustomRules returns the custom rules of an organization
+// @Summary List custom rules
+// @Description Returns the checks an organization runs on every page it analyzes. Organizations are user accounts; non-admins can only manage their own
+// @Tags rules
+// @Produce json
+// @Param id path string true "Organization (user) ID"
+// @Success 200 {object} map[string]interface{} "Custom rules"
+// @Failure 400 {object} map[string]interface{} "Invalid organization ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /organizations/{id}/rules [get]
+func (h *CustomRuleHandler) ListCustomRules(c *fiber.Ctx) error {
+	organizationID, status, message := organizationParam(c)
+	if organizationID == uuid.Nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	rules, err := h.CustomRuleRepo.FindByUserID(organizationID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load custom rules: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    rules,
+	})
+}
+
+// CreateCustomRule adds a check to an organization's analyses
+// @Summary Create a custom rule
+// @Description Adds a check run as the custom_rules analyzer on every page the organization analyzes. Selector rules require or forbid elements matching a CSS selector; pattern rules require or forbid matches of a regular expression in the HTML, written as an RE2 expression or as /expression/flags with the flags i, m and s. Violations are reported as issues of the rule's code with its severity and message
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization (user) ID"
+// @Param rule body CustomRuleRequest true "Custom rule"
+// @Success 201 {object} map[string]interface{} "Custom rule created"
+// @Failure 400 {object} map[string]interface{} "Invalid rule"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 409 {object} map[string]interface{} "A rule with this code exists"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /organizations/{id}/rules [post]
+func (h *CustomRuleHandler) CreateCustomRule(c *fiber.Ctx) error {
+	organizationID, status, message := organizationParam(c)
+	if organizationID == uuid.Nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	req := new(CustomRuleRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+
+	existing, err := h.CustomRuleRepo.FindByUserID(organizationID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load custom rules: " + err.Error(),
+		})
+	}
+	if len(existing) >= analyzer.MaxCustomRules {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Too many custom rules",
+		})
+	}
+
+	rule := &models.CustomRule{UserID: organizationID, Active: true}
+	if status, err := h.applyCustomRule(rule, req); err != nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	if err := h.CustomRuleRepo.Create(rule); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to create custom rule: " + err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data":    rule,
+	})
+}
+
+// UpdateCustomRule replaces a custom rule
+// @Summary Update a custom rule
+// @Description Replaces the definition of a custom rule. Omitting active keeps the rule's current state
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization (user) ID"
+// @Param ruleID path string true "Rule ID"
+// @Param rule body CustomRuleRequest true "Custom rule"
+// @Success 200 {object} map[string]interface{} "Custom rule updated"
+// @Failure 400 {object} map[string]interface{} "Invalid rule"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Rule not found"
+// @Failure 409 {object} map[string]interface{} "A rule with this code exists"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /organizations/{id}/rules/{ruleID} [put]
+func (h *CustomRuleHandler) UpdateCustomRule(c *fiber.Ctx) error {
+	organizationID, status, message := organizationParam(c)
+	if organizationID == uuid.Nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+	rule, status, message := h.findCustomRule(c, organizationID)
+	if rule == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	req := new(CustomRuleRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+	if status, err := h.applyCustomRule(rule, req); err != nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	if err := h.CustomRuleRepo.Update(rule); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to update custom rule: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    rule,
+	})
+}
+
+// DeleteCustomRule removes a custom rule
+// @Summary Delete a custom rule
+// @Description Removes a custom rule; issues it reported on past analyses are kept
+// @Tags rules
+// @Produce json
+// @Param id path string true "Organization (user) ID"
+// @Param ruleID path string true "Rule ID"
+// @Success 200 {object} map[string]interface{} "Custom rule deleted"
+// @Failure 400 {object} map[string]interface{} "Invalid ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Rule not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /organizations/{id}/rules/{ruleID} [delete]
+func (h *CustomRuleHandler) DeleteCustomRule(c *fiber.Ctx) error {
+	organizationID, status, message := organizationParam(c)
+	if organizationID == uuid.Nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+	rule, status, message := h.findCustomRule(c, organizationID)
+	if rule == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	if err := h.CustomRuleRepo.Delete(rule); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to delete custom rule: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Custom rule deleted",
+	})
+}
+
+// organizationParam parses the organization of the route and checks that the
+// user may manage it
+func organizationParam(c *fiber.Ctx) (uuid.UUID, int, string) {
+	organizationID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return uuid.Nil, fiber.StatusBadRequest, "Invalid organization ID"
+	}
+
+	role, _ := c.Locals("role").(string)
+	if userID, _ := c.Locals("userID").(uuid.UUID); role != "admin" && userID != organizationID {
+		return uuid.Nil, fiber.StatusForbidden, "Forbidden, you can only access your own organization"
+	}
+	return organizationID, fiber.StatusOK, ""
+}
+
+// findCustomRule loads the rule of the route that belongs to the organization
+func (h *CustomRuleHandler) findCustomRule(c *fiber.Ctx, organizationID uuid.UUID) (*models.CustomRule, int, string) {
+	ruleID, err := uuid.Parse(c.Params("ruleID"))
+	if err != nil {
+		return nil, fiber.StatusBadRequest, "Invalid rule ID"
+	}
+
+	rule, err := h.CustomRuleRepo.FindForUser(organizationID, ruleID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.StatusNotFound, "Custom rule not found"
+	}
+	if err != nil {
+		return nil, fiber.StatusInternalServerError, "Failed to load custom rule: " + err.Error()
+	}
+	return rule, fiber.StatusOK, ""
+}
+
+// applyCustomRule validates a request and copies it onto the rule, returning
+// the status of the response when it is rejected
+func (h *CustomRuleHandler) applyCustomRule(rule *models.CustomRule, req *CustomRuleRequest) (int, error) {
+	definition := analyzer.CustomRule{
+		Code:     strings.TrimSpace(req.Code),
+		Name:     strings.TrimSpace(req.Name),
+		Kind:     req.Kind,
+		Selector: strings.TrimSpace(req.Selector),
+		Pattern:  req.Pattern,
+		Severity: req.Severity,
+		Message:  strings.TrimSpace(req.Message),
+	}
+	if err := analyzer.ValidateCustomRule(definition); err != nil {
+		return fiber.StatusBadRequest, err
+	}
+
+	exists, err := h.CustomRuleRepo.CodeExists(rule.UserID, definition.Code, rule.ID)
+	if err != nil {
+		return fiber.StatusInternalServerError, errors.New("Failed to check rule code: " + err.Error())
+	}
+	if exists {
+		return fiber.StatusConflict, errors.New("A custom rule with this code already exists")
+	}
+
+	rule.Code = definition.Code
+	rule.Name = definition.Name
+	rule.Kind = definition.Kind
+	rule.Selector, rule.Pattern = "", ""
+	switch definition.Kind {
+	case analyzer.RuleSelectorRequired, analyzer.RuleSelectorForbidden:
+		rule.Selector = definition.Selector
+	default:
+		rule.Pattern = definition.Pattern
+	}
+	rule.Severity = definition.Severity
+	rule.Message = definition.Message
+	if req.Active != nil {
+		rule.Active = *req.Active
+	}
+	return fiber.StatusOK, nil
+}
+
+// customRules loads the active custom rules of a user for an analysis.
+// Analyses run without them if they cannot be loaded.
+func (a *AnalysisHandler) customRules(userID uuid.UUID) []analyzer.CustomRule {
+	if a.CustomRuleRepo == nil {
+		return nil
+	}
+	records, err := a.CustomRuleRepo.FindActiveByUserID(userID)
+	if err != nil {
+		log.Printf("Failed to load custom rules of user %s: %v", userID, err)
+		return nil
+	}
+
+	rules := make([]analyzer.CustomRule, 0, len(records))
+	for _, record := range records {
+		rules = append(rules, analyzer.CustomRule{
+			Code:     record.Code,
+			Name:     record.Name,
+			Kind:     record.Kind,
+			Selector: record.Selector,
+			Pattern:  record.Pattern,
+			Severity: record.Severity,
+			Message:  record.Message,
+		})
+	}
+	return rules
+}
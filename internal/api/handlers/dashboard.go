@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/cache"
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/billing"
+)
+
+const (
+	// dashboardCacheTTL bounds how stale a dashboard can be. It is short
+	// because the dashboard shows the progress of running analyses.
+	dashboardCacheTTL = 15 * time.Second
+
+	dashboardLatestAnalyses = 10
+	dashboardOpenAlerts     = 20
+)
+
+// DashboardAnalysis is a completed analysis on the dashboard
+type DashboardAnalysis struct {
+	ID           uuid.UUID `json:"id"`
+	WebsiteID    uuid.UUID `json:"website_id"`
+	URL          string    `json:"url"`
+	OverallScore *float64  `json:"overall_score,omitempty"`
+	CompletedAt  time.Time `json:"completed_at"`
+}
+
+// DashboardRun is a queued, running or paused analysis and its last
+// checkpointed progress
+type DashboardRun struct {
+	ID        uuid.UUID          `json:"id"`
+	WebsiteID uuid.UUID          `json:"website_id"`
+	URL       string             `json:"url"`
+	Status    string             `json:"status"`
+	Progress  float64            `json:"progress"`
+	Message   string             `json:"message,omitempty"`
+	Analyzers map[string]float64 `json:"analyzers,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// Dashboard is everything the main UI shows for a user
+type Dashboard struct {
+	LatestAnalyses  []DashboardAnalysis    `json:"latest_analyses"`
+	RunningAnalyses []DashboardRun         `json:"running_analyses"`
+	OpenAlerts      []repository.OpenAlert `json:"open_alerts"`
+	Quotas          []*billing.QuotaStatus `json:"quotas"`
+	GeneratedAt     time.Time              `json:"generated_at"`
+}
+
+type DashboardHandler struct {
+	AnalysisRepo      repository.AnalysisRepository
+	AnalysisEventRepo repository.AnalysisEventRepository
+	Quota             *billing.Quota
+	Cache             cache.Cache
+}
+
+// NewDashboardHandler creates a new dashboard handler
+func NewDashboardHandler(repoFactory *repository.Factory, cacheStore cache.Cache, quota *billing.Quota) *DashboardHandler {
+	return &DashboardHandler{
+		AnalysisRepo:      repoFactory.AnalysisRepository,
+		AnalysisEventRepo: repoFactory.AnalysisEventRepository,
+		Quota:             quota,
+		Cache:             cacheStore,
+	}
+}
+
+// GetDashboard returns everything the main UI needs in one call
+// @Summary Get dashboard
+// @Description Returns the user's latest completed analyses with their overall scores, the analyses that are queued, running or paused with their last checkpointed progress, the open alerts raised by the latest analysis of each monitored website, and the quota usage of the plan. The dashboard is cached for 15 seconds
+// @Tags dashboard
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Dashboard"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /dashboard [get]
+func (h *DashboardHandler) GetDashboard(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	cacheKey := "dashboard:" + userID.String()
+	if h.Cache != nil {
+		var cached Dashboard
+		if err := h.Cache.Get(cacheKey, &cached); err == nil {
+			return c.JSON(fiber.Map{
+				"success": true,
+				"data":    cached,
+				"cached":  true,
+			})
+		}
+	}
+
+	dashboard, err := h.buildDashboard(c, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to build dashboard: " + err.Error(),
+		})
+	}
+
+	if h.Cache != nil {
+		h.Cache.Set(cacheKey, dashboard, dashboardCacheTTL)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    dashboard,
+	})
+}
+
+// buildDashboard assembles the dashboard of a user from the repositories
+func (h *DashboardHandler) buildDashboard(c *fiber.Ctx, userID uuid.UUID) (*Dashboard, error) {
+	dashboard := &Dashboard{
+		LatestAnalyses:  []DashboardAnalysis{},
+		RunningAnalyses: []DashboardRun{},
+		OpenAlerts:      []repository.OpenAlert{},
+		Quotas:          []*billing.QuotaStatus{},
+		GeneratedAt:     time.Now(),
+	}
+
+	latest, err := h.AnalysisRepo.FindLatestCompletedByUserID(userID, dashboardLatestAnalyses)
+	if err != nil {
+		return nil, err
+	}
+	for _, analysis := range latest {
+		dashboard.LatestAnalyses = append(dashboard.LatestAnalyses, DashboardAnalysis{
+			ID:           analysis.ID,
+			WebsiteID:    analysis.WebsiteID,
+			URL:          analysis.Website.URL,
+			OverallScore: overallScore(analysis),
+			CompletedAt:  analysis.CompletedAt,
+		})
+	}
+
+	active, err := h.AnalysisRepo.FindActiveByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, analysis := range active {
+		dashboard.RunningAnalyses = append(dashboard.RunningAnalyses, dashboardRun(analysis))
+	}
+
+	alerts, err := h.AnalysisEventRepo.FindOpenAlerts(userID, dashboardOpenAlerts)
+	if err != nil {
+		return nil, err
+	}
+	if alerts != nil {
+		dashboard.OpenAlerts = alerts
+	}
+
+	if h.Quota != nil {
+		if dashboard.Quotas, err = h.Quota.Statuses(c.Context(), userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return dashboard, nil
+}
+
+// dashboardRun reads the progress checkpoint of an active analysis
+func dashboardRun(analysis *models.Analysis) DashboardRun {
+	run := DashboardRun{
+		ID:        analysis.ID,
+		WebsiteID: analysis.WebsiteID,
+		URL:       analysis.Website.URL,
+		Status:    analysis.Status,
+		CreatedAt: analysis.CreatedAt,
+	}
+
+	var checkpoint struct {
+		OverallProgress float64            `json:"overall_progress"`
+		Message         string             `json:"message"`
+		Analyzers       map[string]float64 `json:"analyzers"`
+	}
+	if raw := metadataValue(analysis.Metadata, "progress"); raw != nil && json.Unmarshal(raw, &checkpoint) == nil {
+		run.Progress = checkpoint.OverallProgress
+		run.Message = checkpoint.Message
+		run.Analyzers = checkpoint.Analyzers
+	}
+	return run
+}
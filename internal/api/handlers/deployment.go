@@ -0,0 +1,585 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/billing"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/deploy"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/queue"
+)
+
+// Sources of deployment markers
+const (
+	deploymentSourceAPI    = "api"
+	deploymentSourceGitHub = "github"
+)
+
+// deployAnalysisMaxAge is how long after a deployment a post-deploy analysis
+// is still started. Older markers are recorded for the score history only.
+const deployAnalysisMaxAge = 24 * time.Hour
+
+// deployImpactThreshold is the overall score change, in points, above which a
+// deployment counts as an improvement or a regression
+const deployImpactThreshold = 1.0
+
+// DeploymentRequest is the body of a deployment marker request
+type DeploymentRequest struct {
+	CommitSHA   string     `json:"commit_sha" validate:"required"`
+	Ref         string     `json:"ref"`
+	Repository  string     `json:"repository"` // e.g. acme/website
+	Environment string     `json:"environment"`
+	Description string     `json:"description"`
+	DeployedAt  *time.Time `json:"deployed_at"` // defaults to now
+	Analyze     *bool      `json:"analyze"`     // start a post-deploy analysis, defaults to true
+}
+
+// DeployImpact compares the post-deploy analysis of a deployment with the
+// last analysis before it
+type DeployImpact struct {
+	BaselineAnalysisID *uuid.UUID            `json:"baseline_analysis_id,omitempty"`
+	AnalysisID         uuid.UUID             `json:"analysis_id"`
+	Verdict            string                `json:"verdict"` // improved, regressed, unchanged, no_baseline
+	Summary            string                `json:"summary"`
+	Overall            AnalysisScoreChange   `json:"overall"`
+	Categories         []AnalysisScoreChange `json:"categories"`
+	NewIssues          []AnalysisIssueChange `json:"new_issues"`
+	ResolvedIssues     []AnalysisIssueChange `json:"resolved_issues"`
+}
+
+// CreateDeployment records a deployment marker of a website
+// @Summary Record a deployment
+// @Description Records a deployment of a website by commit SHA, shown as a marker on the website's score history. Unless analyze is false, a post-deploy analysis is started and compared with the last completed analysis before the deployment; the deploy-impact summary is stored on the marker when it completes. Monitored sites are analyzed with their preset. Markers of deployments older than a day are recorded without an analysis
+// @Tags websites
+// @Accept json
+// @Produce json
+// @Param id path string true "Website ID"
+// @Param deployment body DeploymentRequest true "Deployment"
+// @Success 201 {object} map[string]interface{} "Deployment recorded"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Website not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /websites/{id}/deployments [post]
+func (h *AnalysisHandler) CreateDeployment(c *fiber.Ctx) error {
+	website, status, message := h.findWebsite(c)
+	if website == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	req := new(DeploymentRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+	req.CommitSHA = strings.TrimSpace(req.CommitSHA)
+	if req.CommitSHA == "" || len(req.CommitSHA) > 64 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "commit_sha is required and must be at most 64 characters",
+		})
+	}
+	deployedAt := time.Now()
+	if req.DeployedAt != nil {
+		if req.DeployedAt.After(deployedAt.Add(5 * time.Minute)) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "deployed_at must not be in the future",
+			})
+		}
+		deployedAt = *req.DeployedAt
+	}
+
+	deployment := &models.Deployment{
+		UserID:      c.Locals("userID").(uuid.UUID),
+		WebsiteID:   website.ID,
+		CommitSHA:   req.CommitSHA,
+		Ref:         req.Ref,
+		Repository:  req.Repository,
+		Environment: req.Environment,
+		Description: req.Description,
+		Source:      deploymentSourceAPI,
+		DeployedAt:  deployedAt,
+	}
+	analyze := req.Analyze == nil || *req.Analyze
+	analysisErr, err := h.recordDeployment(c.Context(), deployment, website, analyze)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to record deployment: " + err.Error(),
+		})
+	}
+
+	response := fiber.Map{
+		"success": true,
+		"data":    deployment,
+	}
+	if analysisErr != nil {
+		response["analysis_error"] = analysisErr.Error()
+	}
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// ListDeployments returns the deployment markers of a website
+// @Summary List deployments
+// @Description Returns the deployments of a website recorded by the user, oldest first, with their baseline and post-deploy analyses and deploy-impact summaries
+// @Tags websites
+// @Produce json
+// @Param id path string true "Website ID"
+// @Param from query string false "Start of the range (RFC 3339 or YYYY-MM-DD), defaults to 90 days ago"
+// @Success 200 {object} map[string]interface{} "Deployments"
+// @Failure 400 {object} map[string]interface{} "Invalid website ID or query"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Website not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /websites/{id}/deployments [get]
+func (h *AnalysisHandler) ListDeployments(c *fiber.Ctx) error {
+	website, status, message := h.findWebsite(c)
+	if website == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	from, err := parseUsageDate(c.Query("from"), time.Now().AddDate(0, 0, -90))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid from date",
+		})
+	}
+
+	deployments, err := h.DeploymentRepo.FindByWebsite(c.Locals("userID").(uuid.UUID), website.ID, from)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load deployments: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    deployments,
+	})
+}
+
+// GetWebsiteScoreHistory returns the score history of a website with its
+// deployment markers
+// @Summary Get website score history
+// @Description Returns the overall scores of the user's completed analyses of a website, oldest first, together with the deployments in the same range so releases can be overlaid on the score chart
+// @Tags websites
+// @Produce json
+// @Param id path string true "Website ID"
+// @Param from query string false "Start of the range (RFC 3339 or YYYY-MM-DD), defaults to 90 days ago"
+// @Param to query string false "End of the range (RFC 3339 or YYYY-MM-DD), defaults to now"
+// @Success 200 {object} map[string]interface{} "Score history"
+// @Failure 400 {object} map[string]interface{} "Invalid website ID or query"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Website not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /websites/{id}/score-history [get]
+func (h *AnalysisHandler) GetWebsiteScoreHistory(c *fiber.Ctx) error {
+	website, status, message := h.findWebsite(c)
+	if website == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	now := time.Now()
+	from, err := parseUsageDate(c.Query("from"), now.AddDate(0, 0, -90))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid from date",
+		})
+	}
+	to, err := parseUsageDate(c.Query("to"), now)
+	if err != nil || !to.After(from) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid to date",
+		})
+	}
+
+	userID := c.Locals("userID").(uuid.UUID)
+	scores, err := h.KeywordRepo.PageScores(userID, website.URL, from, to)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load scores: " + err.Error(),
+		})
+	}
+	deployments, err := h.DeploymentRepo.FindByWebsite(userID, website.ID, from)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load deployments: " + err.Error(),
+		})
+	}
+	markers := make([]models.Deployment, 0, len(deployments))
+	for _, deployment := range deployments {
+		if deployment.DeployedAt.Before(to) {
+			markers = append(markers, deployment)
+		}
+	}
+	if scores == nil {
+		scores = []repository.PageScore{}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"website_id":  website.ID,
+			"url":         website.URL,
+			"from":        from,
+			"to":          to,
+			"scores":      scores,
+			"deployments": markers,
+		},
+	})
+}
+
+// CreateDeploymentHook creates the GitHub webhook of a website
+// @Summary Create a GitHub deployment webhook
+// @Description Returns the URL and secret of a GitHub webhook recording deployments of the website. Configure it in the repository with the content type application/json and the "Deployment statuses" event, or the "Pushes" event for repositories deployed on every push to the default branch. Calling it again rotates the secret; the secret is only shown in this response
+// @Tags websites
+// @Produce json
+// @Param id path string true "Website ID"
+// @Success 200 {object} map[string]interface{} "Webhook"
+// @Failure 400 {object} map[string]interface{} "Invalid website ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Website not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /websites/{id}/deployments/hook [post]
+func (h *AnalysisHandler) CreateDeploymentHook(c *fiber.Ctx) error {
+	website, status, message := h.findWebsite(c)
+	if website == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to generate webhook secret",
+		})
+	}
+
+	userID := c.Locals("userID").(uuid.UUID)
+	hook, err := h.DeploymentRepo.FindHook(userID, website.ID)
+	if err != nil {
+		hook = &models.DeploymentHook{UserID: userID, WebsiteID: website.ID}
+	}
+	hook.Secret = hex.EncodeToString(secret)
+	if err := h.DeploymentRepo.SaveHook(hook); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to save webhook: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"id":           hook.ID,
+			"website_id":   website.ID,
+			"url":          c.BaseURL() + "/api/deployments/github/" + hook.ID.String(),
+			"secret":       hook.Secret,
+			"content_type": "application/json",
+			"events":       []string{"deployment_status", "push"},
+		},
+	})
+}
+
+// GitHubDeploymentWebhook records deployments reported by GitHub
+// @Summary GitHub deployment webhook
+// @Description Receives GitHub deployment_status and push events for the website of a deployment webhook. Requests are authenticated with the X-Hub-Signature-256 header. Successful deployment statuses and pushes to the default branch are recorded as deployments and analyzed; other events are acknowledged and ignored
+// @Tags websites
+// @Accept json
+// @Produce json
+// @Param hookID path string true "Deployment webhook ID"
+// @Success 200 {object} map[string]interface{} "Event ignored"
+// @Success 201 {object} map[string]interface{} "Deployment recorded"
+// @Failure 400 {object} map[string]interface{} "Invalid payload"
+// @Failure 401 {object} map[string]interface{} "Invalid signature"
+// @Failure 404 {object} map[string]interface{} "Webhook not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /deployments/github/{hookID} [post]
+func (h *AnalysisHandler) GitHubDeploymentWebhook(c *fiber.Ctx) error {
+	hookID, err := uuid.Parse(c.Params("hookID"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Webhook not found",
+		})
+	}
+	var hook models.DeploymentHook
+	if err := h.DeploymentRepo.FindByID(hookID, &hook); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Webhook not found",
+		})
+	}
+
+	if err := deploy.VerifyGitHubSignature(c.Body(), c.Get("X-Hub-Signature-256"), hook.Secret); err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	marker, err := deploy.ParseGitHubEvent(c.Get("X-GitHub-Event"), c.Body())
+	if errors.Is(err, deploy.ErrIgnored) {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"ignored": true,
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	var website models.Website
+	if err := h.WebsiteRepo.FindByID(hook.WebsiteID, &website); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Website not found",
+		})
+	}
+
+	deployment := &models.Deployment{
+		UserID:      hook.UserID,
+		WebsiteID:   hook.WebsiteID,
+		CommitSHA:   marker.CommitSHA,
+		Ref:         marker.Ref,
+		Repository:  marker.Repository,
+		Environment: marker.Environment,
+		Description: marker.Description,
+		Source:      deploymentSourceGitHub,
+		DeployedAt:  marker.DeployedAt,
+	}
+	analysisErr, err := h.recordDeployment(c.Context(), deployment, &website, true)
+	if err != nil {
+		log.Printf("Failed to record deployment %s of %s: %v", marker.CommitSHA, website.URL, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to record deployment",
+		})
+	}
+	if analysisErr != nil {
+		log.Printf("Failed to start post-deploy analysis of %s: %v", website.URL, analysisErr)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data":    deployment,
+	})
+}
+
+// findWebsite loads the website of the :id route parameter
+func (h *AnalysisHandler) findWebsite(c *fiber.Ctx) (*models.Website, int, string) {
+	websiteID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.StatusBadRequest, "Invalid website ID"
+	}
+	var website models.Website
+	if err := h.WebsiteRepo.FindByID(websiteID, &website); err != nil {
+		return nil, fiber.StatusNotFound, "Website not found"
+	}
+	return &website, 0, ""
+}
+
+// recordDeployment stores a deployment marker and, when analyze is set and the
+// deployment is recent, starts its post-deploy analysis. analysisErr reports
+// why the analysis could not be started; the marker is kept regardless.
+func (h *AnalysisHandler) recordDeployment(ctx context.Context, deployment *models.Deployment, website *models.Website, analyze bool) (analysisErr error, err error) {
+	if baseline, err := h.AnalysisRepo.FindLatestCompletedBefore(deployment.UserID, website.ID, deployment.DeployedAt); err == nil {
+		deployment.BaselineAnalysisID = &baseline.ID
+	}
+	if err := h.DeploymentRepo.Create(deployment); err != nil {
+		return nil, err
+	}
+	if !analyze || time.Since(deployment.DeployedAt) > deployAnalysisMaxAge {
+		return nil, nil
+	}
+
+	analysisID, analysisErr := h.startDeployAnalysis(ctx, deployment.UserID, website.URL)
+	if analysisErr != nil {
+		return analysisErr, nil
+	}
+	deployment.AnalysisID = analysisID
+	if err := h.DeploymentRepo.SetAnalysis(deployment.ID, deployment.BaselineAnalysisID, analysisID); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// startDeployAnalysis queues the post-deploy analysis of a page. Monitored
+// sites are analyzed like their scheduled runs, other pages at normal
+// priority without a preset.
+func (h *AnalysisHandler) startDeployAnalysis(ctx context.Context, userID uuid.UUID, pageURL string) (*uuid.UUID, error) {
+	if sites, err := h.MonitoredSiteRepo.FindByUserID(userID); err == nil {
+		for i := range sites {
+			if sites[i].URL == pageURL {
+				return h.startMonitoredAnalysis(ctx, &sites[i])
+			}
+		}
+	}
+
+	if h.Quota != nil {
+		if _, err := h.Quota.Check(ctx, userID, billing.ResourceAnalyses); errors.Is(err, billing.ErrQuotaExceeded) {
+			return nil, fmt.Errorf("analysis quota of the plan has been reached")
+		}
+	}
+	analysis, _, err := h.startAnalysis(userID, pageURL, parser.RequestOverrides{}, nil, queue.PriorityNormal, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &analysis.ID, nil
+}
+
+// recordDeployImpact compares a completed post-deploy analysis with the
+// baseline of its deployments and stores the deploy-impact summary
+func (a *AnalysisHandler) recordDeployImpact(analysisID uuid.UUID) {
+	if a.DeploymentRepo == nil {
+		return
+	}
+	deployments, err := a.DeploymentRepo.FindByAnalysisID(analysisID)
+	if err != nil || len(deployments) == 0 {
+		return
+	}
+
+	var target models.Analysis
+	if err := a.AnalysisRepo.FindByID(analysisID, &target); err != nil {
+		log.Printf("Failed to load post-deploy analysis %s: %v", analysisID, err)
+		return
+	}
+	targetScores, targetIssues, err := a.analysisResults(analysisID)
+	if err != nil {
+		log.Printf("Failed to load results of post-deploy analysis %s: %v", analysisID, err)
+		return
+	}
+
+	for _, deployment := range deployments {
+		impact := a.deployImpact(&deployment, &target, targetScores, targetIssues)
+		encoded, err := json.Marshal(impact)
+		if err != nil {
+			continue
+		}
+		if err := a.DeploymentRepo.SetImpact(deployment.ID, datatypes.JSON(encoded)); err != nil {
+			log.Printf("Failed to store impact of deployment %s: %v", deployment.ID, err)
+		}
+	}
+	if err := a.AnalysisRepo.SetMetadataKey(analysisID, "deployment_id", deployments[0].ID); err != nil {
+		log.Printf("Failed to link analysis %s to its deployment: %v", analysisID, err)
+	}
+}
+
+// deployImpact compares the post-deploy analysis of a deployment with its
+// baseline analysis
+func (a *AnalysisHandler) deployImpact(deployment *models.Deployment, target *models.Analysis, targetScores map[string]*float64, targetIssues []models.Issue) DeployImpact {
+	impact := DeployImpact{
+		BaselineAnalysisID: deployment.BaselineAnalysisID,
+		AnalysisID:         target.ID,
+		Verdict:            "no_baseline",
+		Overall:            scoreChange("overall", nil, overallScore(target)),
+		Categories:         categoryChanges(nil, targetScores),
+		NewIssues:          []AnalysisIssueChange{},
+		ResolvedIssues:     []AnalysisIssueChange{},
+	}
+	sha := shortSHA(deployment.CommitSHA)
+
+	var base models.Analysis
+	if deployment.BaselineAnalysisID == nil || a.AnalysisRepo.FindByID(*deployment.BaselineAnalysisID, &base) != nil {
+		impact.Summary = "No analysis before deploying " + sha + " to compare with"
+		return impact
+	}
+	baseScores, baseIssues, err := a.analysisResults(base.ID)
+	if err != nil {
+		impact.Summary = "Failed to load the analysis before deploying " + sha
+		return impact
+	}
+
+	impact.Overall = scoreChange("overall", overallScore(&base), overallScore(target))
+	impact.Categories = categoryChanges(baseScores, targetScores)
+	impact.NewIssues = issueChanges(targetIssues, baseIssues)
+	impact.ResolvedIssues = issueChanges(baseIssues, targetIssues)
+
+	impact.Verdict = "unchanged"
+	change := 0.0
+	if impact.Overall.Change != nil {
+		change = *impact.Overall.Change
+	}
+	switch {
+	case change >= deployImpactThreshold:
+		impact.Verdict = "improved"
+	case change <= -deployImpactThreshold:
+		impact.Verdict = "regressed"
+	}
+
+	summary := fmt.Sprintf("Overall score %s after deploying %s", describeScoreChange(change), sha)
+	summary += fmt.Sprintf("; %d new and %d resolved issue types", len(impact.NewIssues), len(impact.ResolvedIssues))
+	var worst *AnalysisScoreChange
+	for i := range impact.Categories {
+		category := &impact.Categories[i]
+		if category.Change != nil && *category.Change <= -deployImpactThreshold && (worst == nil || *category.Change < *worst.Change) {
+			worst = category
+		}
+	}
+	if worst != nil {
+		summary += fmt.Sprintf("; largest drop in %s (%.1f points)", worst.Category, *worst.Change)
+	}
+	impact.Summary = summary
+	return impact
+}
+
+// describeScoreChange phrases an overall score change
+func describeScoreChange(change float64) string {
+	switch {
+	case change >= deployImpactThreshold:
+		return fmt.Sprintf("rose %.1f points", change)
+	case change <= -deployImpactThreshold:
+		return fmt.Sprintf("dropped %.1f points", -change)
+	}
+	return "was unchanged"
+}
+
+// shortSHA abbreviates a commit SHA like git does
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/cache"
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+)
+
+// domainDashboardCacheTTL bounds how stale a domain dashboard can be
+const domainDashboardCacheTTL = 5 * time.Minute
+
+type CreateDomainRequest struct {
+	// Name is a domain such as example.com. A "*." prefix or a full URL is accepted
+	Name              string `json:"name" validate:"required"`
+	IncludeSubdomains *bool  `json:"include_subdomains,omitempty"`
+}
+
+type DomainHandler struct {
+	DomainRepo   repository.DomainRepository
+	SnapshotRepo repository.SnapshotRepository
+	Cache        cache.Cache
+}
+
+// NewDomainHandler creates a new domain handler
+func NewDomainHandler(repoFactory *repository.Factory, cacheStore cache.Cache) *DomainHandler {
+	return &DomainHandler{
+		DomainRepo:   repoFactory.DomainRepository,
+		SnapshotRepo: repoFactory.SnapshotRepository,
+		Cache:        cacheStore,
+	}
+}
+
+// CreateDomain registers a domain and groups the existing websites under it
+// @Summary Register a domain
+// @Description Registers a domain so that analyses of any page, and optionally any subdomain, are grouped under it. Existing websites are assigned immediately
+// @Tags domains
+// @Accept json
+// @Produce json
+// @Param domain body CreateDomainRequest true "Domain"
+// @Success 201 {object} map[string]interface{} "Domain registered"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 409 {object} map[string]interface{} "Domain already registered"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /domains [post]
+func (h *DomainHandler) CreateDomain(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	req := new(CreateDomainRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+
+	name := strings.TrimSpace(req.Name)
+	wildcard := strings.HasPrefix(name, "*.")
+	name, err := urlnorm.Hostname(strings.TrimPrefix(name, "*."))
+	if err != nil || !strings.Contains(name, ".") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid domain name",
+		})
+	}
+
+	includeSubdomains := true
+	if req.IncludeSubdomains != nil {
+		includeSubdomains = *req.IncludeSubdomains
+	}
+	if wildcard {
+		includeSubdomains = true
+	}
+
+	if _, err := h.DomainRepo.FindByName(name); err == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"success": false,
+			"error":   "Domain " + name + " is already registered",
+		})
+	}
+
+	domain := models.Domain{
+		Name:              name,
+		IncludeSubdomains: includeSubdomains,
+		CreatedBy:         userID,
+	}
+	if err := h.DomainRepo.Create(&domain); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to create domain: " + err.Error(),
+		})
+	}
+
+	assigned, err := h.DomainRepo.SyncWebsites(name)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Domain created but websites could not be assigned: " + err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"domain":            domain,
+			"websites_assigned": assigned,
+		},
+	})
+}
+
+// ListDomains returns the registered domains
+// @Summary List domains
+// @Description Returns the registered domains with pagination
+// @Tags domains
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Number of items per page" default(10)
+// @Success 200 {object} map[string]interface{} "Domains list"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /domains [get]
+func (h *DomainHandler) ListDomains(c *fiber.Ctx) error {
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	pageSize := c.QueryInt("per_page", 10)
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	domains, total, err := h.DomainRepo.FindAll(page, pageSize)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch domains: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    domains,
+		"meta": fiber.Map{
+			"total":       total,
+			"page":        page,
+			"per_page":    pageSize,
+			"total_pages": int(math.Ceil(float64(total) / float64(pageSize))),
+		},
+	})
+}
+
+// GetDomainDashboard returns the aggregated analyses of a domain
+// @Summary Get domain dashboard
+// @Description Aggregates all pages of a domain: analysis counts, average scores per analyzer from the latest completed analysis of each page, and a per-page breakdown
+// @Tags domains
+// @Produce json
+// @Param id path string true "Domain ID"
+// @Success 200 {object} map[string]interface{} "Domain dashboard"
+// @Failure 400 {object} map[string]interface{} "Invalid domain ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Domain not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /domains/{id}/dashboard [get]
+func (h *DomainHandler) GetDomainDashboard(c *fiber.Ctx) error {
+	domainID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid domain ID",
+		})
+	}
+
+	var domain models.Domain
+	if err := h.DomainRepo.FindByID(domainID, &domain); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Domain not found",
+		})
+	}
+
+	cacheKey := "domain_dashboard:" + domainID.String()
+	if h.Cache != nil {
+		var cached repository.DomainDashboard
+		if err := h.Cache.Get(cacheKey, &cached); err == nil {
+			return c.JSON(fiber.Map{
+				"success": true,
+				"data": fiber.Map{
+					"domain":    domain,
+					"dashboard": cached,
+				},
+				"cached": true,
+			})
+		}
+	}
+
+	dashboard, err := h.DomainRepo.Dashboard(domainID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to build domain dashboard: " + err.Error(),
+		})
+	}
+
+	if h.Cache != nil {
+		h.Cache.Set(cacheKey, dashboard, domainDashboardCacheTTL)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"domain":    domain,
+			"dashboard": dashboard,
+		},
+	})
+}
+
+// DeleteDomain removes a domain. Its websites move to an enclosing
+// registered domain, if any, and are otherwise ungrouped.
+// @Summary Delete a domain
+// @Description Removes a domain registration. Websites and analyses are kept
+// @Tags domains
+// @Produce json
+// @Param id path string true "Domain ID"
+// @Success 200 {object} map[string]interface{} "Domain deleted"
+// @Failure 400 {object} map[string]interface{} "Invalid domain ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Domain not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /domains/{id} [delete]
+func (h *DomainHandler) DeleteDomain(c *fiber.Ctx) error {
+	domainID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid domain ID",
+		})
+	}
+
+	var domain models.Domain
+	if err := h.DomainRepo.FindByID(domainID, &domain); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Domain not found",
+		})
+	}
+
+	if err := h.DomainRepo.Delete(&domain); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to delete domain: " + err.Error(),
+		})
+	}
+
+	if _, err := h.DomainRepo.SyncWebsites(domain.Name); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Domain deleted but websites could not be regrouped: " + err.Error(),
+		})
+	}
+
+	if h.Cache != nil {
+		h.Cache.Delete("domain_dashboard:" + domainID.String())
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Domain deleted",
+	})
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/templates"
+)
+
+const (
+	// defaultTemplatePages and maxTemplatePages bound the pages clustered
+	defaultTemplatePages = 200
+	maxTemplatePages     = 1000
+	// templateSignatureWorkers limits the snapshots parsed in parallel
+	templateSignatureWorkers = 8
+)
+
+// DomainTemplates is the template breakdown of a domain
+type DomainTemplates struct {
+	PagesCount int                        `json:"pages_count"`
+	Similarity float64                    `json:"similarity"`
+	Templates  []templates.TemplateReport `json:"templates"`
+	Fixes      []templates.Fix            `json:"fixes"`
+}
+
+// GetDomainTemplates clusters the pages of a domain into templates
+// @Summary Get domain templates
+// @Description Groups the analyzed pages of a domain into templates (e.g. product pages, blog posts, category listings) by URL pattern and by the similarity of their DOM structure, taken from the snapshot of the latest completed analysis of each page. Returns the average scores and issues of each template and the template-level fixes, the issues found on at least half of the pages of a template, ordered by the number of pages they affect
+// @Tags domains
+// @Produce json
+// @Param id path string true "Domain ID"
+// @Param similarity query number false "Structural similarity from which pages share a template, 0-1 (default 0.6)"
+// @Param limit query int false "Maximum number of pages clustered, most recently analyzed first (default 200, max 1000)"
+// @Success 200 {object} map[string]interface{} "Domain templates"
+// @Failure 400 {object} map[string]interface{} "Invalid domain ID or parameters"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Domain not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /domains/{id}/templates [get]
+func (h *DomainHandler) GetDomainTemplates(c *fiber.Ctx) error {
+	domainID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid domain ID",
+		})
+	}
+
+	similarity := templates.DefaultSimilarity
+	if value := c.Query("similarity"); value != "" {
+		similarity, err = strconv.ParseFloat(value, 64)
+		if err != nil || similarity <= 0 || similarity > 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "similarity must be a number between 0 and 1",
+			})
+		}
+	}
+	limit := c.QueryInt("limit", defaultTemplatePages)
+	if limit <= 0 || limit > maxTemplatePages {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   fmt.Sprintf("limit must be between 1 and %d", maxTemplatePages),
+		})
+	}
+
+	var domain models.Domain
+	if err := h.DomainRepo.FindByID(domainID, &domain); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Domain not found",
+		})
+	}
+
+	cacheKey := fmt.Sprintf("domain_templates:%s:%g:%d", domainID, similarity, limit)
+	if h.Cache != nil {
+		var cached DomainTemplates
+		if err := h.Cache.Get(cacheKey, &cached); err == nil {
+			return c.JSON(fiber.Map{
+				"success": true,
+				"data": fiber.Map{
+					"domain":    domain,
+					"templates": cached,
+				},
+				"cached": true,
+			})
+		}
+	}
+
+	result, err := h.buildDomainTemplates(domainID, similarity, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to build domain templates: " + err.Error(),
+		})
+	}
+
+	if h.Cache != nil {
+		h.Cache.Set(cacheKey, result, domainDashboardCacheTTL)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"domain":    domain,
+			"templates": result,
+		},
+	})
+}
+
+// buildDomainTemplates clusters the latest analyses of the pages of a domain
+// and summarizes their results per template
+func (h *DomainHandler) buildDomainTemplates(domainID uuid.UUID, similarity float64, limit int) (*DomainTemplates, error) {
+	analyses, err := h.DomainRepo.LatestAnalyses(domainID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load analyses: %w", err)
+	}
+
+	analysisIDs := make([]uuid.UUID, len(analyses))
+	for i, analysis := range analyses {
+		analysisIDs[i] = analysis.AnalysisID
+	}
+	scores, err := h.DomainRepo.AnalysisCategoryScores(analysisIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scores: %w", err)
+	}
+	issues, err := h.DomainRepo.AnalysisIssues(analysisIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load issues: %w", err)
+	}
+
+	results := make(map[string]templates.PageResult, len(analyses))
+	for _, analysis := range analyses {
+		results[analysis.AnalysisID.String()] = templates.PageResult{
+			URL:            analysis.URL,
+			AnalysisID:     analysis.AnalysisID.String(),
+			CategoryScores: make(map[string]float64),
+		}
+	}
+	for _, score := range scores {
+		result := results[score.AnalysisID.String()]
+		result.CategoryScores[score.Category] = score.Score
+	}
+	for id, result := range results {
+		if len(result.CategoryScores) == 0 {
+			continue
+		}
+		var sum float64
+		for _, score := range result.CategoryScores {
+			sum += score
+		}
+		avg := sum / float64(len(result.CategoryScores))
+		result.Score = &avg
+		results[id] = result
+	}
+	for _, issue := range issues {
+		id := issue.AnalysisID.String()
+		result := results[id]
+		result.Issues = append(result.Issues, templates.Issue{
+			Category: issue.Category,
+			Type:     issue.Type,
+			Severity: issue.Severity,
+			Title:    issue.Title,
+		})
+		results[id] = result
+	}
+
+	pages := h.templatePages(analyses)
+	clusters := templates.Cluster(pages, similarity)
+	reports, fixes := templates.Summarize(clusters, results)
+
+	return &DomainTemplates{
+		PagesCount: len(pages),
+		Similarity: similarity,
+		Templates:  reports,
+		Fixes:      fixes,
+	}, nil
+}
+
+// templatePages computes the structure signature of each analyzed page from
+// its rendered DOM snapshot, or the fetched HTML when there is none. Pages
+// without snapshots are clustered by URL only.
+func (h *DomainHandler) templatePages(analyses []repository.DomainPageAnalysis) []templates.Page {
+	pages := make([]templates.Page, len(analyses))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < templateSignatureWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				analysis := analyses[i]
+				pages[i] = templates.Page{
+					ID:        analysis.AnalysisID.String(),
+					URL:       analysis.URL,
+					Signature: h.pageSignature(analysis.AnalysisID),
+				}
+			}
+		}()
+	}
+	for i := range analyses {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return pages
+}
+
+// pageSignature returns the structure signature of an analyzed page
+func (h *DomainHandler) pageSignature(analysisID uuid.UUID) templates.Signature {
+	if h.SnapshotRepo == nil {
+		return nil
+	}
+	for _, kind := range []string{models.SnapshotKindDOM, models.SnapshotKindHTML} {
+		snapshot, err := h.SnapshotRepo.Find(analysisID, kind)
+		if err != nil {
+			continue
+		}
+		content, err := repository.DecompressSnapshot(snapshot)
+		if err != nil {
+			continue
+		}
+		return templates.NewSignature(string(content))
+	}
+	return nil
+}
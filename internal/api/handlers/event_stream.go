@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/stream"
+)
+
+// CreateEventStreamRequest is the body of an event stream create request
+type CreateEventStreamRequest struct {
+	Name  string `json:"name" validate:"required"`
+	Sink  string `json:"sink" validate:"required"` // http or kafka
+	URL   string `json:"url" validate:"required"`  // endpoint or Kafka REST proxy URL
+	Topic string `json:"topic"`                    // required for kafka
+}
+
+// UpdateEventStreamRequest is the body of an event stream update request
+type UpdateEventStreamRequest struct {
+	Active *bool `json:"active"`
+}
+
+type EventStreamHandler struct {
+	EventStreamRepo repository.EventStreamRepository
+}
+
+// NewEventStreamHandler creates a new event stream handler
+func NewEventStreamHandler(repoFactory *repository.Factory) *EventStreamHandler {
+	return &EventStreamHandler{
+		EventStreamRepo: repoFactory.EventStreamRepository,
+	}
+}
+
+// ListEventStreams returns the event streams of the current user
+// @Summary List event streams
+// @Description Returns the user's event streams with the outcome of their latest delivery
+// @Tags streams
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Event streams"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /streams [get]
+func (h *EventStreamHandler) ListEventStreams(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	streams, err := h.EventStreamRepo.FindByUserID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load event streams: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    streams,
+	})
+}
+
+// CreateEventStream registers a sink that receives every persisted metric and issue
+// @Summary Create an event stream
+// @Description Streams every metric and issue persisted by the user's analyses to an external sink. The "http" sink posts NDJSON batches signed with HMAC-SHA256 (X-Stream-Signature: sha256=hex(hmac(secret, timestamp + "." + body))); the "kafka" sink produces records to a topic through a Kafka REST proxy. The signing secret is only returned in this response
+// @Tags streams
+// @Accept json
+// @Produce json
+// @Param stream body CreateEventStreamRequest true "Event stream"
+// @Success 201 {object} map[string]interface{} "Event stream created"
+// @Failure 400 {object} map[string]interface{} "Invalid stream"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /streams [post]
+func (h *EventStreamHandler) CreateEventStream(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	req := new(CreateEventStreamRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+	if err := validateEventStream(c.Context(), req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to generate signing secret",
+		})
+	}
+
+	s := &models.EventStream{
+		UserID: userID,
+		Name:   req.Name,
+		Sink:   req.Sink,
+		URL:    req.URL,
+		Topic:  req.Topic,
+		Secret: hex.EncodeToString(secret),
+		Active: true,
+	}
+	if err := h.EventStreamRepo.Create(s); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to create event stream: " + err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data":    s,
+		"secret":  s.Secret,
+	})
+}
+
+// validateEventStream checks the sink type and its destination. Sinks on
+// private or local addresses are refused.
+func validateEventStream(ctx context.Context, req *CreateEventStreamRequest) error {
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+	switch req.Sink {
+	case models.EventStreamSinkHTTP:
+	case models.EventStreamSinkKafka:
+		if req.Topic == "" {
+			return errors.New("topic is required for the kafka sink")
+		}
+	default:
+		return errors.New("sink must be one of: http, kafka")
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+	return checkPublicURL(ctx, req.URL)
+}
+
+// UpdateEventStream pauses or resumes an event stream
+// @Summary Pause or resume an event stream
+// @Tags streams
+// @Accept json
+// @Produce json
+// @Param id path string true "Event stream ID"
+// @Param stream body UpdateEventStreamRequest true "Stream state"
+// @Success 200 {object} map[string]interface{} "Event stream updated"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Event stream not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /streams/{id} [patch]
+func (h *EventStreamHandler) UpdateEventStream(c *fiber.Ctx) error {
+	s, status, message := h.findStream(c)
+	if s == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	req := new(UpdateEventStreamRequest)
+	if err := c.BodyParser(req); err != nil || req.Active == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Request body must contain \"active\"",
+		})
+	}
+
+	s.Active = *req.Active
+	if err := h.EventStreamRepo.Update(s); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to update event stream: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    s,
+	})
+}
+
+// DeleteEventStream removes an event stream
+// @Summary Delete an event stream
+// @Tags streams
+// @Produce json
+// @Param id path string true "Event stream ID"
+// @Success 200 {object} map[string]interface{} "Event stream deleted"
+// @Failure 400 {object} map[string]interface{} "Invalid ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Event stream not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /streams/{id} [delete]
+func (h *EventStreamHandler) DeleteEventStream(c *fiber.Ctx) error {
+	s, status, message := h.findStream(c)
+	if s == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	if err := h.EventStreamRepo.Delete(s); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to delete event stream: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Event stream deleted",
+	})
+}
+
+// TestEventStream sends a single test event to the stream's sink
+// @Summary Send a test event
+// @Description Delivers one event of type "test" synchronously, without retries. The sink's error is only logged on the server
+// @Tags streams
+// @Produce json
+// @Param id path string true "Event stream ID"
+// @Success 200 {object} map[string]interface{} "Test event delivered"
+// @Failure 400 {object} map[string]interface{} "Invalid ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Event stream not found"
+// @Failure 502 {object} map[string]interface{} "Delivery failed"
+// @Security BearerAuth
+// @Router /streams/{id}/test [post]
+func (h *EventStreamHandler) TestEventStream(c *fiber.Ctx) error {
+	s, status, message := h.findStream(c)
+	if s == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	sink, err := stream.NewSink(stream.SinkConfig{Type: s.Sink, URL: s.URL, Topic: s.Topic, Secret: s.Secret})
+	if err == nil {
+		data, _ := json.Marshal(map[string]string{"message": "Test event from stream " + s.Name})
+		ctx, cancel := context.WithTimeout(c.Context(), 30*time.Second)
+		err = sink.Send(ctx, []stream.Event{{
+			ID:        uuid.New(),
+			Type:      stream.EventTest,
+			Timestamp: time.Now(),
+			Data:      data,
+		}})
+		cancel()
+	}
+	h.EventStreamRepo.RecordDelivery(s.ID, err)
+
+	if err != nil {
+		// The sink's response stays in the server log
+		log.Printf("Test delivery to stream %s failed: %v", s.ID, err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"success": false,
+			"error":   "Delivery failed",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Test event delivered",
+	})
+}
+
+// findStream loads the stream from the :id parameter. When it is missing or
+// belongs to another user, the stream is nil and the status and message
+// describe the error.
+func (h *EventStreamHandler) findStream(c *fiber.Ctx) (*models.EventStream, int, string) {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.StatusBadRequest, "Invalid event stream ID"
+	}
+
+	s, err := h.EventStreamRepo.FindForUser(userID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.StatusNotFound, "Event stream not found"
+	}
+	if err != nil {
+		return nil, fiber.StatusInternalServerError, "Failed to load event stream: " + err.Error()
+	}
+	return s, fiber.StatusOK, ""
+}
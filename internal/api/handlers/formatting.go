@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/numfmt"
+)
+
+// responseFormat picks the number format of a response: the "locale" query
+// parameter, then the locale saved for the user, then the Accept-Language
+// header, then the default locale
+func responseFormat(c *fiber.Ctx, users repository.UserRepository) numfmt.Format {
+	if format, ok := numfmt.Lookup(c.Query("locale")); ok {
+		return format
+	}
+
+	if userID, ok := c.Locals("userID").(uuid.UUID); ok && users != nil {
+		var user models.User
+		if err := users.FindByID(userID, &user); err == nil {
+			if format, ok := numfmt.Lookup(user.Locale); ok {
+				return format
+			}
+		}
+	}
+
+	if format, ok := numfmt.FromAcceptLanguage(c.Get(fiber.HeaderAcceptLanguage)); ok {
+		return format
+	}
+	return numfmt.Default()
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/glossary"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/llm"
+)
+
+// maxGlossaryBytes caps the size of an uploaded glossary file
+const maxGlossaryBytes = 1 << 20
+
+// GlossaryRequest replaces the glossary of an organization
+type GlossaryRequest struct {
+	Terms []glossary.Term `json:"terms"`
+}
+
+// GetGlossary returns the terminology glossary of an organization
+// @Summary Get glossary
+// @Description Returns the approved terms the organization's generated content is corrected against, with the variants each replaces and the language it applies to. Organizations are user accounts; non-admins can only access their own
+// @Tags content-improvements
+// @Produce json
+// @Param id path string true "Organization (user) ID"
+// @Success 200 {object} map[string]interface{} "Glossary terms"
+// @Failure 400 {object} map[string]interface{} "Invalid organization ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /organizations/{id}/glossary [get]
+func (h *ContentImprovementHandler) GetGlossary(c *fiber.Ctx) error {
+	organizationID, status, message := organizationParam(c)
+	if organizationID == uuid.Nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	terms, err := h.glossaryTerms(organizationID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load glossary: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"terms": terms,
+			"count": len(terms),
+		},
+	})
+}
+
+// UpdateGlossary replaces the terminology glossary of an organization
+// @Summary Upload glossary
+// @Description Replaces the organization's glossary. Send JSON with a terms list, or a multipart CSV file with the columns term, variants (separated by |) and language. Generated headings, CTAs, content and HTML are corrected to the approved term before they are saved: variants are replaced, and the term itself is fixed to its approved casing. Terms with a language only apply to content generated in that language. An empty list clears the glossary
+// @Tags content-improvements
+// @Accept json,mpfd
+// @Produce json
+// @Param id path string true "Organization (user) ID"
+// @Param request body handlers.GlossaryRequest false "Glossary terms"
+// @Param file formData file false "Glossary CSV"
+// @Success 200 {object} map[string]interface{} "Glossary updated"
+// @Failure 400 {object} map[string]interface{} "Invalid glossary"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /organizations/{id}/glossary [put]
+func (h *ContentImprovementHandler) UpdateGlossary(c *fiber.Ctx) error {
+	organizationID, status, message := organizationParam(c)
+	if organizationID == uuid.Nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	var terms []glossary.Term
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "A glossary file is required",
+			})
+		}
+		if fileHeader.Size > maxGlossaryBytes {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "The glossary must not exceed 1 MB",
+			})
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "Failed to read the glossary: " + err.Error(),
+			})
+		}
+		defer file.Close()
+		if terms, err = glossary.ParseCSV(io.LimitReader(file, maxGlossaryBytes)); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   err.Error(),
+			})
+		}
+	} else {
+		req := new(GlossaryRequest)
+		if err := c.BodyParser(req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "Invalid request body: " + err.Error(),
+			})
+		}
+		terms = req.Terms
+	}
+
+	if err := glossary.Validate(terms); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	records := make([]models.GlossaryTerm, 0, len(terms))
+	for _, term := range terms {
+		variants, _ := json.Marshal(term.Variants)
+		records = append(records, models.GlossaryTerm{
+			Term:     strings.TrimSpace(term.Term),
+			Variants: variants,
+			Language: strings.TrimSpace(term.Language),
+		})
+	}
+	if err := h.GlossaryRepo.Replace(organizationID, records); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to update glossary: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"terms": terms,
+			"count": len(terms),
+		},
+	})
+}
+
+// glossaryTerms loads the glossary of an organization
+func (h *ContentImprovementHandler) glossaryTerms(organizationID uuid.UUID) ([]glossary.Term, error) {
+	records, err := h.GlossaryRepo.FindByUserID(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	terms := make([]glossary.Term, 0, len(records))
+	for _, record := range records {
+		term := glossary.Term{Term: record.Term, Language: record.Language}
+		if len(record.Variants) > 0 {
+			_ = json.Unmarshal(record.Variants, &term.Variants)
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+// enforceGlossary corrects generated content against the glossary of the
+// organization that owns the analysis and returns the replacements made, or
+// nil when the organization has no glossary for the language. Content is
+// saved uncorrected if the glossary cannot be loaded.
+func (h *ContentImprovementHandler) enforceGlossary(organizationID uuid.UUID, language string, response *llm.ContentResponse) []glossary.Replacement {
+	if h.GlossaryRepo == nil {
+		return nil
+	}
+	terms, err := h.glossaryTerms(organizationID)
+	if err != nil {
+		fmt.Println("Failed to load glossary:", err)
+		return nil
+	}
+	g := glossary.New(terms, language)
+	if g.Empty() {
+		return nil
+	}
+
+	corrections := []glossary.Replacement{}
+	apply := func(element string, text *string, html bool) {
+		var replacements []glossary.Replacement
+		if html {
+			*text, replacements = g.ApplyHTML(*text)
+		} else {
+			*text, replacements = g.Apply(*text)
+		}
+		for _, replacement := range replacements {
+			replacement.Element = element
+			corrections = append(corrections, replacement)
+		}
+	}
+	apply("heading", &response.Title, false)
+	apply("cta", &response.CTAText, false)
+	apply("content", &response.Content, false)
+	apply("html", &response.HTML, true)
+	return glossary.Merge(corrections)
+}
+
+// recordGlossaryCorrections stores the replacements of a generation under a
+// key of the analysis metadata, where the generation's responses report them
+func (h *ContentImprovementHandler) recordGlossaryCorrections(analysisID uuid.UUID, key string, corrections []glossary.Replacement) {
+	if corrections == nil {
+		return
+	}
+	if err := h.AnalysisRepo.SetMetadataKey(analysisID, key, corrections); err != nil {
+		fmt.Println("Failed to save glossary corrections:", err)
+	}
+}
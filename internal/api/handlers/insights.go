@@ -0,0 +1,409 @@
+package handlers
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+)
+
+// Limits of the lists returned by GetOrganizationInsights
+const (
+	maxSystemicIssues = 20
+	maxIssueTrends    = 10
+	maxOffenders      = 10
+	maxOffenderTypes  = 3
+)
+
+// trendThreshold is the change of the share of affected analyses between the
+// first and the last period from which an issue type is rising or falling
+const trendThreshold = 0.05
+
+// InsightsHandler aggregates issues across all analyses of an organization.
+// Organizations are user accounts: analyses, usage and subscriptions all
+// belong to the user that owns them.
+type InsightsHandler struct {
+	IssueRepo repository.IssueRepository
+}
+
+// NewInsightsHandler creates a new insights handler
+func NewInsightsHandler(repoFactory *repository.Factory) *InsightsHandler {
+	return &InsightsHandler{
+		IssueRepo: repoFactory.IssueRepository,
+	}
+}
+
+// issueKey identifies an issue type across analyses
+type issueKey struct {
+	category  string
+	issueType string
+}
+
+// SystemicIssue is an issue type found on many pages of an organization
+type SystemicIssue struct {
+	Category string  `json:"category"`
+	Type     string  `json:"type"`
+	Title    string  `json:"title"`
+	Severity string  `json:"severity"`
+	Pages    int     `json:"pages"`
+	Share    float64 `json:"share"`
+	Percent  float64 `json:"percent"`
+}
+
+// IssueTrendPoint is the share of analyses of one period that had an issue type
+type IssueTrendPoint struct {
+	PeriodStart time.Time `json:"period_start"`
+	Analyses    int       `json:"analyses"`
+	Affected    int       `json:"affected"`
+	Share       float64   `json:"share"`
+}
+
+// IssueTrend is how often an issue type occurred over time
+type IssueTrend struct {
+	Category  string            `json:"category"`
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Direction string            `json:"direction"` // rising, falling, stable
+	Change    float64           `json:"change"`
+	Points    []IssueTrendPoint `json:"points"`
+}
+
+// IssueOffender is a page template or page section with many issues
+type IssueOffender struct {
+	Name          string   `json:"name"`
+	Pages         int      `json:"pages"`
+	Issues        int      `json:"issues"`
+	IssuesPerPage float64  `json:"issues_per_page"`
+	TopTypes      []string `json:"top_types"`
+}
+
+// GetOrganizationInsights returns recurring problems across an organization's analyses
+// @Summary Get organization issue insights
+// @Description Aggregates the issues of all completed analyses of an organization: issue types found on a large share of its pages (judged by the latest analysis of each page), the share of analyses per period that had each frequent issue type, and the page templates (URL path prefixes) and page sections (top-level elements) with the most issues. Organizations are user accounts; non-admins can only query their own
+// @Tags insights
+// @Produce json
+// @Param id path string true "Organization (user) ID"
+// @Param period query string false "Trend period (day, week, month)" default(week)
+// @Param from query string false "Start of the range (RFC 3339 or YYYY-MM-DD), defaults to 90 days ago"
+// @Param to query string false "End of the range (RFC 3339 or YYYY-MM-DD), defaults to now"
+// @Success 200 {object} map[string]interface{} "Organization insights"
+// @Failure 400 {object} map[string]interface{} "Invalid query"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /organizations/{id}/insights [get]
+func (h *InsightsHandler) GetOrganizationInsights(c *fiber.Ctx) error {
+	organizationID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid organization ID",
+		})
+	}
+
+	role, _ := c.Locals("role").(string)
+	if userID, _ := c.Locals("userID").(uuid.UUID); role != "admin" && userID != organizationID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"success": false,
+			"error":   "Forbidden, you can only access your own organization",
+		})
+	}
+
+	period := repository.UsagePeriod(c.Query("period", string(repository.UsagePeriodWeek)))
+	switch period {
+	case repository.UsagePeriodDay, repository.UsagePeriodWeek, repository.UsagePeriodMonth:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid period, expected day, week or month",
+		})
+	}
+
+	now := time.Now()
+	from, err := parseUsageDate(c.Query("from"), now.AddDate(0, 0, -90))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid from date",
+		})
+	}
+	to, err := parseUsageDate(c.Query("to"), now)
+	if err != nil || !to.After(from) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid to date",
+		})
+	}
+
+	occurrences, err := h.IssueRepo.FindOccurrences(organizationID, from, to)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load issues",
+		})
+	}
+
+	// The latest analysis of each page describes its current state
+	latest := make(map[uuid.UUID]uuid.UUID)
+	analyses := make(map[uuid.UUID]struct{})
+	for _, o := range occurrences {
+		latest[o.WebsiteID] = o.AnalysisID
+		analyses[o.AnalysisID] = struct{}{}
+	}
+	var current []repository.IssueOccurrence
+	for _, o := range occurrences {
+		if latest[o.WebsiteID] == o.AnalysisID {
+			current = append(current, o)
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"organization_id": organizationID,
+			"from":            from,
+			"to":              to,
+			"period":          period,
+			"pages":           len(latest),
+			"analyses":        len(analyses),
+			"systemic_issues": systemicIssues(current, len(latest)),
+			"trends":          issueTrends(occurrences, period),
+			"top_templates":   issueOffenders(current, func(o repository.IssueOccurrence) string { return pageTemplate(o.URL) }),
+			"top_sections":    issueOffenders(current, func(o repository.IssueOccurrence) string { return pageSection(o.ElementSelector) }),
+		},
+	})
+}
+
+// systemicIssues returns the issue types by the share of pages they occur on
+func systemicIssues(current []repository.IssueOccurrence, pages int) []SystemicIssue {
+	affected := make(map[issueKey]map[uuid.UUID]struct{})
+	last := make(map[issueKey]repository.IssueOccurrence)
+	for _, o := range current {
+		if o.Type == "" {
+			continue
+		}
+		key := issueKey{o.Category, o.Type}
+		if affected[key] == nil {
+			affected[key] = make(map[uuid.UUID]struct{})
+		}
+		affected[key][o.WebsiteID] = struct{}{}
+		last[key] = o
+	}
+
+	issues := make([]SystemicIssue, 0, len(affected))
+	for key, websites := range affected {
+		share := float64(len(websites)) / float64(pages)
+		issues = append(issues, SystemicIssue{
+			Category: key.category,
+			Type:     key.issueType,
+			Title:    last[key].Title,
+			Severity: last[key].Severity,
+			Pages:    len(websites),
+			Share:    share,
+			Percent:  float64(int(share*1000+0.5)) / 10,
+		})
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Pages != issues[j].Pages {
+			return issues[i].Pages > issues[j].Pages
+		}
+		return getSeverityValue(issues[i].Severity) > getSeverityValue(issues[j].Severity)
+	})
+	if len(issues) > maxSystemicIssues {
+		issues = issues[:maxSystemicIssues]
+	}
+	return issues
+}
+
+// issueTrends returns the share of analyses per period that had each of the
+// most frequent issue types
+func issueTrends(occurrences []repository.IssueOccurrence, period repository.UsagePeriod) []IssueTrend {
+	var periods []time.Time
+	analysesIn := make(map[time.Time]map[uuid.UUID]struct{})
+	affectedIn := make(map[issueKey]map[time.Time]map[uuid.UUID]struct{})
+	total := make(map[issueKey]int)
+	titles := make(map[issueKey]string)
+
+	for _, o := range occurrences {
+		start := periodStart(o.AnalyzedAt, period)
+		if analysesIn[start] == nil {
+			analysesIn[start] = make(map[uuid.UUID]struct{})
+			periods = append(periods, start)
+		}
+		analysesIn[start][o.AnalysisID] = struct{}{}
+
+		if o.Type == "" {
+			continue
+		}
+		key := issueKey{o.Category, o.Type}
+		if affectedIn[key] == nil {
+			affectedIn[key] = make(map[time.Time]map[uuid.UUID]struct{})
+		}
+		if affectedIn[key][start] == nil {
+			affectedIn[key][start] = make(map[uuid.UUID]struct{})
+		}
+		if _, seen := affectedIn[key][start][o.AnalysisID]; !seen {
+			affectedIn[key][start][o.AnalysisID] = struct{}{}
+			total[key]++
+		}
+		titles[key] = o.Title
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i].Before(periods[j]) })
+
+	keys := make([]issueKey, 0, len(total))
+	for key := range total {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if total[keys[i]] != total[keys[j]] {
+			return total[keys[i]] > total[keys[j]]
+		}
+		return keys[i].issueType < keys[j].issueType
+	})
+	if len(keys) > maxIssueTrends {
+		keys = keys[:maxIssueTrends]
+	}
+
+	trends := make([]IssueTrend, 0, len(keys))
+	for _, key := range keys {
+		trend := IssueTrend{
+			Category:  key.category,
+			Type:      key.issueType,
+			Title:     titles[key],
+			Direction: "stable",
+		}
+		for _, start := range periods {
+			point := IssueTrendPoint{
+				PeriodStart: start,
+				Analyses:    len(analysesIn[start]),
+				Affected:    len(affectedIn[key][start]),
+			}
+			point.Share = float64(point.Affected) / float64(point.Analyses)
+			trend.Points = append(trend.Points, point)
+		}
+		trend.Change = trend.Points[len(trend.Points)-1].Share - trend.Points[0].Share
+		if trend.Change >= trendThreshold {
+			trend.Direction = "rising"
+		} else if trend.Change <= -trendThreshold {
+			trend.Direction = "falling"
+		}
+		trends = append(trends, trend)
+	}
+	return trends
+}
+
+// issueOffenders groups the current issues by the name returned by groupOf
+// and returns the groups with the most issues per page
+func issueOffenders(current []repository.IssueOccurrence, groupOf func(repository.IssueOccurrence) string) []IssueOffender {
+	pages := make(map[string]map[uuid.UUID]struct{})
+	issues := make(map[string]int)
+	types := make(map[string]map[string]int)
+	for _, o := range current {
+		if o.Type == "" {
+			continue
+		}
+		name := groupOf(o)
+		if name == "" {
+			continue
+		}
+		if pages[name] == nil {
+			pages[name] = make(map[uuid.UUID]struct{})
+			types[name] = make(map[string]int)
+		}
+		pages[name][o.WebsiteID] = struct{}{}
+		issues[name]++
+		types[name][o.Type]++
+	}
+
+	offenders := make([]IssueOffender, 0, len(pages))
+	for name, websites := range pages {
+		offender := IssueOffender{
+			Name:          name,
+			Pages:         len(websites),
+			Issues:        issues[name],
+			IssuesPerPage: float64(issues[name]) / float64(len(websites)),
+		}
+		for issueType := range types[name] {
+			offender.TopTypes = append(offender.TopTypes, issueType)
+		}
+		sort.Slice(offender.TopTypes, func(i, j int) bool {
+			a, b := offender.TopTypes[i], offender.TopTypes[j]
+			if types[name][a] != types[name][b] {
+				return types[name][a] > types[name][b]
+			}
+			return a < b
+		})
+		if len(offender.TopTypes) > maxOffenderTypes {
+			offender.TopTypes = offender.TopTypes[:maxOffenderTypes]
+		}
+		offenders = append(offenders, offender)
+	}
+	sort.Slice(offenders, func(i, j int) bool {
+		if offenders[i].Issues != offenders[j].Issues {
+			return offenders[i].Issues > offenders[j].Issues
+		}
+		return offenders[i].Name < offenders[j].Name
+	})
+	if len(offenders) > maxOffenders {
+		offenders = offenders[:maxOffenders]
+	}
+	return offenders
+}
+
+// periodStart truncates a time to the start of its day, ISO week or month in
+// UTC, matching date_trunc
+func periodStart(t time.Time, period repository.UsagePeriod) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case repository.UsagePeriodWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case repository.UsagePeriodMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+// pageTemplate groups a page URL by host and first path segment, so that
+// "https://example.com/blog/post-1" belongs to "example.com/blog/*"
+func pageTemplate(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch {
+	case segments[0] == "":
+		return u.Host + "/"
+	case len(segments) == 1:
+		return u.Host + "/" + segments[0]
+	}
+	return u.Host + "/" + segments[0] + "/*"
+}
+
+// pageSection returns the top-level part of a page an element selector
+// points into: the element with an id it starts from, or the child of body
+func pageSection(selector string) string {
+	if selector == "" {
+		return ""
+	}
+	parts := strings.Split(selector, " > ")
+	if strings.Contains(parts[0], "[id=") {
+		return parts[0]
+	}
+	for i, part := range parts {
+		if part == "body" {
+			if i+1 < len(parts) {
+				return "body > " + parts[i+1]
+			}
+			return "body"
+		}
+	}
+	return parts[0]
+}
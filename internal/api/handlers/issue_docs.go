@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"log"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/issuedocs"
+)
+
+// IssueWithDocs is an issue with the documentation on fixing it on the
+// analyzed site's platform
+type IssueWithDocs struct {
+	models.Issue
+	Docs *issuedocs.Link `json:"docs,omitempty"`
+}
+
+// issueDocResolver loads the built-in documentation links and those
+// maintained by administrators. Issues keep the built-in links if the
+// maintained ones cannot be loaded.
+func issueDocResolver(repo repository.IssueDocLinkRepository) *issuedocs.Resolver {
+	if repo == nil {
+		return issuedocs.NewResolver(issuedocs.Builtin())
+	}
+	records, err := repo.FindAll()
+	if err != nil {
+		log.Printf("Failed to load issue doc links: %v", err)
+		return issuedocs.NewResolver(issuedocs.Builtin())
+	}
+	return issuedocs.NewResolver(issuedocs.Builtin(), docEntries(records))
+}
+
+// docEntries converts stored doc links to resolver entries
+func docEntries(records []models.IssueDocLink) []issuedocs.Entry {
+	entries := make([]issuedocs.Entry, 0, len(records))
+	for _, record := range records {
+		entries = append(entries, issuedocs.Entry{
+			Category:  record.Category,
+			IssueType: record.IssueType,
+			Platform:  record.Platform,
+			Title:     record.Title,
+			URL:       record.URL,
+		})
+	}
+	return entries
+}
+
+// analysisPlatforms returns the technologies detected by an analysis in the
+// order their documentation is preferred
+func analysisPlatforms(repo repository.PageEntityRepository, analysisID uuid.UUID) []string {
+	if repo == nil {
+		return nil
+	}
+	technologies, err := repo.FindTechnologiesByAnalysisID(analysisID)
+	if err != nil {
+		log.Printf("Failed to load technologies of analysis %s: %v", analysisID, err)
+		return nil
+	}
+	names := make([]string, 0, len(technologies))
+	for _, tech := range technologies {
+		names = append(names, tech.Name)
+	}
+	return issuedocs.Platforms(names)
+}
+
+// withDocs attaches documentation links to the issues of an analysis
+func (h *AnalysisHandler) withDocs(analysisID uuid.UUID, issues []models.Issue) []IssueWithDocs {
+	resolver := issueDocResolver(h.DocLinkRepo)
+	platforms := analysisPlatforms(h.PageEntityRepo, analysisID)
+
+	result := make([]IssueWithDocs, 0, len(issues))
+	for _, issue := range issues {
+		result = append(result, IssueWithDocs{
+			Issue: issue,
+			Docs:  resolver.Resolve(issue.Category, issue.Type, platforms),
+		})
+	}
+	return result
+}
+
+// IssueDocsHandler manages the documentation links of issues
+type IssueDocsHandler struct {
+	DocLinkRepo repository.IssueDocLinkRepository
+}
+
+// NewIssueDocsHandler creates a new issue docs handler
+func NewIssueDocsHandler(repoFactory *repository.Factory) *IssueDocsHandler {
+	return &IssueDocsHandler{
+		DocLinkRepo: repoFactory.IssueDocLinkRepository,
+	}
+}
+
+// IssueDocLinkRequest sets the documentation link of an issue type
+type IssueDocLinkRequest struct {
+	// Category limits the link to issues of one category, empty for all
+	Category  string `json:"category,omitempty" example:"seo"`
+	IssueType string `json:"issue_type" example:"missing_description"`
+	// Platform is the name of a detected technology, empty for any site
+	Platform string `json:"platform,omitempty" example:"WordPress"`
+	Title    string `json:"title" example:"Editing meta descriptions in WordPress"`
+	URL      string `json:"url" example:"https://wordpress.org/documentation/article/search-engine-optimization/"`
+}
+
+// ListIssueDocLinks returns the documentation links of issues
+// @Summary List issue documentation links
+// @Description Returns the links to documentation on fixing issues: those shipped with the service and those maintained by administrators, which replace built-in links with the same category, issue type and platform. Issues link to the documentation of the first detected platform that has one, and otherwise to generic documentation
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Documentation links"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /admin/issues/doc-links [get]
+func (h *IssueDocsHandler) ListIssueDocLinks(c *fiber.Ctx) error {
+	links, err := h.DocLinkRepo.FindAll()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load doc links",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"builtin":    issuedocs.Builtin(),
+			"maintained": links,
+		},
+	})
+}
+
+// SetIssueDocLink creates or replaces a documentation link
+// @Summary Set an issue documentation link
+// @Description Links the issues of a type to documentation on fixing them, optionally only for one category and for sites built with one platform (the name of a detected technology, e.g. WordPress, Shopify or Next.js). A link with the same category, issue type and platform is replaced
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body IssueDocLinkRequest true "Link"
+// @Success 200 {object} map[string]interface{} "Link saved"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /admin/issues/doc-links [put]
+func (h *IssueDocsHandler) SetIssueDocLink(c *fiber.Ctx) error {
+	req := new(IssueDocLinkRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+	if req.IssueType == "" || req.Title == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Issue_type and title are required",
+		})
+	}
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "URL must be an absolute http or https URL",
+		})
+	}
+
+	link := models.IssueDocLink{
+		Category:  req.Category,
+		IssueType: req.IssueType,
+		Platform:  req.Platform,
+		Title:     req.Title,
+		URL:       req.URL,
+	}
+	if userID, ok := c.Locals("userID").(uuid.UUID); ok {
+		link.UpdatedBy = &userID
+	}
+	if err := h.DocLinkRepo.SaveLink(&link); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to save doc link",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    link,
+	})
+}
+
+// DeleteIssueDocLink removes a maintained documentation link
+// @Summary Delete an issue documentation link
+// @Description Removes a link maintained by administrators. A built-in link it replaced applies again
+// @Tags admin
+// @Produce json
+// @Param id path string true "Link ID"
+// @Success 200 {object} map[string]interface{} "Link deleted"
+// @Failure 400 {object} map[string]interface{} "Invalid ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Link not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /admin/issues/doc-links/{id} [delete]
+func (h *IssueDocsHandler) DeleteIssueDocLink(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid doc link ID",
+		})
+	}
+
+	deleted, err := h.DocLinkRepo.DeleteLink(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to delete doc link",
+		})
+	}
+	if !deleted {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Doc link not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Doc link deleted",
+	})
+}
@@ -0,0 +1,401 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+)
+
+// Thresholds of the severity suggested from feedback. An issue code needs
+// minFeedbackRatings ratings before a change is suggested.
+const (
+	minFeedbackRatings  = 20
+	lowPrecision        = 0.4
+	highPrecision       = 0.85
+	highActionableShare = 0.7
+	maxFeedbackComment  = 1000
+)
+
+// severityLevels are the issue severities from least to most severe
+var severityLevels = []string{"low", "medium", "high"}
+
+// severityOverrides maps issue codes ("category/type") to the severity that
+// replaces the one reported by analyzers
+type severityOverrides map[string]string
+
+// severity returns the severity of an issue code after overrides
+func (o severityOverrides) severity(category, issueType, reported string) string {
+	if issueType == "" {
+		return reported
+	}
+	if severity, ok := o[category+"/"+issueType]; ok {
+		return severity
+	}
+	return reported
+}
+
+// severityOverrides loads the deployment's severity overrides. Issues keep
+// the analyzers' severities if they cannot be loaded.
+func (a *AnalysisHandler) severityOverrides() severityOverrides {
+	if a.FeedbackRepo == nil {
+		return nil
+	}
+	records, err := a.FeedbackRepo.FindOverrides()
+	if err != nil {
+		log.Printf("Failed to load severity overrides: %v", err)
+		return nil
+	}
+	overrides := make(severityOverrides, len(records))
+	for _, record := range records {
+		overrides[record.Category+"/"+record.IssueType] = record.Severity
+	}
+	return overrides
+}
+
+// IssueFeedbackHandler collects ratings of reported issues and manages
+// deployment-level severity overrides
+type IssueFeedbackHandler struct {
+	IssueRepo    repository.IssueRepository
+	FeedbackRepo repository.IssueFeedbackRepository
+}
+
+// NewIssueFeedbackHandler creates a new issue feedback handler
+func NewIssueFeedbackHandler(repoFactory *repository.Factory) *IssueFeedbackHandler {
+	return &IssueFeedbackHandler{
+		IssueRepo:    repoFactory.IssueRepository,
+		FeedbackRepo: repoFactory.IssueFeedbackRepository,
+	}
+}
+
+// IssueFeedbackRequest rates a reported issue
+type IssueFeedbackRequest struct {
+	// Useful is whether the issue is a real problem of the page
+	Useful bool `json:"useful"`
+	// Actionable is whether it was clear what to change
+	Actionable bool   `json:"actionable"`
+	Comment    string `json:"comment,omitempty"`
+}
+
+// SeverityOverrideRequest sets the severity of an issue code
+type SeverityOverrideRequest struct {
+	Category  string `json:"category" example:"seo"`
+	IssueType string `json:"issue_type" example:"missing_meta_keywords"`
+	Severity  string `json:"severity" example:"low"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// IssueCodeFeedback is the feedback of an issue code with the severity it suggests
+type IssueCodeFeedback struct {
+	repository.IssueFeedbackStats
+	// Precision is the share of ratings that found the issue useful
+	Precision       float64 `json:"precision"`
+	ActionableShare float64 `json:"actionable_share"`
+	// Override is the severity override in effect, empty when analyzers decide
+	Override          string `json:"override,omitempty"`
+	SuggestedSeverity string `json:"suggested_severity,omitempty"`
+	Suggestion        string `json:"suggestion,omitempty"` // lower, raise
+}
+
+// SubmitIssueFeedback stores the current user's rating of an issue
+// @Summary Rate an issue
+// @Description Records whether a reported issue was useful (a real problem) and actionable (clear what to change). Rating the same issue again replaces the earlier rating. Ratings are aggregated per issue code to calibrate severities
+// @Tags analysis
+// @Accept json
+// @Produce json
+// @Param id path string true "Analysis ID"
+// @Param issueID path string true "Issue ID"
+// @Param request body IssueFeedbackRequest true "Rating"
+// @Success 200 {object} map[string]interface{} "Feedback stored"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Issue not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /analysis/{id}/issues/{issueID}/feedback [post]
+func (h *IssueFeedbackHandler) SubmitIssueFeedback(c *fiber.Ctx) error {
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+	issueID, err := uuid.Parse(c.Params("issueID"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid issue ID",
+		})
+	}
+
+	req := new(IssueFeedbackRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+	if len([]rune(req.Comment)) > maxFeedbackComment {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Comment is too long",
+		})
+	}
+
+	var issue models.Issue
+	if err := h.IssueRepo.FindByID(issueID, &issue); err != nil || issue.AnalysisID != analysisID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Issue not found",
+		})
+	}
+
+	feedback := models.IssueFeedback{
+		IssueID:    issue.ID,
+		UserID:     c.Locals("userID").(uuid.UUID),
+		AnalysisID: issue.AnalysisID,
+		Category:   issue.Category,
+		IssueType:  issue.Type,
+		Severity:   issue.Severity,
+		Useful:     req.Useful,
+		Actionable: req.Actionable,
+		Comment:    req.Comment,
+	}
+	if err := h.FeedbackRepo.SaveFeedback(&feedback); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to save feedback",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    feedback,
+	})
+}
+
+// GetIssueFeedbackStats returns the aggregated feedback per issue code
+// @Summary Get issue feedback statistics
+// @Description Aggregates issue ratings per issue code (category and type): the share of ratings that found the issue useful (precision) and actionable, the severity override in effect and, for codes with at least 20 ratings, a suggested severity. Low precision suggests lowering the severity, high precision with mostly actionable ratings suggests raising it
+// @Tags admin
+// @Produce json
+// @Param from query string false "Only ratings since (RFC 3339 or YYYY-MM-DD), defaults to 180 days ago"
+// @Success 200 {object} map[string]interface{} "Feedback per issue code"
+// @Failure 400 {object} map[string]interface{} "Invalid query"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /admin/issues/feedback [get]
+func (h *IssueFeedbackHandler) GetIssueFeedbackStats(c *fiber.Ctx) error {
+	from, err := parseUsageDate(c.Query("from"), time.Now().AddDate(0, 0, -180))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid from date",
+		})
+	}
+
+	stats, err := h.FeedbackRepo.FindStats(from)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load feedback",
+		})
+	}
+	overrides, err := h.FeedbackRepo.FindOverrides()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load severity overrides",
+		})
+	}
+	inEffect := make(map[string]string, len(overrides))
+	for _, override := range overrides {
+		inEffect[override.Category+"/"+override.IssueType] = override.Severity
+	}
+
+	codes := make([]IssueCodeFeedback, 0, len(stats))
+	for _, stat := range stats {
+		codes = append(codes, issueCodeFeedback(stat, inEffect[stat.Category+"/"+stat.IssueType]))
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"from":        from,
+			"min_ratings": minFeedbackRatings,
+			"issue_codes": codes,
+		},
+	})
+}
+
+// ListSeverityOverrides returns the severity overrides of the deployment
+// @Summary List severity overrides
+// @Description Returns the issue codes whose severity replaces the one reported by analyzers
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Severity overrides"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /admin/issues/severity-overrides [get]
+func (h *IssueFeedbackHandler) ListSeverityOverrides(c *fiber.Ctx) error {
+	overrides, err := h.FeedbackRepo.FindOverrides()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load severity overrides",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    overrides,
+	})
+}
+
+// SetSeverityOverride sets the severity of an issue code
+// @Summary Set a severity override
+// @Description Replaces the severity analyzers report for an issue code in all analyses saved from now on. Existing issues keep their severity
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body SeverityOverrideRequest true "Override"
+// @Success 200 {object} map[string]interface{} "Override saved"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /admin/issues/severity-overrides [put]
+func (h *IssueFeedbackHandler) SetSeverityOverride(c *fiber.Ctx) error {
+	req := new(SeverityOverrideRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+	if req.Category == "" || req.IssueType == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Category and issue_type are required",
+		})
+	}
+	if severityIndex(req.Severity) < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Severity must be high, medium or low",
+		})
+	}
+
+	override := models.IssueSeverityOverride{
+		Category:  req.Category,
+		IssueType: req.IssueType,
+		Severity:  req.Severity,
+		Reason:    req.Reason,
+	}
+	if userID, ok := c.Locals("userID").(uuid.UUID); ok {
+		override.UpdatedBy = &userID
+	}
+	if err := h.FeedbackRepo.SaveOverride(&override); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to save severity override",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    override,
+	})
+}
+
+// DeleteSeverityOverride removes the severity override of an issue code
+// @Summary Delete a severity override
+// @Description Lets analyzers decide the severity of an issue code again
+// @Tags admin
+// @Produce json
+// @Param category path string true "Issue category"
+// @Param type path string true "Issue type"
+// @Success 200 {object} map[string]interface{} "Override deleted"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Override not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /admin/issues/severity-overrides/{category}/{type} [delete]
+func (h *IssueFeedbackHandler) DeleteSeverityOverride(c *fiber.Ctx) error {
+	deleted, err := h.FeedbackRepo.DeleteOverride(c.Params("category"), c.Params("type"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to delete severity override",
+		})
+	}
+	if !deleted {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Severity override not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Severity override deleted",
+	})
+}
+
+// issueCodeFeedback derives precision and a suggested severity from the
+// ratings of an issue code. The suggestion starts from the override in
+// effect, or from the severity most ratings were given at.
+func issueCodeFeedback(stats repository.IssueFeedbackStats, override string) IssueCodeFeedback {
+	feedback := IssueCodeFeedback{IssueFeedbackStats: stats, Override: override}
+	if stats.Ratings == 0 {
+		return feedback
+	}
+	feedback.Precision = roundShare(float64(stats.Useful) / float64(stats.Ratings))
+	feedback.ActionableShare = roundShare(float64(stats.Actionable) / float64(stats.Ratings))
+	if stats.Ratings < minFeedbackRatings {
+		return feedback
+	}
+
+	current := override
+	if current == "" {
+		current = stats.Severity
+	}
+	level := severityIndex(current)
+	switch {
+	case feedback.Precision < lowPrecision && level > 0:
+		feedback.SuggestedSeverity = severityLevels[level-1]
+		feedback.Suggestion = "lower"
+	case feedback.Precision >= highPrecision && feedback.ActionableShare >= highActionableShare &&
+		level >= 0 && level < len(severityLevels)-1:
+		feedback.SuggestedSeverity = severityLevels[level+1]
+		feedback.Suggestion = "raise"
+	}
+	return feedback
+}
+
+// severityIndex returns the position of a severity in severityLevels, or -1
+func severityIndex(severity string) int {
+	for i, level := range severityLevels {
+		if level == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// roundShare rounds a share to three decimals
+func roundShare(share float64) float64 {
+	return math.Round(share*1000) / 1000
+}
@@ -0,0 +1,499 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/config"
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/outbox"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/serp"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+	ws "github.com/chynybekuuludastan/website_optimizer/internal/websocket"
+)
+
+const (
+	// rankCheckTickInterval is how often due keywords are looked up
+	rankCheckTickInterval = time.Minute
+	// rankCheckBatchSize limits how many keywords are checked per tick
+	rankCheckBatchSize = 20
+	// rankSearchTimeout bounds a single SERP API query
+	rankSearchTimeout = 90 * time.Second
+	// maxTrackedKeywords limits the keywords a user may track
+	maxTrackedKeywords = 500
+	// minRankTrackingInterval is the shortest interval between checks of a keyword
+	minRankTrackingInterval = time.Hour
+)
+
+var (
+	countryCodePattern  = regexp.MustCompile(`^[a-z]{2}$`)
+	languageCodePattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]{2})?$`)
+)
+
+// TrackKeywordRequest is the body of a keyword tracking request
+type TrackKeywordRequest struct {
+	Keyword  string `json:"keyword" validate:"required"`
+	URL      string `json:"url" validate:"required"`
+	Country  string `json:"country"`  // ISO 3166-1 alpha-2 code, e.g. us
+	Language string `json:"language"` // ISO 639-1 code, e.g. en
+	Device   string `json:"device"`   // desktop (default) or mobile
+	// Alerts may only refer to rank_position and rank_change
+	Alerts []analyzer.AlertRule `json:"alerts"`
+}
+
+type KeywordHandler struct {
+	KeywordRepo repository.KeywordRepository
+	Provider    serp.Provider
+	Hub         *ws.Hub
+	Outbox      *outbox.Dispatcher // delivers rank alerts; nil before it runs
+	Config      *config.Config
+}
+
+// NewKeywordHandler creates a new keyword handler. Rank tracking stays
+// disabled when no SERP provider is configured.
+func NewKeywordHandler(repoFactory *repository.Factory, hub *ws.Hub, cfg *config.Config) *KeywordHandler {
+	h := &KeywordHandler{
+		KeywordRepo: repoFactory.KeywordRepository,
+		Hub:         hub,
+		Config:      cfg,
+	}
+	if cfg.RankTrackingProvider != "" {
+		provider, err := serp.NewProvider(cfg.RankTrackingProvider, cfg.RankTrackingAPIURL, cfg.RankTrackingAPIKey)
+		if err != nil {
+			log.Printf("Rank tracking disabled: %v", err)
+		} else {
+			h.Provider = provider
+		}
+	}
+	return h
+}
+
+// ListKeywords returns the keywords tracked by the current user
+// @Summary List tracked keywords
+// @Description Returns the user's tracked keywords with their latest and previous search positions. A null position means the page was not found within the checked depth
+// @Tags keywords
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Tracked keywords"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /keywords [get]
+func (h *KeywordHandler) ListKeywords(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	keywords, err := h.KeywordRepo.FindByUserID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch keywords: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    keywords,
+		"meta": fiber.Map{
+			"enabled":  h.Provider != nil,
+			"interval": h.interval().String(),
+			"depth":    h.depth(),
+		},
+	})
+}
+
+// TrackKeyword starts tracking the search position of a page for a keyword
+// @Summary Track a keyword
+// @Description Registers a keyword and page pair. Its search position in the given country, language and device is checked through the configured SERP API on a schedule, starting within a minute. Alert rules on rank_position or rank_change (positions lost since the previous check) notify the user over WebSocket when a check triggers them
+// @Tags keywords
+// @Accept json
+// @Produce json
+// @Param keyword body TrackKeywordRequest true "Keyword"
+// @Success 201 {object} map[string]interface{} "Keyword tracked"
+// @Failure 400 {object} map[string]interface{} "Invalid keyword"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 409 {object} map[string]interface{} "Keyword already tracked"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Failure 503 {object} map[string]interface{} "Rank tracking is not configured"
+// @Security BearerAuth
+// @Router /keywords [post]
+func (h *KeywordHandler) TrackKeyword(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	if h.Provider == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"success": false,
+			"error":   "Rank tracking is not configured",
+		})
+	}
+
+	req := new(TrackKeywordRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+	keyword, err := newTrackedKeyword(req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	existing, err := h.KeywordRepo.FindByUserID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch keywords: " + err.Error(),
+		})
+	}
+	if len(existing) >= maxTrackedKeywords {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   fmt.Sprintf("At most %d keywords can be tracked", maxTrackedKeywords),
+		})
+	}
+	for _, other := range existing {
+		if other.Keyword == keyword.Keyword && other.URL == keyword.URL && other.Country == keyword.Country &&
+			other.Language == keyword.Language && other.Device == keyword.Device {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"success": false,
+				"error":   "Keyword is already tracked for this page",
+			})
+		}
+	}
+
+	now := time.Now()
+	keyword.UserID = userID
+	keyword.NextCheckAt = &now
+	if err := h.KeywordRepo.Create(keyword); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to track keyword: " + err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data":    keyword,
+	})
+}
+
+// newTrackedKeyword validates a tracking request
+func newTrackedKeyword(req *TrackKeywordRequest) (*models.TrackedKeyword, error) {
+	keyword := strings.Join(strings.Fields(strings.ToLower(req.Keyword)), " ")
+	if keyword == "" || len(keyword) > 255 {
+		return nil, errors.New("keyword must be between 1 and 255 characters")
+	}
+	pageURL, err := urlnorm.Normalize(req.URL)
+	if err != nil {
+		return nil, errors.New("invalid URL: " + err.Error())
+	}
+
+	country := strings.ToLower(strings.TrimSpace(req.Country))
+	if country != "" && !countryCodePattern.MatchString(country) {
+		return nil, errors.New("country must be a two-letter country code")
+	}
+	language := strings.ToLower(strings.TrimSpace(req.Language))
+	if language != "" && !languageCodePattern.MatchString(language) {
+		return nil, errors.New("language must be a language code such as en or pt-br")
+	}
+	device := req.Device
+	if device == "" {
+		device = "desktop"
+	}
+	if device != "desktop" && device != "mobile" {
+		return nil, errors.New("device must be desktop or mobile")
+	}
+
+	for _, rule := range req.Alerts {
+		if rule.Metric != analyzer.AlertMetricRankPosition && rule.Metric != analyzer.AlertMetricRankChange {
+			return nil, fmt.Errorf("keyword alerts must use %s or %s", analyzer.AlertMetricRankPosition, analyzer.AlertMetricRankChange)
+		}
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	tracked := &models.TrackedKeyword{
+		Keyword:  keyword,
+		URL:      pageURL,
+		Country:  country,
+		Language: language,
+		Device:   device,
+		Active:   true,
+	}
+	if len(req.Alerts) > 0 {
+		tracked.AlertRules = encodeSiteJSON(req.Alerts)
+	}
+	return tracked, nil
+}
+
+// DeleteKeyword stops tracking a keyword and removes its history
+// @Summary Stop tracking a keyword
+// @Description Removes a tracked keyword together with its position history
+// @Tags keywords
+// @Produce json
+// @Param id path string true "Keyword ID"
+// @Success 200 {object} map[string]interface{} "Keyword deleted"
+// @Failure 400 {object} map[string]interface{} "Invalid ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Keyword not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /keywords/{id} [delete]
+func (h *KeywordHandler) DeleteKeyword(c *fiber.Ctx) error {
+	keyword, status, message := h.findKeyword(c)
+	if keyword == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	if err := h.KeywordRepo.Delete(keyword); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to delete keyword: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Keyword deleted",
+	})
+}
+
+// GetKeywordHistory returns the position history of a keyword
+// @Summary Get keyword position history
+// @Description Returns the position checks of a tracked keyword in a time range together with the overall on-page scores of the user's completed analyses of the page, so that ranking and on-page trends can be compared
+// @Tags keywords
+// @Produce json
+// @Param id path string true "Keyword ID"
+// @Param from query string false "Start of the range (RFC3339 or YYYY-MM-DD), defaults to 90 days ago"
+// @Param to query string false "End of the range (RFC3339 or YYYY-MM-DD), defaults to now"
+// @Success 200 {object} map[string]interface{} "Position history and page scores"
+// @Failure 400 {object} map[string]interface{} "Invalid parameters"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Keyword not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /keywords/{id}/history [get]
+func (h *KeywordHandler) GetKeywordHistory(c *fiber.Ctx) error {
+	keyword, status, message := h.findKeyword(c)
+	if keyword == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	now := time.Now()
+	from, err := parseUsageDate(c.Query("from"), now.AddDate(0, 0, -90))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid from date",
+		})
+	}
+	to, err := parseUsageDate(c.Query("to"), now)
+	if err != nil || !to.After(from) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid to date",
+		})
+	}
+
+	rankings, err := h.KeywordRepo.History(keyword.ID, from, to)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch position history: " + err.Error(),
+		})
+	}
+	scores, err := h.KeywordRepo.PageScores(keyword.UserID, keyword.URL, from, to)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch page scores: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"keyword":     keyword,
+			"from":        from,
+			"to":          to,
+			"rankings":    rankings,
+			"page_scores": scores,
+		},
+	})
+}
+
+// findKeyword loads the keyword named in the path if it belongs to the user
+func (h *KeywordHandler) findKeyword(c *fiber.Ctx) (*models.TrackedKeyword, int, string) {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.StatusBadRequest, "Invalid keyword ID"
+	}
+
+	keyword, err := h.KeywordRepo.FindForUser(userID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.StatusNotFound, "Keyword not found"
+	}
+	if err != nil {
+		return nil, fiber.StatusInternalServerError, "Failed to load keyword: " + err.Error()
+	}
+	return keyword, fiber.StatusOK, ""
+}
+
+// interval returns how often each keyword is checked
+func (h *KeywordHandler) interval() time.Duration {
+	if h.Config.RankTrackingInterval < minRankTrackingInterval {
+		return minRankTrackingInterval
+	}
+	return h.Config.RankTrackingInterval
+}
+
+// depth returns how many results are searched for the page
+func (h *KeywordHandler) depth() int {
+	if h.Config.RankTrackingDepth <= 0 {
+		return serp.DefaultDepth
+	}
+	return h.Config.RankTrackingDepth
+}
+
+// RunRankTracking checks the positions of due keywords until the context is
+// cancelled
+func (h *KeywordHandler) RunRankTracking(ctx context.Context) {
+	if h.Provider == nil || h.KeywordRepo == nil {
+		return
+	}
+	ticker := time.NewTicker(rankCheckTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.checkDueKeywords(ctx)
+		}
+	}
+}
+
+// checkDueKeywords checks the position of every due keyword
+func (h *KeywordHandler) checkDueKeywords(ctx context.Context) {
+	now := time.Now()
+	keywords, err := h.KeywordRepo.FindDue(now, rankCheckBatchSize)
+	if err != nil {
+		log.Printf("Failed to load due keywords: %v", err)
+		return
+	}
+
+	for i := range keywords {
+		keyword := &keywords[i]
+
+		// Several server instances may see the same due keyword
+		claimed, err := h.KeywordRepo.ClaimCheck(keyword.ID, *keyword.NextCheckAt, now.Add(h.interval()))
+		if err != nil || !claimed {
+			continue
+		}
+
+		if err := h.checkKeyword(ctx, keyword); err != nil {
+			log.Printf("Failed to check position of %q for %s: %v", keyword.Keyword, keyword.URL, err)
+			if err := h.KeywordRepo.RecordCheckError(keyword.ID, err); err != nil {
+				log.Printf("Failed to record keyword check error: %v", err)
+			}
+		}
+	}
+}
+
+// checkKeyword queries the SERP API for a keyword, stores the position and
+// evaluates the keyword's alert rules
+func (h *KeywordHandler) checkKeyword(ctx context.Context, keyword *models.TrackedKeyword) error {
+	searchCtx, cancel := context.WithTimeout(ctx, rankSearchTimeout)
+	defer cancel()
+
+	results, err := h.Provider.Search(searchCtx, serp.Query{
+		Keyword:  keyword.Keyword,
+		Country:  keyword.Country,
+		Language: keyword.Language,
+		Device:   keyword.Device,
+		Depth:    h.depth(),
+	})
+	if err != nil {
+		return err
+	}
+
+	hasPrevious := keyword.LastCheckedAt != nil
+	ranking := &models.KeywordRanking{ID: uuid.New(), Provider: h.Provider.Name()}
+	if position, matched := serp.Rank(results, keyword.URL); position > 0 {
+		ranking.Position = &position
+		ranking.MatchedURL = matched
+	}
+
+	// Alerts are queued with the new position, so a crash can neither lose
+	// nor repeat them
+	alerts := h.keywordAlerts(keyword, ranking, hasPrevious)
+	if err := h.KeywordRepo.RecordCheck(keyword, ranking, alerts...); err != nil {
+		return err
+	}
+	if len(alerts) > 0 {
+		h.Outbox.Notify()
+	}
+	return nil
+}
+
+// keywordAlerts builds the notification to the owner of a keyword about every
+// alert rule a new position check triggers
+func (h *KeywordHandler) keywordAlerts(keyword *models.TrackedKeyword, ranking *models.KeywordRanking, hasPrevious bool) []*models.OutboxMessage {
+	var rules []analyzer.AlertRule
+	if len(keyword.AlertRules) == 0 || json.Unmarshal(keyword.AlertRules, &rules) != nil {
+		return nil
+	}
+
+	values := analyzer.RankAlertValues(ranking.Position, keyword.Position, hasPrevious, h.depth())
+	var triggered []analyzer.TriggeredAlert
+	for _, rule := range rules {
+		if value, ok := rule.Evaluate(values); ok {
+			triggered = append(triggered, analyzer.TriggeredAlert{AlertRule: rule, Value: value})
+		}
+	}
+	if len(triggered) == 0 || h.Hub == nil {
+		return nil
+	}
+
+	msg, err := ws.NewMessage(ws.MessageTypeAlert, "", fiber.Map{
+		"keyword_id":        keyword.ID,
+		"keyword":           keyword.Keyword,
+		"url":               keyword.URL,
+		"position":          ranking.Position,
+		"previous_position": keyword.Position,
+		"alerts":            triggered,
+	})
+	if err != nil {
+		return nil
+	}
+	notification, err := notificationMessage("keyword_alert:"+ranking.ID.String(), keyword.UserID, msg)
+	if err != nil {
+		log.Printf("Failed to queue rank alert notification: %v", err)
+		return nil
+	}
+	return []*models.OutboxMessage{notification}
+}
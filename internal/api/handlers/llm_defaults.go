@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/llm"
+)
+
+// LLMDefaults are the generation parameters an organization uses when a
+// content or code snippet request does not set them
+type LLMDefaults struct {
+	Provider    string   `json:"provider,omitempty"`
+	Model       string   `json:"model,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+}
+
+// params returns the generation parameters of the defaults
+func (d LLMDefaults) params() llm.GenerationParams {
+	return llm.GenerationParams{Model: d.Model, Temperature: d.Temperature, MaxTokens: d.MaxTokens}
+}
+
+// GetLLMDefaults returns the default LLM generation parameters of an organization
+// @Summary Get LLM defaults
+// @Description Returns the provider, model, temperature and max tokens used for the organization's content improvements and code snippets when a request does not set them. Organizations are user accounts; non-admins can only access their own
+// @Tags content-improvements
+// @Produce json
+// @Param id path string true "Organization (user) ID"
+// @Success 200 {object} map[string]interface{} "LLM defaults"
+// @Failure 400 {object} map[string]interface{} "Invalid organization ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Organization not found"
+// @Security BearerAuth
+// @Router /organizations/{id}/llm-defaults [get]
+func (h *ContentImprovementHandler) GetLLMDefaults(c *fiber.Ctx) error {
+	organizationID, status, message := organizationParam(c)
+	if organizationID == uuid.Nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	var user models.User
+	if err := h.UserRepo.FindByID(organizationID, &user); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Organization not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    h.llmDefaults(&user),
+	})
+}
+
+// UpdateLLMDefaults replaces the default LLM generation parameters of an organization
+// @Summary Update LLM defaults
+// @Description Sets the provider, model, temperature and max tokens used for the organization's generations when a request does not set them. Parameters are validated against the capabilities of the provider, listed at /llm/providers. An empty body clears the defaults
+// @Tags content-improvements
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization (user) ID"
+// @Param request body handlers.LLMDefaults true "LLM defaults"
+// @Success 200 {object} map[string]interface{} "LLM defaults updated"
+// @Failure 400 {object} map[string]interface{} "Invalid request or parameters not supported by the provider"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /organizations/{id}/llm-defaults [put]
+func (h *ContentImprovementHandler) UpdateLLMDefaults(c *fiber.Ctx) error {
+	organizationID, status, message := organizationParam(c)
+	if organizationID == uuid.Nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	var defaults LLMDefaults
+	if err := c.BodyParser(&defaults); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+
+	var data datatypes.JSON
+	if defaults.Provider != "" || !defaults.params().IsZero() {
+		capabilities, err := h.LLMService.Capabilities(defaults.Provider)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "Unknown LLM provider",
+			})
+		}
+		if err := capabilities.Validate(defaults.params()); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   err.Error(),
+			})
+		}
+		defaults.Provider = capabilities.Provider
+
+		data, _ = json.Marshal(defaults)
+	}
+
+	if err := h.UserRepo.UpdateLLMDefaults(organizationID, data); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to update LLM defaults: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    defaults,
+	})
+}
+
+// llmDefaults decodes the LLM defaults of a user
+func (h *ContentImprovementHandler) llmDefaults(user *models.User) LLMDefaults {
+	var defaults LLMDefaults
+	if len(user.LLMDefaults) > 0 {
+		_ = json.Unmarshal(user.LLMDefaults, &defaults)
+	}
+	return defaults
+}
+
+// generationParams resolves the provider and generation parameters of a
+// request, filling unset values from the defaults of the organization that
+// owns the analysis. Defaults only apply when the request uses the provider
+// they were set for, since models and limits differ between providers.
+func (h *ContentImprovementHandler) generationParams(organizationID uuid.UUID, providerName string, params llm.GenerationParams) (string, llm.GenerationParams, error) {
+	var user models.User
+	if h.UserRepo != nil && h.UserRepo.FindByID(organizationID, &user) == nil {
+		defaults := h.llmDefaults(&user)
+		if providerName == "" {
+			providerName = defaults.Provider
+		}
+		if providerName == defaults.Provider {
+			params = params.Merge(defaults.params())
+		}
+	}
+	if params.IsZero() {
+		return providerName, params, nil
+	}
+
+	capabilities, err := h.LLMService.Capabilities(providerName)
+	if err != nil {
+		return "", params, errors.New("unknown LLM provider")
+	}
+	if err := capabilities.Validate(params); err != nil {
+		return "", params, err
+	}
+	return capabilities.Provider, params, nil
+}
+
+// llmModelLabel names the provider and model of a generation for storage
+// with its results, e.g. "gemini/gemini-1.5-pro"
+func llmModelLabel(providerName, model string) string {
+	if model == "" {
+		return providerName
+	}
+	return providerName + "/" + model
+}
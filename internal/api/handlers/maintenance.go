@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/maintenance"
+	ws "github.com/chynybekuuludastan/website_optimizer/internal/websocket"
+)
+
+// maxNotificationLength limits the text of broadcast notifications
+const maxNotificationLength = 1000
+
+// MaintenanceHandler toggles the maintenance mode and broadcasts system
+// notifications to connected clients
+type MaintenanceHandler struct {
+	Store *maintenance.Store
+	Hub   *ws.Hub
+}
+
+// NewMaintenanceHandler creates a new maintenance handler
+func NewMaintenanceHandler(store *maintenance.Store, hub *ws.Hub) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		Store: store,
+		Hub:   hub,
+	}
+}
+
+// MaintenanceRequest enables or disables the maintenance mode
+type MaintenanceRequest struct {
+	Enabled bool       `json:"enabled"`
+	Message string     `json:"message" example:"Database upgrade, back at 02:00 UTC"`
+	EndsAt  *time.Time `json:"ends_at"`
+	// Notify announces the change to connected clients; defaults to true
+	Notify *bool `json:"notify"`
+}
+
+// BroadcastRequest is a system notification sent to every connected client
+type BroadcastRequest struct {
+	Level     string     `json:"level" example:"warning"`
+	Title     string     `json:"title" example:"Scheduled maintenance"`
+	Message   string     `json:"message" example:"Analyses will be paused for 15 minutes at 02:00 UTC"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// GetMaintenance returns the maintenance mode
+// @Summary Get maintenance mode
+// @Description Returns whether the service is in maintenance mode, the message shown to users and the expected end. Public, so that clients can show a banner
+// @Tags system
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Maintenance mode"
+// @Router /maintenance [get]
+func (h *MaintenanceHandler) GetMaintenance(c *fiber.Ctx) error {
+	mode := h.Store.Get(c.Context())
+	data := fiber.Map{"enabled": mode.Enabled}
+	if mode.Enabled {
+		data["message"] = mode.UserMessage()
+		data["started_at"] = mode.StartedAt
+		data["ends_at"] = mode.EndsAt
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    data,
+	})
+}
+
+// SetMaintenance enables or disables the maintenance mode
+// @Summary Toggle maintenance mode
+// @Description While maintenance mode is enabled new analyses are rejected with 503 and the message; running analyses finish. The change is announced to connected clients as a system_notification unless notify is false
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body MaintenanceRequest true "Maintenance mode"
+// @Success 200 {object} map[string]interface{} "Maintenance mode updated"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /admin/maintenance [put]
+func (h *MaintenanceHandler) SetMaintenance(c *fiber.Ctx) error {
+	req := new(MaintenanceRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+	if utf8.RuneCountInString(req.Message) > maxNotificationLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Message must be at most " + strconv.Itoa(maxNotificationLength) + " characters",
+		})
+	}
+
+	previous := h.Store.Get(c.Context())
+	mode := maintenance.Mode{Enabled: req.Enabled}
+	if req.Enabled {
+		mode.Message = req.Message
+		mode.EndsAt = req.EndsAt
+		mode.StartedAt = time.Now()
+		if previous.Enabled {
+			mode.StartedAt = previous.StartedAt
+		}
+		if userID, ok := c.Locals("userID").(uuid.UUID); ok {
+			mode.StartedBy = userID.String()
+		}
+	}
+
+	if err := h.Store.Set(c.Context(), mode); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to update maintenance mode: " + err.Error(),
+		})
+	}
+
+	notified := 0
+	if req.Notify == nil || *req.Notify {
+		enabled := mode.Enabled
+		notification := ws.SystemNotification{
+			Level:       ws.NotificationLevelInfo,
+			Title:       "Maintenance completed",
+			Message:     "Maintenance is over. New analyses can be started again.",
+			Maintenance: &enabled,
+		}
+		if mode.Enabled {
+			notification.Level = ws.NotificationLevelWarning
+			notification.Title = "Maintenance"
+			notification.Message = mode.UserMessage()
+			notification.ExpiresAt = mode.EndsAt
+		}
+		notified = h.broadcast(notification)
+	}
+	log.Printf("Maintenance mode set to %t, %d clients notified", mode.Enabled, notified)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"maintenance": mode,
+			"notified":    notified,
+		},
+	})
+}
+
+// Broadcast sends a system notification to every connected client
+// @Summary Broadcast system notification
+// @Description Sends a system_notification message to every client connected to the WebSocket hub of this instance. Level is info, warning or critical
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body BroadcastRequest true "Notification"
+// @Success 200 {object} map[string]interface{} "Number of connections notified"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Security BearerAuth
+// @Router /admin/broadcast [post]
+func (h *MaintenanceHandler) Broadcast(c *fiber.Ctx) error {
+	req := new(BroadcastRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+	if req.Message == "" || utf8.RuneCountInString(req.Message) > maxNotificationLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Message is required and must be at most " + strconv.Itoa(maxNotificationLength) + " characters",
+		})
+	}
+	switch req.Level {
+	case "":
+		req.Level = ws.NotificationLevelInfo
+	case ws.NotificationLevelInfo, ws.NotificationLevelWarning, ws.NotificationLevelCritical:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Level must be info, warning or critical",
+		})
+	}
+
+	notified := h.broadcast(ws.SystemNotification{
+		Level:     req.Level,
+		Title:     req.Title,
+		Message:   req.Message,
+		ExpiresAt: req.ExpiresAt,
+	})
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"notified": notified,
+		},
+	})
+}
+
+// broadcast sends a system notification and returns the number of recipients
+func (h *MaintenanceHandler) broadcast(notification ws.SystemNotification) int {
+	msg, err := ws.NewMessage(ws.MessageTypeSystemNotification, "", notification)
+	if err != nil {
+		log.Printf("Failed to build system notification: %v", err)
+		return 0
+	}
+	return h.Hub.Broadcast(msg)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+)
+
+// MetaHandler serves information about the platform itself
+type MetaHandler struct{}
+
+// NewMetaHandler creates a new meta handler
+func NewMetaHandler() *MetaHandler {
+	return &MetaHandler{}
+}
+
+// GetChangelog returns the versioned changes of analyzer behavior
+// @Summary Get analyzer changelog
+// @Description Lists versioned changes to analyzers, thresholds, score calculation and signature datasets with their effective dates, newest first. Each analysis stores the version it ran with as "scoring_version" in its metadata, so score changes can be told apart from platform changes
+// @Tags meta
+// @Produce json
+// @Param since query string false "Only changes effective on or after this date (YYYY-MM-DD)"
+// @Param component query string false "Only changes to this analyzer or dataset, e.g. seo"
+// @Param affects_score query bool false "Only changes that can change scores"
+// @Success 200 {object} map[string]interface{} "Changelog"
+// @Failure 400 {object} map[string]interface{} "Invalid query"
+// @Router /meta/changelog [get]
+func (h *MetaHandler) GetChangelog(c *fiber.Ctx) error {
+	since := analyzer.Changelog[0].EffectiveDate
+	if value := c.Query("since"); value != "" {
+		parsed, err := parseUsageDate(value, since)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "Invalid since date",
+			})
+		}
+		since = parsed
+	}
+	component := c.Query("component")
+	scoreOnly := c.QueryBool("affects_score")
+
+	entries := make([]analyzer.ChangelogEntry, 0, len(analyzer.Changelog))
+	for i := len(analyzer.Changelog) - 1; i >= 0; i-- {
+		entry := analyzer.Changelog[i]
+		if entry.EffectiveDate.Before(since) || (scoreOnly && !entry.AffectsScore) {
+			continue
+		}
+		if component != "" && !containsString(entry.Components, component) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"current_version": analyzer.ScoringVersion(),
+			"entries":         entries,
+		},
+	})
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
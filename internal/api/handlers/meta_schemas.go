@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/report"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/schema"
+)
+
+// schemaVersion is the version of the published response schemas. It changes
+// whenever a response type changes shape.
+const schemaVersion = "1.0.0"
+
+// publicSchemas are the JSON Schemas of the public response types by name
+var publicSchemas = buildPublicSchemas()
+
+// buildPublicSchemas generates the schemas of the public response types
+func buildPublicSchemas() map[string]*schema.Schema {
+	schemas := map[string]*schema.Schema{
+		"analyzer_result": analyzer.ResultSchema,
+		"analyzer_issue":  analyzer.IssueSchema,
+
+		"analysis":              schema.For(models.Analysis{}, "Analysis", "A website analysis with its metrics, issues and recommendations"),
+		"analysis_metric":       schema.For(models.AnalysisMetric{}, "AnalysisMetric", "The metrics of one analyzer category; value holds an analyzer_result"),
+		"issue":                 schema.For(models.Issue{}, "Issue", "An issue found by an analysis"),
+		"recommendation":        schema.For(models.Recommendation{}, "Recommendation", "A recommendation of an analysis"),
+		"analysis_event":        schema.For(models.AnalysisEvent{}, "AnalysisEvent", "A step of the analysis pipeline"),
+		"domain":                schema.For(models.Domain{}, "Domain", "A property grouping websites"),
+		"monitored_site":        schema.For(models.MonitoredSite{}, "MonitoredSite", "A page analyzed on a schedule"),
+		"report_schedule":       schema.For(models.ReportSchedule{}, "ReportSchedule", "A scheduled email report"),
+		"event_stream":          schema.For(models.EventStream{}, "EventStream", "A sink receiving analysis events"),
+		"analysis_preset":       schema.For(models.AnalysisPreset{}, "AnalysisPreset", "A saved analysis configuration"),
+		"tracked_keyword":       schema.For(models.TrackedKeyword{}, "TrackedKeyword", "A keyword whose ranking is tracked"),
+		"keyword_ranking":       schema.For(models.KeywordRanking{}, "KeywordRanking", "A ranking check of a tracked keyword"),
+		"backlink_snapshot":     schema.For(models.BacklinkSnapshot{}, "BacklinkSnapshot", "The backlink profile of a domain at the time of an analysis"),
+		"issue_feedback":        schema.For(models.IssueFeedback{}, "IssueFeedback", "A user's rating of a reported issue"),
+		"severity_override":     schema.For(models.IssueSeverityOverride{}, "IssueSeverityOverride", "A deployment-wide severity of an issue type"),
+		"issue_doc_link":        schema.For(models.IssueDocLink{}, "IssueDocLink", "Documentation on fixing an issue type on a platform"),
+		"issue_with_docs":       schema.For(IssueWithDocs{}, "IssueWithDocs", "An issue with documentation on fixing it"),
+		"custom_rule":           schema.For(models.CustomRule{}, "CustomRule", "A check an organization runs on every page it analyzes"),
+		"deployment":            schema.For(models.Deployment{}, "Deployment", "A release of a website and its deploy impact"),
+		"deploy_impact":         schema.For(DeployImpact{}, "DeployImpact", "The score and issue changes of a post-deploy analysis"),
+		"content_batch":         schema.For(models.ContentBatch{}, "ContentBatch", "A batch of content improvements for the weakest pages of a domain"),
+		"glossary_term":         schema.For(models.GlossaryTerm{}, "GlossaryTerm", "An approved term of an organization's glossary and the variants it replaces"),
+		"content_batch_page":    schema.For(ContentBatchPage{}, "ContentBatchPage", "The status of one page of a content batch"),
+		"dashboard":             schema.For(Dashboard{}, "Dashboard", "Latest analyses, running analyses, open alerts and quota usage of a user"),
+		"domain_inventory":      schema.For(analyzer.DomainInventory{}, "DomainInventory", "The subdomains of an analyzed domain and their liveness and HTTPS"),
+		"changelog_entry":       schema.For(analyzer.ChangelogEntry{}, "ChangelogEntry", "A versioned change of analyzer behavior"),
+		"report":                schema.For(report.Report{}, "Report", "The summary delivered by a report schedule"),
+		"site_config":           schema.For(SiteConfigDocument{}, "SiteConfigDocument", "The desired state of a user's monitored sites"),
+		"site_sync_plan":        schema.For(SiteSyncPlan{}, "SiteSyncPlan", "The changes of a monitored sites sync"),
+		"site_schedule_preview": schema.For(SiteSchedulePreview{}, "SiteSchedulePreview", "The upcoming runs of a monitored site"),
+		"imported_analysis":     schema.For(ImportedAnalysisSummary{}, "ImportedAnalysisSummary", "An analysis created by an import"),
+		"analysis_score_change": schema.For(AnalysisScoreChange{}, "AnalysisScoreChange", "The score change of a category between two analyses"),
+		"analysis_issue_change": schema.For(AnalysisIssueChange{}, "AnalysisIssueChange", "An issue type found by only one of two analyses"),
+		"systemic_issue":        schema.For(SystemicIssue{}, "SystemicIssue", "An issue type found on many pages of an organization"),
+		"issue_trend":           schema.For(IssueTrend{}, "IssueTrend", "How often an issue type occurred over time"),
+		"technology_change":     schema.For(TechnologyChange{}, "TechnologyChange", "A technology change between two analyses"),
+		"status_feed":           schema.For(StatusFeed{}, "StatusFeed", "The public status page payload"),
+		"token_response":        schema.For(TokenResponse{}, "TokenResponse", "Issued authentication tokens"),
+	}
+	for name, s := range schemas {
+		published := *s
+		published.ID = "/api/meta/schemas/" + name
+		schemas[name] = &published
+	}
+	return schemas
+}
+
+// ListSchemas returns the index of the published response schemas
+// @Summary List response schemas
+// @Description Lists the JSON Schemas (draft 2020-12) of the public response types, such as analyses, issues, monitored sites and analyzer results. Responses wrap these types in the "data" field of the usual success envelope
+// @Tags meta
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Schema index"
+// @Router /meta/schemas [get]
+func (h *MetaHandler) ListSchemas(c *fiber.Ctx) error {
+	names := make([]string, 0, len(publicSchemas))
+	for name := range publicSchemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	entries := make([]fiber.Map, 0, len(names))
+	for _, name := range names {
+		entries = append(entries, fiber.Map{
+			"name":        name,
+			"title":       publicSchemas[name].Title,
+			"description": publicSchemas[name].Description,
+			"url":         publicSchemas[name].ID,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"version": schemaVersion,
+			"schemas": entries,
+		},
+	})
+}
+
+// GetSchema returns one published response schema
+// @Summary Get a response schema
+// @Description Returns the JSON Schema of a public response type as a plain schema document, without the success envelope
+// @Tags meta
+// @Produce json
+// @Param name path string true "Schema name, e.g. issue"
+// @Success 200 {object} map[string]interface{} "JSON Schema"
+// @Failure 404 {object} map[string]interface{} "Unknown schema"
+// @Router /meta/schemas/{name} [get]
+func (h *MetaHandler) GetSchema(c *fiber.Ctx) error {
+	s, ok := publicSchemas[c.Params("name")]
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Schema not found",
+		})
+	}
+
+	c.Set("X-Schema-Version", schemaVersion)
+	return c.JSON(s)
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/billing"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/queue"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/schedule"
+	ws "github.com/chynybekuuludastan/website_optimizer/internal/websocket"
+)
+
+const (
+	// monitorTickInterval is how often due monitored sites are looked up
+	monitorTickInterval = time.Minute
+	// monitorBatchSize limits how many due sites are started per tick
+	monitorBatchSize = 50
+	// minScheduleInterval is the shortest schedule a monitored site may use
+	minScheduleInterval = 15 * time.Minute
+)
+
+// namedSchedules are the schedule names accepted besides Go durations
+var namedSchedules = map[string]time.Duration{
+	"hourly": time.Hour,
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+// parseSchedule returns the interval of a schedule; zero means the site is
+// only analyzed on demand
+func parseSchedule(schedule string) (time.Duration, error) {
+	if schedule == "" {
+		return 0, nil
+	}
+	if interval, ok := namedSchedules[schedule]; ok {
+		return interval, nil
+	}
+	interval, err := time.ParseDuration(schedule)
+	if err != nil {
+		return 0, fmt.Errorf("schedule must be hourly, daily, weekly or a duration such as 6h")
+	}
+	if interval < minScheduleInterval {
+		return 0, fmt.Errorf("schedule must be at least %s", minScheduleInterval)
+	}
+	return interval, nil
+}
+
+// siteSchedule returns the schedule of a monitored site in its time zone,
+// or nil when the site is only analyzed on demand
+func siteSchedule(site *models.MonitoredSite) (*schedule.Schedule, error) {
+	interval, err := parseSchedule(site.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	var blackouts []schedule.Window
+	if len(site.Blackouts) > 0 {
+		if err := json.Unmarshal(site.Blackouts, &blackouts); err != nil {
+			return nil, fmt.Errorf("invalid blackouts: %w", err)
+		}
+	}
+	if interval == 0 {
+		if site.RunAt != "" || len(blackouts) > 0 {
+			return nil, errors.New("run_at and blackouts require a schedule")
+		}
+		return nil, nil
+	}
+	return schedule.New(interval, site.TimeZone, site.RunAt, blackouts)
+}
+
+// siteBudgets decodes the budgets stored on a monitored site
+func siteBudgets(site *models.MonitoredSite) (analyzer.PresetBudgets, bool) {
+	var budgets analyzer.PresetBudgets
+	if len(site.Budgets) == 0 || json.Unmarshal(site.Budgets, &budgets) != nil {
+		return budgets, false
+	}
+	return budgets, true
+}
+
+// siteAlertRules decodes the alert rules stored on a monitored site
+func siteAlertRules(site *models.MonitoredSite) []analyzer.AlertRule {
+	var rules []analyzer.AlertRule
+	if len(site.AlertRules) > 0 {
+		json.Unmarshal(site.AlertRules, &rules)
+	}
+	return rules
+}
+
+// RunMonitors starts the analyses of due monitored sites until the context
+// is cancelled
+func (a *AnalysisHandler) RunMonitors(ctx context.Context) {
+	if a.MonitoredSiteRepo == nil {
+		return
+	}
+	ticker := time.NewTicker(monitorTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.startDueMonitors(ctx)
+		}
+	}
+}
+
+// startDueMonitors starts one analysis for every due monitored site
+func (a *AnalysisHandler) startDueMonitors(ctx context.Context) {
+	now := time.Now()
+	sites, err := a.MonitoredSiteRepo.FindDue(now, monitorBatchSize)
+	if err != nil {
+		log.Printf("Failed to load due monitored sites: %v", err)
+		return
+	}
+
+	for i := range sites {
+		site := &sites[i]
+		sched, err := siteSchedule(site)
+		if err != nil || sched == nil {
+			continue
+		}
+
+		// Runs that became due before a blackout window was added wait
+		// until the window ends
+		if end, blocked := sched.BlackoutEnd(now); blocked {
+			if _, err := a.MonitoredSiteRepo.ClaimRun(site.ID, *site.NextRunAt, end); err != nil {
+				log.Printf("Failed to postpone scheduled run of %s: %v", site.URL, err)
+			}
+			continue
+		}
+
+		// Several server instances may see the same due site
+		claimed, err := a.MonitoredSiteRepo.ClaimRun(site.ID, *site.NextRunAt, sched.Next(now))
+		if err != nil || !claimed {
+			continue
+		}
+
+		analysisID, err := a.startMonitoredAnalysis(ctx, site)
+		if err != nil {
+			log.Printf("Failed to start scheduled analysis of %s: %v", site.URL, err)
+		}
+		if err := a.MonitoredSiteRepo.MarkRun(site.ID, analysisID, err); err != nil {
+			log.Printf("Failed to record scheduled run of %s: %v", site.URL, err)
+		}
+	}
+}
+
+// startMonitoredAnalysis queues a low-priority analysis of a monitored site
+func (a *AnalysisHandler) startMonitoredAnalysis(ctx context.Context, site *models.MonitoredSite) (*uuid.UUID, error) {
+	if a.Quota != nil {
+		if _, err := a.Quota.Check(ctx, site.UserID, billing.ResourceAnalyses); errors.Is(err, billing.ErrQuotaExceeded) {
+			return nil, fmt.Errorf("analysis quota of the plan has been reached")
+		}
+	}
+
+	var preset *analyzer.Preset
+	if site.Preset != "" {
+		resolved, err := ResolvePreset(a.PresetRepo, site.UserID, site.Preset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load preset %s: %w", site.Preset, err)
+		}
+		// The site's timeout budget applies to the run itself
+		if budgets, ok := siteBudgets(site); ok && budgets.TimeoutSeconds > 0 {
+			resolved.Budgets.TimeoutSeconds = budgets.TimeoutSeconds
+		}
+		preset = resolved
+	}
+
+	analysis, _, err := a.startAnalysis(site.UserID, site.URL, parser.RequestOverrides{}, preset, queue.PriorityLow, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &analysis.ID, nil
+}
+
+// monitoredSite returns the monitored site whose scheduled run produced the
+// analysis, or nil for analyses started by hand
+func (a *AnalysisHandler) monitoredSite(analysisID uuid.UUID) *models.MonitoredSite {
+	if a.MonitoredSiteRepo == nil {
+		return nil
+	}
+	site, err := a.MonitoredSiteRepo.FindByLastAnalysisID(analysisID)
+	if err != nil {
+		return nil
+	}
+	return site
+}
+
+// budgetPreset returns the preset whose budgets an analysis is checked
+// against. Budgets of a monitored site replace those of its preset.
+func budgetPreset(preset *analyzer.Preset, site *models.MonitoredSite) *analyzer.Preset {
+	if site == nil {
+		return preset
+	}
+	budgets, ok := siteBudgets(site)
+	if !ok || !budgets.HasLimits() {
+		return preset
+	}
+
+	effective := analyzer.Preset{Key: site.Preset}
+	if preset != nil {
+		effective = *preset
+	}
+	effective.Budgets = budgets
+	return &effective
+}
+
+// evaluateSiteAlerts records an event and notifies the owner for every alert
+// rule of a monitored site that the analysis triggers
+func (a *AnalysisHandler) evaluateSiteAlerts(site *models.MonitoredSite, analysisID uuid.UUID, values map[string]float64) {
+	var triggered []analyzer.TriggeredAlert
+	for _, rule := range siteAlertRules(site) {
+		if value, ok := rule.Evaluate(values); ok {
+			triggered = append(triggered, analyzer.TriggeredAlert{AlertRule: rule, Value: value})
+		}
+	}
+	if len(triggered) == 0 {
+		return
+	}
+
+	events := make([]models.AnalysisEvent, 0, len(triggered))
+	for _, alert := range triggered {
+		condition := fmt.Sprintf("%s %s %g (actual %g)", alert.Metric, alert.Operator, alert.Threshold, alert.Value)
+		message := "Alert: " + condition
+		if alert.Name != "" {
+			message = "Alert " + alert.Name + ": " + condition
+		}
+		events = append(events, newAnalysisEvent(analysisID, models.AnalysisEventAlertTriggered, "", message, 0, map[string]interface{}{
+			"site_id": site.ID,
+			"rule":    alert.AlertRule,
+			"value":   alert.Value,
+		}))
+	}
+
+	// The notification is queued with the alert events
+	var notifications []*models.OutboxMessage
+	if a.Hub != nil {
+		msg, err := ws.NewMessage(ws.MessageTypeAlert, ws.AnalysisRoom(analysisID.String()), fiber.Map{
+			"analysis_id": analysisID,
+			"site_id":     site.ID,
+			"url":         site.URL,
+			"alerts":      triggered,
+		})
+		if err == nil {
+			if notification, err := notificationMessage(events[0].ID.String(), site.UserID, msg); err == nil {
+				notifications = append(notifications, notification)
+			}
+		}
+	}
+	a.recordEventWithEffects(events, notifications)
+}
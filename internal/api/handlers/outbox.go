@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/email"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/outbox"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/report"
+	ws "github.com/chynybekuuludastan/website_optimizer/internal/websocket"
+)
+
+// maxDeadLetters limits the dead letters listed to administrators
+const maxDeadLetters = 100
+
+// streamDeliveryPayload delivers the findings of an analysis to a stream.
+// The findings are loaded when the message is delivered.
+type streamDeliveryPayload struct {
+	StreamID   uuid.UUID `json:"stream_id"`
+	AnalysisID uuid.UUID `json:"analysis_id"`
+	URL        string    `json:"url"`
+}
+
+// webSocketPayload is an acknowledged notification to a user
+type webSocketPayload struct {
+	UserID  uuid.UUID   `json:"user_id"`
+	Message *ws.Message `json:"message"`
+}
+
+// reportEmailPayload delivers the report of a schedule run to one recipient
+type reportEmailPayload struct {
+	ScheduleID uuid.UUID        `json:"schedule_id"`
+	Recipient  report.Recipient `json:"recipient"`
+	At         time.Time        `json:"at"`
+}
+
+// notificationMessage builds the outbox message of an acknowledged
+// WebSocket notification. The message ID is the key, so clients can
+// deduplicate a notification delivered twice.
+func notificationMessage(key string, userID uuid.UUID, msg *ws.Message) (*models.OutboxMessage, error) {
+	msg.ID = uuid.NewSHA1(uuid.NameSpaceURL, []byte(key+":"+userID.String())).String()
+	return outbox.NewMessage(outbox.KindWebSocket, msg.ID, webSocketPayload{UserID: userID, Message: msg})
+}
+
+// streamDeliveries builds the messages delivering the findings of an
+// analysis to event streams
+func streamDeliveries(streams []models.EventStream, analysisID uuid.UUID, pageURL string) []*models.OutboxMessage {
+	messages := make([]*models.OutboxMessage, 0, len(streams))
+	for _, s := range streams {
+		msg, err := outbox.NewMessage(outbox.KindStreamDelivery, s.ID.String()+":"+analysisID.String(), streamDeliveryPayload{
+			StreamID:   s.ID,
+			AnalysisID: analysisID,
+			URL:        pageURL,
+		})
+		if err != nil {
+			log.Printf("Failed to queue delivery to stream %s: %v", s.ID, err)
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+// RegisterOutboxHandlers delivers the stream deliveries and WebSocket
+// notifications queued by analyses
+func (a *AnalysisHandler) RegisterOutboxHandlers(dispatcher *outbox.Dispatcher) {
+	a.Outbox = dispatcher
+	if a.EventStreamRepo != nil {
+		dispatcher.Handle(outbox.KindStreamDelivery, a.deliverStreamMessage)
+	}
+	if a.Hub != nil {
+		dispatcher.Handle(outbox.KindWebSocket, a.deliverWebSocketMessage)
+	}
+}
+
+// deliverStreamMessage sends the findings of an analysis to one stream and
+// records the outcome on the stream
+func (a *AnalysisHandler) deliverStreamMessage(ctx context.Context, msg models.OutboxMessage) error {
+	var payload streamDeliveryPayload
+	if err := outbox.Decode(msg, &payload); err != nil {
+		return err
+	}
+
+	var s models.EventStream
+	if err := a.EventStreamRepo.FindByID(payload.StreamID, &s); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return outbox.Permanent(fmt.Errorf("stream %s was deleted", payload.StreamID))
+		}
+		return err
+	}
+	if !s.Active {
+		return nil
+	}
+
+	metrics, err := a.MetricsRepo.FindByAnalysisID(payload.AnalysisID)
+	if err != nil {
+		return fmt.Errorf("failed to load metrics: %w", err)
+	}
+	issues, err := a.IssueRepo.FindByAnalysisID(payload.AnalysisID)
+	if err != nil {
+		return fmt.Errorf("failed to load issues: %w", err)
+	}
+	events := streamEvents(payload.AnalysisID, payload.URL, metrics, issues)
+	if len(events) == 0 {
+		return nil
+	}
+
+	return deliverToStream(ctx, a.EventStreamRepo, &s, events)
+}
+
+// deliverWebSocketMessage hands a notification to the hub, which keeps it
+// until the user acknowledges it
+func (a *AnalysisHandler) deliverWebSocketMessage(ctx context.Context, msg models.OutboxMessage) error {
+	var payload webSocketPayload
+	if err := outbox.Decode(msg, &payload); err != nil {
+		return err
+	}
+	if payload.Message == nil {
+		return outbox.Permanent(errors.New("notification without message"))
+	}
+	return a.Hub.SendCritical(ctx, payload.UserID.String(), payload.Message)
+}
+
+// RegisterOutboxHandlers delivers the report emails queued by schedule runs
+func (h *ReportHandler) RegisterOutboxHandlers(dispatcher *outbox.Dispatcher) {
+	h.Outbox = dispatcher
+	if h.Sender != nil {
+		dispatcher.Handle(outbox.KindReportEmail, h.deliverReportEmail)
+	}
+}
+
+// deliverReportEmail builds the report of a schedule run and emails it to
+// one recipient. The outcome is recorded on the schedule.
+func (h *ReportHandler) deliverReportEmail(ctx context.Context, msg models.OutboxMessage) error {
+	var payload reportEmailPayload
+	if err := outbox.Decode(msg, &payload); err != nil {
+		return err
+	}
+
+	var schedule models.ReportSchedule
+	if err := h.ReportRepo.FindByID(payload.ScheduleID, &schedule); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return outbox.Permanent(fmt.Errorf("report schedule %s was deleted", payload.ScheduleID))
+		}
+		return err
+	}
+
+	rpt, err := h.buildReport(&schedule, payload.At)
+	if err == nil {
+		var message email.Message
+		if message, err = reportMessage(rpt, payload.Recipient, h.Signer, reportSubject(&schedule)); err == nil {
+			err = h.Sender.Send(ctx, message)
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("%s: %w", payload.Recipient.Email, err)
+	}
+	if markErr := h.ReportRepo.MarkRun(schedule.ID, err); markErr != nil {
+		log.Printf("Failed to record delivery of report schedule %s: %v", schedule.ID, markErr)
+	}
+	return err
+}
+
+// OutboxHandler reports the delivery of queued side effects to
+// administrators
+type OutboxHandler struct {
+	OutboxRepo repository.OutboxRepository
+	Dispatcher *outbox.Dispatcher
+}
+
+// NewOutboxHandler creates a new outbox handler
+func NewOutboxHandler(repoFactory *repository.Factory, dispatcher *outbox.Dispatcher) *OutboxHandler {
+	return &OutboxHandler{
+		OutboxRepo: repoFactory.OutboxRepository,
+		Dispatcher: dispatcher,
+	}
+}
+
+// GetOutboxStats returns the queued side effects by state and the dead letters
+// @Summary Get outbox statistics
+// @Description Returns the number of outbox messages (stream deliveries, report emails and WebSocket notifications) of each kind by state: pending, delivered or dead, and the most recent dead letters, which failed on every attempt or permanently
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Outbox statistics"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /admin/outbox [get]
+func (h *OutboxHandler) GetOutboxStats(c *fiber.Ctx) error {
+	counts, err := h.OutboxRepo.CountByStatus()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to count outbox messages",
+		})
+	}
+	dead, err := h.OutboxRepo.FindDead(maxDeadLetters)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load dead letters",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"counts":       counts,
+			"dead_letters": dead,
+		},
+	})
+}
+
+// RequeueOutboxMessage schedules a dead letter for delivery again
+// @Summary Requeue a dead letter
+// @Description Schedules a dead outbox message for delivery again with a fresh budget of attempts, e.g. after the endpoint it failed on was fixed
+// @Tags admin
+// @Produce json
+// @Param id path string true "Outbox message ID"
+// @Success 200 {object} map[string]interface{} "Message requeued"
+// @Failure 400 {object} map[string]interface{} "Invalid ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Dead letter not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /admin/outbox/{id}/requeue [post]
+func (h *OutboxHandler) RequeueOutboxMessage(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid outbox message ID",
+		})
+	}
+
+	requeued, err := h.OutboxRepo.Requeue(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to requeue outbox message",
+		})
+	}
+	if !requeued {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Dead letter not found",
+		})
+	}
+	h.Dispatcher.Notify()
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Outbox message requeued",
+	})
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+)
+
+// errPresetNotFound is returned when neither a built-in nor a user preset has the key
+var errPresetNotFound = errors.New("preset not found")
+
+// SavePresetRequest is the body of a preset create or update request
+type SavePresetRequest struct {
+	Name        string                      `json:"name" validate:"required"`
+	Description string                      `json:"description"`
+	Analyzers   []analyzer.AnalyzerType     `json:"analyzers" validate:"required"`
+	Parse       analyzer.PresetParseOptions `json:"parse"`
+	Budgets     analyzer.PresetBudgets      `json:"budgets"`
+}
+
+// presetDefinition is the stored part of a user preset
+type presetDefinition struct {
+	Analyzers []analyzer.AnalyzerType     `json:"analyzers"`
+	Parse     analyzer.PresetParseOptions `json:"parse"`
+	Budgets   analyzer.PresetBudgets      `json:"budgets"`
+}
+
+type PresetHandler struct {
+	PresetRepo repository.PresetRepository
+}
+
+// NewPresetHandler creates a new analysis preset handler
+func NewPresetHandler(repoFactory *repository.Factory) *PresetHandler {
+	return &PresetHandler{
+		PresetRepo: repoFactory.PresetRepository,
+	}
+}
+
+// builtinPreset returns the built-in preset with the given key
+func builtinPreset(key string) (analyzer.Preset, bool) {
+	for _, preset := range analyzer.BuiltinPresets() {
+		if preset.Key == key {
+			return preset, true
+		}
+	}
+	return analyzer.Preset{}, false
+}
+
+// presetFromModel converts a stored user preset
+func presetFromModel(m *models.AnalysisPreset) (analyzer.Preset, error) {
+	var definition presetDefinition
+	if err := json.Unmarshal(m.Definition, &definition); err != nil {
+		return analyzer.Preset{}, fmt.Errorf("invalid definition of preset %s: %w", m.Key, err)
+	}
+
+	_, builtIn := builtinPreset(m.Key)
+	return analyzer.Preset{
+		Key:         m.Key,
+		Name:        m.Name,
+		Description: m.Description,
+		Analyzers:   definition.Analyzers,
+		Parse:       definition.Parse,
+		Budgets:     definition.Budgets,
+		BuiltIn:     builtIn,
+		Customized:  builtIn,
+	}, nil
+}
+
+// ResolvePreset returns the preset a user refers to by key. A user preset
+// takes precedence over the built-in preset with the same key.
+func ResolvePreset(repo repository.PresetRepository, userID uuid.UUID, key string) (*analyzer.Preset, error) {
+	stored, err := repo.FindByKey(userID, key)
+	if err == nil {
+		preset, err := presetFromModel(stored)
+		if err != nil {
+			return nil, err
+		}
+		return &preset, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if preset, ok := builtinPreset(key); ok {
+		return &preset, nil
+	}
+	return nil, errPresetNotFound
+}
+
+// ListPresets returns the built-in presets merged with the user's presets
+// @Summary List analysis presets
+// @Description Returns the built-in presets, with the user's customizations applied, followed by the user's own presets
+// @Tags presets
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Presets"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /presets [get]
+func (h *PresetHandler) ListPresets(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	stored, err := h.PresetRepo.FindByUserID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load presets: " + err.Error(),
+		})
+	}
+
+	custom := make(map[string]analyzer.Preset, len(stored))
+	for i := range stored {
+		preset, err := presetFromModel(&stored[i])
+		if err != nil {
+			continue
+		}
+		custom[preset.Key] = preset
+	}
+
+	presets := make([]analyzer.Preset, 0, len(stored)+len(analyzer.BuiltinPresets()))
+	for _, preset := range analyzer.BuiltinPresets() {
+		if customized, ok := custom[preset.Key]; ok {
+			preset = customized
+			delete(custom, preset.Key)
+		}
+		presets = append(presets, preset)
+	}
+	for i := range stored {
+		if preset, ok := custom[stored[i].Key]; ok {
+			presets = append(presets, preset)
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    presets,
+	})
+}
+
+// GetPreset returns a single preset
+// @Summary Get an analysis preset
+// @Description Returns a preset by key, with the user's customization applied
+// @Tags presets
+// @Produce json
+// @Param key path string true "Preset key"
+// @Success 200 {object} map[string]interface{} "Preset"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Preset not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /presets/{key} [get]
+func (h *PresetHandler) GetPreset(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	preset, err := ResolvePreset(h.PresetRepo, userID, c.Params("key"))
+	if errors.Is(err, errPresetNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Preset not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load preset: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    preset,
+	})
+}
+
+// SavePreset creates a user preset or customizes a built-in one
+// @Summary Create or customize an analysis preset
+// @Description Stores a preset for the current user. Using the key of a built-in preset customizes it for this user only
+// @Tags presets
+// @Accept json
+// @Produce json
+// @Param key path string true "Preset key"
+// @Param preset body SavePresetRequest true "Preset definition"
+// @Success 200 {object} map[string]interface{} "Preset saved"
+// @Failure 400 {object} map[string]interface{} "Invalid preset"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /presets/{key} [put]
+func (h *PresetHandler) SavePreset(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	req := new(SavePresetRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+
+	preset := analyzer.Preset{
+		Key:         c.Params("key"),
+		Name:        req.Name,
+		Description: req.Description,
+		Analyzers:   req.Analyzers,
+		Parse:       req.Parse,
+		Budgets:     req.Budgets,
+	}
+	if err := preset.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	definition, err := json.Marshal(presetDefinition{
+		Analyzers: preset.Analyzers,
+		Parse:     preset.Parse,
+		Budgets:   preset.Budgets,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to encode preset",
+		})
+	}
+
+	stored := &models.AnalysisPreset{
+		UserID:      userID,
+		Key:         preset.Key,
+		Name:        preset.Name,
+		Description: preset.Description,
+		Definition:  datatypes.JSON(definition),
+	}
+	if err := h.PresetRepo.Save(stored); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	_, preset.BuiltIn = builtinPreset(preset.Key)
+	preset.Customized = preset.BuiltIn
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    preset,
+	})
+}
+
+// DeletePreset removes a user preset or reverts a customized built-in preset
+// @Summary Delete an analysis preset
+// @Description Deletes a user preset. For a built-in key the user's customization is removed and the default applies again
+// @Tags presets
+// @Produce json
+// @Param key path string true "Preset key"
+// @Success 200 {object} map[string]interface{} "Preset deleted"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Preset not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /presets/{key} [delete]
+func (h *PresetHandler) DeletePreset(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	deleted, err := h.PresetRepo.DeleteByKey(userID, c.Params("key"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to delete preset: " + err.Error(),
+		})
+	}
+	if !deleted {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "No custom preset with this key",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Preset deleted",
+	})
+}
@@ -0,0 +1,778 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/config"
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/email"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/issuedocs"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/outbox"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/report"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/signing"
+)
+
+const (
+	// reportTickInterval is how often due report schedules are looked up
+	reportTickInterval = 5 * time.Minute
+	// reportBatchSize limits how many reports are sent per tick
+	reportBatchSize = 20
+	// reportSendTimeout bounds building and delivering one report
+	reportSendTimeout = 2 * time.Minute
+	// maxReportSchedules limits the report schedules of a user
+	maxReportSchedules = 20
+	// maxReportRecipients limits the recipients of a report schedule
+	maxReportRecipients = 20
+)
+
+// ReportScheduleRequest is the body of a report schedule request
+type ReportScheduleRequest struct {
+	Name      string `json:"name"`
+	Frequency string `json:"frequency"` // weekly or monthly
+	// SiteIDs limits the report to some monitored sites; all when empty
+	SiteIDs    []uuid.UUID        `json:"site_ids"`
+	Recipients []report.Recipient `json:"recipients"`
+	Active     *bool              `json:"active"`
+}
+
+// ReportHandler manages scheduled email reports of monitored sites
+type ReportHandler struct {
+	ReportRepo        repository.ReportScheduleRepository
+	MonitoredSiteRepo repository.MonitoredSiteRepository
+	IssueRepo         repository.IssueRepository
+	KeywordRepo       repository.KeywordRepository
+	PageEntityRepo    repository.PageEntityRepository
+	DocLinkRepo       repository.IssueDocLinkRepository
+	// Sender is nil when email delivery is not configured
+	Sender email.Sender
+	// Outbox delivers the emails of scheduled runs
+	Outbox *outbox.Dispatcher
+	// Signer signs exported reports; nil when signing is not configured
+	Signer *signing.Signer
+}
+
+// NewReportHandler creates a new report handler. Reports can be previewed
+// but are not delivered when no SMTP server is configured.
+func NewReportHandler(repoFactory *repository.Factory, cfg *config.Config) *ReportHandler {
+	h := &ReportHandler{
+		ReportRepo:        repoFactory.ReportScheduleRepository,
+		MonitoredSiteRepo: repoFactory.MonitoredSiteRepository,
+		IssueRepo:         repoFactory.IssueRepository,
+		KeywordRepo:       repoFactory.KeywordRepository,
+		PageEntityRepo:    repoFactory.PageEntityRepository,
+		DocLinkRepo:       repoFactory.IssueDocLinkRepository,
+	}
+	sender, err := email.NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+	switch {
+	case err == nil:
+		h.Sender = sender
+	case !errors.Is(err, email.ErrNotConfigured):
+		log.Printf("Report delivery disabled: %v", err)
+	}
+	return h
+}
+
+// ListReportSchedules returns the report schedules of the current user
+// @Summary List report schedules
+// @Description Returns the user's scheduled email reports with their recipients and delivery status
+// @Tags reports
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Report schedules"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /reports/schedules [get]
+func (h *ReportHandler) ListReportSchedules(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	schedules, err := h.ReportRepo.FindByUserID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch report schedules: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    schedules,
+		"meta": fiber.Map{
+			"delivery_enabled": h.Sender != nil,
+		},
+	})
+}
+
+// CreateReportSchedule schedules a periodic email report
+// @Summary Create a report schedule
+// @Description Schedules a weekly or monthly email report of the user's monitored sites with the overall score trend, new issues and resolved issues of each site over the period. Each recipient chooses HTML or PDF and the sections included. The first report is sent one period after creation
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param schedule body ReportScheduleRequest true "Report schedule"
+// @Success 201 {object} map[string]interface{} "Report schedule created"
+// @Failure 400 {object} map[string]interface{} "Invalid schedule"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /reports/schedules [post]
+func (h *ReportHandler) CreateReportSchedule(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	req := new(ReportScheduleRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+
+	existing, err := h.ReportRepo.FindByUserID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch report schedules: " + err.Error(),
+		})
+	}
+	if len(existing) >= maxReportSchedules {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   fmt.Sprintf("At most %d report schedules are allowed", maxReportSchedules),
+		})
+	}
+
+	schedule := &models.ReportSchedule{UserID: userID, Active: true}
+	if err := h.applyReportRequest(schedule, req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	next := report.NextRun(schedule.Frequency, time.Now())
+	schedule.NextRunAt = &next
+
+	if err := h.ReportRepo.Create(schedule); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to create report schedule: " + err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data":    schedule,
+	})
+}
+
+// UpdateReportSchedule replaces the settings of a report schedule
+// @Summary Update a report schedule
+// @Description Replaces the name, frequency, sites and recipients of a report schedule. Changing the frequency restarts the schedule from now
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param id path string true "Report schedule ID"
+// @Param schedule body ReportScheduleRequest true "Report schedule"
+// @Success 200 {object} map[string]interface{} "Report schedule updated"
+// @Failure 400 {object} map[string]interface{} "Invalid schedule"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Report schedule not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /reports/schedules/{id} [put]
+func (h *ReportHandler) UpdateReportSchedule(c *fiber.Ctx) error {
+	schedule, status, message := h.findReportSchedule(c)
+	if schedule == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	req := new(ReportScheduleRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+
+	frequency := schedule.Frequency
+	if err := h.applyReportRequest(schedule, req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	if schedule.Frequency != frequency || schedule.NextRunAt == nil {
+		next := report.NextRun(schedule.Frequency, time.Now())
+		schedule.NextRunAt = &next
+	}
+
+	if err := h.ReportRepo.Update(schedule); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to update report schedule: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    schedule,
+	})
+}
+
+// DeleteReportSchedule stops a scheduled report
+// @Summary Delete a report schedule
+// @Description Removes a report schedule; no further reports are sent
+// @Tags reports
+// @Produce json
+// @Param id path string true "Report schedule ID"
+// @Success 200 {object} map[string]interface{} "Report schedule deleted"
+// @Failure 400 {object} map[string]interface{} "Invalid ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Report schedule not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /reports/schedules/{id} [delete]
+func (h *ReportHandler) DeleteReportSchedule(c *fiber.Ctx) error {
+	schedule, status, message := h.findReportSchedule(c)
+	if schedule == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	if err := h.ReportRepo.Delete(schedule); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to delete report schedule: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Report schedule deleted",
+	})
+}
+
+// PreviewReport renders the report a schedule would send now
+// @Summary Preview a scheduled report
+// @Description Builds the report of a schedule for the period ending now and returns it as an HTML or PDF document, or as JSON data. All sections are included. When the server has a signing key, HTML and PDF documents come with a detached JWS in the X-Signature header and JSON data with a "signature" embedding it; both can be checked at /signatures/verify
+// @Tags reports
+// @Produce json,html,application/pdf
+// @Param id path string true "Report schedule ID"
+// @Param format query string false "Document format (json, html, pdf)" default(json)
+// @Success 200 {object} map[string]interface{} "Report"
+// @Failure 400 {object} map[string]interface{} "Invalid format"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Report schedule not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /reports/schedules/{id}/preview [get]
+func (h *ReportHandler) PreviewReport(c *fiber.Ctx) error {
+	schedule, status, message := h.findReportSchedule(c)
+	if schedule == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	format := c.Query("format", "json")
+	if format != "json" && format != report.FormatHTML && format != report.FormatPDF {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Format must be json, html or pdf",
+		})
+	}
+
+	rpt, err := h.buildReport(schedule, time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to build report: " + err.Error(),
+		})
+	}
+
+	switch format {
+	case report.FormatHTML:
+		document, err := report.RenderHTML(rpt, report.Recipient{})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"success": false,
+				"error":   err.Error(),
+			})
+		}
+		if jws := signDocument(h.Signer, document, fiber.MIMETextHTMLCharsetUTF8, reportSubject(schedule)); jws != "" {
+			c.Set(signatureHeader, jws)
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.Send(document)
+	case report.FormatPDF:
+		document, err := report.RenderPDF(rpt, report.Recipient{})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"success": false,
+				"error":   err.Error(),
+			})
+		}
+		if jws := signDocument(h.Signer, document, "application/pdf", reportSubject(schedule)); jws != "" {
+			c.Set(signatureHeader, jws)
+		}
+		c.Set(fiber.HeaderContentType, "application/pdf")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`inline; filename="%s"`, reportFilename(rpt)))
+		return c.Send(document)
+	}
+
+	response := fiber.Map{
+		"success": true,
+		"data":    rpt,
+	}
+	if jws := signData(h.Signer, rpt, reportSubject(schedule)); jws != "" {
+		response["signature"] = jws
+	}
+	return c.JSON(response)
+}
+
+// SendReport delivers the report of a schedule immediately
+// @Summary Send a scheduled report now
+// @Description Builds the report of a schedule for the period ending now and emails it to all recipients in their preferred format. The regular schedule is not changed
+// @Tags reports
+// @Produce json
+// @Param id path string true "Report schedule ID"
+// @Success 200 {object} map[string]interface{} "Report sent"
+// @Failure 400 {object} map[string]interface{} "Invalid ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Report schedule not found"
+// @Failure 502 {object} map[string]interface{} "Delivery failed"
+// @Failure 503 {object} map[string]interface{} "Email delivery is not configured"
+// @Security BearerAuth
+// @Router /reports/schedules/{id}/send [post]
+func (h *ReportHandler) SendReport(c *fiber.Ctx) error {
+	if h.Sender == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"success": false,
+			"error":   "Email delivery is not configured",
+		})
+	}
+
+	schedule, status, message := h.findReportSchedule(c)
+	if schedule == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), reportSendTimeout)
+	defer cancel()
+
+	sendErr := h.sendReport(ctx, schedule, time.Now())
+	if err := h.ReportRepo.MarkRun(schedule.ID, sendErr); err != nil {
+		log.Printf("Failed to record delivery of report schedule %s: %v", schedule.ID, err)
+	}
+	if sendErr != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to send report: " + sendErr.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Report sent",
+	})
+}
+
+// findReportSchedule loads the report schedule named in the path if it
+// belongs to the user
+func (h *ReportHandler) findReportSchedule(c *fiber.Ctx) (*models.ReportSchedule, int, string) {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.StatusBadRequest, "Invalid report schedule ID"
+	}
+
+	schedule, err := h.ReportRepo.FindForUser(userID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.StatusNotFound, "Report schedule not found"
+	}
+	if err != nil {
+		return nil, fiber.StatusInternalServerError, "Failed to load report schedule: " + err.Error()
+	}
+	return schedule, fiber.StatusOK, ""
+}
+
+// applyReportRequest validates a request and copies it into a schedule
+func (h *ReportHandler) applyReportRequest(schedule *models.ReportSchedule, req *ReportScheduleRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 255 {
+		return errors.New("name must be between 1 and 255 characters")
+	}
+	if !report.ValidFrequency(req.Frequency) {
+		return errors.New("frequency must be weekly or monthly")
+	}
+
+	if len(req.Recipients) == 0 || len(req.Recipients) > maxReportRecipients {
+		return fmt.Errorf("between 1 and %d recipients are required", maxReportRecipients)
+	}
+	recipients := make([]report.Recipient, 0, len(req.Recipients))
+	seen := make(map[string]bool)
+	for _, recipient := range req.Recipients {
+		address, err := mail.ParseAddress(recipient.Email)
+		if err != nil {
+			return fmt.Errorf("invalid recipient email %q", recipient.Email)
+		}
+		recipient.Email = strings.ToLower(address.Address)
+		if seen[recipient.Email] {
+			return fmt.Errorf("recipient %s is listed twice", recipient.Email)
+		}
+		seen[recipient.Email] = true
+		if recipient.Format == "" {
+			recipient.Format = report.FormatHTML
+		}
+		if err := recipient.Validate(); err != nil {
+			return err
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	if len(req.SiteIDs) > 0 {
+		sites, err := h.MonitoredSiteRepo.FindByUserID(schedule.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to load monitored sites: %w", err)
+		}
+		owned := make(map[uuid.UUID]bool, len(sites))
+		for _, site := range sites {
+			owned[site.ID] = true
+		}
+		for _, id := range req.SiteIDs {
+			if !owned[id] {
+				return fmt.Errorf("monitored site %s not found", id)
+			}
+		}
+	}
+
+	schedule.Name = name
+	schedule.Frequency = req.Frequency
+	schedule.Recipients = encodeSiteJSON(recipients)
+	schedule.SiteIDs = nil
+	if len(req.SiteIDs) > 0 {
+		schedule.SiteIDs = encodeSiteJSON(req.SiteIDs)
+	}
+	if req.Active != nil {
+		schedule.Active = *req.Active
+	}
+	return nil
+}
+
+// RunReportSchedules queues due reports for delivery until the context is
+// cancelled
+func (h *ReportHandler) RunReportSchedules(ctx context.Context) {
+	if h.Sender == nil || h.ReportRepo == nil {
+		return
+	}
+	ticker := time.NewTicker(reportTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.queueDueReports()
+		}
+	}
+}
+
+// queueDueReports queues the report of every due schedule
+func (h *ReportHandler) queueDueReports() {
+	now := time.Now()
+	schedules, err := h.ReportRepo.FindDue(now, reportBatchSize)
+	if err != nil {
+		log.Printf("Failed to load due report schedules: %v", err)
+		return
+	}
+
+	for i := range schedules {
+		schedule := &schedules[i]
+
+		// The email of each recipient is queued with the claim of the run,
+		// so a crash can neither lose nor repeat it. Several server
+		// instances may see the same due schedule.
+		deliveries, deliveryErr := reportDeliveries(schedule, now)
+		claimed, err := h.ReportRepo.ClaimRun(schedule.ID, *schedule.NextRunAt, report.NextRun(schedule.Frequency, *schedule.NextRunAt), deliveries...)
+		if err != nil || !claimed {
+			continue
+		}
+		if deliveryErr != nil {
+			log.Printf("Failed to queue report %q of user %s: %v", schedule.Name, schedule.UserID, deliveryErr)
+			if err := h.ReportRepo.MarkRun(schedule.ID, deliveryErr); err != nil {
+				log.Printf("Failed to record delivery of report schedule %s: %v", schedule.ID, err)
+			}
+			continue
+		}
+		h.Outbox.Notify()
+	}
+}
+
+// reportDeliveries builds the outbox messages emailing the report of a
+// schedule run to each recipient
+func reportDeliveries(schedule *models.ReportSchedule, at time.Time) ([]*models.OutboxMessage, error) {
+	var recipients []report.Recipient
+	if err := json.Unmarshal(schedule.Recipients, &recipients); err != nil {
+		return nil, fmt.Errorf("invalid recipients: %w", err)
+	}
+
+	run := schedule.ID.String() + ":" + schedule.NextRunAt.UTC().Format(time.RFC3339)
+	deliveries := make([]*models.OutboxMessage, 0, len(recipients))
+	for _, recipient := range recipients {
+		msg, err := outbox.NewMessage(outbox.KindReportEmail, run+":"+recipient.Email, reportEmailPayload{
+			ScheduleID: schedule.ID,
+			Recipient:  recipient,
+			At:         at,
+		})
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, msg)
+	}
+	return deliveries, nil
+}
+
+// sendReport builds the report of a schedule for the period ending at the
+// given time and emails it to each recipient separately, so that recipients
+// do not see each other's addresses
+func (h *ReportHandler) sendReport(ctx context.Context, schedule *models.ReportSchedule, at time.Time) error {
+	var recipients []report.Recipient
+	if err := json.Unmarshal(schedule.Recipients, &recipients); err != nil {
+		return fmt.Errorf("invalid recipients: %w", err)
+	}
+
+	rpt, err := h.buildReport(schedule, at)
+	if err != nil {
+		return err
+	}
+
+	var failed []error
+	for _, recipient := range recipients {
+		msg, err := reportMessage(rpt, recipient, h.Signer, reportSubject(schedule))
+		if err == nil {
+			err = h.Sender.Send(ctx, msg)
+		}
+		if err != nil {
+			failed = append(failed, fmt.Errorf("%s: %w", recipient.Email, err))
+		}
+	}
+	return errors.Join(failed...)
+}
+
+// reportMessage renders a report in the format a recipient prefers. PDF
+// recipients get the document attached to a short text message. When a
+// signer is given, the signature of the document is attached as well; HTML
+// recipients then also get the document attached, because mail clients
+// rewrite message bodies.
+func reportMessage(rpt report.Report, recipient report.Recipient, signer *signing.Signer, subject string) (email.Message, error) {
+	msg := email.Message{
+		To:      []string{recipient.Email},
+		Subject: report.Subject(rpt),
+		Text:    report.RenderText(rpt, recipient),
+	}
+
+	if recipient.Format == report.FormatPDF {
+		document, err := report.RenderPDF(rpt, recipient)
+		if err != nil {
+			return msg, err
+		}
+		msg.Text = fmt.Sprintf("The %s report for %d sites is attached.", rpt.Title, len(rpt.Sites))
+		msg.HTML = "<p>" + msg.Text + "</p>"
+		attachment := email.Attachment{
+			Filename:    reportFilename(rpt),
+			ContentType: "application/pdf",
+			Data:        document,
+		}
+		msg.Attachments = []email.Attachment{attachment}
+		if signature := signatureAttachment(signer, attachment, subject); signature != nil {
+			msg.Attachments = append(msg.Attachments, *signature)
+		}
+		return msg, nil
+	}
+
+	document, err := report.RenderHTML(rpt, recipient)
+	if err != nil {
+		return msg, err
+	}
+	msg.HTML = string(document)
+
+	attachment := email.Attachment{
+		Filename:    strings.TrimSuffix(reportFilename(rpt), ".pdf") + ".html",
+		ContentType: fiber.MIMETextHTMLCharsetUTF8,
+		Data:        document,
+	}
+	if signature := signatureAttachment(signer, attachment, subject); signature != nil {
+		msg.Attachments = []email.Attachment{attachment, *signature}
+	}
+	return msg, nil
+}
+
+// reportSubject names a report schedule in the signatures of its reports
+func reportSubject(schedule *models.ReportSchedule) string {
+	return "report_schedule:" + schedule.ID.String()
+}
+
+// reportFilename returns the file name of a PDF report
+func reportFilename(rpt report.Report) string {
+	return fmt.Sprintf("report-%s.pdf", rpt.To.Format("2006-01-02"))
+}
+
+// buildReport summarizes the monitored sites of a schedule over the period
+// ending at the given time
+func (h *ReportHandler) buildReport(schedule *models.ReportSchedule, at time.Time) (report.Report, error) {
+	from, to := report.Period(schedule.Frequency, at)
+	rpt := report.Report{
+		Title:       schedule.Name,
+		Frequency:   schedule.Frequency,
+		From:        from,
+		To:          to,
+		GeneratedAt: time.Now(),
+		Sites:       []report.SiteReport{},
+	}
+
+	sites, err := h.MonitoredSiteRepo.FindByUserID(schedule.UserID)
+	if err != nil {
+		return rpt, fmt.Errorf("failed to load monitored sites: %w", err)
+	}
+	var siteIDs []uuid.UUID
+	if len(schedule.SiteIDs) > 0 {
+		json.Unmarshal(schedule.SiteIDs, &siteIDs)
+	}
+	included := make(map[uuid.UUID]bool, len(siteIDs))
+	for _, id := range siteIDs {
+		included[id] = true
+	}
+
+	// Issues found in the previous period give the baseline of new and
+	// resolved issues
+	occurrences, err := h.IssueRepo.FindOccurrences(schedule.UserID, from.Add(-to.Sub(from)), to)
+	if err != nil {
+		return rpt, fmt.Errorf("failed to load issues: %w", err)
+	}
+	snapshots := issueSnapshots(occurrences, h.occurrenceDocs(occurrences))
+
+	for _, site := range sites {
+		if len(included) > 0 && !included[site.ID] {
+			continue
+		}
+
+		pageScores, err := h.KeywordRepo.PageScores(schedule.UserID, site.URL, from, to)
+		if err != nil {
+			return rpt, fmt.Errorf("failed to load scores of %s: %w", site.URL, err)
+		}
+		scores := make([]report.ScorePoint, 0, len(pageScores))
+		for _, score := range pageScores {
+			scores = append(scores, report.ScorePoint{At: score.CreatedAt, Score: score.OverallScore})
+		}
+
+		baseline, current := periodSnapshots(snapshots[site.URL], from)
+		rpt.Sites = append(rpt.Sites, report.BuildSiteReport(site.URL, scores, baseline, current))
+	}
+	return rpt, nil
+}
+
+// occurrenceDocs returns a function resolving the documentation link of an
+// issue occurrence on the platforms detected by its analysis
+func (h *ReportHandler) occurrenceDocs(occurrences []repository.IssueOccurrence) func(repository.IssueOccurrence) *issuedocs.Link {
+	resolver := issueDocResolver(h.DocLinkRepo)
+
+	var analysisIDs []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, occurrence := range occurrences {
+		if !seen[occurrence.AnalysisID] {
+			seen[occurrence.AnalysisID] = true
+			analysisIDs = append(analysisIDs, occurrence.AnalysisID)
+		}
+	}
+	names := make(map[uuid.UUID][]string)
+	if h.PageEntityRepo != nil {
+		technologies, err := h.PageEntityRepo.FindTechnologiesByAnalysisIDs(analysisIDs)
+		if err != nil {
+			log.Printf("Failed to load technologies for report: %v", err)
+		}
+		for _, tech := range technologies {
+			names[tech.AnalysisID] = append(names[tech.AnalysisID], tech.Name)
+		}
+	}
+	platforms := make(map[uuid.UUID][]string, len(names))
+	for analysisID, technologies := range names {
+		platforms[analysisID] = issuedocs.Platforms(technologies)
+	}
+
+	return func(occurrence repository.IssueOccurrence) *issuedocs.Link {
+		return resolver.Resolve(occurrence.Category, occurrence.Type, platforms[occurrence.AnalysisID])
+	}
+}
+
+// issueSnapshots groups issue occurrences by page URL and analysis, oldest
+// analysis first, and links the issues to their documentation
+func issueSnapshots(occurrences []repository.IssueOccurrence, docs func(repository.IssueOccurrence) *issuedocs.Link) map[string][]*report.Snapshot {
+	snapshots := make(map[string][]*report.Snapshot)
+	byAnalysis := make(map[uuid.UUID]*report.Snapshot)
+	for _, occurrence := range occurrences {
+		snapshot, ok := byAnalysis[occurrence.AnalysisID]
+		if !ok {
+			snapshot = &report.Snapshot{At: occurrence.AnalyzedAt}
+			byAnalysis[occurrence.AnalysisID] = snapshot
+			snapshots[occurrence.URL] = append(snapshots[occurrence.URL], snapshot)
+		}
+		// Analyses without issues appear once with an empty category
+		if occurrence.Category == "" {
+			continue
+		}
+		snapshot.Issues = append(snapshot.Issues, report.Issue{
+			Category: occurrence.Category,
+			Type:     occurrence.Type,
+			Title:    occurrence.Title,
+			Severity: occurrence.Severity,
+			Docs:     docs(occurrence),
+		})
+	}
+	return snapshots
+}
+
+// periodSnapshots picks the snapshots a site report compares: the latest
+// analysis before the period, or else the first one in it, and the latest
+// analysis in the period. current is nil when the site was not analyzed in
+// the period; baseline is nil when there is nothing to compare with.
+func periodSnapshots(snapshots []*report.Snapshot, from time.Time) (*report.Snapshot, *report.Snapshot) {
+	if len(snapshots) == 0 {
+		return nil, nil
+	}
+	current := snapshots[len(snapshots)-1]
+	if current.At.Before(from) {
+		return nil, nil
+	}
+
+	baseline := snapshots[0]
+	for _, snapshot := range snapshots {
+		if snapshot.At.Before(from) {
+			baseline = snapshot
+		}
+	}
+	if baseline == current {
+		return nil, current
+	}
+	return baseline, current
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/config"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/email"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/signing"
+)
+
+// signatureHeader carries the detached signature of a signed document
+const signatureHeader = "X-Signature"
+
+// signData signs the data of a JSON response. It returns an empty string
+// when signing is not configured or fails, so the response is sent unsigned.
+func signData(signer *signing.Signer, data interface{}, subject string) string {
+	if signer == nil {
+		return ""
+	}
+	jws, err := signer.SignJSON(data, subject)
+	if err != nil {
+		log.Printf("Failed to sign %s: %v", subject, err)
+		return ""
+	}
+	return jws
+}
+
+// signDocument returns the detached signature of a document, or an empty
+// string when signing is not configured or fails
+func signDocument(signer *signing.Signer, document []byte, contentType, subject string) string {
+	if signer == nil {
+		return ""
+	}
+	jws, err := signer.SignDetached(document, contentType, subject)
+	if err != nil {
+		log.Printf("Failed to sign %s: %v", subject, err)
+		return ""
+	}
+	return jws
+}
+
+// signatureAttachment returns the detached signature of an attached document
+// as a ".jws" file next to it, or nil when the document is not signed
+func signatureAttachment(signer *signing.Signer, document email.Attachment, subject string) *email.Attachment {
+	jws := signDocument(signer, document.Data, document.ContentType, subject)
+	if jws == "" {
+		return nil
+	}
+	return &email.Attachment{
+		Filename:    document.Filename + ".jws",
+		ContentType: "application/jose",
+		Data:        []byte(jws),
+	}
+}
+
+// SignatureHandler publishes the signing keys and verifies signed documents
+type SignatureHandler struct {
+	// Signer is nil when no signing key is configured
+	Signer *signing.Signer
+}
+
+// NewSignatureHandler creates a new signature handler. Documents are not
+// signed when no signing key is configured.
+func NewSignatureHandler(cfg *config.Config) *SignatureHandler {
+	h := &SignatureHandler{}
+	signer, err := signing.NewSigner(cfg.SigningKey, cfg.SigningVerifyKeys)
+	switch {
+	case err == nil:
+		h.Signer = signer
+	case !errors.Is(err, signing.ErrNotConfigured):
+		log.Printf("Report signing disabled: %v", err)
+	}
+	return h
+}
+
+// VerifySignatureRequest is a signature to verify
+type VerifySignatureRequest struct {
+	// Signature is the compact JWS: the "signature" field of a signed
+	// response, the X-Signature header or the content of a ".jws" file
+	Signature string `json:"signature"`
+	// Document is the base64-encoded signed file; required for detached
+	// signatures of HTML and PDF reports
+	Document string `json:"document,omitempty"`
+}
+
+// GetSigningKeys returns the public keys of the server
+// @Summary Get signing keys
+// @Description Returns the Ed25519 public keys exported reports and shared scan results are signed with, as a JSON Web Key Set, so recipients can verify signatures offline. Keys of earlier signing keys are listed after the current one
+// @Tags meta
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Signing keys"
+// @Router /meta/signing-keys [get]
+func (h *SignatureHandler) GetSigningKeys(c *fiber.Ctx) error {
+	keys := []signing.JWK{}
+	if h.Signer != nil {
+		keys = h.Signer.Keys()
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"enabled": h.Signer != nil,
+			"keys":    keys,
+		},
+	})
+}
+
+// VerifySignature checks that a document was signed by the server and not
+// modified
+// @Summary Verify a signed document
+// @Description Verifies a JSON Web Signature made by the server. Signed JSON responses carry the signature with the document embedded; HTML and PDF reports come with a detached signature in the X-Signature header or a ".jws" attachment, which needs the base64-encoded document. Returns whether the signature is valid, the key, what was signed and when, and for JSON documents the signed payload
+// @Tags meta
+// @Accept json
+// @Produce json
+// @Param request body VerifySignatureRequest true "Signature"
+// @Success 200 {object} map[string]interface{} "Verification result"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 503 {object} map[string]interface{} "Signing is not configured"
+// @Router /signatures/verify [post]
+func (h *SignatureHandler) VerifySignature(c *fiber.Ctx) error {
+	if h.Signer == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"success": false,
+			"error":   "Signing is not configured",
+		})
+	}
+
+	req := new(VerifySignatureRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+	if req.Signature == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Signature is required",
+		})
+	}
+	var document []byte
+	if req.Document != "" {
+		decoded, err := base64.StdEncoding.DecodeString(req.Document)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "Document must be base64-encoded",
+			})
+		}
+		document = decoded
+	}
+
+	verified, err := h.Signer.Verify(req.Signature, document)
+	if err != nil {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"data": fiber.Map{
+				"valid":  false,
+				"reason": err.Error(),
+			},
+		})
+	}
+
+	sum := sha256.Sum256(verified.Payload)
+	data := fiber.Map{
+		"valid":           true,
+		"key_id":          verified.Header.KeyID,
+		"content_type":    verified.Header.ContentType,
+		"subject":         verified.Header.Subject,
+		"signed_at":       verified.SignedAt,
+		"document_sha256": hex.EncodeToString(sum[:]),
+	}
+	if verified.Header.ContentType == "application/json" && json.Valid(verified.Payload) {
+		data["payload"] = json.RawMessage(verified.Payload)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    data,
+	})
+}
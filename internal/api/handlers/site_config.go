@@ -0,0 +1,462 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/billing"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/schedule"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+)
+
+// SiteSpec is the desired state of one monitored site
+type SiteSpec struct {
+	URL      string `json:"url"`
+	Preset   string `json:"preset,omitempty"`
+	Schedule string `json:"schedule,omitempty"` // hourly, daily, weekly or a duration such as 6h
+	// TimeZone is the IANA time zone of RunAt and Blackouts; UTC when empty
+	TimeZone  string                  `json:"time_zone,omitempty"`
+	RunAt     string                  `json:"run_at,omitempty"` // local HH:MM of daily and weekly runs
+	Blackouts []schedule.Window       `json:"blackouts,omitempty"`
+	Budgets   *analyzer.PresetBudgets `json:"budgets,omitempty"`
+	Alerts    []analyzer.AlertRule    `json:"alerts,omitempty"`
+	Active    *bool                   `json:"active,omitempty"` // defaults to true
+}
+
+// SiteConfigDocument is the complete set of sites a user monitors
+type SiteConfigDocument struct {
+	Sites []SiteSpec `json:"sites"`
+}
+
+// SiteSyncPlan lists the URLs a sync creates, updates, deletes or leaves as is
+type SiteSyncPlan struct {
+	DryRun    bool     `json:"dry_run"`
+	Created   []string `json:"created"`
+	Updated   []string `json:"updated"`
+	Deleted   []string `json:"deleted"`
+	Unchanged []string `json:"unchanged"`
+	// NextRuns is the next scheduled run of each created or updated site
+	NextRuns map[string]time.Time `json:"next_runs"`
+}
+
+// SiteSchedulePreview lists the upcoming runs of a monitored site
+type SiteSchedulePreview struct {
+	URL       string            `json:"url"`
+	Schedule  string            `json:"schedule"`
+	TimeZone  string            `json:"time_zone"`
+	RunAt     string            `json:"run_at,omitempty"`
+	Blackouts []schedule.Window `json:"blackouts,omitempty"`
+	Active    bool              `json:"active"`
+	NextRuns  []time.Time       `json:"next_runs"` // in the site's time zone
+}
+
+type SiteConfigHandler struct {
+	MonitoredSiteRepo repository.MonitoredSiteRepository
+	PresetRepo        repository.PresetRepository
+	Quota             *billing.Quota
+}
+
+// NewSiteConfigHandler creates a new declarative site configuration handler
+func NewSiteConfigHandler(repoFactory *repository.Factory, quota *billing.Quota) *SiteConfigHandler {
+	return &SiteConfigHandler{
+		MonitoredSiteRepo: repoFactory.MonitoredSiteRepository,
+		PresetRepo:        repoFactory.PresetRepository,
+		Quota:             quota,
+	}
+}
+
+// GetSiteConfig exports the monitored sites of the user as a desired-state document
+// @Summary Export monitored site configuration
+// @Description Returns the user's monitored sites in the document format accepted by PUT /config/sites, so the current state can be committed to version control
+// @Tags config
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Site configuration document"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /config/sites [get]
+func (h *SiteConfigHandler) GetSiteConfig(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	sites, err := h.MonitoredSiteRepo.FindByUserID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load monitored sites: " + err.Error(),
+		})
+	}
+
+	document := SiteConfigDocument{Sites: make([]SiteSpec, 0, len(sites))}
+	for i := range sites {
+		document.Sites = append(document.Sites, siteSpecFromModel(&sites[i]))
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    document,
+		"sites":   sites,
+	})
+}
+
+// SyncSiteConfig reconciles the monitored sites of the user with a desired-state document
+// @Summary Sync monitored site configuration
+// @Description Replaces the user's monitored sites with the given document: sites missing from the database are created, changed ones are updated and sites missing from the document are deleted. The whole document is validated before anything changes. With dry_run=true only the plan is returned
+// @Tags config
+// @Accept json
+// @Produce json
+// @Param dry_run query bool false "Only compute the plan"
+// @Param config body SiteConfigDocument true "Desired state"
+// @Success 200 {object} map[string]interface{} "Sync plan"
+// @Failure 400 {object} map[string]interface{} "Invalid document"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 402 {object} map[string]interface{} "Monitor limit of the plan exceeded"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /config/sites [put]
+func (h *SiteConfigHandler) SyncSiteConfig(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	dryRun := c.QueryBool("dry_run")
+
+	document := new(SiteConfigDocument)
+	if err := c.BodyParser(document); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+
+	desired, err := h.desiredSites(userID, document)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	if status, exceeded := h.exceedsMonitorLimit(c, userID, desired); exceeded {
+		return c.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{
+			"success": false,
+			"error":   fmt.Sprintf("Your %s plan allows %d active monitored sites", status.Plan, status.Limit),
+			"quota":   status,
+		})
+	}
+
+	current, err := h.MonitoredSiteRepo.FindByUserID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load monitored sites: " + err.Error(),
+		})
+	}
+
+	plan := SiteSyncPlan{
+		DryRun:    dryRun,
+		Created:   []string{},
+		Updated:   []string{},
+		Deleted:   []string{},
+		Unchanged: []string{},
+		NextRuns:  map[string]time.Time{},
+	}
+	existing := make(map[string]*models.MonitoredSite, len(current))
+	for i := range current {
+		existing[current[i].URL] = &current[i]
+	}
+
+	var upserts []*models.MonitoredSite
+	for _, site := range desired {
+		old, ok := existing[site.URL]
+		if !ok {
+			if sched, _ := siteSchedule(site); sched != nil {
+				next := sched.First(time.Now())
+				site.NextRunAt = &next
+				plan.NextRuns[site.URL] = next.In(sched.Location())
+			}
+			upserts = append(upserts, site)
+			plan.Created = append(plan.Created, site.URL)
+			continue
+		}
+		delete(existing, site.URL)
+
+		if sameSiteSpec(old, site) {
+			plan.Unchanged = append(plan.Unchanged, site.URL)
+			continue
+		}
+		site.ID = old.ID
+		site.NextRunAt = nextRunAfterChange(old, site)
+		if sched, _ := siteSchedule(site); sched != nil && site.NextRunAt != nil {
+			plan.NextRuns[site.URL] = site.NextRunAt.In(sched.Location())
+		}
+		upserts = append(upserts, site)
+		plan.Updated = append(plan.Updated, site.URL)
+	}
+
+	var deleteIDs []uuid.UUID
+	for i := range current {
+		if _, ok := existing[current[i].URL]; ok {
+			deleteIDs = append(deleteIDs, current[i].ID)
+			plan.Deleted = append(plan.Deleted, current[i].URL)
+		}
+	}
+
+	if !dryRun && (len(upserts) > 0 || len(deleteIDs) > 0) {
+		if err := h.MonitoredSiteRepo.Reconcile(userID, upserts, deleteIDs); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"success": false,
+				"error":   "Failed to apply site configuration: " + err.Error(),
+			})
+		}
+		log.Printf("Synced monitored sites of user %s: %d created, %d updated, %d deleted",
+			userID, len(plan.Created), len(plan.Updated), len(plan.Deleted))
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    plan,
+	})
+}
+
+// PreviewSiteSchedules lists the upcoming runs of the user's monitored sites
+// @Summary Preview monitored site schedules
+// @Description Returns the next scheduled runs of each monitored site in its time zone, after moving runs out of blackout windows. Sites analyzed only on demand have no runs
+// @Tags config
+// @Produce json
+// @Param count query int false "Runs per site (1-50)" default(5)
+// @Success 200 {object} map[string]interface{} "Upcoming runs"
+// @Failure 400 {object} map[string]interface{} "Invalid count"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /config/sites/schedule [get]
+func (h *SiteConfigHandler) PreviewSiteSchedules(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	count := c.QueryInt("count", 5)
+	if count < 1 || count > 50 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Count must be between 1 and 50",
+		})
+	}
+
+	sites, err := h.MonitoredSiteRepo.FindByUserID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load monitored sites: " + err.Error(),
+		})
+	}
+
+	now := time.Now()
+	previews := make([]SiteSchedulePreview, 0, len(sites))
+	for i := range sites {
+		site := &sites[i]
+		spec := siteSpecFromModel(site)
+		preview := SiteSchedulePreview{
+			URL:       site.URL,
+			Schedule:  site.Schedule,
+			TimeZone:  "UTC",
+			RunAt:     site.RunAt,
+			Blackouts: spec.Blackouts,
+			Active:    site.Active,
+			NextRuns:  []time.Time{},
+		}
+		if site.TimeZone != "" {
+			preview.TimeZone = site.TimeZone
+		}
+
+		sched, _ := siteSchedule(site)
+		if sched != nil && site.Active {
+			// Overdue runs start with the next scheduler tick
+			pending := now
+			if site.NextRunAt != nil && site.NextRunAt.After(now) {
+				pending = *site.NextRunAt
+			}
+			preview.NextRuns = sched.Preview(pending, count)
+		}
+		previews = append(previews, preview)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    previews,
+	})
+}
+
+// desiredSites validates a document and converts it to monitored sites
+func (h *SiteConfigHandler) desiredSites(userID uuid.UUID, document *SiteConfigDocument) ([]*models.MonitoredSite, error) {
+	sites := make([]*models.MonitoredSite, 0, len(document.Sites))
+	seen := make(map[string]bool, len(document.Sites))
+
+	for i, spec := range document.Sites {
+		normalizedURL, err := urlnorm.Normalize(spec.URL)
+		if err != nil {
+			return nil, fmt.Errorf("sites[%d]: invalid URL %q", i, spec.URL)
+		}
+		if seen[normalizedURL] {
+			return nil, fmt.Errorf("sites[%d]: %s is listed twice", i, normalizedURL)
+		}
+		seen[normalizedURL] = true
+
+		analyzers := analyzer.AllAnalyzerTypes
+		if spec.Preset != "" {
+			preset, err := ResolvePreset(h.PresetRepo, userID, spec.Preset)
+			if errors.Is(err, errPresetNotFound) {
+				return nil, fmt.Errorf("sites[%d]: unknown preset %s", i, spec.Preset)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("sites[%d]: failed to load preset: %w", i, err)
+			}
+			analyzers = preset.Analyzers
+		}
+		if spec.Budgets != nil {
+			if err := spec.Budgets.Validate(analyzers); err != nil {
+				return nil, fmt.Errorf("sites[%d]: %w", i, err)
+			}
+		}
+		for j, rule := range spec.Alerts {
+			if err := rule.Validate(); err != nil {
+				return nil, fmt.Errorf("sites[%d].alerts[%d]: %w", i, j, err)
+			}
+		}
+
+		site := &models.MonitoredSite{
+			URL:      normalizedURL,
+			Preset:   spec.Preset,
+			Schedule: spec.Schedule,
+			TimeZone: spec.TimeZone,
+			RunAt:    spec.RunAt,
+			Active:   spec.Active == nil || *spec.Active,
+		}
+		if len(spec.Blackouts) > 0 {
+			site.Blackouts = encodeSiteJSON(spec.Blackouts)
+		}
+		if _, err := siteSchedule(site); err != nil {
+			return nil, fmt.Errorf("sites[%d]: %w", i, err)
+		}
+		if spec.Budgets != nil {
+			site.Budgets = encodeSiteJSON(spec.Budgets)
+		}
+		if len(spec.Alerts) > 0 {
+			site.AlertRules = encodeSiteJSON(spec.Alerts)
+		}
+		sites = append(sites, site)
+	}
+	return sites, nil
+}
+
+// exceedsMonitorLimit reports whether the document has more active sites
+// than the user's plan allows
+func (h *SiteConfigHandler) exceedsMonitorLimit(c *fiber.Ctx, userID uuid.UUID, sites []*models.MonitoredSite) (*billing.QuotaStatus, bool) {
+	if h.Quota == nil {
+		return nil, false
+	}
+	if role, _ := c.Locals("role").(string); role == "admin" {
+		return nil, false
+	}
+
+	status, err := h.Quota.Status(c.Context(), userID, billing.ResourceMonitors)
+	if err != nil {
+		log.Printf("Failed to check monitor quota for user %s: %v", userID, err)
+		return nil, false
+	}
+	active := int64(0)
+	for _, site := range sites {
+		if site.Active {
+			active++
+		}
+	}
+	status.Used = active
+	return status, status.Limit != billing.Unlimited && active > status.Limit
+}
+
+// nextRunAfterChange keeps the pending run of an updated site unless its
+// schedule changed. A new schedule starts from the last run.
+func nextRunAfterChange(old, site *models.MonitoredSite) *time.Time {
+	sched, _ := siteSchedule(site)
+	if sched == nil {
+		return nil
+	}
+	if sameSiteTiming(old, site) && old.NextRunAt != nil {
+		return old.NextRunAt
+	}
+
+	next := sched.First(time.Now())
+	if old.LastRunAt != nil {
+		if after := sched.Next(*old.LastRunAt); after.After(next) {
+			next = after
+		}
+	}
+	return &next
+}
+
+// sameSiteTiming reports whether two sites run at the same times
+func sameSiteTiming(old, site *models.MonitoredSite) bool {
+	return old.Schedule == site.Schedule &&
+		old.TimeZone == site.TimeZone &&
+		old.RunAt == site.RunAt &&
+		bytes.Equal(canonicalJSON(old.Blackouts), canonicalJSON(site.Blackouts))
+}
+
+// sameSiteSpec reports whether a stored site already matches the desired state
+func sameSiteSpec(old, site *models.MonitoredSite) bool {
+	return old.Preset == site.Preset &&
+		sameSiteTiming(old, site) &&
+		old.Active == site.Active &&
+		bytes.Equal(canonicalJSON(old.Budgets), canonicalJSON(site.Budgets)) &&
+		bytes.Equal(canonicalJSON(old.AlertRules), canonicalJSON(site.AlertRules))
+}
+
+// siteSpecFromModel converts a stored site to its document form
+func siteSpecFromModel(site *models.MonitoredSite) SiteSpec {
+	active := site.Active
+	spec := SiteSpec{
+		URL:      site.URL,
+		Preset:   site.Preset,
+		Schedule: site.Schedule,
+		TimeZone: site.TimeZone,
+		RunAt:    site.RunAt,
+		Alerts:   siteAlertRules(site),
+		Active:   &active,
+	}
+	if len(site.Blackouts) > 0 {
+		json.Unmarshal(site.Blackouts, &spec.Blackouts)
+	}
+	if budgets, ok := siteBudgets(site); ok {
+		spec.Budgets = &budgets
+	}
+	return spec
+}
+
+// encodeSiteJSON encodes a budgets or alert rules value for storage
+func encodeSiteJSON(value interface{}) datatypes.JSON {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	return datatypes.JSON(encoded)
+}
+
+// canonicalJSON re-encodes stored JSON so that documents differing only in
+// key order or whitespace compare equal; jsonb does not keep the input form
+func canonicalJSON(data datatypes.JSON) []byte {
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return data
+	}
+	encoded, _ := json.Marshal(value)
+	return encoded
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/cache"
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+)
+
+// statusCacheTTL limits how often the public status feed hits the database
+const statusCacheTTL = time.Minute
+
+type StatusHandler struct {
+	EventRepo repository.AnalysisEventRepository
+	Cache     cache.Cache
+}
+
+// NewStatusHandler creates a new status page handler
+func NewStatusHandler(repoFactory *repository.Factory, cacheStore cache.Cache) *StatusHandler {
+	return &StatusHandler{
+		EventRepo: repoFactory.AnalysisEventRepository,
+		Cache:     cacheStore,
+	}
+}
+
+// StatusDay is the platform health of one day
+type StatusDay struct {
+	Date                   string   `json:"date"`
+	AnalysesCompleted      int64    `json:"analyses_completed"`
+	AnalysesFailed         int64    `json:"analyses_failed"`
+	PipelineUptime         *float64 `json:"pipeline_uptime"`
+	AvgQueueDelayMs        *float64 `json:"avg_queue_delay_ms"`
+	LighthouseCalls        int64    `json:"lighthouse_calls"`
+	LighthouseAvailability *float64 `json:"lighthouse_availability"`
+}
+
+// StatusFeed is the public status page payload
+type StatusFeed struct {
+	GeneratedAt            time.Time   `json:"generated_at"`
+	Days                   int         `json:"days"`
+	PipelineUptime         *float64    `json:"pipeline_uptime"`
+	AvgQueueDelayMs        *float64    `json:"avg_queue_delay_ms"`
+	LighthouseAvailability *float64    `json:"lighthouse_availability"`
+	History                []StatusDay `json:"history"`
+}
+
+// ratio returns succeeded/total, or nil when nothing happened
+func ratio(succeeded, failed int64) *float64 {
+	total := succeeded + failed
+	if total == 0 {
+		return nil
+	}
+	value := float64(succeeded) / float64(total)
+	return &value
+}
+
+// GetStatusFeed returns the platform health history for a status page
+// @Summary Get platform status history
+// @Description Returns daily analysis pipeline uptime, average queue delay and Lighthouse API availability. This endpoint is public and cached for one minute
+// @Tags status
+// @Produce json
+// @Param days query int false "Number of days of history (max 90)" default(30)
+// @Success 200 {object} map[string]interface{} "Status history"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /status [get]
+func (h *StatusHandler) GetStatusFeed(c *fiber.Ctx) error {
+	days := c.QueryInt("days", 30)
+	if days <= 0 || days > 90 {
+		days = 30
+	}
+
+	cacheKey := fmt.Sprintf("status_feed:%d", days)
+	if h.Cache != nil {
+		var cached StatusFeed
+		if err := h.Cache.Get(cacheKey, &cached); err == nil {
+			return c.JSON(fiber.Map{
+				"success": true,
+				"data":    cached,
+			})
+		}
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	since := today.AddDate(0, 0, -(days - 1))
+
+	pipeline, err := h.EventRepo.OutcomeStats(since, models.AnalysisEventCompleted, models.AnalysisEventError, "")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load pipeline history",
+		})
+	}
+	lighthouse, err := h.EventRepo.OutcomeStats(since, models.AnalysisEventAnalyzerCompleted, models.AnalysisEventAnalyzerFailed, string(analyzer.LighthouseType))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load Lighthouse history",
+		})
+	}
+	delays, err := h.EventRepo.QueueDelayStats(since)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load queue history",
+		})
+	}
+
+	// Index the aggregates by day so that days without events are still listed
+	history := make([]StatusDay, days)
+	index := make(map[string]*StatusDay, days)
+	for i := range history {
+		date := since.AddDate(0, 0, i).Format("2006-01-02")
+		history[i].Date = date
+		index[date] = &history[i]
+	}
+
+	var completed, failed, lighthouseOK, lighthouseFailed, started int64
+	var delaySum float64
+
+	for _, stat := range pipeline {
+		if day, ok := index[stat.Day.UTC().Format("2006-01-02")]; ok {
+			day.AnalysesCompleted = stat.Succeeded
+			day.AnalysesFailed = stat.Failed
+			day.PipelineUptime = ratio(stat.Succeeded, stat.Failed)
+			completed += stat.Succeeded
+			failed += stat.Failed
+		}
+	}
+	for _, stat := range lighthouse {
+		if day, ok := index[stat.Day.UTC().Format("2006-01-02")]; ok {
+			day.LighthouseCalls = stat.Succeeded + stat.Failed
+			day.LighthouseAvailability = ratio(stat.Succeeded, stat.Failed)
+			lighthouseOK += stat.Succeeded
+			lighthouseFailed += stat.Failed
+		}
+	}
+	for _, stat := range delays {
+		if day, ok := index[stat.Day.UTC().Format("2006-01-02")]; ok {
+			avg := stat.AvgDelayMs
+			day.AvgQueueDelayMs = &avg
+			started += stat.Started
+			delaySum += stat.AvgDelayMs * float64(stat.Started)
+		}
+	}
+
+	feed := StatusFeed{
+		GeneratedAt:            now,
+		Days:                   days,
+		PipelineUptime:         ratio(completed, failed),
+		LighthouseAvailability: ratio(lighthouseOK, lighthouseFailed),
+		History:                history,
+	}
+	if started > 0 {
+		avg := delaySum / float64(started)
+		feed.AvgQueueDelayMs = &avg
+	}
+
+	if h.Cache != nil {
+		h.Cache.Set(cacheKey, feed, statusCacheTTL)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    feed,
+	})
+}
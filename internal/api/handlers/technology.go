@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// Kinds of technology changes between two analyses of a website
+const (
+	technologyAdded          = "added"
+	technologyRemoved        = "removed"
+	technologyVersionChanged = "version_changed"
+)
+
+// TechnologyHandler reports technology detections stored across analyses
+type TechnologyHandler struct {
+	WebsiteRepo       repository.WebsiteRepository
+	MonitoredSiteRepo repository.MonitoredSiteRepository
+	PageEntityRepo    repository.PageEntityRepository
+}
+
+// NewTechnologyHandler creates a new technology handler
+func NewTechnologyHandler(repoFactory *repository.Factory) *TechnologyHandler {
+	return &TechnologyHandler{
+		WebsiteRepo:       repoFactory.WebsiteRepository,
+		MonitoredSiteRepo: repoFactory.MonitoredSiteRepository,
+		PageEntityRepo:    repoFactory.PageEntityRepository,
+	}
+}
+
+// TechnologyChange is a technology that appeared, disappeared or changed
+// version between two analyses of a website
+type TechnologyChange struct {
+	Kind            string    `json:"kind"` // added, removed, version_changed
+	Name            string    `json:"name"`
+	Category        string    `json:"category"`
+	Version         string    `json:"version,omitempty"`
+	PreviousVersion string    `json:"previous_version,omitempty"`
+	AnalysisID      uuid.UUID `json:"analysis_id"`
+	DetectedAt      time.Time `json:"detected_at"`
+}
+
+// OutdatedTechnologySite is a monitored site running an outdated technology version
+type OutdatedTechnologySite struct {
+	SiteID     uuid.UUID `json:"site_id"`
+	URL        string    `json:"url"`
+	Version    string    `json:"version"`
+	AnalysisID uuid.UUID `json:"analysis_id"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// OutdatedTechnology is a technology used in unsupported versions by the
+// monitored sites of an organization
+type OutdatedTechnology struct {
+	parser.VersionSupport
+	Category string                   `json:"category"`
+	Sites    []OutdatedTechnologySite `json:"sites"`
+}
+
+// technologyState is the technologies detected by one analysis
+type technologyState struct {
+	analysisID   uuid.UUID
+	detectedAt   time.Time
+	technologies map[string]models.PageTechnology
+}
+
+// GetWebsiteTechnologyHistory returns the technology changes of a website
+// @Summary Get website technology history
+// @Description Returns the technologies detected by each analysis of a website as a list of changes: technologies that were added or removed and frameworks or CMS whose version changed, oldest first, together with the technologies currently detected
+// @Tags websites
+// @Produce json
+// @Param id path string true "Website ID"
+// @Param from query string false "Start of the range (RFC 3339 or YYYY-MM-DD), defaults to one year ago"
+// @Success 200 {object} map[string]interface{} "Technology history"
+// @Failure 400 {object} map[string]interface{} "Invalid website ID or query"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Website not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /websites/{id}/technologies/history [get]
+func (h *TechnologyHandler) GetWebsiteTechnologyHistory(c *fiber.Ctx) error {
+	websiteID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid website ID",
+		})
+	}
+
+	from, err := parseUsageDate(c.Query("from"), time.Now().AddDate(-1, 0, 0))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid from date",
+		})
+	}
+
+	var website models.Website
+	if err := h.WebsiteRepo.FindByID(websiteID, &website); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Website not found",
+		})
+	}
+
+	detections, err := h.PageEntityRepo.FindTechnologiesByWebsiteID(websiteID, from)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load technologies",
+		})
+	}
+
+	states := technologyStates(detections)
+	changes := technologyChanges(states)
+
+	current := []models.PageTechnology{}
+	var lastAnalysis *time.Time
+	if len(states) > 0 {
+		latest := states[len(states)-1]
+		for _, tech := range latest.technologies {
+			current = append(current, tech)
+		}
+		sort.Slice(current, func(i, j int) bool { return current[i].Name < current[j].Name })
+		lastAnalysis = &latest.detectedAt
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"website_id":       website.ID,
+			"url":              website.URL,
+			"analyses":         len(states),
+			"last_analyzed_at": lastAnalysis,
+			"current":          current,
+			"changes":          changes,
+		},
+	})
+}
+
+// GetOrganizationOutdatedTechnologies reports outdated technology versions on monitored sites
+// @Summary Get outdated technologies of an organization
+// @Description Checks the technologies detected by the latest analysis of each monitored site of an organization against the oldest supported version of each technology and lists the sites running older versions, grouped by technology. Organizations are user accounts; non-admins can only query their own
+// @Tags insights
+// @Produce json
+// @Param id path string true "Organization (user) ID"
+// @Success 200 {object} map[string]interface{} "Outdated technologies"
+// @Failure 400 {object} map[string]interface{} "Invalid organization ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /organizations/{id}/technologies/outdated [get]
+func (h *TechnologyHandler) GetOrganizationOutdatedTechnologies(c *fiber.Ctx) error {
+	organizationID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid organization ID",
+		})
+	}
+
+	role, _ := c.Locals("role").(string)
+	if userID, _ := c.Locals("userID").(uuid.UUID); role != "admin" && userID != organizationID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"success": false,
+			"error":   "Forbidden, you can only access your own organization",
+		})
+	}
+
+	sites, err := h.MonitoredSiteRepo.FindByUserID(organizationID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load monitored sites",
+		})
+	}
+
+	siteByAnalysis := make(map[uuid.UUID]models.MonitoredSite)
+	analysisIDs := make([]uuid.UUID, 0, len(sites))
+	for _, site := range sites {
+		if site.LastAnalysisID == nil {
+			continue
+		}
+		siteByAnalysis[*site.LastAnalysisID] = site
+		analysisIDs = append(analysisIDs, *site.LastAnalysisID)
+	}
+
+	detections, err := h.PageEntityRepo.FindTechnologiesByAnalysisIDs(analysisIDs)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load technologies",
+		})
+	}
+
+	analyzed := make(map[uuid.UUID]bool)
+	outdated := make(map[string]*OutdatedTechnology)
+	affected := make(map[uuid.UUID]bool)
+	for _, tech := range detections {
+		analyzed[tech.AnalysisID] = true
+		support, isOutdated := parser.OutdatedVersion(tech.Name, tech.Version)
+		if !isOutdated {
+			continue
+		}
+
+		site := siteByAnalysis[tech.AnalysisID]
+		entry, ok := outdated[tech.Name]
+		if !ok {
+			entry = &OutdatedTechnology{VersionSupport: support, Category: tech.Category}
+			outdated[tech.Name] = entry
+		}
+		entry.Sites = append(entry.Sites, OutdatedTechnologySite{
+			SiteID:     site.ID,
+			URL:        site.URL,
+			Version:    tech.Version,
+			AnalysisID: tech.AnalysisID,
+			DetectedAt: tech.CreatedAt,
+		})
+		affected[site.ID] = true
+	}
+
+	technologies := make([]OutdatedTechnology, 0, len(outdated))
+	for _, entry := range outdated {
+		sort.Slice(entry.Sites, func(i, j int) bool { return entry.Sites[i].URL < entry.Sites[j].URL })
+		technologies = append(technologies, *entry)
+	}
+	sort.Slice(technologies, func(i, j int) bool {
+		if len(technologies[i].Sites) != len(technologies[j].Sites) {
+			return len(technologies[i].Sites) > len(technologies[j].Sites)
+		}
+		return technologies[i].Name < technologies[j].Name
+	})
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"organization_id": organizationID,
+			"monitored_sites": len(sites),
+			// Sites whose latest analysis detected at least one technology
+			"sites_with_data": len(analyzed),
+			"affected_sites":  len(affected),
+			"technologies":    technologies,
+		},
+	})
+}
+
+// technologyStates groups technology detections by analysis, oldest first
+func technologyStates(detections []models.PageTechnology) []technologyState {
+	var states []technologyState
+	index := make(map[uuid.UUID]int)
+	for _, tech := range detections {
+		i, ok := index[tech.AnalysisID]
+		if !ok {
+			i = len(states)
+			index[tech.AnalysisID] = i
+			states = append(states, technologyState{
+				analysisID:   tech.AnalysisID,
+				detectedAt:   tech.CreatedAt,
+				technologies: make(map[string]models.PageTechnology),
+			})
+		}
+		states[i].technologies[tech.Name] = tech
+	}
+
+	sort.SliceStable(states, func(i, j int) bool { return states[i].detectedAt.Before(states[j].detectedAt) })
+	return states
+}
+
+// technologyChanges compares each analysis with the previous one. The first
+// analysis in the range is the baseline and reports no changes.
+func technologyChanges(states []technologyState) []TechnologyChange {
+	changes := []TechnologyChange{}
+	for i := 1; i < len(states); i++ {
+		previous, current := states[i-1], states[i]
+		var step []TechnologyChange
+
+		for name, tech := range current.technologies {
+			before, existed := previous.technologies[name]
+			switch {
+			case !existed:
+				step = append(step, TechnologyChange{
+					Kind:     technologyAdded,
+					Name:     name,
+					Category: tech.Category,
+					Version:  tech.Version,
+				})
+			case tech.Version != "" && before.Version != "" && tech.Version != before.Version:
+				// A version that was not detected this time is not a change
+				step = append(step, TechnologyChange{
+					Kind:            technologyVersionChanged,
+					Name:            name,
+					Category:        tech.Category,
+					Version:         tech.Version,
+					PreviousVersion: before.Version,
+				})
+			}
+		}
+		for name, tech := range previous.technologies {
+			if _, ok := current.technologies[name]; !ok {
+				step = append(step, TechnologyChange{
+					Kind:            technologyRemoved,
+					Name:            name,
+					Category:        tech.Category,
+					PreviousVersion: tech.Version,
+				})
+			}
+		}
+
+		sort.Slice(step, func(a, b int) bool { return step[a].Name < step[b].Name })
+		for _, change := range step {
+			change.AnalysisID = current.analysisID
+			change.DetectedAt = current.detectedAt
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
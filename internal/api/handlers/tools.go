@@ -0,0 +1,540 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/cache"
+	"github.com/chynybekuuludastan/website_optimizer/internal/config"
+	"github.com/chynybekuuludastan/website_optimizer/internal/database"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/robots"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+)
+
+// Limits of the robots.txt tools
+const (
+	maxRobotsURLs       = 500
+	maxRobotsUserAgents = 10
+	robotsCrawlMaxPages = 100
+	extractFetchTimeout = 20 * time.Second
+	maxCheckURLs        = 5000
+	urlCheckConcurrency = 20
+	urlCheckHostRate    = 5 // requests per second to a single host
+	urlCheckTimeout     = 10 * time.Second
+)
+
+const (
+	// keyPrefixToolsRate counts the page-fetching tool requests of a user per minute
+	keyPrefixToolsRate = "tools:rate:"
+	// keyPrefixURLStatus caches the status of a URL checked by the bulk checker
+	keyPrefixURLStatus = "tools:urlstatus:"
+)
+
+// defaultRobotsUserAgents are evaluated when a request names no user agents
+var defaultRobotsUserAgents = []string{"*", "Googlebot", "Bingbot", "YandexBot"}
+
+// ToolsHandler serves standalone SEO tools that do not need an analysis
+type ToolsHandler struct {
+	Cache       cache.Cache
+	RedisClient *database.RedisClient // per-user rate limits; nil without Redis
+	Config      *config.Config
+}
+
+// NewToolsHandler creates a new tools handler
+func NewToolsHandler(cacheStore cache.Cache, redisClient *database.RedisClient, cfg *config.Config) *ToolsHandler {
+	return &ToolsHandler{Cache: cacheStore, RedisClient: redisClient, Config: cfg}
+}
+
+// RobotsValidateRequest is a robots.txt file and the URLs to check against it
+type RobotsValidateRequest struct {
+	RobotsTxt  string   `json:"robots_txt" validate:"required"`
+	URLs       []string `json:"urls"`
+	UserAgents []string `json:"user_agents"`
+}
+
+// RobotsGenerateRequest names the site to propose a robots.txt for
+type RobotsGenerateRequest struct {
+	URL string `json:"url" validate:"required,url"`
+}
+
+// CheckURLsRequest lists the URLs to check
+type CheckURLsRequest struct {
+	URLs []string `json:"urls" validate:"required"`
+}
+
+// ExtractRequest names a page and the selectors to extract from it
+type ExtractRequest struct {
+	URL       string            `json:"url" validate:"required,url"`
+	Selectors []parser.Selector `json:"selectors" validate:"required"`
+}
+
+// ValidateRobots parses a robots.txt file and evaluates sample URLs against it
+// @Summary Validate robots.txt
+// @Description Parses a robots.txt body, lists the lines crawlers ignore or may misread, and decides for each sample URL and user agent whether crawling is allowed and which rule decided. The most specific user-agent group applies and the longest matching rule wins, with Allow winning ties, as Google evaluates robots.txt
+// @Tags tools
+// @Accept json
+// @Produce json
+// @Param request body RobotsValidateRequest true "robots.txt and sample URLs"
+// @Success 200 {object} map[string]interface{} "Parsed file, problems and decisions"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Security BearerAuth
+// @Router /tools/robots-validate [post]
+func (h *ToolsHandler) ValidateRobots(c *fiber.Ctx) error {
+	req := new(RobotsValidateRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+	if strings.TrimSpace(req.RobotsTxt) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "robots_txt is required",
+		})
+	}
+	if len(req.URLs) > maxRobotsURLs || len(req.UserAgents) > maxRobotsUserAgents {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Too many URLs or user agents",
+		})
+	}
+
+	userAgents := req.UserAgents
+	if len(userAgents) == 0 {
+		userAgents = defaultRobotsUserAgents
+	}
+
+	file := robots.Parse(req.RobotsTxt)
+	decisions := make([]robots.Decision, 0, len(req.URLs)*len(userAgents))
+	for _, target := range req.URLs {
+		for _, agent := range userAgents {
+			decisions = append(decisions, file.Evaluate(agent, target))
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"valid":     file.Valid(),
+			"groups":    file.Groups,
+			"sitemaps":  file.Sitemaps,
+			"problems":  file.Problems,
+			"decisions": decisions,
+		},
+	})
+}
+
+// GenerateRobots crawls a site and proposes a robots.txt for it
+// @Summary Generate robots.txt
+// @Description Crawls the internal links of a site and proposes a robots.txt that disallows the admin, account, cart and search sections found and the query parameters that create duplicate URLs: known tracking, session and sort parameters, and parameters dropped by the canonical URL of a page. The current robots.txt of the site, if any, is validated and evaluated against the crawled URLs for comparison
+// @Tags tools
+// @Accept json
+// @Produce json
+// @Param request body RobotsGenerateRequest true "Site URL"
+// @Success 200 {object} map[string]interface{} "Proposed robots.txt"
+// @Failure 400 {object} map[string]interface{} "Invalid URL"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 422 {object} map[string]interface{} "Site could not be crawled"
+// @Failure 429 {object} map[string]interface{} "Too many tool requests"
+// @Security BearerAuth
+// @Router /tools/robots-generate [post]
+func (h *ToolsHandler) GenerateRobots(c *fiber.Ctx) error {
+	req := new(RobotsGenerateRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+	siteURL, err := urlnorm.Normalize(req.URL)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid URL: " + err.Error(),
+		})
+	}
+	if !h.allowFetch(c) {
+		return nil
+	}
+	if err := checkPublicURL(c.Context(), siteURL); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	timeout := h.Config.AnalysisTimeout
+	if timeout <= 0 || timeout > maxAnalysisTimeout {
+		timeout = maxAnalysisTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	maxPages := h.Config.SitemapCrawlMaxPages
+	if maxPages <= 0 || maxPages > robotsCrawlMaxPages {
+		maxPages = robotsCrawlMaxPages
+	}
+	client := &http.Client{Timeout: sitemapFetchTimeout, Transport: parser.PublicTransport()}
+	crawl, err := parser.CrawlURL(ctx, client, siteURL, maxPages)
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	var sitemaps []string
+	if sitemap, ok := parser.FindSitemap(ctx, client, siteURL); ok {
+		sitemaps = append(sitemaps, sitemap)
+	}
+	proposal := robots.Generate(crawl.Discovered, crawl.Canonicalized, sitemaps)
+
+	data := fiber.Map{
+		"url":        siteURL,
+		"crawled":    crawl.Crawled,
+		"discovered": len(crawl.Discovered),
+		"truncated":  crawl.Truncated,
+		"robots_txt": proposal.Body,
+		"directives": proposal.Directives,
+	}
+	if current := currentRobots(ctx, client, siteURL); current != nil {
+		var blocked []string
+		for _, target := range crawl.Discovered {
+			if !current.Evaluate("*", target).Allowed {
+				blocked = append(blocked, target)
+			}
+		}
+		data["current"] = fiber.Map{
+			"valid":    current.Valid(),
+			"problems": current.Problems,
+			"blocked":  blocked,
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    data,
+	})
+}
+
+// ExtractData fetches a page and returns the values of CSS selectors
+// @Summary Extract data with CSS selectors
+// @Description Fetches a page as served, without running JavaScript, and returns for each named CSS selector the text of its matches, an attribute given in "attr" or, with "attr" set to "html", their inner HTML. Only the first match is returned unless "all" is set, and at most 100 matches per selector. URLs resolving to private or local addresses are refused, and requests count against the per-minute limit of the page-fetching tools
+// @Tags tools
+// @Accept json
+// @Produce json
+// @Param request body ExtractRequest true "Page URL and selectors"
+// @Success 200 {object} map[string]interface{} "Extracted values per selector"
+// @Failure 400 {object} map[string]interface{} "Invalid URL or selectors"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 422 {object} map[string]interface{} "Page could not be fetched"
+// @Failure 429 {object} map[string]interface{} "Too many tool requests"
+// @Security BearerAuth
+// @Router /tools/extract [post]
+func (h *ToolsHandler) ExtractData(c *fiber.Ctx) error {
+	req := new(ExtractRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+	pageURL, err := urlnorm.Normalize(req.URL)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid URL: " + err.Error(),
+		})
+	}
+	if err := parser.ValidateSelectors(req.Selectors); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	if !h.allowFetch(c) {
+		return nil
+	}
+	if err := checkPublicURL(c.Context(), pageURL); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), extractFetchTimeout)
+	defer cancel()
+	client := &http.Client{Timeout: extractFetchTimeout, Transport: parser.PublicTransport()}
+	extraction, err := parser.ExtractURL(ctx, client, pageURL, req.Selectors)
+	if err != nil {
+		status := fiber.StatusUnprocessableEntity
+		if errors.Is(err, parser.ErrPrivateAddress) {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch page: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    extraction,
+	})
+}
+
+// CheckURLs reports the status code, redirect target and response time of many URLs
+// @Summary Check the status of URLs in bulk
+// @Description Sends a HEAD request to each URL, or GET when the server refuses HEAD, without following redirects. Results are cached for the cache TTL and marked "cached", requests to a single host are throttled and URLs resolving to private or local addresses fail. Every URL that is not answered from the cache counts against a per-minute URL limit of the user. With format=csv the results are returned as a CSV file
+// @Tags tools
+// @Accept json
+// @Produce json,text/csv
+// @Param request body CheckURLsRequest true "URLs to check, at most 5000"
+// @Param format query string false "Response format: json (default) or csv"
+// @Success 200 {object} map[string]interface{} "Status of every URL in the order given"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 429 {object} map[string]interface{} "Too many tool requests"
+// @Security BearerAuth
+// @Router /tools/check-urls [post]
+func (h *ToolsHandler) CheckURLs(c *fiber.Ctx) error {
+	req := new(CheckURLsRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body: " + err.Error(),
+		})
+	}
+	if len(req.URLs) == 0 || len(req.URLs) > maxCheckURLs {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   fmt.Sprintf("Between 1 and %d URLs are required", maxCheckURLs),
+		})
+	}
+	format := c.Query("format", "json")
+	if format != "json" && format != "csv" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "format must be json or csv",
+		})
+	}
+	if !h.allowFetch(c) {
+		return nil
+	}
+
+	// Answer from the cache first and check each remaining URL once
+	results := make([]parser.URLStatus, len(req.URLs))
+	pending := make(map[string][]int)
+	var targets []string
+	for i, raw := range req.URLs {
+		target := strings.TrimSpace(raw)
+		results[i] = parser.URLStatus{URL: target}
+		if u, err := url.Parse(target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			results[i].Error = "not an absolute http(s) URL"
+			continue
+		}
+		if cached, ok := h.cachedURLStatus(target); ok {
+			results[i] = cached
+			continue
+		}
+		if _, ok := pending[target]; !ok {
+			targets = append(targets, target)
+		}
+		pending[target] = append(pending[target], i)
+	}
+
+	// Every URL that is fetched counts against the URL limit
+	if !h.allowURLChecks(c, len(targets)) {
+		return nil
+	}
+
+	if len(targets) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), maxAnalysisTimeout)
+		defer cancel()
+		parseOpts := parser.DefaultParseOptions()
+		parseOpts.UserAgent = parser.DesktopDevice.UserAgent
+		parseOpts.MaxRetries = 1
+		parseOpts.RetryDelay = time.Second
+		checked := parser.CheckURLs(ctx, targets, parser.URLCheckOptions{
+			Concurrency:  urlCheckConcurrency,
+			PerHostRate:  urlCheckHostRate,
+			Timeout:      urlCheckTimeout,
+			Transport:    parser.PublicTransport(),
+			ParseOptions: parseOpts,
+		})
+		for _, status := range checked {
+			if status.Error == "" {
+				h.cacheURLStatus(status)
+			}
+			for _, i := range pending[status.URL] {
+				results[i] = status
+			}
+		}
+	}
+
+	if format == "csv" {
+		body, err := urlStatusCSV(results)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"success": false,
+				"error":   "Failed to render CSV: " + err.Error(),
+			})
+		}
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="url-status.csv"`)
+		return c.Send(body)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"results": results,
+			"checked": len(targets),
+		},
+	})
+}
+
+// cachedURLStatus returns the cached status of a URL
+func (h *ToolsHandler) cachedURLStatus(target string) (parser.URLStatus, bool) {
+	var status parser.URLStatus
+	if h.Cache == nil || h.Config.CacheTTL <= 0 {
+		return status, false
+	}
+	if err := h.Cache.Get(keyPrefixURLStatus+target, &status); err != nil {
+		return status, false
+	}
+	status.Cached = true
+	return status, true
+}
+
+// cacheURLStatus stores the status of a URL for the cache TTL
+func (h *ToolsHandler) cacheURLStatus(status parser.URLStatus) {
+	if h.Cache == nil || h.Config.CacheTTL <= 0 {
+		return
+	}
+	if err := h.Cache.Set(keyPrefixURLStatus+status.URL, status, h.Config.CacheTTL); err != nil {
+		log.Printf("Failed to cache status of %s: %v", status.URL, err)
+	}
+}
+
+// urlStatusCSV renders URL statuses as CSV with the response time in milliseconds
+func urlStatusCSV(results []parser.URLStatus) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"url", "status_code", "redirect_to", "response_time_ms", "error", "cached"})
+	for _, r := range results {
+		status := ""
+		if r.StatusCode > 0 {
+			status = strconv.Itoa(r.StatusCode)
+		}
+		w.Write([]string{
+			r.URL,
+			status,
+			r.RedirectTo,
+			strconv.FormatInt(r.ResponseTime.Milliseconds(), 10),
+			r.Error,
+			strconv.FormatBool(r.Cached),
+		})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// allowFetch counts a page-fetching tool request against the per-minute limit
+// of the user and responds with 429 when it is exceeded. Requests are allowed
+// when Redis is unavailable.
+func (h *ToolsHandler) allowFetch(c *fiber.Ctx) bool {
+	return h.allowRate(c, "requests", 1, h.Config.ToolsRateLimit, "Too many tool requests, at most %d per minute are allowed")
+}
+
+// allowURLChecks counts the URLs a bulk check fetches against the per-minute
+// URL limit of the user and responds with 429 when it is exceeded
+func (h *ToolsHandler) allowURLChecks(c *fiber.Ctx, count int) bool {
+	return h.allowRate(c, "urls", count, h.Config.ToolsURLCheckRateLimit, "Too many URL checks, at most %d URLs per minute are allowed")
+}
+
+// allowRate adds cost to a per-minute counter of the user and responds with
+// 429 when it exceeds limit. message is formatted with the limit.
+func (h *ToolsHandler) allowRate(c *fiber.Ctx, counter string, cost, limit int, message string) bool {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if limit <= 0 || cost <= 0 || !ok || h.RedisClient == nil {
+		return true
+	}
+
+	ctx := context.Background()
+	window := time.Now().Truncate(time.Minute)
+	key := fmt.Sprintf("%s%s:%s:%d", keyPrefixToolsRate, counter, userID, window.Unix())
+	pipe := h.RedisClient.Client.TxPipeline()
+	count := pipe.IncrBy(ctx, key, int64(cost))
+	pipe.Expire(ctx, key, time.Minute)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to count tool %s of user %s: %v", counter, userID, err)
+		return true
+	}
+	if count.Val() <= int64(limit) {
+		return true
+	}
+
+	retryAfter := int(time.Until(window.Add(time.Minute)).Seconds()) + 1
+	c.Set(fiber.HeaderRetryAfter, fmt.Sprint(retryAfter))
+	c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"success": false,
+		"error":   fmt.Sprintf(message, limit),
+	})
+	return false
+}
+
+// checkPublicURL refuses URLs whose host resolves to a private or local
+// address. The transport checks every connection again, so this only gives
+// an early and clear error.
+func checkPublicURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	return parser.CheckPublicHost(ctx, u.Hostname())
+}
+
+// currentRobots fetches and parses the robots.txt of a site, or returns nil
+func currentRobots(ctx context.Context, client *http.Client, siteURL string) *robots.File {
+	site, err := url.Parse(siteURL)
+	if err != nil {
+		return nil
+	}
+	robotsURL := site.ResolveReference(&url.URL{Path: "/robots.txt"})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL.String(), nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("User-Agent", parser.DesktopDevice.UserAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, robots.MaxSize))
+	if err != nil {
+		return nil
+	}
+	return robots.Parse(string(body))
+}
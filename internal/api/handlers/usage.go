@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+)
+
+type UsageHandler struct {
+	UsageRepo repository.UsageRepository
+	UserRepo  repository.UserRepository
+}
+
+// NewUsageHandler creates a new usage handler
+func NewUsageHandler(repoFactory *repository.Factory) *UsageHandler {
+	return &UsageHandler{
+		UsageRepo: repoFactory.UsageRepository,
+		UserRepo:  repoFactory.UserRepository,
+	}
+}
+
+// parseUsageDate accepts either RFC 3339 timestamps or plain dates
+func parseUsageDate(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// GetAnalysesUsage returns resource usage of analyses aggregated by period
+// @Summary Get analysis usage
+// @Description Returns bytes fetched, headless browser time, Lighthouse API calls, LLM tokens and cost of analyses aggregated per user and period. Admins may query any user or all users; other roles only see their own usage
+// @Tags usage
+// @Produce json
+// @Param period query string false "Aggregation period (day, week, month)" default(day)
+// @Param from query string false "Start of the range (RFC 3339 or YYYY-MM-DD), defaults to 30 days ago"
+// @Param to query string false "End of the range (RFC 3339 or YYYY-MM-DD), defaults to now"
+// @Param user_id query string false "User ID (admin only)"
+// @Param locale query string false "Locale of the formatted totals, e.g. de-CH"
+// @Success 200 {object} map[string]interface{} "Usage breakdown"
+// @Failure 400 {object} map[string]interface{} "Invalid query"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /usage/analyses [get]
+func (h *UsageHandler) GetAnalysesUsage(c *fiber.Ctx) error {
+	period := repository.UsagePeriod(c.Query("period", string(repository.UsagePeriodDay)))
+	switch period {
+	case repository.UsagePeriodDay, repository.UsagePeriodWeek, repository.UsagePeriodMonth:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid period, expected day, week or month",
+		})
+	}
+
+	now := time.Now()
+	from, err := parseUsageDate(c.Query("from"), now.AddDate(0, 0, -30))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid from date",
+		})
+	}
+	to, err := parseUsageDate(c.Query("to"), now)
+	if err != nil || !to.After(from) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid to date",
+		})
+	}
+
+	filter := repository.UsageFilter{
+		From:   from,
+		To:     to,
+		Period: period,
+	}
+
+	role, _ := c.Locals("role").(string)
+	if role == "admin" {
+		if userParam := c.Query("user_id"); userParam != "" {
+			userID, err := uuid.Parse(userParam)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"success": false,
+					"error":   "Invalid user ID",
+				})
+			}
+			filter.UserID = &userID
+		}
+	} else {
+		userID := c.Locals("userID").(uuid.UUID)
+		filter.UserID = &userID
+	}
+
+	buckets, err := h.UsageRepo.Aggregate(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to aggregate usage: " + err.Error(),
+		})
+	}
+
+	var totals repository.UsageBucket
+	for _, bucket := range buckets {
+		totals.Analyses += bucket.Analyses
+		totals.BytesFetched += bucket.BytesFetched
+		totals.HeadlessMs += bucket.HeadlessMs
+		totals.LighthouseCalls += bucket.LighthouseCalls
+		totals.LLMPromptTokens += bucket.LLMPromptTokens
+		totals.LLMCompletionTokens += bucket.LLMCompletionTokens
+		totals.Cost += bucket.Cost
+	}
+
+	format := responseFormat(c, h.UserRepo)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"period":  period,
+			"from":    from,
+			"to":      to,
+			"buckets": buckets,
+			"totals": fiber.Map{
+				"analyses":              totals.Analyses,
+				"bytes_fetched":         totals.BytesFetched,
+				"headless_ms":           totals.HeadlessMs,
+				"lighthouse_calls":      totals.LighthouseCalls,
+				"llm_prompt_tokens":     totals.LLMPromptTokens,
+				"llm_completion_tokens": totals.LLMCompletionTokens,
+				"cost":                  totals.Cost,
+			},
+			"totals_formatted": fiber.Map{
+				"analyses":              format.Number(float64(totals.Analyses), 0),
+				"bytes_fetched":         format.Bytes(totals.BytesFetched),
+				"headless_time":         format.Duration(totals.HeadlessMs),
+				"lighthouse_calls":      format.Number(float64(totals.LighthouseCalls), 0),
+				"llm_prompt_tokens":     format.Number(float64(totals.LLMPromptTokens), 0),
+				"llm_completion_tokens": format.Number(float64(totals.LLMCompletionTokens), 0),
+				"cost":                  format.Number(totals.Cost, 2),
+			},
+		},
+		"formatting": format,
+	})
+}
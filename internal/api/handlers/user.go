@@ -9,6 +9,7 @@ import (
 	"github.com/chynybekuuludastan/website_optimizer/internal/config"
 	"github.com/chynybekuuludastan/website_optimizer/internal/models"
 	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/numfmt"
 )
 
 // UserHandler handles user-related requests
@@ -30,6 +31,8 @@ type UpdateUserRequest struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// Locale selects number and unit formatting, e.g. "de-CH"; "-" clears it
+	Locale string `json:"locale"`
 }
 
 // UpdateRoleRequest represents a request to update a user's role
@@ -123,6 +126,7 @@ func (h *UserHandler) GetUser(c *fiber.Ctx) error {
 			"username":   user.Username,
 			"email":      user.Email,
 			"role":       user.Role.Name,
+			"locale":     user.Locale,
 			"created_at": user.CreatedAt,
 			"updated_at": user.UpdatedAt,
 		},
@@ -205,6 +209,20 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 		user.PasswordHash = string(hashedPassword)
 	}
 
+	if req.Locale == "-" {
+		user.Locale = ""
+	} else if req.Locale != "" {
+		format, ok := numfmt.Lookup(req.Locale)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success":   false,
+				"error":     "Unsupported locale",
+				"supported": numfmt.Supported(),
+			})
+		}
+		user.Locale = format.Locale
+	}
+
 	if err := h.UserRepo.Update(&user); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"success": false,
@@ -7,8 +7,8 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
+	"github.com/chynybekuuludastan/website_optimizer/internal/cache"
 	"github.com/chynybekuuludastan/website_optimizer/internal/config"
-	"github.com/chynybekuuludastan/website_optimizer/internal/database"
 	"github.com/chynybekuuludastan/website_optimizer/internal/models"
 	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
 )
@@ -22,18 +22,18 @@ type CreateWebsiteRequest struct {
 type WebsiteHandler struct {
 	WebsiteRepo  repository.WebsiteRepository
 	AnalysisRepo repository.AnalysisRepository
-	RedisClient  *database.RedisClient
+	Cache        cache.Cache
 	Config       *config.Config
 }
 
 func NewWebsiteHandler(websiteRepo repository.WebsiteRepository,
 	analysisRepo repository.AnalysisRepository,
-	redisClient *database.RedisClient,
+	cacheStore cache.Cache,
 	cfg *config.Config) *WebsiteHandler {
 	return &WebsiteHandler{
 		WebsiteRepo:  websiteRepo,
 		AnalysisRepo: analysisRepo,
-		RedisClient:  redisClient,
+		Cache:        cacheStore,
 		Config:       cfg,
 	}
 }
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/config"
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	ws "github.com/chynybekuuludastan/website_optimizer/internal/websocket"
+)
+
+// WebSocketHandler handles real-time connections and room presence
+type WebSocketHandler struct {
+	Hub          *ws.Hub
+	UserRepo     repository.UserRepository
+	AnalysisRepo repository.AnalysisRepository
+	ContentEdit  *ContentEditHandler
+	Config       *config.Config
+}
+
+// NewWebSocketHandler creates a new WebSocket handler
+func NewWebSocketHandler(hub *ws.Hub, repoFactory *repository.Factory, contentEdit *ContentEditHandler, cfg *config.Config) *WebSocketHandler {
+	h := &WebSocketHandler{
+		Hub:          hub,
+		UserRepo:     repoFactory.UserRepository,
+		AnalysisRepo: repoFactory.AnalysisRepository,
+		ContentEdit:  contentEdit,
+		Config:       cfg,
+	}
+
+	hub.SetRoomAuthorizer(h.authorizeRoom)
+	hub.SetAbuseHandler(h.recordAbuse)
+
+	return h
+}
+
+// Upgrade rejects requests that are not WebSocket handshakes
+func (h *WebSocketHandler) Upgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return c.Status(fiber.StatusUpgradeRequired).JSON(fiber.Map{
+			"success": false,
+			"error":   "WebSocket upgrade required",
+		})
+	}
+
+	return c.Next()
+}
+
+// @Summary Open a WebSocket connection
+// @Description Upgrades the connection to WebSocket. Clients join analysis rooms with {"type":"join","room":"analysis:<id>"} and receive presence and typing events. The owner of an analysis edits an improvement together with other reviewers in the room improvement:<id>
+// @Tags websocket
+// @Param token query string true "JWT access token"
+// @Success 101 {string} string "Switching Protocols"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 426 {object} map[string]interface{} "WebSocket upgrade required"
+// @Router /ws [get]
+func (h *WebSocketHandler) Connect() fiber.Handler {
+	return websocket.New(func(conn *websocket.Conn) {
+		userID, _ := conn.Locals("userID").(uuid.UUID)
+		role, _ := conn.Locals("role").(string)
+
+		username := userID.String()
+		var user models.User
+		if err := h.UserRepo.FindByID(userID, &user); err == nil {
+			username = user.Username
+		}
+
+		client := ws.NewClient(h.Hub, conn, userID, username, role)
+		client.Serve()
+	}, websocket.Config{
+		// Negotiates permessage-deflate with clients that support it
+		EnableCompression: h.Config.WSCompression,
+	})
+}
+
+// GetAnalysisPresence returns the users currently viewing an analysis
+// @Summary Get analysis presence
+// @Description Returns the users currently connected to the analysis room. REST fallback for clients without WebSocket support
+// @Tags analysis
+// @Accept json
+// @Produce json
+// @Param id path string true "Analysis ID"
+// @Success 200 {object} map[string]interface{} "Presence list"
+// @Failure 400 {object} map[string]interface{} "Invalid analysis ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Analysis not found"
+// @Security BearerAuth
+// @Router /analysis/{id}/presence [get]
+func (h *WebSocketHandler) GetAnalysisPresence(c *fiber.Ctx) error {
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid analysis ID",
+		})
+	}
+
+	var analysis models.Analysis
+	if err := h.AnalysisRepo.FindByID(analysisID, &analysis); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Analysis not found",
+		})
+	}
+
+	room := ws.AnalysisRoom(analysisID.String())
+	presence := h.Hub.Presence(room)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"analysis_id": analysisID,
+			"room":        room,
+			"viewers":     presence,
+			"count":       len(presence),
+		},
+	})
+}
+
+// GetUndeliveredStats returns counts of critical messages by delivery status
+// @Summary Get undelivered WebSocket message stats
+// @Description Returns stored critical messages grouped by delivery status together with the active cleanup policy
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Delivery statistics"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /admin/websocket/undelivered [get]
+func (h *WebSocketHandler) GetUndeliveredStats(c *fiber.Ctx) error {
+	stats, err := h.Hub.UndeliveredStats(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch delivery statistics: " + err.Error(),
+		})
+	}
+
+	policy := h.Hub.AckPolicy()
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"messages":          stats,
+			"connected_clients": h.Hub.ClientCount(),
+			"policy": fiber.Map{
+				"retry_interval_seconds": policy.RetryInterval.Seconds(),
+				"ttl_hours":              policy.TTL.Hours(),
+				"max_attempts":           policy.MaxAttempts,
+				"max_pending_per_user":   policy.MaxPendingPerUser,
+			},
+		},
+	})
+}
+
+// GetBufferStats returns send buffer usage and evictions
+// @Summary Get WebSocket send buffer stats
+// @Description Returns how many outbound messages were queued, compressed, evicted because a client's send buffer exceeded its message or byte limit, or dropped, and how much is buffered right now
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Buffer statistics"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Security BearerAuth
+// @Router /admin/websocket/buffers [get]
+func (h *WebSocketHandler) GetBufferStats(c *fiber.Ctx) error {
+	limits := h.Hub.ClientLimits()
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"buffers":           h.Hub.BufferStats(),
+			"connected_clients": h.Hub.ClientCount(),
+			"limits": fiber.Map{
+				"send_buffer_messages": limits.SendBufferMessages,
+				"send_buffer_bytes":    limits.SendBufferBytes,
+				"compress_threshold":   limits.CompressThreshold,
+			},
+		},
+	})
+}
+
+// CleanupUndelivered applies the cleanup policy to stored critical messages
+// @Summary Clean up undelivered WebSocket messages
+// @Description Removes acknowledged, expired and overflowing critical messages according to the configured policy
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Cleanup result"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /admin/websocket/cleanup [post]
+func (h *WebSocketHandler) CleanupUndelivered(c *fiber.Ctx) error {
+	removed, err := h.Hub.CleanupUndelivered(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to clean up messages: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"removed": removed,
+		},
+	})
+}
+
+// authorizeRoom only allows joining rooms of analyses that exist and rooms
+// of improvements the client may edit
+func (h *WebSocketHandler) authorizeRoom(client *ws.Client, room string) error {
+	if id, ok := strings.CutPrefix(room, "improvement:"); ok && h.ContentEdit != nil {
+		improvementID, err := uuid.Parse(id)
+		if err != nil {
+			return fmt.Errorf("invalid improvement ID")
+		}
+		return h.ContentEdit.AuthorizeRoom(client, improvementID)
+	}
+
+	id, ok := strings.CutPrefix(room, "analysis:")
+	if !ok {
+		return fmt.Errorf("unknown room: %s", room)
+	}
+
+	analysisID, err := uuid.Parse(id)
+	if err != nil {
+		return fmt.Errorf("invalid analysis ID")
+	}
+
+	var analysis models.Analysis
+	if err := h.AnalysisRepo.FindByID(analysisID, &analysis); err != nil {
+		return fmt.Errorf("analysis not found")
+	}
+
+	return nil
+}
+
+// recordAbuse writes a WebSocket limit violation to the user activity log
+func (h *WebSocketHandler) recordAbuse(client *ws.Client, event ws.AbuseEvent) {
+	details, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to marshal WebSocket abuse event: %v", err)
+		return
+	}
+
+	activity := models.UserActivity{
+		UserID:     client.UserID,
+		ActionType: "websocket_abuse",
+		EntityType: "websocket",
+		Details:    details,
+	}
+	if err := h.UserRepo.Create(&activity); err != nil {
+		log.Printf("Failed to record WebSocket abuse for user %s: %v", client.UserID, err)
+	}
+}
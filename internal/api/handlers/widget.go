@@ -0,0 +1,412 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/cache"
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+)
+
+// Traffic-light thresholds of the overall score
+const (
+	widgetGreenScore = 80.0
+	widgetRedScore   = 50.0
+)
+
+// Widget statuses
+const (
+	WidgetStatusGreen = "green"
+	WidgetStatusAmber = "amber"
+	WidgetStatusRed   = "red"
+)
+
+const (
+	// widgetTopIssues is the number of issues shown in a widget
+	widgetTopIssues = 3
+	// widgetOriginCacheTTL bounds how long an origin check is cached
+	widgetOriginCacheTTL = time.Minute
+	// widgetMaxAge is how long browsers may cache a preflight response
+	widgetMaxAge = "600"
+)
+
+// WidgetOriginRequest registers an origin for summary widgets
+type WidgetOriginRequest struct {
+	// Origin is the scheme, host and optional port of the page embedding the
+	// widget, e.g. https://cms.example.com
+	Origin string `json:"origin" validate:"required"`
+}
+
+// WidgetIssue is an issue shown in a summary widget
+type WidgetIssue struct {
+	Category string `json:"category"`
+	Severity string `json:"severity"`
+	Title    string `json:"title"`
+}
+
+// SummaryWidget is the compact status of a website for CMS dashboards
+type SummaryWidget struct {
+	WebsiteID      uuid.UUID     `json:"website_id"`
+	URL            string        `json:"url"`
+	Status         string        `json:"status"` // green, amber or red
+	OverallScore   *float64      `json:"overall_score"`
+	TopIssues      []WidgetIssue `json:"top_issues"`
+	AnalysisID     uuid.UUID     `json:"analysis_id"`
+	LastAnalyzedAt time.Time     `json:"last_analyzed_at"`
+}
+
+// WidgetHandler serves summary widgets embedded in CMS admin dashboards
+type WidgetHandler struct {
+	WebsiteRepo      repository.WebsiteRepository
+	AnalysisRepo     repository.AnalysisRepository
+	WidgetOriginRepo repository.WidgetOriginRepository
+	Cache            cache.Cache
+}
+
+// NewWidgetHandler creates a new widget handler
+func NewWidgetHandler(repoFactory *repository.Factory, cacheStore cache.Cache) *WidgetHandler {
+	return &WidgetHandler{
+		WebsiteRepo:      repoFactory.WebsiteRepository,
+		AnalysisRepo:     repoFactory.AnalysisRepository,
+		WidgetOriginRepo: repoFactory.WidgetOriginRepository,
+		Cache:            cacheStore,
+	}
+}
+
+// CORS answers cross-origin requests to the widget API. Only origins that a
+// user registered are allowed; preflight requests are answered here, before
+// authentication. Requests without an Origin header are not cross-origin
+// and pass through.
+func (h *WidgetHandler) CORS(c *fiber.Ctx) error {
+	origin := c.Get(fiber.HeaderOrigin)
+	if origin == "" {
+		return c.Next()
+	}
+	c.Vary(fiber.HeaderOrigin)
+
+	normalized, err := normalizeOrigin(origin)
+	if err != nil || !h.originRegistered(normalized) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"success": false,
+			"error":   "Origin is not registered for widgets",
+		})
+	}
+
+	c.Set(fiber.HeaderAccessControlAllowOrigin, origin)
+	if c.Method() == fiber.MethodOptions {
+		c.Set(fiber.HeaderAccessControlAllowMethods, "GET, OPTIONS")
+		c.Set(fiber.HeaderAccessControlAllowHeaders, "Authorization, Accept")
+		c.Set(fiber.HeaderAccessControlMaxAge, widgetMaxAge)
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+	c.Locals("widgetOrigin", normalized)
+	return c.Next()
+}
+
+// originRegistered checks an origin against the registered origins, caching
+// the answer briefly since every widget load triggers the check
+func (h *WidgetHandler) originRegistered(origin string) bool {
+	cacheKey := "widget_origin:" + origin
+	if h.Cache != nil {
+		var registered bool
+		if err := h.Cache.Get(cacheKey, &registered); err == nil {
+			return registered
+		}
+	}
+
+	registered, err := h.WidgetOriginRepo.IsRegistered(origin)
+	if err != nil {
+		return false
+	}
+	if h.Cache != nil {
+		h.Cache.Set(cacheKey, registered, widgetOriginCacheTTL)
+	}
+	return registered
+}
+
+// GetSummaryWidget returns the traffic-light summary of a website
+// @Summary Get website summary widget
+// @Description Returns a compact summary of the latest completed analysis of a website for embedding in CMS admin dashboards: a red/amber/green status, the overall score, the top 3 issues by severity and the time of the analysis. Green needs an overall score of at least 80 and no high-severity issue; a score below 50 is red. Browsers may call this endpoint only from origins the user registered under /widget-origins
+// @Tags websites
+// @Produce json
+// @Param id path string true "Website ID"
+// @Success 200 {object} map[string]interface{} "Summary widget"
+// @Failure 400 {object} map[string]interface{} "Invalid website ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Origin not registered"
+// @Failure 404 {object} map[string]interface{} "Website not found or not analyzed yet"
+// @Security BearerAuth
+// @Router /websites/{id}/summary-widget [get]
+func (h *WidgetHandler) GetSummaryWidget(c *fiber.Ctx) error {
+	websiteID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid website ID",
+		})
+	}
+
+	// The origin must be one the calling user registered, not just anyone
+	if origin, ok := c.Locals("widgetOrigin").(string); ok {
+		userID := c.Locals("userID").(uuid.UUID)
+		registered, err := h.WidgetOriginRepo.IsRegisteredForUser(userID, origin)
+		if err != nil || !registered {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"success": false,
+				"error":   "Origin is not registered for widgets",
+			})
+		}
+	}
+
+	var website models.Website
+	if err := h.WebsiteRepo.FindByID(websiteID, &website); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Website not found",
+		})
+	}
+
+	analysis, err := h.AnalysisRepo.FindLatestCompletedByWebsiteID(websiteID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Website has not been analyzed yet",
+		})
+	}
+	_, issues, err := h.AnalysisRepo.FindWithIssues(analysis.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load issues: " + err.Error(),
+		})
+	}
+
+	widget := SummaryWidget{
+		WebsiteID:      website.ID,
+		URL:            website.URL,
+		AnalysisID:     analysis.ID,
+		LastAnalyzedAt: analysis.CompletedAt,
+		TopIssues:      []WidgetIssue{},
+	}
+	if widget.LastAnalyzedAt.IsZero() {
+		widget.LastAnalyzedAt = analysis.UpdatedAt
+	}
+	if raw := metadataValue(analysis.Metadata, "overall_score"); raw != nil {
+		var score float64
+		if json.Unmarshal(raw, &score) == nil {
+			widget.OverallScore = &score
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return getSeverityValue(issues[i].Severity) > getSeverityValue(issues[j].Severity)
+	})
+	highIssues := false
+	for i, issue := range issues {
+		if issue.Severity == "high" {
+			highIssues = true
+		}
+		if i < widgetTopIssues {
+			widget.TopIssues = append(widget.TopIssues, WidgetIssue{
+				Category: issue.Category,
+				Severity: issue.Severity,
+				Title:    issue.Title,
+			})
+		}
+	}
+	widget.Status = widgetStatus(widget.OverallScore, highIssues)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    widget,
+	})
+}
+
+// widgetStatus maps the overall score and issues to a traffic light
+func widgetStatus(score *float64, highIssues bool) string {
+	switch {
+	case score == nil || *score < widgetRedScore:
+		return WidgetStatusRed
+	case *score < widgetGreenScore || highIssues:
+		return WidgetStatusAmber
+	default:
+		return WidgetStatusGreen
+	}
+}
+
+// saveOverallScore stores the overall score of a completed analysis under the
+// "overall_score" metadata key the summary widget reads
+func (a *AnalysisHandler) saveOverallScore(analysisID uuid.UUID, score float64) {
+	if err := a.AnalysisRepo.SetMetadataKey(analysisID, "overall_score", score); err != nil {
+		log.Printf("Failed to store overall score of analysis %s: %v", analysisID, err)
+	}
+}
+
+// ListWidgetOrigins returns the origins the user registered for widgets
+// @Summary List widget origins
+// @Description Returns the origins from which the user embeds summary widgets
+// @Tags websites
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Registered origins"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /widget-origins [get]
+func (h *WidgetHandler) ListWidgetOrigins(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	origins, err := h.WidgetOriginRepo.FindByUserID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to load widget origins: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    origins,
+	})
+}
+
+// RegisterWidgetOrigin allows an origin to call the widget API
+// @Summary Register a widget origin
+// @Description Allows browsers on the origin, e.g. the admin of a WordPress site, to load summary widgets with the user's token
+// @Tags websites
+// @Accept json
+// @Produce json
+// @Param origin body WidgetOriginRequest true "Origin"
+// @Success 201 {object} map[string]interface{} "Origin registered"
+// @Failure 400 {object} map[string]interface{} "Invalid origin"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 409 {object} map[string]interface{} "Origin already registered"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /widget-origins [post]
+func (h *WidgetHandler) RegisterWidgetOrigin(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	var request WidgetOriginRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body",
+		})
+	}
+	origin, err := normalizeOrigin(request.Origin)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	if registered, err := h.WidgetOriginRepo.IsRegisteredForUser(userID, origin); err == nil && registered {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"success": false,
+			"error":   "Origin already registered",
+		})
+	}
+
+	widgetOrigin := &models.WidgetOrigin{UserID: userID, Origin: origin}
+	if err := h.WidgetOriginRepo.Create(widgetOrigin); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to register origin: " + err.Error(),
+		})
+	}
+	h.forgetOrigin(origin)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data":    widgetOrigin,
+	})
+}
+
+// DeleteWidgetOrigin revokes a registered origin
+// @Summary Delete a widget origin
+// @Description Stops browsers on the origin from loading the user's summary widgets
+// @Tags websites
+// @Produce json
+// @Param id path string true "Widget origin ID"
+// @Success 200 {object} map[string]interface{} "Origin deleted"
+// @Failure 400 {object} map[string]interface{} "Invalid ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Origin not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Security BearerAuth
+// @Router /widget-origins/{id} [delete]
+func (h *WidgetHandler) DeleteWidgetOrigin(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid widget origin ID",
+		})
+	}
+
+	widgetOrigin, err := h.WidgetOriginRepo.FindForUser(userID, id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Widget origin not found",
+		})
+	}
+	if err := h.WidgetOriginRepo.Delete(widgetOrigin); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to delete origin: " + err.Error(),
+		})
+	}
+	h.forgetOrigin(widgetOrigin.Origin)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Widget origin deleted",
+	})
+}
+
+// forgetOrigin drops the cached check of an origin after it changed
+func (h *WidgetHandler) forgetOrigin(origin string) {
+	if h.Cache != nil {
+		h.Cache.Delete("widget_origin:" + origin)
+	}
+}
+
+// normalizeOrigin validates an origin and returns it in the form browsers
+// send in the Origin header: lowercase scheme and host, no default port
+func normalizeOrigin(raw string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || parsed.Host == "" {
+		return "", fiber.NewError(fiber.StatusBadRequest, "origin must be a URL such as https://cms.example.com")
+	}
+	scheme := strings.ToLower(parsed.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", fiber.NewError(fiber.StatusBadRequest, "origin must use http or https")
+	}
+	if (parsed.Path != "" && parsed.Path != "/") || parsed.RawQuery != "" || parsed.User != nil {
+		return "", fiber.NewError(fiber.StatusBadRequest, "origin must not contain a path, query or credentials")
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	port := parsed.Port()
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	return scheme + "://" + host, nil
+}
@@ -43,12 +43,8 @@ func JWTMiddleware(cfg *config.Config) fiber.Handler {
 		// If no Bearer prefix, use the header value as is for the token
 
 		// Parse and validate the token
-		claims := &JWTClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(cfg.JWTSecret), nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := ParseToken(tokenString, cfg.JWTSecret)
+		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"success": false,
 				"error":   "Invalid or expired token",
@@ -63,6 +59,53 @@ func JWTMiddleware(cfg *config.Config) fiber.Handler {
 	}
 }
 
+// WebSocketMiddleware authenticates WebSocket upgrade requests. Browsers cannot
+// set headers on the handshake, so the token may also be passed as ?token=
+func WebSocketMiddleware(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tokenString := c.Query("token")
+		if tokenString == "" {
+			tokenString = strings.TrimPrefix(c.Get("Authorization"), "Bearer ")
+		}
+
+		if tokenString == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"success": false,
+				"error":   "Token is required",
+			})
+		}
+
+		claims, err := ParseToken(tokenString, cfg.JWTSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"success": false,
+				"error":   "Invalid or expired token",
+			})
+		}
+
+		c.Locals("userID", claims.UserID)
+		c.Locals("role", claims.Role)
+
+		return c.Next()
+	}
+}
+
+// ParseToken validates a JWT and returns its claims
+func ParseToken(tokenString, secret string) (*JWTClaims, error) {
+	claims := &JWTClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	return claims, nil
+}
+
 // GenerateJWT creates a new JWT token
 func GenerateJWT(user *models.User, role string, secret string, expiration time.Duration) (string, error) {
 	claims := JWTClaims{
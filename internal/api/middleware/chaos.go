@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/chaos"
+)
+
+// Chaos creates fault injection middleware for resilience testing. Faults
+// from the X-Chaos-Faults header are added to the configured ones and carried
+// in the request's user context, so analyses started by the request inherit
+// them. The "request" fault is applied to the request itself.
+func Chaos() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if spec := c.Get(chaos.HeaderFaults); spec != "" {
+			faults, err := chaos.Parse(spec)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"success": false,
+					"error":   err.Error(),
+				})
+			}
+			c.SetUserContext(chaos.WithFaults(c.UserContext(), faults))
+		}
+
+		if err := chaos.Inject(c.UserContext(), chaos.TargetRequest); err != nil {
+			status := fiber.StatusServiceUnavailable
+			if statusErr, ok := err.(*chaos.StatusError); ok {
+				status = statusErr.Code
+			}
+			return c.Status(status).JSON(fiber.Map{
+				"success": false,
+				"error":   err.Error(),
+			})
+		}
+
+		return c.Next()
+	}
+}
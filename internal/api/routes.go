@@ -1,19 +1,25 @@
 package api
 
 import (
+	"context"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/swagger"
+	"github.com/google/uuid"
 	"golang.org/x/time/rate"
 
 	"github.com/chynybekuuludastan/website_optimizer/internal/api/handlers"
 	"github.com/chynybekuuludastan/website_optimizer/internal/api/middleware"
+	"github.com/chynybekuuludastan/website_optimizer/internal/cache"
 	"github.com/chynybekuuludastan/website_optimizer/internal/config"
 	"github.com/chynybekuuludastan/website_optimizer/internal/database"
 	"github.com/chynybekuuludastan/website_optimizer/internal/repository"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/billing"
 	"github.com/chynybekuuludastan/website_optimizer/internal/service/llm"
 	"github.com/chynybekuuludastan/website_optimizer/internal/service/llm/providers"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/outbox"
+	ws "github.com/chynybekuuludastan/website_optimizer/internal/websocket"
 )
 
 // @title Website Optimizer API
@@ -33,20 +39,109 @@ import (
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token
 
-func SetupRoutes(app *fiber.App, db *database.DatabaseClient, redisClient *database.RedisClient, cfg *config.Config) {
+// SetupRoutes registers the API. Responses are cached in cacheStore; the
+// locks, counters and acknowledgements that need Redis fall back to
+// in-process state or are skipped when redisClient is nil.
+func SetupRoutes(app *fiber.App, db *database.DatabaseClient, cacheStore cache.Cache, redisClient *database.RedisClient, cfg *config.Config) {
 	// Initialize repository factory
-	repoFactory := repository.NewRepositoryFactory(db.DB, redisClient.Client)
+	repoFactory := repository.NewRepositoryFactory(db.DB, redisClient.Conn())
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(repoFactory.UserRepository, redisClient, cfg)
+	authHandler := handlers.NewAuthHandler(repoFactory.UserRepository, cacheStore, cfg)
 	userHandler := handlers.NewUserHandler(repoFactory.UserRepository, cfg)
 	websiteHandler := handlers.NewWebsiteHandler(
 		repoFactory.WebsiteRepository,
 		repoFactory.AnalysisRepository,
-		redisClient,
+		cacheStore,
 		cfg,
 	)
-	analysisHandler := handlers.NewAnalysisHandler(repoFactory, redisClient, cfg)
+
+	// Initialize WebSocket hub for real-time analysis rooms
+	hub := ws.NewHub()
+	ackStore := ws.NewMemoryAckStore()
+	if rdb := redisClient.Conn(); rdb != nil {
+		ackStore = ws.NewRedisAckStore(rdb)
+	}
+	hub.SetAckStore(ackStore, ws.AckPolicy{
+		RetryInterval:     cfg.WSAckRetryInterval,
+		TTL:               cfg.WSAckTTL,
+		MaxAttempts:       cfg.WSAckMaxAttempts,
+		MaxPendingPerUser: cfg.WSAckMaxPending,
+	})
+	hub.SetClientLimits(ws.ClientLimits{
+		MessagesPerSecond: cfg.WSRateLimit,
+		Burst:             cfg.WSRateBurst,
+		MaxRooms:          cfg.WSMaxRooms,
+		MaxMessageSize:    cfg.WSMaxMessageSize,
+		MaxViolations:     cfg.WSMaxViolations,
+
+		SendBufferMessages: cfg.WSSendBufferSize,
+		SendBufferBytes:    cfg.WSSendBufferBytes,
+		CompressThreshold:  cfg.WSCompressMinSize,
+	})
+	go hub.RunAckRetry(context.Background())
+	contentEditHandler := handlers.NewContentEditHandler(hub, repoFactory, cacheStore, cfg)
+	go contentEditHandler.RunSnapshots(context.Background())
+	wsHandler := handlers.NewWebSocketHandler(hub, repoFactory, contentEditHandler, cfg)
+
+	// Billing plans and quota enforcement
+	plans := billing.NewPlans(cfg.StripePricePro, cfg.StripePriceAgency)
+	quota := billing.NewQuota(plans, func(userID uuid.UUID) string {
+		return handlers.EffectivePlan(repoFactory.SubscriptionRepository, userID)
+	}, redisClient.Conn())
+	quota.RegisterCounter(billing.ResourceAnalyses, true, func(ctx context.Context, userID uuid.UUID, since time.Time) (int64, error) {
+		return repoFactory.AnalysisRepository.CountByUserSince(userID, since)
+	})
+	quota.RegisterCounter(billing.ResourceMonitors, false, func(ctx context.Context, userID uuid.UUID, since time.Time) (int64, error) {
+		return repoFactory.MonitoredSiteRepository.CountActiveByUser(userID)
+	})
+	quota.TrackMonthly(billing.ResourceLLMGenerations)
+	billingHandler := handlers.NewBillingHandler(
+		repoFactory,
+		billing.NewStripeClient(cfg.StripeSecretKey, cfg.StripeWebhookSecret),
+		plans,
+		quota,
+		cfg,
+	)
+
+	analysisHandler := handlers.NewAnalysisHandler(repoFactory, cacheStore, redisClient, hub, quota, cfg)
+	go analysisHandler.RunMonitors(context.Background())
+	usageHandler := handlers.NewUsageHandler(repoFactory)
+	insightsHandler := handlers.NewInsightsHandler(repoFactory)
+	technologyHandler := handlers.NewTechnologyHandler(repoFactory)
+	issueFeedbackHandler := handlers.NewIssueFeedbackHandler(repoFactory)
+	issueDocsHandler := handlers.NewIssueDocsHandler(repoFactory)
+	toolsHandler := handlers.NewToolsHandler(cacheStore, redisClient, cfg)
+	statusHandler := handlers.NewStatusHandler(repoFactory, cacheStore)
+	metaHandler := handlers.NewMetaHandler()
+	domainHandler := handlers.NewDomainHandler(repoFactory, cacheStore)
+	dashboardHandler := handlers.NewDashboardHandler(repoFactory, cacheStore, quota)
+	presetHandler := handlers.NewPresetHandler(repoFactory)
+	eventStreamHandler := handlers.NewEventStreamHandler(repoFactory)
+	customRuleHandler := handlers.NewCustomRuleHandler(repoFactory)
+	siteConfigHandler := handlers.NewSiteConfigHandler(repoFactory, quota)
+	keywordHandler := handlers.NewKeywordHandler(repoFactory, hub, cfg)
+	widgetHandler := handlers.NewWidgetHandler(repoFactory, cacheStore)
+	maintenanceHandler := handlers.NewMaintenanceHandler(analysisHandler.Maintenance, hub)
+	reportHandler := handlers.NewReportHandler(repoFactory, cfg)
+	signatureHandler := handlers.NewSignatureHandler(cfg)
+	analysisHandler.Signer = signatureHandler.Signer
+	reportHandler.Signer = signatureHandler.Signer
+
+	// Side effects queued with state changes are delivered by the outbox
+	dispatcher := outbox.NewDispatcher(repoFactory.OutboxRepository, outbox.Options{
+		PollInterval: cfg.OutboxPollInterval,
+		MaxAttempts:  cfg.OutboxMaxAttempts,
+		RetryDelay:   cfg.OutboxRetryDelay,
+		Retention:    time.Duration(cfg.OutboxRetentionDays) * 24 * time.Hour,
+	})
+	analysisHandler.RegisterOutboxHandlers(dispatcher)
+	reportHandler.RegisterOutboxHandlers(dispatcher)
+	keywordHandler.Outbox = dispatcher
+	outboxHandler := handlers.NewOutboxHandler(repoFactory, dispatcher)
+	go dispatcher.Run(context.Background())
+	go keywordHandler.RunRankTracking(context.Background())
+	go reportHandler.RunReportSchedules(context.Background())
 
 	// Serve static files
 	app.Static("/static", "./static")
@@ -61,6 +156,23 @@ func SetupRoutes(app *fiber.App, db *database.DatabaseClient, redisClient *datab
 		})
 	})
 
+	// Public status page feed
+	api.Get("/status", statusHandler.GetStatusFeed)
+
+	// Public changelog of analyzer behavior
+	api.Get("/meta/changelog", metaHandler.GetChangelog)
+
+	// Public JSON Schemas of response types
+	api.Get("/meta/schemas", metaHandler.ListSchemas)
+	api.Get("/meta/schemas/:name", metaHandler.GetSchema)
+
+	// Public keys and verification of signed reports and scan results
+	api.Get("/meta/signing-keys", signatureHandler.GetSigningKeys)
+	api.Post("/signatures/verify", signatureHandler.VerifySignature)
+
+	// Public maintenance mode, shown as a banner by clients
+	api.Get("/maintenance", maintenanceHandler.GetMaintenance)
+
 	// Auth routes
 	auth := api.Group("/auth")
 	auth.Post("/register", authHandler.Register)
@@ -77,53 +189,213 @@ func SetupRoutes(app *fiber.App, db *database.DatabaseClient, redisClient *datab
 	users.Delete("/:id", middleware.Self("id"), userHandler.DeleteUser)
 	users.Patch("/:id/role", middleware.AdminOnly(), userHandler.UpdateRole)
 
+	// Summary widgets are embedded in CMS dashboards on other origins. They
+	// are registered before the websites group so that CORS preflight
+	// requests are answered before authentication.
+	api.Options("/websites/:id/summary-widget", widgetHandler.CORS)
+	api.Get("/websites/:id/summary-widget", widgetHandler.CORS, middleware.JWTMiddleware(cfg), widgetHandler.GetSummaryWidget)
+	widgetOrigins := api.Group("/widget-origins", middleware.JWTMiddleware(cfg))
+	widgetOrigins.Get("/", widgetHandler.ListWidgetOrigins)
+	widgetOrigins.Post("/", widgetHandler.RegisterWidgetOrigin)
+	widgetOrigins.Delete("/:id", widgetHandler.DeleteWidgetOrigin)
+
 	// internal/api/routes.go
 	websites := api.Group("/websites", middleware.JWTMiddleware(cfg))
 	websites.Post("/", middleware.AnalystOrAdmin(), websiteHandler.CreateWebsite)
 	websites.Get("/", middleware.AnalystOrAdmin(), websiteHandler.ListWebsites)
 	websites.Get("/popular", middleware.AnalystOrAdmin(), websiteHandler.GetPopularWebsites)
 	websites.Get("/:id", middleware.AnalystOrAdmin(), websiteHandler.GetWebsite)
+	websites.Get("/:id/technologies/history", middleware.AnalystOrAdmin(), technologyHandler.GetWebsiteTechnologyHistory)
 	websites.Delete("/:id", middleware.AnalystOrAdmin(), websiteHandler.DeleteWebsite)
+	websites.Get("/:id/score-history", middleware.AnalystOrAdmin(), analysisHandler.GetWebsiteScoreHistory)
+	websites.Get("/:id/deployments", middleware.AnalystOrAdmin(), analysisHandler.ListDeployments)
+	websites.Post("/:id/deployments", middleware.AnalystOrAdmin(), analysisHandler.CreateDeployment)
+	websites.Post("/:id/deployments/hook", middleware.AnalystOrAdmin(), analysisHandler.CreateDeploymentHook)
+
+	// Domain routes
+	domains := api.Group("/domains", middleware.JWTMiddleware(cfg))
+	domains.Post("/", middleware.AnalystOrAdmin(), domainHandler.CreateDomain)
+	domains.Get("/", middleware.AnalystOrAdmin(), domainHandler.ListDomains)
+	domains.Get("/:id/dashboard", middleware.AnalystOrAdmin(), domainHandler.GetDomainDashboard)
+	domains.Get("/:id/templates", middleware.AnalystOrAdmin(), domainHandler.GetDomainTemplates)
+	domains.Delete("/:id", middleware.AnalystOrAdmin(), domainHandler.DeleteDomain)
+
+	// Analysis preset routes
+	presets := api.Group("/presets", middleware.JWTMiddleware(cfg))
+	presets.Get("/", middleware.AnalystOrAdmin(), presetHandler.ListPresets)
+	presets.Get("/:key", middleware.AnalystOrAdmin(), presetHandler.GetPreset)
+	presets.Put("/:key", middleware.AnalystOrAdmin(), presetHandler.SavePreset)
+	presets.Delete("/:key", middleware.AnalystOrAdmin(), presetHandler.DeletePreset)
+
+	// Rank tracking routes
+	keywords := api.Group("/keywords", middleware.JWTMiddleware(cfg))
+	keywords.Get("/", middleware.AnalystOrAdmin(), keywordHandler.ListKeywords)
+	keywords.Post("/", middleware.AnalystOrAdmin(), keywordHandler.TrackKeyword)
+	keywords.Delete("/:id", middleware.AnalystOrAdmin(), keywordHandler.DeleteKeyword)
+	keywords.Get("/:id/history", middleware.AnalystOrAdmin(), keywordHandler.GetKeywordHistory)
+
+	// Scheduled report routes
+	reports := api.Group("/reports", middleware.JWTMiddleware(cfg))
+	reports.Get("/schedules", middleware.AnalystOrAdmin(), reportHandler.ListReportSchedules)
+	reports.Post("/schedules", middleware.AnalystOrAdmin(), reportHandler.CreateReportSchedule)
+	reports.Put("/schedules/:id", middleware.AnalystOrAdmin(), reportHandler.UpdateReportSchedule)
+	reports.Delete("/schedules/:id", middleware.AnalystOrAdmin(), reportHandler.DeleteReportSchedule)
+	reports.Get("/schedules/:id/preview", middleware.AnalystOrAdmin(), reportHandler.PreviewReport)
+	reports.Post("/schedules/:id/send", middleware.AnalystOrAdmin(), reportHandler.SendReport)
+
+	// Event stream routes
+	streams := api.Group("/streams", middleware.JWTMiddleware(cfg))
+	streams.Get("/", middleware.AnalystOrAdmin(), eventStreamHandler.ListEventStreams)
+	streams.Post("/", middleware.AnalystOrAdmin(), eventStreamHandler.CreateEventStream)
+	streams.Patch("/:id", middleware.AnalystOrAdmin(), eventStreamHandler.UpdateEventStream)
+	streams.Delete("/:id", middleware.AnalystOrAdmin(), eventStreamHandler.DeleteEventStream)
+	streams.Post("/:id/test", middleware.AnalystOrAdmin(), eventStreamHandler.TestEventStream)
+
+	// Declarative configuration routes
+	configRoutes := api.Group("/config", middleware.JWTMiddleware(cfg))
+	configRoutes.Get("/sites", middleware.AnalystOrAdmin(), siteConfigHandler.GetSiteConfig)
+	configRoutes.Put("/sites", middleware.AnalystOrAdmin(), siteConfigHandler.SyncSiteConfig)
+	configRoutes.Get("/sites/schedule", middleware.AnalystOrAdmin(), siteConfigHandler.PreviewSiteSchedules)
 
 	// Analysis routes
 	analysis := api.Group("/analysis")
 	analysis.Post("/", middleware.JWTMiddleware(cfg), middleware.AnalystOrAdmin(), analysisHandler.CreateAnalysis)
+	analysis.Post("/import", middleware.JWTMiddleware(cfg), middleware.AnalystOrAdmin(), analysisHandler.ImportAnalyses)
 
 	// Protected analysis routes with appropriate authorization
 	protectedAnalysis := analysis.Group("/:id", middleware.JWTMiddleware(cfg))
 	protectedAnalysis.Get("/metrics", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisMetrics)
 	protectedAnalysis.Get("/metrics/:category", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisMetricsByCategory)
 	protectedAnalysis.Get("/issues", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisIssues)
+	protectedAnalysis.Get("/issues/:issueID", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisIssue)
+	protectedAnalysis.Post("/issues/:issueID/feedback", middleware.AnalystOrAdmin(), issueFeedbackHandler.SubmitIssueFeedback)
+	protectedAnalysis.Get("/priorities", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisPriorities)
+	protectedAnalysis.Get("/timeline", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisTimeline)
+	protectedAnalysis.Get("/html", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisHTML)
+	protectedAnalysis.Get("/dom", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisDOM)
+	protectedAnalysis.Get("/screenshots", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisScreenshots)
+	protectedAnalysis.Get("/screenshots/:device", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisScreenshot)
+	protectedAnalysis.Get("/content", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisContent)
+	protectedAnalysis.Get("/checklist", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisChecklist)
+	protectedAnalysis.Get("/infrastructure", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisInfrastructure)
+	protectedAnalysis.Get("/subdomains", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisSubdomains)
+	protectedAnalysis.Get("/csp", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisCSP)
+	protectedAnalysis.Get("/og-image", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisOGImage)
+	protectedAnalysis.Get("/recording/har", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisHAR)
+	protectedAnalysis.Get("/recording/screencast", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisScreencast)
+	protectedAnalysis.Get("/presence", middleware.AnalystOrAdmin(), wsHandler.GetAnalysisPresence)
+	protectedAnalysis.Post("/rerun", middleware.AnalystOrAdmin(), analysisHandler.RerunAnalysisCategory)
+	protectedAnalysis.Get("/versions", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisResultVersions)
+	protectedAnalysis.Get("/compare/:otherID", middleware.AnalystOrAdmin(), analysisHandler.CompareAnalyses)
+	protectedAnalysis.Get("/generated-sitemap.xml", middleware.AnalystOrAdmin(), analysisHandler.GetGeneratedSitemap)
+	protectedAnalysis.Get("/backlinks", middleware.AnalystOrAdmin(), analysisHandler.GetAnalysisBacklinks)
+
+	// Anonymous quick scans for the marketing site, claimed after sign-up
+	scan := api.Group("/scan")
+	scan.Post("/", analysisHandler.QuickScan)
+	scan.Get("/:token", analysisHandler.GetQuickScan)
+	scan.Post("/:token/claim", middleware.JWTMiddleware(cfg), middleware.AnalystOrAdmin(), analysisHandler.ClaimQuickScan)
+
+	// Dashboard read model for the main UI
+	api.Get("/dashboard", middleware.JWTMiddleware(cfg), middleware.AnalystOrAdmin(), dashboardHandler.GetDashboard)
+
+	// Usage routes
+	usage := api.Group("/usage", middleware.JWTMiddleware(cfg))
+	usage.Get("/analyses", middleware.AnalystOrAdmin(), usageHandler.GetAnalysesUsage)
+
+	// Standalone SEO tools
+	tools := api.Group("/tools", middleware.JWTMiddleware(cfg))
+	tools.Post("/robots-validate", toolsHandler.ValidateRobots)
+	tools.Post("/robots-generate", middleware.AnalystOrAdmin(), toolsHandler.GenerateRobots)
+	tools.Post("/extract", middleware.AnalystOrAdmin(), toolsHandler.ExtractData)
+	tools.Post("/check-urls", middleware.AnalystOrAdmin(), toolsHandler.CheckURLs)
+
+	// Organization routes. Organizations are user accounts.
+	organizations := api.Group("/organizations", middleware.JWTMiddleware(cfg))
+	organizations.Get("/:id/insights", middleware.AnalystOrAdmin(), insightsHandler.GetOrganizationInsights)
+	organizations.Get("/:id/technologies/outdated", middleware.AnalystOrAdmin(), technologyHandler.GetOrganizationOutdatedTechnologies)
+	organizations.Get("/:id/rules", middleware.AnalystOrAdmin(), customRuleHandler.ListCustomRules)
+	organizations.Post("/:id/rules", middleware.AnalystOrAdmin(), customRuleHandler.CreateCustomRule)
+	organizations.Put("/:id/rules/:ruleID", middleware.AnalystOrAdmin(), customRuleHandler.UpdateCustomRule)
+	organizations.Delete("/:id/rules/:ruleID", middleware.AnalystOrAdmin(), customRuleHandler.DeleteCustomRule)
+
+	// Deployment webhooks are authenticated by their GitHub signature
+	api.Post("/deployments/github/:hookID", analysisHandler.GitHubDeploymentWebhook)
+
+	// Billing routes. The webhook is authenticated by its Stripe signature.
+	api.Post("/billing/webhook", billingHandler.StripeWebhook)
+	billingRoutes := api.Group("/billing", middleware.JWTMiddleware(cfg))
+	billingRoutes.Get("/plans", billingHandler.ListPlans)
+	billingRoutes.Get("/subscription", billingHandler.GetSubscription)
+	billingRoutes.Post("/checkout", billingHandler.CreateCheckout)
+	billingRoutes.Get("/callback", billingHandler.CheckoutCallback)
+
+	// WebSocket route
+	api.Get("/ws", middleware.WebSocketMiddleware(cfg), wsHandler.Upgrade, wsHandler.Connect())
+
+	// Versions saved while reviewers edit an improvement together
+	api.Get("/content-improvements/:id/versions", middleware.JWTMiddleware(cfg), middleware.AnalystOrAdmin(), contentEditHandler.GetImprovementVersions)
+
+	// Admin routes
+	admin := api.Group("/admin", middleware.JWTMiddleware(cfg), middleware.AdminOnly())
+	admin.Get("/websocket/undelivered", wsHandler.GetUndeliveredStats)
+	admin.Get("/websocket/buffers", wsHandler.GetBufferStats)
+	admin.Post("/websocket/cleanup", wsHandler.CleanupUndelivered)
+	admin.Post("/broadcast", maintenanceHandler.Broadcast)
+	admin.Put("/maintenance", maintenanceHandler.SetMaintenance)
+	admin.Get("/analysis/slow", analysisHandler.GetSlowAnalysisDiagnostics)
+	admin.Get("/analysis/queue", analysisHandler.GetQueueStats)
+	admin.Get("/analyzers/dependencies", analysisHandler.GetAnalyzerDependencies)
+	admin.Get("/analyzers/concurrency", analysisHandler.GetAnalyzerConcurrency)
+	admin.Get("/issues/feedback", issueFeedbackHandler.GetIssueFeedbackStats)
+	admin.Get("/issues/severity-overrides", issueFeedbackHandler.ListSeverityOverrides)
+	admin.Put("/issues/severity-overrides", issueFeedbackHandler.SetSeverityOverride)
+	admin.Delete("/issues/severity-overrides/:category/:type", issueFeedbackHandler.DeleteSeverityOverride)
+	admin.Get("/issues/doc-links", issueDocsHandler.ListIssueDocLinks)
+	admin.Put("/issues/doc-links", issueDocsHandler.SetIssueDocLink)
+	admin.Delete("/issues/doc-links/:id", issueDocsHandler.DeleteIssueDocLink)
+	admin.Get("/outbox", outboxHandler.GetOutboxStats)
+	admin.Post("/outbox/:id/requeue", outboxHandler.RequeueOutboxMessage)
 
 	// Setup LLM related routes
-	setupLLMRoutes(api, repoFactory, redisClient, cfg)
+	setupLLMRoutes(api, repoFactory, cacheStore, quota, cfg)
 
 	// Set up Swagger documentation endpoint
 	app.Get("/swagger/*", swagger.HandlerDefault)
 }
 
 // Setup LLM related routes
-func setupLLMRoutes(apiGroup fiber.Router, repoFactory *repository.Factory, redisClient *database.RedisClient, cfg *config.Config) {
-	// Initialize LLM service with the internal redis.Client
+func setupLLMRoutes(apiGroup fiber.Router, repoFactory *repository.Factory, cacheStore cache.Cache, quota *billing.Quota, cfg *config.Config) {
+	// Initialize LLM service
 	llmService := llm.NewService(llm.ServiceOptions{
 		DefaultProvider: "gemini",
-		RedisClient:     redisClient.Client,
+		Cache:           cacheStore,
 		RateLimit:       rate.Limit(5), // 5 requests per second
 		RateBurst:       2,
 		CacheTTL:        24 * time.Hour,
 		MaxRetries:      3,
 		RetryDelay:      time.Second,
 		DefaultTimeout:  2 * time.Minute, // Set a reasonable timeout
+		FallbackChain:   cfg.LLMProviderChain,
+		ProviderTimeout: cfg.LLMProviderTimeout,
 	})
 
 	// Create Gemini provider if config exists
 	geminiProvider, err := providers.NewGeminiProvider(cfg.GeminiAPIKey, "gemini-1.5-flash", nil)
 	if err == nil {
-		llmService.RegisterProvider(geminiProvider)
+		registerLLMProvider(llmService, geminiProvider, cfg)
+	}
+
+	// Create fallback providers if configured
+	if openAIProvider, err := providers.NewOpenAIProvider(cfg.OpenAIAPIKey, "gpt-4o-mini", nil); err == nil {
+		registerLLMProvider(llmService, openAIProvider, cfg)
+	}
+	if localProvider, err := providers.NewLocalProvider(cfg.LocalLLMURL, cfg.LocalLLMModel, nil); err == nil {
+		registerLLMProvider(llmService, localProvider, cfg)
 	}
 
 	// Initialize content improvement handler
-	contentHandler := handlers.NewContentImprovementHandler(llmService, repoFactory, redisClient)
+	contentHandler := handlers.NewContentImprovementHandler(llmService, repoFactory, cacheStore, quota)
 
 	// Set up routes for content improvements
 	contentRoutes := apiGroup.Group("/analysis/:id/content-improvements")
@@ -135,17 +407,57 @@ func setupLLMRoutes(apiGroup fiber.Router, repoFactory *repository.Factory, redi
 	codeSnippetRoutes.Get("/", middleware.JWTMiddleware(cfg), contentHandler.GetCodeSnippets)
 	codeSnippetRoutes.Post("/", middleware.JWTMiddleware(cfg), middleware.AnalystOrAdmin(), contentHandler.GenerateCodeSnippets)
 
+	// Batch content improvements of the weakest pages of a domain
+	apiGroup.Post("/domains/:id/content-improvements", middleware.JWTMiddleware(cfg), middleware.AnalystOrAdmin(), contentHandler.GenerateDomainContentImprovements)
+	apiGroup.Get("/content-batches/:id", middleware.JWTMiddleware(cfg), contentHandler.GetContentBatch)
+	apiGroup.Get("/content-batches/:id/export", middleware.JWTMiddleware(cfg), contentHandler.ExportContentBatch)
+
+	// Content gap analysis against competitor pages
+	contentGapHandler := handlers.NewContentGapHandler(llmService, repoFactory, cacheStore, quota)
+	apiGroup.Post("/analysis/:id/content-gap", middleware.JWTMiddleware(cfg), middleware.AnalystOrAdmin(), contentGapHandler.AnalyzeContentGap)
+
+	// Draft accessibility statements from the accessibility results
+	statementHandler := handlers.NewAccessibilityStatementHandler(llmService, repoFactory, quota)
+	apiGroup.Post("/analysis/:id/accessibility-statement", middleware.JWTMiddleware(cfg), middleware.AnalystOrAdmin(), statementHandler.GenerateAccessibilityStatement)
+
 	// HTML content route
 	apiGroup.Get("/analysis/:id/content-html", middleware.JWTMiddleware(cfg), contentHandler.GetContentHTML)
 
+	// Default generation parameters of an organization
+	apiGroup.Get("/organizations/:id/llm-defaults", middleware.JWTMiddleware(cfg), middleware.AnalystOrAdmin(), contentHandler.GetLLMDefaults)
+	apiGroup.Put("/organizations/:id/llm-defaults", middleware.JWTMiddleware(cfg), middleware.AnalystOrAdmin(), contentHandler.UpdateLLMDefaults)
+
+	// Terminology glossary enforced on generated content
+	apiGroup.Get("/organizations/:id/glossary", middleware.JWTMiddleware(cfg), middleware.AnalystOrAdmin(), contentHandler.GetGlossary)
+	apiGroup.Put("/organizations/:id/glossary", middleware.JWTMiddleware(cfg), middleware.AnalystOrAdmin(), contentHandler.UpdateGlossary)
+
 	// LLM providers info route - useful for the frontend
 	apiGroup.Get("/llm/providers", middleware.JWTMiddleware(cfg), func(c *fiber.Ctx) error {
+		providerNames := llmService.GetAvailableProviders()
+		capabilities := make(map[string]llm.Capabilities, len(providerNames))
+		for _, name := range providerNames {
+			if caps, err := llmService.Capabilities(name); err == nil {
+				capabilities[name] = caps
+			}
+		}
+
 		return c.JSON(fiber.Map{
 			"success": true,
 			"data": fiber.Map{
-				"providers": llmService.GetAvailableProviders(),
-				"default":   cfg.DefaultLLMProvider,
+				"providers":      providerNames,
+				"default":        cfg.DefaultLLMProvider,
+				"capabilities":   capabilities,
+				"fallback_chain": cfg.LLMProviderChain,
 			},
 		})
 	})
 }
+
+// registerLLMProvider registers a provider, wrapped with fault injection when chaos is enabled
+func registerLLMProvider(llmService *llm.Service, provider llm.Provider, cfg *config.Config) {
+	if cfg.ChaosEnabled {
+		llmService.RegisterProvider(llm.NewChaosProvider(provider))
+		return
+	}
+	llmService.RegisterProvider(provider)
+}
@@ -0,0 +1,45 @@
+// Package cache stores short-lived values and broadcasts messages. Handlers
+// and services depend on the Cache interface, so a deployment can run on
+// Redis, keep everything in process, or disable caching altogether.
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrMiss is returned by Get when a key is not cached
+var ErrMiss = errors.New("cache miss")
+
+// Cache is a key-value store of JSON-encoded values with expiration and a
+// publish/subscribe channel
+type Cache interface {
+	// Get decodes the value of a key into dest, or returns ErrMiss
+	Get(key string, dest interface{}) error
+	// Set stores a value for the given time; zero keeps it until deleted
+	Set(key string, value interface{}, ttl time.Duration) error
+	Delete(key string) error
+	// Publish sends a JSON-encoded message to the subscribers of a channel
+	Publish(channel string, message interface{}) error
+	// Subscribe receives the messages published to a channel until the
+	// returned function is called
+	Subscribe(channel string) (<-chan []byte, func())
+	Close() error
+}
+
+// Drivers selectable in the configuration
+const (
+	DriverRedis  = "redis"
+	DriverMemory = "memory"
+	DriverNone   = "none"
+)
+
+// ValidateDriver checks a configured cache driver
+func ValidateDriver(driver string) error {
+	switch driver {
+	case DriverRedis, DriverMemory, DriverNone:
+		return nil
+	}
+	return fmt.Errorf("invalid cache driver %q, expected redis, memory or none", driver)
+}
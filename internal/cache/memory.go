@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+const (
+	// subscriberBuffer is the number of messages a subscriber can lag
+	// behind before further messages are dropped
+	subscriberBuffer = 64
+	// sweepInterval is how often expired entries are removed on write
+	sweepInterval = time.Minute
+)
+
+type memoryEntry struct {
+	data      []byte
+	expiresAt time.Time // zero when the entry does not expire
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// Memory is a cache local to the process. Values are stored encoded, so
+// readers get copies just as with Redis.
+type Memory struct {
+	mu          sync.Mutex
+	entries     map[string]memoryEntry
+	subscribers map[string]map[chan []byte]struct{}
+	lastSweep   time.Time
+}
+
+// NewMemory creates an empty in-process cache
+func NewMemory() *Memory {
+	return &Memory{
+		entries:     make(map[string]memoryEntry),
+		subscribers: make(map[string]map[chan []byte]struct{}),
+		lastSweep:   time.Now(),
+	}
+}
+
+// Get implements Cache
+func (m *Memory) Get(key string, dest interface{}) error {
+	m.mu.Lock()
+	entry, ok := m.entries[key]
+	if ok && entry.expired(time.Now()) {
+		delete(m.entries, key)
+		ok = false
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return ErrMiss
+	}
+	return json.Unmarshal(entry.data, dest)
+}
+
+// Set implements Cache
+func (m *Memory) Set(key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	entry := memoryEntry{data: data}
+	if ttl > 0 {
+		entry.expiresAt = now.Add(ttl)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = entry
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		for k, e := range m.entries {
+			if e.expired(now) {
+				delete(m.entries, k)
+			}
+		}
+		m.lastSweep = now
+	}
+	return nil
+}
+
+// Delete implements Cache
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	delete(m.entries, key)
+	m.mu.Unlock()
+	return nil
+}
+
+// Publish implements Cache
+func (m *Memory) Publish(channel string, message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for subscriber := range m.subscribers[channel] {
+		select {
+		case subscriber <- data:
+		default: // slow subscriber, drop
+		}
+	}
+	return nil
+}
+
+// Subscribe implements Cache
+func (m *Memory) Subscribe(channel string) (<-chan []byte, func()) {
+	messages := make(chan []byte, subscriberBuffer)
+
+	m.mu.Lock()
+	if m.subscribers[channel] == nil {
+		m.subscribers[channel] = make(map[chan []byte]struct{})
+	}
+	m.subscribers[channel][messages] = struct{}{}
+	m.mu.Unlock()
+
+	return messages, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if _, ok := m.subscribers[channel][messages]; !ok {
+			return // already cancelled or closed with the cache
+		}
+		delete(m.subscribers[channel], messages)
+		if len(m.subscribers[channel]) == 0 {
+			delete(m.subscribers, channel)
+		}
+		close(messages)
+	}
+}
+
+// Close implements Cache
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for channel, subscribers := range m.subscribers {
+		for subscriber := range subscribers {
+			close(subscriber)
+		}
+		delete(m.subscribers, channel)
+	}
+	m.entries = make(map[string]memoryEntry)
+	return nil
+}
@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Noop caches nothing: every read misses and messages are discarded. It
+// lets deployments without Redis run the same code paths uncached.
+type Noop struct{}
+
+// NewNoop creates a cache that stores nothing
+func NewNoop() Noop {
+	return Noop{}
+}
+
+// Get implements Cache
+func (Noop) Get(string, interface{}) error { return ErrMiss }
+
+// Set implements Cache
+func (Noop) Set(string, interface{}, time.Duration) error { return nil }
+
+// Delete implements Cache
+func (Noop) Delete(string) error { return nil }
+
+// Publish implements Cache
+func (Noop) Publish(string, interface{}) error { return nil }
+
+// Subscribe implements Cache. The channel receives nothing and is closed
+// when the subscription ends.
+func (Noop) Subscribe(string) (<-chan []byte, func()) {
+	messages := make(chan []byte)
+	var once sync.Once
+	return messages, func() { once.Do(func() { close(messages) }) }
+}
+
+// Close implements Cache
+func (Noop) Close() error { return nil }
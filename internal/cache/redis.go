@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis is a cache shared by all instances of the server
+type Redis struct {
+	client *redis.Client
+	ctx    context.Context
+}
+
+// NewRedis creates a cache on a Redis connection
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client, ctx: context.Background()}
+}
+
+// Get implements Cache
+func (r *Redis) Get(key string, dest interface{}) error {
+	data, err := r.client.Get(r.ctx, key).Bytes()
+	if err == redis.Nil {
+		return ErrMiss
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dest)
+}
+
+// Set implements Cache
+func (r *Redis) Set(key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(r.ctx, key, data, ttl).Err()
+}
+
+// Delete implements Cache
+func (r *Redis) Delete(key string) error {
+	return r.client.Del(r.ctx, key).Err()
+}
+
+// Publish implements Cache
+func (r *Redis) Publish(channel string, message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return r.client.Publish(r.ctx, channel, data).Err()
+}
+
+// Subscribe implements Cache
+func (r *Redis) Subscribe(channel string) (<-chan []byte, func()) {
+	pubsub := r.client.Subscribe(r.ctx, channel)
+	messages := make(chan []byte, subscriberBuffer)
+	go func() {
+		defer close(messages)
+		for msg := range pubsub.Channel() {
+			select {
+			case messages <- []byte(msg.Payload):
+			default: // slow subscriber, drop
+			}
+		}
+	}()
+	return messages, func() { pubsub.Close() }
+}
+
+// Close implements Cache. The connection is owned by the caller.
+func (r *Redis) Close() error {
+	return nil
+}
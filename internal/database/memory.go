@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// memoryServer is an in-process Redis server. It keeps the cache, locks and
+// counters of the self-contained mode, so their users need no second code
+// path.
+type memoryServer struct {
+	*miniredis.Miniredis
+	stop chan struct{}
+}
+
+// InitMemoryRedis starts an in-process cache and connects a client to it.
+// Its data is lost when the server stops.
+func InitMemoryRedis() (*RedisClient, error) {
+	server, err := miniredis.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	memory := &memoryServer{Miniredis: server, stop: make(chan struct{})}
+	go memory.expireKeys(time.Second)
+
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	ctx := context.Background()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		memory.Close()
+		return nil, err
+	}
+
+	return &RedisClient{
+		Client: client,
+		ctx:    ctx,
+		memory: memory,
+	}, nil
+}
+
+// expireKeys advances the server clock, which miniredis does not do on its
+// own, so that keys expire
+func (s *memoryServer) expireKeys(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			s.FastForward(now.Sub(last))
+			last = now
+		}
+	}
+}
+
+// Close stops the server
+func (s *memoryServer) Close() {
+	close(s.stop)
+	s.Miniredis.Close()
+}
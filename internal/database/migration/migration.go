@@ -3,6 +3,7 @@ package migration
 import (
 	"fmt"
 	"log"
+	"sort"
 	"time"
 
 	"gorm.io/gorm"
@@ -100,6 +101,126 @@ func RegisterMigrations() map[string]struct {
 			Up:   AddAlterRecommendationTitleColumn,
 			Down: RollbackAlterRecommendationTitleColumn,
 		},
+		"12_create_analysis_events_table": {
+			Up:   CreateAnalysisEventsTable,
+			Down: DropAnalysisEventsTable,
+		},
+		"13_add_analysis_priority": {
+			Up:   AddAnalysisPriority,
+			Down: RemoveAnalysisPriority,
+		},
+		"14_create_analysis_usages_table": {
+			Up:   CreateAnalysisUsageTable,
+			Down: DropAnalysisUsageTable,
+		},
+		"15_create_subscriptions_table": {
+			Up:   CreateSubscriptionsTable,
+			Down: DropSubscriptionsTable,
+		},
+		"16_create_analysis_snapshots_table": {
+			Up:   CreateAnalysisSnapshotsTable,
+			Down: DropAnalysisSnapshotsTable,
+		},
+		"17_merge_duplicate_websites": {
+			Up:   MergeDuplicateWebsites,
+			Down: RollbackMergeDuplicateWebsites,
+		},
+		"18_create_domains_table": {
+			Up:   CreateDomainsTable,
+			Down: DropDomainsTable,
+		},
+		"19_create_analysis_presets_table": {
+			Up:   CreateAnalysisPresetsTable,
+			Down: DropAnalysisPresetsTable,
+		},
+		"20_create_event_streams_table": {
+			Up:   CreateEventStreamsTable,
+			Down: DropEventStreamsTable,
+		},
+		"21_create_monitored_sites_table": {
+			Up:   CreateMonitoredSitesTable,
+			Down: DropMonitoredSitesTable,
+		},
+		"22_create_analysis_result_versions_table": {
+			Up:   CreateAnalysisResultVersionsTable,
+			Down: DropAnalysisResultVersionsTable,
+		},
+		"23_add_issue_element_columns": {
+			Up:   AddIssueElementColumns,
+			Down: RemoveIssueElementColumns,
+		},
+		"24_add_user_locale": {
+			Up:   AddUserLocale,
+			Down: RemoveUserLocale,
+		},
+		"25_add_issue_type": {
+			Up:   AddIssueType,
+			Down: RemoveIssueType,
+		},
+		"26_create_keyword_tracking_tables": {
+			Up:   CreateKeywordTrackingTables,
+			Down: DropKeywordTrackingTables,
+		},
+		"27_create_backlink_snapshots_table": {
+			Up:   CreateBacklinkSnapshotsTable,
+			Down: DropBacklinkSnapshotsTable,
+		},
+		"28_create_widget_origins_table": {
+			Up:   CreateWidgetOriginsTable,
+			Down: DropWidgetOriginsTable,
+		},
+		"29_create_page_entity_tables": {
+			Up:   CreatePageEntityTables,
+			Down: DropPageEntityTables,
+		},
+		"30_create_issue_feedback_tables": {
+			Up:   CreateIssueFeedbackTables,
+			Down: DropIssueFeedbackTables,
+		},
+		"31_create_report_schedules_table": {
+			Up:   CreateReportSchedulesTable,
+			Down: DropReportSchedulesTable,
+		},
+		"32_add_monitored_site_time_zones": {
+			Up:   AddMonitoredSiteTimeZones,
+			Down: RemoveMonitoredSiteTimeZones,
+		},
+		"33_create_custom_rules_table": {
+			Up:   CreateCustomRulesTable,
+			Down: DropCustomRulesTable,
+		},
+		"34_create_deployment_tables": {
+			Up:   CreateDeploymentTables,
+			Down: DropDeploymentTables,
+		},
+		"35_add_user_llm_defaults": {
+			Up:   AddUserLLMDefaults,
+			Down: RemoveUserLLMDefaults,
+		},
+		"36_create_content_batches_table": {
+			Up:   CreateContentBatchesTable,
+			Down: DropContentBatchesTable,
+		},
+		"37_create_glossary_terms_table": {
+			Up:   CreateGlossaryTermsTable,
+			Down: DropGlossaryTermsTable,
+		},
+		"38_create_content_improvement_versions_table": {
+			Up:   CreateContentImprovementVersionsTable,
+			Down: DropContentImprovementVersionsTable,
+		},
+		"39_create_issue_doc_links_table": {
+			Up:   CreateIssueDocLinksTable,
+			Down: DropIssueDocLinksTable,
+		},
+		"40_create_outbox_messages_table": {
+			Up:   CreateOutboxMessagesTable,
+			Down: DropOutboxMessagesTable,
+		},
+		"41_add_website_sandbox": {
+			Up:   AddWebsiteSandbox,
+			Down: RemoveWebsiteSandbox,
+		},
 	}
 }
 
@@ -118,7 +239,8 @@ func (m *Migrator) Migrate() error {
 	}
 
 	// Run pending migrations in order
-	for name, migration := range m.Migrations {
+	for _, name := range m.names() {
+		migration := m.Migrations[name]
 		if !appliedMap[name] {
 			log.Printf("Running migration: %s", name)
 
@@ -147,6 +269,18 @@ func (m *Migrator) Migrate() error {
 	return nil
 }
 
+// names returns the names of the registered migrations in the order they
+// are applied. Names start with a zero-padded sequence number, so later
+// migrations can rely on the tables and columns of earlier ones.
+func (m *Migrator) names() []string {
+	names := make([]string, 0, len(m.Migrations))
+	for name := range m.Migrations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Rollback rolls back the last batch of migrations
 func (m *Migrator) Rollback() error {
 	// Get migrations from the last batch
@@ -240,7 +374,7 @@ func (m *Migrator) GetStatus() ([]map[string]interface{}, error) {
 
 	// Create status list
 	var status []map[string]interface{}
-	for name := range m.Migrations {
+	for _, name := range m.names() {
 		migration, applied := appliedMap[name]
 
 		// Initialize the status map
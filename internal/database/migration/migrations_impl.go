@@ -3,8 +3,11 @@ package migration
 
 import (
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
 )
 
 // CreateRolesTable creates the roles table
@@ -189,6 +192,918 @@ func DropUserActivityTable(tx *gorm.DB) error {
 	return tx.Exec("DROP TABLE IF EXISTS user_activity CASCADE").Error
 }
 
+// CreateAnalysisEventsTable creates the analysis_events table
+func CreateAnalysisEventsTable(tx *gorm.DB) error {
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS analysis_events (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			analysis_id UUID NOT NULL REFERENCES analysis(id) ON DELETE CASCADE,
+			event_type VARCHAR(50) NOT NULL,
+			analyzer VARCHAR(100),
+			message TEXT,
+			duration_ms BIGINT NOT NULL DEFAULT 0,
+			details JSONB,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error; err != nil {
+		return err
+	}
+
+	return tx.Exec("CREATE INDEX IF NOT EXISTS idx_analysis_events_analysis_created ON analysis_events(analysis_id, created_at)").Error
+}
+
+// DropAnalysisEventsTable drops the analysis_events table
+func DropAnalysisEventsTable(tx *gorm.DB) error {
+	return tx.Exec("DROP TABLE IF EXISTS analysis_events CASCADE").Error
+}
+
+// AddAnalysisPriority adds the scheduling priority column to the analysis table
+func AddAnalysisPriority(tx *gorm.DB) error {
+	if err := tx.Exec("ALTER TABLE analysis ADD COLUMN IF NOT EXISTS priority VARCHAR(20) NOT NULL DEFAULT 'normal'").Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE INDEX IF NOT EXISTS idx_analysis_priority ON analysis(priority)").Error
+}
+
+// RemoveAnalysisPriority drops the scheduling priority column
+func RemoveAnalysisPriority(tx *gorm.DB) error {
+	if err := tx.Exec("DROP INDEX IF EXISTS idx_analysis_priority").Error; err != nil {
+		return err
+	}
+	return tx.Exec("ALTER TABLE analysis DROP COLUMN IF EXISTS priority").Error
+}
+
+// CreateAnalysisUsageTable creates the analysis_usages table
+func CreateAnalysisUsageTable(tx *gorm.DB) error {
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS analysis_usages (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			analysis_id UUID NOT NULL UNIQUE REFERENCES analysis(id) ON DELETE CASCADE,
+			user_id UUID NOT NULL REFERENCES users(id),
+			bytes_fetched BIGINT NOT NULL DEFAULT 0,
+			headless_ms BIGINT NOT NULL DEFAULT 0,
+			lighthouse_calls INTEGER NOT NULL DEFAULT 0,
+			llm_prompt_tokens BIGINT NOT NULL DEFAULT 0,
+			llm_completion_tokens BIGINT NOT NULL DEFAULT 0,
+			cost NUMERIC(12,6) NOT NULL DEFAULT 0,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error; err != nil {
+		return err
+	}
+
+	return tx.Exec("CREATE INDEX IF NOT EXISTS idx_analysis_usages_user_created ON analysis_usages(user_id, created_at)").Error
+}
+
+// DropAnalysisUsageTable drops the analysis_usages table
+func DropAnalysisUsageTable(tx *gorm.DB) error {
+	return tx.Exec("DROP TABLE IF EXISTS analysis_usages CASCADE").Error
+}
+
+// CreateSubscriptionsTable creates the subscriptions table
+func CreateSubscriptionsTable(tx *gorm.DB) error {
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS subscriptions (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+			plan VARCHAR(50) NOT NULL DEFAULT 'free',
+			status VARCHAR(50) NOT NULL DEFAULT 'active',
+			stripe_customer_id VARCHAR(255),
+			stripe_subscription_id VARCHAR(255),
+			current_period_end TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_subscriptions_stripe_customer_id ON subscriptions(stripe_customer_id)").Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE INDEX IF NOT EXISTS idx_subscriptions_stripe_subscription_id ON subscriptions(stripe_subscription_id)").Error
+}
+
+// DropSubscriptionsTable drops the subscriptions table
+func DropSubscriptionsTable(tx *gorm.DB) error {
+	return tx.Exec("DROP TABLE IF EXISTS subscriptions CASCADE").Error
+}
+
+// CreateAnalysisSnapshotsTable creates the analysis_snapshots table
+func CreateAnalysisSnapshotsTable(tx *gorm.DB) error {
+	return tx.Exec(`
+		CREATE TABLE IF NOT EXISTS analysis_snapshots (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			analysis_id UUID NOT NULL REFERENCES analysis(id) ON DELETE CASCADE,
+			kind VARCHAR(20) NOT NULL,
+			content BYTEA NOT NULL,
+			size BIGINT NOT NULL,
+			compressed_size BIGINT NOT NULL,
+			sha256 VARCHAR(64) NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			CONSTRAINT idx_analysis_snapshots_analysis_kind UNIQUE (analysis_id, kind)
+		)
+	`).Error
+}
+
+// DropAnalysisSnapshotsTable drops the analysis_snapshots table
+func DropAnalysisSnapshotsTable(tx *gorm.DB) error {
+	return tx.Exec("DROP TABLE IF EXISTS analysis_snapshots CASCADE").Error
+}
+
+// MergeDuplicateWebsites normalizes website URLs and merges websites that
+// only differ in scheme, host casing, default port, trailing slash or
+// tracking parameters into the oldest record, preferring an https one
+func MergeDuplicateWebsites(tx *gorm.DB) error {
+	type websiteRow struct {
+		ID        string
+		URL       string
+		CreatedAt time.Time
+	}
+
+	var rows []websiteRow
+	if err := tx.Raw("SELECT id, url, created_at FROM websites WHERE deleted_at IS NULL ORDER BY created_at, id").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	type group struct {
+		keeper     websiteRow
+		normalized string
+		duplicates []string
+	}
+	groups := make(map[string]*group)
+	var order []string
+
+	for _, row := range rows {
+		normalized, err := urlnorm.Normalize(row.URL)
+		if err != nil {
+			continue
+		}
+		key := urlnorm.SchemeVariants(normalized)[0]
+
+		g, ok := groups[key]
+		if !ok {
+			groups[key] = &group{keeper: row, normalized: normalized}
+			order = append(order, key)
+			continue
+		}
+
+		// Rows are ordered by age, so only an https URL displaces the keeper
+		if strings.HasPrefix(normalized, "https://") && !strings.HasPrefix(g.normalized, "https://") {
+			g.duplicates = append(g.duplicates, g.keeper.ID)
+			g.keeper = row
+			g.normalized = normalized
+			continue
+		}
+		g.duplicates = append(g.duplicates, row.ID)
+	}
+
+	for _, key := range order {
+		g := groups[key]
+		if len(g.duplicates) > 0 {
+			if err := tx.Exec("UPDATE analysis SET website_id = ? WHERE website_id IN ?", g.keeper.ID, g.duplicates).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("UPDATE websites SET deleted_at = CURRENT_TIMESTAMP WHERE id IN ?", g.duplicates).Error; err != nil {
+				return err
+			}
+		}
+		if g.keeper.URL != g.normalized {
+			if err := tx.Exec("UPDATE websites SET url = ? WHERE id = ?", g.normalized, g.keeper.ID).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// RollbackMergeDuplicateWebsites is a no-op: the original URLs and the
+// analysis assignments are not kept, so a merge cannot be undone
+func RollbackMergeDuplicateWebsites(tx *gorm.DB) error {
+	return nil
+}
+
+// CreateDomainsTable creates the domains table and links websites to it
+func CreateDomainsTable(tx *gorm.DB) error {
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS domains (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			name VARCHAR(255) NOT NULL UNIQUE,
+			include_subdomains BOOLEAN NOT NULL DEFAULT TRUE,
+			created_by UUID NOT NULL REFERENCES users(id),
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Exec("ALTER TABLE websites ADD COLUMN IF NOT EXISTS domain_id UUID REFERENCES domains(id) ON DELETE SET NULL").Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE INDEX IF NOT EXISTS idx_websites_domain_id ON websites(domain_id)").Error
+}
+
+// DropDomainsTable drops the domains table and the website link
+func DropDomainsTable(tx *gorm.DB) error {
+	if err := tx.Exec("DROP INDEX IF EXISTS idx_websites_domain_id").Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("ALTER TABLE websites DROP COLUMN IF EXISTS domain_id").Error; err != nil {
+		return err
+	}
+	return tx.Exec("DROP TABLE IF EXISTS domains CASCADE").Error
+}
+
+// CreateAnalysisPresetsTable creates the analysis_presets table
+func CreateAnalysisPresetsTable(tx *gorm.DB) error {
+	return tx.Exec(`
+		CREATE TABLE IF NOT EXISTS analysis_presets (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			key VARCHAR(50) NOT NULL,
+			name VARCHAR(100) NOT NULL,
+			description TEXT,
+			definition JSONB NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			CONSTRAINT idx_analysis_presets_user_key UNIQUE (user_id, key)
+		)
+	`).Error
+}
+
+// DropAnalysisPresetsTable drops the analysis_presets table
+func DropAnalysisPresetsTable(tx *gorm.DB) error {
+	return tx.Exec("DROP TABLE IF EXISTS analysis_presets CASCADE").Error
+}
+
+// CreateEventStreamsTable creates the event_streams table
+func CreateEventStreamsTable(tx *gorm.DB) error {
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS event_streams (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL,
+			sink VARCHAR(20) NOT NULL,
+			url TEXT NOT NULL,
+			topic VARCHAR(255),
+			secret VARCHAR(64) NOT NULL,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			last_delivered_at TIMESTAMP WITH TIME ZONE,
+			last_error TEXT,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE INDEX IF NOT EXISTS idx_event_streams_user_id ON event_streams(user_id)").Error
+}
+
+// DropEventStreamsTable drops the event_streams table
+func DropEventStreamsTable(tx *gorm.DB) error {
+	return tx.Exec("DROP TABLE IF EXISTS event_streams CASCADE").Error
+}
+
+// CreateMonitoredSitesTable creates the monitored_sites table
+func CreateMonitoredSitesTable(tx *gorm.DB) error {
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS monitored_sites (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			url VARCHAR(2048) NOT NULL,
+			preset VARCHAR(50),
+			schedule VARCHAR(50),
+			budgets JSONB,
+			alert_rules JSONB,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			next_run_at TIMESTAMP WITH TIME ZONE,
+			last_run_at TIMESTAMP WITH TIME ZONE,
+			last_analysis_id UUID REFERENCES analysis(id) ON DELETE SET NULL,
+			last_error TEXT,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			CONSTRAINT idx_monitored_sites_user_url UNIQUE (user_id, url)
+		)
+	`).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_monitored_sites_next_run_at ON monitored_sites(next_run_at) WHERE active").Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE INDEX IF NOT EXISTS idx_monitored_sites_last_analysis_id ON monitored_sites(last_analysis_id)").Error
+}
+
+// DropMonitoredSitesTable drops the monitored_sites table
+func DropMonitoredSitesTable(tx *gorm.DB) error {
+	return tx.Exec("DROP TABLE IF EXISTS monitored_sites CASCADE").Error
+}
+
+// CreateAnalysisResultVersionsTable creates the table of replaced category results
+func CreateAnalysisResultVersionsTable(tx *gorm.DB) error {
+	return tx.Exec(`
+		CREATE TABLE IF NOT EXISTS analysis_result_versions (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			analysis_id UUID NOT NULL REFERENCES analysis(id) ON DELETE CASCADE,
+			category VARCHAR(100) NOT NULL,
+			version INTEGER NOT NULL,
+			metrics JSONB,
+			issues JSONB,
+			recommendations JSONB,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			CONSTRAINT idx_analysis_result_versions_version UNIQUE (analysis_id, category, version)
+		)
+	`).Error
+}
+
+// DropAnalysisResultVersionsTable drops the analysis_result_versions table
+func DropAnalysisResultVersionsTable(tx *gorm.DB) error {
+	return tx.Exec("DROP TABLE IF EXISTS analysis_result_versions CASCADE").Error
+}
+
+// AddIssueElementColumns adds the identifier of the page element an issue is about
+func AddIssueElementColumns(tx *gorm.DB) error {
+	if err := tx.Exec("ALTER TABLE issues ADD COLUMN IF NOT EXISTS element_selector TEXT").Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("ALTER TABLE issues ADD COLUMN IF NOT EXISTS element_hash VARCHAR(64)").Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE INDEX IF NOT EXISTS idx_issues_element_hash ON issues(element_hash)").Error
+}
+
+// RemoveIssueElementColumns drops the element identifier columns of issues
+func RemoveIssueElementColumns(tx *gorm.DB) error {
+	if err := tx.Exec("DROP INDEX IF EXISTS idx_issues_element_hash").Error; err != nil {
+		return err
+	}
+	return tx.Exec("ALTER TABLE issues DROP COLUMN IF EXISTS element_selector, DROP COLUMN IF EXISTS element_hash").Error
+}
+
+// AddUserLocale adds the locale used to format numbers and units for a user
+func AddUserLocale(tx *gorm.DB) error {
+	return tx.Exec("ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(20)").Error
+}
+
+// RemoveUserLocale drops the locale column of users
+func RemoveUserLocale(tx *gorm.DB) error {
+	return tx.Exec("ALTER TABLE users DROP COLUMN IF EXISTS locale").Error
+}
+
+// AddIssueType adds the analyzer issue type to issues
+func AddIssueType(tx *gorm.DB) error {
+	if err := tx.Exec("ALTER TABLE issues ADD COLUMN IF NOT EXISTS type VARCHAR(100)").Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE INDEX IF NOT EXISTS idx_issues_type ON issues(type)").Error
+}
+
+// RemoveIssueType drops the type column of issues
+func RemoveIssueType(tx *gorm.DB) error {
+	if err := tx.Exec("DROP INDEX IF EXISTS idx_issues_type").Error; err != nil {
+		return err
+	}
+	return tx.Exec("ALTER TABLE issues DROP COLUMN IF EXISTS type").Error
+}
+
+// CreateKeywordTrackingTables creates the tables of tracked keywords and
+// their position history
+func CreateKeywordTrackingTables(tx *gorm.DB) error {
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS tracked_keywords (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			keyword VARCHAR(255) NOT NULL,
+			url VARCHAR(2048) NOT NULL,
+			country VARCHAR(10) NOT NULL DEFAULT '',
+			language VARCHAR(10) NOT NULL DEFAULT '',
+			device VARCHAR(20) NOT NULL DEFAULT 'desktop',
+			alert_rules JSONB,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			position INTEGER,
+			previous_position INTEGER,
+			last_checked_at TIMESTAMP WITH TIME ZONE,
+			next_check_at TIMESTAMP WITH TIME ZONE,
+			last_error TEXT,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			CONSTRAINT idx_tracked_keywords_market UNIQUE (user_id, keyword, url, country, language, device)
+		)
+	`).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_tracked_keywords_next_check_at ON tracked_keywords(next_check_at) WHERE active").Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_tracked_keywords_url ON tracked_keywords(url)").Error; err != nil {
+		return err
+	}
+
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS keyword_rankings (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			keyword_id UUID NOT NULL REFERENCES tracked_keywords(id) ON DELETE CASCADE,
+			position INTEGER,
+			matched_url VARCHAR(2048),
+			provider VARCHAR(50) NOT NULL,
+			checked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE INDEX IF NOT EXISTS idx_keyword_rankings_keyword_checked ON keyword_rankings(keyword_id, checked_at)").Error
+}
+
+// DropKeywordTrackingTables drops the keyword tracking tables
+func DropKeywordTrackingTables(tx *gorm.DB) error {
+	if err := tx.Exec("DROP TABLE IF EXISTS keyword_rankings CASCADE").Error; err != nil {
+		return err
+	}
+	return tx.Exec("DROP TABLE IF EXISTS tracked_keywords CASCADE").Error
+}
+
+// CreateBacklinkSnapshotsTable creates the table of backlink profiles fetched per analysis
+func CreateBacklinkSnapshotsTable(tx *gorm.DB) error {
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS backlink_snapshots (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			analysis_id UUID NOT NULL UNIQUE REFERENCES analysis(id) ON DELETE CASCADE,
+			domain VARCHAR(255) NOT NULL,
+			provider VARCHAR(50) NOT NULL,
+			referring_domains BIGINT NOT NULL DEFAULT 0,
+			backlinks BIGINT NOT NULL DEFAULT 0,
+			top_backlinks JSONB,
+			loss JSONB,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE INDEX IF NOT EXISTS idx_backlink_snapshots_domain_created ON backlink_snapshots(domain, created_at)").Error
+}
+
+// DropBacklinkSnapshotsTable drops the backlink_snapshots table
+func DropBacklinkSnapshotsTable(tx *gorm.DB) error {
+	return tx.Exec("DROP TABLE IF EXISTS backlink_snapshots CASCADE").Error
+}
+
+// CreateWidgetOriginsTable creates the table of origins allowed to embed summary widgets
+func CreateWidgetOriginsTable(tx *gorm.DB) error {
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS widget_origins (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			origin VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (user_id, origin)
+		)
+	`).Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE INDEX IF NOT EXISTS idx_widget_origins_origin ON widget_origins(origin)").Error
+}
+
+// DropWidgetOriginsTable drops the widget_origins table
+func DropWidgetOriginsTable(tx *gorm.DB) error {
+	return tx.Exec("DROP TABLE IF EXISTS widget_origins CASCADE").Error
+}
+
+// CreatePageEntityTables creates the tables of links, images and technologies
+// parsed from analyzed pages
+func CreatePageEntityTables(tx *gorm.DB) error {
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS page_links (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			analysis_id UUID NOT NULL REFERENCES analysis(id) ON DELETE CASCADE,
+			website_id UUID NOT NULL REFERENCES websites(id) ON DELETE CASCADE,
+			url VARCHAR(2048) NOT NULL,
+			target_host VARCHAR(255) NOT NULL DEFAULT '',
+			text TEXT,
+			is_internal BOOLEAN NOT NULL DEFAULT FALSE,
+			no_follow BOOLEAN NOT NULL DEFAULT FALSE,
+			status_code INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_page_links_analysis_id ON page_links(analysis_id)").Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_page_links_website_id ON page_links(website_id)").Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_page_links_target_host ON page_links(target_host)").Error; err != nil {
+		return err
+	}
+
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS page_images (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			analysis_id UUID NOT NULL REFERENCES analysis(id) ON DELETE CASCADE,
+			website_id UUID NOT NULL REFERENCES websites(id) ON DELETE CASCADE,
+			url VARCHAR(2048) NOT NULL,
+			alt TEXT,
+			width VARCHAR(20),
+			height VARCHAR(20),
+			file_size BIGINT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_page_images_analysis_id ON page_images(analysis_id)").Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_page_images_website_id ON page_images(website_id)").Error; err != nil {
+		return err
+	}
+
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS page_technologies (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			analysis_id UUID NOT NULL REFERENCES analysis(id) ON DELETE CASCADE,
+			website_id UUID NOT NULL REFERENCES websites(id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL,
+			category VARCHAR(100),
+			version VARCHAR(50),
+			confidence INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (analysis_id, name)
+		)
+	`).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_page_technologies_website_id ON page_technologies(website_id)").Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE INDEX IF NOT EXISTS idx_page_technologies_name_created ON page_technologies(name, created_at)").Error
+}
+
+// DropPageEntityTables drops the page entity tables
+func DropPageEntityTables(tx *gorm.DB) error {
+	for _, table := range []string{"page_technologies", "page_images", "page_links"} {
+		if err := tx.Exec("DROP TABLE IF EXISTS " + table + " CASCADE").Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateIssueFeedbackTables creates the tables of issue ratings and
+// deployment-level severity overrides
+func CreateIssueFeedbackTables(tx *gorm.DB) error {
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS issue_feedbacks (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			issue_id UUID NOT NULL REFERENCES issues(id) ON DELETE CASCADE,
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			analysis_id UUID NOT NULL REFERENCES analysis(id) ON DELETE CASCADE,
+			category VARCHAR(100) NOT NULL,
+			issue_type VARCHAR(100) NOT NULL DEFAULT '',
+			severity VARCHAR(50) NOT NULL,
+			useful BOOLEAN NOT NULL,
+			actionable BOOLEAN NOT NULL,
+			comment TEXT,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (issue_id, user_id)
+		)
+	`).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_issue_feedback_analysis_id ON issue_feedbacks(analysis_id)").Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_issue_feedback_code ON issue_feedbacks(category, issue_type)").Error; err != nil {
+		return err
+	}
+
+	return tx.Exec(`
+		CREATE TABLE IF NOT EXISTS issue_severity_overrides (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			category VARCHAR(100) NOT NULL,
+			issue_type VARCHAR(100) NOT NULL,
+			severity VARCHAR(50) NOT NULL,
+			reason TEXT,
+			updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (category, issue_type)
+		)
+	`).Error
+}
+
+// DropIssueFeedbackTables drops the issue feedback tables
+func DropIssueFeedbackTables(tx *gorm.DB) error {
+	if err := tx.Exec("DROP TABLE IF EXISTS issue_severity_overrides CASCADE").Error; err != nil {
+		return err
+	}
+	return tx.Exec("DROP TABLE IF EXISTS issue_feedbacks CASCADE").Error
+}
+
+// CreateReportSchedulesTable creates the table of scheduled email reports
+func CreateReportSchedulesTable(tx *gorm.DB) error {
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS report_schedules (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name VARCHAR(255) NOT NULL,
+			frequency VARCHAR(20) NOT NULL,
+			site_ids JSONB,
+			recipients JSONB NOT NULL,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			next_run_at TIMESTAMP WITH TIME ZONE,
+			last_run_at TIMESTAMP WITH TIME ZONE,
+			last_error TEXT,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_report_schedules_user_id ON report_schedules(user_id)").Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE INDEX IF NOT EXISTS idx_report_schedules_next_run_at ON report_schedules(next_run_at) WHERE active").Error
+}
+
+// DropReportSchedulesTable drops the report schedules table
+func DropReportSchedulesTable(tx *gorm.DB) error {
+	return tx.Exec("DROP TABLE IF EXISTS report_schedules CASCADE").Error
+}
+
+// AddMonitoredSiteTimeZones adds the time zone, pinned run time and blackout
+// windows of monitored site schedules
+func AddMonitoredSiteTimeZones(tx *gorm.DB) error {
+	return tx.Exec(`
+		ALTER TABLE monitored_sites
+			ADD COLUMN IF NOT EXISTS time_zone VARCHAR(64),
+			ADD COLUMN IF NOT EXISTS run_at VARCHAR(5),
+			ADD COLUMN IF NOT EXISTS blackouts JSONB
+	`).Error
+}
+
+// RemoveMonitoredSiteTimeZones drops the time zone columns of monitored sites
+func RemoveMonitoredSiteTimeZones(tx *gorm.DB) error {
+	return tx.Exec("ALTER TABLE monitored_sites DROP COLUMN IF EXISTS time_zone, DROP COLUMN IF EXISTS run_at, DROP COLUMN IF EXISTS blackouts").Error
+}
+
+// CreateCustomRulesTable creates the table of organization-defined checks
+func CreateCustomRulesTable(tx *gorm.DB) error {
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS custom_rules (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			code VARCHAR(64) NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			kind VARCHAR(30) NOT NULL,
+			selector TEXT,
+			pattern TEXT,
+			severity VARCHAR(20) NOT NULL,
+			message TEXT NOT NULL,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_custom_rules_user_code ON custom_rules(user_id, code)").Error
+}
+
+// DropCustomRulesTable drops the custom rules table
+func DropCustomRulesTable(tx *gorm.DB) error {
+	return tx.Exec("DROP TABLE IF EXISTS custom_rules CASCADE").Error
+}
+
+// CreateDeploymentTables creates the tables of deployment markers and the
+// GitHub webhooks recording them
+func CreateDeploymentTables(tx *gorm.DB) error {
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS deployments (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			website_id UUID NOT NULL REFERENCES websites(id) ON DELETE CASCADE,
+			commit_sha VARCHAR(64) NOT NULL,
+			ref VARCHAR(255),
+			repository VARCHAR(255),
+			environment VARCHAR(100),
+			description TEXT,
+			source VARCHAR(20) NOT NULL,
+			deployed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			baseline_analysis_id UUID REFERENCES analysis(id) ON DELETE SET NULL,
+			analysis_id UUID REFERENCES analysis(id) ON DELETE SET NULL,
+			impact JSONB,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_deployments_user_website_deployed ON deployments(user_id, website_id, deployed_at)").Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_deployments_analysis_id ON deployments(analysis_id)").Error; err != nil {
+		return err
+	}
+
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS deployment_hooks (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			website_id UUID NOT NULL REFERENCES websites(id) ON DELETE CASCADE,
+			secret VARCHAR(64) NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_deployment_hooks_user_website ON deployment_hooks(user_id, website_id)").Error
+}
+
+// DropDeploymentTables drops the deployment tables
+func DropDeploymentTables(tx *gorm.DB) error {
+	if err := tx.Exec("DROP TABLE IF EXISTS deployment_hooks CASCADE").Error; err != nil {
+		return err
+	}
+	return tx.Exec("DROP TABLE IF EXISTS deployments CASCADE").Error
+}
+
+// AddUserLLMDefaults adds the default LLM generation parameters of an organization
+func AddUserLLMDefaults(tx *gorm.DB) error {
+	return tx.Exec("ALTER TABLE users ADD COLUMN IF NOT EXISTS llm_defaults JSONB").Error
+}
+
+// RemoveUserLLMDefaults drops the llm_defaults column of users
+func RemoveUserLLMDefaults(tx *gorm.DB) error {
+	return tx.Exec("ALTER TABLE users DROP COLUMN IF EXISTS llm_defaults").Error
+}
+
+// CreateContentBatchesTable creates the table of batch content improvements
+// for the pages of a domain
+func CreateContentBatchesTable(tx *gorm.DB) error {
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS content_batches (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			domain_id UUID NOT NULL REFERENCES domains(id) ON DELETE CASCADE,
+			status VARCHAR(20) NOT NULL,
+			progress DOUBLE PRECISION NOT NULL DEFAULT 0,
+			options JSONB,
+			pages JSONB,
+			completed_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_content_batches_user_id ON content_batches(user_id)").Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE INDEX IF NOT EXISTS idx_content_batches_domain_id ON content_batches(domain_id)").Error
+}
+
+// DropContentBatchesTable drops the content_batches table
+func DropContentBatchesTable(tx *gorm.DB) error {
+	return tx.Exec("DROP TABLE IF EXISTS content_batches CASCADE").Error
+}
+
+// CreateGlossaryTermsTable creates the table of organization glossary terms
+func CreateGlossaryTermsTable(tx *gorm.DB) error {
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS glossary_terms (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			term VARCHAR(255) NOT NULL,
+			variants JSONB,
+			language VARCHAR(10),
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE INDEX IF NOT EXISTS idx_glossary_terms_user_id ON glossary_terms(user_id)").Error
+}
+
+// DropGlossaryTermsTable drops the glossary_terms table
+func DropGlossaryTermsTable(tx *gorm.DB) error {
+	return tx.Exec("DROP TABLE IF EXISTS glossary_terms CASCADE").Error
+}
+
+// CreateContentImprovementVersionsTable creates the content_improvement_versions table
+func CreateContentImprovementVersionsTable(tx *gorm.DB) error {
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS content_improvement_versions (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			improvement_id UUID NOT NULL REFERENCES content_improvements(id) ON DELETE CASCADE,
+			version INTEGER NOT NULL,
+			content TEXT,
+			source VARCHAR(20) NOT NULL,
+			revision INTEGER NOT NULL DEFAULT 0,
+			editors JSONB,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_content_improvement_versions_version ON content_improvement_versions(improvement_id, version)").Error
+}
+
+// DropContentImprovementVersionsTable drops the content_improvement_versions table
+func DropContentImprovementVersionsTable(tx *gorm.DB) error {
+	return tx.Exec("DROP TABLE IF EXISTS content_improvement_versions CASCADE").Error
+}
+
+// CreateIssueDocLinksTable creates the issue_doc_links table
+func CreateIssueDocLinksTable(tx *gorm.DB) error {
+	return tx.Exec(`
+		CREATE TABLE IF NOT EXISTS issue_doc_links (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			category VARCHAR(100) NOT NULL DEFAULT '',
+			issue_type VARCHAR(100) NOT NULL,
+			platform VARCHAR(100) NOT NULL DEFAULT '',
+			title VARCHAR(255) NOT NULL,
+			url TEXT NOT NULL,
+			updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (category, issue_type, platform)
+		)
+	`).Error
+}
+
+// DropIssueDocLinksTable drops the issue_doc_links table
+func DropIssueDocLinksTable(tx *gorm.DB) error {
+	return tx.Exec("DROP TABLE IF EXISTS issue_doc_links CASCADE").Error
+}
+
+// CreateOutboxMessagesTable creates the outbox_messages table of side effects
+// waiting for delivery
+func CreateOutboxMessagesTable(tx *gorm.DB) error {
+	if err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS outbox_messages (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			kind VARCHAR(50) NOT NULL,
+			key VARCHAR(255) NOT NULL UNIQUE,
+			payload JSONB NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			locked_until TIMESTAMP WITH TIME ZONE,
+			last_error TEXT,
+			delivered_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_outbox_messages_due ON outbox_messages(status, next_attempt_at)").Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE INDEX IF NOT EXISTS idx_outbox_messages_created_at ON outbox_messages(created_at)").Error
+}
+
+// DropOutboxMessagesTable drops the outbox_messages table
+func DropOutboxMessagesTable(tx *gorm.DB) error {
+	return tx.Exec("DROP TABLE IF EXISTS outbox_messages CASCADE").Error
+}
+
+// AddWebsiteSandbox marks the websites of sandbox analyses and moves the
+// sandbox analyses stored on real websites to websites of their own
+func AddWebsiteSandbox(tx *gorm.DB) error {
+	if err := tx.Exec("ALTER TABLE websites ADD COLUMN IF NOT EXISTS sandbox BOOLEAN NOT NULL DEFAULT FALSE").Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_websites_sandbox ON websites(sandbox)").Error; err != nil {
+		return err
+	}
+	return tx.Exec(`
+		WITH moved AS MATERIALIZED (
+			SELECT a.id AS analysis_id, gen_random_uuid() AS website_id, w.url
+			FROM analysis a
+			JOIN websites w ON w.id = a.website_id
+			WHERE a.metadata->>'sandbox' = 'true' AND NOT w.sandbox
+		), created AS (
+			INSERT INTO websites (id, url, sandbox)
+			SELECT website_id, url, TRUE FROM moved
+		)
+		UPDATE analysis a SET website_id = moved.website_id
+		FROM moved
+		WHERE a.id = moved.analysis_id
+	`).Error
+}
+
+// RemoveWebsiteSandbox drops the sandbox flag of websites. Sandbox analyses
+// keep their own websites.
+func RemoveWebsiteSandbox(tx *gorm.DB) error {
+	return tx.Exec("ALTER TABLE websites DROP COLUMN IF EXISTS sandbox").Error
+}
+
 // AddIndexes adds indexes to improve query performance
 func AddIndexes(tx *gorm.DB) error {
 	// Users indexes
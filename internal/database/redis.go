@@ -6,12 +6,15 @@ import (
 	"time"
 
 	"github.com/go-redis/redis/v8"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/chaos"
 )
 
 // RedisClient wraps the Redis client
 type RedisClient struct {
 	Client *redis.Client // Changed from client to Client (capitalized for public access)
 	ctx    context.Context
+	memory *memoryServer // in-process server of the memory cache driver
 }
 
 // InitRedis initializes the Redis connection
@@ -35,9 +38,22 @@ func InitRedis(redisURI string) (*RedisClient, error) {
 	}, nil
 }
 
+// Conn returns the Redis connection, or nil when the server runs without
+// Redis
+func (r *RedisClient) Conn() *redis.Client {
+	if r == nil {
+		return nil
+	}
+	return r.Client
+}
+
 // Close closes the Redis connection
 func (r *RedisClient) Close() error {
-	return r.Client.Close() // Changed from client to Client
+	err := r.Client.Close() // Changed from client to Client
+	if r.memory != nil {
+		r.memory.Close()
+	}
+	return err
 }
 
 // Set stores a key-value pair in Redis with expiration
@@ -90,3 +106,71 @@ func (r *RedisClient) GetCached(key string, dest interface{}, ttl time.Duration,
 
 	return json.Unmarshal(dataBytes, dest)
 }
+
+// releaseLockScript deletes a lock only if it is still held by the given owner
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// AcquireLock tries to take a lock for the given owner. If the lock is already
+// held, it returns false together with the current owner.
+func (r *RedisClient) AcquireLock(key, owner string, ttl time.Duration) (bool, string, error) {
+	acquired, err := r.Client.SetNX(r.ctx, key, owner, ttl).Result()
+	if err != nil {
+		return false, "", err
+	}
+	if acquired {
+		return true, owner, nil
+	}
+
+	current, err := r.Client.Get(r.ctx, key).Result()
+	if err == redis.Nil {
+		// The lock expired between the two calls, try once more
+		acquired, err = r.Client.SetNX(r.ctx, key, owner, ttl).Result()
+		if err != nil {
+			return false, "", err
+		}
+		if acquired {
+			return true, owner, nil
+		}
+		current, err = r.Client.Get(r.ctx, key).Result()
+	}
+	if err != nil {
+		return false, "", err
+	}
+
+	return false, current, nil
+}
+
+// ReleaseLock releases a lock if it is still held by the given owner
+func (r *RedisClient) ReleaseLock(key, owner string) error {
+	return releaseLockScript.Run(r.ctx, r.Client, []string{key}, owner).Err()
+}
+
+// EnableFaultInjection makes Redis commands subject to the "redis" chaos
+// fault, simulating an unavailable or slow Redis
+func (r *RedisClient) EnableFaultInjection() {
+	r.Client.AddHook(chaosHook{})
+}
+
+// chaosHook fails or delays Redis commands according to the chaos faults
+type chaosHook struct{}
+
+func (chaosHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return ctx, chaos.Inject(ctx, chaos.TargetRedis)
+}
+
+func (chaosHook) AfterProcess(context.Context, redis.Cmder) error {
+	return nil
+}
+
+func (chaosHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, chaos.Inject(ctx, chaos.TargetRedis)
+}
+
+func (chaosHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	sqlitedriver "github.com/glebarez/go-sqlite"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/database/seed"
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+)
+
+// sqliteModels are the tables of the self-contained mode. The Postgres
+// migrations are written in Postgres SQL, so the SQLite schema is derived
+// from the models instead.
+var sqliteModels = []interface{}{
+	&models.Role{}, &models.User{}, &models.Domain{}, &models.Website{},
+	&models.Analysis{}, &models.AnalysisMetric{}, &models.Issue{}, &models.IssueFeedback{},
+	&models.IssueSeverityOverride{}, &models.IssueDocLink{}, &models.Recommendation{}, &models.ContentImprovement{}, &models.ContentImprovementVersion{},
+	&models.AnalysisResultVersion{}, &models.AnalysisEvent{}, &models.AnalysisUsage{},
+	&models.Subscription{}, &models.AnalysisSnapshot{}, &models.AnalysisPreset{},
+	&models.EventStream{}, &models.MonitoredSite{}, &models.BacklinkSnapshot{},
+	&models.WidgetOrigin{}, &models.PageLink{}, &models.PageImage{}, &models.PageTechnology{},
+	&models.TrackedKeyword{}, &models.KeywordRanking{}, &models.ReportSchedule{},
+	&models.CustomRule{}, &models.Deployment{}, &models.DeploymentHook{},
+	&models.ContentBatch{}, &models.GlossaryTerm{}, &models.OutboxMessage{}, &models.UserActivity{},
+}
+
+var registerSQLiteFunctions sync.Once
+
+// InitSQLite opens an embedded SQLite database file, creating it and its
+// schema when needed, and seeds the default data. It replaces Postgres in the
+// self-contained mode.
+func InitSQLite(path string) (*DatabaseClient, error) {
+	client, err := OpenSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Seeding default data...")
+	if err := seed.SeedDefaultRoles(client.DB); err != nil {
+		log.Printf("Seed warning: %v", err)
+	}
+	if err := seed.SeedDefaultUsers(client.DB); err != nil {
+		log.Printf("Seed warning: %v", err)
+	}
+
+	log.Println("Database setup completed")
+	return client, nil
+}
+
+// OpenSQLite opens an embedded SQLite database file, creating it and its
+// schema when needed, without seeding it
+func OpenSQLite(path string) (*DatabaseClient, error) {
+	// Model defaults call gen_random_uuid(), which SQLite lacks
+	registerSQLiteFunctions.Do(func() {
+		sqlitedriver.MustRegisterScalarFunction("gen_random_uuid", 0,
+			func(ctx *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+				return uuid.New().String(), nil
+			})
+	})
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	gormConfig := &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Warn),
+		NowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+
+	dsn := path + "?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqliteDialector{Dialector: sqlite.Open(dsn).(*sqlite.Dialector)}, gormConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Creating SQLite schema...")
+	if err := db.AutoMigrate(sqliteModels...); err != nil {
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+	// Raw queries shared with Postgres read the analyses as "analysis"
+	if err := db.Exec("CREATE VIEW IF NOT EXISTS analysis AS SELECT * FROM analyses").Error; err != nil {
+		return nil, fmt.Errorf("failed to create analysis view: %w", err)
+	}
+
+	return &DatabaseClient{DB: db}, nil
+}
+
+// sqliteDialector adapts the column definitions of the models to SQLite
+type sqliteDialector struct {
+	*sqlite.Dialector
+}
+
+func (d sqliteDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return sqliteMigrator{Migrator: d.Dialector.Migrator(db).(sqlite.Migrator)}
+}
+
+type sqliteMigrator struct {
+	sqlite.Migrator
+}
+
+// FullDataTypeOf wraps function defaults in parentheses, which SQLite
+// requires for expressions
+func (m sqliteMigrator) FullDataTypeOf(field *schema.Field) clause.Expr {
+	expr := m.Migrator.FullDataTypeOf(field)
+	expr.SQL = strings.Replace(expr.SQL, "DEFAULT gen_random_uuid()", "DEFAULT (gen_random_uuid())", 1)
+	return expr
+}
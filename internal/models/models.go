@@ -26,6 +26,8 @@ type User struct {
 	PasswordHash string         `gorm:"type:varchar(255);not null"`
 	RoleID       uint           `gorm:"not null;index"`
 	Role         Role           `gorm:"foreignKey:RoleID"`
+	Locale       string         `gorm:"type:varchar(20)"`
+	LLMDefaults  datatypes.JSON `gorm:"type:jsonb"` // default model, temperature and max tokens of LLM generations
 	CreatedAt    time.Time      `gorm:"autoCreateTime;index"`
 	UpdatedAt    time.Time      `gorm:"autoUpdateTime"`
 	DeletedAt    gorm.DeletedAt `gorm:"index"`
@@ -40,6 +42,8 @@ type Website struct {
 	URL         string         `gorm:"type:varchar(2048);not null;index"`
 	Title       string         `gorm:"type:varchar(255);index"`
 	Description string         `gorm:"type:text"`
+	DomainID    *uuid.UUID     `gorm:"type:uuid;index"`
+	Sandbox     bool           `gorm:"not null;default:false;index"` // holds sandbox analyses apart from the real site
 	CreatedAt   time.Time      `gorm:"autoCreateTime;index"`
 	UpdatedAt   time.Time      `gorm:"autoUpdateTime"`
 	DeletedAt   gorm.DeletedAt `gorm:"index"`
@@ -47,6 +51,19 @@ type Website struct {
 	Analyses []Analysis `gorm:"foreignKey:WebsiteID"`
 }
 
+// Domain groups the websites of a property, e.g. every page of example.com
+// and optionally its subdomains
+type Domain struct {
+	ID                uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Name              string    `gorm:"type:varchar(255);not null;uniqueIndex" json:"name"`
+	IncludeSubdomains bool      `gorm:"not null;default:true" json:"include_subdomains"`
+	CreatedBy         uuid.UUID `gorm:"type:uuid;not null;index" json:"created_by"`
+	CreatedAt         time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	// Relationships
+	Websites []Website `gorm:"foreignKey:DomainID" json:"-"`
+}
+
 // Analysis represents a website analysis
 type Analysis struct {
 	ID          uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
@@ -55,6 +72,7 @@ type Analysis struct {
 	UserID      uuid.UUID      `gorm:"type:uuid;not null;index"`
 	User        User           `gorm:"foreignKey:UserID"`
 	Status      string         `gorm:"type:varchar(50);not null;default:'pending';index"`
+	Priority    string         `gorm:"type:varchar(20);not null;default:'normal';index"` // low, normal, high
 	StartedAt   time.Time      `gorm:"default:null;index"`
 	CompletedAt time.Time      `gorm:"default:null;index"`
 	IsPublic    bool           `gorm:"default:false;index"`
@@ -86,10 +104,60 @@ type Issue struct {
 	Analysis    Analysis  `gorm:"foreignKey:AnalysisID;references:ID" json:"analysis"`
 	Category    string    `gorm:"type:varchar(100);not null;index" json:"category"`
 	Severity    string    `gorm:"type:varchar(50);not null;index" json:"severity"` // high, medium, low
+	Type        string    `gorm:"type:varchar(100);index" json:"type,omitempty"`   // issue type reported by the analyzer
 	Title       string    `gorm:"type:varchar(255);not null" json:"title"`
 	Description string    `gorm:"type:text" json:"description"`
 	Location    string    `gorm:"type:text" json:"location"`
-	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	// ElementSelector and ElementHash identify the page element the issue is about
+	ElementSelector string    `gorm:"type:text" json:"element_selector,omitempty"`
+	ElementHash     string    `gorm:"type:varchar(64);index" json:"element_hash,omitempty"`
+	CreatedAt       time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// IssueFeedback is a user's rating of a reported issue
+type IssueFeedback struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	IssueID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_issue_feedback_issue_user" json:"issue_id"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_issue_feedback_issue_user" json:"user_id"`
+	AnalysisID uuid.UUID `gorm:"type:uuid;not null;index" json:"analysis_id"`
+	// Category, IssueType and Severity are copied from the issue so feedback
+	// can be aggregated per issue code without joining issues
+	Category   string    `gorm:"type:varchar(100);not null;index:idx_issue_feedback_code" json:"category"`
+	IssueType  string    `gorm:"type:varchar(100);not null;default:'';index:idx_issue_feedback_code" json:"issue_type"`
+	Severity   string    `gorm:"type:varchar(50);not null" json:"severity"` // as reported, after overrides
+	Useful     bool      `gorm:"not null" json:"useful"`
+	Actionable bool      `gorm:"not null" json:"actionable"`
+	Comment    string    `gorm:"type:text" json:"comment,omitempty"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// IssueSeverityOverride replaces the severity analyzers report for an issue
+// code across the deployment
+type IssueSeverityOverride struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Category  string     `gorm:"type:varchar(100);not null;uniqueIndex:idx_issue_severity_overrides_code" json:"category"`
+	IssueType string     `gorm:"type:varchar(100);not null;uniqueIndex:idx_issue_severity_overrides_code" json:"issue_type"`
+	Severity  string     `gorm:"type:varchar(50);not null" json:"severity"` // high, medium, low
+	Reason    string     `gorm:"type:text" json:"reason,omitempty"`
+	UpdatedBy *uuid.UUID `gorm:"type:uuid" json:"updated_by,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// IssueDocLink points the issues of a type to documentation on fixing them,
+// optionally specific to a category and a detected platform. Links maintained
+// by administrators add to or replace the built-in ones.
+type IssueDocLink struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Category  string     `gorm:"type:varchar(100);not null;default:'';uniqueIndex:idx_issue_doc_links_code" json:"category"` // empty for all categories
+	IssueType string     `gorm:"type:varchar(100);not null;uniqueIndex:idx_issue_doc_links_code" json:"issue_type"`
+	Platform  string     `gorm:"type:varchar(100);not null;default:'';uniqueIndex:idx_issue_doc_links_code" json:"platform"` // detected technology name, empty for any platform
+	Title     string     `gorm:"type:varchar(255);not null" json:"title"`
+	URL       string     `gorm:"type:text;not null" json:"url"`
+	UpdatedBy *uuid.UUID `gorm:"type:uuid" json:"updated_by,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
 }
 
 type Recommendation struct {
@@ -115,6 +183,393 @@ type ContentImprovement struct {
 	CreatedAt       time.Time `gorm:"autoCreateTime" json:"created_at"`
 }
 
+// ContentImprovementVersion is a saved state of the improved content while
+// reviewers edit it together. Version 1 is the generated content.
+type ContentImprovementVersion struct {
+	ID            uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ImprovementID uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_content_improvement_versions_version" json:"improvement_id"`
+	Version       int            `gorm:"not null;uniqueIndex:idx_content_improvement_versions_version" json:"version"`
+	Content       string         `gorm:"type:text" json:"content"`
+	Source        string         `gorm:"type:varchar(20);not null" json:"source"` // generated, edited
+	Revision      int            `gorm:"not null;default:0" json:"revision"`      // edit revision the version was saved at
+	Editors       datatypes.JSON `gorm:"type:jsonb" json:"editors,omitempty"`     // IDs of the users who edited since the previous version
+	CreatedAt     time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// AnalysisResultVersion keeps the results of one category of an analysis
+// that were replaced when the category was analyzed again
+type AnalysisResultVersion struct {
+	ID              uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	AnalysisID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"analysis_id"`
+	Category        string         `gorm:"type:varchar(100);not null" json:"category"`
+	Version         int            `gorm:"not null" json:"version"`
+	Metrics         datatypes.JSON `gorm:"type:jsonb" json:"metrics"`
+	Issues          datatypes.JSON `gorm:"type:jsonb" json:"issues"`
+	Recommendations datatypes.JSON `gorm:"type:jsonb" json:"recommendations"`
+	CreatedAt       time.Time      `gorm:"autoCreateTime" json:"replaced_at"`
+}
+
+// Analysis lifecycle event types
+const (
+	AnalysisEventQueued            = "queued"
+	AnalysisEventAttached          = "attached"
+	AnalysisEventPaused            = "paused"
+	AnalysisEventResumed           = "resumed"
+	AnalysisEventStarted           = "started"
+	AnalysisEventParseStarted      = "parse_started"
+	AnalysisEventParseCompleted    = "parse_completed"
+	AnalysisEventAnalyzerCompleted = "analyzer_completed"
+	AnalysisEventAnalyzerFailed    = "analyzer_failed"
+	AnalysisEventReportGenerated   = "report_generated"
+	AnalysisEventBudgetExceeded    = "budget_exceeded"
+	AnalysisEventAlertTriggered    = "alert_triggered"
+	AnalysisEventBacklinkLoss      = "backlink_loss"
+	AnalysisEventCategoryRerun     = "category_rerun"
+	AnalysisEventCompleted         = "completed"
+	AnalysisEventCancelled         = "cancelled"
+	AnalysisEventBlocked           = "blocked"
+	AnalysisEventError             = "error"
+)
+
+// AnalysisEvent records a step in the lifecycle of an analysis
+type AnalysisEvent struct {
+	ID         uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	AnalysisID uuid.UUID      `gorm:"type:uuid;not null;index" json:"analysis_id"`
+	EventType  string         `gorm:"type:varchar(50);not null;index" json:"event_type"`
+	Analyzer   string         `gorm:"type:varchar(100)" json:"analyzer,omitempty"`
+	Message    string         `gorm:"type:text" json:"message"`
+	DurationMs int64          `gorm:"default:0" json:"duration_ms"`
+	Details    datatypes.JSON `gorm:"type:jsonb" json:"details,omitempty"`
+	CreatedAt  time.Time      `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+// AnalysisUsage records the resources consumed by an analysis for billing and showback
+type AnalysisUsage struct {
+	ID                  uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	AnalysisID          uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"analysis_id"`
+	UserID              uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	BytesFetched        int64     `gorm:"not null;default:0" json:"bytes_fetched"`
+	HeadlessMs          int64     `gorm:"not null;default:0" json:"headless_ms"`
+	LighthouseCalls     int       `gorm:"not null;default:0" json:"lighthouse_calls"`
+	LLMPromptTokens     int64     `gorm:"not null;default:0" json:"llm_prompt_tokens"`
+	LLMCompletionTokens int64     `gorm:"not null;default:0" json:"llm_completion_tokens"`
+	Cost                float64   `gorm:"type:numeric(12,6);not null;default:0" json:"cost"`
+	CreatedAt           time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+	UpdatedAt           time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// Subscription holds the billing plan of a user and its Stripe state
+type Subscription struct {
+	ID                   uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID               uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"user_id"`
+	Plan                 string    `gorm:"type:varchar(50);not null;default:'free'" json:"plan"`     // free, pro, agency
+	Status               string    `gorm:"type:varchar(50);not null;default:'active'" json:"status"` // Stripe subscription status
+	StripeCustomerID     string    `gorm:"type:varchar(255);index" json:"stripe_customer_id,omitempty"`
+	StripeSubscriptionID string    `gorm:"type:varchar(255);index" json:"stripe_subscription_id,omitempty"`
+	CurrentPeriodEnd     time.Time `gorm:"default:null" json:"current_period_end,omitempty"`
+	CreatedAt            time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt            time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// Snapshot kinds
+const (
+	SnapshotKindHTML = "html" // raw fetched HTML
+	SnapshotKindDOM  = "dom"  // rendered DOM from the headless browser
+	// SnapshotKindSitemap is a sitemap.xml proposed from a crawl of a site without one
+	SnapshotKindSitemap = "sitemap"
+	// SnapshotKindScreenshotPrefix is followed by the device name; the content is a JPEG image
+	SnapshotKindScreenshotPrefix = "screenshot_"
+	// SnapshotKindOGImage is a PNG preview image generated for a page without og:image
+	SnapshotKindOGImage = "og_image"
+	// SnapshotKindHAR is the HTTP Archive of a recorded page load
+	SnapshotKindHAR = "har"
+	// SnapshotKindScreencast is a ZIP archive of the screencast frames of a recorded page load
+	SnapshotKindScreencast = "screencast"
+)
+
+// AnalysisSnapshot stores gzip-compressed markup and screenshots captured during an analysis
+type AnalysisSnapshot struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	AnalysisID     uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_analysis_snapshots_analysis_kind" json:"analysis_id"`
+	Kind           string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_analysis_snapshots_analysis_kind" json:"kind"`
+	Content        []byte    `gorm:"type:bytea;not null" json:"-"`
+	Size           int64     `gorm:"not null" json:"size"`
+	CompressedSize int64     `gorm:"not null" json:"compressed_size"`
+	SHA256         string    `gorm:"type:varchar(64);not null" json:"sha256"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// AnalysisPreset is a user's own analysis preset or their customization of
+// a built-in preset with the same key
+type AnalysisPreset struct {
+	ID          uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID      uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_analysis_presets_user_key" json:"user_id"`
+	Key         string         `gorm:"type:varchar(50);not null;uniqueIndex:idx_analysis_presets_user_key" json:"key"`
+	Name        string         `gorm:"type:varchar(100);not null" json:"name"`
+	Description string         `gorm:"type:text" json:"description"`
+	Definition  datatypes.JSON `gorm:"type:jsonb;not null" json:"definition"` // analyzers, parse options and budgets
+	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// Event stream sinks
+const (
+	EventStreamSinkHTTP  = "http"  // NDJSON POST to a customer endpoint
+	EventStreamSinkKafka = "kafka" // Kafka topic through a Kafka REST proxy
+)
+
+// EventStream forwards every persisted metric and issue of a user's analyses
+// to an external sink
+type EventStream struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID          uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name            string     `gorm:"type:varchar(100);not null" json:"name"`
+	Sink            string     `gorm:"type:varchar(20);not null" json:"sink"` // http, kafka
+	URL             string     `gorm:"type:text;not null" json:"url"`
+	Topic           string     `gorm:"type:varchar(255)" json:"topic,omitempty"` // kafka only
+	Secret          string     `gorm:"type:varchar(64);not null" json:"-"`       // HMAC key for payload signatures
+	Active          bool       `gorm:"not null;default:true" json:"active"`
+	LastDeliveredAt *time.Time `json:"last_delivered_at,omitempty"`
+	LastError       string     `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// MonitoredSite is a page a user keeps under observation: it is analyzed on a
+// schedule, held to budgets and raises alerts. Monitored sites are managed
+// declaratively as one desired-state document per user.
+type MonitoredSite struct {
+	ID             uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID         uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_monitored_sites_user_url" json:"user_id"`
+	URL            string         `gorm:"type:varchar(2048);not null;uniqueIndex:idx_monitored_sites_user_url" json:"url"` // normalized
+	Preset         string         `gorm:"type:varchar(50)" json:"preset,omitempty"`
+	Schedule       string         `gorm:"type:varchar(50)" json:"schedule,omitempty"`  // hourly, daily, weekly or a duration such as 6h; empty for manual runs
+	TimeZone       string         `gorm:"type:varchar(64)" json:"time_zone,omitempty"` // IANA time zone of run_at and blackouts; UTC when empty
+	RunAt          string         `gorm:"type:varchar(5)" json:"run_at,omitempty"`     // local HH:MM of daily and weekly runs
+	Blackouts      datatypes.JSON `gorm:"type:jsonb" json:"blackouts,omitempty"`       // local windows during which the site is not analyzed
+	Budgets        datatypes.JSON `gorm:"type:jsonb" json:"budgets,omitempty"`         // overrides the budgets of the preset
+	AlertRules     datatypes.JSON `gorm:"type:jsonb" json:"alert_rules,omitempty"`
+	Active         bool           `gorm:"not null;default:true" json:"active"`
+	NextRunAt      *time.Time     `gorm:"index" json:"next_run_at,omitempty"`
+	LastRunAt      *time.Time     `json:"last_run_at,omitempty"`
+	LastAnalysisID *uuid.UUID     `gorm:"type:uuid;index" json:"last_analysis_id,omitempty"`
+	LastError      string         `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt      time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// BacklinkSnapshot is the backlink profile of the analyzed domain as
+// reported by the backlink data provider at the time of an analysis
+type BacklinkSnapshot struct {
+	ID               uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	AnalysisID       uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex" json:"analysis_id"`
+	Domain           string         `gorm:"type:varchar(255);not null;index:idx_backlink_snapshots_domain_created" json:"domain"`
+	Provider         string         `gorm:"type:varchar(50);not null" json:"provider"`
+	ReferringDomains int64          `gorm:"not null;default:0" json:"referring_domains"`
+	Backlinks        int64          `gorm:"not null;default:0" json:"backlinks"`
+	TopBacklinks     datatypes.JSON `gorm:"type:jsonb" json:"top_backlinks"`
+	Loss             datatypes.JSON `gorm:"type:jsonb" json:"loss,omitempty"` // set when referring domains dropped sharply since the previous snapshot
+	CreatedAt        time.Time      `gorm:"autoCreateTime;index:idx_backlink_snapshots_domain_created" json:"created_at"`
+}
+
+// WidgetOrigin is a web origin, e.g. https://cms.example.com, from which a
+// user embeds summary widgets. Browsers may only call the widget API from
+// registered origins.
+type WidgetOrigin struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_widget_origins_user_origin" json:"user_id"`
+	Origin    string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_widget_origins_user_origin;index" json:"origin"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// PageLink is a link found on the analyzed page
+type PageLink struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	AnalysisID uuid.UUID `gorm:"type:uuid;not null;index" json:"analysis_id"`
+	WebsiteID  uuid.UUID `gorm:"type:uuid;not null;index" json:"website_id"`
+	URL        string    `gorm:"type:varchar(2048);not null" json:"url"`
+	TargetHost string    `gorm:"type:varchar(255);not null;default:'';index" json:"target_host"` // without "www."
+	Text       string    `gorm:"type:text" json:"text"`
+	IsInternal bool      `gorm:"not null;default:false" json:"is_internal"`
+	NoFollow   bool      `gorm:"not null;default:false" json:"no_follow"`
+	StatusCode int       `gorm:"not null;default:0" json:"status_code,omitempty"` // zero when the link was not checked
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// PageImage is an image found on the analyzed page
+type PageImage struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	AnalysisID uuid.UUID `gorm:"type:uuid;not null;index" json:"analysis_id"`
+	WebsiteID  uuid.UUID `gorm:"type:uuid;not null;index" json:"website_id"`
+	URL        string    `gorm:"type:varchar(2048);not null" json:"url"`
+	Alt        string    `gorm:"type:text" json:"alt"`
+	Width      string    `gorm:"type:varchar(20)" json:"width,omitempty"` // as declared in the markup
+	Height     string    `gorm:"type:varchar(20)" json:"height,omitempty"`
+	FileSize   int64     `gorm:"not null;default:0" json:"file_size,omitempty"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// PageTechnology is a technology detected on the analyzed page
+type PageTechnology struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	AnalysisID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_page_technologies_analysis_name" json:"analysis_id"`
+	WebsiteID  uuid.UUID `gorm:"type:uuid;not null;index" json:"website_id"`
+	Name       string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_page_technologies_analysis_name;index:idx_page_technologies_name_created" json:"name"`
+	Category   string    `gorm:"type:varchar(100)" json:"category"`
+	Version    string    `gorm:"type:varchar(50)" json:"version,omitempty"`
+	Confidence int       `gorm:"not null;default:0" json:"confidence"`
+	CreatedAt  time.Time `gorm:"autoCreateTime;index:idx_page_technologies_name_created" json:"created_at"`
+}
+
+// TrackedKeyword is a keyword for which a user follows the search position
+// of a page. Positions are checked on a schedule through a SERP API.
+type TrackedKeyword struct {
+	ID               uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID           uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_tracked_keywords_market" json:"user_id"`
+	Keyword          string         `gorm:"type:varchar(255);not null;uniqueIndex:idx_tracked_keywords_market" json:"keyword"`
+	URL              string         `gorm:"type:varchar(2048);not null;uniqueIndex:idx_tracked_keywords_market" json:"url"` // normalized
+	Country          string         `gorm:"type:varchar(10);not null;default:'';uniqueIndex:idx_tracked_keywords_market" json:"country,omitempty"`
+	Language         string         `gorm:"type:varchar(10);not null;default:'';uniqueIndex:idx_tracked_keywords_market" json:"language,omitempty"`
+	Device           string         `gorm:"type:varchar(20);not null;default:'desktop';uniqueIndex:idx_tracked_keywords_market" json:"device"` // desktop, mobile
+	AlertRules       datatypes.JSON `gorm:"type:jsonb" json:"alert_rules,omitempty"`
+	Active           bool           `gorm:"not null;default:true" json:"active"`
+	Position         *int           `json:"position"` // nil when not ranked within the checked depth
+	PreviousPosition *int           `json:"previous_position"`
+	LastCheckedAt    *time.Time     `json:"last_checked_at,omitempty"`
+	NextCheckAt      *time.Time     `gorm:"index" json:"next_check_at,omitempty"`
+	LastError        string         `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt        time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt        time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// KeywordRanking is one position check of a tracked keyword
+type KeywordRanking struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	KeywordID  uuid.UUID `gorm:"type:uuid;not null;index:idx_keyword_rankings_keyword_checked" json:"keyword_id"`
+	Position   *int      `json:"position"` // nil when not ranked within the checked depth
+	MatchedURL string    `gorm:"type:varchar(2048)" json:"matched_url,omitempty"`
+	Provider   string    `gorm:"type:varchar(50);not null" json:"provider"`
+	CheckedAt  time.Time `gorm:"autoCreateTime;index:idx_keyword_rankings_keyword_checked" json:"checked_at"`
+}
+
+// ReportSchedule delivers a weekly or monthly summary of a user's monitored
+// sites to a list of recipients by email
+type ReportSchedule struct {
+	ID         uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID     uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name       string         `gorm:"type:varchar(255);not null" json:"name"`
+	Frequency  string         `gorm:"type:varchar(20);not null" json:"frequency"` // weekly, monthly
+	SiteIDs    datatypes.JSON `gorm:"type:jsonb" json:"site_ids,omitempty"`       // all monitored sites when empty
+	Recipients datatypes.JSON `gorm:"type:jsonb;not null" json:"recipients"`      // email, format and sections per recipient
+	Active     bool           `gorm:"not null;default:true" json:"active"`
+	NextRunAt  *time.Time     `gorm:"index" json:"next_run_at,omitempty"`
+	LastRunAt  *time.Time     `json:"last_run_at,omitempty"`
+	LastError  string         `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt  time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// CustomRule is a check defined by an organization, run on every page it
+// analyzes: a CSS selector that must or must not be present, or a regular
+// expression the HTML must or must not match
+type CustomRule struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_custom_rules_user_code" json:"user_id"`
+	Code      string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_custom_rules_user_code" json:"code"` // issue type of violations
+	Name      string    `gorm:"type:varchar(255);not null" json:"name"`
+	Kind      string    `gorm:"type:varchar(30);not null" json:"kind"` // selector_required, selector_forbidden, pattern_required, pattern_forbidden
+	Selector  string    `gorm:"type:text" json:"selector,omitempty"`
+	Pattern   string    `gorm:"type:text" json:"pattern,omitempty"` // regular expression, optionally as /expr/flags
+	Severity  string    `gorm:"type:varchar(20);not null" json:"severity"`
+	Message   string    `gorm:"type:text;not null" json:"message"`
+	Active    bool      `gorm:"not null;default:true" json:"active"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// Deployment marks a release of a website, recorded through the API or a
+// GitHub webhook. A post-deploy analysis is compared with the last analysis
+// before the release to measure its impact.
+type Deployment struct {
+	ID                 uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID             uuid.UUID      `gorm:"type:uuid;not null;index:idx_deployments_user_website_deployed" json:"user_id"`
+	WebsiteID          uuid.UUID      `gorm:"type:uuid;not null;index:idx_deployments_user_website_deployed" json:"website_id"`
+	CommitSHA          string         `gorm:"type:varchar(64);not null" json:"commit_sha"`
+	Ref                string         `gorm:"type:varchar(255)" json:"ref,omitempty"`
+	Repository         string         `gorm:"type:varchar(255)" json:"repository,omitempty"`
+	Environment        string         `gorm:"type:varchar(100)" json:"environment,omitempty"`
+	Description        string         `gorm:"type:text" json:"description,omitempty"`
+	Source             string         `gorm:"type:varchar(20);not null" json:"source"` // api, github
+	DeployedAt         time.Time      `gorm:"not null;index:idx_deployments_user_website_deployed" json:"deployed_at"`
+	BaselineAnalysisID *uuid.UUID     `gorm:"type:uuid" json:"baseline_analysis_id,omitempty"` // last completed analysis before the release
+	AnalysisID         *uuid.UUID     `gorm:"type:uuid;index" json:"analysis_id,omitempty"`    // post-deploy analysis
+	Impact             datatypes.JSON `gorm:"type:jsonb" json:"impact,omitempty"`
+	CreatedAt          time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// DeploymentHook accepts GitHub webhooks marking deployments of a website.
+// Payloads are signed with the secret.
+type DeploymentHook struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_deployment_hooks_user_website" json:"user_id"`
+	WebsiteID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_deployment_hooks_user_website" json:"website_id"`
+	Secret    string    `gorm:"type:varchar(64);not null" json:"-"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// ContentBatch generates content improvements for the lowest-scoring pages
+// of a domain in the background. Pages holds the status of each page.
+type ContentBatch struct {
+	ID          uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	DomainID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"domain_id"`
+	Status      string         `gorm:"type:varchar(20);not null" json:"status"` // processing, completed, partial, failed
+	Progress    float64        `gorm:"not null;default:0" json:"progress"`      // 0-100
+	Options     datatypes.JSON `gorm:"type:jsonb" json:"options"`
+	Pages       datatypes.JSON `gorm:"type:jsonb" json:"pages"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// GlossaryTerm is an approved term of an organization's glossary. Generated
+// content is corrected to use the term instead of its variants.
+type GlossaryTerm struct {
+	ID        uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	Term      string         `gorm:"type:varchar(255);not null" json:"term"`
+	Variants  datatypes.JSON `gorm:"type:jsonb" json:"variants"`
+	Language  string         `gorm:"type:varchar(10)" json:"language,omitempty"`
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// Outbox message states
+const (
+	OutboxStatusPending   = "pending"
+	OutboxStatusDelivered = "delivered"
+	OutboxStatusDead      = "dead" // gave up after the last attempt
+)
+
+// OutboxMessage is a side effect, such as a webhook, email or WebSocket
+// notification, written in the same transaction as the state change that
+// causes it and delivered afterwards by the outbox dispatcher. Messages are
+// never deleted before their retention ends, so the table is also a log of
+// the side effects of the deployment.
+type OutboxMessage struct {
+	ID            uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Kind          string         `gorm:"type:varchar(50);not null" json:"kind"`
+	Key           string         `gorm:"type:varchar(255);not null;uniqueIndex" json:"key"` // deduplicates messages of the same effect
+	Payload       datatypes.JSON `gorm:"type:jsonb;not null" json:"payload"`
+	Status        string         `gorm:"type:varchar(20);not null;default:'pending';index:idx_outbox_messages_due" json:"status"`
+	Attempts      int            `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time      `gorm:"not null;index:idx_outbox_messages_due" json:"next_attempt_at"`
+	LockedUntil   *time.Time     `json:"locked_until,omitempty"` // claimed by a dispatcher until then
+	LastError     string         `gorm:"type:text" json:"last_error,omitempty"`
+	DeliveredAt   *time.Time     `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time      `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
 // UserActivity logs user actions in the system
 type UserActivity struct {
 	ID         uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
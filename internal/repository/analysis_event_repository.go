@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// AnalysisEventRepository defines operations for AnalysisEvent model
+type AnalysisEventRepository interface {
+	Repository
+	FindByAnalysisID(analysisID uuid.UUID) ([]models.AnalysisEvent, error)
+	OutcomeStats(since time.Time, successType, failureType, analyzer string) ([]DailyOutcomeStat, error)
+	QueueDelayStats(since time.Time) ([]DailyQueueDelayStat, error)
+	FindOpenAlerts(userID uuid.UUID, limit int) ([]OpenAlert, error)
+}
+
+// OpenAlert is an alert raised by the latest completed analysis of a
+// website. A newer analysis of the website supersedes its alerts.
+type OpenAlert struct {
+	EventID    uuid.UUID      `json:"event_id"`
+	AnalysisID uuid.UUID      `json:"analysis_id"`
+	WebsiteID  uuid.UUID      `json:"website_id"`
+	URL        string         `json:"url"`
+	Message    string         `json:"message"`
+	Details    datatypes.JSON `json:"details,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// DailyOutcomeStat counts successful and failed events of one day
+type DailyOutcomeStat struct {
+	Day       Timestamp `json:"day"`
+	Succeeded int64     `json:"succeeded"`
+	Failed    int64     `json:"failed"`
+}
+
+// DailyQueueDelayStat aggregates the time analyses waited in the queue on one day
+type DailyQueueDelayStat struct {
+	Day        Timestamp `json:"day"`
+	Started    int64     `json:"started"`
+	AvgDelayMs float64   `json:"avg_delay_ms"`
+}
+
+// analysisEventRepository implements AnalysisEventRepository
+type analysisEventRepository struct {
+	*BaseRepository
+}
+
+// NewAnalysisEventRepository creates a new analysis event repository
+func NewAnalysisEventRepository(db *gorm.DB, redisClient *redis.Client) AnalysisEventRepository {
+	return &analysisEventRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// FindByAnalysisID returns the events of an analysis in chronological order
+func (r *analysisEventRepository) FindByAnalysisID(analysisID uuid.UUID) ([]models.AnalysisEvent, error) {
+	var events []models.AnalysisEvent
+	err := r.DB.Where("analysis_id = ?", analysisID).Order("created_at, id").Find(&events).Error
+	return events, err
+}
+
+// OutcomeStats counts success and failure events per day. When analyzer is
+// set, only events of that analyzer are counted.
+func (r *analysisEventRepository) OutcomeStats(since time.Time, successType, failureType, analyzer string) ([]DailyOutcomeStat, error) {
+	var stats []DailyOutcomeStat
+
+	query := r.DB.Model(&models.AnalysisEvent{}).
+		Select(r.Dialect.TruncateTime("day", "created_at")+` AS day,
+			SUM(CASE WHEN event_type = ? THEN 1 ELSE 0 END) AS succeeded,
+			SUM(CASE WHEN event_type = ? THEN 1 ELSE 0 END) AS failed
+		`, successType, failureType).
+		Where("created_at >= ? AND event_type IN ?", since, []string{successType, failureType})
+
+	if analyzer != "" {
+		query = query.Where("analyzer = ?", analyzer)
+	}
+
+	if err := query.Group("day").Order("day").Scan(&stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate event outcomes: %w", err)
+	}
+
+	return stats, nil
+}
+
+// QueueDelayStats averages the time between the queued and started events of
+// analyses per day
+func (r *analysisEventRepository) QueueDelayStats(since time.Time) ([]DailyQueueDelayStat, error) {
+	var stats []DailyQueueDelayStat
+
+	err := r.DB.Raw(`
+		SELECT
+			`+r.Dialect.TruncateTime("day", "s.created_at")+` AS day,
+			COUNT(*) AS started,
+			AVG(`+r.Dialect.MillisBetween("q.created_at", "s.created_at")+`) AS avg_delay_ms
+		FROM analysis_events s
+		JOIN analysis_events q ON q.analysis_id = s.analysis_id AND q.event_type = ?
+		WHERE s.event_type = ? AND s.created_at >= ?
+		GROUP BY day
+		ORDER BY day
+	`, models.AnalysisEventQueued, models.AnalysisEventStarted, since).Scan(&stats).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate queue delays: %w", err)
+	}
+
+	return stats, nil
+}
+
+// FindOpenAlerts returns the alerts of a user's websites that the latest
+// completed analysis of each website raised, newest first
+func (r *analysisEventRepository) FindOpenAlerts(userID uuid.UUID, limit int) ([]OpenAlert, error) {
+	var alerts []OpenAlert
+	err := r.DB.Raw(`
+		SELECT e.id AS event_id, e.analysis_id, a.website_id, w.url, e.message, e.details, e.created_at
+		FROM analysis_events e
+		JOIN analysis a ON a.id = e.analysis_id
+		JOIN websites w ON w.id = a.website_id
+		WHERE a.user_id = ? AND e.event_type = ? AND NOT w.sandbox
+			AND a.id = (
+				SELECT latest.id FROM analysis latest
+				WHERE latest.website_id = a.website_id AND latest.user_id = a.user_id
+					AND latest.status = 'completed'
+				ORDER BY latest.created_at DESC
+				LIMIT 1
+			)
+		ORDER BY e.created_at DESC
+		LIMIT ?
+	`, userID, models.AnalysisEventAlertTriggered, limit).Scan(&alerts).Error
+	return alerts, err
+}
@@ -2,7 +2,10 @@
 package repository
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/chynybekuuludastan/website_optimizer/internal/models"
@@ -28,8 +31,50 @@ type AnalysisRepository interface {
 	CountByDateRange(startDate, endDate time.Time) (int64, error)
 	FindByDateRange(startDate, endDate time.Time, page, pageSize int) ([]*models.Analysis, int64, error)
 	FindLatestByUserID(userID uuid.UUID, limit int) ([]*models.Analysis, error)
+	FindLatestCompletedByUserID(userID uuid.UUID, limit int) ([]*models.Analysis, error)
+	FindActiveByUserID(userID uuid.UUID) ([]*models.Analysis, error)
+	FindLatestCompletedByWebsiteID(websiteID uuid.UUID) (*models.Analysis, error)
+	FindLatestCompletedBefore(userID, websiteID uuid.UUID, before time.Time) (*models.Analysis, error)
 	UpdateMetadata(analysisID uuid.UUID, metadata datatypes.JSON) error
+	SetMetadataKey(analysisID uuid.UUID, key string, value interface{}) error
+	FindReusable(websiteID uuid.UUID, contentHash, presetKey, scoringVersion string, since time.Time, excludeID uuid.UUID) (*models.Analysis, error)
+	CloneResults(fromID, toID uuid.UUID) ([]models.AnalysisMetric, []models.Issue, error)
+	ReplaceCategoryResults(analysisID uuid.UUID, category string, metrics []models.AnalysisMetric, issues []models.Issue, recommendations []models.Recommendation) (int, error)
+	FindResultVersions(analysisID uuid.UUID, category string) ([]models.AnalysisResultVersion, error)
 	CountByStatusAndDate(status string, startDate, endDate time.Time) (int64, error)
+	CountByUserSince(userID uuid.UUID, since time.Time) (int64, error)
+	AnalyzerDurationStats(since time.Time) ([]AnalyzerDurationStat, error)
+	SlowTargetStats(since time.Time, thresholdMs int64, minRuns, limit int) ([]SlowTargetStat, error)
+	ImportExists(userID uuid.UUID, digest string) (bool, error)
+	CreateImported(imported []ImportedAnalysis) error
+}
+
+// ImportedAnalysis is a completed analysis reconstructed from the export of
+// another audit tool, with the URL of its website
+type ImportedAnalysis struct {
+	URL      string
+	Analysis *models.Analysis
+	Metrics  []models.AnalysisMetric
+	Issues   []models.Issue
+}
+
+// AnalyzerDurationStat aggregates the wall time of one analyzer across analyses
+type AnalyzerDurationStat struct {
+	Analyzer string  `json:"analyzer"`
+	Runs     int64   `json:"runs"`
+	AvgMs    float64 `json:"avg_ms"`
+	P95Ms    float64 `json:"p95_ms"`
+	MaxMs    float64 `json:"max_ms"`
+}
+
+// SlowTargetStat aggregates analysis durations for one website URL
+type SlowTargetStat struct {
+	URL        string  `json:"url"`
+	Runs       int64   `json:"runs"`
+	SlowRuns   int64   `json:"slow_runs"`
+	AvgTotalMs float64 `json:"avg_total_ms"`
+	AvgParseMs float64 `json:"avg_parse_ms"`
+	MaxTotalMs float64 `json:"max_total_ms"`
 }
 
 // analysisRepository implements AnalysisRepository
@@ -177,8 +222,8 @@ func (r *analysisRepository) UpdateStatus(analysisID uuid.UUID, status string) e
 		"status": status,
 	}
 
-	// If status is completed, set completed_at
-	if status == "completed" {
+	// If status is completed or blocked by anti-bot protection, set completed_at
+	if status == "completed" || status == "blocked" {
 		updates["completed_at"] = time.Now()
 	}
 
@@ -282,6 +327,58 @@ func (r *analysisRepository) FindByDateRange(startDate, endDate time.Time, page,
 	return analyses, count, nil
 }
 
+// FindLatestCompletedByWebsiteID finds the most recent completed analysis of a website
+func (r *analysisRepository) FindLatestCompletedByWebsiteID(websiteID uuid.UUID) (*models.Analysis, error) {
+	var analysis models.Analysis
+	err := r.DB.Where("website_id = ? AND status = ?", websiteID, "completed").
+		Order("created_at DESC").
+		First(&analysis).Error
+	if err != nil {
+		return nil, err
+	}
+	return &analysis, nil
+}
+
+// FindLatestCompletedBefore finds a user's most recent analysis of a website
+// that completed before a time
+func (r *analysisRepository) FindLatestCompletedBefore(userID, websiteID uuid.UUID, before time.Time) (*models.Analysis, error) {
+	var analysis models.Analysis
+	err := r.DB.Where("user_id = ? AND website_id = ? AND status = ? AND completed_at < ?", userID, websiteID, "completed", before).
+		Order("completed_at DESC").
+		First(&analysis).Error
+	if err != nil {
+		return nil, err
+	}
+	return &analysis, nil
+}
+
+// FindLatestCompletedByUserID finds the most recently completed analyses of a user
+func (r *analysisRepository) FindLatestCompletedByUserID(userID uuid.UUID, limit int) ([]*models.Analysis, error) {
+	var analyses []*models.Analysis
+	err := r.DB.Where("user_id = ? AND status = ?", userID, "completed").
+		Preload("Website").
+		Order("completed_at DESC NULLS LAST, created_at DESC").
+		Limit(limit).
+		Find(&analyses).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find latest completed analyses: %w", err)
+	}
+	return analyses, nil
+}
+
+// FindActiveByUserID finds the analyses of a user that are queued, running or paused
+func (r *analysisRepository) FindActiveByUserID(userID uuid.UUID) ([]*models.Analysis, error) {
+	var analyses []*models.Analysis
+	err := r.DB.Where("user_id = ? AND status IN ?", userID, []string{"pending", "running", "paused"}).
+		Preload("Website").
+		Order("created_at ASC").
+		Find(&analyses).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find active analyses: %w", err)
+	}
+	return analyses, nil
+}
+
 // FindLatestByUserID finds the most recent analyses for a specific user with caching
 func (r *analysisRepository) FindLatestByUserID(userID uuid.UUID, limit int) ([]*models.Analysis, error) {
 	// Try to get from cache if available
@@ -330,6 +427,190 @@ func (r *analysisRepository) UpdateMetadata(analysisID uuid.UUID, metadata datat
 	return nil
 }
 
+// SetMetadataKey atomically sets a single top-level key of the metadata,
+// preserving the other keys
+func (r *analysisRepository) SetMetadataKey(analysisID uuid.UUID, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata value: %w", err)
+	}
+
+	var result *gorm.DB
+	if r.Dialect == DialectSQLite {
+		result = r.DB.Model(&models.Analysis{}).Where("id = ?", analysisID).Updates(map[string]interface{}{
+			"metadata":   gorm.Expr(`json_set(COALESCE(metadata, '{}'), '$."' || ? || '"', json(?))`, key, string(data)),
+			"updated_at": time.Now(),
+		})
+	} else {
+		result = r.DB.Exec(
+			"UPDATE analysis SET metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object(?::text, ?::jsonb), updated_at = ? WHERE id = ?",
+			key, string(data), time.Now(), analysisID,
+		)
+	}
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to update analysis metadata: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("analysis not found: %s", analysisID)
+	}
+
+	return nil
+}
+
+// FindReusable finds the latest completed analysis of a website since the
+// given time that analyzed the same content with the same preset, the same
+// analyzer version and without request overrides, so its results still apply
+func (r *analysisRepository) FindReusable(websiteID uuid.UUID, contentHash, presetKey, scoringVersion string, since time.Time, excludeID uuid.UUID) (*models.Analysis, error) {
+	var analysis models.Analysis
+	err := r.DB.
+		Where("website_id = ? AND id <> ? AND status = ? AND completed_at >= ?", websiteID, excludeID, "completed", since).
+		Where("metadata->>'content_hash' = ?", contentHash).
+		Where("COALESCE(metadata->>'preset', '') = ?", presetKey).
+		Where("metadata->>'scoring_version' = ?", scoringVersion).
+		Where("metadata->'request_overrides' IS NULL").
+		Order("completed_at DESC").
+		First(&analysis).Error
+	if err != nil {
+		return nil, err
+	}
+	return &analysis, nil
+}
+
+// CloneResults copies the metrics, issues and recommendations of one analysis
+// to another in a single transaction and returns the copied metrics and issues
+func (r *analysisRepository) CloneResults(fromID, toID uuid.UUID) ([]models.AnalysisMetric, []models.Issue, error) {
+	var metrics []models.AnalysisMetric
+	var issues []models.Issue
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("analysis_id = ?", fromID).Find(&metrics).Error; err != nil {
+			return fmt.Errorf("failed to load metrics: %w", err)
+		}
+		for i := range metrics {
+			metrics[i].ID = uuid.Nil
+			metrics[i].AnalysisID = toID
+			metrics[i].CreatedAt = time.Time{}
+			if err := tx.Omit("Analysis").Create(&metrics[i]).Error; err != nil {
+				return fmt.Errorf("failed to copy metric: %w", err)
+			}
+		}
+
+		if err := tx.Where("analysis_id = ?", fromID).Find(&issues).Error; err != nil {
+			return fmt.Errorf("failed to load issues: %w", err)
+		}
+		for i := range issues {
+			issues[i].ID = uuid.Nil
+			issues[i].AnalysisID = toID
+			issues[i].CreatedAt = time.Time{}
+			if err := tx.Omit("Analysis").Create(&issues[i]).Error; err != nil {
+				return fmt.Errorf("failed to copy issue: %w", err)
+			}
+		}
+
+		var recommendations []models.Recommendation
+		if err := tx.Where("analysis_id = ?", fromID).Find(&recommendations).Error; err != nil {
+			return fmt.Errorf("failed to load recommendations: %w", err)
+		}
+		for i := range recommendations {
+			recommendations[i].ID = uuid.Nil
+			recommendations[i].AnalysisID = toID
+			recommendations[i].CreatedAt = time.Time{}
+			if err := tx.Omit("Analysis").Create(&recommendations[i]).Error; err != nil {
+				return fmt.Errorf("failed to copy recommendation: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return metrics, issues, nil
+}
+
+// ReplaceCategoryResults replaces the metrics, issues and recommendations of
+// one category of an analysis. The replaced records are kept as a new version
+// whose number is returned.
+func (r *analysisRepository) ReplaceCategoryResults(analysisID uuid.UUID, category string, metrics []models.AnalysisMetric, issues []models.Issue, recommendations []models.Recommendation) (int, error) {
+	var version int
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var oldMetrics []models.AnalysisMetric
+		var oldIssues []models.Issue
+		var oldRecommendations []models.Recommendation
+		if err := tx.Where("analysis_id = ? AND category = ?", analysisID, category).Find(&oldMetrics).Error; err != nil {
+			return fmt.Errorf("failed to load metrics: %w", err)
+		}
+		if err := tx.Where("analysis_id = ? AND category = ?", analysisID, category).Find(&oldIssues).Error; err != nil {
+			return fmt.Errorf("failed to load issues: %w", err)
+		}
+		if err := tx.Where("analysis_id = ? AND category = ?", analysisID, category).Find(&oldRecommendations).Error; err != nil {
+			return fmt.Errorf("failed to load recommendations: %w", err)
+		}
+
+		if err := tx.Model(&models.AnalysisResultVersion{}).
+			Where("analysis_id = ? AND category = ?", analysisID, category).
+			Select("COALESCE(MAX(version), 0) + 1").
+			Scan(&version).Error; err != nil {
+			return fmt.Errorf("failed to determine version: %w", err)
+		}
+
+		metricsJSON, _ := json.Marshal(oldMetrics)
+		issuesJSON, _ := json.Marshal(oldIssues)
+		recommendationsJSON, _ := json.Marshal(oldRecommendations)
+		if err := tx.Create(&models.AnalysisResultVersion{
+			AnalysisID:      analysisID,
+			Category:        category,
+			Version:         version,
+			Metrics:         datatypes.JSON(metricsJSON),
+			Issues:          datatypes.JSON(issuesJSON),
+			Recommendations: datatypes.JSON(recommendationsJSON),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to save result version: %w", err)
+		}
+
+		for _, model := range []interface{}{&models.AnalysisMetric{}, &models.Issue{}, &models.Recommendation{}} {
+			if err := tx.Where("analysis_id = ? AND category = ?", analysisID, category).Delete(model).Error; err != nil {
+				return fmt.Errorf("failed to remove replaced results: %w", err)
+			}
+		}
+
+		for i := range metrics {
+			if err := tx.Omit("Analysis").Create(&metrics[i]).Error; err != nil {
+				return fmt.Errorf("failed to save metric: %w", err)
+			}
+		}
+		for i := range issues {
+			if err := tx.Omit("Analysis").Create(&issues[i]).Error; err != nil {
+				return fmt.Errorf("failed to save issue: %w", err)
+			}
+		}
+		for i := range recommendations {
+			if err := tx.Omit("Analysis").Create(&recommendations[i]).Error; err != nil {
+				return fmt.Errorf("failed to save recommendation: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// FindResultVersions returns the replaced results of an analysis, newest
+// first, optionally limited to one category
+func (r *analysisRepository) FindResultVersions(analysisID uuid.UUID, category string) ([]models.AnalysisResultVersion, error) {
+	var versions []models.AnalysisResultVersion
+	query := r.DB.Where("analysis_id = ?", analysisID)
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+	err := query.Order("category, version DESC").Find(&versions).Error
+	return versions, err
+}
+
 // CountByStatusAndDate counts analyses by status within a date range
 func (r *analysisRepository) CountByStatusAndDate(status string, startDate, endDate time.Time) (int64, error) {
 	var count int64
@@ -349,3 +630,178 @@ func (r *analysisRepository) CountByStatusAndDate(status string, startDate, endD
 
 	return count, nil
 }
+
+// CountByUserSince counts the analyses a user created since the given time.
+// Imported analyses are not counted.
+func (r *analysisRepository) CountByUserSince(userID uuid.UUID, since time.Time) (int64, error) {
+	var count int64
+
+	err := r.DB.Model(&models.Analysis{}).
+		Where("user_id = ? AND created_at >= ? AND metadata->'import' IS NULL", userID, since).
+		Count(&count).Error
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to count user analyses: %w", err)
+	}
+
+	return count, nil
+}
+
+// AnalyzerDurationStats aggregates per-analyzer durations stored in the
+// profile section of analysis metadata
+func (r *analysisRepository) AnalyzerDurationStats(since time.Time) ([]AnalyzerDurationStat, error) {
+	if r.Dialect == DialectSQLite {
+		return r.analyzerDurationStatsSQLite(since)
+	}
+
+	var stats []AnalyzerDurationStat
+
+	err := r.DB.Raw(`
+		SELECT
+			timing.key AS analyzer,
+			COUNT(*) AS runs,
+			AVG(timing.value::numeric) AS avg_ms,
+			PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY timing.value::numeric) AS p95_ms,
+			MAX(timing.value::numeric) AS max_ms
+		FROM analysis a,
+			jsonb_each_text(a.metadata->'profile'->'analyzers') AS timing
+		WHERE a.deleted_at IS NULL AND a.created_at >= ?
+		GROUP BY timing.key
+		ORDER BY avg_ms DESC
+	`, since).Scan(&stats).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate analyzer durations: %w", err)
+	}
+
+	return stats, nil
+}
+
+// analyzerDurationStatsSQLite aggregates the analyzer durations in Go, as
+// SQLite has no percentile aggregate
+func (r *analysisRepository) analyzerDurationStatsSQLite(since time.Time) ([]AnalyzerDurationStat, error) {
+	var timings []struct {
+		Analyzer string
+		Ms       float64
+	}
+	err := r.DB.Raw(`
+		SELECT timing.key AS analyzer, CAST(timing.value AS REAL) AS ms
+		FROM analysis a, json_each(a.metadata, '$.profile.analyzers') AS timing
+		WHERE a.deleted_at IS NULL AND a.created_at >= ?
+	`, since).Scan(&timings).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate analyzer durations: %w", err)
+	}
+
+	durations := make(map[string][]float64)
+	for _, timing := range timings {
+		durations[timing.Analyzer] = append(durations[timing.Analyzer], timing.Ms)
+	}
+
+	stats := make([]AnalyzerDurationStat, 0, len(durations))
+	for analyzer, values := range durations {
+		sort.Float64s(values)
+		var sum float64
+		for _, value := range values {
+			sum += value
+		}
+		stats = append(stats, AnalyzerDurationStat{
+			Analyzer: analyzer,
+			Runs:     int64(len(values)),
+			AvgMs:    sum / float64(len(values)),
+			P95Ms:    percentile(values, 0.95),
+			MaxMs:    values[len(values)-1],
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].AvgMs > stats[j].AvgMs })
+
+	return stats, nil
+}
+
+// percentile interpolates a percentile of sorted values like PERCENTILE_CONT
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// SlowTargetStats returns the websites whose analyses take the longest on
+// average, counting runs slower than thresholdMs
+func (r *analysisRepository) SlowTargetStats(since time.Time, thresholdMs int64, minRuns, limit int) ([]SlowTargetStat, error) {
+	var stats []SlowTargetStat
+
+	err := r.DB.Raw(`
+		SELECT
+			w.url AS url,
+			COUNT(*) AS runs,
+			SUM(CASE WHEN CAST(a.metadata->'profile'->>'total_ms' AS NUMERIC) > ? THEN 1 ELSE 0 END) AS slow_runs,
+			AVG(CAST(a.metadata->'profile'->>'total_ms' AS NUMERIC)) AS avg_total_ms,
+			AVG(CAST(a.metadata->'profile'->>'parse_ms' AS NUMERIC)) AS avg_parse_ms,
+			MAX(CAST(a.metadata->'profile'->>'total_ms' AS NUMERIC)) AS max_total_ms
+		FROM analysis a
+		JOIN websites w ON w.id = a.website_id
+		WHERE a.deleted_at IS NULL AND NOT w.sandbox
+			AND a.created_at >= ?
+			AND a.metadata->'profile' IS NOT NULL
+		GROUP BY w.url
+		HAVING COUNT(*) >= ?
+		ORDER BY avg_total_ms DESC
+		LIMIT ?
+	`, thresholdMs, since, minRuns, limit).Scan(&stats).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate slow targets: %w", err)
+	}
+
+	return stats, nil
+}
+
+// ImportExists reports whether the user already imported an export with the
+// given digest
+func (r *analysisRepository) ImportExists(userID uuid.UUID, digest string) (bool, error) {
+	var count int64
+	err := r.DB.Model(&models.Analysis{}).
+		Where("user_id = ? AND metadata->'import'->>'digest' = ?", userID, digest).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CreateImported stores imported analyses with their metrics and issues in
+// one transaction, creating the websites that do not exist yet
+func (r *analysisRepository) CreateImported(imported []ImportedAnalysis) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		for _, item := range imported {
+			var website models.Website
+			err := tx.Where("url = ? AND NOT sandbox", item.URL).First(&website).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				website = models.Website{URL: item.URL}
+				err = tx.Create(&website).Error
+			}
+			if err != nil {
+				return fmt.Errorf("failed to store website %s: %w", item.URL, err)
+			}
+
+			item.Analysis.WebsiteID = website.ID
+			if err := tx.Create(item.Analysis).Error; err != nil {
+				return fmt.Errorf("failed to store analysis of %s: %w", item.URL, err)
+			}
+			if len(item.Metrics) > 0 {
+				if err := tx.Create(&item.Metrics).Error; err != nil {
+					return fmt.Errorf("failed to store metrics of %s: %w", item.URL, err)
+				}
+			}
+			if len(item.Issues) > 0 {
+				if err := tx.Create(&item.Issues).Error; err != nil {
+					return fmt.Errorf("failed to store issues of %s: %w", item.URL, err)
+				}
+			}
+		}
+		return nil
+	})
+}
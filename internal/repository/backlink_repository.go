@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BacklinkRepository defines operations for BacklinkSnapshot model
+type BacklinkRepository interface {
+	Repository
+	FindByAnalysisID(analysisID uuid.UUID) (*models.BacklinkSnapshot, error)
+	FindLatestByDomain(domain string) (*models.BacklinkSnapshot, error)
+	FindByDomainSince(domain string, since time.Time) ([]models.BacklinkSnapshot, error)
+}
+
+// backlinkRepository implements BacklinkRepository
+type backlinkRepository struct {
+	*BaseRepository
+}
+
+// NewBacklinkRepository creates a new backlink repository
+func NewBacklinkRepository(db *gorm.DB, redisClient *redis.Client) BacklinkRepository {
+	return &backlinkRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// FindByAnalysisID finds the backlink snapshot taken for an analysis
+func (r *backlinkRepository) FindByAnalysisID(analysisID uuid.UUID) (*models.BacklinkSnapshot, error) {
+	var snapshot models.BacklinkSnapshot
+	err := r.DB.Where("analysis_id = ?", analysisID).First(&snapshot).Error
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// FindLatestByDomain finds the most recent backlink snapshot of a domain
+func (r *backlinkRepository) FindLatestByDomain(domain string) (*models.BacklinkSnapshot, error) {
+	var snapshot models.BacklinkSnapshot
+	err := r.DB.Where("domain = ?", domain).Order("created_at DESC").First(&snapshot).Error
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// FindByDomainSince returns the backlink snapshots of a domain taken since
+// the given time, oldest first
+func (r *backlinkRepository) FindByDomainSince(domain string, since time.Time) ([]models.BacklinkSnapshot, error) {
+	var snapshots []models.BacklinkSnapshot
+	err := r.DB.Where("domain = ? AND created_at >= ?", domain, since).Order("created_at ASC").Find(&snapshots).Error
+	return snapshots, err
+}
@@ -19,6 +19,7 @@ type Repository interface {
 type BaseRepository struct {
 	DB        *gorm.DB
 	CacheRepo *cache.Repository
+	Dialect   Dialect
 }
 
 // NewBaseRepository creates a new base repository
@@ -31,6 +32,7 @@ func NewBaseRepository(db *gorm.DB, redisClient *redis.Client) *BaseRepository {
 	return &BaseRepository{
 		DB:        db,
 		CacheRepo: cacheRepo,
+		Dialect:   DialectOf(db),
 	}
 }
 
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ContentBatchRepository defines operations for ContentBatch model
+type ContentBatchRepository interface {
+	Repository
+	FindForUser(userID, id uuid.UUID) (*models.ContentBatch, error)
+	UpdateProgress(id uuid.UUID, progress float64, pages datatypes.JSON) error
+	Complete(id uuid.UUID, status string, pages datatypes.JSON) error
+}
+
+// contentBatchRepository implements ContentBatchRepository
+type contentBatchRepository struct {
+	*BaseRepository
+}
+
+// NewContentBatchRepository creates a new content batch repository
+func NewContentBatchRepository(db *gorm.DB, redisClient *redis.Client) ContentBatchRepository {
+	return &contentBatchRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// FindForUser finds a content batch by ID that belongs to the user
+func (r *contentBatchRepository) FindForUser(userID, id uuid.UUID) (*models.ContentBatch, error) {
+	var batch models.ContentBatch
+	err := r.DB.Where("id = ? AND user_id = ?", id, userID).First(&batch).Error
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// UpdateProgress stores the aggregate progress and page statuses of a running batch
+func (r *contentBatchRepository) UpdateProgress(id uuid.UUID, progress float64, pages datatypes.JSON) error {
+	return r.DB.Model(&models.ContentBatch{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"progress": progress,
+		"pages":    pages,
+	}).Error
+}
+
+// Complete stores the final status and page statuses of a batch
+func (r *contentBatchRepository) Complete(id uuid.UUID, status string, pages datatypes.JSON) error {
+	return r.DB.Model(&models.ContentBatch{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       status,
+		"progress":     100,
+		"pages":        pages,
+		"completed_at": time.Now(),
+	}).Error
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"encoding/json"
+
 	"github.com/chynybekuuludastan/website_optimizer/internal/models"
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
@@ -12,6 +14,8 @@ type ContentImprovementRepository interface {
 	FindByAnalysisID(analysisID uuid.UUID) ([]models.ContentImprovement, error)
 	FindByElementType(analysisID uuid.UUID, elementType string) ([]models.ContentImprovement, error)
 	CreateBatch(improvements []models.ContentImprovement) error
+	FindVersions(improvementID uuid.UUID) ([]models.ContentImprovementVersion, error)
+	SaveEditedVersion(improvementID uuid.UUID, content string, revision int, editors []uuid.UUID) (*models.ContentImprovementVersion, error)
 }
 
 type contentImprovementRepository struct {
@@ -69,3 +73,69 @@ func (r *contentImprovementRepository) FindByElementType(analysisID uuid.UUID, e
 func (r *contentImprovementRepository) CreateBatch(improvements []models.ContentImprovement) error {
 	return r.DB.Create(&improvements).Error
 }
+
+// FindVersions returns the saved versions of an improvement, oldest first
+func (r *contentImprovementRepository) FindVersions(improvementID uuid.UUID) ([]models.ContentImprovementVersion, error) {
+	var versions []models.ContentImprovementVersion
+	err := r.DB.Where("improvement_id = ?", improvementID).
+		Order("version ASC").
+		Find(&versions).Error
+	return versions, err
+}
+
+// SaveEditedVersion saves edited content as the next version of an
+// improvement and makes it the improved content. The generated content is
+// kept as version 1 when the improvement is first edited.
+func (r *contentImprovementRepository) SaveEditedVersion(improvementID uuid.UUID, content string, revision int, editors []uuid.UUID) (*models.ContentImprovementVersion, error) {
+	editorsJSON, err := json.Marshal(editors)
+	if err != nil {
+		return nil, err
+	}
+
+	var version models.ContentImprovementVersion
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		var improvement models.ContentImprovement
+		if err := tx.Select("id", "improved_content").First(&improvement, "id = ?", improvementID).Error; err != nil {
+			return err
+		}
+
+		var latest int
+		if err := tx.Model(&models.ContentImprovementVersion{}).
+			Where("improvement_id = ?", improvementID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error; err != nil {
+			return err
+		}
+		if latest == 0 {
+			generated := models.ContentImprovementVersion{
+				ImprovementID: improvementID,
+				Version:       1,
+				Content:       improvement.ImprovedContent,
+				Source:        "generated",
+			}
+			if err := tx.Create(&generated).Error; err != nil {
+				return err
+			}
+			latest = 1
+		}
+
+		version = models.ContentImprovementVersion{
+			ImprovementID: improvementID,
+			Version:       latest + 1,
+			Content:       content,
+			Source:        "edited",
+			Revision:      revision,
+			Editors:       editorsJSON,
+		}
+		if err := tx.Create(&version).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ContentImprovement{}).
+			Where("id = ?", improvementID).
+			Update("improved_content", content).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CustomRuleRepository defines operations for CustomRule model
+type CustomRuleRepository interface {
+	Repository
+	FindByUserID(userID uuid.UUID) ([]models.CustomRule, error)
+	FindActiveByUserID(userID uuid.UUID) ([]models.CustomRule, error)
+	FindForUser(userID, id uuid.UUID) (*models.CustomRule, error)
+	CodeExists(userID uuid.UUID, code string, excludeID uuid.UUID) (bool, error)
+}
+
+// customRuleRepository implements CustomRuleRepository
+type customRuleRepository struct {
+	*BaseRepository
+}
+
+// NewCustomRuleRepository creates a new custom rule repository
+func NewCustomRuleRepository(db *gorm.DB, redisClient *redis.Client) CustomRuleRepository {
+	return &customRuleRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// FindByUserID returns the custom rules of a user ordered by code
+func (r *customRuleRepository) FindByUserID(userID uuid.UUID) ([]models.CustomRule, error) {
+	var rules []models.CustomRule
+	err := r.DB.Where("user_id = ?", userID).Order("code ASC").Find(&rules).Error
+	return rules, err
+}
+
+// FindActiveByUserID returns the custom rules of a user that run on analyses
+func (r *customRuleRepository) FindActiveByUserID(userID uuid.UUID) ([]models.CustomRule, error) {
+	var rules []models.CustomRule
+	err := r.DB.Where("user_id = ? AND active = ?", userID, true).Order("code ASC").Find(&rules).Error
+	return rules, err
+}
+
+// FindForUser finds a custom rule by ID that belongs to the user
+func (r *customRuleRepository) FindForUser(userID, id uuid.UUID) (*models.CustomRule, error) {
+	var rule models.CustomRule
+	err := r.DB.Where("id = ? AND user_id = ?", id, userID).First(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// CodeExists reports whether another rule of the user already has the code
+func (r *customRuleRepository) CodeExists(userID uuid.UUID, code string, excludeID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.DB.Model(&models.CustomRule{}).
+		Where("user_id = ? AND code = ? AND id <> ?", userID, code, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// DeploymentRepository defines operations for Deployment and DeploymentHook models
+type DeploymentRepository interface {
+	Repository
+	FindByWebsite(userID, websiteID uuid.UUID, from time.Time) ([]models.Deployment, error)
+	FindForUser(userID, id uuid.UUID) (*models.Deployment, error)
+	FindByAnalysisID(analysisID uuid.UUID) ([]models.Deployment, error)
+	SetAnalysis(id uuid.UUID, baselineID, analysisID *uuid.UUID) error
+	SetImpact(id uuid.UUID, impact datatypes.JSON) error
+	FindHook(userID, websiteID uuid.UUID) (*models.DeploymentHook, error)
+	SaveHook(hook *models.DeploymentHook) error
+}
+
+// deploymentRepository implements DeploymentRepository
+type deploymentRepository struct {
+	*BaseRepository
+}
+
+// NewDeploymentRepository creates a new deployment repository
+func NewDeploymentRepository(db *gorm.DB, redisClient *redis.Client) DeploymentRepository {
+	return &deploymentRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// FindByWebsite returns the deployments of a website recorded by a user
+// since a time, oldest first
+func (r *deploymentRepository) FindByWebsite(userID, websiteID uuid.UUID, from time.Time) ([]models.Deployment, error) {
+	var deployments []models.Deployment
+	err := r.DB.Where("user_id = ? AND website_id = ? AND deployed_at >= ?", userID, websiteID, from).
+		Order("deployed_at ASC").
+		Find(&deployments).Error
+	return deployments, err
+}
+
+// FindForUser finds a deployment by ID that belongs to the user
+func (r *deploymentRepository) FindForUser(userID, id uuid.UUID) (*models.Deployment, error) {
+	var deployment models.Deployment
+	err := r.DB.Where("id = ? AND user_id = ?", id, userID).First(&deployment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &deployment, nil
+}
+
+// FindByAnalysisID returns the deployments whose post-deploy analysis is the
+// analysis. Several markers share an analysis when it was deduplicated.
+func (r *deploymentRepository) FindByAnalysisID(analysisID uuid.UUID) ([]models.Deployment, error) {
+	var deployments []models.Deployment
+	err := r.DB.Where("analysis_id = ?", analysisID).Find(&deployments).Error
+	return deployments, err
+}
+
+// SetAnalysis records the baseline and post-deploy analyses of a deployment
+func (r *deploymentRepository) SetAnalysis(id uuid.UUID, baselineID, analysisID *uuid.UUID) error {
+	return r.DB.Model(&models.Deployment{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"baseline_analysis_id": baselineID,
+		"analysis_id":          analysisID,
+	}).Error
+}
+
+// SetImpact stores the deploy-impact summary of a deployment
+func (r *deploymentRepository) SetImpact(id uuid.UUID, impact datatypes.JSON) error {
+	return r.DB.Model(&models.Deployment{}).Where("id = ?", id).Update("impact", impact).Error
+}
+
+// FindHook finds the deployment webhook of a user's website
+func (r *deploymentRepository) FindHook(userID, websiteID uuid.UUID) (*models.DeploymentHook, error) {
+	var hook models.DeploymentHook
+	err := r.DB.Where("user_id = ? AND website_id = ?", userID, websiteID).First(&hook).Error
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// SaveHook creates a deployment webhook or rotates the secret of an existing one
+func (r *deploymentRepository) SaveHook(hook *models.DeploymentHook) error {
+	if hook.ID == uuid.Nil {
+		return r.DB.Create(hook).Error
+	}
+	return r.DB.Model(hook).Update("secret", hook.Secret).Error
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Dialect is the SQL dialect of the database behind the repositories.
+// Queries are written in SQL both dialects understand where possible; the
+// helpers below cover the rest.
+type Dialect string
+
+const (
+	DialectPostgres Dialect = "postgres"
+	DialectSQLite   Dialect = "sqlite"
+)
+
+// DialectOf returns the dialect of a database connection
+func DialectOf(db *gorm.DB) Dialect {
+	if db != nil && db.Dialector != nil && db.Dialector.Name() == string(DialectSQLite) {
+		return DialectSQLite
+	}
+	return DialectPostgres
+}
+
+// TruncateTime returns an expression truncating a timestamp column to the
+// start of its day, week (starting Monday) or month. Other units truncate to
+// the day.
+func (d Dialect) TruncateTime(unit, column string) string {
+	switch unit {
+	case "week", "month":
+	default:
+		unit = "day"
+	}
+	if d != DialectSQLite {
+		return fmt.Sprintf("date_trunc('%s', %s)", unit, column)
+	}
+	switch unit {
+	case "week":
+		return fmt.Sprintf("date(%s, '-6 days', 'weekday 1')", column)
+	case "month":
+		return fmt.Sprintf("date(%s, 'start of month')", column)
+	}
+	return fmt.Sprintf("date(%s)", column)
+}
+
+// MillisBetween returns an expression of the milliseconds between two
+// timestamp columns
+func (d Dialect) MillisBetween(start, end string) string {
+	if d == DialectSQLite {
+		return fmt.Sprintf("(julianday(%s) - julianday(%s)) * 86400000.0", end, start)
+	}
+	return fmt.Sprintf("EXTRACT(EPOCH FROM (%s - %s)) * 1000", end, start)
+}
+
+// JSONIsNumber returns a condition that a key of a JSON column holds a number
+func (d Dialect) JSONIsNumber(column, key string) string {
+	if d == DialectSQLite {
+		return fmt.Sprintf("json_type(%s, '$.%s') IN ('integer', 'real')", column, key)
+	}
+	return fmt.Sprintf("jsonb_typeof(%s->'%s') = 'number'", column, key)
+}
+
+// Timestamp is a time computed by an aggregation, such as the day of a
+// bucket. SQLite returns computed times as text, which Timestamp parses.
+type Timestamp struct {
+	time.Time
+}
+
+// timestampLayouts are the text forms SQLite returns computed times in
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Scan implements sql.Scanner
+func (t *Timestamp) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		t.Time = time.Time{}
+		return nil
+	case time.Time:
+		t.Time = v
+		return nil
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	}
+	return fmt.Errorf("cannot scan %T into a timestamp", value)
+}
+
+// Value implements driver.Valuer
+func (t Timestamp) Value() (driver.Value, error) {
+	return t.Time, nil
+}
+
+func (t *Timestamp) parse(value string) error {
+	for _, layout := range timestampLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid timestamp %q", value)
+}
@@ -0,0 +1,434 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// DomainRepository defines operations for Domain model
+type DomainRepository interface {
+	Repository
+	FindByName(name string) (*models.Domain, error)
+	FindAll(page, pageSize int) ([]*models.Domain, int64, error)
+	SyncWebsites(name string) (int64, error)
+	Dashboard(domainID uuid.UUID) (*DomainDashboard, error)
+	LatestAnalyses(domainID uuid.UUID, limit int) ([]DomainPageAnalysis, error)
+	LowestScoringPages(domainID uuid.UUID, limit int) ([]DomainPageScore, error)
+	AnalysisCategoryScores(analysisIDs []uuid.UUID) ([]AnalysisCategoryScore, error)
+	AnalysisIssues(analysisIDs []uuid.UUID) ([]models.Issue, error)
+}
+
+// DomainPage is one website of a domain with its latest results
+type DomainPage struct {
+	WebsiteID        uuid.UUID  `json:"website_id"`
+	URL              string     `json:"url"`
+	Title            string     `json:"title"`
+	AnalysesCount    int64      `json:"analyses_count"`
+	LastAnalysisAt   *Timestamp `json:"last_analysis_at"`
+	LatestAnalysisID *uuid.UUID `json:"latest_analysis_id"`
+	Score            *float64   `json:"score"`
+}
+
+// DomainCategoryScore is the average score of one analyzer across the pages
+// of a domain
+type DomainCategoryScore struct {
+	Category string  `json:"category"`
+	AvgScore float64 `json:"avg_score"`
+	Pages    int     `json:"pages"`
+}
+
+// DomainKeywordRanking is the latest search position of a keyword tracked
+// for a page of a domain
+type DomainKeywordRanking struct {
+	KeywordID        uuid.UUID  `json:"keyword_id"`
+	WebsiteID        uuid.UUID  `json:"website_id"`
+	URL              string     `json:"url"`
+	Keyword          string     `json:"keyword"`
+	Country          string     `json:"country,omitempty"`
+	Device           string     `json:"device"`
+	Position         *int       `json:"position"`
+	PreviousPosition *int       `json:"previous_position"`
+	LastCheckedAt    *time.Time `json:"last_checked_at"`
+}
+
+// DomainPageAnalysis is the latest completed analysis of a page of a domain
+type DomainPageAnalysis struct {
+	WebsiteID  uuid.UUID `json:"website_id"`
+	URL        string    `json:"url"`
+	AnalysisID uuid.UUID `json:"analysis_id"`
+}
+
+// DomainPageScore is the overall score of the latest completed analysis of a
+// page of a domain
+type DomainPageScore struct {
+	WebsiteID  uuid.UUID `json:"website_id"`
+	URL        string    `json:"url"`
+	AnalysisID uuid.UUID `json:"analysis_id"`
+	Score      float64   `json:"score"`
+}
+
+// AnalysisCategoryScore is the score of one analyzer in an analysis
+type AnalysisCategoryScore struct {
+	AnalysisID uuid.UUID `json:"analysis_id"`
+	Category   string    `json:"category"`
+	Score      float64   `json:"score"`
+}
+
+// DomainBacklinkPoint is one backlink snapshot in the backlink trend of a
+// domain. Loss is set when the snapshot flagged a sudden loss of referring
+// domains.
+type DomainBacklinkPoint struct {
+	AnalysisID       uuid.UUID      `json:"analysis_id"`
+	ReferringDomains int64          `json:"referring_domains"`
+	Backlinks        int64          `json:"backlinks"`
+	Loss             datatypes.JSON `json:"loss,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+}
+
+// DomainDashboard aggregates the analyses of all pages of a domain. Scores
+// come from the latest completed analysis of each page.
+type DomainDashboard struct {
+	PagesCount     int64                  `json:"pages_count"`
+	AnalysesCount  int64                  `json:"analyses_count"`
+	AnalyzedPages  int                    `json:"analyzed_pages"`
+	AvgScore       *float64               `json:"avg_score"`
+	LastAnalysisAt *time.Time             `json:"last_analysis_at"`
+	CategoryScores []DomainCategoryScore  `json:"category_scores"`
+	Pages          []DomainPage           `json:"pages"`
+	Rankings       []DomainKeywordRanking `json:"rankings"`
+	Backlinks      []DomainBacklinkPoint  `json:"backlinks"`
+	BacklinkLosses []DomainBacklinkPoint  `json:"backlink_losses"`
+}
+
+// domainRepository implements DomainRepository
+type domainRepository struct {
+	*BaseRepository
+}
+
+// NewDomainRepository creates a new domain repository
+func NewDomainRepository(db *gorm.DB, redisClient *redis.Client) DomainRepository {
+	return &domainRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// FindByName finds a domain by its name
+func (r *domainRepository) FindByName(name string) (*models.Domain, error) {
+	var domain models.Domain
+	err := r.DB.Where("name = ?", name).First(&domain).Error
+	if err != nil {
+		return nil, err
+	}
+	return &domain, nil
+}
+
+// FindAll retrieves all domains with pagination
+func (r *domainRepository) FindAll(page, pageSize int) ([]*models.Domain, int64, error) {
+	var domains []*models.Domain
+	var count int64
+
+	if err := r.DB.Model(&models.Domain{}).Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := r.DB.Offset(offset).Limit(pageSize).Order("name ASC").Find(&domains).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return domains, count, nil
+}
+
+// SyncWebsites reassigns the websites whose URL mentions a domain name to the
+// most specific registered domain they belong to. It is run after a domain is
+// added or removed and returns the number of websites that changed.
+func (r *domainRepository) SyncWebsites(name string) (int64, error) {
+	var domains []models.Domain
+	if err := r.DB.Find(&domains).Error; err != nil {
+		return 0, fmt.Errorf("failed to load domains: %w", err)
+	}
+
+	var websites []models.Website
+	if err := r.DB.Select("id", "url", "domain_id").
+		Where("LOWER(url) LIKE ?", "%"+strings.ToLower(name)+"%").
+		Find(&websites).Error; err != nil {
+		return 0, fmt.Errorf("failed to load websites: %w", err)
+	}
+
+	var changed int64
+	for _, website := range websites {
+		domainID := matchDomain(domains, website.URL)
+		if sameDomain(domainID, website.DomainID) {
+			continue
+		}
+		if err := r.DB.Model(&models.Website{}).
+			Where("id = ?", website.ID).
+			Update("domain_id", domainID).Error; err != nil {
+			return changed, fmt.Errorf("failed to assign website %s: %w", website.ID, err)
+		}
+		changed++
+	}
+
+	return changed, nil
+}
+
+// Dashboard aggregates the pages and analyses of a domain
+func (r *domainRepository) Dashboard(domainID uuid.UUID) (*DomainDashboard, error) {
+	var pages []DomainPage
+	err := r.DB.Raw(`
+		SELECT websites.id AS website_id, websites.url, websites.title,
+			COUNT(analyses.id) AS analyses_count,
+			MAX(analyses.created_at) AS last_analysis_at
+		FROM websites
+		LEFT JOIN analyses ON analyses.website_id = websites.id AND analyses.deleted_at IS NULL
+		WHERE websites.domain_id = ? AND websites.deleted_at IS NULL
+		GROUP BY websites.id
+		ORDER BY last_analysis_at DESC NULLS LAST, websites.url
+	`, domainID).Scan(&pages).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load domain pages: %w", err)
+	}
+
+	var scores []struct {
+		WebsiteID  uuid.UUID
+		AnalysisID uuid.UUID
+		Category   string
+		Score      float64
+	}
+	err = r.DB.Raw(`
+		WITH latest AS (
+			SELECT id, website_id FROM (
+				SELECT analyses.id, analyses.website_id,
+					ROW_NUMBER() OVER (PARTITION BY analyses.website_id ORDER BY analyses.created_at DESC) AS row_rank
+				FROM analyses
+				JOIN websites ON websites.id = analyses.website_id
+				WHERE websites.domain_id = ? AND websites.deleted_at IS NULL
+					AND analyses.deleted_at IS NULL AND analyses.status = 'completed'
+			) ranked
+			WHERE row_rank = 1
+		)
+		SELECT latest.website_id, latest.id AS analysis_id, analysis_metrics.category,
+			AVG(CAST(analysis_metrics.value->>'score' AS FLOAT)) AS score
+		FROM latest
+		JOIN analysis_metrics ON analysis_metrics.analysis_id = latest.id
+		WHERE `+r.Dialect.JSONIsNumber("analysis_metrics.value", "score")+`
+		GROUP BY latest.website_id, latest.id, analysis_metrics.category
+	`, domainID).Scan(&scores).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load domain scores: %w", err)
+	}
+
+	type accumulator struct {
+		sum   float64
+		count int
+	}
+	pageScores := make(map[uuid.UUID]*accumulator)
+	latest := make(map[uuid.UUID]uuid.UUID)
+	categories := make(map[string]*accumulator)
+	for _, score := range scores {
+		if pageScores[score.WebsiteID] == nil {
+			pageScores[score.WebsiteID] = &accumulator{}
+		}
+		pageScores[score.WebsiteID].sum += score.Score
+		pageScores[score.WebsiteID].count++
+		latest[score.WebsiteID] = score.AnalysisID
+
+		if categories[score.Category] == nil {
+			categories[score.Category] = &accumulator{}
+		}
+		categories[score.Category].sum += score.Score
+		categories[score.Category].count++
+	}
+
+	// Search positions of the keywords tracked for the pages
+	rankings := []DomainKeywordRanking{}
+	err = r.DB.Raw(`
+		SELECT tracked_keywords.id AS keyword_id, websites.id AS website_id, tracked_keywords.url,
+			tracked_keywords.keyword, tracked_keywords.country, tracked_keywords.device,
+			tracked_keywords.position, tracked_keywords.previous_position, tracked_keywords.last_checked_at
+		FROM tracked_keywords
+		JOIN websites ON websites.url = tracked_keywords.url
+		WHERE websites.domain_id = ? AND websites.deleted_at IS NULL AND tracked_keywords.active
+		ORDER BY tracked_keywords.position ASC NULLS LAST, tracked_keywords.keyword
+	`, domainID).Scan(&rankings).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load domain keyword rankings: %w", err)
+	}
+
+	// Backlink trend of the registrable name, oldest first
+	backlinkPoints := []DomainBacklinkPoint{}
+	err = r.DB.Raw(`
+		SELECT backlink_snapshots.analysis_id, backlink_snapshots.referring_domains,
+			backlink_snapshots.backlinks, backlink_snapshots.loss, backlink_snapshots.created_at
+		FROM backlink_snapshots
+		JOIN domains ON backlink_snapshots.domain = CASE
+			WHEN lower(domains.name) LIKE 'www.%' THEN substr(lower(domains.name), 5)
+			ELSE lower(domains.name)
+		END
+		WHERE domains.id = ? AND backlink_snapshots.created_at >= ?
+		ORDER BY backlink_snapshots.created_at ASC
+	`, domainID, time.Now().AddDate(-1, 0, 0)).Scan(&backlinkPoints).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load domain backlinks: %w", err)
+	}
+	backlinkLosses := []DomainBacklinkPoint{}
+	for _, point := range backlinkPoints {
+		if len(point.Loss) > 0 && string(point.Loss) != "null" {
+			backlinkLosses = append(backlinkLosses, point)
+		}
+	}
+
+	dashboard := &DomainDashboard{
+		PagesCount:     int64(len(pages)),
+		CategoryScores: make([]DomainCategoryScore, 0, len(categories)),
+		Pages:          pages,
+		Rankings:       rankings,
+		Backlinks:      backlinkPoints,
+		BacklinkLosses: backlinkLosses,
+	}
+
+	var total float64
+	for i := range dashboard.Pages {
+		page := &dashboard.Pages[i]
+		dashboard.AnalysesCount += page.AnalysesCount
+		if page.LastAnalysisAt != nil && (dashboard.LastAnalysisAt == nil || page.LastAnalysisAt.After(*dashboard.LastAnalysisAt)) {
+			dashboard.LastAnalysisAt = &page.LastAnalysisAt.Time
+		}
+
+		acc, ok := pageScores[page.WebsiteID]
+		if !ok {
+			continue
+		}
+		analysisID := latest[page.WebsiteID]
+		score := acc.sum / float64(acc.count)
+		page.LatestAnalysisID = &analysisID
+		page.Score = &score
+		dashboard.AnalyzedPages++
+		total += score
+	}
+	if dashboard.AnalyzedPages > 0 {
+		avg := total / float64(dashboard.AnalyzedPages)
+		dashboard.AvgScore = &avg
+	}
+
+	for category, acc := range categories {
+		dashboard.CategoryScores = append(dashboard.CategoryScores, DomainCategoryScore{
+			Category: category,
+			AvgScore: acc.sum / float64(acc.count),
+			Pages:    acc.count,
+		})
+	}
+	sort.Slice(dashboard.CategoryScores, func(i, j int) bool {
+		return dashboard.CategoryScores[i].Category < dashboard.CategoryScores[j].Category
+	})
+
+	return dashboard, nil
+}
+
+// LatestAnalyses returns the latest completed analysis of each page of a
+// domain, most recently analyzed pages first
+func (r *domainRepository) LatestAnalyses(domainID uuid.UUID, limit int) ([]DomainPageAnalysis, error) {
+	var analyses []DomainPageAnalysis
+	err := r.DB.Raw(`
+		SELECT website_id, url, analysis_id, created_at FROM (
+			SELECT a.website_id, w.url, a.id AS analysis_id, a.created_at,
+				ROW_NUMBER() OVER (PARTITION BY a.website_id ORDER BY a.created_at DESC) AS row_rank
+			FROM analysis a
+			JOIN websites w ON w.id = a.website_id
+			WHERE w.domain_id = ? AND w.deleted_at IS NULL
+				AND a.deleted_at IS NULL AND a.status = 'completed'
+		) latest
+		WHERE latest.row_rank = 1
+		ORDER BY latest.created_at DESC
+		LIMIT ?
+	`, domainID, limit).Scan(&analyses).Error
+	return analyses, err
+}
+
+// LowestScoringPages returns the pages of a domain whose latest completed
+// analysis has the lowest overall score, lowest first
+func (r *domainRepository) LowestScoringPages(domainID uuid.UUID, limit int) ([]DomainPageScore, error) {
+	var pages []DomainPageScore
+	err := r.DB.Raw(`
+		SELECT website_id, url, analysis_id, score FROM (
+			SELECT a.website_id, w.url, a.id AS analysis_id,
+				CAST(a.metadata->>'overall_score' AS FLOAT) AS score,
+				ROW_NUMBER() OVER (PARTITION BY a.website_id ORDER BY a.created_at DESC) AS row_rank
+			FROM analysis a
+			JOIN websites w ON w.id = a.website_id
+			WHERE w.domain_id = ? AND w.deleted_at IS NULL
+				AND a.deleted_at IS NULL AND a.status = 'completed'
+				AND a.metadata->>'overall_score' IS NOT NULL
+		) latest
+		WHERE latest.row_rank = 1
+		ORDER BY latest.score ASC, latest.url
+		LIMIT ?
+	`, domainID, limit).Scan(&pages).Error
+	return pages, err
+}
+
+// AnalysisCategoryScores returns the average metric score per analyzer of
+// the given analyses
+func (r *domainRepository) AnalysisCategoryScores(analysisIDs []uuid.UUID) ([]AnalysisCategoryScore, error) {
+	var scores []AnalysisCategoryScore
+	if len(analysisIDs) == 0 {
+		return scores, nil
+	}
+	err := r.DB.Raw(`
+		SELECT analysis_id, category, AVG(CAST(value->>'score' AS FLOAT)) AS score
+		FROM analysis_metrics
+		WHERE analysis_id IN ? AND `+r.Dialect.JSONIsNumber("value", "score")+`
+		GROUP BY analysis_id, category
+	`, analysisIDs).Scan(&scores).Error
+	return scores, err
+}
+
+// AnalysisIssues returns the issues of the given analyses
+func (r *domainRepository) AnalysisIssues(analysisIDs []uuid.UUID) ([]models.Issue, error) {
+	var issues []models.Issue
+	if len(analysisIDs) == 0 {
+		return issues, nil
+	}
+	err := r.DB.Where("analysis_id IN ?", analysisIDs).Find(&issues).Error
+	return issues, err
+}
+
+// matchDomain returns the most specific domain a URL belongs to
+func matchDomain(domains []models.Domain, url string) *uuid.UUID {
+	host, err := urlnorm.Hostname(url)
+	if err != nil {
+		return nil
+	}
+
+	var best *models.Domain
+	for i := range domains {
+		domain := &domains[i]
+		if !urlnorm.InDomain(host, domain.Name, domain.IncludeSubdomains) {
+			continue
+		}
+		if best == nil || len(domain.Name) > len(best.Name) {
+			best = domain
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return &best.ID
+}
+
+// sameDomain compares optional domain IDs
+func sameDomain(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EventStreamRepository defines operations for EventStream model
+type EventStreamRepository interface {
+	Repository
+	FindByUserID(userID uuid.UUID) ([]models.EventStream, error)
+	FindActiveByUserID(userID uuid.UUID) ([]models.EventStream, error)
+	FindForUser(userID, id uuid.UUID) (*models.EventStream, error)
+	RecordDelivery(id uuid.UUID, deliveryErr error) error
+}
+
+// eventStreamRepository implements EventStreamRepository
+type eventStreamRepository struct {
+	*BaseRepository
+}
+
+// NewEventStreamRepository creates a new event stream repository
+func NewEventStreamRepository(db *gorm.DB, redisClient *redis.Client) EventStreamRepository {
+	return &eventStreamRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// FindByUserID returns the event streams of a user, newest first
+func (r *eventStreamRepository) FindByUserID(userID uuid.UUID) ([]models.EventStream, error) {
+	var streams []models.EventStream
+	err := r.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&streams).Error
+	return streams, err
+}
+
+// FindActiveByUserID returns the event streams of a user that receive events
+func (r *eventStreamRepository) FindActiveByUserID(userID uuid.UUID) ([]models.EventStream, error) {
+	var streams []models.EventStream
+	err := r.DB.Where("user_id = ? AND active = ?", userID, true).Find(&streams).Error
+	return streams, err
+}
+
+// FindForUser finds an event stream by ID that belongs to the user
+func (r *eventStreamRepository) FindForUser(userID, id uuid.UUID) (*models.EventStream, error) {
+	var stream models.EventStream
+	err := r.DB.Where("id = ? AND user_id = ?", id, userID).First(&stream).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stream, nil
+}
+
+// RecordDelivery stores the outcome of the latest delivery attempt
+func (r *eventStreamRepository) RecordDelivery(id uuid.UUID, deliveryErr error) error {
+	updates := map[string]interface{}{"last_error": ""}
+	if deliveryErr != nil {
+		updates["last_error"] = deliveryErr.Error()
+	} else {
+		updates["last_delivered_at"] = time.Now()
+	}
+	return r.DB.Model(&models.EventStream{}).Where("id = ?", id).Updates(updates).Error
+}
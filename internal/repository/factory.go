@@ -16,6 +16,26 @@ type Factory struct {
 	RecommendationRepository     RecommendationRepository
 	IssueRepository              IssueRepository
 	ContentImprovementRepository ContentImprovementRepository
+	AnalysisEventRepository      AnalysisEventRepository
+	UsageRepository              UsageRepository
+	SubscriptionRepository       SubscriptionRepository
+	SnapshotRepository           SnapshotRepository
+	DomainRepository             DomainRepository
+	PresetRepository             PresetRepository
+	EventStreamRepository        EventStreamRepository
+	MonitoredSiteRepository      MonitoredSiteRepository
+	KeywordRepository            KeywordRepository
+	BacklinkRepository           BacklinkRepository
+	WidgetOriginRepository       WidgetOriginRepository
+	PageEntityRepository         PageEntityRepository
+	IssueFeedbackRepository      IssueFeedbackRepository
+	IssueDocLinkRepository       IssueDocLinkRepository
+	OutboxRepository             OutboxRepository
+	ReportScheduleRepository     ReportScheduleRepository
+	CustomRuleRepository         CustomRuleRepository
+	DeploymentRepository         DeploymentRepository
+	ContentBatchRepository       ContentBatchRepository
+	GlossaryRepository           GlossaryRepository
 	CacheRepository              *cache.Repository
 }
 
@@ -29,6 +49,26 @@ func NewRepositoryFactory(db *gorm.DB, redisClient *redis.Client) *Factory {
 		RecommendationRepository:     NewRecommendationRepository(db, redisClient),
 		IssueRepository:              NewIssueRepository(db, redisClient),
 		ContentImprovementRepository: NewContentImprovementRepository(db, redisClient),
+		AnalysisEventRepository:      NewAnalysisEventRepository(db, redisClient),
+		UsageRepository:              NewUsageRepository(db, redisClient),
+		SubscriptionRepository:       NewSubscriptionRepository(db, redisClient),
+		SnapshotRepository:           NewSnapshotRepository(db, redisClient),
+		DomainRepository:             NewDomainRepository(db, redisClient),
+		PresetRepository:             NewPresetRepository(db, redisClient),
+		EventStreamRepository:        NewEventStreamRepository(db, redisClient),
+		MonitoredSiteRepository:      NewMonitoredSiteRepository(db, redisClient),
+		KeywordRepository:            NewKeywordRepository(db, redisClient),
+		BacklinkRepository:           NewBacklinkRepository(db, redisClient),
+		WidgetOriginRepository:       NewWidgetOriginRepository(db, redisClient),
+		PageEntityRepository:         NewPageEntityRepository(db, redisClient),
+		IssueFeedbackRepository:      NewIssueFeedbackRepository(db, redisClient),
+		IssueDocLinkRepository:       NewIssueDocLinkRepository(db, redisClient),
+		OutboxRepository:             NewOutboxRepository(db, redisClient),
+		ReportScheduleRepository:     NewReportScheduleRepository(db, redisClient),
+		CustomRuleRepository:         NewCustomRuleRepository(db, redisClient),
+		DeploymentRepository:         NewDeploymentRepository(db, redisClient),
+		ContentBatchRepository:       NewContentBatchRepository(db, redisClient),
+		GlossaryRepository:           NewGlossaryRepository(db, redisClient),
 		CacheRepository:              cache.NewRepository(redisClient),
 	}
 }
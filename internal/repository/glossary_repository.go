@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GlossaryRepository defines operations for GlossaryTerm model
+type GlossaryRepository interface {
+	Repository
+	FindByUserID(userID uuid.UUID) ([]models.GlossaryTerm, error)
+	Replace(userID uuid.UUID, terms []models.GlossaryTerm) error
+}
+
+// glossaryRepository implements GlossaryRepository
+type glossaryRepository struct {
+	*BaseRepository
+}
+
+// NewGlossaryRepository creates a new glossary repository
+func NewGlossaryRepository(db *gorm.DB, redisClient *redis.Client) GlossaryRepository {
+	return &glossaryRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// FindByUserID finds the glossary terms of a user in upload order
+func (r *glossaryRepository) FindByUserID(userID uuid.UUID) ([]models.GlossaryTerm, error) {
+	var terms []models.GlossaryTerm
+	err := r.DB.Where("user_id = ?", userID).Order("created_at ASC, term ASC").Find(&terms).Error
+	return terms, err
+}
+
+// Replace swaps the glossary of a user for a new set of terms
+func (r *glossaryRepository) Replace(userID uuid.UUID, terms []models.GlossaryTerm) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.GlossaryTerm{}).Error; err != nil {
+			return err
+		}
+		if len(terms) == 0 {
+			return nil
+		}
+		for i := range terms {
+			terms[i].UserID = userID
+		}
+		return tx.CreateInBatches(terms, 100).Error
+	})
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IssueDocLinkRepository defines operations for IssueDocLink model
+type IssueDocLinkRepository interface {
+	Repository
+	FindAll() ([]models.IssueDocLink, error)
+	SaveLink(link *models.IssueDocLink) error
+	DeleteLink(id uuid.UUID) (bool, error)
+}
+
+// issueDocLinkRepository implements IssueDocLinkRepository
+type issueDocLinkRepository struct {
+	*BaseRepository
+}
+
+// NewIssueDocLinkRepository creates a new issue doc link repository
+func NewIssueDocLinkRepository(db *gorm.DB, redisClient *redis.Client) IssueDocLinkRepository {
+	return &issueDocLinkRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// FindAll returns the doc links maintained by administrators
+func (r *issueDocLinkRepository) FindAll() ([]models.IssueDocLink, error) {
+	var links []models.IssueDocLink
+	err := r.DB.Order("issue_type ASC, category ASC, platform ASC").Find(&links).Error
+	return links, err
+}
+
+// SaveLink creates or replaces the doc link of an issue type, category and
+// platform
+func (r *issueDocLinkRepository) SaveLink(link *models.IssueDocLink) error {
+	err := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "category"}, {Name: "issue_type"}, {Name: "platform"}},
+		DoUpdates: clause.AssignmentColumns([]string{"title", "url", "updated_by", "updated_at"}),
+	}).Create(link).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to save doc link: %w", err)
+	}
+	return nil
+}
+
+// DeleteLink removes a doc link and reports whether it existed
+func (r *issueDocLinkRepository) DeleteLink(id uuid.UUID) (bool, error) {
+	result := r.DB.Where("id = ?", id).Delete(&models.IssueDocLink{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IssueFeedbackStats aggregates the ratings of one issue code
+type IssueFeedbackStats struct {
+	Category  string `json:"category"`
+	IssueType string `json:"issue_type"`
+	// Severity is the severity most ratings were given at
+	Severity   string `json:"rated_severity"`
+	Ratings    int64  `json:"ratings"`
+	Useful     int64  `json:"useful"`
+	Actionable int64  `json:"actionable"`
+}
+
+// IssueFeedbackRepository defines operations for IssueFeedback and IssueSeverityOverride models
+type IssueFeedbackRepository interface {
+	Repository
+	SaveFeedback(feedback *models.IssueFeedback) error
+	FindStats(since time.Time) ([]IssueFeedbackStats, error)
+	FindOverrides() ([]models.IssueSeverityOverride, error)
+	SaveOverride(override *models.IssueSeverityOverride) error
+	DeleteOverride(category, issueType string) (bool, error)
+}
+
+// issueFeedbackRepository implements IssueFeedbackRepository
+type issueFeedbackRepository struct {
+	*BaseRepository
+}
+
+// NewIssueFeedbackRepository creates a new issue feedback repository
+func NewIssueFeedbackRepository(db *gorm.DB, redisClient *redis.Client) IssueFeedbackRepository {
+	return &issueFeedbackRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// SaveFeedback stores a user's rating of an issue, replacing their earlier rating
+func (r *issueFeedbackRepository) SaveFeedback(feedback *models.IssueFeedback) error {
+	err := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "issue_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"useful", "actionable", "comment", "severity", "updated_at"}),
+	}).Create(feedback).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to save issue feedback: %w", err)
+	}
+	return nil
+}
+
+// FindStats aggregates the ratings given since the given time per issue code,
+// most rated first
+func (r *issueFeedbackRepository) FindStats(since time.Time) ([]IssueFeedbackStats, error) {
+	var stats []IssueFeedbackStats
+
+	// SQLite has no MODE aggregate, so the most rated severity is selected
+	// per group instead
+	mode, args := "MODE() WITHIN GROUP (ORDER BY severity)", []interface{}{}
+	if r.Dialect == DialectSQLite {
+		mode = `(SELECT f.severity FROM issue_feedbacks f
+			WHERE f.category = issue_feedbacks.category AND f.issue_type = issue_feedbacks.issue_type
+				AND f.updated_at >= ?
+			GROUP BY f.severity ORDER BY COUNT(*) DESC, f.severity LIMIT 1)`
+		args = append(args, since)
+	}
+
+	err := r.DB.Model(&models.IssueFeedback{}).
+		Select(`category, issue_type, COUNT(*) AS ratings,
+			`+mode+` AS severity,
+			COUNT(*) FILTER (WHERE useful) AS useful,
+			COUNT(*) FILTER (WHERE actionable) AS actionable`, args...).
+		Where("updated_at >= ?", since).
+		Group("category, issue_type").
+		Order("ratings DESC, category ASC, issue_type ASC").
+		Scan(&stats).Error
+	return stats, err
+}
+
+// FindOverrides returns all severity overrides
+func (r *issueFeedbackRepository) FindOverrides() ([]models.IssueSeverityOverride, error) {
+	var overrides []models.IssueSeverityOverride
+	err := r.DB.Order("category ASC, issue_type ASC").Find(&overrides).Error
+	return overrides, err
+}
+
+// SaveOverride creates or replaces the severity override of an issue code
+func (r *issueFeedbackRepository) SaveOverride(override *models.IssueSeverityOverride) error {
+	err := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "category"}, {Name: "issue_type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"severity", "reason", "updated_by", "updated_at"}),
+	}).Create(override).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to save severity override: %w", err)
+	}
+	return nil
+}
+
+// DeleteOverride removes the severity override of an issue code and reports
+// whether one existed
+func (r *issueFeedbackRepository) DeleteOverride(category, issueType string) (bool, error) {
+	result := r.DB.Where("category = ? AND issue_type = ?", category, issueType).Delete(&models.IssueSeverityOverride{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// KeywordRepository defines operations for TrackedKeyword and KeywordRanking models
+type KeywordRepository interface {
+	Repository
+	FindByUserID(userID uuid.UUID) ([]models.TrackedKeyword, error)
+	FindForUser(userID, id uuid.UUID) (*models.TrackedKeyword, error)
+	FindDue(now time.Time, limit int) ([]models.TrackedKeyword, error)
+	CountByUser(userID uuid.UUID) (int64, error)
+	ClaimCheck(id uuid.UUID, dueAt time.Time, nextCheckAt time.Time) (bool, error)
+	RecordCheck(keyword *models.TrackedKeyword, ranking *models.KeywordRanking, effects ...*models.OutboxMessage) error
+	RecordCheckError(id uuid.UUID, checkErr error) error
+	History(keywordID uuid.UUID, from, to time.Time) ([]models.KeywordRanking, error)
+	PageScores(userID uuid.UUID, pageURL string, from, to time.Time) ([]PageScore, error)
+}
+
+// PageScore is the overall on-page score of a completed analysis
+type PageScore struct {
+	AnalysisID   uuid.UUID `json:"analysis_id"`
+	OverallScore float64   `json:"overall_score"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// keywordRepository implements KeywordRepository
+type keywordRepository struct {
+	*BaseRepository
+}
+
+// NewKeywordRepository creates a new keyword repository
+func NewKeywordRepository(db *gorm.DB, redisClient *redis.Client) KeywordRepository {
+	return &keywordRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// FindByUserID returns the tracked keywords of a user ordered by URL and keyword
+func (r *keywordRepository) FindByUserID(userID uuid.UUID) ([]models.TrackedKeyword, error) {
+	var keywords []models.TrackedKeyword
+	err := r.DB.Where("user_id = ?", userID).Order("url ASC, keyword ASC").Find(&keywords).Error
+	return keywords, err
+}
+
+// FindForUser finds a tracked keyword by ID that belongs to the user
+func (r *keywordRepository) FindForUser(userID, id uuid.UUID) (*models.TrackedKeyword, error) {
+	var keyword models.TrackedKeyword
+	err := r.DB.Where("id = ? AND user_id = ?", id, userID).First(&keyword).Error
+	if err != nil {
+		return nil, err
+	}
+	return &keyword, nil
+}
+
+// FindDue returns active keywords whose next check is due, oldest first
+func (r *keywordRepository) FindDue(now time.Time, limit int) ([]models.TrackedKeyword, error) {
+	var keywords []models.TrackedKeyword
+	err := r.DB.Where("active = ? AND next_check_at IS NOT NULL AND next_check_at <= ?", true, now).
+		Order("next_check_at ASC").
+		Limit(limit).
+		Find(&keywords).Error
+	return keywords, err
+}
+
+// CountByUser returns the number of keywords a user tracks
+func (r *keywordRepository) CountByUser(userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.DB.Model(&models.TrackedKeyword{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// ClaimCheck moves the next check of a due keyword forward. It reports false
+// when another instance has already claimed this check.
+func (r *keywordRepository) ClaimCheck(id uuid.UUID, dueAt time.Time, nextCheckAt time.Time) (bool, error) {
+	result := r.DB.Model(&models.TrackedKeyword{}).
+		Where("id = ? AND next_check_at = ?", id, dueAt).
+		Update("next_check_at", nextCheckAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+// RecordCheck stores a position check and makes it the current position of
+// the keyword, keeping the former one as the previous position. The alerts
+// the check triggers are queued in the same transaction.
+func (r *keywordRepository) RecordCheck(keyword *models.TrackedKeyword, ranking *models.KeywordRanking, effects ...*models.OutboxMessage) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		ranking.KeywordID = keyword.ID
+		if err := tx.Create(ranking).Error; err != nil {
+			return fmt.Errorf("failed to save ranking: %w", err)
+		}
+
+		keyword.PreviousPosition = keyword.Position
+		keyword.Position = ranking.Position
+		keyword.LastCheckedAt = &ranking.CheckedAt
+		keyword.LastError = ""
+		err := tx.Model(&models.TrackedKeyword{}).
+			Where("id = ?", keyword.ID).
+			Updates(map[string]interface{}{
+				"position":          keyword.Position,
+				"previous_position": keyword.PreviousPosition,
+				"last_checked_at":   keyword.LastCheckedAt,
+				"last_error":        "",
+			}).Error
+		if err != nil {
+			return fmt.Errorf("failed to update keyword position: %w", err)
+		}
+		return EnqueueOutbox(tx, effects...)
+	})
+}
+
+// RecordCheckError records why a position check failed
+func (r *keywordRepository) RecordCheckError(id uuid.UUID, checkErr error) error {
+	return r.DB.Model(&models.TrackedKeyword{}).Where("id = ?", id).Update("last_error", checkErr.Error()).Error
+}
+
+// History returns the position checks of a keyword in a time range, oldest first
+func (r *keywordRepository) History(keywordID uuid.UUID, from, to time.Time) ([]models.KeywordRanking, error) {
+	var rankings []models.KeywordRanking
+	err := r.DB.Where("keyword_id = ? AND checked_at >= ? AND checked_at < ?", keywordID, from, to).
+		Order("checked_at ASC").
+		Find(&rankings).Error
+	return rankings, err
+}
+
+// PageScores returns the overall scores of a user's completed analyses of a
+// page in a time range, oldest first
+func (r *keywordRepository) PageScores(userID uuid.UUID, pageURL string, from, to time.Time) ([]PageScore, error) {
+	var scores []PageScore
+	err := r.DB.Raw(`
+		SELECT a.id AS analysis_id, CAST(a.metadata->>'overall_score' AS FLOAT) AS overall_score, a.created_at
+		FROM analysis a
+		JOIN websites w ON w.id = a.website_id
+		WHERE a.user_id = ? AND w.url = ? AND NOT w.sandbox AND a.status = 'completed'
+			AND a.deleted_at IS NULL AND a.metadata->>'overall_score' IS NOT NULL
+			AND a.created_at >= ? AND a.created_at < ?
+		ORDER BY a.created_at ASC
+	`, userID, pageURL, from, to).Scan(&scores).Error
+	return scores, err
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/chynybekuuludastan/website_optimizer/internal/models"
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
@@ -148,6 +150,21 @@ type IssueRepository interface {
 	FindByCategory(analysisID uuid.UUID, category string) ([]models.Issue, error)
 	FindBySeverity(analysisID uuid.UUID, severity string) ([]models.Issue, error)
 	CreateBatch(issues []models.Issue) error
+	FindOccurrences(userID uuid.UUID, from, to time.Time) ([]IssueOccurrence, error)
+}
+
+// IssueOccurrence is an issue of a completed analysis together with the page
+// it was found on. Analyses without issues appear once with an empty Type.
+type IssueOccurrence struct {
+	AnalysisID      uuid.UUID
+	WebsiteID       uuid.UUID
+	URL             string
+	AnalyzedAt      time.Time
+	Category        string
+	Type            string
+	Title           string
+	Severity        string
+	ElementSelector string
 }
 
 // issueRepository implements IssueRepository
@@ -174,6 +191,32 @@ func (r *issueRepository) CreateBatch(issues []models.Issue) error {
 	return r.DB.Create(&issues).Error
 }
 
+// FindOccurrences returns the issues of the completed analyses a user ran in
+// a time range, oldest analysis first. Issues stored before issue types were
+// recorded use their title as type.
+func (r *issueRepository) FindOccurrences(userID uuid.UUID, from, to time.Time) ([]IssueOccurrence, error) {
+	var occurrences []IssueOccurrence
+	err := r.DB.Raw(`
+		SELECT
+			a.id AS analysis_id,
+			a.website_id,
+			w.url,
+			a.created_at AS analyzed_at,
+			COALESCE(i.category, '') AS category,
+			COALESCE(NULLIF(i.type, ''), i.title, '') AS type,
+			COALESCE(i.title, '') AS title,
+			COALESCE(i.severity, '') AS severity,
+			COALESCE(i.element_selector, '') AS element_selector
+		FROM analysis a
+		JOIN websites w ON w.id = a.website_id
+		LEFT JOIN issues i ON i.analysis_id = a.id
+		WHERE a.user_id = ? AND a.status = 'completed' AND a.deleted_at IS NULL AND NOT w.sandbox
+			AND a.created_at >= ? AND a.created_at < ?
+		ORDER BY a.created_at, a.id
+	`, userID, from, to).Scan(&occurrences).Error
+	return occurrences, err
+}
+
 func (r *issueRepository) FindByCategory(analysisID uuid.UUID, category string) ([]models.Issue, error) {
 	var issues []models.Issue
 
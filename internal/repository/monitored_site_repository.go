@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MonitoredSiteRepository defines operations for MonitoredSite model
+type MonitoredSiteRepository interface {
+	Repository
+	FindByUserID(userID uuid.UUID) ([]models.MonitoredSite, error)
+	FindByLastAnalysisID(analysisID uuid.UUID) (*models.MonitoredSite, error)
+	FindDue(now time.Time, limit int) ([]models.MonitoredSite, error)
+	CountActiveByUser(userID uuid.UUID) (int64, error)
+	Reconcile(userID uuid.UUID, upserts []*models.MonitoredSite, deleteIDs []uuid.UUID) error
+	ClaimRun(id uuid.UUID, dueAt time.Time, nextRunAt time.Time) (bool, error)
+	MarkRun(id uuid.UUID, analysisID *uuid.UUID, runErr error) error
+}
+
+// monitoredSiteRepository implements MonitoredSiteRepository
+type monitoredSiteRepository struct {
+	*BaseRepository
+}
+
+// NewMonitoredSiteRepository creates a new monitored site repository
+func NewMonitoredSiteRepository(db *gorm.DB, redisClient *redis.Client) MonitoredSiteRepository {
+	return &monitoredSiteRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// FindByUserID returns the monitored sites of a user ordered by URL
+func (r *monitoredSiteRepository) FindByUserID(userID uuid.UUID) ([]models.MonitoredSite, error) {
+	var sites []models.MonitoredSite
+	err := r.DB.Where("user_id = ?", userID).Order("url ASC").Find(&sites).Error
+	return sites, err
+}
+
+// FindByLastAnalysisID finds the monitored site whose latest scheduled run
+// is the given analysis
+func (r *monitoredSiteRepository) FindByLastAnalysisID(analysisID uuid.UUID) (*models.MonitoredSite, error) {
+	var site models.MonitoredSite
+	err := r.DB.Where("last_analysis_id = ?", analysisID).First(&site).Error
+	if err != nil {
+		return nil, err
+	}
+	return &site, nil
+}
+
+// FindDue returns active scheduled sites whose next run is due, oldest first
+func (r *monitoredSiteRepository) FindDue(now time.Time, limit int) ([]models.MonitoredSite, error) {
+	var sites []models.MonitoredSite
+	err := r.DB.Where("active = ? AND next_run_at IS NOT NULL AND next_run_at <= ?", true, now).
+		Order("next_run_at ASC").
+		Limit(limit).
+		Find(&sites).Error
+	return sites, err
+}
+
+// CountActiveByUser returns the number of active monitored sites of a user
+func (r *monitoredSiteRepository) CountActiveByUser(userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.DB.Model(&models.MonitoredSite{}).Where("user_id = ? AND active = ?", userID, true).Count(&count).Error
+	return count, err
+}
+
+// Reconcile applies a sync plan in one transaction: sites without an ID are
+// created, the others are updated and the listed IDs are deleted
+func (r *monitoredSiteRepository) Reconcile(userID uuid.UUID, upserts []*models.MonitoredSite, deleteIDs []uuid.UUID) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if len(deleteIDs) > 0 {
+			if err := tx.Where("user_id = ? AND id IN ?", userID, deleteIDs).Delete(&models.MonitoredSite{}).Error; err != nil {
+				return fmt.Errorf("failed to delete monitored sites: %w", err)
+			}
+		}
+		for _, site := range upserts {
+			site.UserID = userID
+			if site.ID == uuid.Nil {
+				if err := tx.Create(site).Error; err != nil {
+					return fmt.Errorf("failed to create monitored site %s: %w", site.URL, err)
+				}
+				continue
+			}
+			err := tx.Model(&models.MonitoredSite{}).
+				Where("id = ? AND user_id = ?", site.ID, userID).
+				Updates(map[string]interface{}{
+					"preset":      site.Preset,
+					"schedule":    site.Schedule,
+					"time_zone":   site.TimeZone,
+					"run_at":      site.RunAt,
+					"blackouts":   site.Blackouts,
+					"budgets":     site.Budgets,
+					"alert_rules": site.AlertRules,
+					"active":      site.Active,
+					"next_run_at": site.NextRunAt,
+					"updated_at":  time.Now(),
+				}).Error
+			if err != nil {
+				return fmt.Errorf("failed to update monitored site %s: %w", site.URL, err)
+			}
+		}
+		return nil
+	})
+}
+
+// ClaimRun moves the next run of a due site forward. It reports false when
+// another instance has already claimed this run.
+func (r *monitoredSiteRepository) ClaimRun(id uuid.UUID, dueAt time.Time, nextRunAt time.Time) (bool, error) {
+	result := r.DB.Model(&models.MonitoredSite{}).
+		Where("id = ? AND next_run_at = ?", id, dueAt).
+		Update("next_run_at", nextRunAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+// MarkRun records the outcome of a scheduled run
+func (r *monitoredSiteRepository) MarkRun(id uuid.UUID, analysisID *uuid.UUID, runErr error) error {
+	updates := map[string]interface{}{
+		"last_run_at": time.Now(),
+		"last_error":  "",
+	}
+	if analysisID != nil {
+		updates["last_analysis_id"] = analysisID
+	}
+	if runErr != nil {
+		updates["last_error"] = runErr.Error()
+	}
+	return r.DB.Model(&models.MonitoredSite{}).Where("id = ?", id).Updates(updates).Error
+}
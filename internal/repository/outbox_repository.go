@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OutboxStatusCount is the number of outbox messages of a kind in a state
+type OutboxStatusCount struct {
+	Kind   string `json:"kind"`
+	Status string `json:"status"`
+	Count  int64  `json:"count"`
+}
+
+// OutboxRepository defines operations for OutboxMessage model
+type OutboxRepository interface {
+	Repository
+	Enqueue(tx *gorm.DB, messages ...*models.OutboxMessage) error
+	FindDue(kinds []string, now time.Time, limit int) ([]models.OutboxMessage, error)
+	Claim(id uuid.UUID, now, lockedUntil time.Time) (bool, error)
+	MarkDelivered(id uuid.UUID) error
+	MarkFailed(id uuid.UUID, lastError string, retryAt *time.Time) error
+	FindDead(limit int) ([]models.OutboxMessage, error)
+	Requeue(id uuid.UUID) (bool, error)
+	CountByStatus() ([]OutboxStatusCount, error)
+	PruneDelivered(before time.Time) (int64, error)
+}
+
+// outboxRepository implements OutboxRepository
+type outboxRepository struct {
+	*BaseRepository
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *gorm.DB, redisClient *redis.Client) OutboxRepository {
+	return &outboxRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// EnqueueOutbox writes outbox messages with the transaction of the state
+// change causing them. Messages whose key is already queued are skipped, so
+// repeating a state change does not repeat its side effects.
+func EnqueueOutbox(tx *gorm.DB, messages ...*models.OutboxMessage) error {
+	for _, msg := range messages {
+		if msg.Status == "" {
+			msg.Status = models.OutboxStatusPending
+		}
+		if msg.NextAttemptAt.IsZero() {
+			msg.NextAttemptAt = time.Now()
+		}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoNothing: true,
+		}).Create(msg).Error; err != nil {
+			return fmt.Errorf("failed to enqueue %s message: %w", msg.Kind, err)
+		}
+	}
+	return nil
+}
+
+// Enqueue writes outbox messages in a transaction, or on their own when tx
+// is nil
+func (r *outboxRepository) Enqueue(tx *gorm.DB, messages ...*models.OutboxMessage) error {
+	if tx == nil {
+		tx = r.DB
+	}
+	return EnqueueOutbox(tx, messages...)
+}
+
+// FindDue returns pending messages of the given kinds that are due and not
+// claimed by a dispatcher, oldest first
+func (r *outboxRepository) FindDue(kinds []string, now time.Time, limit int) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	err := r.DB.Where("status = ? AND kind IN ? AND next_attempt_at <= ? AND (locked_until IS NULL OR locked_until < ?)",
+		models.OutboxStatusPending, kinds, now, now).
+		Order("next_attempt_at ASC, created_at ASC").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+// Claim locks a due message for one delivery attempt. It reports false when
+// another dispatcher has claimed it first.
+func (r *outboxRepository) Claim(id uuid.UUID, now, lockedUntil time.Time) (bool, error) {
+	result := r.DB.Model(&models.OutboxMessage{}).
+		Where("id = ? AND status = ? AND (locked_until IS NULL OR locked_until < ?)", id, models.OutboxStatusPending, now).
+		Updates(map[string]interface{}{
+			"locked_until": lockedUntil,
+			"attempts":     gorm.Expr("attempts + 1"),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// MarkDelivered records the successful delivery of a message
+func (r *outboxRepository) MarkDelivered(id uuid.UUID) error {
+	return r.DB.Model(&models.OutboxMessage{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       models.OutboxStatusDelivered,
+		"delivered_at": time.Now(),
+		"locked_until": nil,
+		"last_error":   "",
+	}).Error
+}
+
+// MarkFailed records a failed delivery attempt. The message is retried at
+// retryAt, or moved to the dead letters when retryAt is nil.
+func (r *outboxRepository) MarkFailed(id uuid.UUID, lastError string, retryAt *time.Time) error {
+	updates := map[string]interface{}{
+		"last_error":   lastError,
+		"locked_until": nil,
+	}
+	if retryAt != nil {
+		updates["next_attempt_at"] = *retryAt
+	} else {
+		updates["status"] = models.OutboxStatusDead
+	}
+	return r.DB.Model(&models.OutboxMessage{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// FindDead returns the dead letters, most recent first
+func (r *outboxRepository) FindDead(limit int) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	err := r.DB.Where("status = ?", models.OutboxStatusDead).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+// Requeue schedules a dead letter for delivery again with a fresh attempt
+// budget and reports whether it was a dead letter
+func (r *outboxRepository) Requeue(id uuid.UUID) (bool, error) {
+	result := r.DB.Model(&models.OutboxMessage{}).
+		Where("id = ? AND status = ?", id, models.OutboxStatusDead).
+		Updates(map[string]interface{}{
+			"status":          models.OutboxStatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountByStatus counts the messages of each kind and state
+func (r *outboxRepository) CountByStatus() ([]OutboxStatusCount, error) {
+	var counts []OutboxStatusCount
+	err := r.DB.Model(&models.OutboxMessage{}).
+		Select("kind, status, COUNT(*) AS count").
+		Group("kind, status").
+		Order("kind ASC, status ASC").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// PruneDelivered deletes messages delivered before the given time
+func (r *outboxRepository) PruneDelivered(before time.Time) (int64, error) {
+	result := r.DB.Where("status = ? AND delivered_at < ?", models.OutboxStatusDelivered, before).
+		Delete(&models.OutboxMessage{})
+	return result.RowsAffected, result.Error
+}
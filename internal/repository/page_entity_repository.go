@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// pageEntityBatchSize is the number of rows inserted per statement
+const pageEntityBatchSize = 500
+
+// PageEntities are the parsed links, images and technologies of one analysis
+type PageEntities struct {
+	Links        []models.PageLink
+	Images       []models.PageImage
+	Technologies []models.PageTechnology
+}
+
+// TechnologyUsage is the number of websites a technology was detected on
+type TechnologyUsage struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Websites int64  `json:"websites"`
+}
+
+// PageEntityRepository defines operations for PageLink, PageImage and PageTechnology models
+type PageEntityRepository interface {
+	Repository
+	SaveForAnalysis(analysisID uuid.UUID, entities PageEntities) error
+	FindLinksByAnalysisID(analysisID uuid.UUID) ([]models.PageLink, error)
+	FindLinksToHost(host string, since time.Time) ([]models.PageLink, error)
+	FindImagesByAnalysisID(analysisID uuid.UUID) ([]models.PageImage, error)
+	FindTechnologiesByAnalysisID(analysisID uuid.UUID) ([]models.PageTechnology, error)
+	FindTechnologiesByAnalysisIDs(analysisIDs []uuid.UUID) ([]models.PageTechnology, error)
+	FindTechnologiesByWebsiteID(websiteID uuid.UUID, since time.Time) ([]models.PageTechnology, error)
+	CountTechnologyUsage(since time.Time, limit int) ([]TechnologyUsage, error)
+}
+
+// pageEntityRepository implements PageEntityRepository
+type pageEntityRepository struct {
+	*BaseRepository
+}
+
+// NewPageEntityRepository creates a new page entity repository
+func NewPageEntityRepository(db *gorm.DB, redisClient *redis.Client) PageEntityRepository {
+	return &pageEntityRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// SaveForAnalysis replaces the stored entities of an analysis
+func (r *pageEntityRepository) SaveForAnalysis(analysisID uuid.UUID, entities PageEntities) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.PageLink{}, &models.PageImage{}, &models.PageTechnology{}} {
+			if err := tx.Where("analysis_id = ?", analysisID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if len(entities.Links) > 0 {
+			if err := tx.CreateInBatches(entities.Links, pageEntityBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		if len(entities.Images) > 0 {
+			if err := tx.CreateInBatches(entities.Images, pageEntityBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		if len(entities.Technologies) > 0 {
+			if err := tx.CreateInBatches(entities.Technologies, pageEntityBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save page entities: %w", err)
+	}
+	return nil
+}
+
+// FindLinksByAnalysisID returns the links found by an analysis
+func (r *pageEntityRepository) FindLinksByAnalysisID(analysisID uuid.UUID) ([]models.PageLink, error) {
+	var links []models.PageLink
+	err := r.DB.Where("analysis_id = ?", analysisID).Order("created_at ASC").Find(&links).Error
+	return links, err
+}
+
+// FindLinksToHost returns the links pointing to a host found since the
+// given time, newest first
+func (r *pageEntityRepository) FindLinksToHost(host string, since time.Time) ([]models.PageLink, error) {
+	var links []models.PageLink
+	err := r.DB.Where("target_host = ? AND created_at >= ?", host, since).Order("created_at DESC").Find(&links).Error
+	return links, err
+}
+
+// FindImagesByAnalysisID returns the images found by an analysis
+func (r *pageEntityRepository) FindImagesByAnalysisID(analysisID uuid.UUID) ([]models.PageImage, error) {
+	var images []models.PageImage
+	err := r.DB.Where("analysis_id = ?", analysisID).Order("created_at ASC").Find(&images).Error
+	return images, err
+}
+
+// FindTechnologiesByAnalysisID returns the technologies detected by an analysis
+func (r *pageEntityRepository) FindTechnologiesByAnalysisID(analysisID uuid.UUID) ([]models.PageTechnology, error) {
+	var technologies []models.PageTechnology
+	err := r.DB.Where("analysis_id = ?", analysisID).Order("name ASC").Find(&technologies).Error
+	return technologies, err
+}
+
+// FindTechnologiesByAnalysisIDs returns the technologies detected by several analyses
+func (r *pageEntityRepository) FindTechnologiesByAnalysisIDs(analysisIDs []uuid.UUID) ([]models.PageTechnology, error) {
+	var technologies []models.PageTechnology
+	if len(analysisIDs) == 0 {
+		return technologies, nil
+	}
+	err := r.DB.Where("analysis_id IN ?", analysisIDs).Order("name ASC").Find(&technologies).Error
+	return technologies, err
+}
+
+// FindTechnologiesByWebsiteID returns the technologies detected on a website
+// since the given time, oldest analysis first
+func (r *pageEntityRepository) FindTechnologiesByWebsiteID(websiteID uuid.UUID, since time.Time) ([]models.PageTechnology, error) {
+	var technologies []models.PageTechnology
+	err := r.DB.Where("website_id = ? AND created_at >= ?", websiteID, since).
+		Order("created_at ASC, name ASC").
+		Find(&technologies).Error
+	return technologies, err
+}
+
+// CountTechnologyUsage returns the technologies detected since the given time
+// with the number of distinct websites using them, most used first
+func (r *pageEntityRepository) CountTechnologyUsage(since time.Time, limit int) ([]TechnologyUsage, error) {
+	var usage []TechnologyUsage
+	err := r.DB.Model(&models.PageTechnology{}).
+		Select("name, MAX(category) AS category, COUNT(DISTINCT website_id) AS websites").
+		Where("created_at >= ?", since).
+		Group("name").
+		Order("websites DESC, name ASC").
+		Limit(limit).
+		Scan(&usage).Error
+	return usage, err
+}
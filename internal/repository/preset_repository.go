@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PresetRepository defines operations for AnalysisPreset model
+type PresetRepository interface {
+	Repository
+	FindByUserID(userID uuid.UUID) ([]models.AnalysisPreset, error)
+	FindByKey(userID uuid.UUID, key string) (*models.AnalysisPreset, error)
+	Save(preset *models.AnalysisPreset) error
+	DeleteByKey(userID uuid.UUID, key string) (bool, error)
+}
+
+// presetRepository implements PresetRepository
+type presetRepository struct {
+	*BaseRepository
+}
+
+// NewPresetRepository creates a new preset repository
+func NewPresetRepository(db *gorm.DB, redisClient *redis.Client) PresetRepository {
+	return &presetRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// FindByUserID returns the presets of a user ordered by key
+func (r *presetRepository) FindByUserID(userID uuid.UUID) ([]models.AnalysisPreset, error) {
+	var presets []models.AnalysisPreset
+	err := r.DB.Where("user_id = ?", userID).Order("key ASC").Find(&presets).Error
+	return presets, err
+}
+
+// FindByKey finds a preset of a user by its key
+func (r *presetRepository) FindByKey(userID uuid.UUID, key string) (*models.AnalysisPreset, error) {
+	var preset models.AnalysisPreset
+	err := r.DB.Where("user_id = ? AND key = ?", userID, key).First(&preset).Error
+	if err != nil {
+		return nil, err
+	}
+	return &preset, nil
+}
+
+// Save creates a preset or replaces the user's preset with the same key
+func (r *presetRepository) Save(preset *models.AnalysisPreset) error {
+	err := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "description", "definition", "updated_at"}),
+	}).Create(preset).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to save preset: %w", err)
+	}
+	return nil
+}
+
+// DeleteByKey removes a preset of a user and reports whether it existed
+func (r *presetRepository) DeleteByKey(userID uuid.UUID, key string) (bool, error) {
+	result := r.DB.Where("user_id = ? AND key = ?", userID, key).Delete(&models.AnalysisPreset{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReportScheduleRepository defines operations for ReportSchedule model
+type ReportScheduleRepository interface {
+	Repository
+	FindByUserID(userID uuid.UUID) ([]models.ReportSchedule, error)
+	FindForUser(userID, id uuid.UUID) (*models.ReportSchedule, error)
+	FindDue(now time.Time, limit int) ([]models.ReportSchedule, error)
+	ClaimRun(id uuid.UUID, dueAt time.Time, nextRunAt time.Time, effects ...*models.OutboxMessage) (bool, error)
+	MarkRun(id uuid.UUID, runErr error) error
+}
+
+// reportScheduleRepository implements ReportScheduleRepository
+type reportScheduleRepository struct {
+	*BaseRepository
+}
+
+// NewReportScheduleRepository creates a new report schedule repository
+func NewReportScheduleRepository(db *gorm.DB, redisClient *redis.Client) ReportScheduleRepository {
+	return &reportScheduleRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// FindByUserID returns the report schedules of a user ordered by name
+func (r *reportScheduleRepository) FindByUserID(userID uuid.UUID) ([]models.ReportSchedule, error) {
+	var schedules []models.ReportSchedule
+	err := r.DB.Where("user_id = ?", userID).Order("name ASC, created_at ASC").Find(&schedules).Error
+	return schedules, err
+}
+
+// FindForUser finds a report schedule by ID that belongs to the user
+func (r *reportScheduleRepository) FindForUser(userID, id uuid.UUID) (*models.ReportSchedule, error) {
+	var schedule models.ReportSchedule
+	err := r.DB.Where("id = ? AND user_id = ?", id, userID).First(&schedule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// FindDue returns active schedules whose next report is due, oldest first
+func (r *reportScheduleRepository) FindDue(now time.Time, limit int) ([]models.ReportSchedule, error) {
+	var schedules []models.ReportSchedule
+	err := r.DB.Where("active = ? AND next_run_at IS NOT NULL AND next_run_at <= ?", true, now).
+		Order("next_run_at ASC").
+		Limit(limit).
+		Find(&schedules).Error
+	return schedules, err
+}
+
+// ClaimRun moves the next run of a due schedule forward and queues the
+// deliveries of the run in the same transaction. It reports false when
+// another instance has already claimed this run.
+func (r *reportScheduleRepository) ClaimRun(id uuid.UUID, dueAt time.Time, nextRunAt time.Time, effects ...*models.OutboxMessage) (bool, error) {
+	claimed := false
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ReportSchedule{}).
+			Where("id = ? AND next_run_at = ?", id, dueAt).
+			Update("next_run_at", nextRunAt)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		claimed = true
+		return EnqueueOutbox(tx, effects...)
+	})
+	return claimed && err == nil, err
+}
+
+// MarkRun records the outcome of a report delivery
+func (r *reportScheduleRepository) MarkRun(id uuid.UUID, runErr error) error {
+	updates := map[string]interface{}{
+		"last_run_at": time.Now(),
+		"last_error":  "",
+	}
+	if runErr != nil {
+		updates["last_error"] = runErr.Error()
+	}
+	return r.DB.Model(&models.ReportSchedule{}).Where("id = ?", id).Updates(updates).Error
+}
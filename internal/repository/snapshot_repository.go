@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SnapshotRepository defines operations for AnalysisSnapshot model
+type SnapshotRepository interface {
+	Repository
+	Save(analysisID uuid.UUID, kind, content string) error
+	Find(analysisID uuid.UUID, kind string) (*models.AnalysisSnapshot, error)
+}
+
+// snapshotRepository implements SnapshotRepository
+type snapshotRepository struct {
+	*BaseRepository
+}
+
+// NewSnapshotRepository creates a new snapshot repository
+func NewSnapshotRepository(db *gorm.DB, redisClient *redis.Client) SnapshotRepository {
+	return &snapshotRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// Save gzip-compresses and stores a snapshot, replacing an existing one of
+// the same kind
+func (r *snapshotRepository) Save(analysisID uuid.UUID, kind, content string) error {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(content)); err != nil {
+		return fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(content))
+	snapshot := models.AnalysisSnapshot{
+		AnalysisID:     analysisID,
+		Kind:           kind,
+		Content:        buf.Bytes(),
+		Size:           int64(len(content)),
+		CompressedSize: int64(buf.Len()),
+		SHA256:         hex.EncodeToString(sum[:]),
+	}
+
+	err := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "analysis_id"}, {Name: "kind"}},
+		DoUpdates: clause.AssignmentColumns([]string{"content", "size", "compressed_size", "sha256", "created_at"}),
+	}).Create(&snapshot).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return nil
+}
+
+// Find returns a stored snapshot with its content still compressed
+func (r *snapshotRepository) Find(analysisID uuid.UUID, kind string) (*models.AnalysisSnapshot, error) {
+	var snapshot models.AnalysisSnapshot
+	err := r.DB.Where("analysis_id = ? AND kind = ?", analysisID, kind).First(&snapshot).Error
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// DecompressSnapshot returns the uncompressed content of a snapshot
+func DecompressSnapshot(snapshot *models.AnalysisSnapshot) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(snapshot.Content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
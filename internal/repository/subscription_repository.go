@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SubscriptionRepository defines operations for Subscription model
+type SubscriptionRepository interface {
+	Repository
+	FindByUserID(userID uuid.UUID) (*models.Subscription, error)
+	FindByStripeSubscriptionID(subscriptionID string) (*models.Subscription, error)
+	FindByStripeCustomerID(customerID string) (*models.Subscription, error)
+	Save(subscription *models.Subscription) error
+}
+
+// subscriptionRepository implements SubscriptionRepository
+type subscriptionRepository struct {
+	*BaseRepository
+}
+
+// NewSubscriptionRepository creates a new subscription repository
+func NewSubscriptionRepository(db *gorm.DB, redisClient *redis.Client) SubscriptionRepository {
+	return &subscriptionRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// FindByUserID returns the subscription of a user
+func (r *subscriptionRepository) FindByUserID(userID uuid.UUID) (*models.Subscription, error) {
+	var subscription models.Subscription
+	if err := r.DB.Where("user_id = ?", userID).First(&subscription).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// FindByStripeSubscriptionID returns the subscription backed by a Stripe subscription
+func (r *subscriptionRepository) FindByStripeSubscriptionID(subscriptionID string) (*models.Subscription, error) {
+	var subscription models.Subscription
+	if err := r.DB.Where("stripe_subscription_id = ?", subscriptionID).First(&subscription).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// FindByStripeCustomerID returns the subscription of a Stripe customer
+func (r *subscriptionRepository) FindByStripeCustomerID(customerID string) (*models.Subscription, error) {
+	var subscription models.Subscription
+	if err := r.DB.Where("stripe_customer_id = ?", customerID).First(&subscription).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// Save creates or replaces the subscription of a user
+func (r *subscriptionRepository) Save(subscription *models.Subscription) error {
+	err := r.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"plan":                   subscription.Plan,
+			"status":                 subscription.Status,
+			"stripe_customer_id":     subscription.StripeCustomerID,
+			"stripe_subscription_id": subscription.StripeSubscriptionID,
+			"current_period_end":     subscription.CurrentPeriodEnd,
+			"updated_at":             time.Now(),
+		}),
+	}).Create(subscription).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to save subscription: %w", err)
+	}
+	return nil
+}
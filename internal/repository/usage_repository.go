@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UsagePeriod is the bucket size of an aggregated usage report
+type UsagePeriod string
+
+// Supported usage aggregation periods
+const (
+	UsagePeriodDay   UsagePeriod = "day"
+	UsagePeriodWeek  UsagePeriod = "week"
+	UsagePeriodMonth UsagePeriod = "month"
+)
+
+// UsageFilter narrows an aggregated usage report
+type UsageFilter struct {
+	UserID *uuid.UUID
+	From   time.Time
+	To     time.Time
+	Period UsagePeriod
+}
+
+// UsageBucket aggregates analysis usage of one user in one period
+type UsageBucket struct {
+	PeriodStart         Timestamp `json:"period_start"`
+	UserID              uuid.UUID `json:"user_id"`
+	Analyses            int64     `json:"analyses"`
+	BytesFetched        int64     `json:"bytes_fetched"`
+	HeadlessMs          int64     `json:"headless_ms"`
+	LighthouseCalls     int64     `json:"lighthouse_calls"`
+	LLMPromptTokens     int64     `json:"llm_prompt_tokens"`
+	LLMCompletionTokens int64     `json:"llm_completion_tokens"`
+	Cost                float64   `json:"cost"`
+}
+
+// UsageRepository defines operations for AnalysisUsage model
+type UsageRepository interface {
+	Repository
+	FindByAnalysisID(analysisID uuid.UUID) (*models.AnalysisUsage, error)
+	Upsert(usage *models.AnalysisUsage) error
+	AddLLMUsage(analysisID, userID uuid.UUID, promptTokens, completionTokens int64, cost float64) error
+	Aggregate(filter UsageFilter) ([]UsageBucket, error)
+}
+
+// usageRepository implements UsageRepository
+type usageRepository struct {
+	*BaseRepository
+}
+
+// NewUsageRepository creates a new usage repository
+func NewUsageRepository(db *gorm.DB, redisClient *redis.Client) UsageRepository {
+	return &usageRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// FindByAnalysisID returns the usage record of an analysis
+func (r *usageRepository) FindByAnalysisID(analysisID uuid.UUID) (*models.AnalysisUsage, error) {
+	var usage models.AnalysisUsage
+	if err := r.DB.Where("analysis_id = ?", analysisID).First(&usage).Error; err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// Upsert adds the crawl-side usage of an analysis run to the usage of the
+// analysis, so repeated runs accumulate. LLM token counters that were
+// recorded separately are preserved and their cost is kept in the total.
+func (r *usageRepository) Upsert(usage *models.AnalysisUsage) error {
+	err := r.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "analysis_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"bytes_fetched":    gorm.Expr("analysis_usages.bytes_fetched + ?", usage.BytesFetched),
+			"headless_ms":      gorm.Expr("analysis_usages.headless_ms + ?", usage.HeadlessMs),
+			"lighthouse_calls": gorm.Expr("analysis_usages.lighthouse_calls + ?", usage.LighthouseCalls),
+			"cost":             gorm.Expr("analysis_usages.cost + ?", usage.Cost),
+			"updated_at":       time.Now(),
+		}),
+	}).Create(usage).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to save analysis usage: %w", err)
+	}
+	return nil
+}
+
+// AddLLMUsage increments the LLM token counters and cost of an analysis
+func (r *usageRepository) AddLLMUsage(analysisID, userID uuid.UUID, promptTokens, completionTokens int64, cost float64) error {
+	usage := models.AnalysisUsage{
+		AnalysisID:          analysisID,
+		UserID:              userID,
+		LLMPromptTokens:     promptTokens,
+		LLMCompletionTokens: completionTokens,
+		Cost:                cost,
+	}
+
+	err := r.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "analysis_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"llm_prompt_tokens":     gorm.Expr("analysis_usages.llm_prompt_tokens + ?", promptTokens),
+			"llm_completion_tokens": gorm.Expr("analysis_usages.llm_completion_tokens + ?", completionTokens),
+			"cost":                  gorm.Expr("analysis_usages.cost + ?", cost),
+			"updated_at":            time.Now(),
+		}),
+	}).Create(&usage).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to add LLM usage: %w", err)
+	}
+	return nil
+}
+
+// Aggregate sums usage per user and period within the filter's time range
+func (r *usageRepository) Aggregate(filter UsageFilter) ([]UsageBucket, error) {
+	var buckets []UsageBucket
+
+	query := r.DB.Model(&models.AnalysisUsage{}).
+		Select(r.Dialect.TruncateTime(string(filter.Period), "created_at")+` AS period_start,
+			user_id,
+			COUNT(*) AS analyses,
+			SUM(bytes_fetched) AS bytes_fetched,
+			SUM(headless_ms) AS headless_ms,
+			SUM(lighthouse_calls) AS lighthouse_calls,
+			SUM(llm_prompt_tokens) AS llm_prompt_tokens,
+			SUM(llm_completion_tokens) AS llm_completion_tokens,
+			SUM(cost) AS cost
+		`).
+		Where("created_at >= ? AND created_at < ?", filter.From, filter.To)
+
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+
+	err := query.Group("period_start, user_id").
+		Order("period_start, user_id").
+		Scan(&buckets).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate usage: %w", err)
+	}
+
+	return buckets, nil
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/chynybekuuludastan/website_optimizer/internal/models"
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -17,6 +18,7 @@ type UserRepository interface {
 	FindWithActivity(userID uuid.UUID) (*models.User, []models.UserActivity, error)
 	UpdatePassword(userID uuid.UUID, passwordHash string) error
 	UpdateRole(userID uuid.UUID, roleID uint) error
+	UpdateLLMDefaults(userID uuid.UUID, defaults datatypes.JSON) error
 	ExistsByEmail(email string) (bool, error)
 	ExistsByUsername(username string) (bool, error)
 }
@@ -120,6 +122,11 @@ func (r *userRepository) UpdateRole(userID uuid.UUID, roleID uint) error {
 	return r.DB.Model(&models.User{}).Where("id = ?", userID).Update("role_id", roleID).Error
 }
 
+// UpdateLLMDefaults updates the default LLM generation parameters of a user
+func (r *userRepository) UpdateLLMDefaults(userID uuid.UUID, defaults datatypes.JSON) error {
+	return r.DB.Model(&models.User{}).Where("id = ?", userID).Update("llm_defaults", defaults).Error
+}
+
 // ExistsByEmail checks if a user with the given email exists
 func (r *userRepository) ExistsByEmail(email string) (bool, error) {
 	var count int64
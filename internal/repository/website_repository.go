@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -20,6 +21,7 @@ type WebsiteRepository interface {
 	ExistsByURL(url string) (bool, error)
 	FindDomainStatistics(domain string) (map[string]interface{}, error)
 	FindPopularWebsites(limit int) ([]*models.Website, error)
+	UpdateCanonicalURL(websiteID uuid.UUID, finalURL string) error
 }
 
 // websiteRepository implements WebsiteRepository
@@ -34,23 +36,52 @@ func NewWebsiteRepository(db *gorm.DB, redisClient *redis.Client) WebsiteReposit
 	}
 }
 
-// FindByURL finds a website by URL
+// Create normalizes the URL of a website and assigns it to its registered
+// domain before inserting it
+func (r *websiteRepository) Create(entity interface{}) error {
+	if website, ok := entity.(*models.Website); ok {
+		if normalized, err := urlnorm.Normalize(website.URL); err == nil {
+			website.URL = normalized
+		}
+
+		var domains []models.Domain
+		if err := r.DB.Find(&domains).Error; err != nil {
+			return fmt.Errorf("failed to load domains: %w", err)
+		}
+		website.DomainID = matchDomain(domains, website.URL)
+	}
+	return r.BaseRepository.Create(entity)
+}
+
+// FindByURL finds a website by URL. The URL is normalized first and matched
+// over both http and https, preferring the https record.
 func (r *websiteRepository) FindByURL(url string) (*models.Website, error) {
 	var website models.Website
-	err := r.DB.Where("url = ?", url).First(&website).Error
+	err := r.DB.Where("url IN ? AND NOT sandbox", urlVariants(url)).
+		Order("url LIKE 'https://%' DESC, created_at ASC").
+		First(&website).Error
 	if err != nil {
 		return nil, err
 	}
 	return &website, nil
 }
 
+// urlVariants returns the stored forms a URL may have
+func urlVariants(url string) []string {
+	normalized, err := urlnorm.Normalize(url)
+	if err != nil {
+		return []string{url}
+	}
+	return urlnorm.SchemeVariants(normalized)
+}
+
 // FindAll retrieves all websites with pagination
 func (r *websiteRepository) FindAll(page, pageSize int) ([]*models.Website, int64, error) {
 	var websites []*models.Website
 	var count int64
 
 	// Count total websites
-	if err := r.DB.Model(&models.Website{}).Count(&count).Error; err != nil {
+	if err := r.DB.Model(&models.Website{}).Where("NOT sandbox").Count(&count).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -58,7 +89,7 @@ func (r *websiteRepository) FindAll(page, pageSize int) ([]*models.Website, int6
 	offset := (page - 1) * pageSize
 
 	// Get websites with pagination
-	if err := r.DB.Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&websites).Error; err != nil {
+	if err := r.DB.Where("NOT sandbox").Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&websites).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -75,7 +106,7 @@ func (r *websiteRepository) Search(query string, page, pageSize int) ([]*models.
 
 	// Count matching websites
 	if err := r.DB.Model(&models.Website{}).
-		Where("url LIKE ? OR title LIKE ?", searchQuery, searchQuery).
+		Where("(url LIKE ? OR title LIKE ?) AND NOT sandbox", searchQuery, searchQuery).
 		Count(&count).Error; err != nil {
 		return nil, 0, err
 	}
@@ -84,7 +115,7 @@ func (r *websiteRepository) Search(query string, page, pageSize int) ([]*models.
 	offset := (page - 1) * pageSize
 
 	// Search websites
-	if err := r.DB.Where("url LIKE ? OR title LIKE ?", searchQuery, searchQuery).
+	if err := r.DB.Where("(url LIKE ? OR title LIKE ?) AND NOT sandbox", searchQuery, searchQuery).
 		Offset(offset).
 		Limit(pageSize).
 		Order("created_at DESC").
@@ -119,10 +150,48 @@ func (r *websiteRepository) FindWithAnalyses(websiteID uuid.UUID) (*models.Websi
 // ExistsByURL checks if a website with the given URL exists
 func (r *websiteRepository) ExistsByURL(url string) (bool, error) {
 	var count int64
-	err := r.DB.Model(&models.Website{}).Where("url = ?", url).Count(&count).Error
+	err := r.DB.Model(&models.Website{}).Where("url IN ? AND NOT sandbox", urlVariants(url)).Count(&count).Error
 	return count > 0, err
 }
 
+// UpdateCanonicalURL moves a website to the normalized URL it finally
+// resolved to after redirects. The URL is kept when another website already
+// owns the target, so that histories are never silently merged.
+func (r *websiteRepository) UpdateCanonicalURL(websiteID uuid.UUID, finalURL string) error {
+	normalized, err := urlnorm.Normalize(finalURL)
+	if err != nil {
+		return fmt.Errorf("invalid final URL: %w", err)
+	}
+
+	var website models.Website
+	if err := r.DB.First(&website, "id = ?", websiteID).Error; err != nil {
+		return err
+	}
+	if website.URL == normalized {
+		return nil
+	}
+
+	var owners int64
+	if err := r.DB.Model(&models.Website{}).
+		Where("url = ? AND id <> ? AND NOT sandbox", normalized, websiteID).
+		Count(&owners).Error; err != nil {
+		return err
+	}
+	if owners > 0 {
+		return nil
+	}
+
+	var domains []models.Domain
+	if err := r.DB.Find(&domains).Error; err != nil {
+		return fmt.Errorf("failed to load domains: %w", err)
+	}
+
+	return r.DB.Model(&website).Updates(map[string]interface{}{
+		"url":       normalized,
+		"domain_id": matchDomain(domains, normalized),
+	}).Error
+}
+
 // FindDomainStatistics gathers statistics for a specific domain
 func (r *websiteRepository) FindDomainStatistics(domain string) (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
@@ -131,7 +200,7 @@ func (r *websiteRepository) FindDomainStatistics(domain string) (map[string]inte
 	var analysesCount int64
 	err := r.DB.Model(&models.Analysis{}).
 		Joins("JOIN websites ON analyses.website_id = websites.id").
-		Where("websites.url LIKE ?", "%"+domain+"%").
+		Where("websites.url LIKE ? AND NOT websites.sandbox", "%"+domain+"%").
 		Count(&analysesCount).Error
 
 	if err != nil {
@@ -144,7 +213,7 @@ func (r *websiteRepository) FindDomainStatistics(domain string) (map[string]inte
 	var avgScore float64
 	err = r.DB.Model(&models.Analysis{}).
 		Joins("JOIN websites ON analyses.website_id = websites.id").
-		Where("websites.url LIKE ?", "%"+domain+"%").
+		Where("websites.url LIKE ? AND NOT websites.sandbox", "%"+domain+"%").
 		Select("AVG(CAST(metadata->>'overall_score' AS FLOAT))").
 		Row().Scan(&avgScore)
 
@@ -157,7 +226,7 @@ func (r *websiteRepository) FindDomainStatistics(domain string) (map[string]inte
 	var lastAnalysis time.Time
 	err = r.DB.Model(&models.Analysis{}).
 		Joins("JOIN websites ON analyses.website_id = websites.id").
-		Where("websites.url LIKE ?", "%"+domain+"%").
+		Where("websites.url LIKE ? AND NOT websites.sandbox", "%"+domain+"%").
 		Order("analyses.created_at DESC").
 		Limit(1).
 		Select("analyses.created_at").
@@ -186,6 +255,7 @@ func (r *websiteRepository) FindPopularWebsites(limit int) ([]*models.Website, e
 	err := r.DB.Model(&models.Website{}).
 		Select("websites.*, COUNT(analyses.id) as analysis_count").
 		Joins("LEFT JOIN analyses ON websites.id = analyses.website_id").
+		Where("NOT websites.sandbox").
 		Group("websites.id").
 		Order("analysis_count DESC").
 		Limit(limit).
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WidgetOriginRepository defines operations for WidgetOrigin model
+type WidgetOriginRepository interface {
+	Repository
+	FindByUserID(userID uuid.UUID) ([]models.WidgetOrigin, error)
+	FindForUser(userID, id uuid.UUID) (*models.WidgetOrigin, error)
+	IsRegistered(origin string) (bool, error)
+	IsRegisteredForUser(userID uuid.UUID, origin string) (bool, error)
+}
+
+// widgetOriginRepository implements WidgetOriginRepository
+type widgetOriginRepository struct {
+	*BaseRepository
+}
+
+// NewWidgetOriginRepository creates a new widget origin repository
+func NewWidgetOriginRepository(db *gorm.DB, redisClient *redis.Client) WidgetOriginRepository {
+	return &widgetOriginRepository{
+		BaseRepository: NewBaseRepository(db, redisClient),
+	}
+}
+
+// FindByUserID returns the origins a user registered, ordered by origin
+func (r *widgetOriginRepository) FindByUserID(userID uuid.UUID) ([]models.WidgetOrigin, error) {
+	var origins []models.WidgetOrigin
+	err := r.DB.Where("user_id = ?", userID).Order("origin ASC").Find(&origins).Error
+	return origins, err
+}
+
+// FindForUser finds a registered origin by ID that belongs to the user
+func (r *widgetOriginRepository) FindForUser(userID, id uuid.UUID) (*models.WidgetOrigin, error) {
+	var origin models.WidgetOrigin
+	err := r.DB.Where("id = ? AND user_id = ?", id, userID).First(&origin).Error
+	if err != nil {
+		return nil, err
+	}
+	return &origin, nil
+}
+
+// IsRegistered reports whether any user registered the origin
+func (r *widgetOriginRepository) IsRegistered(origin string) (bool, error) {
+	var count int64
+	err := r.DB.Model(&models.WidgetOrigin{}).Where("origin = ?", origin).Count(&count).Error
+	return count > 0, err
+}
+
+// IsRegisteredForUser reports whether the user registered the origin
+func (r *widgetOriginRepository) IsRegisteredForUser(userID uuid.UUID, origin string) (bool, error) {
+	var count int64
+	err := r.DB.Model(&models.WidgetOrigin{}).Where("user_id = ? AND origin = ?", userID, origin).Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,206 @@
+// Package analytics exports flattened analysis facts to an analytical store
+// such as ClickHouse or BigQuery, so cross-site analytics can run without
+// loading the transactional Postgres database.
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Defaults of an export
+const (
+	DefaultTimeout     = 30 * time.Second
+	DefaultTablePrefix = "analysis_"
+	// maxAttempts is how many times facts are written before they are dropped
+	maxAttempts = 3
+	// retryDelay is the delay before the first retry, doubled for each next one
+	retryDelay = time.Second
+	// maxMetricDepth limits how deep nested analyzer results are flattened
+	maxMetricDepth = 4
+)
+
+// Table names, without the configured prefix
+const (
+	TableFacts   = "facts"
+	TableScores  = "scores"
+	TableMetrics = "metrics"
+)
+
+// ErrUnknownWriter is returned for a writer name that is not registered
+var ErrUnknownWriter = errors.New("unknown analytics writer")
+
+// AnalysisFact is one row per completed analysis
+type AnalysisFact struct {
+	AnalysisID     uuid.UUID `json:"analysis_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	URL            string    `json:"url"`
+	Host           string    `json:"host"`
+	CompletedAt    time.Time `json:"completed_at"`
+	OverallScore   float64   `json:"overall_score"`
+	Reused         bool      `json:"reused"` // results were copied from an analysis of identical content
+	IssueCount     int       `json:"issue_count"`
+	CriticalIssues int       `json:"critical_issues"`
+	HighIssues     int       `json:"high_issues"`
+	MediumIssues   int       `json:"medium_issues"`
+	LowIssues      int       `json:"low_issues"`
+}
+
+// ScoreFact is one row per analyzer category of an analysis
+type ScoreFact struct {
+	AnalysisID  uuid.UUID `json:"analysis_id"`
+	Host        string    `json:"host"`
+	CompletedAt time.Time `json:"completed_at"`
+	Category    string    `json:"category"`
+	Score       float64   `json:"score"`
+	IssueCount  int       `json:"issue_count"`
+}
+
+// MetricFact is one numeric value reported by an analyzer. Nested values are
+// named by their dotted path, e.g. "core_web_vitals.lcp".
+type MetricFact struct {
+	AnalysisID  uuid.UUID `json:"analysis_id"`
+	Host        string    `json:"host"`
+	CompletedAt time.Time `json:"completed_at"`
+	Category    string    `json:"category"`
+	Name        string    `json:"name"`
+	Value       float64   `json:"value"`
+}
+
+// Facts are the flattened facts of one analysis
+type Facts struct {
+	Analysis AnalysisFact
+	Scores   []ScoreFact
+	Metrics  []MetricFact
+}
+
+// Writer writes the facts of an analysis to an analytical store. Writes must
+// be idempotent per analysis, as failed writes are retried.
+type Writer interface {
+	Name() string
+	Write(ctx context.Context, facts *Facts) error
+}
+
+// Config configures a writer
+type Config struct {
+	BaseURL     string
+	Database    string // ClickHouse database, or BigQuery "project.dataset"
+	TablePrefix string
+	User        string
+	Token       string // ClickHouse password or BigQuery OAuth access token
+	Timeout     time.Duration
+}
+
+// table returns the full name of a table
+func (c Config) table(name string) string {
+	return c.TablePrefix + name
+}
+
+// Factory creates a writer from its configuration
+type Factory func(cfg Config) (Writer, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{}
+)
+
+// Register makes a writer available under a name
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories[name] = factory
+}
+
+// NewWriter creates the named writer
+func NewWriter(name string, cfg Config) (Writer, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownWriter, name)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.TablePrefix == "" {
+		cfg.TablePrefix = DefaultTablePrefix
+	}
+	return factory(cfg)
+}
+
+// Export writes facts, retrying failed writes with exponential backoff
+func Export(ctx context.Context, writer Writer, facts *Facts) error {
+	delay := retryDelay
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = writer.Write(ctx, facts); err == nil {
+			return nil
+		}
+		if attempt == maxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return fmt.Errorf("facts dropped after %d attempts: %w", maxAttempts, err)
+}
+
+// FlattenMetrics returns the numeric values of an analyzer result as metric
+// facts. Nested maps are flattened into dotted names; lists and non-numeric
+// values are skipped. The score is exported as a score fact instead.
+func FlattenMetrics(base MetricFact, result map[string]interface{}) []MetricFact {
+	var metrics []MetricFact
+	flatten(base, "", result, 1, &metrics)
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Name < metrics[j].Name })
+	return metrics
+}
+
+func flatten(base MetricFact, prefix string, values map[string]interface{}, depth int, metrics *[]MetricFact) {
+	for key, value := range values {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		} else if key == "score" {
+			continue
+		}
+
+		var number float64
+		switch v := value.(type) {
+		case float64:
+			number = v
+		case float32:
+			number = float64(v)
+		case int:
+			number = float64(v)
+		case int64:
+			number = float64(v)
+		case bool:
+			if v {
+				number = 1
+			}
+		case map[string]interface{}:
+			if depth < maxMetricDepth {
+				flatten(base, name, v, depth+1, metrics)
+			}
+			continue
+		default:
+			continue
+		}
+
+		metric := base
+		metric.Name = strings.ToLower(name)
+		metric.Value = number
+		*metrics = append(*metrics, metric)
+	}
+}
@@ -0,0 +1,135 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const bigQueryBaseURL = "https://bigquery.googleapis.com/bigquery/v2"
+
+func init() {
+	Register("bigquery", func(cfg Config) (Writer, error) {
+		project, dataset, ok := strings.Cut(cfg.Database, ".")
+		if !ok || project == "" || dataset == "" {
+			return nil, errors.New("bigquery writer requires a database of the form project.dataset")
+		}
+		baseURL := cfg.BaseURL
+		if baseURL == "" {
+			baseURL = bigQueryBaseURL
+		}
+		return &BigQueryWriter{
+			config:  cfg,
+			baseURL: strings.TrimRight(baseURL, "/"),
+			project: project,
+			dataset: dataset,
+			client:  &http.Client{Timeout: cfg.Timeout},
+		}, nil
+	})
+}
+
+// BigQueryWriter streams facts into BigQuery tables with the tabledata
+// insertAll API. Rows carry an insert ID derived from the analysis, so
+// BigQuery deduplicates retried inserts on a best-effort basis.
+type BigQueryWriter struct {
+	config  Config
+	baseURL string
+	project string
+	dataset string
+	client  *http.Client
+}
+
+type bigQueryRow struct {
+	InsertID string      `json:"insertId"`
+	JSON     interface{} `json:"json"`
+}
+
+// Name returns the writer name
+func (w *BigQueryWriter) Name() string {
+	return "bigquery"
+}
+
+// Write inserts the facts of an analysis into the three fact tables
+func (w *BigQueryWriter) Write(ctx context.Context, facts *Facts) error {
+	analysisID := facts.Analysis.AnalysisID.String()
+	if err := w.insert(ctx, TableFacts, []bigQueryRow{{InsertID: analysisID, JSON: facts.Analysis}}); err != nil {
+		return err
+	}
+
+	scores := make([]bigQueryRow, len(facts.Scores))
+	for i, score := range facts.Scores {
+		scores[i] = bigQueryRow{InsertID: analysisID + "/" + score.Category, JSON: score}
+	}
+	if err := w.insert(ctx, TableScores, scores); err != nil {
+		return err
+	}
+
+	metrics := make([]bigQueryRow, len(facts.Metrics))
+	for i, metric := range facts.Metrics {
+		metrics[i] = bigQueryRow{InsertID: analysisID + "/" + metric.Category + "/" + metric.Name, JSON: metric}
+	}
+	return w.insert(ctx, TableMetrics, metrics)
+}
+
+// insert streams rows into one table
+func (w *BigQueryWriter) insert(ctx context.Context, table string, rows []bigQueryRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"rows": rows})
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/projects/%s/datasets/%s/tables/%s/insertAll",
+		w.baseURL, url.PathEscape(w.project), url.PathEscape(w.dataset), url.PathEscape(w.config.table(table)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.config.Token)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Rejected rows are reported in a 200 response
+	var result struct {
+		InsertErrors []struct {
+			Index  int `json:"index"`
+			Errors []struct {
+				Reason  string `json:"reason"`
+				Message string `json:"message"`
+			} `json:"errors"`
+		} `json:"insertErrors"`
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("bigquery insert into %s returned status %d: %s", table, resp.StatusCode, result.Error.Message)
+	}
+	if len(result.InsertErrors) > 0 {
+		first := result.InsertErrors[0]
+		message := "unknown error"
+		if len(first.Errors) > 0 {
+			message = first.Errors[0].Message
+		}
+		return fmt.Errorf("bigquery rejected %d rows of %s, first at index %d: %s", len(result.InsertErrors), table, first.Index, message)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func init() {
+	Register("clickhouse", func(cfg Config) (Writer, error) {
+		if cfg.BaseURL == "" {
+			return nil, errors.New("clickhouse writer requires a URL")
+		}
+		return &ClickHouseWriter{
+			config: cfg,
+			client: &http.Client{Timeout: cfg.Timeout},
+		}, nil
+	})
+}
+
+// ClickHouseWriter inserts facts through the ClickHouse HTTP interface as
+// JSONEachRow. The tables are expected to use a ReplacingMergeTree keyed by
+// analysis ID (and category and name), so retried inserts collapse.
+type ClickHouseWriter struct {
+	config Config
+	client *http.Client
+}
+
+// Name returns the writer name
+func (w *ClickHouseWriter) Name() string {
+	return "clickhouse"
+}
+
+// Write inserts the facts of an analysis into the three fact tables
+func (w *ClickHouseWriter) Write(ctx context.Context, facts *Facts) error {
+	if err := w.insert(ctx, TableFacts, []interface{}{facts.Analysis}); err != nil {
+		return err
+	}
+
+	scores := make([]interface{}, len(facts.Scores))
+	for i, score := range facts.Scores {
+		scores[i] = score
+	}
+	if err := w.insert(ctx, TableScores, scores); err != nil {
+		return err
+	}
+
+	metrics := make([]interface{}, len(facts.Metrics))
+	for i, metric := range facts.Metrics {
+		metrics[i] = metric
+	}
+	return w.insert(ctx, TableMetrics, metrics)
+}
+
+// insert writes rows into one table
+func (w *ClickHouseWriter) insert(ctx context.Context, table string, rows []interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, row := range rows {
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+	}
+
+	name := w.config.table(table)
+	if w.config.Database != "" {
+		name = w.config.Database + "." + name
+	}
+	params := url.Values{}
+	params.Set("query", "INSERT INTO "+name+" FORMAT JSONEachRow")
+	// Times are encoded as RFC 3339
+	params.Set("date_time_input_format", "best_effort")
+
+	endpoint := strings.TrimRight(w.config.BaseURL, "/") + "/?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return err
+	}
+	if w.config.User != "" {
+		req.Header.Set("X-ClickHouse-User", w.config.User)
+	}
+	if w.config.Token != "" {
+		req.Header.Set("X-ClickHouse-Key", w.config.Token)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("clickhouse insert into %s returned status %d: %s", name, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Alert metrics that do not come from a single analyzer. Scores of individual
+// analyzers are referred to as "<analyzer>_score", e.g. "seo_score".
+const (
+	AlertMetricOverallScore     = "overall_score"
+	AlertMetricLoadTimeMs       = "load_time_ms"
+	AlertMetricHighIssues       = "high_issues"
+	AlertMetricBudgetViolations = "budget_violations"
+)
+
+// Alert metrics of tracked keywords. A keyword that is not ranked within the
+// checked depth counts as ranked one position below it.
+const (
+	AlertMetricRankPosition = "rank_position"
+	AlertMetricRankChange   = "rank_change" // positions lost since the previous check; negative when gained
+)
+
+// AlertRule triggers an alert when a value of an analysis result compares
+// to the threshold with the operator, e.g. overall_score < 70
+type AlertRule struct {
+	Name      string  `json:"name,omitempty"`
+	Metric    string  `json:"metric"`
+	Operator  string  `json:"operator"` // <, <=, >, >=
+	Threshold float64 `json:"threshold"`
+}
+
+// TriggeredAlert is an alert rule together with the value that triggered it
+type TriggeredAlert struct {
+	AlertRule
+	Value float64 `json:"value"`
+}
+
+// Validate checks that the rule refers to a known metric and operator
+func (r AlertRule) Validate() error {
+	switch r.Operator {
+	case "<", "<=", ">", ">=":
+	default:
+		return fmt.Errorf("alert operator must be one of <, <=, >, >=")
+	}
+
+	switch r.Metric {
+	case AlertMetricOverallScore, AlertMetricLoadTimeMs, AlertMetricHighIssues, AlertMetricBudgetViolations,
+		AlertMetricRankPosition, AlertMetricRankChange:
+		return nil
+	}
+	if name, ok := strings.CutSuffix(r.Metric, "_score"); ok {
+		for _, t := range AllAnalyzerTypes {
+			if string(t) == name {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("unknown alert metric: %s", r.Metric)
+}
+
+// Evaluate returns the value of the rule's metric and whether it triggers
+// the alert. Rules whose metric was not measured never trigger.
+func (r AlertRule) Evaluate(values map[string]float64) (float64, bool) {
+	value, ok := values[r.Metric]
+	if !ok {
+		return 0, false
+	}
+	switch r.Operator {
+	case "<":
+		return value, value < r.Threshold
+	case "<=":
+		return value, value <= r.Threshold
+	case ">":
+		return value, value > r.Threshold
+	case ">=":
+		return value, value >= r.Threshold
+	}
+	return value, false
+}
+
+// AlertValues collects the values alert rules can refer to
+func AlertValues(loadTime time.Duration, overallScore float64, results map[AnalyzerType]map[string]interface{}, highIssues, budgetViolations int) map[string]float64 {
+	values := map[string]float64{
+		AlertMetricOverallScore:     overallScore,
+		AlertMetricLoadTimeMs:       float64(loadTime.Milliseconds()),
+		AlertMetricHighIssues:       float64(highIssues),
+		AlertMetricBudgetViolations: float64(budgetViolations),
+	}
+	for t, result := range results {
+		if score, ok := result["score"].(float64); ok {
+			values[string(t)+"_score"] = score
+		}
+	}
+	return values
+}
+
+// RankAlertValues collects the values alert rules of a tracked keyword can
+// refer to. The change is only measured when there is a previous check.
+func RankAlertValues(position, previous *int, hasPrevious bool, depth int) map[string]float64 {
+	rank := func(p *int) float64 {
+		if p == nil {
+			return float64(depth + 1)
+		}
+		return float64(*p)
+	}
+	values := map[string]float64{
+		AlertMetricRankPosition: rank(position),
+	}
+	if hasPrevious {
+		values[AlertMetricRankChange] = rank(position) - rank(previous)
+	}
+	return values
+}
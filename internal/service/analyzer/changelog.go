@@ -0,0 +1,159 @@
+package analyzer
+
+import "time"
+
+// Виды изменений в журнале
+const (
+	ChangeKindAnalyzer   = "analyzer"   // новый анализатор или новые проверки
+	ChangeKindThreshold  = "threshold"  // изменены пороги или бюджеты
+	ChangeKindScoring    = "scoring"    // изменен расчет оценки
+	ChangeKindSignatures = "signatures" // обновлен набор сигнатур
+)
+
+// ChangelogEntry описывает изменение поведения анализаторов, которое может
+// изменить оценки сайта без изменений на самом сайте
+type ChangelogEntry struct {
+	Version       string    `json:"version"`
+	EffectiveDate time.Time `json:"effective_date"`
+	Kind          string    `json:"kind"`
+	// Components - затронутые анализаторы или наборы сигнатур
+	Components   []string `json:"components"`
+	Summary      string   `json:"summary"`
+	AffectsScore bool     `json:"affects_score"`
+}
+
+// changeDate возвращает дату вступления изменения в силу (UTC)
+func changeDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Changelog - журнал изменений анализаторов от старых к новым. Новая запись
+// добавляется при каждом изменении проверок, порогов, расчета оценки или
+// наборов сигнатур; ее версия становится текущей ScoringVersion.
+var Changelog = []ChangelogEntry{
+	{
+		Version:       "1.0.0",
+		EffectiveDate: changeDate(2026, time.October, 15),
+		Kind:          ChangeKindScoring,
+		Components:    []string{string(LighthouseType), string(SEOType), string(PerformanceType), string(AccessibilityType), string(SecurityType), string(StructureType), string(MobileType), string(ContentType)},
+		Summary:       "Initial analyzers. Category scores start at 100 and lose 15, 10 or 5 points per high, medium or low severity issue",
+		AffectsScore:  true,
+	},
+	{
+		Version:       "1.1.0",
+		EffectiveDate: changeDate(2026, time.October, 15),
+		Kind:          ChangeKindAnalyzer,
+		Components:    []string{string(SEOType)},
+		Summary:       "Keyword density is computed on the main content without navigation and footer; punctuation is stripped before short words are skipped",
+		AffectsScore:  false,
+	},
+	{
+		Version:       "1.2.0",
+		EffectiveDate: changeDate(2026, time.October, 15),
+		Kind:          ChangeKindAnalyzer,
+		Components:    []string{string(GeoType)},
+		Summary:       "New geo analyzer detecting locale-dependent content variants and verifying hreflang annotations",
+		AffectsScore:  true,
+	},
+	{
+		Version:       "1.3.0",
+		EffectiveDate: changeDate(2026, time.October, 15),
+		Kind:          ChangeKindThreshold,
+		Components:    []string{"presets"},
+		Summary:       "Analysis presets with load time, overall score and category score budgets; budget violations are reported per analysis",
+		AffectsScore:  false,
+	},
+	{
+		Version:       "1.4.0",
+		EffectiveDate: changeDate(2026, time.October, 15),
+		Kind:          ChangeKindAnalyzer,
+		Components:    []string{string(ChecklistType)},
+		Summary:       "New pre-launch checklist analyzer with pass/fail go-live gates",
+		AffectsScore:  true,
+	},
+	{
+		Version:       "1.5.0",
+		EffectiveDate: changeDate(2026, time.October, 16),
+		Kind:          ChangeKindAnalyzer,
+		Components:    []string{string(InfrastructureType)},
+		Summary:       "New infrastructure analyzer detecting hosting, CDN and server location",
+		AffectsScore:  true,
+	},
+	{
+		Version:       "1.5.0",
+		EffectiveDate: changeDate(2026, time.October, 16),
+		Kind:          ChangeKindSignatures,
+		Components:    []string{"cdn_signatures"},
+		Summary:       "CDN detection by response headers and Server/Via signatures",
+		AffectsScore:  false,
+	},
+	{
+		Version:       "1.6.0",
+		EffectiveDate: changeDate(2026, time.October, 16),
+		Kind:          ChangeKindAnalyzer,
+		Components:    []string{string(InfrastructureType)},
+		Summary:       "IPv6 reachability and dual-stack latency checks",
+		AffectsScore:  true,
+	},
+	{
+		Version:       "1.7.0",
+		EffectiveDate: changeDate(2026, time.October, 16),
+		Kind:          ChangeKindAnalyzer,
+		Components:    []string{string(SEOType)},
+		Summary:       "Link hygiene checks for tracking parameters, broken mailto/tel links, malformed URLs and javascript: hrefs",
+		AffectsScore:  true,
+	},
+	{
+		Version:       "1.8.0",
+		EffectiveDate: changeDate(2026, time.October, 16),
+		Kind:          ChangeKindAnalyzer,
+		Components:    []string{string(PerformanceType)},
+		Summary:       "Large images served through an image CDN without transformations are reported as a low severity issue with suggested URLs",
+		AffectsScore:  true,
+	},
+	{
+		Version:       "1.9.0",
+		EffectiveDate: changeDate(2026, time.October, 16),
+		Kind:          ChangeKindAnalyzer,
+		Components:    []string{string(NoScriptType)},
+		Summary:       "New noscript analyzer comparing the page with JavaScript disabled against the rendered page",
+		AffectsScore:  true,
+	},
+	{
+		Version:       "1.10.0",
+		EffectiveDate: changeDate(2026, time.October, 16),
+		Kind:          ChangeKindAnalyzer,
+		Components:    []string{string(InfrastructureType)},
+		Summary:       "Responses to Googlebot, browser and analyzer user agents are compared to detect cloaking and crawler blocking",
+		AffectsScore:  true,
+	},
+	{
+		Version:       "1.11.0",
+		EffectiveDate: changeDate(2026, time.October, 16),
+		Kind:          ChangeKindAnalyzer,
+		Components:    []string{string(JourneysType)},
+		Summary:       "New journeys analyzer scoring user journeys supplied with an analysis by completion, step timing, layout shifts and console errors",
+		AffectsScore:  true,
+	},
+	{
+		Version:       "1.12.0",
+		EffectiveDate: changeDate(2026, time.October, 16),
+		Kind:          ChangeKindAnalyzer,
+		Components:    []string{string(CustomRulesType)},
+		Summary:       "New custom_rules analyzer running the selector and pattern checks an organization defines on every page it analyzes",
+		AffectsScore:  true,
+	},
+	{
+		Version:       "1.13.0",
+		EffectiveDate: changeDate(2026, time.October, 16),
+		Kind:          ChangeKindAnalyzer,
+		Components:    []string{string(DomainType)},
+		Summary:       "New domain analyzer finding subdomains from certificate SANs, DNS and page links and reporting unreachable ones, invalid or expiring certificates, default server pages and takeover risks",
+		AffectsScore:  true,
+	},
+}
+
+// ScoringVersion возвращает версию последнего изменения анализаторов
+func ScoringVersion() string {
+	return Changelog[len(Changelog)-1].Version
+}
@@ -0,0 +1,52 @@
+package analyzer
+
+import (
+	"context"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/chaos"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// ChaosAnalyzer оборачивает анализатор и внедряет сбои для тестирования
+// устойчивости: задержки, ошибки, таймауты и частичные результаты
+type ChaosAnalyzer struct {
+	Analyzer
+}
+
+// NewChaosAnalyzer создает обертку с внедрением сбоев
+func NewChaosAnalyzer(analyzer Analyzer) *ChaosAnalyzer {
+	return &ChaosAnalyzer{Analyzer: analyzer}
+}
+
+// Analyze применяет сбой для "analyzer.<тип>" (для Lighthouse также
+// "lighthouse") или "analyzer", затем выполняет анализ
+func (a *ChaosAnalyzer) Analyze(ctx context.Context, data *parser.WebsiteData, prevResults map[AnalyzerType]map[string]interface{}) (map[string]interface{}, error) {
+	targets := []string{chaos.TargetAnalyzer + "." + string(a.GetType())}
+	if a.GetType() == LighthouseType {
+		targets = append(targets, chaos.TargetLighthouse)
+	}
+	targets = append(targets, chaos.TargetAnalyzer)
+
+	fault, ok := chaos.Lookup(ctx, targets...)
+	if !ok {
+		return a.Analyzer.Analyze(ctx, data, prevResults)
+	}
+	if err := fault.Apply(ctx); err != nil {
+		return nil, err
+	}
+
+	result, err := a.Analyzer.Analyze(ctx, data, prevResults)
+	if err != nil || fault.Kind != chaos.KindPartial {
+		return result, err
+	}
+
+	// Частичный сбой: результат без итоговой оценки
+	partial := make(map[string]interface{}, len(result))
+	for key, value := range result {
+		if key != "score" {
+			partial[key] = value
+		}
+	}
+	partial["chaos_partial"] = true
+	return partial, nil
+}
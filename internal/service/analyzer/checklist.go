@@ -0,0 +1,448 @@
+package analyzer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/google/uuid"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+const (
+	// checklistFetchTimeout ограничивает время одного проверочного запроса
+	checklistFetchTimeout = 15 * time.Second
+	// maxChecklistBodySize ограничивает объем читаемого ответа
+	maxChecklistBodySize = 1 << 20
+)
+
+// Ключи пунктов чек-листа запуска
+const (
+	CheckNoIndex     = "no_noindex"
+	CheckSSL         = "valid_ssl"
+	CheckNotFound    = "not_found_page"
+	CheckFavicon     = "favicon"
+	CheckAnalytics   = "analytics"
+	CheckSitemap     = "sitemap"
+	CheckContactInfo = "contact_info"
+)
+
+var (
+	// analyticsMarkers - фрагменты кода и адреса известных систем аналитики
+	analyticsMarkers = map[string]string{
+		"googletagmanager.com":          "Google Tag Manager",
+		"google-analytics.com":          "Google Analytics",
+		"gtag(":                         "Google Analytics",
+		"mc.yandex.ru/metrika":          "Yandex Metrica",
+		"plausible.io/js":               "Plausible",
+		"static.cloudflareinsights.com": "Cloudflare Web Analytics",
+		"matomo.js":                     "Matomo",
+		"piwik.js":                      "Matomo",
+		"cdn.segment.com":               "Segment",
+		"static.hotjar.com":             "Hotjar",
+		"connect.facebook.net":          "Meta Pixel",
+		"cdn.mxpnl.com":                 "Mixpanel",
+		"cdn.amplitude.com":             "Amplitude",
+	}
+
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`\+\d[\d\s().-]{8,}\d`)
+
+	// contactPageWords - слова, которыми обычно называют страницу контактов
+	contactPageWords = []string{"contact", "kontakt", "контакт", "impressum", "about-us"}
+)
+
+// ChecklistItem - результат проверки одного критерия готовности к запуску
+type ChecklistItem struct {
+	Key     string                 `json:"key"`
+	Title   string                 `json:"title"`
+	Passed  bool                   `json:"passed"`
+	Details string                 `json:"details"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
+// ChecklistAnalyzer проверяет фиксированный набор критериев готовности сайта
+// к запуску и возвращает результат "пройдено / не пройдено" без баллов
+type ChecklistAnalyzer struct {
+	*BaseAnalyzer
+	client *http.Client
+}
+
+// NewChecklistAnalyzer создает новый анализатор чек-листа запуска
+func NewChecklistAnalyzer() *ChecklistAnalyzer {
+	return &ChecklistAnalyzer{
+		BaseAnalyzer: NewBaseAnalyzer(ChecklistType),
+		client:       &http.Client{Timeout: checklistFetchTimeout},
+	}
+}
+
+// Analyze выполняет все проверки чек-листа
+func (a *ChecklistAnalyzer) Analyze(ctx context.Context, data *parser.WebsiteData, prevResults map[AnalyzerType]map[string]interface{}) (map[string]interface{}, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	pageURL := data.FinalURL
+	if pageURL == "" {
+		pageURL = data.URL
+	}
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page URL: %w", err)
+	}
+
+	html := data.RawHTML
+	if html == "" {
+		html = data.HTML
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	// Проверки с сетевыми запросами выполняются параллельно
+	checks := []func() ChecklistItem{
+		func() ChecklistItem { return a.checkNoIndex(ctx, page, doc) },
+		func() ChecklistItem { return a.checkSSL(ctx, page) },
+		func() ChecklistItem { return a.checkNotFoundPage(ctx, page) },
+		func() ChecklistItem { return a.checkFavicon(ctx, page, doc) },
+		func() ChecklistItem { return a.checkAnalytics(data, html) },
+		func() ChecklistItem { return a.checkSitemap(ctx, page) },
+		func() ChecklistItem { return a.checkContactInfo(data, doc) },
+	}
+	items := make([]ChecklistItem, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check func() ChecklistItem) {
+			defer wg.Done()
+			items[i] = check()
+		}(i, check)
+	}
+	wg.Wait()
+
+	passedCount := 0
+	for _, item := range items {
+		if item.Passed {
+			passedCount++
+			continue
+		}
+		a.AddIssue(map[string]interface{}{
+			"type":        "checklist_" + item.Key,
+			"severity":    "high",
+			"description": item.Title + ": " + item.Details,
+			"url":         pageURL,
+		})
+	}
+
+	a.SetMetric("items", items)
+	a.SetMetric("passed_count", passedCount)
+	a.SetMetric("total", len(items))
+	a.SetMetric("passed", passedCount == len(items))
+
+	return a.GetMetrics(), nil
+}
+
+// fetch выполняет GET-запрос и читает не более maxChecklistBodySize байт ответа
+func (a *ChecklistAnalyzer) fetch(ctx context.Context, target string) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", parser.DesktopDevice.UserAgent)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxChecklistBodySize))
+	return resp, body, err
+}
+
+// checkNoIndex проверяет, что страница не закрыта от индексации
+func (a *ChecklistAnalyzer) checkNoIndex(ctx context.Context, page *url.URL, doc *goquery.Document) ChecklistItem {
+	item := ChecklistItem{Key: CheckNoIndex, Title: "Страница открыта для индексации"}
+
+	var directives []string
+	doc.Find(`meta[name]`).Each(func(_ int, s *goquery.Selection) {
+		name, _ := s.Attr("name")
+		name = strings.ToLower(name)
+		if name != "robots" && name != "googlebot" && name != "yandex" {
+			return
+		}
+		if content, _ := s.Attr("content"); strings.Contains(strings.ToLower(content), "noindex") {
+			directives = append(directives, fmt.Sprintf(`<meta name="%s" content="%s">`, name, content))
+		}
+	})
+
+	if resp, _, err := a.fetch(ctx, page.String()); err == nil {
+		for _, value := range resp.Header.Values("X-Robots-Tag") {
+			if strings.Contains(strings.ToLower(value), "noindex") {
+				directives = append(directives, "X-Robots-Tag: "+value)
+			}
+		}
+	}
+
+	if len(directives) > 0 {
+		item.Details = "Страница запрещает индексацию: " + strings.Join(directives, ", ")
+		item.Data = map[string]interface{}{"directives": directives}
+		a.AddRecommendation("Удалите noindex из мета-тега robots и заголовка X-Robots-Tag перед запуском")
+		return item
+	}
+	item.Passed = true
+	item.Details = "Директивы noindex не найдены"
+	return item
+}
+
+// checkSSL проверяет, что сайт работает по HTTPS с действительным сертификатом
+func (a *ChecklistAnalyzer) checkSSL(ctx context.Context, page *url.URL) ChecklistItem {
+	item := ChecklistItem{Key: CheckSSL, Title: "Действительный SSL-сертификат"}
+
+	secure := *page
+	secure.Scheme = "https"
+	resp, _, err := a.fetch(ctx, secure.String())
+	if err != nil {
+		item.Details = "Не удалось установить защищенное соединение: " + err.Error()
+		a.AddRecommendation("Установите действительный SSL-сертификат, например бесплатный сертификат Let's Encrypt")
+		return item
+	}
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		item.Details = "Сервер не предоставил сертификат"
+		a.AddRecommendation("Установите действительный SSL-сертификат, например бесплатный сертификат Let's Encrypt")
+		return item
+	}
+
+	cert := resp.TLS.PeerCertificates[0]
+	daysLeft := int(time.Until(cert.NotAfter).Hours() / 24)
+	item.Data = map[string]interface{}{
+		"issuer":     cert.Issuer.CommonName,
+		"expires_at": cert.NotAfter,
+		"days_left":  daysLeft,
+	}
+
+	if page.Scheme != "https" {
+		item.Details = "Сертификат действителен, но страница открывается по HTTP без перенаправления на HTTPS"
+		a.AddRecommendation("Настройте постоянное перенаправление (301) всех HTTP-запросов на HTTPS")
+		return item
+	}
+	item.Passed = true
+	item.Details = fmt.Sprintf("Сертификат действителен еще %d дн.", daysLeft)
+	return item
+}
+
+// checkNotFoundPage проверяет, что несуществующие адреса отдают статус 404
+func (a *ChecklistAnalyzer) checkNotFoundPage(ctx context.Context, page *url.URL) ChecklistItem {
+	item := ChecklistItem{Key: CheckNotFound, Title: "Страница 404"}
+
+	probe := page.ResolveReference(&url.URL{Path: "/" + uuid.NewString()})
+	resp, body, err := a.fetch(ctx, probe.String())
+	if err != nil {
+		item.Details = "Не удалось запросить несуществующую страницу: " + err.Error()
+		return item
+	}
+
+	item.Data = map[string]interface{}{
+		"probe_url":   probe.String(),
+		"status_code": resp.StatusCode,
+	}
+	switch {
+	case resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusGone:
+		item.Details = fmt.Sprintf("Несуществующая страница возвращает статус %d вместо 404", resp.StatusCode)
+		a.AddRecommendation("Настройте сервер так, чтобы несуществующие страницы возвращали статус 404")
+	case len(strings.TrimSpace(string(body))) == 0:
+		item.Details = "Сервер возвращает 404 с пустой страницей"
+		a.AddRecommendation("Добавьте собственную страницу 404 с навигацией по сайту и поиском")
+	default:
+		item.Passed = true
+		item.Details = "Несуществующие страницы возвращают 404"
+	}
+	return item
+}
+
+// checkFavicon проверяет наличие доступной иконки сайта
+func (a *ChecklistAnalyzer) checkFavicon(ctx context.Context, page *url.URL, doc *goquery.Document) ChecklistItem {
+	item := ChecklistItem{Key: CheckFavicon, Title: "Favicon"}
+
+	iconURL := page.ResolveReference(&url.URL{Path: "/favicon.ico"})
+	doc.Find("link[rel][href]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		rel, _ := s.Attr("rel")
+		for _, value := range strings.Fields(strings.ToLower(rel)) {
+			if value == "icon" || value == "apple-touch-icon" {
+				href, _ := s.Attr("href")
+				if ref, err := page.Parse(href); err == nil {
+					iconURL = ref
+				}
+				return false
+			}
+		}
+		return true
+	})
+
+	item.Data = map[string]interface{}{"url": iconURL.String()}
+	if iconURL.Scheme == "data" {
+		item.Passed = true
+		item.Details = "Иконка встроена в страницу"
+		return item
+	}
+
+	resp, body, err := a.fetch(ctx, iconURL.String())
+	if err != nil || resp.StatusCode != http.StatusOK || len(body) == 0 {
+		item.Details = "Иконка сайта не найдена по адресу " + iconURL.String()
+		a.AddRecommendation("Добавьте favicon и укажите его через <link rel=\"icon\" href=\"...\">")
+		return item
+	}
+	item.Passed = true
+	item.Details = "Иконка доступна по адресу " + iconURL.String()
+	return item
+}
+
+// checkAnalytics проверяет, что на странице установлена система аналитики
+func (a *ChecklistAnalyzer) checkAnalytics(data *parser.WebsiteData, html string) ChecklistItem {
+	item := ChecklistItem{Key: CheckAnalytics, Title: "Система аналитики"}
+
+	found := make(map[string]bool)
+	for _, tech := range data.Technologies {
+		if strings.EqualFold(tech.Category, "Analytics") {
+			found[tech.Name] = true
+		}
+	}
+	lower := strings.ToLower(html)
+	for marker, name := range analyticsMarkers {
+		if strings.Contains(lower, marker) {
+			found[name] = true
+		}
+	}
+
+	if len(found) == 0 {
+		item.Details = "Код систем аналитики на странице не найден"
+		a.AddRecommendation("Установите систему веб-аналитики, чтобы отслеживать посещаемость после запуска")
+		return item
+	}
+
+	tools := make([]string, 0, len(found))
+	for name := range found {
+		tools = append(tools, name)
+	}
+	sort.Strings(tools)
+	item.Passed = true
+	item.Details = "Найдено: " + strings.Join(tools, ", ")
+	item.Data = map[string]interface{}{"tools": tools}
+	return item
+}
+
+// checkSitemap проверяет, что карта сайта объявлена в robots.txt и доступна
+func (a *ChecklistAnalyzer) checkSitemap(ctx context.Context, page *url.URL) ChecklistItem {
+	item := ChecklistItem{Key: CheckSitemap, Title: "Карта сайта"}
+
+	var declared []string
+	robotsURL := page.ResolveReference(&url.URL{Path: "/robots.txt"})
+	if resp, body, err := a.fetch(ctx, robotsURL.String()); err == nil && resp.StatusCode == http.StatusOK {
+		scanner := bufio.NewScanner(strings.NewReader(string(body)))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if len(line) > 8 && strings.EqualFold(line[:8], "sitemap:") {
+				declared = append(declared, strings.TrimSpace(line[8:]))
+			}
+		}
+	}
+
+	if len(declared) == 0 {
+		fallback := page.ResolveReference(&url.URL{Path: "/sitemap.xml"})
+		if resp, _, err := a.fetch(ctx, fallback.String()); err == nil && resp.StatusCode == http.StatusOK {
+			item.Details = "Файл " + fallback.String() + " существует, но не указан в robots.txt"
+			item.Data = map[string]interface{}{"sitemap": fallback.String()}
+		} else {
+			item.Details = "Карта сайта не найдена"
+		}
+		a.AddRecommendation("Создайте sitemap.xml, укажите его в robots.txt директивой Sitemap: и отправьте в Google Search Console и Яндекс Вебмастер")
+		return item
+	}
+
+	item.Data = map[string]interface{}{"sitemaps": declared}
+	for _, sitemap := range declared {
+		resp, _, err := a.fetch(ctx, sitemap)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			item.Details = "Карта сайта из robots.txt недоступна: " + sitemap
+			a.AddRecommendation("Проверьте, что все карты сайта из robots.txt доступны и возвращают статус 200")
+			return item
+		}
+	}
+	item.Passed = true
+	item.Details = "Карта сайта указана в robots.txt: " + strings.Join(declared, ", ")
+	return item
+}
+
+// checkContactInfo проверяет наличие контактных данных или страницы контактов
+func (a *ChecklistAnalyzer) checkContactInfo(data *parser.WebsiteData, doc *goquery.Document) ChecklistItem {
+	item := ChecklistItem{Key: CheckContactInfo, Title: "Контактная информация"}
+
+	var found []string
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		href = strings.ToLower(strings.TrimSpace(href))
+		switch {
+		case strings.HasPrefix(href, "mailto:"):
+			found = append(found, "email")
+		case strings.HasPrefix(href, "tel:"):
+			found = append(found, "phone")
+		}
+	})
+	if emailPattern.MatchString(data.TextContent) {
+		found = append(found, "email")
+	}
+	if phonePattern.MatchString(data.TextContent) {
+		found = append(found, "phone")
+	}
+	for _, link := range data.Links {
+		if !link.IsInternal {
+			continue
+		}
+		target := strings.ToLower(link.URL + " " + link.Text)
+		for _, word := range contactPageWords {
+			if strings.Contains(target, word) {
+				found = append(found, "contact_page")
+				break
+			}
+		}
+	}
+
+	if len(found) == 0 {
+		item.Details = "На странице нет адреса электронной почты, телефона или ссылки на страницу контактов"
+		a.AddRecommendation("Разместите контактные данные или ссылку на страницу контактов, например в шапке или подвале сайта")
+		return item
+	}
+
+	kinds := uniqueStrings(found)
+	item.Passed = true
+	item.Details = "Найдено: " + strings.Join(kinds, ", ")
+	item.Data = map[string]interface{}{"found": kinds}
+	return item
+}
+
+// uniqueStrings возвращает отсортированные уникальные значения
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var result []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
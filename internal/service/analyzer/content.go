@@ -2,16 +2,25 @@ package analyzer
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
 	"regexp"
 	"strings"
 	"unicode"
 
+	"github.com/chynybekuuludastan/website_optimizer/internal/config"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/originality"
 	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
 )
 
 // ContentAnalyzer анализирует качество и читаемость контента
 type ContentAnalyzer struct {
 	*BaseAnalyzer
+	// originality проверяет, не скопирован ли текст с других сайтов (опционально)
+	originality   originality.Checker
+	minSimilarity float64
 }
 
 // NewContentAnalyzer создает новый анализатор контента
@@ -102,6 +111,11 @@ func (a *ContentAnalyzer) Analyze(ctx context.Context, data *parser.WebsiteData,
 		a.analyzeTextToHtmlRatio(text, data.HTML)
 	}
 
+	// Проверка уникальности текста через внешний сервис
+	if a.originality != nil {
+		a.analyzeOriginality(ctx, data, text)
+	}
+
 	// Расчет общей оценки
 	score := a.CalculateScore()
 	a.SetMetric("score", score)
@@ -414,6 +428,77 @@ func (a *ContentAnalyzer) analyzeTextToHtmlRatio(text string, html string) {
 	}
 }
 
+// SetOriginalityChecker включает проверку уникальности текста. Источники,
+// совпадающие с текстом страницы не менее чем на minSimilarity процентов,
+// добавляются как проблемы.
+func (a *ContentAnalyzer) SetOriginalityChecker(checker originality.Checker, minSimilarity float64) {
+	a.originality = checker
+	a.minSimilarity = minSimilarity
+}
+
+// newOriginalityChecker создает проверку уникальности текста, если она
+// настроена. Ошибки конфигурации отключают проверку.
+func newOriginalityChecker(cfg *config.Config) originality.Checker {
+	if cfg == nil || cfg.OriginalityProvider == "" {
+		return nil
+	}
+	checker, err := originality.NewChecker(originality.Config{
+		Provider: cfg.OriginalityProvider,
+		BaseURL:  cfg.OriginalityAPIURL,
+		APIUser:  cfg.OriginalityAPIUser,
+		APIKey:   cfg.OriginalityAPIKey,
+		Samples:  cfg.OriginalitySamples,
+	})
+	if err != nil {
+		log.Printf("Originality check disabled: %v", err)
+		return nil
+	}
+	return checker
+}
+
+// analyzeOriginality ищет копии выборки предложений страницы на других сайтах
+func (a *ContentAnalyzer) analyzeOriginality(ctx context.Context, data *parser.WebsiteData, text string) {
+	pageURL := data.FinalURL
+	if pageURL == "" {
+		pageURL = data.URL
+	}
+
+	report, err := a.originality.Check(ctx, pageURL, text)
+	if err != nil {
+		if !errors.Is(err, originality.ErrNoText) {
+			log.Printf("Originality check of %s failed: %v", pageURL, err)
+			a.SetMetric("originality_error", err.Error())
+		}
+		return
+	}
+	a.SetMetric("originality", report)
+
+	copied := 0
+	for _, match := range report.Matches {
+		if match.Similarity < a.minSimilarity {
+			continue
+		}
+		copied++
+		severity := "medium"
+		if match.Similarity >= 50 {
+			severity = "high"
+		}
+		a.AddIssue(map[string]interface{}{
+			"type":        "copied_content",
+			"severity":    severity,
+			"description": fmt.Sprintf("Текст страницы совпадает с %s на %.0f%%", match.URL, match.Similarity),
+			"url":         match.URL,
+			"title":       match.Title,
+			"similarity":  match.Similarity,
+		})
+	}
+	a.SetMetric("copied_content_sources", copied)
+
+	if copied > 0 {
+		a.AddRecommendation("Перепишите текст, найденный на других сайтах, или укажите первоисточник через rel=canonical, чтобы страница не считалась дублем")
+	}
+}
+
 // splitIntoSentences разбивает текст на предложения
 func splitIntoSentences(text string) []string {
 	// Упрощённое разделение на предложения (не идеальное, но достаточное)
@@ -0,0 +1,223 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+const (
+	// maxGapTopics ограничивает число тем в отчете
+	maxGapTopics = 30
+	// maxGapHeadings ограничивает число разделов конкурентов в отчете
+	maxGapHeadings = 20
+)
+
+// ContentGapTopic - тема, которую раскрывают конкуренты, но не страница пользователя
+type ContentGapTopic struct {
+	Phrase      string   `json:"phrase"`
+	Coverage    float64  `json:"coverage"`
+	Mentions    int      `json:"mentions"`
+	Competitors []string `json:"competitors"`
+}
+
+// ContentGapHeading - раздел конкурента, тема которого не раскрыта на странице
+type ContentGapHeading struct {
+	Text       string `json:"text"`
+	Level      int    `json:"level"`
+	Competitor string `json:"competitor"`
+}
+
+// CompetitorSummary кратко описывает страницу конкурента
+type CompetitorSummary struct {
+	URL          string `json:"url"`
+	Title        string `json:"title,omitempty"`
+	WordCount    int    `json:"word_count,omitempty"`
+	HeadingCount int    `json:"heading_count,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// ContentGapReport - результат сравнения тематического охвата страницы с конкурентами
+type ContentGapReport struct {
+	Keyword         string              `json:"keyword"`
+	URL             string              `json:"url"`
+	WordCount       int                 `json:"word_count"`
+	Competitors     []CompetitorSummary `json:"competitors"`
+	MissingTopics   []ContentGapTopic   `json:"missing_topics"`
+	MissingHeadings []ContentGapHeading `json:"missing_headings"`
+	SharedTopics    []string            `json:"shared_topics"`
+}
+
+// FindContentGaps сравнивает темы страницы с темами конкурентов. Тема считается
+// пробелом, если ее раскрывает хотя бы половина конкурентов, а страница - нет.
+// Конкуренты, которые не удалось загрузить, передаются в failed (URL -> ошибка)
+// и в сравнении не участвуют.
+func FindContentGaps(keyword string, page *parser.PageTopics, competitors []*parser.PageTopics, failed map[string]string) ContentGapReport {
+	report := ContentGapReport{
+		Keyword:         keyword,
+		URL:             page.URL,
+		WordCount:       page.WordCount,
+		MissingTopics:   []ContentGapTopic{},
+		MissingHeadings: []ContentGapHeading{},
+		SharedTopics:    []string{},
+	}
+
+	for _, competitor := range competitors {
+		report.Competitors = append(report.Competitors, CompetitorSummary{
+			URL:          competitor.URL,
+			Title:        competitor.Title,
+			WordCount:    competitor.WordCount,
+			HeadingCount: len(competitor.Headings),
+		})
+	}
+	failedURLs := make([]string, 0, len(failed))
+	for url := range failed {
+		failedURLs = append(failedURLs, url)
+	}
+	sort.Strings(failedURLs)
+	for _, url := range failedURLs {
+		report.Competitors = append(report.Competitors, CompetitorSummary{URL: url, Error: failed[url]})
+	}
+	if len(competitors) == 0 {
+		return report
+	}
+
+	// Ключевое слово и его части не считаются пробелом
+	keywordWords := make(map[string]bool)
+	for _, word := range parser.TopicWords(keyword) {
+		keywordWords[word] = true
+	}
+	isKeyword := func(phrase string) bool {
+		for _, word := range strings.Fields(phrase) {
+			if !keywordWords[word] {
+				return false
+			}
+		}
+		return true
+	}
+
+	type phraseStats struct {
+		mentions    int
+		competitors []string
+	}
+	stats := make(map[string]*phraseStats)
+	for _, competitor := range competitors {
+		for phrase, count := range competitor.Phrases {
+			s, ok := stats[phrase]
+			if !ok {
+				s = &phraseStats{}
+				stats[phrase] = s
+			}
+			s.mentions += count
+			s.competitors = append(s.competitors, competitor.URL)
+		}
+	}
+
+	minCoverage := (len(competitors) + 1) / 2
+	for phrase, s := range stats {
+		if len(s.competitors) < minCoverage || isKeyword(phrase) {
+			continue
+		}
+		if page.Covers(phrase) {
+			report.SharedTopics = append(report.SharedTopics, phrase)
+			continue
+		}
+		report.MissingTopics = append(report.MissingTopics, ContentGapTopic{
+			Phrase:      phrase,
+			Coverage:    float64(len(s.competitors)) / float64(len(competitors)),
+			Mentions:    s.mentions,
+			Competitors: s.competitors,
+		})
+	}
+
+	sort.Slice(report.MissingTopics, func(i, j int) bool {
+		a, b := report.MissingTopics[i], report.MissingTopics[j]
+		if a.Coverage != b.Coverage {
+			return a.Coverage > b.Coverage
+		}
+		if a.Mentions != b.Mentions {
+			return a.Mentions > b.Mentions
+		}
+		return a.Phrase < b.Phrase
+	})
+	report.MissingTopics = dropContainedTopics(report.MissingTopics)
+	if len(report.MissingTopics) > maxGapTopics {
+		report.MissingTopics = report.MissingTopics[:maxGapTopics]
+	}
+	sort.Strings(report.SharedTopics)
+
+	report.MissingHeadings = missingHeadings(page, competitors)
+
+	return report
+}
+
+// dropContainedTopics убирает отдельные слова, которые входят во фразу из
+// двух слов с не меньшим охватом
+func dropContainedTopics(topics []ContentGapTopic) []ContentGapTopic {
+	pairCoverage := make(map[string]float64)
+	for _, topic := range topics {
+		words := strings.Fields(topic.Phrase)
+		if len(words) < 2 {
+			continue
+		}
+		for _, word := range words {
+			if topic.Coverage > pairCoverage[word] {
+				pairCoverage[word] = topic.Coverage
+			}
+		}
+	}
+
+	result := topics[:0]
+	for _, topic := range topics {
+		if !strings.Contains(topic.Phrase, " ") && pairCoverage[topic.Phrase] >= topic.Coverage {
+			continue
+		}
+		result = append(result, topic)
+	}
+	return result
+}
+
+// missingHeadings возвращает разделы конкурентов (h2-h3), большинство значимых
+// слов которых не встречается на странице
+func missingHeadings(page *parser.PageTopics, competitors []*parser.PageTopics) []ContentGapHeading {
+	headings := []ContentGapHeading{}
+	seen := make(map[string]bool)
+
+	for _, competitor := range competitors {
+		for _, heading := range competitor.Headings {
+			if heading.Level < 2 || heading.Level > 3 {
+				continue
+			}
+			key := strings.ToLower(heading.Text)
+			if seen[key] {
+				continue
+			}
+
+			var terms, missing int
+			for _, word := range parser.TopicWords(heading.Text) {
+				if len([]rune(word)) < 4 {
+					continue
+				}
+				terms++
+				if !page.Covers(word) {
+					missing++
+				}
+			}
+			if terms == 0 || missing*2 <= terms {
+				continue
+			}
+
+			seen[key] = true
+			headings = append(headings, ContentGapHeading{
+				Text:       heading.Text,
+				Level:      heading.Level,
+				Competitor: competitor.URL,
+			})
+			if len(headings) == maxGapHeadings {
+				return headings
+			}
+		}
+	}
+	return headings
+}
@@ -0,0 +1,318 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// Источники данных для рекомендуемой CSP
+const (
+	CSPSourceNetworkLog = "network_log" // запросы, сделанные страницей в headless-браузере
+	CSPSourceDOM        = "dom"         // ресурсы, объявленные в разметке
+)
+
+// maxInlineScriptHashes ограничивает число хешей встроенных скриптов в политике
+const maxInlineScriptHashes = 20
+
+// cspDirectiveOrder задает порядок директив в заголовке
+var cspDirectiveOrder = []string{
+	"default-src", "script-src", "style-src", "img-src", "font-src", "connect-src",
+	"media-src", "frame-src", "worker-src", "manifest-src", "object-src", "base-uri",
+	"form-action", "frame-ancestors",
+}
+
+// cspResourceDirectives сопоставляет типы ресурсов Chrome директивам CSP
+var cspResourceDirectives = map[string]string{
+	"Script":             "script-src",
+	"Stylesheet":         "style-src",
+	"Image":              "img-src",
+	"Font":               "font-src",
+	"Media":              "media-src",
+	"XHR":                "connect-src",
+	"Fetch":              "connect-src",
+	"EventSource":        "connect-src",
+	"WebSocket":          "connect-src",
+	"Ping":               "connect-src",
+	"CSPViolationReport": "connect-src",
+	"Manifest":           "manifest-src",
+	"Document":           "frame-src",
+}
+
+// CSPPolicy - рекомендуемая Content Security Policy, построенная по ресурсам,
+// которые страница действительно использует
+type CSPPolicy struct {
+	Source     string              `json:"source"`
+	Directives map[string][]string `json:"directives"`
+	Policy     string              `json:"policy"`
+	// Header - заголовок в режиме report-only, готовый для копирования
+	Header string `json:"header"`
+	// InlineScripts - встроенные скрипты, которым нужен nonce или хеш
+	InlineScripts       int      `json:"inline_scripts"`
+	InlineEventHandlers int      `json:"inline_event_handlers"`
+	JavaScriptURLs      int      `json:"javascript_urls"`
+	InlineStyles        int      `json:"inline_styles"`
+	StyleAttributes     int      `json:"style_attributes"`
+	InlineScriptHashes  []string `json:"inline_script_hashes,omitempty"`
+	Warnings            []string `json:"warnings,omitempty"`
+}
+
+// GenerateCSP строит рекомендуемую CSP по сетевому журналу headless-браузера,
+// а без него - по ресурсам из разметки страницы
+func GenerateCSP(data *parser.WebsiteData, doc *goquery.Document) *CSPPolicy {
+	pageURL := data.FinalURL
+	if pageURL == "" {
+		pageURL = data.URL
+	}
+	page, _ := url.Parse(pageURL)
+
+	sources := make(map[string]map[string]bool)
+	add := func(directive, resource string) {
+		source := cspSourceExpression(page, resource)
+		if source == "" {
+			return
+		}
+		if sources[directive] == nil {
+			sources[directive] = make(map[string]bool)
+		}
+		sources[directive][source] = true
+	}
+
+	policy := &CSPPolicy{Source: CSPSourceDOM}
+	if len(data.NetworkRequests) > 0 {
+		policy.Source = CSPSourceNetworkLog
+		for i, request := range data.NetworkRequests {
+			// Первый документ - сама страница, а не фрейм
+			if i == 0 && request.Type == "Document" {
+				continue
+			}
+			if directive, ok := cspResourceDirectives[request.Type]; ok {
+				add(directive, request.URL)
+			}
+		}
+	} else {
+		for _, script := range data.Scripts {
+			add("script-src", script.URL)
+		}
+		for _, style := range data.Styles {
+			if style.IsLink {
+				add("style-src", style.URL)
+			}
+		}
+		for _, image := range data.Images {
+			add("img-src", image.URL)
+		}
+	}
+
+	if doc != nil {
+		doc.Find("iframe[src], frame[src]").Each(func(_ int, s *goquery.Selection) {
+			add("frame-src", absoluteURL(page, s.AttrOr("src", "")))
+		})
+		doc.Find("form[action]").Each(func(_ int, s *goquery.Selection) {
+			add("form-action", absoluteURL(page, s.AttrOr("action", "")))
+		})
+		policy.countInline(doc)
+	}
+
+	directives := map[string][]string{
+		"default-src":     {"'self'"},
+		"object-src":      {"'none'"},
+		"base-uri":        {"'self'"},
+		"form-action":     {"'self'"},
+		"frame-ancestors": {"'self'"},
+	}
+	for directive, set := range sources {
+		list := directives[directive]
+		// Директива заменяет default-src, поэтому собственный источник сохраняется
+		if list == nil {
+			list = []string{"'self'"}
+		}
+		for source := range set {
+			if !containsString(list, source) {
+				list = append(list, source)
+			}
+		}
+		directives[directive] = list
+	}
+
+	// Встроенные скрипты требуют nonce; статические можно разрешить хешами
+	for _, hash := range policy.InlineScriptHashes {
+		directives["script-src"] = appendCSPSource(directives["script-src"], "'"+hash+"'")
+	}
+	// Атрибуты style нельзя разрешить хешем без 'unsafe-hashes'
+	if policy.StyleAttributes > 0 {
+		directives["style-src"] = appendCSPSource(directives["style-src"], "'unsafe-inline'")
+	}
+
+	for directive, list := range directives {
+		sortCSPSources(list)
+		directives[directive] = list
+	}
+	policy.Directives = directives
+	policy.Policy = formatCSP(directives)
+	policy.Header = "Content-Security-Policy-Report-Only: " + policy.Policy
+	policy.Warnings = policy.warnings()
+
+	return policy
+}
+
+// countInline подсчитывает встроенные скрипты, обработчики и стили
+func (p *CSPPolicy) countInline(doc *goquery.Document) {
+	doc.Find("script:not([src])").Each(func(_ int, s *goquery.Selection) {
+		scriptType := strings.ToLower(strings.TrimSpace(s.AttrOr("type", "")))
+		// Блоки данных, например JSON-LD, не исполняются и не требуют nonce
+		if scriptType != "" && scriptType != "text/javascript" && scriptType != "module" && scriptType != "application/javascript" {
+			return
+		}
+		content := s.Text()
+		if strings.TrimSpace(content) == "" {
+			return
+		}
+		p.InlineScripts++
+		if len(p.InlineScriptHashes) < maxInlineScriptHashes {
+			sum := sha256.Sum256([]byte(content))
+			p.InlineScriptHashes = append(p.InlineScriptHashes, "sha256-"+base64.StdEncoding.EncodeToString(sum[:]))
+		}
+	})
+
+	doc.Find("*").Each(func(_ int, s *goquery.Selection) {
+		for _, attr := range s.Nodes[0].Attr {
+			name := strings.ToLower(attr.Key)
+			switch {
+			case strings.HasPrefix(name, "on"):
+				p.InlineEventHandlers++
+			case name == "style":
+				p.StyleAttributes++
+			case (name == "href" || name == "src" || name == "action") &&
+				strings.HasPrefix(strings.ToLower(strings.TrimSpace(attr.Val)), "javascript:"):
+				p.JavaScriptURLs++
+			}
+		}
+	})
+
+	p.InlineStyles = doc.Find("style").Length()
+}
+
+// warnings описывает, что сломается при включении политики
+func (p *CSPPolicy) warnings() []string {
+	var warnings []string
+	if p.Source == CSPSourceDOM {
+		warnings = append(warnings, "Политика построена по разметке без сетевого журнала: запросы, которые делают скрипты (fetch, XHR, WebSocket), не учтены. Включите headless-браузер для точного результата")
+	}
+	if p.InlineScripts > 0 {
+		warnings = append(warnings, fmt.Sprintf("Встроенных скриптов: %d. Динамические скрипты потребуют nonce в каждом ответе; для статических в политику добавлены хеши", p.InlineScripts))
+	}
+	if p.InlineScripts > maxInlineScriptHashes {
+		warnings = append(warnings, fmt.Sprintf("Хеши добавлены только для первых %d встроенных скриптов", maxInlineScriptHashes))
+	}
+	if p.InlineEventHandlers > 0 {
+		warnings = append(warnings, fmt.Sprintf("Встроенных обработчиков событий (onclick и др.): %d. Они будут заблокированы - перенесите их в addEventListener", p.InlineEventHandlers))
+	}
+	if p.JavaScriptURLs > 0 {
+		warnings = append(warnings, fmt.Sprintf("Ссылок javascript: %d. Они будут заблокированы политикой", p.JavaScriptURLs))
+	}
+	if p.InlineStyles > 0 {
+		warnings = append(warnings, fmt.Sprintf("Встроенных блоков <style>: %d. Им потребуется nonce или хеш в style-src", p.InlineStyles))
+	}
+	if p.StyleAttributes > 0 {
+		warnings = append(warnings, fmt.Sprintf("Атрибутов style: %d. Для них в style-src добавлен 'unsafe-inline'", p.StyleAttributes))
+	}
+	return warnings
+}
+
+// cspSourceExpression возвращает источник CSP для URL ресурса
+func cspSourceExpression(page *url.URL, resource string) string {
+	u, err := url.Parse(strings.TrimSpace(resource))
+	if err != nil || resource == "" {
+		return ""
+	}
+	switch u.Scheme {
+	case "data", "blob":
+		return u.Scheme + ":"
+	case "http", "https", "ws", "wss":
+	default:
+		return ""
+	}
+	if u.Host == "" {
+		return ""
+	}
+	if page != nil && strings.EqualFold(u.Host, page.Host) && sameOriginScheme(page.Scheme, u.Scheme) {
+		return "'self'"
+	}
+	return u.Scheme + "://" + strings.ToLower(u.Host)
+}
+
+// sameOriginScheme сообщает, покрывает ли 'self' схему ресурса
+func sameOriginScheme(pageScheme, scheme string) bool {
+	switch pageScheme {
+	case "https":
+		return scheme == "https" || scheme == "wss"
+	case "http":
+		return scheme == "http" || scheme == "ws" || scheme == "https" || scheme == "wss"
+	}
+	return pageScheme == scheme
+}
+
+// absoluteURL разрешает ссылку относительно страницы
+func absoluteURL(page *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if page == nil || ref == "" {
+		return ref
+	}
+	u, err := page.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	return u.String()
+}
+
+// sortCSPSources сортирует источники: ключевые слова первыми, затем по алфавиту
+func sortCSPSources(sources []string) {
+	sort.SliceStable(sources, func(i, j int) bool {
+		ki := strings.HasPrefix(sources[i], "'")
+		kj := strings.HasPrefix(sources[j], "'")
+		if ki != kj {
+			return ki
+		}
+		if ki && (sources[i] == "'self'" || sources[j] == "'self'") {
+			return sources[i] == "'self'"
+		}
+		return sources[i] < sources[j]
+	})
+}
+
+// formatCSP собирает директивы в строку политики
+func formatCSP(directives map[string][]string) string {
+	parts := make([]string, 0, len(directives))
+	for _, directive := range cspDirectiveOrder {
+		if list, ok := directives[directive]; ok {
+			parts = append(parts, directive+" "+strings.Join(list, " "))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// appendCSPSource добавляет источник в директиву, создавая ее с 'self'
+func appendCSPSource(list []string, source string) []string {
+	if list == nil {
+		list = []string{"'self'"}
+	}
+	return append(list, source)
+}
+
+// containsString сообщает, есть ли строка в списке
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// Виды пользовательских правил
+const (
+	RuleSelectorRequired  = "selector_required"  // на странице должен быть элемент
+	RuleSelectorForbidden = "selector_forbidden" // на странице не должно быть элемента
+	RulePatternRequired   = "pattern_required"   // HTML должен содержать совпадение
+	RulePatternForbidden  = "pattern_forbidden"  // HTML не должен содержать совпадений
+)
+
+const (
+	// MaxCustomRules ограничивает число правил одной организации
+	MaxCustomRules = 50
+	// maxRuleMatchExample ограничивает длину найденного фрагмента в проблеме
+	maxRuleMatchExample = 120
+)
+
+// ruleCodePattern - допустимый код правила, он же тип проблемы
+var ruleCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,63}$`)
+
+// CustomRule - проверка, заданная организацией
+type CustomRule struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Selector string `json:"selector,omitempty"`
+	Pattern  string `json:"pattern,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// CustomRuleResult - итог проверки страницы одним правилом
+type CustomRuleResult struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Matches int    `json:"matches"`
+}
+
+// ValidateCustomRule проверяет правило: код, вид, серьезность, а также
+// синтаксис селектора или регулярного выражения
+func ValidateCustomRule(rule CustomRule) error {
+	if !ruleCodePattern.MatchString(rule.Code) {
+		return errors.New("code must be 2-64 lowercase letters, digits or underscores starting with a letter")
+	}
+	if strings.TrimSpace(rule.Name) == "" {
+		return errors.New("name is required")
+	}
+	if strings.TrimSpace(rule.Message) == "" {
+		return errors.New("message is required")
+	}
+	switch rule.Severity {
+	case "high", "medium", "low":
+	default:
+		return errors.New("severity must be one of: high, medium, low")
+	}
+
+	switch rule.Kind {
+	case RuleSelectorRequired, RuleSelectorForbidden:
+		if strings.TrimSpace(rule.Selector) == "" {
+			return errors.New("selector is required for selector rules")
+		}
+		if _, err := cascadia.Compile(rule.Selector); err != nil {
+			return fmt.Errorf("invalid selector: %w", err)
+		}
+	case RulePatternRequired, RulePatternForbidden:
+		if strings.TrimSpace(rule.Pattern) == "" {
+			return errors.New("pattern is required for pattern rules")
+		}
+		if _, err := CompileRulePattern(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	default:
+		return fmt.Errorf("kind must be one of: %s, %s, %s, %s",
+			RuleSelectorRequired, RuleSelectorForbidden, RulePatternRequired, RulePatternForbidden)
+	}
+	return nil
+}
+
+// CompileRulePattern компилирует регулярное выражение правила. Выражение
+// можно записать как /выражение/флаги с флагами i, m и s
+func CompileRulePattern(pattern string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(pattern, "/") {
+		if end := strings.LastIndex(pattern, "/"); end > 0 {
+			flags := pattern[end+1:]
+			if strings.Trim(flags, "ims") == "" {
+				pattern = pattern[1:end]
+				if flags != "" {
+					pattern = "(?" + flags + ")" + pattern
+				}
+			}
+		}
+	}
+	return regexp.Compile(pattern)
+}
+
+// CustomRulesAnalyzer проверяет страницу правилами организации и сообщает
+// о нарушениях с заданными в правилах серьезностью и текстом
+type CustomRulesAnalyzer struct {
+	*BaseAnalyzer
+	rules []CustomRule
+}
+
+// NewCustomRulesAnalyzer создает анализатор пользовательских правил
+func NewCustomRulesAnalyzer(rules []CustomRule) *CustomRulesAnalyzer {
+	return &CustomRulesAnalyzer{
+		BaseAnalyzer: NewBaseAnalyzer(CustomRulesType),
+		rules:        rules,
+	}
+}
+
+// Analyze применяет правила к HTML страницы
+func (a *CustomRulesAnalyzer) Analyze(ctx context.Context, data *parser.WebsiteData, prevResults map[AnalyzerType]map[string]interface{}) (map[string]interface{}, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	if len(a.rules) == 0 {
+		a.SetMetric("error", "Пользовательские правила не заданы")
+		return a.GetMetrics(), nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(data.HTML))
+	if err != nil {
+		return a.GetMetrics(), err
+	}
+
+	results := make([]CustomRuleResult, 0, len(a.rules))
+	passed := 0
+	for _, rule := range a.rules {
+		result, issue := a.checkRule(rule, doc, data.HTML)
+		if issue != nil {
+			a.AddIssue(issue)
+		} else {
+			passed++
+		}
+		results = append(results, result)
+	}
+
+	a.SetMetric("rules", len(a.rules))
+	a.SetMetric("passed", passed)
+	a.SetMetric("failed", len(a.rules)-passed)
+	a.SetMetric("results", results)
+	a.SetMetric("score", a.CalculateScore())
+
+	return a.GetMetrics(), nil
+}
+
+// checkRule проверяет одно правило и возвращает проблему при нарушении.
+// Правила с ошибкой в селекторе или выражении считаются нарушенными
+func (a *CustomRulesAnalyzer) checkRule(rule CustomRule, doc *goquery.Document, html string) (CustomRuleResult, map[string]interface{}) {
+	result := CustomRuleResult{Code: rule.Code, Name: rule.Name}
+	issue := map[string]interface{}{
+		"type":        rule.Code,
+		"severity":    rule.Severity,
+		"description": rule.Message,
+		"rule":        rule.Name,
+	}
+
+	switch rule.Kind {
+	case RuleSelectorRequired, RuleSelectorForbidden:
+		selector, err := cascadia.Compile(rule.Selector)
+		if err != nil {
+			issue["error"] = "Некорректный селектор: " + err.Error()
+			return result, issue
+		}
+		result.Matches = doc.FindMatcher(selector).Length()
+		if rule.Kind == RuleSelectorRequired {
+			result.Passed = result.Matches > 0
+		} else {
+			result.Passed = result.Matches == 0
+			issue["selector"] = rule.Selector
+			issue["count"] = result.Matches
+		}
+
+	case RulePatternRequired, RulePatternForbidden:
+		pattern, err := CompileRulePattern(rule.Pattern)
+		if err != nil {
+			issue["error"] = "Некорректное регулярное выражение: " + err.Error()
+			return result, issue
+		}
+		matches := pattern.FindAllStringIndex(html, -1)
+		result.Matches = len(matches)
+		if rule.Kind == RulePatternRequired {
+			result.Passed = result.Matches > 0
+		} else {
+			result.Passed = result.Matches == 0
+			if len(matches) > 0 {
+				issue["match"] = ruleMatchExample(html[matches[0][0]:matches[0][1]])
+				issue["count"] = result.Matches
+			}
+		}
+
+	default:
+		issue["error"] = "Неизвестный вид правила: " + rule.Kind
+		return result, issue
+	}
+
+	if result.Passed {
+		return result, nil
+	}
+	return result, issue
+}
+
+// ruleMatchExample сокращает найденный фрагмент для показа в проблеме
+func ruleMatchExample(match string) string {
+	match = strings.Join(strings.Fields(match), " ")
+	if utf8.RuneCountInString(match) <= maxRuleMatchExample {
+		return match
+	}
+	return string([]rune(match)[:maxRuleMatchExample]) + "…"
+}
@@ -0,0 +1,201 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DependencyGraph maps an analyzer to the analyzers whose results it needs.
+// Dependencies on analyzers that are not registered are ignored at run time,
+// so custom analyzers may declare prerequisites by type name.
+type DependencyGraph map[AnalyzerType][]AnalyzerType
+
+// DefaultDependencyGraph returns the dependency graph used when no
+// dependencies file is configured
+func DefaultDependencyGraph() DependencyGraph {
+	return DependencyGraph(buildDefaultDependencyGraph())
+}
+
+// Validate checks the graph for empty names, self-dependencies and cycles
+func (g DependencyGraph) Validate() error {
+	for at, deps := range g {
+		if strings.TrimSpace(string(at)) == "" {
+			return fmt.Errorf("analyzer type must not be empty")
+		}
+		for _, dep := range deps {
+			if strings.TrimSpace(string(dep)) == "" {
+				return fmt.Errorf("dependency of %s must not be empty", at)
+			}
+			if dep == at {
+				return fmt.Errorf("analyzer %s depends on itself", at)
+			}
+		}
+	}
+
+	// Depth-first search; a node met again while still on the path closes a cycle
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[AnalyzerType]int)
+	var path []AnalyzerType
+
+	var visit func(at AnalyzerType) error
+	visit = func(at AnalyzerType) error {
+		switch state[at] {
+		case visiting:
+			start := 0
+			for i, p := range path {
+				if p == at {
+					start = i
+					break
+				}
+			}
+			cycle := make([]string, 0, len(path)-start+1)
+			for _, p := range path[start:] {
+				cycle = append(cycle, string(p))
+			}
+			cycle = append(cycle, string(at))
+			return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+		case visited:
+			return nil
+		}
+
+		state[at] = visiting
+		path = append(path, at)
+		for _, dep := range g[at] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[at] = visited
+		return nil
+	}
+
+	// Visit in a stable order so the reported cycle is deterministic
+	types := make([]AnalyzerType, 0, len(g))
+	for at := range g {
+		types = append(types, at)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	for _, at := range types {
+		if err := visit(at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadDependencyGraph reads and validates a dependency graph from a JSON
+// file of the form {"seo": ["lighthouse"], "performance": ["infrastructure"]}.
+// The file replaces the default graph entirely.
+func LoadDependencyGraph(path string) (DependencyGraph, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dependencies file: %w", err)
+	}
+
+	var graph DependencyGraph
+	if err := json.Unmarshal(data, &graph); err != nil {
+		return nil, fmt.Errorf("invalid dependencies file: %w", err)
+	}
+	if graph == nil {
+		graph = DependencyGraph{}
+	}
+	if err := graph.Validate(); err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
+
+// DependencyGraphStatus describes the dependency graph in effect
+type DependencyGraphStatus struct {
+	Graph    DependencyGraph `json:"graph"`
+	Source   string          `json:"source"` // the dependencies file, or "default"
+	LoadedAt time.Time       `json:"loaded_at"`
+	Error    string          `json:"error,omitempty"` // why the file was last rejected
+}
+
+// dependencyGraphs caches the graph loaded from the dependencies file and
+// reloads it when the file's modification time changes. An invalid file is
+// rejected and the last valid graph stays in effect.
+var dependencyGraphs struct {
+	sync.Mutex
+	path    string
+	modTime time.Time
+	status  DependencyGraphStatus
+}
+
+// CurrentDependencyGraph returns the dependency graph in effect for the
+// dependencies file at path, reloading the file if it changed. An empty path
+// selects the default graph.
+func CurrentDependencyGraph(path string) DependencyGraphStatus {
+	dependencyGraphs.Lock()
+	defer dependencyGraphs.Unlock()
+
+	if path == "" {
+		return DependencyGraphStatus{Graph: DefaultDependencyGraph(), Source: "default"}
+	}
+
+	cache := &dependencyGraphs
+	if cache.path != path {
+		cache.path = path
+		cache.modTime = time.Time{}
+		cache.status = DependencyGraphStatus{Graph: DefaultDependencyGraph(), Source: "default"}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if cache.status.Error == "" {
+			log.Printf("Warning: analyzer dependencies file unavailable, keeping %s graph: %v", cache.status.Source, err)
+		}
+		cache.status.Error = err.Error()
+		return cache.status.copy()
+	}
+	if info.ModTime().Equal(cache.modTime) {
+		return cache.status.copy()
+	}
+	cache.modTime = info.ModTime()
+
+	graph, err := LoadDependencyGraph(path)
+	if err != nil {
+		log.Printf("Warning: rejected analyzer dependencies file %s, keeping %s graph: %v", path, cache.status.Source, err)
+		cache.status.Error = err.Error()
+		return cache.status.copy()
+	}
+
+	cache.status = DependencyGraphStatus{Graph: graph, Source: path, LoadedAt: time.Now()}
+	log.Printf("Loaded analyzer dependency graph from %s", path)
+	return cache.status.copy()
+}
+
+// copy returns the status with a copy of the graph, which callers may modify
+func (s DependencyGraphStatus) copy() DependencyGraphStatus {
+	graph := make(DependencyGraph, len(s.Graph))
+	for at, deps := range s.Graph {
+		graph[at] = append([]AnalyzerType(nil), deps...)
+	}
+	s.Graph = graph
+	return s
+}
+
+// SetDependencyGraph replaces the manager's dependency graph after
+// validating it
+func (m *AnalyzerManager) SetDependencyGraph(graph DependencyGraph) error {
+	if err := graph.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dependencyGraph = graph
+	return nil
+}
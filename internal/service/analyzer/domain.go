@@ -0,0 +1,588 @@
+package analyzer
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/config"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+const (
+	// subdomainTimeout ограничивает проверку одного поддомена
+	subdomainTimeout = 8 * time.Second
+	// subdomainConcurrency - число поддоменов, проверяемых одновременно
+	subdomainConcurrency = 5
+	// subdomainBodyLimit - сколько байт страницы читается для поиска заглушек
+	subdomainBodyLimit = 64 << 10
+	// certExpiryWarning - за сколько до истечения сертификата сообщать о нем
+	certExpiryWarning = 14 * 24 * time.Hour
+)
+
+// Источники, из которых найден поддомен
+const (
+	SubdomainSourceCertificate = "certificate" // SAN сертификата сайта
+	SubdomainSourceDNS         = "dns"         // распространенное имя, которое резолвится
+	SubdomainSourceLinks       = "links"       // ссылки и ресурсы страницы
+)
+
+// commonSubdomains - распространенные имена поддоменов, которые проверяются в DNS
+var commonSubdomains = []string{
+	"www", "m", "mail", "webmail", "blog", "shop", "store", "api", "app",
+	"admin", "portal", "dev", "staging", "stage", "test", "beta", "demo",
+	"old", "new", "cdn", "static", "img", "docs", "help", "support", "status",
+}
+
+// defaultPageSignatures - фрагменты стандартных страниц веб-серверов и
+// хостинг-панелей, которые остаются на забытых поддоменах
+var defaultPageSignatures = map[string]string{
+	"Welcome to nginx!":                      "nginx",
+	"Apache2 Ubuntu Default Page":            "Apache (Ubuntu)",
+	"Apache2 Debian Default Page":            "Apache (Debian)",
+	"Test Page for the Apache HTTP Server":   "Apache",
+	"<h1>It works!</h1>":                     "Apache",
+	"IIS Windows Server":                     "IIS",
+	"Welcome to CentOS":                      "CentOS",
+	"Web Server's Default Page":              "Plesk",
+	"Default Web Site Page":                  "Plesk",
+	"Welcome to LiteSpeed Web Server":        "LiteSpeed",
+	"cPanel, Inc.":                           "cPanel",
+	"Domain Default page":                    "Plesk",
+	"Congratulations! Your Caddy web server": "Caddy",
+}
+
+// takeoverSignatures - ответы сервисов, на которых поддомен указывает на
+// удаленный ресурс: его может занять кто угодно
+var takeoverSignatures = map[string]string{
+	"There isn't a GitHub Pages site here.":                        "GitHub Pages",
+	"NoSuchBucket":                                                 "Amazon S3",
+	"herokucdn.com/error-pages/no-such-app":                        "Heroku",
+	"Repository not found":                                         "Bitbucket",
+	"The requested URL was not found on this server. Did you mean": "Google Cloud Storage",
+	"Do you want to register":                                      "WordPress.com",
+	"project not found":                                            "GitLab Pages",
+	"Fastly error: unknown domain":                                 "Fastly",
+	"The feed has not been found.":                                 "Feedpress",
+	"404 Web Site not found":                                       "Azure",
+}
+
+// SubdomainInfo описывает проверку одного поддомена
+type SubdomainInfo struct {
+	Host      string   `json:"host"`
+	Sources   []string `json:"sources"`
+	Addresses []string `json:"addresses,omitempty"`
+	Resolves  bool     `json:"resolves"`
+	Live      bool     `json:"live"`
+	HTTPS     bool     `json:"https"`
+	// CertValid - сертификат действителен для поддомена и подписан доверенным центром
+	CertValid     bool       `json:"cert_valid"`
+	CertError     string     `json:"cert_error,omitempty"`
+	CertExpiresAt *time.Time `json:"cert_expires_at,omitempty"`
+	StatusCode    int        `json:"status_code,omitempty"`
+	// DefaultPage - ПО, чья стандартная страница отдается вместо сайта
+	DefaultPage string `json:"default_page,omitempty"`
+	// Takeover - сервис, на удаленный ресурс которого указывает поддомен
+	Takeover string `json:"takeover,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// DomainInventory - поддомены домена анализируемой страницы
+type DomainInventory struct {
+	Domain      string          `json:"domain"`
+	Host        string          `json:"host"`
+	WildcardDNS bool            `json:"wildcard_dns"`
+	Subdomains  []SubdomainInfo `json:"subdomains"`
+	// Truncated - найдено больше поддоменов, чем проверяется за анализ
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// DomainAnalyzer находит поддомены домена по сертификату, DNS и ссылкам
+// страницы и проверяет их доступность и HTTPS
+type DomainAnalyzer struct {
+	*BaseAnalyzer
+	maxHosts int
+	// lookupHost и dial разрешают имена и подключаются к поддоменам
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+	dial       func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// NewDomainAnalyzer создает анализатор поддоменов
+func NewDomainAnalyzer(cfg *config.Config) *DomainAnalyzer {
+	maxHosts := cfg.SubdomainInventoryMaxHosts
+	if maxHosts <= 0 {
+		maxHosts = 20
+	}
+	return &DomainAnalyzer{
+		BaseAnalyzer: NewBaseAnalyzer(DomainType),
+		maxHosts:     maxHosts,
+		lookupHost:   net.DefaultResolver.LookupHost,
+		dial:         (&net.Dialer{Timeout: subdomainTimeout}).DialContext,
+	}
+}
+
+// Analyze составляет список поддоменов и проверяет каждый из них
+func (a *DomainAnalyzer) Analyze(ctx context.Context, data *parser.WebsiteData, prevResults map[AnalyzerType]map[string]interface{}) (map[string]interface{}, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	pageURL := data.FinalURL
+	if pageURL == "" {
+		pageURL = data.URL
+	}
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page URL: %w", err)
+	}
+	host := strings.ToLower(page.Hostname())
+	if net.ParseIP(host) != nil {
+		a.SetMetric("error", "Страница открыта по IP-адресу, поддомены не проверяются")
+		return a.GetMetrics(), nil
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		a.SetMetric("error", "Не удалось определить домен: "+err.Error())
+		return a.GetMetrics(), nil
+	}
+
+	inventory := &DomainInventory{Domain: domain, Host: host, Subdomains: []SubdomainInfo{}}
+	sources := make(map[string]map[string]bool)
+	add := func(name, source string) {
+		name = strings.TrimSuffix(strings.ToLower(name), ".")
+		if name == host || (name != domain && !strings.HasSuffix(name, "."+domain)) {
+			return
+		}
+		if sources[name] == nil {
+			sources[name] = make(map[string]bool)
+		}
+		sources[name][source] = true
+	}
+
+	for _, name := range a.certificateNames(ctx, host) {
+		add(strings.TrimPrefix(name, "*."), SubdomainSourceCertificate)
+	}
+	for _, name := range pageHosts(data) {
+		add(name, SubdomainSourceLinks)
+	}
+	inventory.WildcardDNS = a.hasWildcardDNS(ctx, domain)
+	if !inventory.WildcardDNS {
+		for _, name := range a.resolvingNames(ctx, domain) {
+			add(name, SubdomainSourceDNS)
+		}
+	}
+
+	names := rankSubdomains(sources)
+	if len(names) > a.maxHosts {
+		names = names[:a.maxHosts]
+		inventory.Truncated = true
+	}
+	inventory.Subdomains = a.checkSubdomains(ctx, names, sources)
+
+	live, misconfigured := 0, 0
+	for _, sub := range inventory.Subdomains {
+		if sub.Live {
+			live++
+		}
+		if a.reportSubdomainIssues(sub) {
+			misconfigured++
+		}
+	}
+
+	a.SetMetric("inventory", inventory)
+	a.SetMetric("subdomains_found", len(sources))
+	a.SetMetric("subdomains_checked", len(inventory.Subdomains))
+	a.SetMetric("subdomains_live", live)
+	a.SetMetric("subdomains_misconfigured", misconfigured)
+	a.SetMetric("score", a.CalculateScore())
+
+	return a.GetMetrics(), nil
+}
+
+// certificateNames возвращает имена из SAN сертификата сайта. Сертификат
+// читается без проверки, чтобы получить имена и у недействительного.
+func (a *DomainAnalyzer) certificateNames(ctx context.Context, host string) []string {
+	ctx, cancel := context.WithTimeout(ctx, subdomainTimeout)
+	defer cancel()
+
+	raw, err := a.dial(ctx, "tcp", net.JoinHostPort(host, "443"))
+	if err != nil {
+		return nil
+	}
+	conn := tls.Client(raw, &tls.Config{ServerName: host, InsecureSkipVerify: true})
+	defer conn.Close()
+	if err := conn.HandshakeContext(ctx); err != nil {
+		return nil
+	}
+	return leafNames(conn.ConnectionState())
+}
+
+// leafNames возвращает DNS-имена сертификата сервера
+func leafNames(state tls.ConnectionState) []string {
+	if len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	return state.PeerCertificates[0].DNSNames
+}
+
+// pageHosts возвращает хосты ссылок, ресурсов и сетевых запросов страницы
+func pageHosts(data *parser.WebsiteData) []string {
+	var urls []string
+	for _, link := range data.Links {
+		urls = append(urls, link.URL)
+	}
+	for _, image := range data.Images {
+		urls = append(urls, image.URL)
+	}
+	for _, script := range data.Scripts {
+		urls = append(urls, script.URL)
+	}
+	for _, style := range data.Styles {
+		urls = append(urls, style.URL)
+	}
+	for _, request := range data.NetworkRequests {
+		urls = append(urls, request.URL)
+	}
+
+	seen := make(map[string]bool)
+	var hosts []string
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		if !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// hasWildcardDNS проверяет, резолвится ли случайное имя в домене. При
+// wildcard-записи перебор имен в DNS ничего не говорит о поддоменах.
+func (a *DomainAnalyzer) hasWildcardDNS(ctx context.Context, domain string) bool {
+	label := make([]byte, 8)
+	if _, err := rand.Read(label); err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, subdomainTimeout)
+	defer cancel()
+	addrs, err := a.lookupHost(ctx, "wo-"+hex.EncodeToString(label)+"."+domain)
+	return err == nil && len(addrs) > 0
+}
+
+// resolvingNames возвращает распространенные поддомены, у которых есть DNS-записи
+func (a *DomainAnalyzer) resolvingNames(ctx context.Context, domain string) []string {
+	ctx, cancel := context.WithTimeout(ctx, subdomainTimeout)
+	defer cancel()
+
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		found []string
+	)
+	sem := make(chan struct{}, subdomainConcurrency*2)
+	for _, label := range commonSubdomains {
+		name := label + "." + domain
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if addrs, err := a.lookupHost(ctx, name); err == nil && len(addrs) > 0 {
+				mu.Lock()
+				found = append(found, name)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return found
+}
+
+// rankSubdomains упорядочивает поддомены: сначала найденные в ссылках
+// страницы, затем в большем числе источников, затем по имени
+func rankSubdomains(sources map[string]map[string]bool) []string {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := sources[names[i]], sources[names[j]]
+		if a[SubdomainSourceLinks] != b[SubdomainSourceLinks] {
+			return a[SubdomainSourceLinks]
+		}
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// checkSubdomains проверяет поддомены параллельно, сохраняя их порядок
+func (a *DomainAnalyzer) checkSubdomains(ctx context.Context, names []string, sources map[string]map[string]bool) []SubdomainInfo {
+	results := make([]SubdomainInfo, len(names))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, subdomainConcurrency)
+	for i, name := range names {
+		found := make([]string, 0, len(sources[name]))
+		for source := range sources[name] {
+			found = append(found, source)
+		}
+		sort.Strings(found)
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, name string, found []string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = a.checkSubdomain(ctx, name, found)
+		}(i, name, found)
+	}
+	wg.Wait()
+	return results
+}
+
+// checkSubdomain проверяет DNS, HTTPS и содержимое главной страницы поддомена
+func (a *DomainAnalyzer) checkSubdomain(ctx context.Context, host string, sources []string) SubdomainInfo {
+	info := SubdomainInfo{Host: host, Sources: sources}
+	ctx, cancel := context.WithTimeout(ctx, subdomainTimeout)
+	defer cancel()
+
+	addrs, err := a.lookupHost(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		info.Error = "DNS-запись не найдена"
+		return info
+	}
+	info.Addresses = addrs
+	info.Resolves = true
+
+	// HTTPS с проверкой сертификата, при ошибке сертификата - без нее, чтобы
+	// увидеть, что отдает поддомен
+	resp, body, err := a.fetchSubdomain(ctx, "https://"+host+"/", false)
+	if err != nil && certificateError(err) != "" {
+		info.HTTPS = true
+		info.CertError = certificateError(err)
+		resp, body, err = a.fetchSubdomain(ctx, "https://"+host+"/", true)
+	} else if err == nil {
+		info.HTTPS = true
+		info.CertValid = true
+	}
+	if err != nil && !info.HTTPS {
+		// HTTPS недоступен: проверяем, отвечает ли поддомен по HTTP
+		resp, body, err = a.fetchSubdomain(ctx, "http://"+host+"/", false)
+	}
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+
+	info.Live = true
+	info.StatusCode = resp.StatusCode
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		expires := resp.TLS.PeerCertificates[0].NotAfter
+		info.CertExpiresAt = &expires
+	}
+	info.DefaultPage = matchSignature(body, defaultPageSignatures)
+	if resp.StatusCode >= 400 {
+		info.Takeover = matchSignature(body, takeoverSignatures)
+	}
+	return info
+}
+
+// fetchSubdomain запрашивает страницу без перехода по редиректам и читает
+// начало тела ответа
+func (a *DomainAnalyzer) fetchSubdomain(ctx context.Context, pageURL string, insecure bool) (*http.Response, string, error) {
+	transport := &http.Transport{
+		DialContext:         a.dial,
+		TLSHandshakeTimeout: subdomainTimeout,
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: insecure},
+		DisableKeepAlives:   true,
+	}
+	defer transport.CloseIdleConnections()
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   subdomainTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", parser.DesktopDevice.UserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, subdomainBodyLimit))
+	return resp, string(body), nil
+}
+
+// certificateError описывает ошибку проверки сертификата или возвращает
+// пустую строку для других ошибок
+func certificateError(err error) string {
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	var unknown x509.UnknownAuthorityError
+	var verification *tls.CertificateVerificationError
+	switch {
+	case errors.As(err, &invalid):
+		if invalid.Reason == x509.Expired {
+			return "expired"
+		}
+		return "invalid"
+	case errors.As(err, &hostname):
+		return "hostname_mismatch"
+	case errors.As(err, &unknown):
+		return "untrusted"
+	case errors.As(err, &verification):
+		return "invalid"
+	}
+	return ""
+}
+
+// matchSignature возвращает название первой найденной сигнатуры
+func matchSignature(body string, signatures map[string]string) string {
+	keys := make([]string, 0, len(signatures))
+	for signature := range signatures {
+		keys = append(keys, signature)
+	}
+	sort.Strings(keys)
+	for _, signature := range keys {
+		if strings.Contains(body, signature) {
+			return signatures[signature]
+		}
+	}
+	return ""
+}
+
+// reportSubdomainIssues сообщает о проблемах поддомена и возвращает true,
+// если он настроен неправильно
+func (a *DomainAnalyzer) reportSubdomainIssues(sub SubdomainInfo) bool {
+	linked := contains(sub.Sources, SubdomainSourceLinks)
+	subURL := "https://" + sub.Host + "/"
+	if !sub.HTTPS {
+		subURL = "http://" + sub.Host + "/"
+	}
+
+	switch {
+	case !sub.Resolves:
+		// Имена из сертификата часто внутренние, о них не сообщаем
+		if !linked {
+			return false
+		}
+		a.AddIssue(map[string]interface{}{
+			"type":        "subdomain_unresolved",
+			"severity":    "medium",
+			"description": fmt.Sprintf("Страница ссылается на поддомен %s, у которого нет DNS-записи", sub.Host),
+			"url":         subURL,
+		})
+		a.AddRecommendation(fmt.Sprintf("Удалите ссылки на %s или восстановите его DNS-запись", sub.Host))
+		return true
+
+	case sub.Takeover != "":
+		a.AddIssue(map[string]interface{}{
+			"type":        "subdomain_takeover_risk",
+			"severity":    "high",
+			"description": fmt.Sprintf("Поддомен %s указывает на удаленный ресурс %s: его может занять посторонний", sub.Host, sub.Takeover),
+			"url":         subURL,
+			"service":     sub.Takeover,
+		})
+		a.AddRecommendation(fmt.Sprintf("Удалите DNS-запись %s или снова создайте ресурс в %s", sub.Host, sub.Takeover))
+		return true
+
+	case !sub.Live:
+		severity := "low"
+		if linked {
+			severity = "medium"
+		}
+		a.AddIssue(map[string]interface{}{
+			"type":        "subdomain_unreachable",
+			"severity":    severity,
+			"description": fmt.Sprintf("Поддомен %s есть в DNS, но не отвечает ни по HTTPS, ни по HTTP", sub.Host),
+			"url":         subURL,
+			"error":       sub.Error,
+		})
+		a.AddRecommendation(fmt.Sprintf("Удалите забытую DNS-запись %s или восстановите сервер", sub.Host))
+		return true
+	}
+
+	misconfigured := false
+	if !sub.HTTPS {
+		a.AddIssue(map[string]interface{}{
+			"type":        "subdomain_no_https",
+			"severity":    "medium",
+			"description": fmt.Sprintf("Поддомен %s доступен только по HTTP", sub.Host),
+			"url":         subURL,
+		})
+		a.AddRecommendation(fmt.Sprintf("Выпустите сертификат для %s и перенаправляйте HTTP на HTTPS", sub.Host))
+		misconfigured = true
+	}
+	if sub.CertError != "" {
+		severity := "medium"
+		description := fmt.Sprintf("Сертификат поддомена %s недействителен", sub.Host)
+		switch sub.CertError {
+		case "expired":
+			severity = "high"
+			description = fmt.Sprintf("Срок действия сертификата поддомена %s истек", sub.Host)
+		case "hostname_mismatch":
+			description = fmt.Sprintf("Сертификат поддомена %s выдан для другого имени", sub.Host)
+		case "untrusted":
+			description = fmt.Sprintf("Сертификат поддомена %s подписан недоверенным центром", sub.Host)
+		}
+		a.AddIssue(map[string]interface{}{
+			"type":        "subdomain_invalid_certificate",
+			"severity":    severity,
+			"description": description,
+			"url":         subURL,
+			"reason":      sub.CertError,
+		})
+		a.AddRecommendation(fmt.Sprintf("Обновите сертификат %s, например через Let's Encrypt с автоматическим продлением", sub.Host))
+		misconfigured = true
+	} else if sub.CertExpiresAt != nil && time.Until(*sub.CertExpiresAt) < certExpiryWarning {
+		a.AddIssue(map[string]interface{}{
+			"type":        "subdomain_certificate_expiring",
+			"severity":    "medium",
+			"description": fmt.Sprintf("Сертификат поддомена %s истекает %s", sub.Host, sub.CertExpiresAt.Format("2006-01-02")),
+			"url":         subURL,
+		})
+		a.AddRecommendation(fmt.Sprintf("Продлите сертификат %s и настройте автоматическое продление", sub.Host))
+		misconfigured = true
+	}
+	if sub.DefaultPage != "" {
+		a.AddIssue(map[string]interface{}{
+			"type":        "subdomain_default_page",
+			"severity":    "medium",
+			"description": fmt.Sprintf("Поддомен %s отдает стандартную страницу %s вместо сайта", sub.Host, sub.DefaultPage),
+			"url":         subURL,
+			"server":      sub.DefaultPage,
+		})
+		a.AddRecommendation(fmt.Sprintf("Настройте сайт на %s или удалите поддомен, если он не используется", sub.Host))
+		misconfigured = true
+	}
+	return misconfigured
+}
@@ -0,0 +1,140 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// FamilyProbe - результат запроса к сайту по одному семейству адресов (IPv4 или IPv6)
+type FamilyProbe struct {
+	Reachable  bool   `json:"reachable"`
+	StatusCode int    `json:"status_code,omitempty"`
+	LatencyMs  int64  `json:"latency_ms,omitempty"`
+	Error      string `json:"error,omitempty"`
+	// Skipped - проверка невозможна, так как у сервера анализа нет маршрута в эту сеть
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// DualStackInfo описывает доступность сайта по IPv4 и IPv6
+type DualStackInfo struct {
+	IPv4Addresses []string     `json:"ipv4_addresses"`
+	IPv6Addresses []string     `json:"ipv6_addresses"`
+	IPv4          *FamilyProbe `json:"ipv4,omitempty"`
+	IPv6          *FamilyProbe `json:"ipv6,omitempty"`
+	// LatencyDiffMs - задержка по IPv6 минус задержка по IPv4
+	LatencyDiffMs *int64 `json:"latency_diff_ms,omitempty"`
+	DualStack     bool   `json:"dual_stack"`
+}
+
+// checkDualStack разделяет адреса хоста по семействам и запрашивает страницу
+// отдельно по IPv4 и по IPv6
+func (a *InfrastructureAnalyzer) checkDualStack(ctx context.Context, pageURL string, addresses []string) *DualStackInfo {
+	info := &DualStackInfo{IPv4Addresses: []string{}, IPv6Addresses: []string{}}
+	for _, addr := range addresses {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			continue
+		}
+		if ip.To4() != nil {
+			info.IPv4Addresses = append(info.IPv4Addresses, addr)
+		} else {
+			info.IPv6Addresses = append(info.IPv6Addresses, addr)
+		}
+	}
+
+	if len(info.IPv4Addresses) > 0 {
+		info.IPv4 = a.probeFamily(ctx, pageURL, "tcp4")
+	}
+	if len(info.IPv6Addresses) > 0 {
+		if hasIPv6Route() {
+			info.IPv6 = a.probeFamily(ctx, pageURL, "tcp6")
+		} else {
+			info.IPv6 = &FamilyProbe{Skipped: true, Error: "no IPv6 connectivity on the analysis server"}
+		}
+	}
+
+	if info.IPv4 != nil && info.IPv4.Reachable && info.IPv6 != nil && info.IPv6.Reachable {
+		info.DualStack = true
+		diff := info.IPv6.LatencyMs - info.IPv4.LatencyMs
+		info.LatencyDiffMs = &diff
+	}
+	return info
+}
+
+// probeFamily запрашивает страницу, подключаясь только по указанной сети
+// ("tcp4" или "tcp6"), и измеряет время до получения заголовков ответа
+func (a *InfrastructureAnalyzer) probeFamily(ctx context.Context, pageURL, network string) *FamilyProbe {
+	dialer := &net.Dialer{Timeout: infrastructureTimeout}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+		TLSHandshakeTimeout: infrastructureTimeout,
+		DisableKeepAlives:   true,
+	}
+	defer transport.CloseIdleConnections()
+
+	client := &http.Client{Transport: transport, Timeout: infrastructureTimeout}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return &FamilyProbe{Error: err.Error()}
+	}
+	req.Header.Set("User-Agent", parser.DesktopDevice.UserAgent)
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return &FamilyProbe{Error: err.Error()}
+	}
+	resp.Body.Close()
+
+	return &FamilyProbe{
+		Reachable:  true,
+		StatusCode: resp.StatusCode,
+		LatencyMs:  time.Since(start).Milliseconds(),
+	}
+}
+
+// reportDualStackIssues добавляет проблемы, связанные с поддержкой IPv6
+func (a *InfrastructureAnalyzer) reportDualStackIssues(info *DualStackInfo) {
+	if info.IPv4 == nil || !info.IPv4.Reachable {
+		return
+	}
+
+	if len(info.IPv6Addresses) == 0 {
+		a.AddIssue(map[string]interface{}{
+			"type":        "no_ipv6",
+			"severity":    "low",
+			"description": "Для домена не настроена AAAA-запись: сайт недоступен по IPv6",
+		})
+		a.AddRecommendation("Добавьте AAAA-запись для домена, чтобы сайт был доступен пользователям мобильных сетей и провайдеров, работающих только по IPv6")
+		return
+	}
+
+	if info.IPv6 != nil && !info.IPv6.Reachable && !info.IPv6.Skipped {
+		a.AddIssue(map[string]interface{}{
+			"type":        "ipv6_unreachable",
+			"severity":    "medium",
+			"description": "AAAA-запись настроена, но сайт не отвечает по IPv6",
+			"addresses":   info.IPv6Addresses,
+			"error":       info.IPv6.Error,
+		})
+		a.AddRecommendation(fmt.Sprintf("Проверьте, что веб-сервер принимает подключения по IPv6-адресу %s, либо удалите неработающую AAAA-запись", info.IPv6Addresses[0]))
+	}
+}
+
+// hasIPv6Route проверяет, есть ли у сервера анализа маршрут в IPv6-интернет.
+// UDP-"подключение" не отправляет пакетов, а только выбирает маршрут.
+func hasIPv6Route() bool {
+	conn, err := net.Dial("udp6", "[2001:4860:4860::8888]:53")
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
@@ -26,14 +26,23 @@ type ProgressUpdate struct {
 type AnalyzerType string
 
 const (
-	SEOType           AnalyzerType = "seo"
-	PerformanceType   AnalyzerType = "performance"
-	StructureType     AnalyzerType = "structure"
-	AccessibilityType AnalyzerType = "accessibility"
-	SecurityType      AnalyzerType = "security"
-	MobileType        AnalyzerType = "mobile"
-	ContentType       AnalyzerType = "content"
-	LighthouseType    AnalyzerType = "lighthouse"
+	SEOType            AnalyzerType = "seo"
+	PerformanceType    AnalyzerType = "performance"
+	StructureType      AnalyzerType = "structure"
+	AccessibilityType  AnalyzerType = "accessibility"
+	SecurityType       AnalyzerType = "security"
+	MobileType         AnalyzerType = "mobile"
+	ContentType        AnalyzerType = "content"
+	LighthouseType     AnalyzerType = "lighthouse"
+	GeoType            AnalyzerType = "geo"
+	ChecklistType      AnalyzerType = "checklist"
+	InfrastructureType AnalyzerType = "infrastructure"
+	NoScriptType       AnalyzerType = "noscript"
+	JourneysType       AnalyzerType = "journeys"
+	DomainType         AnalyzerType = "domain"
+	// CustomRulesType runs the rules of an organization; it is registered
+	// with its rules through NewCustomRulesAnalyzer, not by the factory
+	CustomRulesType AnalyzerType = "custom_rules"
 )
 
 // All analyzer types in a slice for easy iteration
@@ -46,6 +55,12 @@ var AllAnalyzerTypes = []AnalyzerType{
 	StructureType,
 	MobileType,
 	ContentType,
+	GeoType,
+	ChecklistType,
+	InfrastructureType,
+	NoScriptType,
+	JourneysType,
+	DomainType,
 }
 
 // AnalyzerFactory creates analyzers of a specified type
@@ -84,11 +99,31 @@ func (f *AnalyzerFactory) CreateAnalyzer(analyzerType AnalyzerType) (Analyzer, e
 		analyzer = NewMobileAnalyzer()
 		analyzer.SetPriority(20)
 	case ContentType:
-		analyzer = NewContentAnalyzer()
+		content := NewContentAnalyzer()
+		content.SetOriginalityChecker(newOriginalityChecker(f.config), f.config.OriginalityMinSimilarity)
+		analyzer = content
 		analyzer.SetPriority(10)
 	case LighthouseType:
 		analyzer = NewLighthouseAnalyzer(f.config)
 		analyzer.SetPriority(100) // Highest priority
+	case GeoType:
+		analyzer = NewGeoAnalyzer(f.config)
+		analyzer.SetPriority(15)
+	case ChecklistType:
+		analyzer = NewChecklistAnalyzer()
+		analyzer.SetPriority(5)
+	case InfrastructureType:
+		analyzer = NewInfrastructureAnalyzer(f.config)
+		analyzer.SetPriority(12)
+	case NoScriptType:
+		analyzer = NewNoScriptAnalyzer()
+		analyzer.SetPriority(8)
+	case JourneysType:
+		analyzer = NewJourneysAnalyzer()
+		analyzer.SetPriority(6)
+	case DomainType:
+		analyzer = NewDomainAnalyzer(f.config)
+		analyzer.SetPriority(11)
 	default:
 		return nil, fmt.Errorf("unknown analyzer type: %s", analyzerType)
 	}
@@ -103,10 +138,13 @@ type AnalyzerManager struct {
 	factory           *AnalyzerFactory
 	mu                sync.RWMutex // For thread-safe access
 	progressCallback  func(ProgressUpdate)
-	dependencyGraph   map[AnalyzerType][]AnalyzerType // Defines which analyzer depends on which
+	dependencyGraph   DependencyGraph // Defines which analyzer depends on which
 	isExecuting       bool
 	executingMu       sync.Mutex
 	analysisStartTime time.Time
+	retries           map[AnalyzerType]int // Retries of each analyzer after transient errors
+	retriesMu         sync.Mutex
+	throttle          *Throttle // shared by the analyses of the instance
 }
 
 // NewAnalyzerManager creates a new analysis manager
@@ -117,8 +155,9 @@ func NewAnalyzerManager() *AnalyzerManager {
 		analyzers:       make(map[AnalyzerType]Analyzer),
 		config:          config,
 		factory:         NewAnalyzerFactory(config),
-		dependencyGraph: buildDefaultDependencyGraph(),
+		dependencyGraph: CurrentDependencyGraph(config.AnalyzerDependenciesFile).Graph,
 		isExecuting:     false,
+		throttle:        CurrentThrottle(config),
 	}
 
 	return manager
@@ -131,7 +170,8 @@ func buildDefaultDependencyGraph() map[AnalyzerType][]AnalyzerType {
 
 	// Lighthouse provides data for many other analyzers
 	dependencies[SEOType] = []AnalyzerType{LighthouseType}
-	dependencies[PerformanceType] = []AnalyzerType{LighthouseType}
+	// Infrastructure detects the site's CDN used for image transformation URLs
+	dependencies[PerformanceType] = []AnalyzerType{LighthouseType, InfrastructureType}
 	dependencies[AccessibilityType] = []AnalyzerType{LighthouseType}
 	dependencies[SecurityType] = []AnalyzerType{LighthouseType}
 	dependencies[MobileType] = []AnalyzerType{LighthouseType}
@@ -142,6 +182,10 @@ func buildDefaultDependencyGraph() map[AnalyzerType][]AnalyzerType {
 
 // RegisterAnalyzer registers an analyzer of a specific type
 func (m *AnalyzerManager) RegisterAnalyzer(analyzerType AnalyzerType, analyzer Analyzer) {
+	if m.config != nil && m.config.ChaosEnabled {
+		analyzer = NewChaosAnalyzer(analyzer)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -167,6 +211,7 @@ func (m *AnalyzerManager) RegisterAllAnalyzers() {
 	for _, aType := range []AnalyzerType{
 		SEOType, PerformanceType, StructureType,
 		AccessibilityType, SecurityType, MobileType, ContentType,
+		InfrastructureType, NoScriptType,
 	} {
 		analyzer, err := m.factory.CreateAnalyzer(aType)
 		if err == nil {
@@ -175,6 +220,57 @@ func (m *AnalyzerManager) RegisterAllAnalyzers() {
 			log.Printf("Failed to create analyzer %s: %v", aType, err)
 		}
 	}
+
+	m.registerGeoAnalyzer()
+	m.registerDomainAnalyzer()
+}
+
+// RegisterAnalyzers registers the given analyzers, e.g. the selection of a
+// preset. Lighthouse is skipped when no API key is configured.
+func (m *AnalyzerManager) RegisterAnalyzers(types []AnalyzerType) {
+	for _, aType := range types {
+		if aType == LighthouseType && m.config.LighthouseAPIKey == "" {
+			log.Println("Lighthouse API key not provided, skipping Lighthouse analyzer")
+			continue
+		}
+
+		analyzer, err := m.factory.CreateAnalyzer(aType)
+		if err == nil {
+			m.RegisterAnalyzer(aType, analyzer)
+		} else {
+			log.Printf("Failed to create analyzer %s: %v", aType, err)
+		}
+	}
+}
+
+// registerGeoAnalyzer registers the locale variant analyzer when it is enabled.
+// It fetches the page once per configured locale, so it is opt-in.
+func (m *AnalyzerManager) registerGeoAnalyzer() {
+	if !m.config.GeoVariantDetection || len(m.config.GeoVariantLocales) == 0 {
+		return
+	}
+
+	analyzer, err := m.factory.CreateAnalyzer(GeoType)
+	if err != nil {
+		log.Printf("Failed to create analyzer %s: %v", GeoType, err)
+		return
+	}
+	m.RegisterAnalyzer(GeoType, analyzer)
+}
+
+// registerDomainAnalyzer registers the subdomain inventory when it is enabled.
+// It probes every subdomain it finds, so it is opt-in.
+func (m *AnalyzerManager) registerDomainAnalyzer() {
+	if !m.config.SubdomainInventory {
+		return
+	}
+
+	analyzer, err := m.factory.CreateAnalyzer(DomainType)
+	if err != nil {
+		log.Printf("Failed to create analyzer %s: %v", DomainType, err)
+		return
+	}
+	m.RegisterAnalyzer(DomainType, analyzer)
 }
 
 // RunAnalyzer runs a specific analyzer
@@ -202,9 +298,17 @@ func (m *AnalyzerManager) RunAnalyzer(
 		})
 	}
 
-	// Run the analysis
+	release, err := m.throttle.Acquire(ctx, analyzerType)
+	if err != nil {
+		return nil, fmt.Errorf("%s analysis was not started: %w", analyzerType, err)
+	}
+	defer release()
+
+	// Run the analysis, retrying transient failures
 	startTime := time.Now()
-	result, err := analyzer.Analyze(ctx, data, prevResults)
+	result, retries, err := m.analyzeWithRetries(ctx, analyzerType, analyzer, data, prevResults)
+	m.recordRetries(analyzerType, retries)
+	result = withRetries(result, retries)
 
 	// Emit progress update upon completion
 	if m.progressCallback != nil {
@@ -242,11 +346,13 @@ func (m *AnalyzerManager) RegisterCriticalAnalyzers() {
 		log.Println("Lighthouse API key not provided, skipping Lighthouse analyzer")
 	}
 
-	// Register only the most important analyzers - SEO, Security, and Performance
+	// Register only the most important analyzers - SEO, Security, and Performance,
+	// plus the cheap infrastructure detection whose results are kept with the analysis
 	criticalAnalyzers := []AnalyzerType{
 		SEOType,
 		SecurityType,
 		PerformanceType,
+		InfrastructureType,
 	}
 
 	for _, aType := range criticalAnalyzers {
@@ -257,6 +363,8 @@ func (m *AnalyzerManager) RegisterCriticalAnalyzers() {
 			log.Printf("Failed to create analyzer %s: %v", aType, err)
 		}
 	}
+
+	m.registerGeoAnalyzer()
 }
 
 // RunAllAnalyzers runs all registered analyzers with improved timeouts and error handling
@@ -270,6 +378,10 @@ func (m *AnalyzerManager) RunAllAnalyzers(ctx context.Context, data *parser.Webs
 	m.analysisStartTime = time.Now()
 	m.executingMu.Unlock()
 
+	m.retriesMu.Lock()
+	m.retries = make(map[AnalyzerType]int)
+	m.retriesMu.Unlock()
+
 	defer func() {
 		m.executingMu.Lock()
 		m.isExecuting = false
@@ -320,7 +432,8 @@ func (m *AnalyzerManager) RunAllAnalyzers(ctx context.Context, data *parser.Webs
 			err          error
 		}, len(layer))
 
-		// Execute all analyzers in this layer in parallel
+		// Execute the analyzers of this layer in parallel, as far as the
+		// throttle shared with other analyses allows
 		for _, analyzerType := range layer {
 			m.mu.RLock()
 			analyzer, exists := m.analyzers[analyzerType]
@@ -341,20 +454,43 @@ func (m *AnalyzerManager) RunAllAnalyzers(ctx context.Context, data *parser.Webs
 				}
 				resultsMutex.RUnlock()
 
+				// Wait for room among the analyzers running on the instance
+				queuedAt := time.Now()
+				release, err := m.throttle.Acquire(layerCtx, at)
+				queued := time.Since(queuedAt)
+				if err != nil {
+					err = fmt.Errorf("%s analysis was not started after waiting %v: %w", at, queued, err)
+					resultChan <- struct {
+						analyzerType AnalyzerType
+						result       map[string]interface{}
+						err          error
+					}{at, nil, err}
+					return
+				}
+				if queued >= time.Second {
+					log.Printf("Analyzer %s waited %v for capacity", at, queued)
+				}
+
 				// Report start of analyzer
 				if m.progressCallback != nil {
 					m.progressCallback(ProgressUpdate{
 						AnalyzerType: string(at),
 						Progress:     0.0,
 						Message:      fmt.Sprintf("Starting %s analysis", at),
-						Timestamp:    time.Now(),
+						Details: map[string]interface{}{
+							"queued_ms": queued.Milliseconds(),
+						},
+						Timestamp: time.Now(),
 					})
 				}
 
-				// Execute the analyzer with timeout
+				// Execute the analyzer with timeout, retrying transient failures
 				startTime := time.Now()
-				result, err := a.Analyze(layerCtx, data, prevResults)
+				result, retries, err := m.analyzeWithRetries(layerCtx, at, a, data, prevResults)
 				duration := time.Since(startTime)
+				release()
+				m.recordRetries(at, retries)
+				result = withRetries(result, retries)
 
 				// Send result to channel
 				resultChan <- struct {
@@ -370,13 +506,22 @@ func (m *AnalyzerManager) RunAllAnalyzers(ctx context.Context, data *parser.Webs
 							AnalyzerType: string(at),
 							Progress:     100.0,
 							Message:      fmt.Sprintf("Failed in %v: %v", duration, err),
-							Timestamp:    time.Now(),
+							Details: map[string]interface{}{
+								"duration_ms": duration.Milliseconds(),
+								"error":       err.Error(),
+								"retries":     retries,
+							},
+							Timestamp: time.Now(),
 						})
 					} else {
 						m.progressCallback(ProgressUpdate{
-							AnalyzerType:   string(at),
-							Progress:       100.0,
-							Message:        fmt.Sprintf("Completed in %v", duration),
+							AnalyzerType: string(at),
+							Progress:     100.0,
+							Message:      fmt.Sprintf("Completed in %v", duration),
+							Details: map[string]interface{}{
+								"duration_ms": duration.Milliseconds(),
+								"retries":     retries,
+							},
 							PartialResults: result,
 							Timestamp:      time.Now(),
 						})
@@ -0,0 +1,337 @@
+package analyzer
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/config"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+)
+
+const (
+	// geoFetchTimeout ограничивает время загрузки одного варианта страницы
+	geoFetchTimeout = 15 * time.Second
+	// maxHreflangChecks ограничивает число проверяемых hreflang-ссылок
+	maxHreflangChecks = 10
+)
+
+// GeoAnalyzer загружает страницу с разными Accept-Language (и, при наличии,
+// через региональные прокси), чтобы обнаружить гео-таргетированный контент
+// и проверить, что hreflang соответствует реально отдаваемым версиям
+type GeoAnalyzer struct {
+	*BaseAnalyzer
+	locales []string
+	proxies map[string]string
+}
+
+// NewGeoAnalyzer создает новый анализатор языковых и региональных версий
+func NewGeoAnalyzer(cfg *config.Config) *GeoAnalyzer {
+	return &GeoAnalyzer{
+		BaseAnalyzer: NewBaseAnalyzer(GeoType),
+		locales:      cfg.GeoVariantLocales,
+		proxies:      cfg.GeoVariantProxies,
+	}
+}
+
+// geoVariantResult описывает отличия варианта страницы от версии по умолчанию
+type geoVariantResult struct {
+	*parser.LocaleVariant
+	Differences []string `json:"differences,omitempty"`
+}
+
+// hreflangCheck описывает результат проверки одной hreflang-ссылки
+type hreflangCheck struct {
+	Lang        string `json:"hreflang"`
+	URL         string `json:"href"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	ServedLang  string `json:"served_lang,omitempty"`
+	LangMatches bool   `json:"lang_matches"`
+	Reciprocal  bool   `json:"reciprocal"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Analyze выполняет анализ языковых и региональных версий страницы
+func (a *GeoAnalyzer) Analyze(ctx context.Context, data *parser.WebsiteData, prevResults map[AnalyzerType]map[string]interface{}) (map[string]interface{}, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	html := data.RawHTML
+	if html == "" {
+		html = data.HTML
+	}
+	baseline := parser.ParseLocaleSignals(html)
+	baseline.URL = data.URL
+	baseline.FinalURL = data.FinalURL
+	if baseline.FinalURL == "" {
+		baseline.FinalURL = data.URL
+	}
+	a.SetMetric("default_variant", baseline)
+
+	// Загружаем варианты страницы параллельно
+	variants := make([]geoVariantResult, len(a.locales))
+	var wg sync.WaitGroup
+	for i, locale := range a.locales {
+		wg.Add(1)
+		go func(i int, locale string) {
+			defer wg.Done()
+			variant, err := parser.FetchLocaleVariant(ctx, data.URL, locale, a.proxies[locale], "", geoFetchTimeout)
+			if err != nil {
+				variant = &parser.LocaleVariant{Locale: locale, URL: data.URL, Error: err.Error()}
+			}
+			variants[i] = geoVariantResult{LocaleVariant: variant}
+		}(i, locale)
+	}
+	wg.Wait()
+
+	geoTargeted := false
+	varyByLanguage := false
+	var adaptiveLocales []string
+	for i := range variants {
+		variant := &variants[i]
+		if variant.Error != "" {
+			continue
+		}
+		variant.Differences = diffVariants(&baseline, variant.LocaleVariant)
+		if len(variant.Differences) == 0 {
+			continue
+		}
+		geoTargeted = true
+		if strings.Contains(strings.ToLower(variant.Vary), "accept-language") {
+			varyByLanguage = true
+		}
+		if !contains(variant.Differences, "final_url") {
+			adaptiveLocales = append(adaptiveLocales, variant.Locale)
+		}
+	}
+
+	a.SetMetric("locales_probed", a.locales)
+	a.SetMetric("variants", variants)
+	a.SetMetric("geo_targeted", geoTargeted)
+
+	checks := a.verifyHreflang(ctx, &baseline, variants)
+	a.SetMetric("hreflang", checks)
+
+	a.reportIssues(&baseline, geoTargeted, varyByLanguage, adaptiveLocales, checks)
+
+	a.SetMetric("score", a.CalculateScore())
+	return a.GetMetrics(), nil
+}
+
+// diffVariants возвращает список сигналов, которые отличаются у варианта
+func diffVariants(baseline, variant *parser.LocaleVariant) []string {
+	var differences []string
+	if variant.Title != baseline.Title {
+		differences = append(differences, "title")
+	}
+	if variant.Description != baseline.Description {
+		differences = append(differences, "description")
+	}
+	if !strings.EqualFold(variant.Lang, baseline.Lang) {
+		differences = append(differences, "lang")
+	}
+	if variant.Canonical != baseline.Canonical {
+		differences = append(differences, "canonical")
+	}
+	if variant.ContentHash != baseline.ContentHash {
+		differences = append(differences, "content")
+	}
+	if !sameURL(variant.FinalURL, baseline.FinalURL) {
+		differences = append(differences, "final_url")
+	}
+	return differences
+}
+
+// verifyHreflang проверяет, что страницы из hreflang доступны, отдают
+// заявленный язык и ссылаются обратно на исходную страницу
+func (a *GeoAnalyzer) verifyHreflang(ctx context.Context, baseline *parser.LocaleVariant, variants []geoVariantResult) []hreflangCheck {
+	base, err := url.Parse(baseline.FinalURL)
+	if err != nil {
+		return nil
+	}
+
+	var links []parser.HreflangLink
+	for _, link := range baseline.Hreflang {
+		if link.Lang == "x-default" {
+			continue
+		}
+		if len(links) == maxHreflangChecks {
+			break
+		}
+		if ref, err := base.Parse(link.URL); err == nil {
+			link.URL = ref.String()
+		}
+		links = append(links, link)
+	}
+
+	checks := make([]hreflangCheck, len(links))
+	var wg sync.WaitGroup
+	for i, link := range links {
+		checks[i] = hreflangCheck{Lang: link.Lang, URL: link.URL}
+
+		// Для ссылки на саму страницу используем уже загруженный вариант
+		if sameURL(link.URL, baseline.FinalURL) {
+			served := baseline
+			for _, variant := range variants {
+				if variant.Error == "" && languageOf(variant.Locale) == languageOf(link.Lang) {
+					served = variant.LocaleVariant
+					break
+				}
+			}
+			checks[i].StatusCode = served.StatusCode
+			checks[i].ServedLang = served.Lang
+			checks[i].LangMatches = served.Lang == "" || languageOf(served.Lang) == languageOf(link.Lang)
+			checks[i].Reciprocal = true
+			continue
+		}
+
+		wg.Add(1)
+		go func(check *hreflangCheck) {
+			defer wg.Done()
+			variant, err := parser.FetchLocaleVariant(ctx, check.URL, check.Lang, a.proxies[check.Lang], "", geoFetchTimeout)
+			if err != nil {
+				check.Error = err.Error()
+				return
+			}
+			check.StatusCode = variant.StatusCode
+			check.ServedLang = variant.Lang
+			check.LangMatches = variant.Lang == "" || languageOf(variant.Lang) == languageOf(check.Lang)
+
+			target, err := url.Parse(variant.FinalURL)
+			if err != nil {
+				return
+			}
+			for _, back := range variant.Hreflang {
+				if ref, err := target.Parse(back.URL); err == nil && sameURL(ref.String(), baseline.FinalURL) {
+					check.Reciprocal = true
+					break
+				}
+			}
+		}(&checks[i])
+	}
+	wg.Wait()
+
+	return checks
+}
+
+// reportIssues добавляет проблемы и рекомендации по результатам проверок
+func (a *GeoAnalyzer) reportIssues(baseline *parser.LocaleVariant, geoTargeted, varyByLanguage bool, adaptiveLocales []string, checks []hreflangCheck) {
+	if geoTargeted && len(baseline.Hreflang) == 0 {
+		a.AddIssue(map[string]interface{}{
+			"type":        "geo_content_without_hreflang",
+			"severity":    "high",
+			"description": "Содержимое страницы зависит от языка или региона посетителя, но аннотации hreflang отсутствуют",
+		})
+		a.AddRecommendation("Добавьте <link rel=\"alternate\" hreflang=\"...\"> для каждой языковой версии страницы, чтобы поисковые системы показывали нужную версию")
+	}
+
+	if len(adaptiveLocales) > 0 && !varyByLanguage {
+		a.AddIssue(map[string]interface{}{
+			"type":        "missing_vary_accept_language",
+			"severity":    "medium",
+			"description": "Один и тот же URL отдает разное содержимое в зависимости от Accept-Language без заголовка Vary: Accept-Language",
+			"locales":     adaptiveLocales,
+		})
+		a.AddRecommendation("Отправляйте заголовок Vary: Accept-Language или вынесите языковые версии на отдельные URL, чтобы кеши и поисковые роботы не смешивали версии")
+	}
+
+	if len(adaptiveLocales) > 0 && len(baseline.Hreflang) > 0 {
+		a.AddIssue(map[string]interface{}{
+			"type":        "hreflang_adaptive_content",
+			"severity":    "medium",
+			"description": "Страница объявляет отдельные языковые версии через hreflang, но сама меняет содержимое по Accept-Language",
+			"locales":     adaptiveLocales,
+		})
+		a.AddRecommendation("Отдавайте на каждом URL из hreflang одну и ту же языковую версию независимо от Accept-Language; поисковые роботы обычно не отправляют этот заголовок")
+	}
+
+	if len(baseline.Hreflang) > 0 {
+		hasDefault := false
+		for _, link := range baseline.Hreflang {
+			if link.Lang == "x-default" {
+				hasDefault = true
+				break
+			}
+		}
+		if !hasDefault {
+			a.AddIssue(map[string]interface{}{
+				"type":        "missing_hreflang_x_default",
+				"severity":    "low",
+				"description": "Среди аннотаций hreflang нет x-default",
+			})
+			a.AddRecommendation("Добавьте hreflang=\"x-default\" для версии, которую следует показывать посетителям с неподдерживаемым языком")
+		}
+	}
+
+	for _, check := range checks {
+		switch {
+		case check.Error != "" || check.StatusCode >= 400:
+			a.AddIssue(map[string]interface{}{
+				"type":        "hreflang_unreachable",
+				"severity":    "high",
+				"description": "Страница из аннотации hreflang недоступна",
+				"hreflang":    check.Lang,
+				"url":         check.URL,
+				"status_code": check.StatusCode,
+			})
+		case !check.LangMatches:
+			a.AddIssue(map[string]interface{}{
+				"type":        "hreflang_language_mismatch",
+				"severity":    "medium",
+				"description": "Язык страницы не совпадает с языком, указанным в hreflang",
+				"hreflang":    check.Lang,
+				"served_lang": check.ServedLang,
+				"url":         check.URL,
+			})
+		case !check.Reciprocal:
+			a.AddIssue(map[string]interface{}{
+				"type":        "hreflang_not_reciprocal",
+				"severity":    "medium",
+				"description": "Страница из аннотации hreflang не ссылается обратно на исходную страницу",
+				"hreflang":    check.Lang,
+				"url":         check.URL,
+			})
+		}
+	}
+	for _, check := range checks {
+		if check.Error == "" && check.StatusCode < 400 && (!check.LangMatches || !check.Reciprocal) {
+			a.AddRecommendation("Убедитесь, что каждая языковая версия указывает верный код языка и содержит полный взаимный набор hreflang-ссылок")
+			break
+		}
+	}
+}
+
+// languageOf возвращает основной языковой подтег, например "de" для "de-AT"
+func languageOf(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+// sameURL сравнивает URL без учета схемы, регистра хоста и параметров отслеживания
+func sameURL(a, b string) bool {
+	keyA, errA := urlnorm.Key(a)
+	keyB, errB := urlnorm.Key(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return keyA == keyB
+}
+
+// contains проверяет наличие строки в срезе
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
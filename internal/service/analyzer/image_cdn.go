@@ -0,0 +1,227 @@
+package analyzer
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+const (
+	// largeImageBytes - размер, начиная с которого изображение считается большим
+	largeImageBytes = 100000
+	// rewriteQuality - качество сжатия в предлагаемых URL
+	rewriteQuality = 75
+	// defaultRewriteWidth - ширина, если в разметке она не указана
+	defaultRewriteWidth = 1280
+	// maxRewriteWidth ограничивает ширину с учетом экранов высокой плотности
+	maxRewriteWidth = 2560
+)
+
+// cloudinaryVersion - сегмент версии в пути Cloudinary (v1712345678)
+var cloudinaryVersion = regexp.MustCompile(`^v\d+$`)
+
+// ImageRewrite - URL преобразованного изображения, которым можно заменить исходный
+type ImageRewrite struct {
+	URL          string `json:"url"`
+	CDN          string `json:"cdn"`
+	Size         int64  `json:"size"`
+	Width        int    `json:"width"`
+	SuggestedURL string `json:"suggested_url"`
+}
+
+// analyzeImageCDN предлагает для больших изображений готовые URL с
+// преобразованием формата, качества и ширины средствами CDN, через который
+// изображение уже отдается: Cloudinary, Imgix, Cloudflare Images или CDN самого сайта
+func (a *PerformanceAnalyzer) analyzeImageCDN(data *parser.WebsiteData, prevResults map[AnalyzerType]map[string]interface{}) {
+	pageURL := data.FinalURL
+	if pageURL == "" {
+		pageURL = data.URL
+	}
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return
+	}
+	siteCDN := siteCDNFromResults(prevResults)
+
+	rewrites := []ImageRewrite{}
+	for _, img := range data.Images {
+		if img.FileSize <= largeImageBytes {
+			continue
+		}
+		imageURL, err := url.Parse(img.URL)
+		if err != nil || imageURL.Host == "" {
+			continue
+		}
+
+		width := rewriteWidth(img.Width)
+		suggested, cdn := rewriteImageURL(imageURL, width, page.Hostname(), siteCDN)
+		if suggested == "" || suggested == img.URL {
+			continue
+		}
+		rewrites = append(rewrites, ImageRewrite{
+			URL:          img.URL,
+			CDN:          cdn,
+			Size:         img.FileSize,
+			Width:        width,
+			SuggestedURL: suggested,
+		})
+	}
+
+	a.SetMetric("image_cdn_rewrites", rewrites)
+	if len(rewrites) == 0 {
+		return
+	}
+
+	examples := rewrites
+	if len(examples) > 5 {
+		examples = examples[:5]
+	}
+	a.AddIssue(map[string]interface{}{
+		"type":        "image_cdn_transform_available",
+		"severity":    "low",
+		"description": fmt.Sprintf("Крупные изображения отдаются через %s без преобразования формата, качества и размера", rewrites[0].CDN),
+		"count":       len(rewrites),
+		"examples":    examples,
+	})
+	a.AddRecommendation(fmt.Sprintf("Используйте преобразования %s: например, замените %s на %s. Готовые URL для всех изображений приведены в метрике image_cdn_rewrites", rewrites[0].CDN, rewrites[0].URL, rewrites[0].SuggestedURL))
+}
+
+// siteCDNFromResults возвращает CDN сайта, определенный анализатором инфраструктуры
+func siteCDNFromResults(prevResults map[AnalyzerType]map[string]interface{}) string {
+	if results, ok := prevResults[InfrastructureType]; ok {
+		if info, ok := results["infrastructure"].(*InfrastructureInfo); ok {
+			return info.CDN
+		}
+	}
+	return ""
+}
+
+// rewriteWidth возвращает ширину для преобразования: удвоенную ширину из
+// разметки для экранов высокой плотности или ширину по умолчанию
+func rewriteWidth(attr string) int {
+	width, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(attr), "px"))
+	if err != nil || width <= 0 {
+		return defaultRewriteWidth
+	}
+	if width*2 > maxRewriteWidth {
+		return maxRewriteWidth
+	}
+	return width * 2
+}
+
+// rewriteImageURL строит URL изображения с преобразованием формата, качества
+// и ширины. Возвращает пустую строку, если CDN изображения не поддерживается.
+func rewriteImageURL(u *url.URL, width int, pageHost, siteCDN string) (string, string) {
+	host := strings.ToLower(u.Hostname())
+
+	switch {
+	case host == "res.cloudinary.com" || strings.HasSuffix(host, ".cloudinary.com"):
+		return rewriteCloudinary(u, width), "Cloudinary"
+	case strings.HasSuffix(host, ".imgix.net"):
+		query := u.Query()
+		query.Set("auto", "format,compress")
+		query.Set("w", strconv.Itoa(width))
+		query.Set("q", strconv.Itoa(rewriteQuality))
+		return withQuery(u, query), "Imgix"
+	case host == "imagedelivery.net":
+		// Гибкие варианты Cloudflare Images: /<account hash>/<image id>/<variant>
+		segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(segments) < 2 {
+			return "", ""
+		}
+		rewritten := *u
+		rewritten.Path = "/" + strings.Join(segments[:2], "/") + "/" + cloudflareOptions(width)
+		return rewritten.String(), "Cloudflare Images"
+	case strings.HasPrefix(u.Path, "/cdn-cgi/image/"):
+		// Изображение уже проходит через Cloudflare Image Resizing: заменяем параметры
+		rest := strings.TrimPrefix(u.Path, "/cdn-cgi/image/")
+		if i := strings.Index(rest, "/"); i >= 0 {
+			rewritten := *u
+			rewritten.Path = "/cdn-cgi/image/" + cloudflareOptions(width) + rest[i:]
+			return rewritten.String(), "Cloudflare"
+		}
+		return "", ""
+	}
+
+	if siteCDN == "" || host != strings.ToLower(pageHost) {
+		return "", ""
+	}
+	switch siteCDN {
+	case "Cloudflare":
+		rewritten := *u
+		rewritten.Path = "/cdn-cgi/image/" + cloudflareOptions(width) + u.Path
+		return rewritten.String(), siteCDN
+	case "Fastly":
+		query := u.Query()
+		query.Set("width", strconv.Itoa(width))
+		query.Set("quality", strconv.Itoa(rewriteQuality))
+		query.Set("auto", "webp")
+		return withQuery(u, query), siteCDN
+	case "Bunny CDN":
+		query := u.Query()
+		query.Set("width", strconv.Itoa(width))
+		query.Set("quality", strconv.Itoa(rewriteQuality))
+		return withQuery(u, query), siteCDN
+	case "Netlify":
+		query := url.Values{}
+		query.Set("url", u.RequestURI())
+		query.Set("w", strconv.Itoa(width))
+		query.Set("q", strconv.Itoa(rewriteQuality))
+		rewritten := url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/.netlify/images"}
+		return withQuery(&rewritten, query), siteCDN
+	}
+	return "", ""
+}
+
+// rewriteCloudinary вставляет f_auto,q_auto и ширину в цепочку преобразований
+// Cloudinary, заменяя уже указанные формат, качество и ширину
+func rewriteCloudinary(u *url.URL, width int) string {
+	segments := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	deliveryIndex := -1
+	for i, segment := range segments {
+		if segment == "upload" || segment == "fetch" {
+			deliveryIndex = i
+			break
+		}
+	}
+	if deliveryIndex < 0 || deliveryIndex+1 >= len(segments) {
+		return ""
+	}
+
+	transformation := []string{"f_auto", "q_auto", "w_" + strconv.Itoa(width)}
+	rest := segments[deliveryIndex+1:]
+	// Первый сегмент после upload - цепочка преобразований, если это не версия и не имя файла
+	if next := rest[0]; len(rest) > 1 && !cloudinaryVersion.MatchString(next) && strings.Contains(next, "_") {
+		var kept []string
+		for _, param := range strings.Split(next, ",") {
+			if strings.HasPrefix(param, "f_") || strings.HasPrefix(param, "q_") || strings.HasPrefix(param, "w_") {
+				continue
+			}
+			kept = append(kept, param)
+		}
+		transformation = append(kept, transformation...)
+		rest = rest[1:]
+	}
+
+	path := append(append(segments[:deliveryIndex+1:deliveryIndex+1], strings.Join(transformation, ",")), rest...)
+	rewritten := *u
+	rewritten.Path = "/" + strings.Join(path, "/")
+	return rewritten.String()
+}
+
+// cloudflareOptions - параметры преобразования Cloudflare Images и Image Resizing
+func cloudflareOptions(width int) string {
+	return fmt.Sprintf("width=%d,quality=%d,format=auto", width, rewriteQuality)
+}
+
+// withQuery возвращает URL с новыми параметрами запроса; запятые не экранируются,
+// чтобы URL было удобно читать и копировать
+func withQuery(u *url.URL, query url.Values) string {
+	rewritten := *u
+	rewritten.RawQuery = strings.ReplaceAll(query.Encode(), "%2C", ",")
+	return rewritten.String()
+}
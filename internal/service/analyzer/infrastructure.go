@@ -0,0 +1,485 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/config"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// infrastructureTimeout ограничивает время запросов к сайту и сервису геолокации
+const infrastructureTimeout = 10 * time.Second
+
+// cdnHeaderSignatures - заголовки ответа, по которым определяется CDN
+var cdnHeaderSignatures = map[string]string{
+	"cf-ray":               "Cloudflare",
+	"cf-cache-status":      "Cloudflare",
+	"x-amz-cf-id":          "Amazon CloudFront",
+	"x-amz-cf-pop":         "Amazon CloudFront",
+	"x-fastly-request-id":  "Fastly",
+	"fastly-debug-digest":  "Fastly",
+	"x-akamai-transformed": "Akamai",
+	"akamai-grn":           "Akamai",
+	"x-akamai-request-id":  "Akamai",
+	"x-azure-ref":          "Azure Front Door",
+	"x-msedge-ref":         "Azure CDN",
+	"x-vercel-id":          "Vercel",
+	"x-nf-request-id":      "Netlify",
+	"cdn-pullzone":         "Bunny CDN",
+	"x-sucuri-id":          "Sucuri",
+	"x-77-nzt":             "CDN77",
+	"x-ngenix-cache":       "NGENIX",
+}
+
+// cdnServerSignatures - фрагменты заголовков Server и Via, по которым определяется CDN
+var cdnServerSignatures = map[string]string{
+	"cloudflare":   "Cloudflare",
+	"cloudfront":   "Amazon CloudFront",
+	"akamaighost":  "Akamai",
+	"netlify":      "Netlify",
+	"vercel":       "Vercel",
+	"bunnycdn":     "Bunny CDN",
+	"ddos-guard":   "DDoS-Guard",
+	"qrator":       "Qrator",
+	"ngenix":       "NGENIX",
+	"1.1 google":   "Google Cloud CDN",
+	"fastly":       "Fastly",
+	"gcore":        "G-Core Labs",
+	"stackpath":    "StackPath",
+	"sucuri/cloud": "Sucuri",
+}
+
+// cdnCNAMESuffixes - суффиксы CNAME-записей CDN-провайдеров
+var cdnCNAMESuffixes = map[string]string{
+	".cloudfront.net":     "Amazon CloudFront",
+	".akamaiedge.net":     "Akamai",
+	".edgekey.net":        "Akamai",
+	".edgesuite.net":      "Akamai",
+	".akamai.net":         "Akamai",
+	".fastly.net":         "Fastly",
+	".fastlylb.net":       "Fastly",
+	".cdn.cloudflare.net": "Cloudflare",
+	".azureedge.net":      "Azure CDN",
+	".azurefd.net":        "Azure Front Door",
+	".vercel-dns.com":     "Vercel",
+	".netlify.app":        "Netlify",
+	".netlify.com":        "Netlify",
+	".b-cdn.net":          "Bunny CDN",
+	".cdn77.org":          "CDN77",
+	".ngenix.net":         "NGENIX",
+	".llnwd.net":          "Limelight",
+	".stackpathdns.com":   "StackPath",
+	".incapdns.net":       "Imperva",
+	".gcdn.co":            "G-Core Labs",
+}
+
+// cdnNetworkNames - фрагменты названий автономных систем CDN-провайдеров
+var cdnNetworkNames = map[string]string{
+	"cloudflare": "Cloudflare",
+	"akamai":     "Akamai",
+	"fastly":     "Fastly",
+	"ddos-guard": "DDoS-Guard",
+	"qrator":     "Qrator",
+	"g-core":     "G-Core Labs",
+	"cdn77":      "CDN77",
+	"ngenix":     "NGENIX",
+	"incapsula":  "Imperva",
+}
+
+// genericTLDs - двухбуквенные домены верхнего уровня, которые не указывают на страну
+var genericTLDs = map[string]bool{
+	"io": true, "co": true, "me": true, "tv": true, "ai": true, "cc": true, "fm": true, "gg": true, "ly": true, "to": true,
+}
+
+// IPGeo описывает расположение и сеть IP-адреса сервера
+type IPGeo struct {
+	Country     string `json:"country,omitempty"`
+	CountryCode string `json:"country_code,omitempty"`
+	Region      string `json:"region,omitempty"`
+	City        string `json:"city,omitempty"`
+	ISP         string `json:"isp,omitempty"`
+	Org         string `json:"org,omitempty"`
+	ASN         string `json:"asn,omitempty"`
+	ASName      string `json:"as_name,omitempty"`
+}
+
+// InfrastructureInfo описывает инфраструктуру, которая обслуживает сайт
+type InfrastructureInfo struct {
+	Host           string   `json:"host"`
+	IP             string   `json:"ip,omitempty"`
+	Addresses      []string `json:"addresses,omitempty"`
+	CNAME          string   `json:"cname,omitempty"`
+	CDN            string   `json:"cdn,omitempty"`
+	CDNEvidence    []string `json:"cdn_evidence,omitempty"`
+	ServerSoftware string   `json:"server_software,omitempty"`
+	PoweredBy      string   `json:"powered_by,omitempty"`
+	Protocol       string   `json:"protocol,omitempty"`
+	TLS            bool     `json:"tls"`
+	CacheStatus    string   `json:"cache_status,omitempty"`
+	Geo            *IPGeo   `json:"geo,omitempty"`
+	// Audience - "global", если страница ориентирована на несколько стран или языков, иначе "regional"
+	Audience        string         `json:"audience"`
+	AudienceRegions []string       `json:"audience_regions,omitempty"`
+	DualStack       *DualStackInfo `json:"dual_stack,omitempty"`
+	// UserAgents сравнивает ответы сайта браузеру, поисковому роботу и анализатору
+	UserAgents *UserAgentComparison `json:"user_agents,omitempty"`
+}
+
+// InfrastructureAnalyzer определяет хостинг, CDN, серверное ПО и расположение сервера
+type InfrastructureAnalyzer struct {
+	*BaseAnalyzer
+	geoLookupURL string
+	client       *http.Client
+}
+
+// NewInfrastructureAnalyzer создает новый анализатор инфраструктуры
+func NewInfrastructureAnalyzer(cfg *config.Config) *InfrastructureAnalyzer {
+	return &InfrastructureAnalyzer{
+		BaseAnalyzer: NewBaseAnalyzer(InfrastructureType),
+		geoLookupURL: cfg.IPGeoLookupURL,
+		client:       &http.Client{Timeout: infrastructureTimeout},
+	}
+}
+
+// Analyze выполняет анализ инфраструктуры сайта
+func (a *InfrastructureAnalyzer) Analyze(ctx context.Context, data *parser.WebsiteData, prevResults map[AnalyzerType]map[string]interface{}) (map[string]interface{}, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	pageURL := data.FinalURL
+	if pageURL == "" {
+		pageURL = data.URL
+	}
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page URL: %w", err)
+	}
+
+	info := &InfrastructureInfo{Host: page.Hostname()}
+	cdnEvidence := make(map[string][]string)
+
+	// DNS: адреса и CNAME
+	resolver := net.DefaultResolver
+	if addrs, err := resolver.LookupHost(ctx, info.Host); err == nil {
+		info.Addresses = addrs
+	}
+	if cname, err := resolver.LookupCNAME(ctx, info.Host); err == nil {
+		cname = strings.TrimSuffix(strings.ToLower(cname), ".")
+		if cname != info.Host {
+			info.CNAME = cname
+			for suffix, provider := range cdnCNAMESuffixes {
+				if strings.HasSuffix(cname, suffix) {
+					cdnEvidence[provider] = append(cdnEvidence[provider], "cname:"+cname)
+				}
+			}
+		}
+	}
+
+	// Заголовки ответа и IP-адрес, к которому фактически произошло подключение
+	if resp, remoteIP, err := a.fetchHeaders(ctx, pageURL); err == nil {
+		info.IP = remoteIP
+		info.Protocol = resp.Proto
+		info.TLS = resp.TLS != nil
+		info.ServerSoftware = resp.Header.Get("Server")
+		info.PoweredBy = resp.Header.Get("X-Powered-By")
+		info.CacheStatus = firstHeader(resp.Header, "Cache-Status", "X-Cache", "CF-Cache-Status", "X-Cache-Status")
+
+		for header, provider := range cdnHeaderSignatures {
+			if resp.Header.Get(header) != "" {
+				cdnEvidence[provider] = append(cdnEvidence[provider], "header:"+header)
+			}
+		}
+		server := strings.ToLower(info.ServerSoftware + " " + resp.Header.Get("Via"))
+		for signature, provider := range cdnServerSignatures {
+			if strings.Contains(server, signature) {
+				cdnEvidence[provider] = append(cdnEvidence[provider], "server:"+signature)
+			}
+		}
+	}
+	if info.IP == "" && len(info.Addresses) > 0 {
+		info.IP = info.Addresses[0]
+	}
+
+	// Геолокация и автономная система
+	if ip := net.ParseIP(info.IP); ip != nil && !ip.IsPrivate() && !ip.IsLoopback() && a.geoLookupURL != "" {
+		if geo, err := a.lookupGeo(ctx, info.IP); err == nil {
+			info.Geo = geo
+			network := strings.ToLower(geo.ASName + " " + geo.Org + " " + geo.ISP)
+			for name, provider := range cdnNetworkNames {
+				if strings.Contains(network, name) {
+					cdnEvidence[provider] = append(cdnEvidence[provider], "asn:"+geo.ASN)
+				}
+			}
+		}
+	}
+
+	info.CDN, info.CDNEvidence = strongestCDN(cdnEvidence)
+
+	// Доступность по IPv4 и IPv6
+	if len(info.Addresses) > 0 {
+		info.DualStack = a.checkDualStack(ctx, pageURL, info.Addresses)
+	}
+
+	// Ответы браузеру, поисковому роботу и анализатору
+	info.UserAgents = a.compareUserAgents(ctx, pageURL)
+
+	html := data.RawHTML
+	if html == "" {
+		html = data.HTML
+	}
+	info.AudienceRegions = audienceRegions(html, info.Host)
+	info.Audience = "regional"
+	if len(info.AudienceRegions) > 1 {
+		info.Audience = "global"
+	}
+
+	a.SetMetric("infrastructure", info)
+	a.SetMetric("cdn_detected", info.CDN != "")
+	a.reportIssues(info)
+	if info.DualStack != nil {
+		a.SetMetric("ipv6_supported", info.DualStack.DualStack)
+		a.reportDualStackIssues(info.DualStack)
+	}
+	a.SetMetric("user_agent_mismatch", len(info.UserAgents.Mismatches) > 0)
+	a.reportUserAgentIssues(info.UserAgents)
+	a.SetMetric("score", a.CalculateScore())
+
+	return a.GetMetrics(), nil
+}
+
+// fetchHeaders запрашивает страницу и возвращает ответ и IP-адрес сервера.
+// Тело ответа не читается.
+func (a *InfrastructureAnalyzer) fetchHeaders(ctx context.Context, pageURL string) (*http.Response, string, error) {
+	var remoteIP string
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if addr, ok := info.Conn.RemoteAddr().(*net.TCPAddr); ok {
+				remoteIP = addr.IP.String()
+			}
+		},
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", parser.DesktopDevice.UserAgent)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	resp.Body.Close()
+
+	return resp, remoteIP, nil
+}
+
+// lookupGeo определяет страну и автономную систему IP-адреса через внешний сервис
+func (a *InfrastructureAnalyzer) lookupGeo(ctx context.Context, ip string) (*IPGeo, error) {
+	lookupURL := strings.ReplaceAll(a.geoLookupURL, "{ip}", url.PathEscape(ip))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, lookupURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("geo lookup returned status %d", resp.StatusCode)
+	}
+
+	// Формат ответа ip-api.com
+	var result struct {
+		Status      string `json:"status"`
+		Message     string `json:"message"`
+		Country     string `json:"country"`
+		CountryCode string `json:"countryCode"`
+		RegionName  string `json:"regionName"`
+		City        string `json:"city"`
+		ISP         string `json:"isp"`
+		Org         string `json:"org"`
+		AS          string `json:"as"`
+		ASName      string `json:"asname"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&result); err != nil {
+		return nil, err
+	}
+	if result.Status != "" && result.Status != "success" {
+		return nil, fmt.Errorf("geo lookup failed: %s", result.Message)
+	}
+
+	asn, _, _ := strings.Cut(result.AS, " ")
+	return &IPGeo{
+		Country:     result.Country,
+		CountryCode: result.CountryCode,
+		Region:      result.RegionName,
+		City:        result.City,
+		ISP:         result.ISP,
+		Org:         result.Org,
+		ASN:         asn,
+		ASName:      result.ASName,
+	}, nil
+}
+
+// reportIssues добавляет проблемы и рекомендации по инфраструктуре
+func (a *InfrastructureAnalyzer) reportIssues(info *InfrastructureInfo) {
+	if info.CDN == "" {
+		if info.Audience == "global" {
+			a.AddIssue(map[string]interface{}{
+				"type":        "no_cdn_global_audience",
+				"severity":    "high",
+				"description": "Сайт ориентирован на посетителей из разных стран, но CDN не обнаружен",
+				"regions":     info.AudienceRegions,
+			})
+			a.AddRecommendation("Подключите CDN (например, Cloudflare, Amazon CloudFront или Fastly), чтобы сократить задержку для посетителей из других стран")
+		} else {
+			a.AddIssue(map[string]interface{}{
+				"type":        "no_cdn",
+				"severity":    "low",
+				"description": "CDN не обнаружен: статические ресурсы отдаются напрямую с сервера",
+			})
+			a.AddRecommendation("Рассмотрите подключение CDN для кеширования статических ресурсов и защиты от DDoS-атак")
+		}
+
+		// Регионы в верхнем регистре - коды стран, в нижнем - только языки
+		var countries []string
+		for _, region := range info.AudienceRegions {
+			if region == strings.ToUpper(region) {
+				countries = append(countries, region)
+			}
+		}
+		if info.Geo != nil && info.Geo.CountryCode != "" && len(countries) > 0 && !contains(countries, info.Geo.CountryCode) {
+			a.AddIssue(map[string]interface{}{
+				"type":        "server_far_from_audience",
+				"severity":    "medium",
+				"description": fmt.Sprintf("Сервер расположен в стране %s, а страница ориентирована на %s", info.Geo.CountryCode, strings.Join(countries, ", ")),
+			})
+			a.AddRecommendation("Разместите сервер ближе к основной аудитории или используйте CDN с точками присутствия в ее регионе")
+		}
+	}
+
+	if versioned(info.ServerSoftware) || info.PoweredBy != "" {
+		a.AddIssue(map[string]interface{}{
+			"type":        "server_software_disclosed",
+			"severity":    "low",
+			"description": "Заголовки Server или X-Powered-By раскрывают используемое ПО и его версию",
+			"server":      info.ServerSoftware,
+			"powered_by":  info.PoweredBy,
+		})
+		a.AddRecommendation("Скройте версию серверного ПО в заголовке Server и удалите заголовок X-Powered-By")
+	}
+
+	if info.TLS && !strings.HasPrefix(info.Protocol, "HTTP/2") && !strings.HasPrefix(info.Protocol, "HTTP/3") {
+		a.AddIssue(map[string]interface{}{
+			"type":        "no_http2",
+			"severity":    "medium",
+			"description": "Сервер не поддерживает HTTP/2",
+			"protocol":    info.Protocol,
+		})
+		a.AddRecommendation("Включите HTTP/2 на сервере или CDN, чтобы ресурсы загружались параллельно по одному соединению")
+	}
+}
+
+// strongestCDN выбирает CDN с наибольшим числом признаков
+func strongestCDN(evidence map[string][]string) (string, []string) {
+	providers := make([]string, 0, len(evidence))
+	for provider := range evidence {
+		providers = append(providers, provider)
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		if len(evidence[providers[i]]) != len(evidence[providers[j]]) {
+			return len(evidence[providers[i]]) > len(evidence[providers[j]])
+		}
+		return providers[i] < providers[j]
+	})
+	if len(providers) == 0 {
+		return "", nil
+	}
+	found := evidence[providers[0]]
+	sort.Strings(found)
+	return providers[0], found
+}
+
+// audienceRegions определяет страны и языки, на которые ориентирована страница,
+// по hreflang, og:locale и национальному домену
+func audienceRegions(html, host string) []string {
+	regions := make(map[string]bool)
+
+	signals := parser.ParseLocaleSignals(html)
+	for _, link := range signals.Hreflang {
+		if link.Lang != "x-default" {
+			regions[localeRegion(link.Lang)] = true
+		}
+	}
+
+	if doc, err := goquery.NewDocumentFromReader(strings.NewReader(html)); err == nil {
+		doc.Find(`meta[property="og:locale"], meta[property="og:locale:alternate"]`).Each(func(_ int, s *goquery.Selection) {
+			if content, _ := s.Attr("content"); content != "" {
+				regions[localeRegion(content)] = true
+			}
+		})
+	}
+
+	if len(regions) == 0 {
+		if signals.Lang != "" && strings.ContainsAny(signals.Lang, "-_") {
+			regions[localeRegion(signals.Lang)] = true
+		} else if labels := strings.Split(host, "."); len(labels) > 1 {
+			if tld := labels[len(labels)-1]; len(tld) == 2 && !genericTLDs[tld] {
+				regions[strings.ToUpper(tld)] = true
+			}
+		}
+	}
+
+	result := make([]string, 0, len(regions))
+	for region := range regions {
+		if region != "" {
+			result = append(result, region)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// localeRegion возвращает страну из локали ("de-AT" -> "AT"), а если она не
+// указана - язык ("de" -> "de")
+func localeRegion(locale string) string {
+	locale = strings.TrimSpace(strings.ReplaceAll(locale, "_", "-"))
+	if _, region, ok := strings.Cut(locale, "-"); ok && len(region) == 2 {
+		return strings.ToUpper(region)
+	}
+	return languageOf(locale)
+}
+
+// firstHeader возвращает значение первого непустого заголовка из списка
+func firstHeader(header http.Header, names ...string) string {
+	for _, name := range names {
+		if value := header.Get(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// versioned проверяет, содержит ли значение заголовка номер версии
+func versioned(value string) bool {
+	return strings.Contains(value, "/") && strings.IndexAny(value, "0123456789") >= 0
+}
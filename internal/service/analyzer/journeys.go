@@ -0,0 +1,164 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+const (
+	// journeySlowStepMs - шаг сценария, который пользователь ждет дольше этого, считается медленным
+	journeySlowStepMs = 3000
+	// Пороги смещения макета за шаг, как у Cumulative Layout Shift
+	journeyShiftMedium = 0.1
+	journeyShiftHigh   = 0.25
+)
+
+// JourneysAnalyzer оценивает пользовательские сценарии (например, главная →
+// цены → отправка формы), выполненные в headless-браузере: завершенность
+// воронки, время каждого шага, ошибки и смещения макета
+type JourneysAnalyzer struct {
+	*BaseAnalyzer
+}
+
+// NewJourneysAnalyzer создает новый анализатор пользовательских сценариев
+func NewJourneysAnalyzer() *JourneysAnalyzer {
+	return &JourneysAnalyzer{
+		BaseAnalyzer: NewBaseAnalyzer(JourneysType),
+	}
+}
+
+// JourneySummary - сводка воронки по всем сценариям
+type JourneySummary struct {
+	Journeys       int     `json:"journeys"`
+	Completed      int     `json:"completed"`
+	CompletionRate float64 `json:"completion_rate"`
+	TotalMs        int64   `json:"total_duration_ms"`
+	// SlowestJourney и SlowestStep указывают на самый долгий шаг
+	SlowestJourney string  `json:"slowest_journey,omitempty"`
+	SlowestStep    int     `json:"slowest_step,omitempty"`
+	SlowestStepMs  int64   `json:"slowest_step_ms"`
+	LayoutShift    float64 `json:"layout_shift"`
+}
+
+// Analyze оценивает результаты сценариев, выполненных при разборе страницы
+func (a *JourneysAnalyzer) Analyze(ctx context.Context, data *parser.WebsiteData, prevResults map[AnalyzerType]map[string]interface{}) (map[string]interface{}, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	if len(data.Journeys) == 0 {
+		a.SetMetric("error", "Сценарии не выполнялись: страница не была загружена или сценарии не заданы")
+		return a.GetMetrics(), nil
+	}
+
+	summary := SummarizeJourneys(data.Journeys)
+	a.SetMetric("journeys", data.Journeys)
+	a.SetMetric("summary", summary)
+
+	for _, journey := range data.Journeys {
+		a.reportJourneyIssues(journey)
+	}
+	a.SetMetric("score", a.CalculateScore())
+
+	return a.GetMetrics(), nil
+}
+
+// SummarizeJourneys считает показатели воронки по результатам сценариев
+func SummarizeJourneys(journeys []parser.JourneyResult) JourneySummary {
+	summary := JourneySummary{Journeys: len(journeys)}
+	for _, journey := range journeys {
+		if journey.Completed {
+			summary.Completed++
+		}
+		summary.TotalMs += journey.DurationMs
+		summary.LayoutShift += journey.LayoutShift
+		for _, step := range journey.Steps {
+			if !step.Skipped && step.DurationMs > summary.SlowestStepMs {
+				summary.SlowestStepMs = step.DurationMs
+				summary.SlowestJourney = journey.Name
+				summary.SlowestStep = step.Step
+			}
+		}
+	}
+	if summary.Journeys > 0 {
+		summary.CompletionRate = float64(summary.Completed) / float64(summary.Journeys) * 100
+	}
+	return summary
+}
+
+// reportJourneyIssues добавляет проблемы одного сценария
+func (a *JourneysAnalyzer) reportJourneyIssues(journey parser.JourneyResult) {
+	name := journeyLabel(journey)
+
+	if !journey.Completed && journey.FailedStep > 0 && journey.FailedStep <= len(journey.Steps) {
+		failed := journey.Steps[journey.FailedStep-1]
+		a.AddIssue(map[string]interface{}{
+			"type":        "journey_failed",
+			"severity":    "high",
+			"description": fmt.Sprintf("Сценарий %s прерван на шаге %d (%s): %s", name, failed.Step, failed.Action, failed.Error),
+			"journey":     journey.Name,
+			"step":        failed.Step,
+			"url":         failed.URL,
+		})
+		a.AddRecommendation(fmt.Sprintf("Проверьте шаг %d сценария %s: элемент должен быть видим и доступен, а страница должна отвечать без ошибок", failed.Step, name))
+	}
+
+	consoleErrors := 0
+	for _, step := range journey.Steps {
+		if step.Skipped {
+			continue
+		}
+		consoleErrors += len(step.ConsoleErrors)
+
+		if step.Error == "" && step.DurationMs > journeySlowStepMs {
+			a.AddIssue(map[string]interface{}{
+				"type":        "journey_step_slow",
+				"severity":    "medium",
+				"description": fmt.Sprintf("Шаг %d сценария %s (%s) занимает %.1f с: пользователи могут покинуть воронку", step.Step, name, step.Action, float64(step.DurationMs)/1000),
+				"journey":     journey.Name,
+				"step":        step.Step,
+				"duration_ms": step.DurationMs,
+				"url":         step.URL,
+			})
+		}
+
+		if step.LayoutShift >= journeyShiftMedium {
+			severity := "medium"
+			if step.LayoutShift >= journeyShiftHigh {
+				severity = "high"
+			}
+			a.AddIssue(map[string]interface{}{
+				"type":         "journey_layout_shift",
+				"severity":     severity,
+				"description":  fmt.Sprintf("На шаге %d сценария %s макет смещается на %.2f: элементы сдвигаются под курсором пользователя", step.Step, name, step.LayoutShift),
+				"journey":      journey.Name,
+				"step":         step.Step,
+				"layout_shift": step.LayoutShift,
+				"url":          step.URL,
+			})
+		}
+	}
+
+	if consoleErrors > 0 {
+		a.AddIssue(map[string]interface{}{
+			"type":        "journey_console_errors",
+			"severity":    "low",
+			"description": fmt.Sprintf("Во время сценария %s в консоли браузера возникло ошибок JavaScript: %d", name, consoleErrors),
+			"journey":     journey.Name,
+			"count":       consoleErrors,
+		})
+		a.AddRecommendation(fmt.Sprintf("Исправьте ошибки JavaScript, возникающие при прохождении сценария %s", name))
+	}
+}
+
+// journeyLabel возвращает имя сценария для описания проблем
+func journeyLabel(journey parser.JourneyResult) string {
+	if journey.Name == "" {
+		return "без названия"
+	}
+	return "«" + journey.Name + "»"
+}
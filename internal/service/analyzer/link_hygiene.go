@@ -0,0 +1,410 @@
+package analyzer
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+)
+
+// maxLinkFindingExamples ограничивает число примеров в каждой группе находок
+const maxLinkFindingExamples = 20
+
+// malformedURLPatterns - типичные ошибки в записи абсолютных и
+// протокол-относительных URL и способ их исправления
+var malformedURLPatterns = []struct {
+	pattern *regexp.Regexp
+	problem string
+	fix     func(href string, match []string) string
+}{
+	{
+		// https:\\example.com, \\example.com
+		pattern: regexp.MustCompile(`^((?i:https?):)?[\\/]*\\[\\/]*`),
+		problem: "обратная косая черта вместо прямой",
+		fix: func(href string, match []string) string {
+			return match[1] + "//" + strings.TrimLeft(href[len(match[0]):], `\/`)
+		},
+	},
+	{
+		// https//example.com
+		pattern: regexp.MustCompile(`^((?i:https?))//`),
+		problem: "после схемы пропущено двоеточие",
+		fix: func(href string, match []string) string {
+			return match[1] + "://" + href[len(match[0]):]
+		},
+	},
+	{
+		// https:/example.com, https:///example.com
+		pattern: regexp.MustCompile(`^((?i:https?)):(/|/{3,})([^/]|$)`),
+		problem: "неверное число косых черт после схемы",
+		fix: func(href string, match []string) string {
+			return match[1] + "://" + strings.TrimLeft(href[len(match[1])+1:], "/")
+		},
+	},
+	{
+		// ://example.com
+		pattern: regexp.MustCompile(`^:/+`),
+		problem: "пропущена схема перед \"://\"",
+		fix: func(href string, match []string) string {
+			return "https://" + href[len(match[0]):]
+		},
+	},
+	{
+		// ///example.com
+		pattern: regexp.MustCompile(`^/{3,}`),
+		problem: "лишние косые черты в протокол-относительном URL",
+		fix: func(href string, match []string) string {
+			return "//" + href[len(match[0]):]
+		},
+	},
+}
+
+// phoneKeypad переводит буквы "красивых" номеров (1-800-FLOWERS) в цифры
+var phoneKeypad = map[rune]rune{}
+
+func init() {
+	for digit, letters := range map[rune]string{
+		'2': "abc", '3': "def", '4': "ghi", '5': "jkl", '6': "mno", '7': "pqrs", '8': "tuv", '9': "wxyz",
+	} {
+		for _, letter := range letters {
+			phoneKeypad[letter] = digit
+		}
+	}
+}
+
+// LinkFix - проблемная ссылка и ее исправленный вариант. Пустой Fix означает,
+// что ссылку нужно заменить вручную или удалить.
+type LinkFix struct {
+	Href    string `json:"href"`
+	Fix     string `json:"fix,omitempty"`
+	Problem string `json:"problem"`
+}
+
+// LinkFindings - группа проблемных ссылок: общее число и примеры
+type LinkFindings struct {
+	Count    int       `json:"count"`
+	Examples []LinkFix `json:"examples,omitempty"`
+}
+
+func (f *LinkFindings) add(fix LinkFix) {
+	f.Count++
+	if len(f.Examples) < maxLinkFindingExamples {
+		f.Examples = append(f.Examples, fix)
+	}
+}
+
+// LinkHygieneReport - результат проверки ссылок на трекинговые параметры и
+// ошибки в записи
+type LinkHygieneReport struct {
+	TotalLinks       int          `json:"total_links"`
+	TrackingLinks    LinkFindings `json:"tracking_links"`
+	InternalTracking int          `json:"internal_tracking_links"`
+	BrokenMailto     LinkFindings `json:"broken_mailto"`
+	BrokenTel        LinkFindings `json:"broken_tel"`
+	MalformedURLs    LinkFindings `json:"malformed_urls"`
+	JavascriptLinks  LinkFindings `json:"javascript_links"`
+	// ParameterizedInternalLinks - внутренние ссылки с query-параметрами,
+	// которые поисковый робот может считать отдельными страницами
+	ParameterizedInternalLinks int `json:"parameterized_internal_links"`
+	// ParameterizedPaths - пути, на которые ведут ссылки с разными наборами параметров
+	ParameterizedPaths []string `json:"parameterized_paths"`
+}
+
+// analyzeLinkHygiene проверяет исходные значения href: трекинговые параметры,
+// неработающие mailto:/tel:, неверно записанные URL и javascript:-ссылки
+func (a *SEOAnalyzer) analyzeLinkHygiene(data *parser.WebsiteData) {
+	html := data.RawHTML
+	if html == "" {
+		html = data.HTML
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return
+	}
+
+	pageURL := data.FinalURL
+	if pageURL == "" {
+		pageURL = data.URL
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return
+	}
+
+	report := CheckLinkHygiene(doc, base)
+	a.SetMetric("link_hygiene", report)
+
+	if report.InternalTracking > 0 {
+		a.AddIssue(map[string]interface{}{
+			"type":        "internal_tracking_params",
+			"severity":    "medium",
+			"description": "Внутренние ссылки содержат UTM и другие трекинговые параметры: это искажает источники трафика в аналитике и создает дубли страниц",
+			"count":       report.InternalTracking,
+		})
+		a.AddRecommendation("Уберите UTM и click-ID параметры из внутренних ссылок; для отслеживания переходов внутри сайта используйте события аналитики")
+	} else if report.TrackingLinks.Count > 0 {
+		a.AddIssue(map[string]interface{}{
+			"type":        "tracking_params_in_links",
+			"severity":    "low",
+			"description": "Ссылки содержат скопированные трекинговые параметры (utm_*, fbclid, gclid и т.п.)",
+			"count":       report.TrackingLinks.Count,
+		})
+		a.AddRecommendation("Очистите ссылки от трекинговых параметров, оставшихся после копирования URL из рекламных кампаний")
+	}
+
+	if report.BrokenMailto.Count > 0 || report.BrokenTel.Count > 0 {
+		a.AddIssue(map[string]interface{}{
+			"type":        "broken_contact_links",
+			"severity":    "medium",
+			"description": "На странице есть некорректные ссылки mailto: или tel:, по которым нельзя написать или позвонить",
+			"mailto":      report.BrokenMailto.Count,
+			"tel":         report.BrokenTel.Count,
+		})
+		a.AddRecommendation("Исправьте адреса в ссылках mailto: и номера в ссылках tel: (номер в формате tel:+79991234567)")
+	}
+
+	if report.MalformedURLs.Count > 0 {
+		a.AddIssue(map[string]interface{}{
+			"type":        "malformed_urls",
+			"severity":    "medium",
+			"description": "Ссылки содержат ошибки в записи схемы или косых черт, браузер откроет их как относительные пути",
+			"count":       report.MalformedURLs.Count,
+		})
+		a.AddRecommendation("Исправьте адреса ссылок с ошибками в записи (например, https:/ или http//)")
+	}
+
+	if report.JavascriptLinks.Count > 0 {
+		a.AddIssue(map[string]interface{}{
+			"type":        "javascript_links",
+			"severity":    "low",
+			"description": "Ссылки с href=\"javascript:...\" недоступны поисковым роботам и не открываются в новой вкладке",
+			"count":       report.JavascriptLinks.Count,
+		})
+		a.AddRecommendation("Замените javascript:-ссылки на элементы <button> или на ссылки с реальным адресом")
+	}
+
+	if report.ParameterizedInternalLinks > 0 && len(report.ParameterizedPaths) > 0 {
+		a.AddIssue(map[string]interface{}{
+			"type":        "parameterized_internal_links",
+			"severity":    "low",
+			"description": fmt.Sprintf("%d внутренних ссылок с параметрами ведут на одни и те же страницы с разными наборами параметров: робот может сканировать их как дубли", report.ParameterizedInternalLinks),
+			"paths":       report.ParameterizedPaths,
+		})
+		a.AddRecommendation("Укажите canonical на страницах с параметрами и по возможности ссылайтесь на адреса без параметров")
+	}
+}
+
+// CheckLinkHygiene проверяет ссылки документа. base - адрес страницы, по
+// которому определяются внутренние ссылки.
+func CheckLinkHygiene(doc *goquery.Document, base *url.URL) LinkHygieneReport {
+	report := LinkHygieneReport{ParameterizedPaths: []string{}}
+	pathQueries := make(map[string]map[string]bool)
+
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		raw, _ := s.Attr("href")
+		href := strings.TrimSpace(raw)
+		if href == "" || href == "#" {
+			return
+		}
+		report.TotalLinks++
+
+		lower := strings.ToLower(href)
+		switch {
+		case strings.HasPrefix(lower, "javascript:"):
+			report.JavascriptLinks.add(LinkFix{Href: href, Problem: "javascript:-ссылка вместо кнопки"})
+			return
+		case strings.HasPrefix(lower, "mailto:"):
+			if fix, problem := checkMailto(href); problem != "" {
+				report.BrokenMailto.add(LinkFix{Href: href, Fix: fix, Problem: problem})
+			}
+			return
+		case strings.HasPrefix(lower, "tel:"):
+			if fix, problem := checkTel(href); problem != "" {
+				report.BrokenTel.add(LinkFix{Href: href, Fix: fix, Problem: problem})
+			}
+			return
+		}
+
+		for _, p := range malformedURLPatterns {
+			if match := p.pattern.FindStringSubmatch(href); match != nil {
+				report.MalformedURLs.add(LinkFix{Href: href, Fix: p.fix(href, match), Problem: p.problem})
+				return
+			}
+		}
+
+		u, err := base.Parse(href)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+		internal := strings.EqualFold(u.Hostname(), base.Hostname())
+
+		if stripped, params := stripTrackingParams(u); len(params) > 0 {
+			report.TrackingLinks.add(LinkFix{
+				Href:    href,
+				Fix:     stripped,
+				Problem: "трекинговые параметры: " + strings.Join(params, ", "),
+			})
+			if internal {
+				report.InternalTracking++
+			}
+		}
+
+		if internal && u.RawQuery != "" {
+			report.ParameterizedInternalLinks++
+			if pathQueries[u.Path] == nil {
+				pathQueries[u.Path] = make(map[string]bool)
+			}
+			pathQueries[u.Path][u.RawQuery] = true
+		}
+	})
+
+	for path, queries := range pathQueries {
+		if len(queries) > 1 && len(report.ParameterizedPaths) < maxLinkFindingExamples {
+			report.ParameterizedPaths = append(report.ParameterizedPaths, path)
+		}
+	}
+	sort.Strings(report.ParameterizedPaths)
+
+	return report
+}
+
+// stripTrackingParams удаляет трекинговые параметры из URL, сохраняя порядок
+// остальных параметров, и возвращает очищенный URL и имена удаленных параметров
+func stripTrackingParams(u *url.URL) (string, []string) {
+	if u.RawQuery == "" {
+		return u.String(), nil
+	}
+
+	var kept, removed []string
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		key, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); err == nil && urlnorm.IsTrackingParam(name) {
+			removed = append(removed, name)
+			continue
+		}
+		if pair != "" {
+			kept = append(kept, pair)
+		}
+	}
+	if len(removed) == 0 {
+		return u.String(), nil
+	}
+
+	clean := *u
+	clean.RawQuery = strings.Join(kept, "&")
+	return clean.String(), removed
+}
+
+// checkMailto проверяет адреса в ссылке mailto:. Возвращает исправленную
+// ссылку (если ее можно восстановить) и описание проблемы.
+func checkMailto(href string) (string, string) {
+	value := href[len("mailto:"):]
+	addresses, query, _ := strings.Cut(value, "?")
+	if decoded, err := url.PathUnescape(addresses); err == nil {
+		addresses = decoded
+	}
+
+	if validMailAddresses(addresses) {
+		return "", ""
+	}
+
+	// Частые ошибки: пробелы, повторный "mailto:", угловые скобки
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '<' || r == '>' {
+			return -1
+		}
+		return r
+	}, addresses)
+	for strings.HasPrefix(strings.ToLower(cleaned), "mailto:") {
+		cleaned = cleaned[len("mailto:"):]
+	}
+
+	problem := "некорректный адрес электронной почты"
+	if strings.TrimSpace(addresses) == "" {
+		problem = "не указан адрес электронной почты"
+	}
+	if cleaned == "" || !validMailAddresses(cleaned) {
+		return "", problem
+	}
+
+	fix := "mailto:" + cleaned
+	if query != "" {
+		fix += "?" + query
+	}
+	return fix, problem
+}
+
+// validMailAddresses проверяет список адресов через запятую
+func validMailAddresses(list string) bool {
+	if strings.TrimSpace(list) == "" {
+		return false
+	}
+	for _, address := range strings.Split(list, ",") {
+		address = strings.TrimSpace(address)
+		parsed, err := mail.ParseAddress(address)
+		if err != nil || parsed.Address != address {
+			return false
+		}
+		_, domain, _ := strings.Cut(parsed.Address, "@")
+		if !strings.Contains(domain, ".") {
+			return false
+		}
+	}
+	return true
+}
+
+// checkTel проверяет номер в ссылке tel: и возвращает номер в формате
+// tel:+79991234567 (если его можно восстановить) и описание проблемы
+func checkTel(href string) (string, string) {
+	value := href[len("tel:"):]
+	if decoded, err := url.PathUnescape(value); err == nil {
+		value = decoded
+	}
+	// Добавочный номер и параметры RFC 3966 не проверяются
+	number, _, _ := strings.Cut(value, ";")
+	number = strings.TrimSpace(number)
+
+	var digits strings.Builder
+	hasLetters, invalid := false, false
+	for i, r := range number {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		case unicode.IsLetter(r):
+			hasLetters = true
+			if digit, ok := phoneKeypad[unicode.ToLower(r)]; ok {
+				digits.WriteRune(digit)
+			} else {
+				invalid = true
+			}
+		default:
+			invalid = true
+		}
+	}
+
+	switch {
+	case number == "":
+		return "", "не указан номер телефона"
+	case digits.Len() < 3:
+		return "", "номер телефона слишком короткий"
+	case invalid:
+		return "", "номер содержит недопустимые символы"
+	case hasLetters:
+		fix := "tel:" + digits.String()
+		if strings.HasPrefix(number, "+") {
+			fix = "tel:+" + digits.String()
+		}
+		return fix, "буквы в номере не поддерживаются телефонами"
+	}
+	return "", ""
+}
@@ -0,0 +1,279 @@
+package analyzer
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+const (
+	// noscriptFetchTimeout ограничивает загрузку страницы без JavaScript
+	noscriptFetchTimeout = 15 * time.Second
+	// noscriptRenderTimeout ограничивает отрисовку страницы в headless-браузере,
+	// если при разборе страницы JavaScript не выполнялся
+	noscriptRenderTimeout = 30 * time.Second
+	// maxNoScriptExamples ограничивает число примеров пропавших заголовков и ссылок
+	maxNoScriptExamples = 20
+)
+
+// Уровни зависимости контента от JavaScript
+const (
+	JSDependencyNone    = "none"
+	JSDependencyPartial = "partial"
+	JSDependencyHeavy   = "heavy"
+)
+
+// NoScriptAnalyzer сравнивает страницу с отключенным JavaScript и страницу
+// после выполнения скриптов и оценивает, сколько важного контента и навигации
+// пропадает без JavaScript (прогрессивное улучшение, устойчивость SEO)
+type NoScriptAnalyzer struct {
+	*BaseAnalyzer
+}
+
+// NewNoScriptAnalyzer создает новый анализатор страницы без JavaScript
+func NewNoScriptAnalyzer() *NoScriptAnalyzer {
+	return &NoScriptAnalyzer{
+		BaseAnalyzer: NewBaseAnalyzer(NoScriptType),
+	}
+}
+
+// NoScriptComparison описывает, что теряется при отключенном JavaScript
+type NoScriptComparison struct {
+	TextRetained     float64  `json:"text_retained"`
+	ContentRetained  float64  `json:"main_content_retained"`
+	LinksRetained    float64  `json:"links_retained"`
+	NavRetained      float64  `json:"nav_links_retained"`
+	MissingHeadings  []string `json:"missing_headings,omitempty"`
+	MissingNavLinks  []string `json:"missing_nav_links,omitempty"`
+	CriticalMissing  []string `json:"critical_missing,omitempty"`
+	CriticalChanged  []string `json:"critical_changed,omitempty"`
+	Dependency       string   `json:"js_dependency"`
+	NoscriptFallback bool     `json:"noscript_fallback"`
+}
+
+// Analyze выполняет сравнение версий страницы с JavaScript и без него
+func (a *NoScriptAnalyzer) Analyze(ctx context.Context, data *parser.WebsiteData, prevResults map[AnalyzerType]map[string]interface{}) (map[string]interface{}, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	baseURL := data.FinalURL
+	if baseURL == "" {
+		baseURL = data.URL
+	}
+
+	// Версия без JavaScript: исходный HTML, как его получает клиент без скриптов
+	withoutJS := data.RawHTML
+	if withoutJS == "" {
+		fetched, err := parser.FetchWithoutJavaScript(ctx, baseURL, "", noscriptFetchTimeout)
+		if err != nil {
+			a.SetMetric("error", "Не удалось загрузить страницу без JavaScript: "+err.Error())
+			return a.GetMetrics(), nil
+		}
+		withoutJS = fetched
+	}
+
+	// Версия с JavaScript: DOM после выполнения скриптов
+	withJS := data.RenderedDOM
+	source := "rendered_dom"
+	if withJS == "" {
+		rendered, err := parser.RenderDOM(ctx, baseURL, "", noscriptRenderTimeout)
+		if err != nil {
+			a.SetMetric("error", "Не удалось отрисовать страницу с JavaScript: "+err.Error())
+			return a.GetMetrics(), nil
+		}
+		withJS = rendered
+		source = "headless_render"
+	}
+	a.SetMetric("rendered_source", source)
+
+	noJSExperience := parser.ExtractExperience(withoutJS, baseURL, false)
+	jsExperience := parser.ExtractExperience(withJS, baseURL, true)
+	comparison := CompareExperiences(noJSExperience, jsExperience)
+
+	a.SetMetric("without_js", noJSExperience)
+	a.SetMetric("with_js", jsExperience)
+	a.SetMetric("comparison", comparison)
+	a.SetMetric("js_dependency", comparison.Dependency)
+
+	a.reportNoScriptIssues(comparison, jsExperience)
+	a.SetMetric("score", a.CalculateScore())
+
+	return a.GetMetrics(), nil
+}
+
+// CompareExperiences сравнивает версию страницы без JavaScript с версией
+// после выполнения скриптов
+func CompareExperiences(withoutJS, withJS parser.PageExperience) NoScriptComparison {
+	comparison := NoScriptComparison{
+		TextRetained:     retainedPercent(withoutJS.WordCount, withJS.WordCount),
+		ContentRetained:  retainedPercent(withoutJS.MainWords, withJS.MainWords),
+		LinksRetained:    retainedPercent(countPresent(withJS.Links, withoutJS.Links), len(withJS.Links)),
+		NavRetained:      retainedPercent(countPresent(withJS.NavLinks, withoutJS.Links), len(withJS.NavLinks)),
+		NoscriptFallback: withoutJS.Noscript,
+	}
+
+	headings := make(map[string]bool, len(withoutJS.Headings))
+	for _, heading := range withoutJS.Headings {
+		headings[strings.ToLower(heading)] = true
+	}
+	for _, heading := range withJS.Headings {
+		if !headings[strings.ToLower(heading)] && len(comparison.MissingHeadings) < maxNoScriptExamples {
+			comparison.MissingHeadings = append(comparison.MissingHeadings, heading)
+		}
+	}
+
+	links := make(map[string]bool, len(withoutJS.Links))
+	for _, link := range withoutJS.Links {
+		links[link] = true
+	}
+	for _, link := range withJS.NavLinks {
+		if !links[link] && len(comparison.MissingNavLinks) < maxNoScriptExamples {
+			comparison.MissingNavLinks = append(comparison.MissingNavLinks, link)
+		}
+	}
+
+	// Элементы, важные для поисковых систем и для первого впечатления
+	critical := []struct {
+		name        string
+		without     string
+		with        string
+		compareText bool
+	}{
+		{"title", withoutJS.Title, withJS.Title, true},
+		{"h1", strings.Join(withoutJS.H1, " "), strings.Join(withJS.H1, " "), false},
+		{"meta_description", withoutJS.Description, withJS.Description, true},
+		{"canonical", withoutJS.Canonical, withJS.Canonical, true},
+	}
+	for _, element := range critical {
+		switch {
+		case element.with == "":
+			continue
+		case element.without == "":
+			comparison.CriticalMissing = append(comparison.CriticalMissing, element.name)
+		case element.compareText && element.without != element.with:
+			comparison.CriticalChanged = append(comparison.CriticalChanged, element.name)
+		}
+	}
+
+	retained := math.Min(comparison.ContentRetained, comparison.TextRetained)
+	switch {
+	case retained < 50 || contains(comparison.CriticalMissing, "h1"):
+		comparison.Dependency = JSDependencyHeavy
+	case retained < 90 || comparison.NavRetained < 90 || len(comparison.CriticalMissing) > 0:
+		comparison.Dependency = JSDependencyPartial
+	default:
+		comparison.Dependency = JSDependencyNone
+	}
+
+	return comparison
+}
+
+// reportNoScriptIssues добавляет проблемы по результатам сравнения
+func (a *NoScriptAnalyzer) reportNoScriptIssues(comparison NoScriptComparison, withJS parser.PageExperience) {
+	switch {
+	case comparison.ContentRetained < 50:
+		a.AddIssue(map[string]interface{}{
+			"type":        "content_requires_javascript",
+			"severity":    "high",
+			"description": "Основной контент страницы появляется только после выполнения JavaScript: без скриптов видна лишь малая его часть, что ухудшает индексацию и доступность",
+			"retained":    comparison.ContentRetained,
+		})
+		a.AddRecommendation("Отдавайте основной контент в HTML с сервера (SSR, пререндеринг или статическая генерация), а JavaScript используйте для улучшения, а не для вывода текста")
+	case comparison.ContentRetained < 80:
+		a.AddIssue(map[string]interface{}{
+			"type":        "content_partially_requires_javascript",
+			"severity":    "medium",
+			"description": "Часть основного контента страницы доступна только после выполнения JavaScript",
+			"retained":    comparison.ContentRetained,
+		})
+		a.AddRecommendation("Проверьте, какие блоки контента подгружаются скриптами, и выведите важные из них в исходном HTML")
+	}
+
+	if len(withJS.NavLinks) > 0 && comparison.NavRetained < 50 {
+		a.AddIssue(map[string]interface{}{
+			"type":        "navigation_requires_javascript",
+			"severity":    "high",
+			"description": "Навигация сайта строится JavaScript: без скриптов поисковые роботы и пользователи не видят ссылок меню",
+			"retained":    comparison.NavRetained,
+			"examples":    comparison.MissingNavLinks,
+		})
+		a.AddRecommendation("Разместите ссылки меню в HTML обычными элементами <a href>, а раскрывающиеся меню реализуйте поверх них")
+	} else if len(comparison.MissingNavLinks) > 0 {
+		a.AddIssue(map[string]interface{}{
+			"type":        "nav_links_require_javascript",
+			"severity":    "low",
+			"description": "Некоторые ссылки навигации появляются только после выполнения JavaScript",
+			"examples":    comparison.MissingNavLinks,
+		})
+	}
+
+	if len(comparison.CriticalMissing) > 0 {
+		a.AddIssue(map[string]interface{}{
+			"type":        "seo_elements_require_javascript",
+			"severity":    "high",
+			"description": "Важные для SEO элементы (заголовок, H1, мета-описание, canonical) добавляются только JavaScript",
+			"elements":    comparison.CriticalMissing,
+		})
+		a.AddRecommendation("Выводите title, H1, мета-описание и canonical в исходном HTML, не полагаясь на JavaScript")
+	}
+	if len(comparison.CriticalChanged) > 0 {
+		a.AddIssue(map[string]interface{}{
+			"type":        "seo_elements_changed_by_javascript",
+			"severity":    "medium",
+			"description": "JavaScript изменяет title, мета-описание или canonical: поисковые системы могут проиндексировать разные значения",
+			"elements":    comparison.CriticalChanged,
+		})
+		a.AddRecommendation("Сделайте так, чтобы title, мета-описание и canonical в исходном HTML совпадали со значениями после выполнения JavaScript")
+	}
+
+	if len(comparison.MissingHeadings) > 0 {
+		a.AddIssue(map[string]interface{}{
+			"type":        "headings_require_javascript",
+			"severity":    "low",
+			"description": "Часть заголовков страницы видна только после выполнения JavaScript",
+			"examples":    comparison.MissingHeadings,
+		})
+	}
+
+	if comparison.Dependency == JSDependencyHeavy && !comparison.NoscriptFallback {
+		a.AddIssue(map[string]interface{}{
+			"type":        "no_noscript_fallback",
+			"severity":    "low",
+			"description": "Страница без JavaScript почти пуста и не содержит блока <noscript> с альтернативным содержимым",
+		})
+		a.AddRecommendation("Добавьте в <noscript> краткое содержимое страницы и ссылки на основные разделы для пользователей без JavaScript")
+	}
+}
+
+// retainedPercent возвращает долю (в процентах), сохранившуюся без JavaScript
+func retainedPercent(withoutJS, withJS int) float64 {
+	if withJS <= 0 {
+		return 100
+	}
+	percent := float64(withoutJS) / float64(withJS) * 100
+	if percent > 100 {
+		percent = 100
+	}
+	return math.Round(percent*10) / 10
+}
+
+// countPresent считает элементы list, присутствующие в set
+func countPresent(list, set []string) int {
+	present := make(map[string]bool, len(set))
+	for _, item := range set {
+		present[item] = true
+	}
+	count := 0
+	for _, item := range list {
+		if present[item] {
+			count++
+		}
+	}
+	return count
+}
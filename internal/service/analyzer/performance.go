@@ -123,6 +123,9 @@ func (a *PerformanceAnalyzer) Analyze(ctx context.Context, data *parser.WebsiteD
 		a.analyzeRequestCount(numRequests)
 	}
 
+	// Готовые URL преобразованных изображений для CDN, через который они отдаются
+	a.analyzeImageCDN(data, prevResults)
+
 	// Расчет оценки производительности
 	var score float64
 
@@ -197,7 +200,7 @@ func (a *PerformanceAnalyzer) analyzeLargeImages(data *parser.WebsiteData) {
 	largeImages := []map[string]interface{}{}
 
 	for _, img := range data.Images {
-		if img.FileSize > 100000 { // порог в 100 КБ
+		if img.FileSize > largeImageBytes {
 			largeImages = append(largeImages, map[string]interface{}{
 				"url":  img.URL,
 				"size": img.FileSize,
@@ -0,0 +1,241 @@
+package analyzer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// presetKeyPattern restricts preset keys to URL-friendly identifiers
+var presetKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{1,49}$`)
+
+// PresetParseOptions is the subset of parser options a preset controls
+type PresetParseOptions struct {
+	UseHeadlessBrowser bool `json:"use_headless_browser"`
+	CheckExternalURLs  bool `json:"check_external_urls"`
+	DetectTechnologies bool `json:"detect_technologies"`
+	CaptureScreenshots bool `json:"capture_screenshots"`
+	RespectRobotsTxt   bool `json:"respect_robots_txt"`
+}
+
+// PresetBudgets are the limits an analysis run with a preset is held to.
+// Zero values mean no limit.
+type PresetBudgets struct {
+	TimeoutSeconds  int                      `json:"timeout_seconds,omitempty"`
+	MaxLoadTimeMs   int64                    `json:"max_load_time_ms,omitempty"`
+	MinOverallScore float64                  `json:"min_overall_score,omitempty"`
+	MinScores       map[AnalyzerType]float64 `json:"min_scores,omitempty"`
+}
+
+// Preset is a named analysis configuration: which analyzers run, how the
+// page is fetched and which budgets the result must meet
+type Preset struct {
+	Key         string             `json:"key"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Analyzers   []AnalyzerType     `json:"analyzers"`
+	Parse       PresetParseOptions `json:"parse"`
+	Budgets     PresetBudgets      `json:"budgets"`
+	BuiltIn     bool               `json:"built_in"`
+	Customized  bool               `json:"customized,omitempty"`
+}
+
+// BudgetViolation describes a budget the analysis result did not meet
+type BudgetViolation struct {
+	Budget   string  `json:"budget"`
+	Limit    float64 `json:"limit"`
+	Actual   float64 `json:"actual"`
+	Analyzer string  `json:"analyzer,omitempty"`
+}
+
+// LaunchChecklistKey is the built-in preset behind the checklist analysis mode
+const LaunchChecklistKey = "launch-checklist"
+
+// HasLimits reports whether any budget other than the timeout is set
+func (b PresetBudgets) HasLimits() bool {
+	return b.MaxLoadTimeMs > 0 || b.MinOverallScore > 0 || len(b.MinScores) > 0
+}
+
+// BuiltinPresets returns the presets shipped with the service
+func BuiltinPresets() []Preset {
+	return []Preset{
+		{
+			Key:         "quick-seo",
+			Name:        "Quick SEO check",
+			Description: "On-page SEO and content checks of the fetched HTML",
+			Analyzers:   []AnalyzerType{SEOType, ContentType},
+			Parse:       PresetParseOptions{RespectRobotsTxt: true},
+			Budgets:     PresetBudgets{TimeoutSeconds: 60},
+			BuiltIn:     true,
+		},
+		{
+			Key:         "full-audit",
+			Name:        "Full technical audit",
+			Description: "Every analyzer, with JavaScript rendering, technology detection and external link checks",
+			Analyzers: []AnalyzerType{
+				LighthouseType, SEOType, PerformanceType, AccessibilityType,
+				SecurityType, StructureType, MobileType, ContentType,
+				NoScriptType, DomainType,
+			},
+			Parse: PresetParseOptions{
+				UseHeadlessBrowser: true,
+				CheckExternalURLs:  true,
+				DetectTechnologies: true,
+				RespectRobotsTxt:   true,
+			},
+			Budgets: PresetBudgets{TimeoutSeconds: 300},
+			BuiltIn: true,
+		},
+		{
+			Key:         "performance",
+			Name:        "Performance only",
+			Description: "Lighthouse and performance analysis with a load time budget",
+			Analyzers:   []AnalyzerType{LighthouseType, PerformanceType},
+			Parse:       PresetParseOptions{RespectRobotsTxt: true},
+			Budgets: PresetBudgets{
+				TimeoutSeconds: 120,
+				MaxLoadTimeMs:  3000,
+				MinScores:      map[AnalyzerType]float64{PerformanceType: 70},
+			},
+			BuiltIn: true,
+		},
+		{
+			Key:         "pre-launch",
+			Name:        "Pre-launch checklist",
+			Description: "SEO, security, accessibility and mobile checks that should pass before a site goes live",
+			Analyzers:   []AnalyzerType{SEOType, SecurityType, AccessibilityType, MobileType, StructureType},
+			Parse: PresetParseOptions{
+				UseHeadlessBrowser: true,
+				CheckExternalURLs:  true,
+			},
+			Budgets: PresetBudgets{
+				TimeoutSeconds:  180,
+				MinOverallScore: 80,
+				MinScores:       map[AnalyzerType]float64{SecurityType: 80, AccessibilityType: 80},
+			},
+			BuiltIn: true,
+		},
+		{
+			Key:         LaunchChecklistKey,
+			Name:        "Launch sign-off checklist",
+			Description: "Pass/fail go-live gates: indexable, valid SSL, 404 page, favicon, analytics, sitemap and contact info",
+			Analyzers:   []AnalyzerType{ChecklistType},
+			Budgets:     PresetBudgets{TimeoutSeconds: 120},
+			BuiltIn:     true,
+		},
+	}
+}
+
+// Validate checks that a preset only references known analyzers and sane limits
+func (p *Preset) Validate() error {
+	if !presetKeyPattern.MatchString(p.Key) {
+		return fmt.Errorf("preset key must be 2-50 lowercase letters, digits, '-' or '_'")
+	}
+	if p.Name == "" {
+		return fmt.Errorf("preset name is required")
+	}
+	if len(p.Analyzers) == 0 {
+		return fmt.Errorf("preset must select at least one analyzer")
+	}
+
+	known := make(map[AnalyzerType]bool, len(AllAnalyzerTypes))
+	for _, t := range AllAnalyzerTypes {
+		known[t] = true
+	}
+	seen := make(map[AnalyzerType]bool, len(p.Analyzers))
+	for _, t := range p.Analyzers {
+		if !known[t] {
+			return fmt.Errorf("unknown analyzer: %s", t)
+		}
+		if seen[t] {
+			return fmt.Errorf("analyzer %s is listed twice", t)
+		}
+		seen[t] = true
+	}
+	return p.Budgets.Validate(p.Analyzers)
+}
+
+// Validate checks budget limits. Score budgets may only refer to the given
+// analyzers, which are the ones the analysis runs.
+func (b PresetBudgets) Validate(analyzers []AnalyzerType) error {
+	runs := make(map[AnalyzerType]bool, len(analyzers))
+	for _, t := range analyzers {
+		runs[t] = true
+	}
+	for t, score := range b.MinScores {
+		if !runs[t] {
+			return fmt.Errorf("score budget for %s, which the preset does not run", t)
+		}
+		if score < 0 || score > 100 {
+			return fmt.Errorf("score budget for %s must be between 0 and 100", t)
+		}
+	}
+
+	if b.TimeoutSeconds < 0 || b.TimeoutSeconds > 300 {
+		return fmt.Errorf("timeout budget must be between 0 and 300 seconds")
+	}
+	if b.MaxLoadTimeMs < 0 {
+		return fmt.Errorf("load time budget must not be negative")
+	}
+	if b.MinOverallScore < 0 || b.MinOverallScore > 100 {
+		return fmt.Errorf("overall score budget must be between 0 and 100")
+	}
+	return nil
+}
+
+// ApplyParseOptions copies the fetch settings of the preset into parse options
+func (p *Preset) ApplyParseOptions(opts *parser.ParseOptions) {
+	opts.UseHeadlessBrowser = p.Parse.UseHeadlessBrowser
+	opts.ExecuteJavaScript = p.Parse.UseHeadlessBrowser
+	opts.CheckExternalURLs = p.Parse.CheckExternalURLs
+	opts.DetectTechnologies = p.Parse.DetectTechnologies
+	opts.CaptureScreenshots = p.Parse.CaptureScreenshots
+	opts.RespectRobotsTxt = p.Parse.RespectRobotsTxt
+	if p.Parse.CaptureScreenshots && len(opts.ScreenshotDevices) == 0 {
+		opts.ScreenshotDevices = []parser.DeviceConfig{parser.DesktopDevice, parser.MobileDevice}
+	}
+}
+
+// CheckBudgets compares analysis results with the budgets of the preset
+func (p *Preset) CheckBudgets(loadTime time.Duration, overallScore float64, results map[AnalyzerType]map[string]interface{}) []BudgetViolation {
+	var violations []BudgetViolation
+
+	if p.Budgets.MaxLoadTimeMs > 0 && loadTime.Milliseconds() > p.Budgets.MaxLoadTimeMs {
+		violations = append(violations, BudgetViolation{
+			Budget: "max_load_time_ms",
+			Limit:  float64(p.Budgets.MaxLoadTimeMs),
+			Actual: float64(loadTime.Milliseconds()),
+		})
+	}
+	if p.Budgets.MinOverallScore > 0 && overallScore < p.Budgets.MinOverallScore {
+		violations = append(violations, BudgetViolation{
+			Budget: "min_overall_score",
+			Limit:  p.Budgets.MinOverallScore,
+			Actual: overallScore,
+		})
+	}
+
+	analyzers := make([]string, 0, len(p.Budgets.MinScores))
+	for t := range p.Budgets.MinScores {
+		analyzers = append(analyzers, string(t))
+	}
+	sort.Strings(analyzers)
+	for _, name := range analyzers {
+		t := AnalyzerType(name)
+		limit := p.Budgets.MinScores[t]
+		score, ok := results[t]["score"].(float64)
+		if !ok || score < limit {
+			violations = append(violations, BudgetViolation{
+				Budget:   "min_score",
+				Limit:    limit,
+				Actual:   score,
+				Analyzer: name,
+			})
+		}
+	}
+
+	return violations
+}
@@ -0,0 +1,170 @@
+package analyzer
+
+import (
+	"sync"
+)
+
+// ProgressSink consumes progress updates. Each sink runs in its own goroutine
+// so a slow sink never blocks the analyzers or the other sinks.
+type ProgressSink func(update ProgressUpdate)
+
+// ProgressDispatcher fans progress updates out to sinks. When a sink falls
+// behind, intermediate updates of the same analyzer are coalesced so only the
+// latest state is delivered; the final update passed to Close is always delivered.
+type ProgressDispatcher struct {
+	subscribers []*progressSubscriber
+	closed      bool
+	mu          sync.Mutex
+	wg          sync.WaitGroup
+}
+
+// progressSubscriber buffers updates for a single sink
+type progressSubscriber struct {
+	sink      ProgressSink
+	pending   map[string]ProgressUpdate
+	order     []string
+	coalesced int
+	closed    bool
+	notify    chan struct{}
+	mu        sync.Mutex
+}
+
+// NewProgressDispatcher creates a dispatcher without sinks
+func NewProgressDispatcher() *ProgressDispatcher {
+	return &ProgressDispatcher{}
+}
+
+// Subscribe registers a sink. Sinks must be registered before the first Publish.
+func (d *ProgressDispatcher) Subscribe(sink ProgressSink) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return
+	}
+
+	sub := &progressSubscriber{
+		sink:    sink,
+		pending: make(map[string]ProgressUpdate),
+		notify:  make(chan struct{}, 1),
+	}
+	d.subscribers = append(d.subscribers, sub)
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		sub.run()
+	}()
+}
+
+// Publish queues an update for every sink without blocking. It is safe to
+// call from multiple goroutines and can be used as the manager's progress callback.
+func (d *ProgressDispatcher) Publish(update ProgressUpdate) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return
+	}
+
+	for _, sub := range d.subscribers {
+		sub.enqueue(update, false)
+	}
+}
+
+// Close delivers the final update to every sink and waits until all queued
+// updates have been consumed
+func (d *ProgressDispatcher) Close(final ProgressUpdate) {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	for _, sub := range d.subscribers {
+		sub.enqueue(final, true)
+		sub.close()
+	}
+	d.mu.Unlock()
+
+	d.wg.Wait()
+}
+
+// Coalesced returns how many updates were replaced by newer ones before delivery
+func (d *ProgressDispatcher) Coalesced() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	total := 0
+	for _, sub := range d.subscribers {
+		sub.mu.Lock()
+		total += sub.coalesced
+		sub.mu.Unlock()
+	}
+	return total
+}
+
+// enqueue stores the update, replacing an undelivered update of the same
+// analyzer. Forced updates always replace the pending one.
+func (s *progressSubscriber) enqueue(update ProgressUpdate, force bool) {
+	s.mu.Lock()
+	key := update.AnalyzerType
+	if previous, exists := s.pending[key]; exists {
+		// Never let an intermediate update hide a completion
+		if !force && previous.Progress >= 100 && update.Progress < 100 {
+			s.mu.Unlock()
+			return
+		}
+		s.coalesced++
+	} else {
+		s.order = append(s.order, key)
+	}
+	s.pending[key] = update
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+		// A wake-up is already queued
+	}
+}
+
+// close marks the subscriber as finished once its queue is drained
+func (s *progressSubscriber) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers queued updates in arrival order until closed and drained
+func (s *progressSubscriber) run() {
+	for range s.notify {
+		s.mu.Lock()
+		batch := make([]ProgressUpdate, 0, len(s.order))
+		for _, key := range s.order {
+			batch = append(batch, s.pending[key])
+		}
+		s.pending = make(map[string]ProgressUpdate)
+		s.order = nil
+		closed := s.closed
+		s.mu.Unlock()
+
+		for _, update := range batch {
+			s.sink(update)
+		}
+
+		if closed {
+			s.mu.Lock()
+			drained := len(s.order) == 0
+			s.mu.Unlock()
+			if drained {
+				return
+			}
+		}
+	}
+}
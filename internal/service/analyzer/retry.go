@@ -0,0 +1,153 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/chaos"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/lighthouse"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// resetter сбрасывает метрики, проблемы и рекомендации, накопленные
+// неудачной попыткой анализа
+type resetter interface {
+	Reset()
+}
+
+// Reset очищает результаты анализатора перед повторной попыткой
+func (a *BaseAnalyzer) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.metrics = make(map[string]interface{})
+	a.issues = make([]map[string]interface{}, 0)
+	a.recommendations = make([]string, 0)
+}
+
+// Reset очищает результаты обернутого анализатора
+func (a *ChaosAnalyzer) Reset() {
+	if r, ok := a.Analyzer.(resetter); ok {
+		r.Reset()
+	}
+}
+
+// IsTransient сообщает, является ли ошибка анализатора временной: сетевой
+// сбой, таймаут или ответ внешнего сервиса 5xx/429. Такие ошибки имеет смысл
+// повторить
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var lighthouseErr *lighthouse.StatusError
+	if errors.As(err, &lighthouseErr) {
+		return transientStatus(lighthouseErr.Code)
+	}
+	var chaosErr *chaos.StatusError
+	if errors.As(err, &chaosErr) {
+		return transientStatus(chaosErr.Code)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// transientStatus сообщает, стоит ли повторять запрос с таким HTTP-статусом
+func transientStatus(code int) bool {
+	return code >= 500 || code == http.StatusTooManyRequests
+}
+
+// analyzeWithRetries выполняет анализатор, повторяя его при временных ошибках
+// до AnalyzerMaxRetries раз с экспоненциальной задержкой. Возвращает число
+// выполненных повторов
+func (m *AnalyzerManager) analyzeWithRetries(
+	ctx context.Context,
+	analyzerType AnalyzerType,
+	analyzer Analyzer,
+	data *parser.WebsiteData,
+	prevResults map[AnalyzerType]map[string]interface{},
+) (map[string]interface{}, int, error) {
+	maxRetries, backoff := 0, time.Duration(0)
+	if m.config != nil {
+		maxRetries, backoff = m.config.AnalyzerMaxRetries, m.config.AnalyzerRetryBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		result, err := analyzer.Analyze(ctx, data, prevResults)
+		// Повторы не начинаются, если истекло время всего слоя или анализа
+		if err == nil || attempt >= maxRetries || ctx.Err() != nil || !IsTransient(err) {
+			return result, attempt, err
+		}
+
+		delay := backoff << attempt
+		log.Printf("Analyzer %s failed with a transient error, retry %d of %d in %v: %v",
+			analyzerType, attempt+1, maxRetries, delay, err)
+		select {
+		case <-ctx.Done():
+			return result, attempt, err
+		case <-time.After(delay):
+		}
+
+		if r, ok := analyzer.(resetter); ok {
+			r.Reset()
+		}
+	}
+}
+
+// recordRetries запоминает число повторов анализатора в текущем анализе
+func (m *AnalyzerManager) recordRetries(analyzerType AnalyzerType, retries int) {
+	m.retriesMu.Lock()
+	defer m.retriesMu.Unlock()
+
+	if retries == 0 {
+		delete(m.retries, analyzerType)
+		return
+	}
+	if m.retries == nil {
+		m.retries = make(map[AnalyzerType]int)
+	}
+	m.retries[analyzerType] = retries
+}
+
+// GetRetries возвращает число повторов анализаторов, которые завершились
+// временной ошибкой хотя бы один раз
+func (m *AnalyzerManager) GetRetries() map[AnalyzerType]int {
+	m.retriesMu.Lock()
+	defer m.retriesMu.Unlock()
+
+	retries := make(map[AnalyzerType]int, len(m.retries))
+	for analyzerType, count := range m.retries {
+		retries[analyzerType] = count
+	}
+	return retries
+}
+
+// withRetries добавляет число повторов в копию результата анализатора
+func withRetries(result map[string]interface{}, retries int) map[string]interface{} {
+	if retries == 0 || result == nil {
+		return result
+	}
+	annotated := make(map[string]interface{}, len(result)+1)
+	for key, value := range result {
+		annotated[key] = value
+	}
+	annotated["retries"] = retries
+	return annotated
+}
@@ -0,0 +1,131 @@
+package analyzer
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+)
+
+// SandboxAnalyzerTypes — анализаторы, для которых генерируются
+// синтетические результаты в режиме песочницы
+var SandboxAnalyzerTypes = []AnalyzerType{
+	SEOType,
+	PerformanceType,
+	SecurityType,
+	AccessibilityType,
+	MobileType,
+	StructureType,
+	ContentType,
+}
+
+// sandboxFinding — типичная проблема анализатора с рекомендацией
+type sandboxFinding struct {
+	issueType      string
+	severity       string
+	description    string
+	recommendation string
+}
+
+// sandboxCatalog содержит реальные типы проблем анализаторов, из которых
+// составляются синтетические результаты
+var sandboxCatalog = map[AnalyzerType][]sandboxFinding{
+	SEOType: {
+		{"missing_h1", "high", "На странице отсутствует заголовок H1", "Добавьте один заголовок H1, описывающий содержание страницы"},
+		{"multiple_h1", "medium", "На странице несколько заголовков H1", "Оставьте на странице только один заголовок H1"},
+		{"missing_h2", "medium", "На странице отсутствуют заголовки H2", "Структурируйте контент с помощью подзаголовков H2"},
+		{"broken_links", "high", "На странице есть неработающие ссылки", "Исправьте или удалите неработающие ссылки"},
+	},
+	PerformanceType: {
+		{"slow_lcp", "high", "Медленный Largest Contentful Paint", "Оптимизируйте загрузку самого крупного элемента первого экрана"},
+		{"slow_fcp", "medium", "Медленный First Contentful Paint", "Сократите время до первой отрисовки контента"},
+		{"large_image", "medium", "Изображение слишком большого размера", "Сжимайте изображения и используйте современные форматы (WebP, AVIF)"},
+		{"render_blocking_script", "medium", "Скрипт может блокировать рендеринг", "Добавьте атрибуты async или defer к скриптам"},
+	},
+	SecurityType: {
+		{"missing_csp", "medium", "Content Security Policy не реализована", "Настройте заголовок Content-Security-Policy"},
+		{"missing_hsts", "medium", "HTTP Strict Transport Security не реализован", "Добавьте заголовок Strict-Transport-Security"},
+		{"missing_xss_protection", "medium", "Заголовок X-XSS-Protection не реализован", "Добавьте заголовок X-XSS-Protection"},
+		{"no_https", "high", "Сайт не использует HTTPS", "Переведите сайт на HTTPS"},
+	},
+	AccessibilityType: {
+		{"missing_alt_text", "high", "Изображения без альтернативного текста", "Добавьте атрибут alt ко всем информативным изображениям"},
+		{"missing_form_labels", "high", "Поля форм без соответствующих меток", "Свяжите каждое поле формы с элементом label"},
+		{"potential_contrast_issues", "medium", "Потенциальные проблемы с контрастностью текста", "Обеспечьте контрастность текста не ниже 4.5:1"},
+		{"no_aria", "medium", "ARIA-атрибуты не используются для вспомогательных технологий", "Используйте ARIA-атрибуты для интерактивных элементов"},
+	},
+	MobileType: {
+		{"incomplete_viewport", "medium", "Неполная конфигурация метатега viewport", "Используйте <meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">"},
+		{"small_font_for_mobile", "medium", "Размер шрифта может быть слишком мал для мобильных устройств", "Используйте базовый размер шрифта не меньше 16px"},
+		{"no_media_queries", "high", "Не обнаружены медиа-запросы для адаптивного дизайна", "Добавьте медиа-запросы для адаптации под разные экраны"},
+	},
+	StructureType: {
+		{"insufficient_semantic_html", "medium", "Недостаточное использование семантических HTML-элементов", "Используйте header, nav, main, article и footer"},
+		{"missing_critical_semantic_tags", "high", "Отсутствуют критически важные семантические элементы", "Добавьте элементы main и nav"},
+		{"missing_doctype", "high", "Отсутствует объявление DOCTYPE", "Добавьте <!DOCTYPE html> в начало документа"},
+	},
+	ContentType: {
+		{"low_word_count", "medium", "Недостаточное количество слов для качественного контента", "Увеличьте объем текстового контента до 300+ слов"},
+		{"long_sentences", "medium", "Предложения слишком длинные, что затрудняет чтение", "Сократите длину предложений до 15-20 слов"},
+		{"complex_readability", "medium", "Текст может быть слишком сложным для понимания", "Упростите текст для улучшения читаемости"},
+	},
+}
+
+// SandboxResult — синтетический результат анализа
+type SandboxResult struct {
+	Results         map[AnalyzerType]map[string]interface{}
+	Issues          map[AnalyzerType][]map[string]interface{}
+	Recommendations map[AnalyzerType][]string
+}
+
+// SyntheticResults генерирует правдоподобные результаты анализа без загрузки
+// страницы. Результаты детерминированы: один и тот же URL всегда получает
+// одни и те же оценки и проблемы, чтобы интеграции можно было тестировать.
+func SyntheticResults(pageURL string, types []AnalyzerType) *SandboxResult {
+	if len(types) == 0 {
+		types = SandboxAnalyzerTypes
+	}
+
+	result := &SandboxResult{
+		Results:         make(map[AnalyzerType]map[string]interface{}),
+		Issues:          make(map[AnalyzerType][]map[string]interface{}),
+		Recommendations: make(map[AnalyzerType][]string),
+	}
+	for _, analyzerType := range types {
+		catalog, ok := sandboxCatalog[analyzerType]
+		if !ok {
+			continue
+		}
+
+		hash := fnv.New64a()
+		hash.Write([]byte(pageURL + "|" + string(analyzerType)))
+		rng := rand.New(rand.NewSource(int64(hash.Sum64())))
+
+		// Оценка 45-98; чем ниже оценка, тем больше проблем
+		score := math.Round((45+rng.Float64()*53)*10) / 10
+		count := int(math.Ceil((100 - score) / 100 * float64(len(catalog)) * 1.5))
+		if count > len(catalog) {
+			count = len(catalog)
+		}
+
+		issues := make([]map[string]interface{}, 0, count)
+		recommendations := make([]string, 0, count)
+		for _, i := range rng.Perm(len(catalog))[:count] {
+			finding := catalog[i]
+			issues = append(issues, map[string]interface{}{
+				"type":        finding.issueType,
+				"severity":    finding.severity,
+				"description": finding.description,
+			})
+			recommendations = append(recommendations, finding.recommendation)
+		}
+
+		result.Results[analyzerType] = map[string]interface{}{
+			"score":        score,
+			"issues_count": len(issues),
+			"sandbox":      true,
+		}
+		result.Issues[analyzerType] = issues
+		result.Recommendations[analyzerType] = recommendations
+	}
+	return result
+}
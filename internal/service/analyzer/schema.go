@@ -0,0 +1,59 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/schema"
+)
+
+// IssueSeverities - допустимые значения severity в проблемах анализаторов
+var IssueSeverities = []interface{}{"high", "medium", "low"}
+
+// ResultSchema описывает результат анализатора: произвольные метрики и
+// оценку от 0 до 100 у оцениваемых категорий
+var ResultSchema = &schema.Schema{
+	Schema:      schema.Draft,
+	Title:       "AnalyzerResult",
+	Description: "Metrics reported by one analyzer. Besides the 0-100 score of scored categories, the metrics depend on the analyzer",
+	Type:        "object",
+	Properties: map[string]*schema.Schema{
+		"score": schema.Range(&schema.Schema{Type: "number"}, 0, 100),
+	},
+}
+
+// IssueSchema описывает проблему, найденную анализатором
+var IssueSchema = &schema.Schema{
+	Schema:      schema.Draft,
+	Title:       "AnalyzerIssue",
+	Description: "An issue reported by an analyzer. Besides the listed fields, issues carry analyzer-specific details",
+	Type:        "object",
+	Properties: map[string]*schema.Schema{
+		"type":        {Type: "string"},
+		"severity":    {Type: "string", Enum: IssueSeverities},
+		"description": {Type: "string"},
+		"url":         {Type: "string"},
+		"selector":    {Type: "string"},
+	},
+	Required: []string{"type", "severity", "description"},
+}
+
+// ValidateResults проверяет результаты и проблемы анализаторов по схемам и
+// возвращает найденные нарушения, упорядоченные по анализатору
+func ValidateResults(results map[AnalyzerType]map[string]interface{}, issues map[AnalyzerType][]map[string]interface{}) []string {
+	var violations []string
+	for analyzerType, result := range results {
+		for _, violation := range ResultSchema.Validate(result) {
+			violations = append(violations, fmt.Sprintf("%s result %s", analyzerType, violation))
+		}
+	}
+	for analyzerType, analyzerIssues := range issues {
+		for i, issue := range analyzerIssues {
+			for _, violation := range IssueSchema.Validate(issue) {
+				violations = append(violations, fmt.Sprintf("%s issue %d %s", analyzerType, i, violation))
+			}
+		}
+	}
+	sort.Strings(violations)
+	return violations
+}
@@ -88,6 +88,9 @@ func (a *SecurityAnalyzer) Analyze(ctx context.Context, data *parser.WebsiteData
 	// Проверка HTTPS - всегда выполняем, так как это базовая проверка безопасности
 	a.analyzeHTTPS(data)
 
+	// Рекомендуемая CSP по фактически используемым ресурсам
+	a.SetMetric("csp_policy", GenerateCSP(data, doc))
+
 	// Проверки, которые можно пропустить, если у нас есть достаточно данных из Lighthouse
 	if !hasBestPracticesData {
 		a.analyzeSecurityHeaders(data)
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
 )
@@ -88,6 +89,7 @@ func (a *SEOAnalyzer) Analyze(ctx context.Context, data *parser.WebsiteData, pre
 	a.analyzeHeadings(data)
 	a.analyzeImages(data)
 	a.analyzeLinks(data)
+	a.analyzeLinkHygiene(data)
 	a.analyzeCanonical(data)
 	a.analyzeKeywords(data)
 
@@ -202,7 +204,13 @@ func (a *SEOAnalyzer) analyzeLinks(data *parser.WebsiteData) {
 
 // analyzeKeywords анализирует плотность ключевых слов
 func (a *SEOAnalyzer) analyzeKeywords(data *parser.WebsiteData) {
-	words := strings.Fields(strings.ToLower(data.TextContent))
+	// Считаем плотность по основному контенту, чтобы меню и футер не искажали результат
+	text := data.MainContent.Text
+	if text == "" {
+		text = data.TextContent
+	}
+
+	words := strings.Fields(strings.ToLower(text))
 	wordCount := make(map[string]int)
 	totalWords := len(words)
 
@@ -211,15 +219,13 @@ func (a *SEOAnalyzer) analyzeKeywords(data *parser.WebsiteData) {
 	}
 
 	for _, word := range words {
+		// Удаляем пунктуацию
+		word = strings.Trim(word, ".,?!:;()\"'«»")
 		// Пропускаем короткие слова и стоп-слова
-		if len(word) <= 2 || isStopWord(word) {
+		if utf8.RuneCountInString(word) <= 2 || isStopWord(word) {
 			continue
 		}
-		// Удаляем пунктуацию
-		word = strings.Trim(word, ".,?!:;()")
-		if word != "" {
-			wordCount[word]++
-		}
+		wordCount[word]++
 	}
 
 	keywordDensity := make(map[string]float64)
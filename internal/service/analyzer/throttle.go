@@ -0,0 +1,305 @@
+package analyzer
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/config"
+)
+
+// Weights of the analyzers that are heavier than the others. Analyzers not
+// listed weigh 1.
+var defaultAnalyzerWeights = map[AnalyzerType]int{
+	LighthouseType: 4, // waits on a full Lighthouse run
+	NoScriptType:   3, // renders the page in a headless browser
+	GeoType:        2, // fetches the page once per locale
+	DomainType:     2, // probes every subdomain
+}
+
+// pressureSampleInterval limits how often memory and CPU pressure is read
+const pressureSampleInterval = time.Second
+
+// Pressure is the memory and CPU pressure of the instance
+type Pressure struct {
+	// MemoryPercent is the share of the memory limit in use, 0 when unknown
+	MemoryPercent float64 `json:"memory_percent"`
+	// LoadPerCPU is the one-minute load average per CPU, 0 when unknown
+	LoadPerCPU float64 `json:"load_per_cpu"`
+	High       bool    `json:"high"`
+}
+
+// ThrottleStats describes the analyzers running on the instance
+type ThrottleStats struct {
+	Capacity          int                  `json:"capacity"`
+	EffectiveCapacity int                  `json:"effective_capacity"`
+	InUse             int                  `json:"in_use"`
+	Running           map[AnalyzerType]int `json:"running"`
+	Waiting           int                  `json:"waiting"`
+	Weights           map[AnalyzerType]int `json:"weights"`
+	Pressure          Pressure             `json:"pressure"`
+}
+
+// Throttle limits the analyzers running at once across all analyses of the
+// instance. Each analyzer takes its weight out of a shared capacity, so
+// heavy analyzers do not run alongside each other on small instances. Under
+// memory or CPU pressure the capacity is halved until the pressure passes.
+// An analyzer heavier than the capacity runs alone.
+type Throttle struct {
+	capacity       int
+	weights        map[AnalyzerType]int
+	memoryHigh     float64 // percent of the memory limit
+	loadHigh       float64 // load average per CPU
+	sampleInterval time.Duration
+
+	mu       sync.Mutex
+	inUse    int
+	running  map[AnalyzerType]int
+	waiting  int
+	released chan struct{} // closed when capacity is released
+
+	pressure   Pressure
+	sampledAt  time.Time
+	readMemory func() float64
+	readLoad   func() float64
+}
+
+// NewThrottle creates a throttle. A capacity of zero derives it from the
+// number of CPUs; weights override the defaults by analyzer type.
+func NewThrottle(capacity int, weights map[string]int, memoryHighPercent, loadHighPerCPU float64) *Throttle {
+	if capacity <= 0 {
+		capacity = 2 * runtime.NumCPU()
+		if capacity < 2 {
+			capacity = 2
+		}
+	}
+
+	merged := make(map[AnalyzerType]int, len(defaultAnalyzerWeights)+len(weights))
+	for at, w := range defaultAnalyzerWeights {
+		merged[at] = w
+	}
+	for name, w := range weights {
+		if w > 0 {
+			merged[AnalyzerType(name)] = w
+		}
+	}
+
+	return &Throttle{
+		capacity:       capacity,
+		weights:        merged,
+		memoryHigh:     memoryHighPercent,
+		loadHigh:       loadHighPerCPU,
+		sampleInterval: pressureSampleInterval,
+		running:        make(map[AnalyzerType]int),
+		released:       make(chan struct{}),
+		readMemory:     memoryPercent,
+		readLoad:       loadPerCPU,
+	}
+}
+
+var (
+	sharedThrottle     *Throttle
+	sharedThrottleOnce sync.Once
+)
+
+// CurrentThrottle returns the throttle shared by the analyses of the
+// instance, created from the configuration on first use
+func CurrentThrottle(cfg *config.Config) *Throttle {
+	sharedThrottleOnce.Do(func() {
+		sharedThrottle = NewThrottle(cfg.AnalyzerMaxParallel, cfg.AnalyzerWeights,
+			cfg.AnalyzerMemoryHighPercent, cfg.AnalyzerLoadHighPerCPU)
+	})
+	return sharedThrottle
+}
+
+// Weight returns the weight of an analyzer
+func (t *Throttle) Weight(at AnalyzerType) int {
+	if w, ok := t.weights[at]; ok {
+		return w
+	}
+	return 1
+}
+
+// Acquire waits until the analyzer fits in the capacity and returns the
+// function releasing it. It fails only when the context ends first.
+func (t *Throttle) Acquire(ctx context.Context, at AnalyzerType) (func(), error) {
+	weight := t.Weight(at)
+
+	t.mu.Lock()
+	t.waiting++
+	for {
+		effective := t.effectiveCapacityLocked()
+		if t.inUse == 0 || t.inUse+weight <= effective {
+			break
+		}
+
+		released := t.released
+		t.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			t.mu.Lock()
+			t.waiting--
+			t.mu.Unlock()
+			return nil, ctx.Err()
+		case <-released:
+		case <-time.After(t.sampleInterval):
+			// Pressure may have passed without any analyzer finishing
+		}
+		t.mu.Lock()
+	}
+	t.waiting--
+	t.inUse += weight
+	t.running[at]++
+	t.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { t.release(at, weight) })
+	}, nil
+}
+
+// release returns the weight of a finished analyzer and wakes the waiting ones
+func (t *Throttle) release(at AnalyzerType, weight int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inUse -= weight
+	if t.running[at]--; t.running[at] <= 0 {
+		delete(t.running, at)
+	}
+	close(t.released)
+	t.released = make(chan struct{})
+}
+
+// effectiveCapacityLocked returns the capacity under the current pressure
+func (t *Throttle) effectiveCapacityLocked() int {
+	if time.Since(t.sampledAt) >= t.sampleInterval {
+		t.sampledAt = time.Now()
+		t.pressure = t.samplePressure()
+	}
+	if !t.pressure.High {
+		return t.capacity
+	}
+	if reduced := t.capacity / 2; reduced > 1 {
+		return reduced
+	}
+	return 1
+}
+
+// samplePressure reads memory and CPU pressure. Thresholds of zero disable
+// the corresponding check.
+func (t *Throttle) samplePressure() Pressure {
+	p := Pressure{
+		MemoryPercent: t.readMemory(),
+		LoadPerCPU:    t.readLoad(),
+	}
+	p.High = (t.memoryHigh > 0 && p.MemoryPercent >= t.memoryHigh) ||
+		(t.loadHigh > 0 && p.LoadPerCPU >= t.loadHigh)
+	return p
+}
+
+// Stats returns the analyzers running on the instance and the pressure
+func (t *Throttle) Stats() ThrottleStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	running := make(map[AnalyzerType]int, len(t.running))
+	for at, n := range t.running {
+		running[at] = n
+	}
+	weights := make(map[AnalyzerType]int, len(t.weights))
+	for at, w := range t.weights {
+		weights[at] = w
+	}
+	effective := t.effectiveCapacityLocked()
+
+	return ThrottleStats{
+		Capacity:          t.capacity,
+		EffectiveCapacity: effective,
+		InUse:             t.inUse,
+		Running:           running,
+		Waiting:           t.waiting,
+		Weights:           weights,
+		Pressure:          t.pressure,
+	}
+}
+
+// memoryPercent returns the share of the memory limit in use: the cgroup
+// limit in containers, the machine memory otherwise. It returns 0 where
+// neither can be read.
+func memoryPercent() float64 {
+	// cgroup v2, then v1
+	for _, files := range [][2]string{
+		{"/sys/fs/cgroup/memory.current", "/sys/fs/cgroup/memory.max"},
+		{"/sys/fs/cgroup/memory/memory.usage_in_bytes", "/sys/fs/cgroup/memory/memory.limit_in_bytes"},
+	} {
+		usage, okUsage := readUint(files[0])
+		limit, okLimit := readUint(files[1])
+		// Unlimited groups report "max" or a huge number
+		if okUsage && okLimit && limit > 0 && limit < 1<<60 {
+			return float64(usage) / float64(limit) * 100
+		}
+	}
+
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	var total, available float64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			total = value
+		case "MemAvailable:":
+			available = value
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return (total - available) / total * 100
+}
+
+// loadPerCPU returns the one-minute load average per CPU, or 0 where it
+// cannot be read
+func loadPerCPU() float64 {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0
+	}
+	load, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	return load / float64(runtime.NumCPU())
+}
+
+// readUint reads a file holding one unsigned integer
+func readUint(path string) (uint64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	return value, err == nil
+}
@@ -0,0 +1,281 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// maxUserAgentBodySize ограничивает объем страницы, читаемый при каждом запросе
+const maxUserAgentBodySize = 5 << 20
+
+// contentLengthMismatch - относительная разница размера страницы, начиная
+// с которой версии для разных User-Agent считаются различными
+const contentLengthMismatch = 0.3
+
+// Виды User-Agent, с которыми запрашивается страница
+const (
+	UserAgentBrowser  = "browser"
+	UserAgentCrawler  = "googlebot"
+	UserAgentAnalyzer = "analyzer"
+)
+
+// userAgentProbes - User-Agent, с которыми запрашивается страница. Версия
+// для браузера служит эталоном для сравнения.
+var userAgentProbes = []struct {
+	name      string
+	userAgent string
+}{
+	{UserAgentBrowser, parser.DesktopDevice.UserAgent},
+	{UserAgentCrawler, "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"},
+	{UserAgentAnalyzer, parser.DefaultParseOptions().UserAgent},
+}
+
+// UserAgentProbe - ответ сайта на запрос с одним User-Agent
+type UserAgentProbe struct {
+	Name          string `json:"name"`
+	UserAgent     string `json:"user_agent"`
+	StatusCode    int    `json:"status_code,omitempty"`
+	FinalURL      string `json:"final_url,omitempty"`
+	ContentLength int    `json:"content_length"`
+	Title         string `json:"title,omitempty"`
+	MetaRobots    string `json:"meta_robots,omitempty"`
+	XRobotsTag    string `json:"x_robots_tag,omitempty"`
+	// Challenge - система защиты, отдавшая страницу проверки вместо контента
+	Challenge string `json:"challenge,omitempty"`
+	LatencyMs int64  `json:"latency_ms,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// UserAgentMismatch - отличие ответа для одного User-Agent от ответа браузеру
+type UserAgentMismatch struct {
+	Probe   string `json:"probe"`
+	Field   string `json:"field"`
+	Browser string `json:"browser"`
+	Value   string `json:"value"`
+}
+
+// UserAgentComparison сравнивает ответы сайта браузеру, поисковому роботу
+// и анализатору
+type UserAgentComparison struct {
+	Probes     []*UserAgentProbe   `json:"probes"`
+	Mismatches []UserAgentMismatch `json:"mismatches,omitempty"`
+}
+
+// compareUserAgents запрашивает страницу с разными User-Agent и сравнивает
+// ответы с ответом браузеру
+func (a *InfrastructureAnalyzer) compareUserAgents(ctx context.Context, pageURL string) *UserAgentComparison {
+	comparison := &UserAgentComparison{Probes: make([]*UserAgentProbe, len(userAgentProbes))}
+
+	var wg sync.WaitGroup
+	for i, probe := range userAgentProbes {
+		wg.Add(1)
+		go func(i int, name, userAgent string) {
+			defer wg.Done()
+			comparison.Probes[i] = a.probeUserAgent(ctx, pageURL, name, userAgent)
+		}(i, probe.name, probe.userAgent)
+	}
+	wg.Wait()
+
+	browser := comparison.Probes[0]
+	if browser.Error != "" {
+		return comparison
+	}
+	for _, probe := range comparison.Probes[1:] {
+		comparison.Mismatches = append(comparison.Mismatches, diffUserAgentProbes(browser, probe)...)
+	}
+	return comparison
+}
+
+// probeUserAgent запрашивает страницу с указанным User-Agent
+func (a *InfrastructureAnalyzer) probeUserAgent(ctx context.Context, pageURL, name, userAgent string) *UserAgentProbe {
+	probe := &UserAgentProbe{Name: name, UserAgent: userAgent}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	start := time.Now()
+	resp, err := a.client.Do(req)
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxUserAgentBodySize))
+	probe.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+
+	probe.StatusCode = resp.StatusCode
+	probe.FinalURL = resp.Request.URL.String()
+	probe.ContentLength = len(body)
+	probe.XRobotsTag = strings.ToLower(strings.TrimSpace(resp.Header.Get("X-Robots-Tag")))
+
+	if doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(body))); err == nil {
+		probe.Title = strings.Join(strings.Fields(doc.Find("head title").First().Text()), " ")
+		doc.Find("meta[name]").Each(func(_ int, s *goquery.Selection) {
+			name, _ := s.Attr("name")
+			if strings.EqualFold(name, "robots") {
+				content, _ := s.Attr("content")
+				probe.MetaRobots = strings.ToLower(strings.Join(strings.Fields(content), ""))
+			}
+		})
+	}
+
+	if challenge := parser.DetectChallenge(resp.StatusCode, resp.Header, string(body)); challenge != nil {
+		probe.Challenge = challenge.Provider
+		if probe.Challenge == "" {
+			probe.Challenge = challenge.Kind
+		}
+	}
+
+	return probe
+}
+
+// diffUserAgentProbes возвращает отличия ответа probe от ответа браузеру
+func diffUserAgentProbes(browser, probe *UserAgentProbe) []UserAgentMismatch {
+	if probe.Error != "" {
+		return []UserAgentMismatch{{Probe: probe.Name, Field: "error", Value: probe.Error}}
+	}
+
+	var mismatches []UserAgentMismatch
+	add := func(field, browserValue, value string) {
+		mismatches = append(mismatches, UserAgentMismatch{Probe: probe.Name, Field: field, Browser: browserValue, Value: value})
+	}
+
+	if browser.StatusCode != probe.StatusCode {
+		add("status_code", fmt.Sprint(browser.StatusCode), fmt.Sprint(probe.StatusCode))
+	}
+	if probe.Challenge != "" && browser.Challenge == "" {
+		add("challenge", "", probe.Challenge)
+	}
+	if browser.MetaRobots != probe.MetaRobots {
+		add("meta_robots", browser.MetaRobots, probe.MetaRobots)
+	}
+	if browser.XRobotsTag != probe.XRobotsTag {
+		add("x_robots_tag", browser.XRobotsTag, probe.XRobotsTag)
+	}
+	if browser.Title != probe.Title {
+		add("title", browser.Title, probe.Title)
+	}
+	if browser.FinalURL != probe.FinalURL {
+		add("final_url", browser.FinalURL, probe.FinalURL)
+	}
+	if browser.ContentLength > 0 {
+		diff := math.Abs(float64(probe.ContentLength-browser.ContentLength)) / float64(browser.ContentLength)
+		if diff >= contentLengthMismatch {
+			add("content_length", fmt.Sprint(browser.ContentLength), fmt.Sprint(probe.ContentLength))
+		}
+	}
+	return mismatches
+}
+
+// reportUserAgentIssues добавляет проблемы по результатам сравнения ответов
+// для разных User-Agent
+func (a *InfrastructureAnalyzer) reportUserAgentIssues(comparison *UserAgentComparison) {
+	browser := comparison.Probes[0]
+	if browser.Error != "" || browser.StatusCode >= http.StatusBadRequest {
+		return
+	}
+
+	fields := make(map[string]map[string]UserAgentMismatch)
+	for _, mismatch := range comparison.Mismatches {
+		if fields[mismatch.Probe] == nil {
+			fields[mismatch.Probe] = make(map[string]UserAgentMismatch)
+		}
+		fields[mismatch.Probe][mismatch.Field] = mismatch
+	}
+	var crawler, analyzerProbe *UserAgentProbe
+	for _, probe := range comparison.Probes {
+		switch probe.Name {
+		case UserAgentCrawler:
+			crawler = probe
+		case UserAgentAnalyzer:
+			analyzerProbe = probe
+		}
+	}
+
+	if crawler != nil && blockedUserAgent(browser, crawler) {
+		a.AddIssue(map[string]interface{}{
+			"type":        "crawler_blocked",
+			"severity":    "high",
+			"description": "Поисковому роботу Googlebot сайт отвечает ошибкой или страницей проверки WAF, тогда как браузер получает контент. Проверка выполнялась не с IP-адресов Google, поэтому WAF, проверяющий подлинность робота, мог заблокировать именно этот запрос",
+			"status_code": crawler.StatusCode,
+			"challenge":   crawler.Challenge,
+		})
+		a.AddRecommendation("Убедитесь, что WAF и анти-бот защита пропускают настоящих поисковых роботов (проверка по обратному DNS), и проверьте отчет о сканировании в Google Search Console")
+	} else if crawler != nil {
+		mismatches := fields[UserAgentCrawler]
+		_, metaRobots := mismatches["meta_robots"]
+		_, xRobots := mismatches["x_robots_tag"]
+		if metaRobots || xRobots {
+			a.AddIssue(map[string]interface{}{
+				"type":        "robots_directives_differ_by_user_agent",
+				"severity":    "high",
+				"description": "Директивы robots (мета-тег или X-Robots-Tag) для поискового робота отличаются от директив для браузера",
+				"browser":     []string{browser.MetaRobots, browser.XRobotsTag},
+				"crawler":     []string{crawler.MetaRobots, crawler.XRobotsTag},
+			})
+			a.AddRecommendation("Отдавайте поисковым роботам и посетителям одинаковые директивы robots, иначе страница может неожиданно выпасть из индекса")
+		}
+		_, title := mismatches["title"]
+		_, length := mismatches["content_length"]
+		_, redirect := mismatches["final_url"]
+		if title || length || redirect {
+			a.AddIssue(map[string]interface{}{
+				"type":        "cloaking_suspected",
+				"severity":    "medium",
+				"description": "Поисковый робот получает страницу, отличающуюся от версии для браузера (заголовок, объем контента или адрес после перенаправлений). Поисковые системы считают такой клоакинг нарушением",
+				"differences": userAgentMismatchFields(mismatches),
+			})
+			a.AddRecommendation("Показывайте поисковым роботам тот же контент, что и посетителям; для динамического рендеринга отдавайте роботу эквивалентную версию страницы")
+		}
+	}
+
+	if analyzerProbe != nil && blockedUserAgent(browser, analyzerProbe) {
+		a.AddIssue(map[string]interface{}{
+			"type":        "automated_clients_blocked",
+			"severity":    "low",
+			"description": "Сайт блокирует автоматизированные клиенты: инструменты аудита, мониторинга и предпросмотра ссылок могут получать страницу проверки вместо контента",
+			"status_code": analyzerProbe.StatusCode,
+			"challenge":   analyzerProbe.Challenge,
+		})
+	}
+}
+
+// blockedUserAgent сообщает, получил ли probe ошибку или страницу проверки
+// вместо контента, который получил браузер
+func blockedUserAgent(browser, probe *UserAgentProbe) bool {
+	if probe.Error != "" {
+		return false
+	}
+	return probe.StatusCode >= http.StatusBadRequest || (probe.Challenge != "" && browser.Challenge == "")
+}
+
+// userAgentMismatchFields возвращает поля, по которым ответы различаются
+func userAgentMismatchFields(mismatches map[string]UserAgentMismatch) []string {
+	fields := make([]string, 0, len(mismatches))
+	for _, field := range []string{"status_code", "challenge", "meta_robots", "x_robots_tag", "title", "final_url", "content_length"} {
+		if _, ok := mismatches[field]; ok {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,144 @@
+package backlinks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProviderAhrefs is the Ahrefs Site Explorer API v3
+const ProviderAhrefs = "ahrefs"
+
+const ahrefsDefaultURL = "https://api.ahrefs.com/v3/site-explorer"
+
+func init() {
+	Register(ProviderAhrefs, newAhrefs)
+}
+
+// ahrefs fetches backlink profiles from the Ahrefs Site Explorer API
+type ahrefs struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func newAhrefs(cfg Config) (Provider, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("%s requires an API key", ProviderAhrefs)
+	}
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = ahrefsDefaultURL
+	}
+	return &ahrefs{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     cfg.APIKey,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// Name returns the provider name
+func (a *ahrefs) Name() string {
+	return ProviderAhrefs
+}
+
+// Profile returns the live backlink counts and the top backlinks by domain
+// rating of the linking site
+func (a *ahrefs) Profile(ctx context.Context, domain string, topLimit int) (*Profile, error) {
+	if topLimit <= 0 {
+		topLimit = DefaultTopLimit
+	}
+
+	var stats struct {
+		Metrics struct {
+			Live           int64 `json:"live"`
+			LiveRefdomains int64 `json:"live_refdomains"`
+		} `json:"metrics"`
+	}
+	params := url.Values{}
+	params.Set("target", domain)
+	params.Set("mode", "domain")
+	params.Set("date", time.Now().UTC().Format("2006-01-02"))
+	if err := a.get(ctx, "/backlinks-stats", params, &stats); err != nil {
+		return nil, err
+	}
+
+	var links struct {
+		Backlinks []struct {
+			URLFrom            string  `json:"url_from"`
+			URLTo              string  `json:"url_to"`
+			Anchor             string  `json:"anchor"`
+			DomainRatingSource float64 `json:"domain_rating_source"`
+			FirstSeen          string  `json:"first_seen"`
+		} `json:"backlinks"`
+	}
+	params = url.Values{}
+	params.Set("target", domain)
+	params.Set("mode", "domain")
+	params.Set("select", "url_from,url_to,anchor,domain_rating_source,first_seen")
+	params.Set("order_by", "domain_rating_source:desc")
+	params.Set("limit", strconv.Itoa(topLimit))
+	if err := a.get(ctx, "/all-backlinks", params, &links); err != nil {
+		return nil, err
+	}
+
+	profile := &Profile{
+		Domain:           domain,
+		ReferringDomains: stats.Metrics.LiveRefdomains,
+		Backlinks:        stats.Metrics.Live,
+		TopBacklinks:     make([]Backlink, 0, len(links.Backlinks)),
+	}
+	for _, link := range links.Backlinks {
+		backlink := Backlink{
+			SourceURL:    link.URLFrom,
+			TargetURL:    link.URLTo,
+			AnchorText:   link.Anchor,
+			DomainRating: link.DomainRatingSource,
+		}
+		if firstSeen, err := time.Parse(time.RFC3339, link.FirstSeen); err == nil {
+			backlink.FirstSeen = &firstSeen
+		}
+		profile.TopBacklinks = append(profile.TopBacklinks, backlink)
+	}
+	return profile, nil
+}
+
+// get calls an API endpoint and decodes its JSON response
+func (a *ahrefs) get(ctx context.Context, path string, params url.Values, dest interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", ProviderAhrefs, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", ProviderAhrefs, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s error: %s", ProviderAhrefs, apiErr.Error)
+		}
+		return fmt.Errorf("%s error: status %d", ProviderAhrefs, resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, dest); err != nil {
+		return fmt.Errorf("invalid %s response: %w", ProviderAhrefs, err)
+	}
+	return nil
+}
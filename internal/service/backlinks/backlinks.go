@@ -0,0 +1,137 @@
+// Package backlinks fetches the backlink profile of a domain from a backlink
+// data provider and detects sudden losses between two profiles.
+package backlinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of a profile fetch
+const (
+	DefaultTimeout  = 30 * time.Second
+	DefaultTopLimit = 20
+)
+
+// ErrUnknownProvider is returned for a provider name that is not registered
+var ErrUnknownProvider = errors.New("unknown backlink provider")
+
+// Backlink is a link from another site to the domain
+type Backlink struct {
+	SourceURL    string     `json:"source_url"`
+	TargetURL    string     `json:"target_url"`
+	AnchorText   string     `json:"anchor_text,omitempty"`
+	DomainRating float64    `json:"domain_rating,omitempty"` // authority of the linking domain, 0-100
+	FirstSeen    *time.Time `json:"first_seen,omitempty"`
+}
+
+// Profile is the backlink profile of a domain at one point in time
+type Profile struct {
+	Domain           string     `json:"domain"`
+	ReferringDomains int64      `json:"referring_domains"`
+	Backlinks        int64      `json:"backlinks"`
+	TopBacklinks     []Backlink `json:"top_backlinks"`
+}
+
+// Provider fetches backlink profiles from a backlink data service
+type Provider interface {
+	Name() string
+	Profile(ctx context.Context, domain string, topLimit int) (*Profile, error)
+}
+
+// Config configures a provider adapter
+type Config struct {
+	BaseURL string
+	APIKey  string
+	Timeout time.Duration
+}
+
+// Factory creates a provider adapter from its configuration
+type Factory func(cfg Config) (Provider, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{}
+)
+
+// Register makes a provider adapter available under a name
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories[name] = factory
+}
+
+// NewProvider creates the named provider adapter
+func NewProvider(name string, cfg Config) (Provider, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	return factory(cfg)
+}
+
+// Domain returns the domain whose backlinks are fetched for a host: the host
+// without a leading "www."
+func Domain(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
+
+// Loss describes backlinks that disappeared between two profiles
+type Loss struct {
+	ReferringDomainsBefore int64   `json:"referring_domains_before"`
+	ReferringDomainsAfter  int64   `json:"referring_domains_after"`
+	ReferringDomainsLost   int64   `json:"referring_domains_lost"`
+	BacklinksBefore        int64   `json:"backlinks_before"`
+	BacklinksAfter         int64   `json:"backlinks_after"`
+	LostRatio              float64 `json:"lost_ratio"` // share of referring domains lost
+	// LostTopBacklinks were among the top backlinks before and no longer are
+	LostTopBacklinks []Backlink `json:"lost_top_backlinks,omitempty"`
+}
+
+// DetectLoss compares a profile with the previous one and returns the loss
+// when the domain lost at least the threshold share of its referring
+// domains. It returns nil otherwise.
+func DetectLoss(previous, current *Profile, threshold float64) *Loss {
+	if previous == nil || current == nil {
+		return nil
+	}
+
+	loss := &Loss{
+		ReferringDomainsBefore: previous.ReferringDomains,
+		ReferringDomainsAfter:  current.ReferringDomains,
+		BacklinksBefore:        previous.Backlinks,
+		BacklinksAfter:         current.Backlinks,
+	}
+	if previous.ReferringDomains <= current.ReferringDomains {
+		return nil
+	}
+	loss.ReferringDomainsLost = previous.ReferringDomains - current.ReferringDomains
+	loss.LostRatio = float64(loss.ReferringDomainsLost) / float64(previous.ReferringDomains)
+	if loss.LostRatio < threshold {
+		return nil
+	}
+
+	kept := make(map[string]bool, len(current.TopBacklinks))
+	for _, link := range current.TopBacklinks {
+		kept[link.SourceURL] = true
+	}
+	for _, link := range previous.TopBacklinks {
+		if !kept[link.SourceURL] {
+			loss.LostTopBacklinks = append(loss.LostTopBacklinks, link)
+		}
+	}
+	sort.SliceStable(loss.LostTopBacklinks, func(i, j int) bool {
+		return loss.LostTopBacklinks[i].DomainRating > loss.LostTopBacklinks[j].DomainRating
+	})
+	return loss
+}
@@ -0,0 +1,85 @@
+package backlinks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ProviderHTTP is a custom backlink service answering GET requests with a
+// Profile encoded as JSON. It lets self-hosted or proxied data sources be
+// plugged in without a dedicated adapter.
+const ProviderHTTP = "http"
+
+func init() {
+	Register(ProviderHTTP, newHTTPProvider)
+}
+
+// httpProvider fetches profiles from a custom JSON endpoint
+type httpProvider struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func newHTTPProvider(cfg Config) (Provider, error) {
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%s provider requires an absolute http(s) URL", ProviderHTTP)
+	}
+	return &httpProvider{
+		baseURL:    cfg.BaseURL,
+		apiKey:     cfg.APIKey,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// Name returns the provider name
+func (p *httpProvider) Name() string {
+	return ProviderHTTP
+}
+
+// Profile requests <base URL>?domain=<domain>&limit=<top limit>, sending the
+// API key as a bearer token when one is configured
+func (p *httpProvider) Profile(ctx context.Context, domain string, topLimit int) (*Profile, error) {
+	if topLimit <= 0 {
+		topLimit = DefaultTopLimit
+	}
+	u, _ := url.Parse(p.baseURL)
+	params := u.Query()
+	params.Set("domain", domain)
+	params.Set("limit", strconv.Itoa(topLimit))
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("backlink request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("backlink service returned status %d", resp.StatusCode)
+	}
+
+	var profile Profile
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 10<<20)).Decode(&profile); err != nil {
+		return nil, fmt.Errorf("invalid backlink service response: %w", err)
+	}
+	profile.Domain = domain
+	if len(profile.TopBacklinks) > topLimit {
+		profile.TopBacklinks = profile.TopBacklinks[:topLimit]
+	}
+	return &profile, nil
+}
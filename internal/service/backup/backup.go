@@ -0,0 +1,254 @@
+// Package backup exports the data of selected organizations or date ranges
+// to an archive and restores archives into another instance.
+//
+// An archive is a gzip-compressed tar holding manifest.json, one JSON Lines
+// file of rows per table under tables/ and the stored snapshots of analyses
+// under artifacts/. Tables are written in foreign key order, so a restore
+// can insert them as they are read.
+package backup
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+)
+
+// Version is the format version of the archives written by Export
+const Version = 1
+
+const (
+	manifestPath  = "manifest.json"
+	tablesDir     = "tables/"
+	artifactsDir  = "artifacts/"
+	tableFileExt  = ".jsonl"
+	batchSize     = 500
+	contentColumn = "content" // column of analysis_snapshots stored as artifacts
+)
+
+// Filter selects the data of a backup. Empty fields select everything.
+type Filter struct {
+	// UserIDs are the organizations to export
+	UserIDs []uuid.UUID `json:"user_ids,omitempty"`
+	// From and To bound the creation time of the exported analyses, rank
+	// checks and activities. To is exclusive.
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+}
+
+// Manifest describes the content of an archive
+type Manifest struct {
+	Version   int          `json:"version"`
+	CreatedAt time.Time    `json:"created_at"`
+	Dialect   string       `json:"dialect"` // database the archive was exported from
+	Filter    Filter       `json:"filter"`
+	Tables    []TableCount `json:"tables"`
+	Artifacts []Artifact   `json:"artifacts"`
+}
+
+// TableCount is the number of rows exported from a table
+type TableCount struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+}
+
+// Artifact is the stored gzip-compressed content of an analysis snapshot.
+// SHA256 is the checksum of the uncompressed content.
+type Artifact struct {
+	Path       string    `json:"path"`
+	AnalysisID uuid.UUID `json:"analysis_id"`
+	Kind       string    `json:"kind"`
+	Size       int64     `json:"size"`
+	SHA256     string    `json:"sha256"`
+}
+
+func artifactPath(analysisID uuid.UUID, kind string) string {
+	return fmt.Sprintf("%s%s/%s", artifactsDir, analysisID, kind)
+}
+
+// table is a table of the backup
+type table struct {
+	model interface{}
+	// scope restricts the rows of the table to the filter; nil exports the
+	// whole table
+	scope func(s *scope, q *gorm.DB) *gorm.DB
+	// refs are the columns referencing rows that are remapped on restore or
+	// may be missing from a filtered export, by referenced table. user_id
+	// columns are added automatically.
+	refs map[string]string
+
+	name   string
+	schema *schema.Schema
+}
+
+// tables lists every table in foreign key order
+var tables = []*table{
+	{model: &models.Role{}},
+	{model: &models.User{}, scope: byUser("id"), refs: map[string]string{"role_id": "roles"}},
+	{model: &models.Domain{}, scope: domainScope, refs: map[string]string{"created_by": "users"}},
+	{model: &models.Website{}, scope: websiteScope, refs: map[string]string{"domain_id": "domains"}},
+	{model: &models.Analysis{}, scope: analysisScope},
+	{model: &models.AnalysisMetric{}, scope: byAnalysis},
+	{model: &models.Issue{}, scope: byAnalysis},
+	{model: &models.IssueFeedback{}, scope: func(s *scope, q *gorm.DB) *gorm.DB {
+		return byUser("user_id")(s, byAnalysis(s, q))
+	}},
+	{model: &models.IssueSeverityOverride{}, scope: overrideScope, refs: map[string]string{"updated_by": "users"}},
+	{model: &models.IssueDocLink{}, scope: overrideScope, refs: map[string]string{"updated_by": "users"}},
+	{model: &models.Recommendation{}, scope: byAnalysis},
+	{model: &models.ContentImprovement{}, scope: byAnalysis},
+	{model: &models.ContentImprovementVersion{}, scope: func(s *scope, q *gorm.DB) *gorm.DB {
+		if !s.byUsers() && s.filter.From == nil && s.filter.To == nil {
+			return q
+		}
+		return q.Where("improvement_id IN (?)", byAnalysis(s, s.query(&models.ContentImprovement{})).Select("id"))
+	}},
+	{model: &models.AnalysisResultVersion{}, scope: byAnalysis},
+	{model: &models.AnalysisEvent{}, scope: byAnalysis},
+	{model: &models.AnalysisUsage{}, scope: byAnalysis},
+	{model: &models.Subscription{}, scope: byUser("user_id")},
+	{model: &models.AnalysisSnapshot{}, scope: byAnalysis},
+	{model: &models.AnalysisPreset{}, scope: byUser("user_id")},
+	{model: &models.EventStream{}, scope: byUser("user_id")},
+	{model: &models.MonitoredSite{}, scope: byUser("user_id"), refs: map[string]string{"last_analysis_id": "analyses"}},
+	{model: &models.BacklinkSnapshot{}, scope: byAnalysis},
+	{model: &models.WidgetOrigin{}, scope: byUser("user_id")},
+	{model: &models.PageLink{}, scope: byAnalysis},
+	{model: &models.PageImage{}, scope: byAnalysis},
+	{model: &models.PageTechnology{}, scope: byAnalysis},
+	{model: &models.TrackedKeyword{}, scope: byUser("user_id")},
+	{model: &models.KeywordRanking{}, scope: rankingScope},
+	{model: &models.ReportSchedule{}, scope: byUser("user_id")},
+	{model: &models.CustomRule{}, scope: byUser("user_id")},
+	{model: &models.Deployment{}, scope: byUser("user_id"), refs: map[string]string{
+		"baseline_analysis_id": "analyses",
+		"analysis_id":          "analyses",
+	}},
+	{model: &models.DeploymentHook{}, scope: byUser("user_id")},
+	{model: &models.ContentBatch{}, scope: byUser("user_id"), refs: map[string]string{"domain_id": "domains"}},
+	{model: &models.GlossaryTerm{}, scope: byUser("user_id")},
+	{model: &models.UserActivity{}, scope: func(s *scope, q *gorm.DB) *gorm.DB {
+		return s.inRange(byUser("user_id")(s, q), "created_at")
+	}},
+}
+
+var parseTables sync.Once
+
+// tableList parses the schemas of the tables once
+func tableList() ([]*table, error) {
+	var err error
+	parseTables.Do(func() {
+		cache := &sync.Map{}
+		for _, t := range tables {
+			if t.schema, err = schema.Parse(t.model, cache, schema.NamingStrategy{}); err != nil {
+				err = fmt.Errorf("failed to parse %T: %w", t.model, err)
+				return
+			}
+			t.name = t.schema.Table
+			if t.schema.LookUpField("user_id") != nil && t.name != "users" {
+				if t.refs == nil {
+					t.refs = map[string]string{}
+				}
+				t.refs["user_id"] = "users"
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// scope builds the queries selecting the rows of a filter
+type scope struct {
+	tx     *gorm.DB
+	filter Filter
+}
+
+// query starts a query of a model including soft-deleted rows
+func (s *scope) query(model interface{}) *gorm.DB {
+	return s.tx.Session(&gorm.Session{NewDB: true}).Model(model).Unscoped()
+}
+
+func (s *scope) byUsers() bool {
+	return len(s.filter.UserIDs) > 0
+}
+
+func (s *scope) inRange(q *gorm.DB, column string) *gorm.DB {
+	if s.filter.From != nil {
+		q = q.Where(column+" >= ?", *s.filter.From)
+	}
+	if s.filter.To != nil {
+		q = q.Where(column+" < ?", *s.filter.To)
+	}
+	return q
+}
+
+// analysisIDs selects the IDs of the exported analyses
+func (s *scope) analysisIDs() *gorm.DB {
+	return analysisScope(s, s.query(&models.Analysis{})).Select("id")
+}
+
+func byUser(column string) func(s *scope, q *gorm.DB) *gorm.DB {
+	return func(s *scope, q *gorm.DB) *gorm.DB {
+		if !s.byUsers() {
+			return q
+		}
+		return q.Where(column+" IN ?", s.filter.UserIDs)
+	}
+}
+
+func byAnalysis(s *scope, q *gorm.DB) *gorm.DB {
+	if !s.byUsers() && s.filter.From == nil && s.filter.To == nil {
+		return q
+	}
+	return q.Where("analysis_id IN (?)", s.analysisIDs())
+}
+
+func analysisScope(s *scope, q *gorm.DB) *gorm.DB {
+	return s.inRange(byUser("user_id")(s, q), "created_at")
+}
+
+// websiteScope exports the websites of the exported analyses and deployments
+func websiteScope(s *scope, q *gorm.DB) *gorm.DB {
+	if !s.byUsers() {
+		return q
+	}
+	return q.Where("id IN (?) OR id IN (?) OR id IN (?)",
+		analysisScope(s, s.query(&models.Analysis{})).Select("website_id"),
+		byUser("user_id")(s, s.query(&models.Deployment{})).Select("website_id"),
+		byUser("user_id")(s, s.query(&models.DeploymentHook{})).Select("website_id"))
+}
+
+// domainScope exports the domains created by the organizations and those
+// their websites and content batches belong to
+func domainScope(s *scope, q *gorm.DB) *gorm.DB {
+	if !s.byUsers() {
+		return q
+	}
+	return q.Where("created_by IN ? OR id IN (?) OR id IN (?)",
+		s.filter.UserIDs,
+		websiteScope(s, s.query(&models.Website{})).Select("domain_id"),
+		byUser("user_id")(s, s.query(&models.ContentBatch{})).Select("domain_id"))
+}
+
+// overrideScope exports the deployment-wide severity overrides and issue doc
+// links only with the whole deployment
+func overrideScope(s *scope, q *gorm.DB) *gorm.DB {
+	if s.byUsers() {
+		return q.Where("1 = 0")
+	}
+	return q
+}
+
+func rankingScope(s *scope, q *gorm.DB) *gorm.DB {
+	if s.byUsers() {
+		q = q.Where("keyword_id IN (?)", byUser("user_id")(s, s.query(&models.TrackedKeyword{})).Select("id"))
+	}
+	return s.inRange(q, "checked_at")
+}
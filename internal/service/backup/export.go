@@ -0,0 +1,260 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+)
+
+// exporter holds the state of one export
+type exporter struct {
+	ctx      context.Context
+	scope    *scope
+	dir      string
+	manifest *Manifest
+	// exported are the IDs of the exported users and analyses, to drop
+	// references to rows outside the filter
+	exported  map[string]map[string]bool
+	firstUser json.RawMessage
+}
+
+// Export writes an archive of the data selected by the filter to w. The
+// rows are read in a single read-only transaction, so the archive is
+// consistent even while the instance keeps running.
+func Export(ctx context.Context, db *gorm.DB, filter Filter, w io.Writer) (*Manifest, error) {
+	list, err := tableList()
+	if err != nil {
+		return nil, err
+	}
+
+	var tx *gorm.DB
+	if db.Dialector.Name() == "postgres" {
+		tx = db.WithContext(ctx).Begin(&sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	} else {
+		tx = db.WithContext(ctx).Begin()
+	}
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", tx.Error)
+	}
+	defer tx.Rollback()
+
+	// Rows are spooled to files because tar needs the size of an entry
+	// before its content
+	dir, err := os.MkdirTemp("", "backup-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	e := &exporter{
+		ctx:   ctx,
+		scope: &scope{tx: tx, filter: filter},
+		dir:   dir,
+		manifest: &Manifest{
+			Version:   Version,
+			CreatedAt: time.Now().UTC(),
+			Dialect:   db.Dialector.Name(),
+			Filter:    filter,
+			Artifacts: []Artifact{},
+		},
+		exported: map[string]map[string]bool{"users": {}, "analyses": {}},
+	}
+
+	for _, t := range list {
+		rows, err := e.exportTable(t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", t.name, err)
+		}
+		e.manifest.Tables = append(e.manifest.Tables, TableCount{Name: t.name, Rows: rows})
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest, err := json.MarshalIndent(e.manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeEntry(tw, manifestPath, int64(len(manifest)), bytes.NewReader(manifest)); err != nil {
+		return nil, err
+	}
+
+	for _, t := range list {
+		if err := e.writeTable(tw, t); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, artifact := range e.manifest.Artifacts {
+		var snapshot models.AnalysisSnapshot
+		err := tx.Select(contentColumn).
+			Where("analysis_id = ? AND kind = ?", artifact.AnalysisID, artifact.Kind).
+			Take(&snapshot).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot %s: %w", artifact.Path, err)
+		}
+		if err := writeEntry(tw, artifact.Path, int64(len(snapshot.Content)), bytes.NewReader(snapshot.Content)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return e.manifest, nil
+}
+
+// exportTable spools the rows of a table in the scope to a file
+func (e *exporter) exportTable(t *table) (int64, error) {
+	file, err := os.Create(filepath.Join(e.dir, t.name+tableFileExt))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	out := bufio.NewWriter(file)
+
+	q := e.scope.query(t.model)
+	if t.scope != nil {
+		q = t.scope(e.scope, q)
+	}
+	if t.name == "analysis_snapshots" {
+		q = q.Omit(contentColumn)
+	}
+
+	var count int64
+	rows := reflect.New(reflect.SliceOf(reflect.PointerTo(t.schema.ModelType)))
+	result := q.FindInBatches(rows.Interface(), batchSize, func(batch *gorm.DB, _ int) error {
+		items := rows.Elem()
+		for i := 0; i < items.Len(); i++ {
+			item := items.Index(i)
+			row, err := encodeRow(e.ctx, t, item.Elem())
+			if err != nil {
+				return err
+			}
+			e.dropMissingRefs(t, row)
+			e.track(t, row)
+
+			if snapshot, ok := item.Interface().(*models.AnalysisSnapshot); ok {
+				e.manifest.Artifacts = append(e.manifest.Artifacts, Artifact{
+					Path:       artifactPath(snapshot.AnalysisID, snapshot.Kind),
+					AnalysisID: snapshot.AnalysisID,
+					Kind:       snapshot.Kind,
+					Size:       snapshot.CompressedSize,
+					SHA256:     snapshot.SHA256,
+				})
+			}
+
+			line, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			out.Write(line)
+			if err := out.WriteByte('\n'); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return count, out.Flush()
+}
+
+// track records the IDs of exported users and analyses
+func (e *exporter) track(t *table, row map[string]json.RawMessage) {
+	ids, ok := e.exported[t.name]
+	if !ok {
+		return
+	}
+	ids[string(row["id"])] = true
+	if t.name == "users" && e.firstUser == nil {
+		e.firstUser = row["id"]
+	}
+}
+
+// dropMissingRefs clears optional references to users and analyses outside
+// the filter. Domains shared with other organizations are attributed to the
+// first exported user.
+func (e *exporter) dropMissingRefs(t *table, row map[string]json.RawMessage) {
+	for column, ref := range t.refs {
+		ids, ok := e.exported[ref]
+		value := row[column]
+		if !ok || value == nil || string(value) == "null" || ids[string(value)] {
+			continue
+		}
+		switch {
+		case t.schema.LookUpField(column).FieldType.Kind() == reflect.Ptr:
+			row[column] = json.RawMessage("null")
+		case ref == "users" && e.firstUser != nil:
+			row[column] = e.firstUser
+		}
+	}
+}
+
+// writeTable copies the spooled rows of a table to the archive
+func (e *exporter) writeTable(tw *tar.Writer, t *table) error {
+	file, err := os.Open(filepath.Join(e.dir, t.name+tableFileExt))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	return writeEntry(tw, tablesDir+t.name+tableFileExt, info.Size(), file)
+}
+
+func writeEntry(tw *tar.Writer, name string, size int64, content io.Reader) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    size,
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := io.Copy(tw, content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// encodeRow encodes the columns of a row, including those hidden from the
+// API such as secrets, by column name
+func encodeRow(ctx context.Context, t *table, value reflect.Value) (map[string]json.RawMessage, error) {
+	row := make(map[string]json.RawMessage, len(t.schema.Fields))
+	for _, field := range t.schema.Fields {
+		if field.DBName == "" || (t.name == "analysis_snapshots" && field.DBName == contentColumn) {
+			continue
+		}
+		fieldValue, _ := field.ValueOf(ctx, value)
+		raw, err := json.Marshal(fieldValue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s.%s: %w", t.name, field.DBName, err)
+		}
+		row[field.DBName] = raw
+	}
+	return row, nil
+}
@@ -0,0 +1,327 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+)
+
+// restorer holds the state of one restore
+type restorer struct {
+	ctx    context.Context
+	tx     *gorm.DB
+	tables map[string]*table
+	// remap maps the IDs of the archive to those of existing rows, by table
+	remap map[string]map[string]json.RawMessage
+	// snapshots wait for their content, by artifact path
+	snapshots map[string]*models.AnalysisSnapshot
+	rows      map[string]int64
+}
+
+// Restore imports an archive written by Export into db, whose schema must
+// be up to date. It runs in a single transaction and skips rows that
+// already exist, so an interrupted restore can be repeated.
+//
+// Roles, users and domains are matched to existing ones by name, email and
+// name, so an archive can be restored into an instance that has already
+// seeded its default accounts.
+func Restore(ctx context.Context, db *gorm.DB, r io.Reader) (*Manifest, error) {
+	list, err := tableList()
+	if err != nil {
+		return nil, err
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	header, err := tr.Next()
+	if err != nil || header.Name != manifestPath {
+		return nil, fmt.Errorf("invalid archive: %s must be its first entry", manifestPath)
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.Version != Version {
+		return nil, fmt.Errorf("unsupported archive version %d", manifest.Version)
+	}
+
+	rs := &restorer{
+		ctx:       ctx,
+		tables:    make(map[string]*table, len(list)),
+		remap:     map[string]map[string]json.RawMessage{"roles": {}, "users": {}, "domains": {}},
+		snapshots: make(map[string]*models.AnalysisSnapshot),
+		rows:      make(map[string]int64),
+	}
+	for _, t := range list {
+		rs.tables[t.name] = t
+	}
+
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		rs.tx = tx
+		for {
+			header, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("invalid archive: %w", err)
+			}
+
+			switch {
+			case strings.HasPrefix(header.Name, tablesDir):
+				name := strings.TrimSuffix(strings.TrimPrefix(header.Name, tablesDir), tableFileExt)
+				if err := rs.restoreTable(name, tr); err != nil {
+					return fmt.Errorf("failed to restore %s: %w", name, err)
+				}
+			case strings.HasPrefix(header.Name, artifactsDir):
+				if err := rs.restoreArtifact(header.Name, tr); err != nil {
+					return err
+				}
+			}
+		}
+
+		for path := range rs.snapshots {
+			return fmt.Errorf("archive is missing %s", path)
+		}
+		for _, count := range manifest.Tables {
+			if rs.rows[count.Name] != count.Rows {
+				return fmt.Errorf("archive holds %d rows of %s, the manifest lists %d", rs.rows[count.Name], count.Name, count.Rows)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// restoreTable inserts the rows of a table in batches
+func (rs *restorer) restoreTable(name string, r io.Reader) error {
+	t, ok := rs.tables[name]
+	if !ok {
+		return fmt.Errorf("unknown table")
+	}
+
+	batch := reflect.MakeSlice(reflect.SliceOf(reflect.PointerTo(t.schema.ModelType)), 0, batchSize)
+	flush := func() error {
+		if batch.Len() == 0 {
+			return nil
+		}
+		if err := rs.insert(batch.Interface()); err != nil {
+			return err
+		}
+		batch = batch.Slice(0, 0)
+		return nil
+	}
+
+	lines := bufio.NewReader(r)
+	for {
+		line, err := lines.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var row map[string]json.RawMessage
+			if err := json.Unmarshal(line, &row); err != nil {
+				return fmt.Errorf("invalid row: %w", err)
+			}
+			rs.rows[name]++
+
+			keep, err := rs.prepare(t, row)
+			if err != nil {
+				return err
+			}
+			if !keep {
+				continue
+			}
+
+			value, err := decodeRow(rs.ctx, t, row)
+			if err != nil {
+				return err
+			}
+			if snapshot, ok := value.Interface().(*models.AnalysisSnapshot); ok {
+				rs.snapshots[artifactPath(snapshot.AnalysisID, snapshot.Kind)] = snapshot
+				continue
+			}
+			if t.name == "roles" {
+				// Role IDs are generated, so new roles are inserted one by
+				// one to learn theirs
+				if err := rs.insertRole(row, value.Interface().(*models.Role)); err != nil {
+					return err
+				}
+				continue
+			}
+
+			batch = reflect.Append(batch, value)
+			if batch.Len() >= batchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return flush()
+}
+
+// prepare remaps the references of a row and matches it to an existing
+// role, user or domain. It returns false when the existing one is kept.
+func (rs *restorer) prepare(t *table, row map[string]json.RawMessage) (bool, error) {
+	for column, ref := range t.refs {
+		if mapped, ok := rs.remap[ref][string(row[column])]; ok {
+			row[column] = mapped
+		}
+	}
+
+	var match *gorm.DB
+	switch t.name {
+	case "roles":
+		match = rs.tx.Model(&models.Role{}).Where("name = ?", jsonString(row["name"]))
+	case "users":
+		match = rs.tx.Model(&models.User{}).Unscoped().Where("email = ?", jsonString(row["email"]))
+	case "domains":
+		match = rs.tx.Model(&models.Domain{}).Where("name = ?", jsonString(row["name"]))
+	default:
+		return true, nil
+	}
+
+	var found []map[string]interface{}
+	if err := match.Select("id").Limit(1).Find(&found).Error; err != nil {
+		return false, err
+	}
+	if len(found) == 0 {
+		if t.name == "users" {
+			return true, rs.checkUsername(row)
+		}
+		return true, nil
+	}
+
+	id, err := json.Marshal(normalizeID(found[0]["id"]))
+	if err != nil {
+		return false, err
+	}
+	rs.remap[t.name][string(row["id"])] = id
+	return false, nil
+}
+
+// checkUsername rejects users whose username belongs to another account
+func (rs *restorer) checkUsername(row map[string]json.RawMessage) error {
+	var count int64
+	err := rs.tx.Model(&models.User{}).Unscoped().
+		Where("username = ?", jsonString(row["username"])).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("username %q is taken by another account", jsonString(row["username"]))
+	}
+	return nil
+}
+
+func (rs *restorer) insertRole(row map[string]json.RawMessage, role *models.Role) error {
+	oldID := string(row["id"])
+	role.ID = 0
+	if err := rs.tx.Session(&gorm.Session{SkipHooks: true}).Omit(clause.Associations).Create(role).Error; err != nil {
+		return err
+	}
+	id, _ := json.Marshal(role.ID)
+	rs.remap["roles"][oldID] = id
+	return nil
+}
+
+// restoreArtifact inserts a snapshot with its content once the content
+// matches the checksum of the snapshot
+func (rs *restorer) restoreArtifact(path string, r io.Reader) error {
+	snapshot, ok := rs.snapshots[path]
+	if !ok {
+		return fmt.Errorf("archive holds %s without its snapshot", path)
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", path, err)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, gz); err != nil {
+		return fmt.Errorf("invalid %s: %w", path, err)
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != snapshot.SHA256 {
+		return fmt.Errorf("checksum mismatch of %s", path)
+	}
+
+	snapshot.Content = content
+	if err := rs.insert(snapshot); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", path, err)
+	}
+	delete(rs.snapshots, path)
+	return nil
+}
+
+// insert creates rows, keeping those that already exist
+func (rs *restorer) insert(value interface{}) error {
+	return rs.tx.Session(&gorm.Session{SkipHooks: true}).
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(value).Error
+}
+
+// decodeRow decodes the columns of a row into a new model
+func decodeRow(ctx context.Context, t *table, row map[string]json.RawMessage) (reflect.Value, error) {
+	value := reflect.New(t.schema.ModelType)
+	for _, field := range t.schema.Fields {
+		raw, ok := row[field.DBName]
+		if field.DBName == "" || !ok || string(raw) == "null" {
+			continue
+		}
+		fieldValue := reflect.New(field.FieldType)
+		if err := json.Unmarshal(raw, fieldValue.Interface()); err != nil {
+			return value, fmt.Errorf("invalid %s.%s: %w", t.name, field.DBName, err)
+		}
+		if err := field.Set(ctx, value.Elem(), fieldValue.Elem().Interface()); err != nil {
+			return value, fmt.Errorf("invalid %s.%s: %w", t.name, field.DBName, err)
+		}
+	}
+	return value, nil
+}
+
+func jsonString(raw json.RawMessage) string {
+	var s string
+	json.Unmarshal(raw, &s)
+	return s
+}
+
+// normalizeID converts an ID scanned without a model to its JSON form
+func normalizeID(id interface{}) interface{} {
+	if b, ok := id.([]byte); ok {
+		return string(b)
+	}
+	return id
+}
@@ -0,0 +1,86 @@
+package billing
+
+import "fmt"
+
+// Plan names
+const (
+	PlanFree   = "free"
+	PlanPro    = "pro"
+	PlanAgency = "agency"
+)
+
+// Unlimited marks a plan limit without an upper bound
+const Unlimited = -1
+
+// Plan describes the monthly limits of a subscription plan
+type Plan struct {
+	Name                   string `json:"name"`
+	AnalysesPerMonth       int64  `json:"analyses_per_month"`
+	Monitors               int64  `json:"monitors"`
+	LLMGenerationsPerMonth int64  `json:"llm_generations_per_month"`
+	MaxPriority            string `json:"max_priority"` // highest analysis priority users of the plan may request
+	StripePriceID          string `json:"-"`
+}
+
+// Plans holds the available plans and the Stripe prices backing them
+type Plans struct {
+	plans map[string]Plan
+}
+
+// NewPlans creates the plan catalogue. The free plan has no Stripe price.
+func NewPlans(proPriceID, agencyPriceID string) *Plans {
+	return &Plans{
+		plans: map[string]Plan{
+			PlanFree: {
+				Name:                   PlanFree,
+				AnalysesPerMonth:       20,
+				Monitors:               1,
+				LLMGenerationsPerMonth: 5,
+				MaxPriority:            "normal",
+			},
+			PlanPro: {
+				Name:                   PlanPro,
+				AnalysesPerMonth:       500,
+				Monitors:               20,
+				LLMGenerationsPerMonth: 200,
+				MaxPriority:            "high",
+				StripePriceID:          proPriceID,
+			},
+			PlanAgency: {
+				Name:                   PlanAgency,
+				AnalysesPerMonth:       Unlimited,
+				Monitors:               200,
+				LLMGenerationsPerMonth: 2000,
+				MaxPriority:            "high",
+				StripePriceID:          agencyPriceID,
+			},
+		},
+	}
+}
+
+// Get returns a plan by name
+func (p *Plans) Get(name string) (Plan, error) {
+	plan, ok := p.plans[name]
+	if !ok {
+		return Plan{}, fmt.Errorf("unknown plan: %s", name)
+	}
+	return plan, nil
+}
+
+// ByPriceID returns the plan billed with the given Stripe price
+func (p *Plans) ByPriceID(priceID string) (Plan, bool) {
+	if priceID == "" {
+		return Plan{}, false
+	}
+	for _, plan := range p.plans {
+		if plan.StripePriceID == priceID {
+			return plan, true
+		}
+	}
+	return Plan{}, false
+}
+
+// List returns all plans from the smallest to the largest
+func (p *Plans) List() []Plan {
+	return []Plan{p.plans[PlanFree], p.plans[PlanPro], p.plans[PlanAgency]}
+}
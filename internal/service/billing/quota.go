@@ -0,0 +1,196 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// Resource is a quota-limited resource
+type Resource string
+
+const (
+	ResourceAnalyses       Resource = "analyses"
+	ResourceMonitors       Resource = "monitors"
+	ResourceLLMGenerations Resource = "llm_generations"
+)
+
+// Resources lists every quota-limited resource
+var Resources = []Resource{ResourceAnalyses, ResourceMonitors, ResourceLLMGenerations}
+
+// ErrQuotaExceeded is returned when a plan limit has been reached
+var ErrQuotaExceeded = errors.New("plan quota exceeded")
+
+// Counter returns how much of a resource a user has used since the given time
+type Counter func(ctx context.Context, userID uuid.UUID, since time.Time) (int64, error)
+
+// PlanResolver returns the name of the plan a user is currently entitled to
+type PlanResolver func(userID uuid.UUID) string
+
+// QuotaStatus describes the usage of one resource against a plan limit
+type QuotaStatus struct {
+	Plan     string    `json:"plan"`
+	Resource Resource  `json:"resource"`
+	Limit    int64     `json:"limit"`
+	Used     int64     `json:"used"`
+	ResetsAt time.Time `json:"resets_at,omitempty"`
+}
+
+// Quota enforces the limits of the user's plan
+type Quota struct {
+	plans       *Plans
+	planOf      PlanResolver
+	redisClient *redis.Client
+	counters    map[Resource]Counter
+	monthly     map[Resource]bool
+	mu          sync.RWMutex
+}
+
+// NewQuota creates a quota enforcer
+func NewQuota(plans *Plans, planOf PlanResolver, redisClient *redis.Client) *Quota {
+	return &Quota{
+		plans:       plans,
+		planOf:      planOf,
+		redisClient: redisClient,
+		counters:    make(map[Resource]Counter),
+		monthly:     make(map[Resource]bool),
+	}
+}
+
+// RegisterCounter sets how usage of a resource is counted. Monthly resources
+// are counted from the start of the current calendar month.
+func (q *Quota) RegisterCounter(resource Resource, monthly bool, counter Counter) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.counters[resource] = counter
+	q.monthly[resource] = monthly
+}
+
+// TrackMonthly counts a resource with a per-user monthly Redis counter that is
+// incremented through Record
+func (q *Quota) TrackMonthly(resource Resource) {
+	q.RegisterCounter(resource, true, func(ctx context.Context, userID uuid.UUID, since time.Time) (int64, error) {
+		if q.redisClient == nil {
+			return 0, nil // nothing is recorded without Redis
+		}
+		count, err := q.redisClient.Get(ctx, q.counterKey(resource, userID, since)).Int64()
+		if err == redis.Nil {
+			return 0, nil
+		}
+		return count, err
+	})
+}
+
+// Record increments the monthly counter of a tracked resource
+func (q *Quota) Record(ctx context.Context, userID uuid.UUID, resource Resource) error {
+	if q.redisClient == nil {
+		return nil
+	}
+
+	key := q.counterKey(resource, userID, monthStart(time.Now()))
+	pipe := q.redisClient.TxPipeline()
+	pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, 32*24*time.Hour)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Plan returns the plan a user is currently entitled to. Users of an unknown
+// plan get the free plan.
+func (q *Quota) Plan(userID uuid.UUID) Plan {
+	plan, err := q.plans.Get(q.planOf(userID))
+	if err != nil {
+		plan, _ = q.plans.Get(PlanFree)
+	}
+	return plan
+}
+
+// Status returns the usage of a resource against the user's plan limit
+func (q *Quota) Status(ctx context.Context, userID uuid.UUID, resource Resource) (*QuotaStatus, error) {
+	plan := q.Plan(userID)
+
+	status := &QuotaStatus{
+		Plan:     plan.Name,
+		Resource: resource,
+		Limit:    planLimit(plan, resource),
+	}
+
+	q.mu.RLock()
+	counter, ok := q.counters[resource]
+	monthly := q.monthly[resource]
+	q.mu.RUnlock()
+	if !ok {
+		return status, nil
+	}
+
+	var since time.Time
+	if monthly {
+		since = monthStart(time.Now())
+		status.ResetsAt = since.AddDate(0, 1, 0)
+	}
+
+	used, err := counter(ctx, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count %s usage: %w", resource, err)
+	}
+	status.Used = used
+	return status, nil
+}
+
+// Statuses returns the usage of every resource against the user's plan limits
+func (q *Quota) Statuses(ctx context.Context, userID uuid.UUID) ([]*QuotaStatus, error) {
+	statuses := make([]*QuotaStatus, 0, len(Resources))
+	for _, resource := range Resources {
+		status, err := q.Status(ctx, userID, resource)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Check returns ErrQuotaExceeded when the user cannot consume one more unit
+// of the resource. Resources without a registered counter are not enforced.
+func (q *Quota) Check(ctx context.Context, userID uuid.UUID, resource Resource) (*QuotaStatus, error) {
+	status, err := q.Status(ctx, userID, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	if status.Limit != Unlimited && status.Used >= status.Limit {
+		return status, ErrQuotaExceeded
+	}
+	return status, nil
+}
+
+// counterKey returns the Redis key of a monthly counter
+func (q *Quota) counterKey(resource Resource, userID uuid.UUID, month time.Time) string {
+	return fmt.Sprintf("quota:%s:%s:%s", resource, userID, month.Format("2006-01"))
+}
+
+// planLimit returns the limit of a resource in a plan
+func planLimit(plan Plan, resource Resource) int64 {
+	switch resource {
+	case ResourceAnalyses:
+		return plan.AnalysesPerMonth
+	case ResourceMonitors:
+		return plan.Monitors
+	case ResourceLLMGenerations:
+		return plan.LLMGenerationsPerMonth
+	default:
+		return Unlimited
+	}
+}
+
+// monthStart returns the start of the calendar month in UTC
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
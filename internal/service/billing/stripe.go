@@ -0,0 +1,258 @@
+package billing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	stripeAPIURL = "https://api.stripe.com/v1"
+
+	// webhookTolerance is the maximum age of a signed webhook payload
+	webhookTolerance = 5 * time.Minute
+)
+
+var (
+	// ErrNotConfigured is returned when no Stripe secret key is set
+	ErrNotConfigured = errors.New("stripe billing is not configured")
+	// ErrInvalidSignature is returned for webhooks that fail verification
+	ErrInvalidSignature = errors.New("invalid stripe webhook signature")
+)
+
+// CheckoutSession is the subset of a Stripe checkout session used by billing
+type CheckoutSession struct {
+	ID                string            `json:"id"`
+	URL               string            `json:"url"`
+	Status            string            `json:"status"`
+	PaymentStatus     string            `json:"payment_status"`
+	Customer          string            `json:"customer"`
+	Subscription      string            `json:"subscription"`
+	ClientReferenceID string            `json:"client_reference_id"`
+	Metadata          map[string]string `json:"metadata"`
+}
+
+// Subscription is the subset of a Stripe subscription used by billing
+type Subscription struct {
+	ID               string            `json:"id"`
+	Customer         string            `json:"customer"`
+	Status           string            `json:"status"`
+	CurrentPeriodEnd int64             `json:"current_period_end"`
+	Metadata         map[string]string `json:"metadata"`
+	Items            struct {
+		Data []struct {
+			Price struct {
+				ID string `json:"id"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// PriceID returns the price of the first subscription item
+func (s *Subscription) PriceID() string {
+	if len(s.Items.Data) == 0 {
+		return ""
+	}
+	return s.Items.Data[0].Price.ID
+}
+
+// Invoice is the subset of a Stripe invoice used by billing
+type Invoice struct {
+	ID           string `json:"id"`
+	Customer     string `json:"customer"`
+	Subscription string `json:"subscription"`
+}
+
+// Event is a Stripe webhook event
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// CheckoutParams describes a subscription checkout
+type CheckoutParams struct {
+	PriceID    string
+	CustomerID string
+	Email      string
+	UserID     string
+	Plan       string
+	SuccessURL string
+	CancelURL  string
+}
+
+// StripeClient talks to the Stripe REST API
+type StripeClient struct {
+	secretKey     string
+	webhookSecret string
+	httpClient    *http.Client
+}
+
+// NewStripeClient creates a new Stripe client
+func NewStripeClient(secretKey, webhookSecret string) *StripeClient {
+	return &StripeClient{
+		secretKey:     secretKey,
+		webhookSecret: webhookSecret,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Configured reports whether a secret key is set
+func (c *StripeClient) Configured() bool {
+	return c.secretKey != ""
+}
+
+// CreateCheckoutSession starts a subscription checkout
+func (c *StripeClient) CreateCheckoutSession(ctx context.Context, params CheckoutParams) (*CheckoutSession, error) {
+	form := url.Values{}
+	form.Set("mode", "subscription")
+	form.Set("line_items[0][price]", params.PriceID)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("success_url", params.SuccessURL)
+	form.Set("cancel_url", params.CancelURL)
+	form.Set("client_reference_id", params.UserID)
+	form.Set("metadata[user_id]", params.UserID)
+	form.Set("metadata[plan]", params.Plan)
+	form.Set("subscription_data[metadata][user_id]", params.UserID)
+	form.Set("subscription_data[metadata][plan]", params.Plan)
+	if params.CustomerID != "" {
+		form.Set("customer", params.CustomerID)
+	} else if params.Email != "" {
+		form.Set("customer_email", params.Email)
+	}
+
+	var session CheckoutSession
+	if err := c.do(ctx, http.MethodPost, "/checkout/sessions", form, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetCheckoutSession retrieves a checkout session by ID
+func (c *StripeClient) GetCheckoutSession(ctx context.Context, sessionID string) (*CheckoutSession, error) {
+	var session CheckoutSession
+	if err := c.do(ctx, http.MethodGet, "/checkout/sessions/"+url.PathEscape(sessionID), nil, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetSubscription retrieves a subscription by ID
+func (c *StripeClient) GetSubscription(ctx context.Context, subscriptionID string) (*Subscription, error) {
+	var subscription Subscription
+	if err := c.do(ctx, http.MethodGet, "/subscriptions/"+url.PathEscape(subscriptionID), nil, &subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// do performs an authenticated form-encoded request and decodes the response
+func (c *StripeClient) do(ctx context.Context, method, path string, form url.Values, dest interface{}) error {
+	if !c.Configured() {
+		return ErrNotConfigured
+	}
+
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, stripeAPIURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create stripe request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.secretKey)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("stripe request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read stripe response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			return fmt.Errorf("stripe error (%d): %s", resp.StatusCode, apiErr.Error.Message)
+		}
+		return fmt.Errorf("stripe error (%d)", resp.StatusCode)
+	}
+
+	return json.Unmarshal(data, dest)
+}
+
+// ParseWebhook verifies the Stripe-Signature header of a webhook payload and
+// decodes the event
+func (c *StripeClient) ParseWebhook(payload []byte, signatureHeader string) (*Event, error) {
+	if c.webhookSecret == "" {
+		return nil, ErrNotConfigured
+	}
+
+	var timestamp int64
+	var signatures []string
+	for _, part := range strings.Split(signatureHeader, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp, _ = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	if timestamp == 0 || len(signatures) == 0 {
+		return nil, ErrInvalidSignature
+	}
+	if age := time.Since(time.Unix(timestamp, 0)); age > webhookTolerance || age < -webhookTolerance {
+		return nil, ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(c.webhookSecret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+
+	valid := false
+	for _, signature := range signatures {
+		decoded, err := hex.DecodeString(signature)
+		if err == nil && hmac.Equal(decoded, expected) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode stripe event: %w", err)
+	}
+	return &event, nil
+}
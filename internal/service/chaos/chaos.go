@@ -0,0 +1,228 @@
+// Package chaos injects faults such as latency, errors and timeouts into the
+// analysis pipeline for resilience testing. It is meant for development and
+// staging only: faults are configured through CHAOS_FAULTS or, per request,
+// the X-Chaos-Faults header, and are ignored unless chaos is enabled.
+//
+// A fault specification is a comma-separated list of target=fault entries,
+// each optionally followed by @probability:
+//
+//	lighthouse=timeout, llm=status:500@0.5, redis=error, analyzer.seo=partial, request=latency:300ms
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HeaderFaults is the request header with the faults of a request
+const HeaderFaults = "X-Chaos-Faults"
+
+// Fault targets
+const (
+	TargetRequest    = "request"  // the API request itself
+	TargetAnalyzer   = "analyzer" // every analyzer; "analyzer.<type>" targets one
+	TargetLighthouse = "lighthouse"
+	TargetRedis      = "redis"
+	TargetLLM        = "llm"
+)
+
+// Fault kinds
+const (
+	KindLatency = "latency" // delay, then continue normally
+	KindError   = "error"   // fail with ErrInjected
+	KindTimeout = "timeout" // block until the context is done
+	KindStatus  = "status"  // fail with a StatusError, e.g. an upstream HTTP 500
+	KindPartial = "partial" // succeed with incomplete results (analyzers only)
+)
+
+// ErrInjected is the error of an injected fault
+var ErrInjected = errors.New("chaos: injected fault")
+
+// StatusError is an injected upstream HTTP error
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("chaos: injected HTTP %d", e.Code)
+}
+
+// Unwrap makes StatusError match ErrInjected
+func (e *StatusError) Unwrap() error {
+	return ErrInjected
+}
+
+// Fault is a fault injected into one target
+type Fault struct {
+	Kind        string        `json:"kind"`
+	Delay       time.Duration `json:"delay,omitempty"`
+	Status      int           `json:"status,omitempty"`
+	Probability float64       `json:"probability"` // 0-1
+}
+
+// Faults maps targets to the faults injected into them
+type Faults map[string]Fault
+
+// Parse parses a fault specification
+func Parse(spec string) (Faults, error) {
+	faults := make(Faults)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		target, value, ok := strings.Cut(entry, "=")
+		target = strings.ToLower(strings.TrimSpace(target))
+		if !ok || target == "" {
+			return nil, fmt.Errorf("invalid fault %q: expected target=fault", entry)
+		}
+
+		fault := Fault{Probability: 1}
+		value, probability, ok := strings.Cut(value, "@")
+		if ok {
+			p, err := strconv.ParseFloat(strings.TrimSpace(probability), 64)
+			if err != nil || p < 0 || p > 1 {
+				return nil, fmt.Errorf("invalid probability in fault %q", entry)
+			}
+			fault.Probability = p
+		}
+
+		kind, arg, _ := strings.Cut(strings.TrimSpace(value), ":")
+		fault.Kind = strings.ToLower(kind)
+		switch fault.Kind {
+		case KindLatency:
+			delay, err := time.ParseDuration(arg)
+			if err != nil || delay < 0 {
+				return nil, fmt.Errorf("invalid latency in fault for %s: %q", target, arg)
+			}
+			fault.Delay = delay
+		case KindStatus:
+			code, err := strconv.Atoi(arg)
+			if err != nil || code < 400 || code > 599 {
+				return nil, fmt.Errorf("invalid status in fault for %s: %q", target, arg)
+			}
+			fault.Status = code
+		case KindError, KindTimeout, KindPartial:
+		default:
+			return nil, fmt.Errorf("unknown fault kind %q for %s", kind, target)
+		}
+		faults[target] = fault
+	}
+	return faults, nil
+}
+
+// Merge returns the faults of both sets; faults of override win
+func Merge(base, override Faults) Faults {
+	if len(override) == 0 {
+		return base
+	}
+	merged := make(Faults, len(base)+len(override))
+	for target, fault := range base {
+		merged[target] = fault
+	}
+	for target, fault := range override {
+		merged[target] = fault
+	}
+	return merged
+}
+
+var (
+	globalMu sync.RWMutex
+	global   Faults
+)
+
+// SetGlobal sets the faults injected everywhere, including code paths without
+// a request context such as Redis commands
+func SetGlobal(faults Faults) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	global = faults
+}
+
+type contextKey struct{}
+
+// WithFaults returns a context carrying faults in addition to the global ones
+func WithFaults(ctx context.Context, faults Faults) context.Context {
+	if len(faults) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, faults)
+}
+
+// FromContext returns the faults of a context without the global ones
+func FromContext(ctx context.Context) Faults {
+	if ctx == nil {
+		return nil
+	}
+	faults, _ := ctx.Value(contextKey{}).(Faults)
+	return faults
+}
+
+// Lookup returns the fault for the first of the targets that has one,
+// looking in the context before the global faults. It reports false when
+// none applies, including when the fault's probability roll fails.
+func Lookup(ctx context.Context, targets ...string) (Fault, bool) {
+	scoped := FromContext(ctx)
+	globalMu.RLock()
+	defaults := global
+	globalMu.RUnlock()
+	if len(scoped) == 0 && len(defaults) == 0 {
+		return Fault{}, false
+	}
+
+	for _, target := range targets {
+		fault, ok := scoped[target]
+		if !ok {
+			fault, ok = defaults[target]
+		}
+		if !ok {
+			continue
+		}
+		if fault.Probability < 1 && rand.Float64() >= fault.Probability {
+			return Fault{}, false
+		}
+		return fault, true
+	}
+	return Fault{}, false
+}
+
+// Inject applies the fault for the first matching target: it waits for
+// latency faults and returns the error of failing ones. It returns nil when
+// no fault applies. Partial faults are left to the caller and return nil.
+func Inject(ctx context.Context, targets ...string) error {
+	fault, ok := Lookup(ctx, targets...)
+	if !ok {
+		return nil
+	}
+	return fault.Apply(ctx)
+}
+
+// Apply performs a fault
+func (f Fault) Apply(ctx context.Context) error {
+	switch f.Kind {
+	case KindLatency:
+		timer := time.NewTimer(f.Delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	case KindTimeout:
+		<-ctx.Done()
+		return fmt.Errorf("%w: %v", ErrInjected, ctx.Err())
+	case KindStatus:
+		return &StatusError{Code: f.Status}
+	case KindError:
+		return ErrInjected
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,88 @@
+package collab
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// maxHistory bounds the edits kept to transform late edits against. Edits
+// made on older revisions are rejected and the editor has to resync.
+const maxHistory = 1000
+
+// ErrStaleRevision is returned for edits made on a revision that is no
+// longer in the history
+var ErrStaleRevision = errors.New("revision is too old, resync the document")
+
+// Document is the shared text of an editing session
+type Document struct {
+	mu       sync.Mutex
+	text     []rune
+	revision int
+	// history holds the operations of the last revisions; history[i]
+	// produced revision base+i+1
+	history [][]Op
+	base    int
+	// maxLength caps the text in code points; 0 means no limit
+	maxLength int
+}
+
+// NewDocument starts a document at revision 0
+func NewDocument(text string, maxLength int) *Document {
+	return &Document{text: []rune(text), maxLength: maxLength}
+}
+
+// State returns the text and its revision
+func (d *Document) State() (string, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return string(d.text), d.revision
+}
+
+// Apply applies operations made on a revision of the document. It returns
+// the operations as applied, after transforming them against the edits
+// made since, and the new revision.
+func (d *Document) Apply(revision int, edit []Op) ([]Op, int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if revision < d.base || revision > d.revision {
+		if revision > d.revision {
+			return nil, d.revision, fmt.Errorf("%w: unknown revision %d", ErrInvalidOperation, revision)
+		}
+		return nil, d.revision, ErrStaleRevision
+	}
+
+	for _, op := range edit {
+		if (op.Type != OpInsert && op.Type != OpDelete) || op.Position < 0 || op.Length < 0 {
+			return nil, d.revision, fmt.Errorf("%w: %+v", ErrInvalidOperation, op)
+		}
+	}
+
+	for _, concurrent := range d.history[revision-d.base:] {
+		edit, _ = Transform(edit, concurrent)
+	}
+	edit = ops(edit...)
+	if len(edit) == 0 {
+		return edit, d.revision, nil
+	}
+
+	text, err := Apply(d.text, edit...)
+	if err != nil {
+		return nil, d.revision, err
+	}
+	if d.maxLength > 0 && len(text) > d.maxLength && len(text) > len(d.text) {
+		return nil, d.revision, fmt.Errorf("%w: the text is limited to %d characters", ErrInvalidOperation, d.maxLength)
+	}
+
+	d.text = text
+	d.revision++
+	d.history = append(d.history, edit)
+	if len(d.history) > maxHistory {
+		drop := len(d.history) - maxHistory
+		d.history = append([][]Op(nil), d.history[drop:]...)
+		d.base += drop
+	}
+	return edit, d.revision, nil
+}
@@ -0,0 +1,166 @@
+// Package collab merges concurrent edits of a text by several reviewers.
+//
+// Edits are operational transforms of single inserts and deletes. Every
+// edit names the revision of the document it was made on; the server
+// transforms it against the edits applied since, applies it and hands the
+// result to the other editors, so all copies of the text converge.
+// Positions count Unicode code points.
+package collab
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Operation types
+const (
+	OpInsert = "insert"
+	OpDelete = "delete"
+)
+
+// ErrInvalidOperation is returned for operations that do not fit the text
+var ErrInvalidOperation = errors.New("invalid operation")
+
+// Op inserts text at a position or deletes a number of code points from it
+type Op struct {
+	Type     string `json:"type"`
+	Position int    `json:"position"`
+	Text     string `json:"text,omitempty"`   // inserted text
+	Length   int    `json:"length,omitempty"` // deleted code points
+}
+
+// Insert returns an operation inserting text at a position
+func Insert(position int, text string) Op {
+	return Op{Type: OpInsert, Position: position, Text: text}
+}
+
+// Delete returns an operation deleting length code points at a position
+func Delete(position, length int) Op {
+	return Op{Type: OpDelete, Position: position, Length: length}
+}
+
+// size is the number of code points the operation inserts
+func (o Op) size() int {
+	return len([]rune(o.Text))
+}
+
+// noop reports whether the operation leaves the text unchanged
+func (o Op) noop() bool {
+	if o.Type == OpInsert {
+		return o.Text == ""
+	}
+	return o.Length == 0
+}
+
+// Apply applies operations in order to a text
+func Apply(text []rune, ops ...Op) ([]rune, error) {
+	for _, op := range ops {
+		if op.Position < 0 || op.Position > len(text) {
+			return nil, fmt.Errorf("%w: position %d outside the text of %d characters", ErrInvalidOperation, op.Position, len(text))
+		}
+		switch op.Type {
+		case OpInsert:
+			inserted := []rune(op.Text)
+			next := make([]rune, 0, len(text)+len(inserted))
+			next = append(next, text[:op.Position]...)
+			next = append(next, inserted...)
+			text = append(next, text[op.Position:]...)
+		case OpDelete:
+			if op.Length < 0 || op.Position+op.Length > len(text) {
+				return nil, fmt.Errorf("%w: deletion of %d characters at %d outside the text of %d characters", ErrInvalidOperation, op.Length, op.Position, len(text))
+			}
+			next := make([]rune, 0, len(text)-op.Length)
+			next = append(next, text[:op.Position]...)
+			text = append(next, text[op.Position+op.Length:]...)
+		default:
+			return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidOperation, op.Type)
+		}
+	}
+	return text, nil
+}
+
+// Transform transforms two sequences of operations made concurrently on the
+// same text. It returns a rewritten to apply after b and b rewritten to
+// apply after a. When both insert at the same position, b's text comes
+// first.
+func Transform(a, b []Op) ([]Op, []Op) {
+	switch {
+	case len(a) == 0 || len(b) == 0:
+		return a, b
+	case len(a) == 1 && len(b) == 1:
+		return transformOp(a[0], b[0])
+	case len(a) > 1:
+		a1, b1 := Transform(a[:1], b)
+		a2, b2 := Transform(a[1:], b1)
+		return append(a1, a2...), b2
+	default:
+		a1, b1 := Transform(a, b[:1])
+		a2, b2 := Transform(a1, b[1:])
+		return a2, append(b1, b2...)
+	}
+}
+
+// transformOp transforms two single concurrent operations. A deletion
+// spanning a concurrent insertion is split so the inserted text survives.
+func transformOp(a, b Op) ([]Op, []Op) {
+	switch {
+	case a.Type == OpInsert && b.Type == OpInsert:
+		if a.Position < b.Position {
+			return ops(a), ops(Insert(b.Position+a.size(), b.Text))
+		}
+		return ops(Insert(a.Position+b.size(), a.Text)), ops(b)
+
+	case a.Type == OpInsert && b.Type == OpDelete:
+		return insertAgainstDelete(a, b)
+
+	case a.Type == OpDelete && b.Type == OpInsert:
+		bt, at := insertAgainstDelete(b, a)
+		return at, bt
+
+	default:
+		return ops(deleteAfterDelete(a, b)), ops(deleteAfterDelete(b, a))
+	}
+}
+
+// insertAgainstDelete transforms a concurrent insertion and deletion, in
+// that order
+func insertAgainstDelete(ins, del Op) ([]Op, []Op) {
+	switch {
+	case ins.Position <= del.Position:
+		return ops(ins), ops(Delete(del.Position+ins.size(), del.Length))
+	case ins.Position >= del.Position+del.Length:
+		return ops(Insert(ins.Position-del.Length, ins.Text)), ops(del)
+	default:
+		before := ins.Position - del.Position
+		return ops(Insert(del.Position, ins.Text)),
+			ops(Delete(del.Position, before), Delete(del.Position+ins.size(), del.Length-before))
+	}
+}
+
+// deleteAfterDelete rewrites deletion a to apply after the concurrent
+// deletion b, dropping what b already deleted
+func deleteAfterDelete(a, b Op) Op {
+	shift := func(position int) int {
+		switch {
+		case position <= b.Position:
+			return position
+		case position <= b.Position+b.Length:
+			return b.Position
+		default:
+			return position - b.Length
+		}
+	}
+	start, end := shift(a.Position), shift(a.Position+a.Length)
+	return Delete(start, end-start)
+}
+
+// ops drops the operations that change nothing
+func ops(list ...Op) []Op {
+	result := make([]Op, 0, len(list))
+	for _, op := range list {
+		if !op.noop() {
+			result = append(result, op)
+		}
+	}
+	return result
+}
@@ -0,0 +1,339 @@
+// Package conformance drafts accessibility conformance statements from the
+// results of the accessibility analyzer. A statement summarizes the WCAG 2.1
+// Level AA success criteria the automated checks cover, lists the known
+// issues and plans their remediation by severity. It is a draft for a legal
+// compliance page: automated checks cannot establish full conformance, and
+// the statement says so.
+package conformance
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Standard is the standard statements are drafted against
+const Standard = "WCAG 2.1 Level AA"
+
+// Results of a success criterion
+const (
+	ResultPasses = "passes" // no failures found by the automated checks
+	ResultFails  = "fails"
+)
+
+// Conformance statuses of a statement
+const (
+	// StatusPartiallyConformant means some content does not conform
+	StatusPartiallyConformant = "partially_conformant"
+	// StatusNoKnownFailures means the automated checks found no failures;
+	// conformance still needs a manual review
+	StatusNoKnownFailures = "no_known_failures"
+)
+
+// dateLayout formats the dates of a statement
+const dateLayout = "2 January 2006"
+
+// Criterion is a WCAG success criterion
+type Criterion struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Level string `json:"level"`
+}
+
+// criteria are the success criteria the automated checks cover, in WCAG order
+var criteria = []Criterion{
+	{ID: "1.1.1", Name: "Non-text Content", Level: "A"},
+	{ID: "1.3.1", Name: "Info and Relationships", Level: "A"},
+	{ID: "1.4.3", Name: "Contrast (Minimum)", Level: "AA"},
+	{ID: "1.4.4", Name: "Resize Text", Level: "AA"},
+	{ID: "2.4.1", Name: "Bypass Blocks", Level: "A"},
+	{ID: "2.4.2", Name: "Page Titled", Level: "A"},
+	{ID: "2.4.3", Name: "Focus Order", Level: "A"},
+	{ID: "2.4.4", Name: "Link Purpose (In Context)", Level: "A"},
+	{ID: "3.1.1", Name: "Language of Page", Level: "A"},
+	{ID: "3.3.2", Name: "Labels or Instructions", Level: "A"},
+	{ID: "4.1.1", Name: "Parsing", Level: "A"},
+	{ID: "4.1.2", Name: "Name, Role, Value", Level: "A"},
+}
+
+// totalCriteria is the number of WCAG 2.1 success criteria at Levels A and AA
+const totalCriteria = 50
+
+// issueCriteria maps the issue types of the accessibility analyzer, including
+// failed Lighthouse audits, to the success criteria they fail
+var issueCriteria = map[string]string{
+	"missing_alt_text":                      "1.1.1",
+	"lighthouse_image-alt":                  "1.1.1",
+	"lighthouse_input-image-alt":            "1.1.1",
+	"missing_form_labels":                   "1.3.1",
+	"insufficient_semantic_html":            "1.3.1",
+	"lighthouse_label":                      "1.3.1",
+	"lighthouse_form-field-multiple-labels": "1.3.1",
+	"lighthouse_list":                       "1.3.1",
+	"lighthouse_td-headers-attr":            "1.3.1",
+	"lighthouse_heading-order":              "1.3.1",
+	"potential_contrast_issues":             "1.4.3",
+	"lighthouse_color-contrast":             "1.4.3",
+	"small_font_size":                       "1.4.4",
+	"lighthouse_meta-viewport":              "1.4.4",
+	"no_skip_links":                         "2.4.1",
+	"lighthouse_document-title":             "2.4.2",
+	"tabindex_issue":                        "2.4.3",
+	"lighthouse_tabindex":                   "2.4.3",
+	"lighthouse_link-name":                  "2.4.4",
+	"missing_language":                      "3.1.1",
+	"lighthouse_html-has-lang":              "3.1.1",
+	"lighthouse_valid-lang":                 "3.1.1",
+	"inaccessible_forms":                    "3.3.2",
+	"lighthouse_duplicate-id-aria":          "4.1.1",
+	"lighthouse_button-name":                "4.1.2",
+	"lighthouse_aria-required-attr":         "4.1.2",
+	"lighthouse_aria-roles":                 "4.1.2",
+	"lighthouse_aria-valid-attr":            "4.1.2",
+	"no_aria":                               "4.1.2",
+}
+
+// Issue is an accessibility issue found by the analysis
+type Issue struct {
+	Type     string `json:"type"`
+	Severity string `json:"severity"`
+	Title    string `json:"title"`
+	Location string `json:"location,omitempty"`
+}
+
+// RemediationDays are the days from the statement date by which issues of
+// each severity are planned to be fixed
+type RemediationDays struct {
+	High   int `json:"high"`
+	Medium int `json:"medium"`
+	Low    int `json:"low"`
+}
+
+// DefaultRemediationDays plans fixes within one, two and three months
+var DefaultRemediationDays = RemediationDays{High: 30, Medium: 60, Low: 90}
+
+// withDefaults fills unset periods with the defaults
+func (d RemediationDays) withDefaults() RemediationDays {
+	if d.High <= 0 {
+		d.High = DefaultRemediationDays.High
+	}
+	if d.Medium <= 0 {
+		d.Medium = DefaultRemediationDays.Medium
+	}
+	if d.Low <= 0 {
+		d.Low = DefaultRemediationDays.Low
+	}
+	return d
+}
+
+// days returns the period of a severity
+func (d RemediationDays) days(severity string) int {
+	switch severity {
+	case "high", "critical":
+		return d.High
+	case "medium":
+		return d.Medium
+	default:
+		return d.Low
+	}
+}
+
+// Input is what a statement is drafted from
+type Input struct {
+	Organization string
+	ContactEmail string
+	URL          string
+	AnalysisID   string
+	AnalyzedAt   time.Time
+	// Score is the accessibility score of the analysis, 0-100
+	Score       float64
+	Issues      []Issue
+	Date        time.Time
+	Remediation RemediationDays
+}
+
+// KnownIssue is an issue failing a success criterion, with the date its fix
+// is planned by
+type KnownIssue struct {
+	Issue
+	TargetDate time.Time `json:"target_date"`
+}
+
+// CriterionResult is the outcome of a success criterion
+type CriterionResult struct {
+	Criterion
+	Result string       `json:"result"`
+	Issues []KnownIssue `json:"issues,omitempty"`
+	// TargetDate is when all issues of the criterion are planned to be fixed
+	TargetDate *time.Time `json:"target_date,omitempty"`
+}
+
+// Section is a part of the statement text
+type Section struct {
+	Heading string `json:"heading"`
+	Text    string `json:"text"`
+}
+
+// Statement is a draft accessibility conformance statement
+type Statement struct {
+	Title        string            `json:"title"`
+	Organization string            `json:"organization"`
+	URL          string            `json:"url"`
+	AnalysisID   string            `json:"analysis_id"`
+	Standard     string            `json:"standard"`
+	Status       string            `json:"status"`
+	Score        float64           `json:"score"`
+	Date         time.Time         `json:"date"`
+	AnalyzedAt   time.Time         `json:"analyzed_at"`
+	Criteria     []CriterionResult `json:"criteria"`
+	// Unmatched are accessibility issues that no success criterion covers
+	Unmatched []Issue   `json:"unmatched_issues,omitempty"`
+	Sections  []Section `json:"sections"`
+	// PolishedBy names the LLM provider that polished the sections, if any
+	PolishedBy string `json:"polished_by,omitempty"`
+}
+
+// Build drafts the statement of an analysis
+func Build(in Input) Statement {
+	if in.Date.IsZero() {
+		in.Date = time.Now()
+	}
+	in.Date = in.Date.UTC().Truncate(24 * time.Hour)
+	remediation := in.Remediation.withDefaults()
+
+	byCriterion := make(map[string][]KnownIssue)
+	var unmatched []Issue
+	for _, issue := range in.Issues {
+		id, ok := issueCriteria[issue.Type]
+		if !ok {
+			unmatched = append(unmatched, issue)
+			continue
+		}
+		byCriterion[id] = append(byCriterion[id], KnownIssue{
+			Issue:      issue,
+			TargetDate: in.Date.AddDate(0, 0, remediation.days(issue.Severity)),
+		})
+	}
+
+	results := make([]CriterionResult, 0, len(criteria))
+	status := StatusNoKnownFailures
+	for _, criterion := range criteria {
+		result := CriterionResult{Criterion: criterion, Result: ResultPasses}
+		if issues := byCriterion[criterion.ID]; len(issues) > 0 {
+			sort.SliceStable(issues, func(i, j int) bool {
+				return issues[i].TargetDate.Before(issues[j].TargetDate)
+			})
+			latest := issues[len(issues)-1].TargetDate
+			result.Result = ResultFails
+			result.Issues = issues
+			result.TargetDate = &latest
+			status = StatusPartiallyConformant
+		}
+		results = append(results, result)
+	}
+
+	organization := strings.TrimSpace(in.Organization)
+	if organization == "" {
+		organization = hostOf(in.URL)
+	}
+
+	statement := Statement{
+		Title:        "Accessibility Statement for " + organization,
+		Organization: organization,
+		URL:          in.URL,
+		AnalysisID:   in.AnalysisID,
+		Standard:     Standard,
+		Status:       status,
+		Score:        in.Score,
+		Date:         in.Date,
+		AnalyzedAt:   in.AnalyzedAt,
+		Criteria:     results,
+		Unmatched:    unmatched,
+	}
+	statement.Sections = sections(statement, in.ContactEmail, remediation)
+	return statement
+}
+
+// Failures returns the criteria the site fails
+func (s Statement) Failures() []CriterionResult {
+	var failures []CriterionResult
+	for _, result := range s.Criteria {
+		if result.Result == ResultFails {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+// sections writes the templated text of a statement
+func sections(s Statement, contact string, remediation RemediationDays) []Section {
+	failures := s.Failures()
+	passes := len(s.Criteria) - len(failures)
+
+	commitment := fmt.Sprintf("%s is committed to ensuring digital accessibility for people with disabilities. We are continually improving the user experience for everyone and applying the relevant accessibility standards to %s.", s.Organization, s.URL)
+
+	var status string
+	if s.Status == StatusPartiallyConformant {
+		status = fmt.Sprintf("The Web Content Accessibility Guidelines (WCAG) define requirements for designers and developers to improve accessibility for people with disabilities. %s is partially conformant with %s: some parts of the content do not fully conform to the standard.", s.URL, Standard)
+	} else {
+		status = fmt.Sprintf("The Web Content Accessibility Guidelines (WCAG) define requirements for designers and developers to improve accessibility for people with disabilities. Automated testing found no failures of the %s success criteria it covers on %s. Full conformance has not been verified by a manual review.", Standard, s.URL)
+	}
+
+	assessment := fmt.Sprintf("%s was assessed by automated testing on %s, with an accessibility score of %.0f out of 100. The testing covered %d of the %d success criteria of %s: %d passed and %d failed. The remaining criteria, such as captions, keyboard operation and consistent navigation, require manual evaluation and are not covered by this statement.",
+		s.URL, s.AnalyzedAt.Format(dateLayout), s.Score, len(s.Criteria), totalCriteria, Standard, passes, len(failures))
+
+	var limitations string
+	if len(failures) == 0 {
+		limitations = "No known accessibility issues were found by automated testing."
+	} else {
+		lines := make([]string, 0, len(failures))
+		for _, failure := range failures {
+			titles := make([]string, 0, len(failure.Issues))
+			for _, issue := range failure.Issues {
+				titles = append(titles, strings.TrimRight(issue.Title, ". "))
+			}
+			lines = append(lines, fmt.Sprintf("%s %s (Level %s): %s.", failure.ID, failure.Name, failure.Level, strings.Join(titles, "; ")))
+		}
+		limitations = "Despite our best efforts, users may experience some problems. These are the known limitations:\n" + strings.Join(lines, "\n")
+	}
+
+	var plan string
+	if len(failures) == 0 {
+		plan = "We will keep monitoring the site and address any accessibility issues found in future assessments."
+	} else {
+		lines := make([]string, 0, len(failures))
+		for _, failure := range failures {
+			lines = append(lines, fmt.Sprintf("%s %s: by %s.", failure.ID, failure.Name, failure.TargetDate.Format(dateLayout)))
+		}
+		plan = fmt.Sprintf("We plan to fix high severity issues within %d days, medium severity issues within %d days and low severity issues within %d days of this statement:\n%s",
+			remediation.High, remediation.Medium, remediation.Low, strings.Join(lines, "\n"))
+	}
+
+	feedback := "We welcome your feedback on the accessibility of the site. Please let us know if you encounter accessibility barriers."
+	if contact != "" {
+		feedback += " Contact us at " + contact + "; we try to respond within 5 business days."
+	}
+
+	return []Section{
+		{Heading: "Commitment", Text: commitment},
+		{Heading: "Conformance status", Text: status},
+		{Heading: "Assessment approach", Text: assessment},
+		{Heading: "Known limitations", Text: limitations},
+		{Heading: "Remediation plan", Text: plan},
+		{Heading: "Feedback", Text: feedback},
+		{Heading: "Date", Text: fmt.Sprintf("This statement was created on %s.", s.Date.Format(dateLayout))},
+	}
+}
+
+// hostOf returns the host of a URL for use as the organization name
+func hostOf(pageURL string) string {
+	host := pageURL
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.IndexAny(host, "/?#"); i >= 0 {
+		host = host[:i]
+	}
+	return strings.TrimPrefix(host, "www.")
+}
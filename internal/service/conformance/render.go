@@ -0,0 +1,81 @@
+package conformance
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/report"
+)
+
+var htmlTemplate = template.Must(template.New("statement").Funcs(template.FuncMap{
+	"lines": func(text string) []string { return strings.Split(text, "\n") },
+	"date":  func(s Statement) string { return s.Date.Format(dateLayout) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body style="font-family: Arial, Helvetica, sans-serif; color: #222; max-width: 720px; margin: 0 auto;">
+<main>
+<h1 style="font-size: 22px;">{{.Title}}</h1>
+{{range .Sections}}
+<section>
+<h2 style="font-size: 18px;">{{.Heading}}</h2>
+{{range lines .Text}}{{if .}}<p>{{.}}</p>
+{{end}}{{end}}
+</section>
+{{end}}
+<section>
+<h2 style="font-size: 18px;">{{.Standard}} summary</h2>
+<table style="border-collapse: collapse; font-size: 13px;">
+<caption style="text-align: left; padding-bottom: 4px;">Success criteria covered by automated testing</caption>
+<tr><th scope="col" style="text-align: left; padding: 2px 12px 2px 0;">Criterion</th><th scope="col" style="text-align: left; padding: 2px 12px 2px 0;">Level</th><th scope="col" style="text-align: left; padding: 2px 12px 2px 0;">Result</th><th scope="col" style="text-align: left;">Target date</th></tr>
+{{range .Criteria}}<tr><td style="padding: 2px 12px 2px 0;">{{.ID}} {{.Name}}</td><td style="padding: 2px 12px 2px 0;">{{.Level}}</td><td style="padding: 2px 12px 2px 0;">{{if eq .Result "fails"}}Does not conform{{else}}No failures found{{end}}</td><td>{{with .TargetDate}}{{.Format "2 January 2006"}}{{end}}</td></tr>
+{{end}}</table>
+</section>
+</main>
+<p style="color: #999; font-size: 12px;">Draft generated from the automated analysis {{.AnalysisID}} on {{date .}}. Review it before publishing.</p>
+</body>
+</html>
+`))
+
+// RenderHTML renders a statement as an HTML page
+func RenderHTML(s Statement) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, s); err != nil {
+		return nil, fmt.Errorf("failed to render statement: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderPDF renders a statement as a PDF document. The built-in Helvetica
+// font covers Latin-1; other characters are replaced by question marks.
+func RenderPDF(s Statement) []byte {
+	return report.TextPDF(textLines(s))
+}
+
+// textLines lays out a statement as lines of text with "# " and "## "
+// headings
+func textLines(s Statement) []string {
+	lines := []string{"# " + s.Title}
+	for _, section := range s.Sections {
+		lines = append(lines, "## "+section.Heading)
+		lines = append(lines, strings.Split(section.Text, "\n")...)
+	}
+
+	lines = append(lines, "## "+s.Standard+" summary")
+	for _, result := range s.Criteria {
+		line := fmt.Sprintf("%s %s (Level %s): ", result.ID, result.Name, result.Level)
+		if result.Result == ResultFails {
+			line += "does not conform, fix planned by " + result.TargetDate.Format(dateLayout)
+		} else {
+			line += "no failures found"
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "", fmt.Sprintf("Draft generated from the automated analysis %s on %s. Review it before publishing.", s.AnalysisID, s.Date.Format(dateLayout)))
+	return lines
+}
@@ -0,0 +1,143 @@
+// Package deploy reads deployment markers from GitHub webhooks
+package deploy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidSignature is returned for webhooks not signed with the secret
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrIgnored is returned for events that do not mark a deployment, such
+	// as pings, failed deployments and pushes to other branches
+	ErrIgnored = errors.New("event does not mark a deployment")
+)
+
+// Marker is a deployment of a commit
+type Marker struct {
+	CommitSHA   string
+	Ref         string
+	Repository  string
+	Environment string
+	Description string
+	DeployedAt  time.Time
+}
+
+// githubDeploymentStatus is the payload of a deployment_status event
+type githubDeploymentStatus struct {
+	DeploymentStatus struct {
+		State       string    `json:"state"`
+		Description string    `json:"description"`
+		CreatedAt   time.Time `json:"created_at"`
+	} `json:"deployment_status"`
+	Deployment struct {
+		SHA         string `json:"sha"`
+		Ref         string `json:"ref"`
+		Environment string `json:"environment"`
+		Description string `json:"description"`
+	} `json:"deployment"`
+	Repository githubRepository `json:"repository"`
+}
+
+// githubPush is the payload of a push event
+type githubPush struct {
+	Ref        string `json:"ref"`
+	After      string `json:"after"`
+	Deleted    bool   `json:"deleted"`
+	HeadCommit *struct {
+		Message   string    `json:"message"`
+		Timestamp time.Time `json:"timestamp"`
+	} `json:"head_commit"`
+	Repository githubRepository `json:"repository"`
+}
+
+type githubRepository struct {
+	FullName      string `json:"full_name"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+// VerifyGitHubSignature checks the X-Hub-Signature-256 header of a webhook
+func VerifyGitHubSignature(payload []byte, signatureHeader, secret string) error {
+	signature, ok := strings.CutPrefix(signatureHeader, "sha256=")
+	if !ok || secret == "" {
+		return ErrInvalidSignature
+	}
+	decoded, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	if !hmac.Equal(decoded, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// ParseGitHubEvent reads the deployment marked by a GitHub webhook event:
+// a successful deployment_status, or a push to the default branch for
+// repositories that deploy on every push
+func ParseGitHubEvent(event string, payload []byte) (*Marker, error) {
+	switch event {
+	case "deployment_status":
+		var status githubDeploymentStatus
+		if err := json.Unmarshal(payload, &status); err != nil {
+			return nil, fmt.Errorf("failed to decode deployment_status event: %w", err)
+		}
+		if status.DeploymentStatus.State != "success" || status.Deployment.SHA == "" {
+			return nil, ErrIgnored
+		}
+		description := status.DeploymentStatus.Description
+		if description == "" {
+			description = status.Deployment.Description
+		}
+		deployedAt := status.DeploymentStatus.CreatedAt
+		if deployedAt.IsZero() {
+			deployedAt = time.Now()
+		}
+		return &Marker{
+			CommitSHA:   status.Deployment.SHA,
+			Ref:         status.Deployment.Ref,
+			Repository:  status.Repository.FullName,
+			Environment: status.Deployment.Environment,
+			Description: description,
+			DeployedAt:  deployedAt,
+		}, nil
+
+	case "push":
+		var push githubPush
+		if err := json.Unmarshal(payload, &push); err != nil {
+			return nil, fmt.Errorf("failed to decode push event: %w", err)
+		}
+		branch := strings.TrimPrefix(push.Ref, "refs/heads/")
+		if push.Deleted || push.HeadCommit == nil || branch != push.Repository.DefaultBranch {
+			return nil, ErrIgnored
+		}
+		deployedAt := push.HeadCommit.Timestamp
+		if deployedAt.IsZero() {
+			deployedAt = time.Now()
+		}
+		return &Marker{
+			CommitSHA:   push.After,
+			Ref:         branch,
+			Repository:  push.Repository.FullName,
+			Description: firstLine(push.HeadCommit.Message),
+			DeployedAt:  deployedAt,
+		}, nil
+	}
+	return nil, ErrIgnored
+}
+
+// firstLine returns the subject of a commit message
+func firstLine(message string) string {
+	subject, _, _ := strings.Cut(message, "\n")
+	return strings.TrimSpace(subject)
+}
@@ -0,0 +1,287 @@
+// Package glossary enforces an organization's terminology in generated
+// content: approved translations, product names and trademark casing.
+package glossary
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxTerms is the largest glossary an organization can upload
+const MaxTerms = 1000
+
+// Term is an approved form and the forms generated content must not use
+// instead, e.g. "iPhone" for "IPhone" and "I-Phone", or "Warenkorb" for
+// "Einkaufswagen" in German. The approved form itself is matched without
+// regard to case, which enforces trademark casing.
+type Term struct {
+	Term     string   `json:"term"`
+	Variants []string `json:"variants,omitempty"`
+	Language string   `json:"language,omitempty"` // language the term applies to, e.g. de; empty for all
+}
+
+// Replacement is a correction made to generated content
+type Replacement struct {
+	Element string `json:"element,omitempty"` // heading, cta, content, html
+	Found   string `json:"found"`
+	Term    string `json:"term"`
+	Count   int    `json:"count"`
+}
+
+// Validate checks a glossary before it is saved
+func Validate(terms []Term) error {
+	if len(terms) > MaxTerms {
+		return fmt.Errorf("a glossary can have at most %d terms", MaxTerms)
+	}
+	for i, term := range terms {
+		if strings.TrimSpace(term.Term) == "" {
+			return fmt.Errorf("term %d: the approved form is required", i+1)
+		}
+		for _, variant := range term.Variants {
+			if strings.TrimSpace(variant) == "" {
+				return fmt.Errorf("term %q: variants must not be empty", term.Term)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseCSV reads a glossary from CSV rows of the approved form, its variants
+// separated by "|", and an optional language. A header row is skipped.
+func ParseCSV(r io.Reader) ([]Term, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var terms []Term
+	for line := 1; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid glossary CSV: %w", err)
+		}
+		if len(row) == 0 || strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(row[0]), "term") {
+			continue
+		}
+
+		term := Term{Term: strings.TrimSpace(row[0])}
+		if len(row) > 1 {
+			for _, variant := range strings.Split(row[1], "|") {
+				if variant = strings.TrimSpace(variant); variant != "" {
+					term.Variants = append(term.Variants, variant)
+				}
+			}
+		}
+		if len(row) > 2 {
+			term.Language = strings.TrimSpace(row[2])
+		}
+		terms = append(terms, term)
+	}
+	if len(terms) == 0 {
+		return nil, errors.New("the glossary has no terms")
+	}
+	return terms, nil
+}
+
+// rule replaces the matches of one form with its approved term
+type rule struct {
+	pattern *regexp.Regexp
+	form    string
+	term    string
+}
+
+// Glossary corrects text against the terms of one language
+type Glossary struct {
+	rules []rule
+}
+
+// New compiles the terms that apply to content in a language. Longer forms
+// are applied first so that "Apple Watch" wins over "Apple".
+func New(terms []Term, language string) *Glossary {
+	g := &Glossary{}
+	for _, term := range terms {
+		if !appliesTo(term.Language, language) {
+			continue
+		}
+		forms := append([]string{term.Term}, term.Variants...)
+		for _, form := range forms {
+			g.rules = append(g.rules, rule{
+				pattern: regexp.MustCompile(`(?i)` + regexp.QuoteMeta(form)),
+				form:    form,
+				term:    term.Term,
+			})
+		}
+	}
+	sort.SliceStable(g.rules, func(i, j int) bool {
+		return utf8.RuneCountInString(g.rules[i].form) > utf8.RuneCountInString(g.rules[j].form)
+	})
+	return g
+}
+
+// Empty reports whether no term applies
+func (g *Glossary) Empty() bool {
+	return len(g.rules) == 0
+}
+
+// Apply replaces the forms that differ from the approved terms in plain text
+func (g *Glossary) Apply(text string) (string, []Replacement) {
+	counts := make(map[[2]string]int)
+	var order [][2]string
+	for _, r := range g.rules {
+		text = r.replace(text, func(found string) {
+			key := [2]string{found, r.term}
+			if counts[key] == 0 {
+				order = append(order, key)
+			}
+			counts[key]++
+		})
+	}
+
+	replacements := make([]Replacement, 0, len(order))
+	for _, key := range order {
+		replacements = append(replacements, Replacement{Found: key[0], Term: key[1], Count: counts[key]})
+	}
+	return text, replacements
+}
+
+// ApplyHTML replaces terms in the text of an HTML fragment, leaving tags,
+// attributes, scripts and styles untouched
+func (g *Glossary) ApplyHTML(html string) (string, []Replacement) {
+	var out strings.Builder
+	var all []Replacement
+	skipUntil := ""
+	for len(html) > 0 {
+		start := strings.IndexByte(html, '<')
+		if start < 0 {
+			start = len(html)
+		}
+		if text := html[:start]; text != "" {
+			if skipUntil == "" {
+				corrected, replacements := g.Apply(text)
+				text = corrected
+				all = append(all, replacements...)
+			}
+			out.WriteString(text)
+		}
+		html = html[start:]
+		if html == "" {
+			break
+		}
+
+		end := strings.IndexByte(html, '>')
+		if end < 0 {
+			end = len(html) - 1
+		}
+		tag := html[:end+1]
+		out.WriteString(tag)
+		html = html[end+1:]
+
+		lower := strings.ToLower(tag)
+		switch {
+		case skipUntil != "":
+			if strings.HasPrefix(lower, "</"+skipUntil) {
+				skipUntil = ""
+			}
+		case strings.HasPrefix(lower, "<script"):
+			skipUntil = "script"
+		case strings.HasPrefix(lower, "<style"):
+			skipUntil = "style"
+		}
+	}
+	return out.String(), Merge(all)
+}
+
+// Merge combines the replacements of the same form and term
+func Merge(replacements []Replacement) []Replacement {
+	merged := make([]Replacement, 0, len(replacements))
+	index := make(map[[3]string]int)
+	for _, replacement := range replacements {
+		key := [3]string{replacement.Element, replacement.Found, replacement.Term}
+		if i, ok := index[key]; ok {
+			merged[i].Count += replacement.Count
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, replacement)
+	}
+	return merged
+}
+
+// replace substitutes the approved term for whole-word matches of the form
+// that are not already written as the term
+func (r rule) replace(text string, replaced func(found string)) string {
+	matches := r.pattern.FindAllStringIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, m := range matches {
+		found := text[m[0]:m[1]]
+		if found == r.term || !wordBoundary(text, m[0], m[1]) {
+			continue
+		}
+		out.WriteString(text[last:m[0]])
+		out.WriteString(r.term)
+		last = m[1]
+		replaced(found)
+	}
+	if last == 0 {
+		return text
+	}
+	out.WriteString(text[last:])
+	return out.String()
+}
+
+// wordBoundary reports whether a match is not part of a longer word
+func wordBoundary(text string, start, end int) bool {
+	if start > 0 {
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		if isWordRune(before) {
+			return false
+		}
+	}
+	if end < len(text) {
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if isWordRune(after) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// appliesTo reports whether a term of a language applies to content in
+// another, comparing primary subtags so that "de" covers "de-CH"
+func appliesTo(termLanguage, contentLanguage string) bool {
+	if termLanguage == "" {
+		return true
+	}
+	if contentLanguage == "" {
+		contentLanguage = "en"
+	}
+	primary := func(tag string) string {
+		tag = strings.ToLower(tag)
+		if i := strings.IndexAny(tag, "-_"); i >= 0 {
+			tag = tag[:i]
+		}
+		return tag
+	}
+	return primary(termLanguage) == primary(contentLanguage)
+}
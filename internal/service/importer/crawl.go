@@ -0,0 +1,310 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// crawlColumns maps the fields of a crawl to the column names Screaming Frog
+// and Ahrefs Site Audit use for them in their page exports
+var crawlColumns = map[string][]string{
+	"url":          {"address", "url", "page url"},
+	"status":       {"status code", "http status code", "http code"},
+	"content_type": {"content type", "content-type"},
+	"title":        {"title 1", "title"},
+	"description":  {"meta description 1", "meta description"},
+	"h1":           {"h1-1", "h1"},
+	"h1_second":    {"h1-2"},
+	"h1_count":     {"h1 count", "no. of h1", "number of h1"},
+	"canonical":    {"canonical link element 1", "canonical url", "canonical"},
+	"indexability": {"indexability", "is indexable", "indexable"},
+	"word_count":   {"word count", "no. of words", "words"},
+	"response":     {"response time"},
+	"size":         {"size (bytes)", "size"},
+	"crawled_at":   {"crawl timestamp", "crawl date", "crawled"},
+}
+
+// crawlTimeLayouts are the timestamp formats of crawl exports
+var crawlTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseCrawl maps a crawler page export to one page per HTML URL. Issues
+// are derived with the thresholds of the seo and content analyzers; crawlers
+// do not score pages, so imported crawls carry no score.
+func parseCrawl(data []byte, auditedAt time.Time) ([]Page, error) {
+	rows, err := readTable(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Older Screaming Frog exports put the report name above the header
+	header := -1
+	var columns map[string]int
+	for i := 0; i < len(rows) && i < 3; i++ {
+		columns = mapColumns(rows[i])
+		if _, ok := columns["url"]; ok {
+			header = i
+			break
+		}
+	}
+	if header < 0 {
+		return nil, errors.New("the export has no Address or URL column")
+	}
+
+	has := func(name string) bool {
+		_, ok := columns[name]
+		return ok
+	}
+
+	var pages []Page
+	seen := make(map[string]bool)
+	for _, row := range rows[header+1:] {
+		field := func(name string) string {
+			index, ok := columns[name]
+			if !ok || index >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[index])
+		}
+
+		pageURL := field("url")
+		if pageURL == "" || seen[pageURL] {
+			continue
+		}
+		// Crawls list images, scripts and stylesheets too
+		if contentType := field("content_type"); contentType != "" && !strings.Contains(strings.ToLower(contentType), "html") {
+			continue
+		}
+		seen[pageURL] = true
+
+		pages = append(pages, crawlPage(pageURL, field, has, auditedAt))
+	}
+	return pages, nil
+}
+
+// crawlPage builds the page of one crawl row. Checks whose column is not in
+// the export are skipped.
+func crawlPage(pageURL string, field func(string) string, has func(string) bool, auditedAt time.Time) Page {
+	page := Page{URL: pageURL, AuditedAt: auditedAt, Issues: map[string][]map[string]interface{}{}}
+	if crawledAt := field("crawled_at"); crawledAt != "" {
+		for _, layout := range crawlTimeLayouts {
+			if parsed, err := time.Parse(layout, crawledAt); err == nil {
+				page.AuditedAt = parsed
+				break
+			}
+		}
+	}
+
+	seo := map[string]interface{}{"type": "seo"}
+	var seoIssues []map[string]interface{}
+
+	status, statusErr := strconv.Atoi(field("status"))
+	if statusErr == nil {
+		seo["status_code"] = status
+		if status >= 400 {
+			seoIssues = append(seoIssues, issue("http_error", "high", fmt.Sprintf("Страница отвечает ошибкой HTTP %d", status)))
+		}
+	}
+	// Redirects and errors have no content to check
+	checkContent := statusErr != nil || (status >= 200 && status < 300)
+
+	if checkContent && has("title") {
+		title := field("title")
+		titleLength := utf8.RuneCountInString(title)
+		seo["title"] = title
+		seo["title_length"] = titleLength
+		switch {
+		case title == "":
+			seoIssues = append(seoIssues, issue("missing_title", "high", "На странице отсутствует тег title"))
+		case titleLength < 30:
+			seoIssues = append(seoIssues, issue("title_too_short", "medium", "Тег title слишком короткий"))
+		case titleLength > 60:
+			seoIssues = append(seoIssues, issue("title_too_long", "medium", "Тег title слишком длинный"))
+		}
+	}
+
+	if checkContent && has("description") {
+		description := field("description")
+		descriptionLength := utf8.RuneCountInString(description)
+		seo["meta_description_length"] = descriptionLength
+		switch {
+		case description == "":
+			seoIssues = append(seoIssues, issue("missing_description", "high", "На странице отсутствует мета-тег description"))
+		case descriptionLength < 50:
+			seoIssues = append(seoIssues, issue("description_too_short", "medium", "Мета-тег description слишком короткий"))
+		case descriptionLength > 160:
+			seoIssues = append(seoIssues, issue("description_too_long", "medium", "Мета-тег description слишком длинный"))
+		}
+	}
+
+	if checkContent && (has("h1") || has("h1_count")) {
+		h1Count := 0
+		if count, err := strconv.Atoi(field("h1_count")); err == nil {
+			h1Count = count
+		} else if field("h1") != "" {
+			h1Count = 1
+			if field("h1_second") != "" {
+				h1Count = 2
+			}
+		}
+		seo["h1_count"] = h1Count
+		switch {
+		case h1Count == 0:
+			seoIssues = append(seoIssues, issue("missing_h1", "high", "На странице отсутствует заголовок H1"))
+		case h1Count > 1:
+			seoIssues = append(seoIssues, issue("multiple_h1", "medium", "На странице несколько заголовков H1"))
+		}
+	}
+
+	if checkContent && has("canonical") {
+		canonical := field("canonical")
+		seo["canonical_url"] = canonical
+		if canonical == "" {
+			seoIssues = append(seoIssues, issue("missing_canonical", "medium", "На странице отсутствует канонический URL"))
+		}
+	}
+
+	if checkContent {
+		if indexable, ok := parseIndexable(field("indexability")); ok {
+			seo["indexable"] = indexable
+			if !indexable {
+				seoIssues = append(seoIssues, issue("not_indexable", "medium", "Страница закрыта от индексации"))
+			}
+		}
+
+		if wordCount, err := strconv.Atoi(field("word_count")); err == nil {
+			page.Metrics = append(page.Metrics, Metric{
+				Category: "content",
+				Name:     "content_imported",
+				Value:    map[string]interface{}{"type": "content", "word_count": wordCount},
+			})
+			if wordCount < 300 {
+				page.Issues["content"] = []map[string]interface{}{
+					issue("low_word_count", "medium", "Недостаточное количество слов для качественного контента"),
+				}
+			}
+		}
+	}
+
+	page.Metrics = append(page.Metrics, Metric{Category: "seo", Name: "seo_imported", Value: seo})
+	if len(seoIssues) > 0 {
+		page.Issues["seo"] = seoIssues
+	}
+
+	performance := map[string]interface{}{"type": "performance"}
+	if seconds, err := strconv.ParseFloat(field("response"), 64); err == nil {
+		performance["response_time_ms"] = seconds * 1000
+	}
+	if size, err := strconv.ParseInt(field("size"), 10, 64); err == nil {
+		performance["html_size_bytes"] = size
+	}
+	if len(performance) > 1 {
+		page.Metrics = append(page.Metrics, Metric{Category: "performance", Name: "performance_imported", Value: performance})
+	}
+	return page
+}
+
+// parseIndexable reads the indexability of a page: "Indexable" and
+// "Non-Indexable" in Screaming Frog, true/false in Ahrefs
+func parseIndexable(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "indexable", "true", "yes", "1":
+		return true, true
+	case "non-indexable", "false", "no", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// mapColumns finds the fields of a crawl in a header row
+func mapColumns(header []string) map[string]int {
+	columns := make(map[string]int)
+	for field, names := range crawlColumns {
+		for _, name := range names {
+			for i, column := range header {
+				if strings.EqualFold(strings.TrimSpace(column), name) {
+					columns[field] = i
+					break
+				}
+			}
+			if _, ok := columns[field]; ok {
+				break
+			}
+		}
+	}
+	return columns
+}
+
+// readTable decodes a CSV or TSV export. Ahrefs exports UTF-16 with tabs for
+// Excel; Screaming Frog exports UTF-8 with commas.
+func readTable(data []byte) ([][]string, error) {
+	text, err := decodeText(data)
+	if err != nil {
+		return nil, err
+	}
+
+	firstLine := text
+	if end := strings.IndexByte(text, '\n'); end >= 0 {
+		firstLine = text[:end]
+	}
+	reader := csv.NewReader(strings.NewReader(text))
+	if strings.Count(firstLine, "\t") > strings.Count(firstLine, ",") {
+		reader.Comma = '\t'
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var rows [][]string
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV export: %w", err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// decodeText converts an export to UTF-8 text, dropping byte order marks
+func decodeText(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], false), nil
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], true), nil
+	}
+	data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+	if !utf8.Valid(data) {
+		return "", errors.New("the export must be encoded as UTF-8 or UTF-16")
+	}
+	return string(data), nil
+}
+
+// decodeUTF16 decodes UTF-16 text of the given byte order
+func decodeUTF16(data []byte, bigEndian bool) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		if bigEndian {
+			units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+		} else {
+			units = append(units, uint16(data[i+1])<<8|uint16(data[i]))
+		}
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,86 @@
+// Package importer maps audit exports of other tools, such as Lighthouse
+// reports and crawler CSV exports, to the analyses, metrics and issues of
+// this platform, so that historical data survives a migration.
+package importer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Supported tools
+const (
+	ToolLighthouse    = "lighthouse"
+	ToolScreamingFrog = "screaming_frog"
+	ToolAhrefs        = "ahrefs"
+)
+
+// Tools lists the supported tools
+var Tools = []string{ToolLighthouse, ToolScreamingFrog, ToolAhrefs}
+
+// MaxPages limits the pages of one import; larger crawls must be split
+const MaxPages = 500
+
+// Metric is a result of an analyzer category reconstructed from an export.
+// Value holds "type" and, when the tool scores the category, a "score" from
+// 0 to 100.
+type Metric struct {
+	Category string
+	Name     string
+	Value    map[string]interface{}
+}
+
+// Page is the audit of one URL
+type Page struct {
+	URL       string
+	AuditedAt time.Time
+	// Score is the overall score; nil when the tool does not score pages
+	Score   *float64
+	Metrics []Metric
+	// Issues are keyed by analyzer category and use the fields analyzers
+	// report: type, severity, description and optionally url
+	Issues map[string][]map[string]interface{}
+}
+
+// Import is the content of one export file
+type Import struct {
+	Tool  string
+	Pages []Page
+}
+
+// Parse reads an export of a tool. auditedAt is used for pages whose export
+// does not record when they were audited.
+func Parse(tool string, data []byte, auditedAt time.Time) (*Import, error) {
+	var (
+		pages []Page
+		err   error
+	)
+	switch tool {
+	case ToolLighthouse:
+		pages, err = parseLighthouse(data, auditedAt)
+	case ToolScreamingFrog, ToolAhrefs:
+		pages, err = parseCrawl(data, auditedAt)
+	default:
+		return nil, fmt.Errorf("unsupported tool %q, expected one of %s", tool, strings.Join(Tools, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("the export contains no pages")
+	}
+	if len(pages) > MaxPages {
+		return nil, fmt.Errorf("the export contains %d pages; at most %d can be imported at once", len(pages), MaxPages)
+	}
+	return &Import{Tool: tool, Pages: pages}, nil
+}
+
+// issue builds an issue in the form analyzers report it
+func issue(issueType, severity, description string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        issueType,
+		"severity":    severity,
+		"description": description,
+	}
+}
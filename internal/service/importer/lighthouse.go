@@ -0,0 +1,60 @@
+package importer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/lighthouse"
+)
+
+// parseLighthouse maps a Lighthouse report to the result of the lighthouse
+// analyzer: the same score, performance metrics and issue types, so that
+// imported reports compare directly with new analyses
+func parseLighthouse(data []byte, auditedAt time.Time) ([]Page, error) {
+	report, err := lighthouse.ParseReport(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Lighthouse report: %w", err)
+	}
+	if len(report.Scores) == 0 || len(report.Audits) == 0 {
+		return nil, fmt.Errorf("invalid Lighthouse report: no category scores or audits")
+	}
+
+	pageURL := report.FinalURL
+	if pageURL == "" {
+		pageURL = report.URL
+	}
+	if pageURL == "" {
+		return nil, fmt.Errorf("invalid Lighthouse report: no URL")
+	}
+	if fetched, err := time.Parse(time.RFC3339, report.FetchTime); err == nil {
+		auditedAt = fetched
+	}
+
+	// The analyzer averages the category scores
+	total := 0.0
+	for _, score := range report.Scores {
+		total += score
+	}
+	score := total / float64(len(report.Scores)) * 100
+
+	value := map[string]interface{}{
+		"type":                     "lighthouse",
+		"score":                    score,
+		"category_scores":          report.Scores,
+		"lighthouse_version":       report.LighthouseVersion,
+		"first_contentful_paint":   report.Metrics.FirstContentfulPaint,
+		"largest_contentful_paint": report.Metrics.LargestContentfulPaint,
+		"speed_index":              report.Metrics.SpeedIndex,
+		"time_to_interactive":      report.Metrics.TimeToInteractive,
+		"total_blocking_time":      report.Metrics.TotalBlockingTime,
+		"cumulative_layout_shift":  report.Metrics.CumulativeLayoutShift,
+	}
+
+	return []Page{{
+		URL:       pageURL,
+		AuditedAt: auditedAt,
+		Score:     &score,
+		Metrics:   []Metric{{Category: "lighthouse", Name: "lighthouse_score", Value: value}},
+		Issues:    map[string][]map[string]interface{}{"lighthouse": report.Issues},
+	}}, nil
+}
@@ -0,0 +1,169 @@
+package issuedocs
+
+// topic is documentation shared by several issue types
+type topic struct {
+	issueTypes []string
+	// docs by platform; the empty platform is the generic documentation
+	docs map[string]Link
+}
+
+// Documentation shipped with the service. Platform names match the names of
+// detected technologies.
+var topics = []topic{
+	{
+		issueTypes: []string{"missing_title", "title_too_short", "title_too_long", "no_keywords_in_title"},
+		docs: map[string]Link{
+			"":          {Title: "Influencing title links in Google Search", URL: "https://developers.google.com/search/docs/appearance/title-link"},
+			"Shopify":   {Title: "Editing page titles and meta descriptions in Shopify", URL: "https://help.shopify.com/en/manual/promoting-marketing/seo/adding-keywords"},
+			"WordPress": {Title: "Search engine optimization in WordPress", URL: "https://wordpress.org/documentation/article/search-engine-optimization/"},
+			"Drupal":    {Title: "Metatag module for Drupal", URL: "https://www.drupal.org/project/metatag"},
+			"Magento":   {Title: "SEO settings in Adobe Commerce", URL: "https://experienceleague.adobe.com/docs/commerce-admin/marketing/seo/seo-overview.html"},
+			"Next.js":   {Title: "Metadata in Next.js", URL: "https://nextjs.org/docs/app/building-your-application/optimizing/metadata"},
+			"Gatsby":    {Title: "Adding an SEO component in Gatsby", URL: "https://www.gatsbyjs.com/docs/how-to/adding-common-features/adding-seo-component/"},
+		},
+	},
+	{
+		issueTypes: []string{"missing_description", "description_too_short", "description_too_long"},
+		docs: map[string]Link{
+			"":          {Title: "Writing meta descriptions", URL: "https://developers.google.com/search/docs/appearance/snippet#meta-descriptions"},
+			"Shopify":   {Title: "Editing page titles and meta descriptions in Shopify", URL: "https://help.shopify.com/en/manual/promoting-marketing/seo/adding-keywords"},
+			"WordPress": {Title: "Search engine optimization in WordPress", URL: "https://wordpress.org/documentation/article/search-engine-optimization/"},
+			"Drupal":    {Title: "Metatag module for Drupal", URL: "https://www.drupal.org/project/metatag"},
+			"Magento":   {Title: "SEO settings in Adobe Commerce", URL: "https://experienceleague.adobe.com/docs/commerce-admin/marketing/seo/seo-overview.html"},
+			"Next.js":   {Title: "Metadata in Next.js", URL: "https://nextjs.org/docs/app/building-your-application/optimizing/metadata"},
+			"Gatsby":    {Title: "Adding an SEO component in Gatsby", URL: "https://www.gatsbyjs.com/docs/how-to/adding-common-features/adding-seo-component/"},
+		},
+	},
+	{
+		issueTypes: []string{"missing_alt", "missing_alt_text", "too_short_alt", "suspicious_alt"},
+		docs: map[string]Link{
+			"":          {Title: "The alt attribute of images", URL: "https://developer.mozilla.org/en-US/docs/Web/HTML/Element/img#alt"},
+			"WordPress": {Title: "Editing image alt text in the WordPress media library", URL: "https://wordpress.org/documentation/article/media-library-screen/"},
+			"Shopify":   {Title: "Adding alt text to product media in Shopify", URL: "https://help.shopify.com/en/manual/products/product-media/add-alt-text"},
+			"Next.js":   {Title: "Image component in Next.js", URL: "https://nextjs.org/docs/app/api-reference/components/image"},
+			"Gatsby":    {Title: "Using gatsby-plugin-image", URL: "https://www.gatsbyjs.com/docs/how-to/images-and-media/using-gatsby-plugin-image/"},
+		},
+	},
+	{
+		issueTypes: []string{"missing_canonical", "canonical_mismatch", "relative_canonical", "duplicate_content"},
+		docs: map[string]Link{
+			"":        {Title: "Consolidating duplicate URLs", URL: "https://developers.google.com/search/docs/crawling-indexing/consolidate-duplicate-urls"},
+			"Drupal":  {Title: "Metatag module for Drupal", URL: "https://www.drupal.org/project/metatag"},
+			"Magento": {Title: "SEO settings in Adobe Commerce", URL: "https://experienceleague.adobe.com/docs/commerce-admin/marketing/seo/seo-overview.html"},
+			"Next.js": {Title: "Metadata in Next.js", URL: "https://nextjs.org/docs/app/building-your-application/optimizing/metadata"},
+		},
+	},
+	{
+		issueTypes: []string{"missing_viewport", "incomplete_viewport"},
+		docs: map[string]Link{
+			"":        {Title: "The viewport meta tag", URL: "https://developer.mozilla.org/en-US/docs/Web/HTML/Viewport_meta_tag"},
+			"Next.js": {Title: "Viewport configuration in Next.js", URL: "https://nextjs.org/docs/app/api-reference/functions/generate-viewport"},
+		},
+	},
+	{
+		issueTypes: []string{"missing_language"},
+		docs: map[string]Link{
+			"": {Title: "The lang attribute", URL: "https://developer.mozilla.org/en-US/docs/Web/HTML/Global_attributes/lang"},
+		},
+	},
+	{
+		issueTypes: []string{"no_https", "subdomain_no_https"},
+		docs: map[string]Link{
+			"":          {Title: "Why HTTPS matters", URL: "https://web.dev/articles/why-https-matters"},
+			"WordPress": {Title: "HTTPS for WordPress", URL: "https://wordpress.org/documentation/article/https-for-wordpress/"},
+		},
+	},
+	{
+		issueTypes: []string{"mixed_content"},
+		docs: map[string]Link{
+			"":          {Title: "Mixed content", URL: "https://developer.mozilla.org/en-US/docs/Web/Security/Mixed_content"},
+			"WordPress": {Title: "HTTPS for WordPress", URL: "https://wordpress.org/documentation/article/https-for-wordpress/"},
+		},
+	},
+	{
+		issueTypes: []string{"missing_hsts"},
+		docs: map[string]Link{
+			"":        {Title: "Strict-Transport-Security header", URL: "https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Strict-Transport-Security"},
+			"Next.js": {Title: "Custom headers in Next.js", URL: "https://nextjs.org/docs/app/api-reference/next-config-js/headers"},
+		},
+	},
+	{
+		issueTypes: []string{"missing_csp"},
+		docs: map[string]Link{
+			"":        {Title: "Content Security Policy", URL: "https://developer.mozilla.org/en-US/docs/Web/HTTP/CSP"},
+			"Next.js": {Title: "Content Security Policy in Next.js", URL: "https://nextjs.org/docs/app/building-your-application/configuring/content-security-policy"},
+		},
+	},
+	{
+		issueTypes: []string{"missing_x_frame_options"},
+		docs: map[string]Link{
+			"":        {Title: "X-Frame-Options header", URL: "https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/X-Frame-Options"},
+			"Next.js": {Title: "Custom headers in Next.js", URL: "https://nextjs.org/docs/app/api-reference/next-config-js/headers"},
+		},
+	},
+	{
+		issueTypes: []string{"large_image", "large_images_mobile", "no_responsive_images"},
+		docs: map[string]Link{
+			"":        {Title: "Serving responsive images", URL: "https://web.dev/articles/serve-responsive-images"},
+			"Next.js": {Title: "Image optimization in Next.js", URL: "https://nextjs.org/docs/app/building-your-application/optimizing/images"},
+			"Gatsby":  {Title: "Using gatsby-plugin-image", URL: "https://www.gatsbyjs.com/docs/how-to/images-and-media/using-gatsby-plugin-image/"},
+		},
+	},
+	{
+		issueTypes: []string{"missing_hreflang_x_default", "hreflang_not_reciprocal", "hreflang_unreachable", "hreflang_language_mismatch", "geo_content_without_hreflang"},
+		docs: map[string]Link{
+			"":        {Title: "Localized versions of your pages", URL: "https://developers.google.com/search/docs/specialty/international/localized-versions"},
+			"Next.js": {Title: "Metadata in Next.js", URL: "https://nextjs.org/docs/app/building-your-application/optimizing/metadata"},
+		},
+	},
+	{
+		issueTypes: []string{"render_blocking_script"},
+		docs: map[string]Link{
+			"":        {Title: "Eliminating render-blocking resources", URL: "https://developer.chrome.com/docs/lighthouse/performance/render-blocking-resources"},
+			"Next.js": {Title: "Script optimization in Next.js", URL: "https://nextjs.org/docs/app/building-your-application/optimizing/scripts"},
+		},
+	},
+	{
+		issueTypes: []string{"unminified_js"},
+		docs: map[string]Link{
+			"": {Title: "Minifying JavaScript", URL: "https://developer.chrome.com/docs/lighthouse/performance/unminified-javascript"},
+		},
+	},
+	{
+		issueTypes: []string{"unminified_css"},
+		docs: map[string]Link{
+			"": {Title: "Minifying CSS", URL: "https://developer.chrome.com/docs/lighthouse/performance/unminified-css"},
+		},
+	},
+	{
+		issueTypes: []string{"skipped_heading_levels", "heading_order"},
+		docs: map[string]Link{
+			"": {Title: "Heading elements", URL: "https://developer.mozilla.org/en-US/docs/Web/HTML/Element/Heading_Elements"},
+		},
+	},
+	{
+		issueTypes: []string{"missing_form_labels", "forms_without_labels", "inaccessible_forms"},
+		docs: map[string]Link{
+			"": {Title: "The label element", URL: "https://developer.mozilla.org/en-US/docs/Web/HTML/Element/label"},
+		},
+	},
+}
+
+// Builtin returns the documentation shipped with the service as entries for
+// any category
+func Builtin() []Entry {
+	var entries []Entry
+	for _, t := range topics {
+		for _, issueType := range t.issueTypes {
+			for platform, doc := range t.docs {
+				entries = append(entries, Entry{
+					IssueType: issueType,
+					Platform:  platform,
+					Title:     doc.Title,
+					URL:       doc.URL,
+				})
+			}
+		}
+	}
+	return entries
+}
@@ -0,0 +1,104 @@
+// Package issuedocs links issues to documentation on fixing them on the
+// platform a site is built with, e.g. how to edit the meta description in
+// WordPress, Shopify or Next.js, so recommendations are actionable for
+// people who do not write code.
+package issuedocs
+
+// Link is the documentation of an issue
+type Link struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	// Platform is the technology the documentation is written for, empty
+	// for documentation that applies to any site
+	Platform string `json:"platform,omitempty"`
+}
+
+// Entry maps the issues of a type to a link. Empty Category and Platform
+// match any category and platform.
+type Entry struct {
+	Category  string `json:"category"`
+	IssueType string `json:"issue_type"`
+	Platform  string `json:"platform"`
+	Title     string `json:"title"`
+	URL       string `json:"url"`
+}
+
+// platformOrder ranks the detected technologies whose documentation explains
+// a fix best: content and shop systems, where site owners edit pages, come
+// before the frameworks they may run on
+var platformOrder = []string{
+	"Shopify", "Magento", "WooCommerce", "WordPress", "Drupal", "Joomla",
+	"Next.js", "Gatsby",
+}
+
+type key struct {
+	category, issueType, platform string
+}
+
+// Resolver finds the link of an issue
+type Resolver struct {
+	links map[key]Link
+}
+
+// NewResolver creates a resolver from sets of entries. Entries of later sets
+// replace those of earlier sets with the same category, issue type and
+// platform.
+func NewResolver(sets ...[]Entry) *Resolver {
+	r := &Resolver{links: make(map[key]Link)}
+	for _, entries := range sets {
+		for _, entry := range entries {
+			r.links[key{entry.Category, entry.IssueType, entry.Platform}] = Link{
+				Title:    entry.Title,
+				URL:      entry.URL,
+				Platform: entry.Platform,
+			}
+		}
+	}
+	return r
+}
+
+// Resolve returns the link of an issue on a site built with the given
+// platforms, most relevant first. Links for a platform come before generic
+// ones and links for the category before those for any category. It
+// returns nil when the issue type has no documentation.
+func (r *Resolver) Resolve(category, issueType string, platforms []string) *Link {
+	if issueType == "" {
+		return nil
+	}
+	candidates := make([]string, 0, len(platforms)+1)
+	candidates = append(candidates, platforms...)
+	for _, platform := range append(candidates, "") {
+		for _, cat := range []string{category, ""} {
+			if link, ok := r.links[key{cat, issueType, platform}]; ok {
+				return &link
+			}
+		}
+	}
+	return nil
+}
+
+// Platforms orders the names of detected technologies by how well their
+// documentation explains fixes. Technologies without a rank keep their
+// order after the ranked ones.
+func Platforms(technologies []string) []string {
+	detected := make(map[string]bool, len(technologies))
+	for _, name := range technologies {
+		detected[name] = true
+	}
+
+	platforms := make([]string, 0, len(technologies))
+	ranked := make(map[string]bool, len(platformOrder))
+	for _, name := range platformOrder {
+		ranked[name] = true
+		if detected[name] {
+			platforms = append(platforms, name)
+		}
+	}
+	for _, name := range technologies {
+		if !ranked[name] {
+			platforms = append(platforms, name)
+			ranked[name] = true
+		}
+	}
+	return platforms
+}
@@ -127,6 +127,16 @@ type AuditResult struct {
 	Recommendations   []string                 `json:"recommendations"`
 }
 
+// StatusError is a non-200 response of the Lighthouse API
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API returned non-200 status: %d, body: %s", e.Code, e.Body)
+}
+
 // Client represents a Lighthouse API client
 type Client struct {
 	baseURL     string
@@ -374,13 +384,14 @@ func (c *Client) doRequest(ctx context.Context, method, url string, body []byte)
 			break // Success or client error
 		}
 
-		// Handle server errors or network issues
-		if resp != nil {
-			resp.Body.Close()
-		}
-
-		// Exponential backoff
+		// Handle server errors or network issues; the last response is
+		// kept to report its status
 		if attempt < c.retries {
+			if resp != nil {
+				resp.Body.Close()
+			}
+
+			// Exponential backoff
 			backoff := time.Duration(1<<uint(attempt)) * time.Second
 			select {
 			case <-ctx.Done():
@@ -405,7 +416,7 @@ func (c *Client) doRequest(ctx context.Context, method, url string, body []byte)
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned non-200 status: %d, body: %s", resp.StatusCode, string(respBody))
+		return nil, &StatusError{Code: resp.StatusCode, Body: string(respBody)}
 	}
 
 	return respBody, nil
@@ -413,6 +424,12 @@ func (c *Client) doRequest(ctx context.Context, method, url string, body []byte)
 
 // processLighthouseResponse processes the raw Lighthouse API response
 func (c *Client) processLighthouseResponse(data []byte) (*AuditResult, error) {
+	return ParseReport(data)
+}
+
+// ParseReport parses a Lighthouse JSON report, either as saved by the
+// Lighthouse CLI and DevTools or wrapped in a PageSpeed Insights response
+func ParseReport(data []byte) (*AuditResult, error) {
 	var rawResponse map[string]interface{}
 	if err := json.Unmarshal(data, &rawResponse); err != nil {
 		return nil, fmt.Errorf("failed to parse API response: %w", err)
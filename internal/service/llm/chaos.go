@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/chaos"
+)
+
+// ChaosProvider wraps a provider and injects the "llm" chaos fault into its
+// calls, simulating slow or failing LLM APIs such as upstream HTTP 500s
+type ChaosProvider struct {
+	Provider
+}
+
+// NewChaosProvider creates a provider wrapper with fault injection
+func NewChaosProvider(provider Provider) *ChaosProvider {
+	return &ChaosProvider{Provider: provider}
+}
+
+// GenerateContent injects the LLM fault, then generates content
+func (p *ChaosProvider) GenerateContent(ctx context.Context, request *ContentRequest) (*ContentResponse, error) {
+	if err := chaos.Inject(ctx, chaos.TargetLLM); err != nil {
+		return nil, err
+	}
+	return p.Provider.GenerateContent(ctx, request)
+}
+
+// GenerateContentWithProgress injects the LLM fault, then generates content
+func (p *ChaosProvider) GenerateContentWithProgress(ctx context.Context, request *ContentRequest, progressCb ProgressCallback) (*ContentResponse, error) {
+	if err := chaos.Inject(ctx, chaos.TargetLLM); err != nil {
+		return nil, err
+	}
+	return p.Provider.GenerateContentWithProgress(ctx, request, progressCb)
+}
+
+// GenerateHTML injects the LLM fault, then generates HTML
+func (p *ChaosProvider) GenerateHTML(ctx context.Context, original string, improved *ContentResponse) (string, error) {
+	if err := chaos.Inject(ctx, chaos.TargetLLM); err != nil {
+		return "", err
+	}
+	return p.Provider.GenerateHTML(ctx, original, improved)
+}
+
+// GenerateOutline injects the LLM fault, then generates an outline
+func (p *ChaosProvider) GenerateOutline(ctx context.Context, request *OutlineRequest) (*OutlineResponse, error) {
+	if err := chaos.Inject(ctx, chaos.TargetLLM); err != nil {
+		return nil, err
+	}
+	return p.Provider.GenerateOutline(ctx, request)
+}
+
+// PolishStatement injects the LLM fault, then polishes a statement
+func (p *ChaosProvider) PolishStatement(ctx context.Context, request *StatementRequest) (*StatementResponse, error) {
+	if err := chaos.Inject(ctx, chaos.TargetLLM); err != nil {
+		return nil, err
+	}
+	return p.Provider.PolishStatement(ctx, request)
+}
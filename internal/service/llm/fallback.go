@@ -0,0 +1,94 @@
+package llm
+
+import (
+	"context"
+)
+
+// providerChain returns the provider of a request followed by the other
+// providers of the fallback chain, in chain order. Chain entries that are
+// not registered are skipped.
+func (s *Service) providerChain(name string) ([]Provider, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if name == "" {
+		name = s.defaultProvider
+	}
+
+	primary, exists := s.providers[name]
+	if !exists {
+		return nil, ErrInvalidProvider
+	}
+
+	chain := []Provider{primary}
+	for _, fallback := range s.fallbackChain {
+		if fallback == name {
+			continue
+		}
+		if provider, ok := s.providers[fallback]; ok {
+			chain = append(chain, provider)
+		}
+	}
+	return chain, nil
+}
+
+// withFallback calls generate with the providers of the chain until one
+// succeeds, and returns that provider and the names of all providers tried.
+// A provider that fails or runs out of its time falls back to the next one;
+// cancellation or the deadline of the request itself stops the chain.
+func (s *Service) withFallback(ctx context.Context, providerName string,
+	generate func(ctx context.Context, provider Provider) error) (Provider, []string, error) {
+
+	chain, err := s.providerChain(providerName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	params := ParamsFromContext(ctx)
+	tried := make([]string, 0, len(chain))
+	var lastErr error
+
+	for i, provider := range chain {
+		attemptCtx := ctx
+		if i > 0 {
+			s.logger.Info("Falling back to the next LLM provider",
+				"failed", tried[i-1],
+				"provider", provider.GetName(),
+				"error", lastErr)
+			attemptCtx = fallbackParams(ctx, provider, params)
+		}
+
+		cancel := func() {}
+		if s.providerTimeout > 0 && len(chain) > 1 {
+			attemptCtx, cancel = context.WithTimeout(attemptCtx, s.providerTimeout)
+		}
+		lastErr = generate(attemptCtx, provider)
+		cancel()
+
+		tried = append(tried, provider.GetName())
+		if lastErr == nil {
+			return provider, tried, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return nil, tried, lastErr
+}
+
+// fallbackParams adapts the generation parameters of a request to a fallback
+// provider. The requested model belongs to the primary provider, so the
+// fallback uses its own default model, and parameters outside its
+// capabilities are dropped.
+func fallbackParams(ctx context.Context, provider Provider, params GenerationParams) context.Context {
+	if params.IsZero() {
+		return ctx
+	}
+
+	params.Model = ""
+	if provider.Capabilities().Validate(params) != nil {
+		params = GenerationParams{}
+	}
+	return context.WithValue(ctx, paramsContextKey{}, params)
+}
@@ -2,7 +2,6 @@ package llm
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -11,8 +10,9 @@ import (
 	"sync"
 	"time"
 
-	"github.com/go-redis/redis/v8"
 	"golang.org/x/time/rate"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/cache"
 )
 
 // Logger interface for service logging
@@ -55,7 +55,7 @@ type ProgressCallback func(progress float64, message string)
 type Service struct {
 	providers       map[string]Provider
 	defaultProvider string
-	redisClient     *redis.Client
+	cache           cache.Cache
 	limiter         *rate.Limiter
 	cacheTTL        time.Duration
 	maxRetries      int
@@ -63,12 +63,14 @@ type Service struct {
 	mutex           sync.RWMutex
 	logger          Logger
 	defaultTimeout  time.Duration
+	fallbackChain   []string
+	providerTimeout time.Duration
 }
 
 // ServiceOptions contains configuration for the LLM service
 type ServiceOptions struct {
 	DefaultProvider string
-	RedisClient     *redis.Client
+	Cache           cache.Cache
 	RateLimit       rate.Limit
 	RateBurst       int
 	CacheTTL        time.Duration
@@ -76,6 +78,12 @@ type ServiceOptions struct {
 	RetryDelay      time.Duration
 	Logger          Logger
 	DefaultTimeout  time.Duration
+	// FallbackChain lists the providers tried in order when the requested
+	// one fails, e.g. gemini, openai, local
+	FallbackChain []string
+	// ProviderTimeout bounds each provider of a chain so that a hanging
+	// provider leaves time for the next one; zero disables it
+	ProviderTimeout time.Duration
 }
 
 // NewService creates a new LLM service with the specified options
@@ -106,13 +114,15 @@ func NewService(opts ServiceOptions) *Service {
 	return &Service{
 		providers:       make(map[string]Provider),
 		defaultProvider: opts.DefaultProvider,
-		redisClient:     opts.RedisClient,
+		cache:           opts.Cache,
 		limiter:         rate.NewLimiter(opts.RateLimit, opts.RateBurst),
 		cacheTTL:        opts.CacheTTL,
 		maxRetries:      opts.MaxRetries,
 		retryDelay:      opts.RetryDelay,
 		logger:          opts.Logger,
 		defaultTimeout:  opts.DefaultTimeout,
+		fallbackChain:   opts.FallbackChain,
+		providerTimeout: opts.ProviderTimeout,
 	}
 }
 
@@ -127,9 +137,18 @@ type Provider interface {
 	// GenerateHTML generates HTML code for the improved content
 	GenerateHTML(ctx context.Context, original string, improved *ContentResponse) (string, error)
 
+	// GenerateOutline suggests a page outline covering missing topics
+	GenerateOutline(ctx context.Context, request *OutlineRequest) (*OutlineResponse, error)
+
+	// PolishStatement rewords an accessibility statement keeping its facts
+	PolishStatement(ctx context.Context, request *StatementRequest) (*StatementResponse, error)
+
 	// GetName returns the name of the provider
 	GetName() string
 
+	// Capabilities returns the models and parameter ranges the provider supports
+	Capabilities() Capabilities
+
 	// Close performs any necessary cleanup
 	Close() error
 }
@@ -166,8 +185,9 @@ func (s *Service) GetProvider(name string) (Provider, error) {
 	return provider, nil
 }
 
-// generateCacheKey creates a cache key from the request
-func (s *Service) generateCacheKey(request *ContentRequest, operation string) string {
+// generateCacheKey creates a cache key from the request and the generation
+// parameters of the context
+func (s *Service) generateCacheKey(ctx context.Context, request *ContentRequest, operation string) string {
 	// Add language to the cache key if specified
 	langPart := ""
 	if request.Language != "" && request.Language != "en" {
@@ -180,41 +200,34 @@ func (s *Service) generateCacheKey(request *ContentRequest, operation string) st
 		audiencePart = ":" + request.TargetAudience
 	}
 
-	return fmt.Sprintf("llm:%s:%s%s%s", operation, request.URL, langPart, audiencePart)
+	return fmt.Sprintf("llm:%s:%s%s%s%s", operation, request.URL, langPart, audiencePart,
+		ParamsFromContext(ctx).cacheSuffix())
 }
 
-// getFromCache retrieves a response from Redis cache
+// getFromCache retrieves a response from the cache
 func (s *Service) getFromCache(ctx context.Context, key string) (*ContentResponse, error) {
-	if s.redisClient == nil {
-		return nil, ErrCacheMiss
-	}
-
-	data, err := s.redisClient.Get(ctx, key).Result()
-	if err != nil {
+	if s.cache == nil {
 		return nil, ErrCacheMiss
 	}
 
 	var response ContentResponse
-	if err := json.Unmarshal([]byte(data), &response); err != nil {
-		s.logger.Error("Failed to unmarshal cached response", "error", err, "key", key)
+	if err := s.cache.Get(key, &response); err != nil {
+		if err != cache.ErrMiss {
+			s.logger.Error("Failed to read cached response", "error", err, "key", key)
+		}
 		return nil, ErrCacheMiss
 	}
 
 	return &response, nil
 }
 
-// saveToCache saves a response to Redis cache
+// saveToCache saves a response to the cache
 func (s *Service) saveToCache(ctx context.Context, key string, response *ContentResponse) error {
-	if s.redisClient == nil {
+	if s.cache == nil {
 		return nil
 	}
 
-	data, err := json.Marshal(response)
-	if err != nil {
-		return err
-	}
-
-	return s.redisClient.Set(ctx, key, data, s.cacheTTL).Err()
+	return s.cache.Set(key, response, s.cacheTTL)
 }
 
 // GenerateContent generates improved content with caching, rate limiting and retries
@@ -229,8 +242,8 @@ func (s *Service) GenerateContent(ctx context.Context, request *ContentRequest,
 	}
 
 	// Try to get from cache first
-	cacheKey := s.generateCacheKey(request, "content")
-	if s.redisClient != nil {
+	cacheKey := s.generateCacheKey(ctx, request, "content")
+	if s.cache != nil {
 		cachedResponse, err := s.getFromCache(ctx, cacheKey)
 		if err == nil {
 			// Cache hit
@@ -251,71 +264,71 @@ func (s *Service) GenerateContent(ctx context.Context, request *ContentRequest,
 		return nil, ErrRateLimitExceeded
 	}
 
-	// Get provider
-	provider, err := s.GetProvider(providerName)
-	if err != nil {
-		return nil, err
-	}
-
-	// Execute with retries
+	// Execute with retries, falling back along the provider chain
 	var response *ContentResponse
-	var lastErr error
-
-	for retry := 0; retry <= s.maxRetries; retry++ {
-		if retry > 0 {
-			// Log retry attempt
-			s.logger.Info("Retrying LLM API request",
-				"attempt", retry,
-				"provider", provider.GetName(),
-				"url", request.URL)
-
-			// Wait before retry with exponential backoff
-			select {
-			case <-time.After(s.retryDelay * time.Duration(1<<uint(retry-1))):
-				// Continue after delay
-			case <-ctx.Done():
-				switch ctx.Err() {
-				case context.Canceled:
-					return nil, ErrCancelled
-				case context.DeadlineExceeded:
-					return nil, ErrTimeout
-				default:
-					return nil, ctx.Err()
+	provider, tried, err := s.withFallback(ctx, providerName, func(ctx context.Context, provider Provider) error {
+		var lastErr error
+		for retry := 0; retry <= s.maxRetries; retry++ {
+			if retry > 0 {
+				// Log retry attempt
+				s.logger.Info("Retrying LLM API request",
+					"attempt", retry,
+					"provider", provider.GetName(),
+					"url", request.URL)
+
+				// Wait before retry with exponential backoff
+				select {
+				case <-time.After(s.retryDelay * time.Duration(1<<uint(retry-1))):
+					// Continue after delay
+				case <-ctx.Done():
+					switch ctx.Err() {
+					case context.Canceled:
+						return ErrCancelled
+					case context.DeadlineExceeded:
+						return ErrTimeout
+					default:
+						return ctx.Err()
+					}
 				}
 			}
-		}
-
-		// Generate content
-		response, lastErr = provider.GenerateContent(ctx, request)
-		if lastErr == nil {
-			break
-		}
 
-		// Check if we should continue retrying
-		if errors.Is(lastErr, context.Canceled) {
-			return nil, ErrCancelled
-		} else if errors.Is(lastErr, context.DeadlineExceeded) {
-			return nil, ErrTimeout
-		}
+			// Generate content
+			response, lastErr = provider.GenerateContent(ctx, request)
+			if lastErr == nil {
+				return nil
+			}
 
-		// Log error
-		s.logger.Error("LLM API request failed",
-			"error", lastErr,
-			"provider", provider.GetName(),
-			"retry", retry)
-	}
+			// Check if we should continue retrying
+			if errors.Is(lastErr, context.Canceled) {
+				return ErrCancelled
+			} else if errors.Is(lastErr, context.DeadlineExceeded) {
+				return ErrTimeout
+			}
 
-	if lastErr != nil {
-		return nil, fmt.Errorf("%w: %v", ErrAPIRequestFailed, lastErr)
+			// Log error
+			s.logger.Error("LLM API request failed",
+				"error", lastErr,
+				"provider", provider.GetName(),
+				"retry", retry)
+		}
+		return fmt.Errorf("%w: %v", ErrAPIRequestFailed, lastErr)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Set metadata
 	response.ProviderUsed = provider.GetName()
+	response.ProvidersTried = tried
+	if len(tried) == 1 {
+		// Fallback providers generate with their default model
+		response.Model = ParamsFromContext(ctx).Model
+	}
 	response.ProcessingTime = time.Since(startTime)
 	response.CachedResult = false
 
 	// Cache the result
-	if s.redisClient != nil {
+	if s.cache != nil {
 		if err := s.saveToCache(ctx, cacheKey, response); err != nil {
 			s.logger.Error("Failed to cache LLM response", "error", err)
 		}
@@ -343,8 +356,8 @@ func (s *Service) GenerateContentWithProgress(ctx context.Context, request *Cont
 	}
 
 	// Try to get from cache first
-	cacheKey := s.generateCacheKey(request, "content")
-	if s.redisClient != nil {
+	cacheKey := s.generateCacheKey(ctx, request, "content")
+	if s.cache != nil {
 		cachedResponse, err := s.getFromCache(ctx, cacheKey)
 		if err == nil {
 			// Cache hit
@@ -375,32 +388,39 @@ func (s *Service) GenerateContentWithProgress(ctx context.Context, request *Cont
 		return nil, ErrRateLimitExceeded
 	}
 
-	// Get provider
-	provider, err := s.GetProvider(providerName)
-	if err != nil {
-		return nil, err
-	}
+	// Generate content with progress, falling back along the provider chain
+	var response *ContentResponse
+	provider, tried, err := s.withFallback(ctx, providerName, func(ctx context.Context, provider Provider) error {
+		var err error
+		response, err = provider.GenerateContentWithProgress(ctx, request, progressCb)
+		if err != nil {
+			// Check for specific errors
+			if errors.Is(err, context.Canceled) {
+				return ErrCancelled
+			} else if errors.Is(err, context.DeadlineExceeded) {
+				return ErrTimeout
+			}
 
-	// Generate content with progress
-	response, err := provider.GenerateContentWithProgress(ctx, request, progressCb)
-	if err != nil {
-		// Check for specific errors
-		if errors.Is(err, context.Canceled) {
-			return nil, ErrCancelled
-		} else if errors.Is(err, context.DeadlineExceeded) {
-			return nil, ErrTimeout
+			return fmt.Errorf("%w: %v", ErrAPIRequestFailed, err)
 		}
-
-		return nil, fmt.Errorf("%w: %v", ErrAPIRequestFailed, err)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Set metadata
 	response.ProviderUsed = provider.GetName()
+	response.ProvidersTried = tried
+	if len(tried) == 1 {
+		// Fallback providers generate with their default model
+		response.Model = ParamsFromContext(ctx).Model
+	}
 	response.ProcessingTime = time.Since(startTime)
 	response.CachedResult = false
 
 	// Cache the result
-	if s.redisClient != nil {
+	if s.cache != nil {
 		if err := s.saveToCache(ctx, cacheKey, response); err != nil {
 			s.logger.Error("Failed to cache LLM response", "error", err)
 		}
@@ -432,10 +452,10 @@ func (s *Service) GenerateHTML(ctx context.Context, request *ContentRequest,
 	}
 
 	// Try to get from cache first
-	cacheKey := s.generateCacheKey(request, "html")
-	if s.redisClient != nil {
-		cachedHTML, err := s.redisClient.Get(ctx, cacheKey).Result()
-		if err == nil && cachedHTML != "" {
+	cacheKey := s.generateCacheKey(ctx, request, "html")
+	if s.cache != nil {
+		var cachedHTML string
+		if err := s.cache.Get(cacheKey, &cachedHTML); err == nil && cachedHTML != "" {
 			s.logger.Debug("Cache hit for HTML generation", "url", request.URL)
 			return cachedHTML, nil
 		}
@@ -447,66 +467,61 @@ func (s *Service) GenerateHTML(ctx context.Context, request *ContentRequest,
 		return "", ErrRateLimitExceeded
 	}
 
-	// Get provider
-	provider, err := s.GetProvider(providerName)
-	if err != nil {
-		return "", err
-	}
-
-	// Generate HTML with retries
+	// Generate HTML with retries, falling back along the provider chain
 	var html string
-	var lastErr error
-
-	for retry := 0; retry <= s.maxRetries; retry++ {
-		if retry > 0 {
-			// Log retry attempt
-			s.logger.Info("Retrying HTML generation",
-				"attempt", retry,
-				"provider", provider.GetName())
-
-			// Wait before retry with exponential backoff
-			select {
-			case <-time.After(s.retryDelay * time.Duration(1<<uint(retry-1))):
-				// Continue after delay
-			case <-ctx.Done():
-				switch ctx.Err() {
-				case context.Canceled:
-					return "", ErrCancelled
-				case context.DeadlineExceeded:
-					return "", ErrTimeout
-				default:
-					return "", ctx.Err()
+	provider, _, err := s.withFallback(ctx, providerName, func(ctx context.Context, provider Provider) error {
+		var lastErr error
+		for retry := 0; retry <= s.maxRetries; retry++ {
+			if retry > 0 {
+				// Log retry attempt
+				s.logger.Info("Retrying HTML generation",
+					"attempt", retry,
+					"provider", provider.GetName())
+
+				// Wait before retry with exponential backoff
+				select {
+				case <-time.After(s.retryDelay * time.Duration(1<<uint(retry-1))):
+					// Continue after delay
+				case <-ctx.Done():
+					switch ctx.Err() {
+					case context.Canceled:
+						return ErrCancelled
+					case context.DeadlineExceeded:
+						return ErrTimeout
+					default:
+						return ctx.Err()
+					}
 				}
 			}
-		}
-
-		// Generate HTML
-		html, lastErr = provider.GenerateHTML(ctx, request.Content, improved)
-		if lastErr == nil {
-			break
-		}
 
-		// Check if we should continue retrying
-		if errors.Is(lastErr, context.Canceled) {
-			return "", ErrCancelled
-		} else if errors.Is(lastErr, context.DeadlineExceeded) {
-			return "", ErrTimeout
-		}
+			// Generate HTML
+			html, lastErr = provider.GenerateHTML(ctx, request.Content, improved)
+			if lastErr == nil {
+				return nil
+			}
 
-		// Log error
-		s.logger.Error("HTML generation failed",
-			"error", lastErr,
-			"provider", provider.GetName(),
-			"retry", retry)
-	}
+			// Check if we should continue retrying
+			if errors.Is(lastErr, context.Canceled) {
+				return ErrCancelled
+			} else if errors.Is(lastErr, context.DeadlineExceeded) {
+				return ErrTimeout
+			}
 
-	if lastErr != nil {
-		return "", fmt.Errorf("%w: %v", ErrAPIRequestFailed, lastErr)
+			// Log error
+			s.logger.Error("HTML generation failed",
+				"error", lastErr,
+				"provider", provider.GetName(),
+				"retry", retry)
+		}
+		return fmt.Errorf("%w: %v", ErrAPIRequestFailed, lastErr)
+	})
+	if err != nil {
+		return "", err
 	}
 
 	// Cache the result
-	if s.redisClient != nil && html != "" {
-		if err := s.redisClient.Set(ctx, cacheKey, html, s.cacheTTL).Err(); err != nil {
+	if s.cache != nil && html != "" {
+		if err := s.cache.Set(cacheKey, html, s.cacheTTL); err != nil {
 			s.logger.Error("Failed to cache HTML", "error", err)
 		}
 	}
@@ -575,6 +590,24 @@ func (s *Service) GetAvailableProviders() []string {
 	return providers
 }
 
+// Capabilities returns the capabilities of a provider, using the default if name is empty
+func (s *Service) Capabilities(name string) (Capabilities, error) {
+	provider, err := s.GetProvider(name)
+	if err != nil {
+		return Capabilities{}, err
+	}
+	return provider.Capabilities(), nil
+}
+
+// ValidateParams checks generation parameters against the capabilities of a provider
+func (s *Service) ValidateParams(name string, params GenerationParams) error {
+	capabilities, err := s.Capabilities(name)
+	if err != nil {
+		return err
+	}
+	return capabilities.Validate(params)
+}
+
 // Close closes all providers
 func (s *Service) Close() error {
 	s.mutex.Lock()
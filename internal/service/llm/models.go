@@ -22,6 +22,8 @@ type ContentResponse struct {
 	Content        string        `json:"improved_content"`          // Improved content
 	HTML           string        `json:"html,omitempty"`            // Optional HTML representation
 	ProviderUsed   string        `json:"provider_used,omitempty"`   // Provider that generated the content
+	Model          string        `json:"model,omitempty"`           // Model requested from the provider, empty for its default
+	ProvidersTried []string      `json:"providers_tried,omitempty"` // Providers of the fallback chain tried, in order
 	CachedResult   bool          `json:"cached_result"`             // Whether this came from cache
 	ProcessingTime time.Duration `json:"processing_time,omitempty"` // How long it took to generate
 }
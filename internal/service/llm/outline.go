@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OutlineRequest asks for a content outline that covers the topics a page
+// is missing compared to competitor pages for the same keyword
+type OutlineRequest struct {
+	URL                string   `json:"url"`
+	Keyword            string   `json:"keyword"`
+	Title              string   `json:"title"`
+	Headings           []string `json:"headings,omitempty"`
+	MissingTopics      []string `json:"missing_topics"`
+	CompetitorHeadings []string `json:"competitor_headings,omitempty"`
+	Language           string   `json:"language,omitempty"`
+}
+
+// OutlineSection is one section of a suggested outline
+type OutlineSection struct {
+	Heading string   `json:"heading"`
+	Points  []string `json:"points"`
+}
+
+// OutlineResponse is a suggested page outline
+type OutlineResponse struct {
+	Title        string           `json:"title"`
+	Sections     []OutlineSection `json:"sections"`
+	ProviderUsed string           `json:"provider_used,omitempty"`
+	CachedResult bool             `json:"cached_result"`
+}
+
+// ParseOutline parses an outline from a model response, tolerating markdown
+// code fences and text around the JSON object
+func ParseOutline(text string) (*OutlineResponse, error) {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("%w: no JSON object in outline response", ErrResponseProcessing)
+	}
+
+	var outline OutlineResponse
+	if err := json.Unmarshal([]byte(text[start:end+1]), &outline); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrResponseProcessing, err)
+	}
+	if len(outline.Sections) == 0 {
+		return nil, fmt.Errorf("%w: outline has no sections", ErrResponseProcessing)
+	}
+	return &outline, nil
+}
+
+// outlineCacheKey creates a cache key from the full outline request and the
+// generation parameters of the context
+func outlineCacheKey(ctx context.Context, request *OutlineRequest) string {
+	data, _ := json.Marshal(request)
+	sum := sha256.Sum256(data)
+	return "llm:outline:" + hex.EncodeToString(sum[:16]) + ParamsFromContext(ctx).cacheSuffix()
+}
+
+// GenerateOutline generates an outline suggestion with caching, rate limiting and retries
+func (s *Service) GenerateOutline(ctx context.Context, request *OutlineRequest, providerName string) (*OutlineResponse, error) {
+	var cancel context.CancelFunc
+	if _, ok := ctx.Deadline(); !ok {
+		ctx, cancel = context.WithTimeout(ctx, s.defaultTimeout)
+		defer cancel()
+	}
+
+	cacheKey := outlineCacheKey(ctx, request)
+	if s.cache != nil {
+		var cached OutlineResponse
+		if err := s.cache.Get(cacheKey, &cached); err == nil {
+			cached.CachedResult = true
+			return &cached, nil
+		}
+	}
+
+	if err := s.limiter.Wait(ctx); err != nil {
+		s.logger.Error("Rate limit exceeded for outline generation", "error", err)
+		return nil, ErrRateLimitExceeded
+	}
+
+	var outline *OutlineResponse
+	provider, _, err := s.withFallback(ctx, providerName, func(ctx context.Context, provider Provider) error {
+		var lastErr error
+		for retry := 0; retry <= s.maxRetries; retry++ {
+			if retry > 0 {
+				select {
+				case <-time.After(s.retryDelay * time.Duration(1<<uint(retry-1))):
+				case <-ctx.Done():
+					if ctx.Err() == context.Canceled {
+						return ErrCancelled
+					}
+					return ErrTimeout
+				}
+			}
+
+			outline, lastErr = provider.GenerateOutline(ctx, request)
+			if lastErr == nil {
+				return nil
+			}
+			if errors.Is(lastErr, context.Canceled) {
+				return ErrCancelled
+			} else if errors.Is(lastErr, context.DeadlineExceeded) {
+				return ErrTimeout
+			}
+
+			s.logger.Error("Outline generation failed",
+				"error", lastErr,
+				"provider", provider.GetName(),
+				"retry", retry)
+		}
+		return fmt.Errorf("%w: %v", ErrAPIRequestFailed, lastErr)
+	})
+	if err != nil {
+		return nil, err
+	}
+	outline.ProviderUsed = provider.GetName()
+
+	if s.cache != nil {
+		if err := s.cache.Set(cacheKey, outline, s.cacheTTL); err != nil {
+			s.logger.Error("Failed to cache outline", "error", err)
+		}
+	}
+
+	s.logger.Info("Generated outline successfully", "provider", provider.GetName(), "keyword", request.Keyword)
+	return outline, nil
+}
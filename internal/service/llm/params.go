@@ -0,0 +1,157 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidParams is returned for generation parameters a provider does not support
+var ErrInvalidParams = errors.New("invalid generation parameters")
+
+// GenerationParams tunes a single generation. Zero values keep the defaults
+// of the provider, so callers only set what they want to change.
+type GenerationParams struct {
+	Model       string   `json:"model,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+}
+
+// IsZero reports whether no parameter is set
+func (p GenerationParams) IsZero() bool {
+	return p.Model == "" && p.Temperature == nil && p.MaxTokens == 0
+}
+
+// Merge returns the parameters with unset values taken from defaults
+func (p GenerationParams) Merge(defaults GenerationParams) GenerationParams {
+	if p.Model == "" {
+		p.Model = defaults.Model
+	}
+	if p.Temperature == nil {
+		p.Temperature = defaults.Temperature
+	}
+	if p.MaxTokens == 0 {
+		p.MaxTokens = defaults.MaxTokens
+	}
+	return p
+}
+
+// ModelOr returns the requested model, or the fallback when none is set
+func (p GenerationParams) ModelOr(fallback string) string {
+	if p.Model != "" {
+		return p.Model
+	}
+	return fallback
+}
+
+// TemperatureOr returns the requested temperature, or the fallback when none
+// is set. Providers use different fallbacks for prose and code.
+func (p GenerationParams) TemperatureOr(fallback float64) float64 {
+	if p.Temperature != nil {
+		return *p.Temperature
+	}
+	return fallback
+}
+
+// MaxTokensOr returns the requested output token limit, or the fallback when none is set
+func (p GenerationParams) MaxTokensOr(fallback int) int {
+	if p.MaxTokens > 0 {
+		return p.MaxTokens
+	}
+	return fallback
+}
+
+// ModelInfo describes a model a provider can generate with
+type ModelInfo struct {
+	Name            string `json:"name"`
+	MaxOutputTokens int    `json:"max_output_tokens"`
+	Default         bool   `json:"default,omitempty"`
+}
+
+// Capabilities lists the models and parameter ranges a provider supports
+type Capabilities struct {
+	Provider       string      `json:"provider"`
+	Models         []ModelInfo `json:"models"`
+	MinTemperature float64     `json:"min_temperature"`
+	MaxTemperature float64     `json:"max_temperature"`
+}
+
+// Model returns a supported model by name
+func (c Capabilities) Model(name string) (ModelInfo, bool) {
+	for _, model := range c.Models {
+		if model.Name == name {
+			return model, true
+		}
+	}
+	return ModelInfo{}, false
+}
+
+// DefaultModel returns the model used when a request does not choose one
+func (c Capabilities) DefaultModel() ModelInfo {
+	for _, model := range c.Models {
+		if model.Default {
+			return model
+		}
+	}
+	if len(c.Models) > 0 {
+		return c.Models[0]
+	}
+	return ModelInfo{}
+}
+
+// Validate checks parameters against the capabilities
+func (c Capabilities) Validate(params GenerationParams) error {
+	model := c.DefaultModel()
+	if params.Model != "" {
+		var ok bool
+		if model, ok = c.Model(params.Model); !ok {
+			return fmt.Errorf("%w: model %q is not supported by %s", ErrInvalidParams, params.Model, c.Provider)
+		}
+	}
+	if t := params.Temperature; t != nil && (*t < c.MinTemperature || *t > c.MaxTemperature) {
+		return fmt.Errorf("%w: temperature must be between %g and %g for %s",
+			ErrInvalidParams, c.MinTemperature, c.MaxTemperature, c.Provider)
+	}
+	if params.MaxTokens < 0 {
+		return fmt.Errorf("%w: max_tokens must be positive", ErrInvalidParams)
+	}
+	if model.MaxOutputTokens > 0 && params.MaxTokens > model.MaxOutputTokens {
+		return fmt.Errorf("%w: max_tokens must be at most %d for %s",
+			ErrInvalidParams, model.MaxOutputTokens, model.Name)
+	}
+	return nil
+}
+
+type paramsContextKey struct{}
+
+// WithParams returns a context carrying the generation parameters of a request
+func WithParams(ctx context.Context, params GenerationParams) context.Context {
+	if params.IsZero() {
+		return ctx
+	}
+	return context.WithValue(ctx, paramsContextKey{}, params)
+}
+
+// ParamsFromContext returns the generation parameters of a context
+func ParamsFromContext(ctx context.Context) GenerationParams {
+	if ctx == nil {
+		return GenerationParams{}
+	}
+	params, _ := ctx.Value(paramsContextKey{}).(GenerationParams)
+	return params
+}
+
+// cacheSuffix distinguishes cached results generated with different parameters
+func (p GenerationParams) cacheSuffix() string {
+	if p.IsZero() {
+		return ""
+	}
+	suffix := ":" + p.Model
+	if p.Temperature != nil {
+		suffix += fmt.Sprintf(":t%g", *p.Temperature)
+	}
+	if p.MaxTokens > 0 {
+		suffix += fmt.Sprintf(":m%d", p.MaxTokens)
+	}
+	return suffix
+}
@@ -0,0 +1,54 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/llm"
+)
+
+// OutlinePrompt creates a prompt for an outline that closes the content gaps
+// of a page against its competitors
+func (g *Generator) OutlinePrompt(request *llm.OutlineRequest) string {
+	var sb strings.Builder
+
+	sb.WriteString("You are an expert SEO content strategist.\n\n")
+	sb.WriteString(fmt.Sprintf("The page %s targets the search keyword \"%s\".\n", request.URL, request.Keyword))
+	if request.Title != "" {
+		sb.WriteString(fmt.Sprintf("Current page title: \"%s\"\n", request.Title))
+	}
+
+	if len(request.Headings) > 0 {
+		sb.WriteString("\nCurrent page headings:\n")
+		for _, heading := range request.Headings {
+			sb.WriteString(fmt.Sprintf("- %s\n", heading))
+		}
+	}
+
+	sb.WriteString("\nTopics that competing pages for this keyword cover but this page does not:\n")
+	for _, topic := range request.MissingTopics {
+		sb.WriteString(fmt.Sprintf("- %s\n", topic))
+	}
+
+	if len(request.CompetitorHeadings) > 0 {
+		sb.WriteString("\nCompetitor sections without a counterpart on this page:\n")
+		for _, heading := range request.CompetitorHeadings {
+			sb.WriteString(fmt.Sprintf("- %s\n", heading))
+		}
+	}
+
+	if request.Language != "" {
+		sb.WriteString(fmt.Sprintf("\nWrite the outline in this language: %s\n", request.Language))
+	}
+
+	sb.WriteString("\nSuggest an improved outline for the page that:\n")
+	sb.WriteString("- Keeps the existing sections that are relevant\n")
+	sb.WriteString("- Adds sections covering the missing topics where they fit the search intent\n")
+	sb.WriteString("- Does not copy competitor headings word for word\n")
+	sb.WriteString("- Has 4 to 10 sections with 2 to 5 key points each\n\n")
+
+	sb.WriteString("Response format: JSON object {\"title\": \"...\", \"sections\": [{\"heading\": \"...\", \"points\": [\"...\"]}]}.\n")
+	sb.WriteString("Do not include any explanations, just return the JSON object.")
+
+	return sb.String()
+}
@@ -0,0 +1,37 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/llm"
+)
+
+// StatementPrompt creates a prompt that polishes the wording of a templated
+// accessibility statement while keeping its facts
+func (g *Generator) StatementPrompt(request *llm.StatementRequest) string {
+	var sb strings.Builder
+
+	sb.WriteString("You are an expert accessibility compliance writer.\n\n")
+	sb.WriteString(fmt.Sprintf("Below is a draft accessibility statement of %s for the site %s, one section per heading.\n", request.Organization, request.URL))
+	sb.WriteString("It will be published on a legal compliance page.\n\n")
+
+	for _, section := range request.Sections {
+		sb.WriteString(fmt.Sprintf("## %s\n%s\n\n", section.Heading, section.Text))
+	}
+
+	if request.Language != "" {
+		sb.WriteString(fmt.Sprintf("Write the statement in this language: %s\n\n", request.Language))
+	}
+
+	sb.WriteString("Improve the wording of each section so it reads clearly and professionally:\n")
+	sb.WriteString("- Keep every section, in the same order\n")
+	sb.WriteString("- Keep all facts: success criteria numbers, scores, counts, dates, URLs and contact details\n")
+	sb.WriteString("- Do not claim conformance the draft does not state, and do not add new issues or commitments\n")
+	sb.WriteString("- Keep lists of issues and dates as one item per line\n\n")
+
+	sb.WriteString("Response format: JSON object {\"sections\": [{\"heading\": \"...\", \"text\": \"...\"}]}.\n")
+	sb.WriteString("Do not include any explanations, just return the JSON object.")
+
+	return sb.String()
+}
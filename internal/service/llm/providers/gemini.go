@@ -66,18 +66,34 @@ func (p *GeminiProvider) GetName() string {
 	return "gemini"
 }
 
+// Capabilities implements the Provider interface
+func (p *GeminiProvider) Capabilities() llm.Capabilities {
+	models := []llm.ModelInfo{
+		{Name: "gemini-1.5-flash", MaxOutputTokens: 8192},
+		{Name: "gemini-1.5-pro", MaxOutputTokens: 8192},
+		{Name: "gemini-2.0-flash", MaxOutputTokens: 8192},
+	}
+	return llm.Capabilities{
+		Provider:       p.GetName(),
+		Models:         withDefaultModel(models, p.modelName),
+		MinTemperature: 0,
+		MaxTemperature: 2,
+	}
+}
+
 // GenerateContent implements the Provider interface
 func (p *GeminiProvider) GenerateContent(ctx context.Context, request *llm.ContentRequest) (*llm.ContentResponse, error) {
 	startTime := time.Now()
 
-	// Get the model
-	model := p.client.GenerativeModel(p.modelName)
+	// Get the model, applying the generation parameters of the request
+	params := llm.ParamsFromContext(ctx)
+	model := p.client.GenerativeModel(params.ModelOr(p.modelName))
 
 	// Configure the model settings
-	model.SetTemperature(0.7)
+	model.SetTemperature(float32(params.TemperatureOr(0.7)))
 	model.SetTopP(0.95)
 	model.SetTopK(40)
-	model.SetMaxOutputTokens(2048)
+	model.SetMaxOutputTokens(int32(params.MaxTokensOr(2048)))
 
 	// Generate prompt for content improvement
 	prompt := p.generator.GenerateContentPrompt(request)
@@ -177,14 +193,15 @@ func (p *GeminiProvider) GenerateContentWithProgress(ctx context.Context, reques
 		progressCb(10, "Initializing Gemini model")
 	}
 
-	// Get the model
-	model := p.client.GenerativeModel(p.modelName)
+	// Get the model, applying the generation parameters of the request
+	params := llm.ParamsFromContext(ctx)
+	model := p.client.GenerativeModel(params.ModelOr(p.modelName))
 
 	// Configure the model settings
-	model.SetTemperature(0.7)
+	model.SetTemperature(float32(params.TemperatureOr(0.7)))
 	model.SetTopP(0.95)
 	model.SetTopK(40)
-	model.SetMaxOutputTokens(2048)
+	model.SetMaxOutputTokens(int32(params.MaxTokensOr(2048)))
 
 	// Generate prompt
 	if progressCb != nil {
@@ -280,12 +297,13 @@ func (p *GeminiProvider) GenerateContentWithProgress(ctx context.Context, reques
 
 // GenerateHTML implements the Provider interface
 func (p *GeminiProvider) GenerateHTML(ctx context.Context, originalContent string, improved *llm.ContentResponse) (string, error) {
-	// Get the model
-	model := p.client.GenerativeModel(p.modelName)
+	// Get the model, applying the generation parameters of the request
+	params := llm.ParamsFromContext(ctx)
+	model := p.client.GenerativeModel(params.ModelOr(p.modelName))
 
 	// Configure the model for code generation
-	model.SetTemperature(0.2)
-	model.SetMaxOutputTokens(2048)
+	model.SetTemperature(float32(params.TemperatureOr(0.2)))
+	model.SetMaxOutputTokens(int32(params.MaxTokensOr(2048)))
 
 	// Generate prompt for HTML generation
 	prompt := p.generator.GenerateHTMLPrompt(originalContent, improved)
@@ -326,6 +344,68 @@ func (p *GeminiProvider) GenerateHTML(ctx context.Context, originalContent strin
 	return html, nil
 }
 
+// GenerateOutline implements the Provider interface
+func (p *GeminiProvider) GenerateOutline(ctx context.Context, request *llm.OutlineRequest) (*llm.OutlineResponse, error) {
+	params := llm.ParamsFromContext(ctx)
+	model := p.client.GenerativeModel(params.ModelOr(p.modelName))
+	model.SetTemperature(float32(params.TemperatureOr(0.4)))
+	model.SetMaxOutputTokens(int32(params.MaxTokensOr(2048)))
+	model.ResponseMIMEType = "application/json"
+
+	prompt := p.generator.OutlinePrompt(request)
+
+	p.logger.Debug("Sending outline prompt to Gemini", "prompt", prompt)
+
+	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
+	if err != nil {
+		p.logger.Error("Gemini outline generation error", "error", err)
+		return nil, fmt.Errorf("outline generation error with Gemini: %w", err)
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return nil, errors.New("outline not generated")
+	}
+
+	var responseText string
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if textPart, ok := part.(genai.Text); ok {
+			responseText += string(textPart)
+		}
+	}
+
+	return llm.ParseOutline(responseText)
+}
+
+// PolishStatement implements the Provider interface
+func (p *GeminiProvider) PolishStatement(ctx context.Context, request *llm.StatementRequest) (*llm.StatementResponse, error) {
+	params := llm.ParamsFromContext(ctx)
+	model := p.client.GenerativeModel(params.ModelOr(p.modelName))
+	model.SetTemperature(float32(params.TemperatureOr(0.3)))
+	model.SetMaxOutputTokens(int32(params.MaxTokensOr(4096)))
+	model.ResponseMIMEType = "application/json"
+
+	prompt := p.generator.StatementPrompt(request)
+
+	p.logger.Debug("Sending statement prompt to Gemini", "prompt", prompt)
+
+	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
+	if err != nil {
+		p.logger.Error("Gemini statement polishing error", "error", err)
+		return nil, fmt.Errorf("statement polishing error with Gemini: %w", err)
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return nil, errors.New("statement not generated")
+	}
+
+	var responseText string
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if textPart, ok := part.(genai.Text); ok {
+			responseText += string(textPart)
+		}
+	}
+
+	return llm.ParseStatement(responseText)
+}
+
 // Close closes the Gemini client
 func (p *GeminiProvider) Close() error {
 	if p.client != nil {
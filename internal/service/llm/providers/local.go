@@ -0,0 +1,53 @@
+package providers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/llm"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/llm/prompts"
+)
+
+// LocalProvider generates with a self-hosted model served behind an
+// OpenAI-compatible chat completions API, such as Ollama or vLLM
+type LocalProvider struct {
+	*OpenAIProvider
+}
+
+// NewLocalProvider creates a provider for the OpenAI-compatible API at baseURL,
+// e.g. http://localhost:11434/v1
+func NewLocalProvider(baseURL string, model string, logger llm.Logger) (*LocalProvider, error) {
+	if baseURL == "" {
+		return nil, errors.New("local LLM URL is required")
+	}
+	if model == "" {
+		return nil, errors.New("local LLM model is required")
+	}
+
+	if logger == nil {
+		logger = &llm.DefaultLogger{}
+	}
+
+	return &LocalProvider{
+		OpenAIProvider: &OpenAIProvider{
+			name:       "local",
+			url:        strings.TrimSuffix(baseURL, "/") + "/chat/completions",
+			model:      model,
+			httpClient: &http.Client{Timeout: defaultTimeout},
+			logger:     logger,
+			generator:  prompts.NewGenerator(),
+		},
+	}, nil
+}
+
+// Capabilities implements the Provider interface. Only the served model is
+// known, and its output limit depends on how it is served.
+func (p *LocalProvider) Capabilities() llm.Capabilities {
+	return llm.Capabilities{
+		Provider:       p.GetName(),
+		Models:         []llm.ModelInfo{{Name: p.model, Default: true}},
+		MinTemperature: 0,
+		MaxTemperature: 2,
+	}
+}
@@ -21,6 +21,8 @@ const (
 
 // OpenAIProvider implements the Provider interface for OpenAI
 type OpenAIProvider struct {
+	name       string
+	url        string
 	apiKey     string
 	model      string
 	httpClient *http.Client
@@ -64,7 +66,7 @@ type OpenAIResponse struct {
 
 // NewOpenAIProvider creates a new OpenAI provider
 func NewOpenAIProvider(apiKey string, model string, logger llm.Logger) (*OpenAIProvider, error) {
-	if apiKey == "" {
+	if apiKey == "" || apiKey == "your-openai-api-key" {
 		return nil, errors.New("OpenAI API key is required")
 	}
 
@@ -77,6 +79,8 @@ func NewOpenAIProvider(apiKey string, model string, logger llm.Logger) (*OpenAIP
 	}
 
 	return &OpenAIProvider{
+		name:       "openai",
+		url:        openAICompletionsURL,
 		apiKey:     apiKey,
 		model:      model,
 		httpClient: &http.Client{Timeout: defaultTimeout},
@@ -87,7 +91,22 @@ func NewOpenAIProvider(apiKey string, model string, logger llm.Logger) (*OpenAIP
 
 // GetName returns the provider name
 func (p *OpenAIProvider) GetName() string {
-	return "openai"
+	return p.name
+}
+
+// Capabilities implements the Provider interface
+func (p *OpenAIProvider) Capabilities() llm.Capabilities {
+	models := []llm.ModelInfo{
+		{Name: "gpt-4", MaxOutputTokens: 8192},
+		{Name: "gpt-4o", MaxOutputTokens: 16384},
+		{Name: "gpt-4o-mini", MaxOutputTokens: 16384},
+	}
+	return llm.Capabilities{
+		Provider:       p.GetName(),
+		Models:         withDefaultModel(models, p.model),
+		MinTemperature: 0,
+		MaxTemperature: 2,
+	}
 }
 
 // GenerateContent implements the Provider interface
@@ -106,10 +125,12 @@ func (p *OpenAIProvider) GenerateContent(ctx context.Context, request *llm.Conte
 		},
 	}
 
+	params := llm.ParamsFromContext(ctx)
 	apiRequest := OpenAIRequest{
-		Model:       p.model,
+		Model:       params.ModelOr(p.model),
 		Messages:    messages,
-		Temperature: 0.7,
+		Temperature: params.TemperatureOr(0.7),
+		MaxTokens:   params.MaxTokens,
 	}
 
 	apiResponse, err := p.makeRequest(ctx, apiRequest)
@@ -146,6 +167,26 @@ func (p *OpenAIProvider) GenerateContent(ctx context.Context, request *llm.Conte
 	}, nil
 }
 
+// GenerateContentWithProgress implements the Provider interface. The chat
+// completions API does not stream progress, so it is reported around the call.
+func (p *OpenAIProvider) GenerateContentWithProgress(ctx context.Context, request *llm.ContentRequest,
+	progressCb llm.ProgressCallback) (*llm.ContentResponse, error) {
+
+	if progressCb != nil {
+		progressCb(30, "Sending request to "+p.name)
+	}
+
+	response, err := p.GenerateContent(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if progressCb != nil {
+		progressCb(90, "Finalizing content response")
+	}
+	return response, nil
+}
+
 // GenerateHTML implements the Provider interface
 func (p *OpenAIProvider) GenerateHTML(ctx context.Context, originalContent string, improved *llm.ContentResponse) (string, error) {
 	// Generate prompt for HTML
@@ -162,10 +203,12 @@ func (p *OpenAIProvider) GenerateHTML(ctx context.Context, originalContent strin
 		},
 	}
 
+	params := llm.ParamsFromContext(ctx)
 	apiRequest := OpenAIRequest{
-		Model:       p.model,
+		Model:       params.ModelOr(p.model),
 		Messages:    messages,
-		Temperature: 0.3, // Lower temperature for more deterministic output
+		Temperature: params.TemperatureOr(0.3), // Lower temperature for more deterministic output
+		MaxTokens:   params.MaxTokens,
 	}
 
 	apiResponse, err := p.makeRequest(ctx, apiRequest)
@@ -185,6 +228,68 @@ func (p *OpenAIProvider) GenerateHTML(ctx context.Context, originalContent strin
 	return html, nil
 }
 
+// GenerateOutline implements the Provider interface
+func (p *OpenAIProvider) GenerateOutline(ctx context.Context, request *llm.OutlineRequest) (*llm.OutlineResponse, error) {
+	messages := []OpenAIMessage{
+		{
+			Role:    "system",
+			Content: "You are an expert SEO content strategist. Respond with JSON only.",
+		},
+		{
+			Role:    "user",
+			Content: p.generator.OutlinePrompt(request),
+		},
+	}
+
+	params := llm.ParamsFromContext(ctx)
+	apiResponse, err := p.makeRequest(ctx, OpenAIRequest{
+		Model:       params.ModelOr(p.model),
+		Messages:    messages,
+		Temperature: params.TemperatureOr(0.4),
+		MaxTokens:   params.MaxTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(apiResponse.Choices) == 0 {
+		return nil, errors.New("empty response from OpenAI")
+	}
+
+	return llm.ParseOutline(apiResponse.Choices[0].Message.Content)
+}
+
+// PolishStatement implements the Provider interface
+func (p *OpenAIProvider) PolishStatement(ctx context.Context, request *llm.StatementRequest) (*llm.StatementResponse, error) {
+	messages := []OpenAIMessage{
+		{
+			Role:    "system",
+			Content: "You are an expert accessibility compliance writer. Respond with JSON only.",
+		},
+		{
+			Role:    "user",
+			Content: p.generator.StatementPrompt(request),
+		},
+	}
+
+	params := llm.ParamsFromContext(ctx)
+	apiResponse, err := p.makeRequest(ctx, OpenAIRequest{
+		Model:       params.ModelOr(p.model),
+		Messages:    messages,
+		Temperature: params.TemperatureOr(0.3),
+		MaxTokens:   params.MaxTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(apiResponse.Choices) == 0 {
+		return nil, errors.New("empty response from OpenAI")
+	}
+
+	return llm.ParseStatement(apiResponse.Choices[0].Message.Content)
+}
+
 // makeRequest sends a request to the OpenAI API
 func (p *OpenAIProvider) makeRequest(ctx context.Context, request OpenAIRequest) (*OpenAIResponse, error) {
 	requestBody, err := json.Marshal(request)
@@ -192,13 +297,15 @@ func (p *OpenAIProvider) makeRequest(ctx context.Context, request OpenAIRequest)
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", openAICompletionsURL, bytes.NewBuffer(requestBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.url, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
 
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
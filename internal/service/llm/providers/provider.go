@@ -14,9 +14,30 @@ type Provider interface {
 	// GenerateHTML generates HTML code for the improved content
 	GenerateHTML(ctx context.Context, original string, improved *llm.ContentResponse) (string, error)
 
+	// GenerateOutline suggests a page outline covering missing topics
+	GenerateOutline(ctx context.Context, request *llm.OutlineRequest) (*llm.OutlineResponse, error)
+
+	// PolishStatement rewords an accessibility statement keeping its facts
+	PolishStatement(ctx context.Context, request *llm.StatementRequest) (*llm.StatementResponse, error)
+
 	// GetName returns the name of the provider
 	GetName() string
 
+	// Capabilities returns the models and parameter ranges the provider supports
+	Capabilities() llm.Capabilities
+
 	// Close closes any connections or resources used by the provider
 	Close() error
 }
+
+// withDefaultModel marks the configured model of a provider as the default,
+// adding it to the supported models when it is not one of the known ones
+func withDefaultModel(models []llm.ModelInfo, name string) []llm.ModelInfo {
+	for i := range models {
+		if models[i].Name == name {
+			models[i].Default = true
+			return models
+		}
+	}
+	return append(models, llm.ModelInfo{Name: name, Default: true})
+}
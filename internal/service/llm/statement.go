@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// StatementSection is one section of an accessibility statement
+type StatementSection struct {
+	Heading string `json:"heading"`
+	Text    string `json:"text"`
+}
+
+// StatementRequest asks to polish the wording of a templated accessibility
+// statement without changing its facts
+type StatementRequest struct {
+	URL          string             `json:"url"`
+	Organization string             `json:"organization"`
+	Sections     []StatementSection `json:"sections"`
+	Language     string             `json:"language,omitempty"`
+}
+
+// StatementResponse is a polished accessibility statement
+type StatementResponse struct {
+	Sections     []StatementSection `json:"sections"`
+	ProviderUsed string             `json:"provider_used,omitempty"`
+	CachedResult bool               `json:"cached_result"`
+}
+
+// ParseStatement parses a polished statement from a model response,
+// tolerating markdown code fences and text around the JSON object
+func ParseStatement(text string) (*StatementResponse, error) {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("%w: no JSON object in statement response", ErrResponseProcessing)
+	}
+
+	var statement StatementResponse
+	if err := json.Unmarshal([]byte(text[start:end+1]), &statement); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrResponseProcessing, err)
+	}
+	if len(statement.Sections) == 0 {
+		return nil, fmt.Errorf("%w: statement has no sections", ErrResponseProcessing)
+	}
+	return &statement, nil
+}
+
+// checkStatement rejects polished statements that dropped, added or
+// reordered sections, so the facts of each section stay in place
+func checkStatement(request *StatementRequest, statement *StatementResponse) error {
+	if len(statement.Sections) != len(request.Sections) {
+		return fmt.Errorf("%w: expected %d statement sections, got %d",
+			ErrResponseProcessing, len(request.Sections), len(statement.Sections))
+	}
+	for i, section := range statement.Sections {
+		if strings.TrimSpace(section.Text) == "" {
+			return fmt.Errorf("%w: statement section %d is empty", ErrResponseProcessing, i+1)
+		}
+	}
+	return nil
+}
+
+// statementCacheKey creates a cache key from the full statement request and
+// the generation parameters of the context
+func statementCacheKey(ctx context.Context, request *StatementRequest) string {
+	data, _ := json.Marshal(request)
+	sum := sha256.Sum256(data)
+	return "llm:statement:" + hex.EncodeToString(sum[:16]) + ParamsFromContext(ctx).cacheSuffix()
+}
+
+// PolishStatement polishes an accessibility statement with caching, rate
+// limiting and retries
+func (s *Service) PolishStatement(ctx context.Context, request *StatementRequest, providerName string) (*StatementResponse, error) {
+	var cancel context.CancelFunc
+	if _, ok := ctx.Deadline(); !ok {
+		ctx, cancel = context.WithTimeout(ctx, s.defaultTimeout)
+		defer cancel()
+	}
+
+	cacheKey := statementCacheKey(ctx, request)
+	if s.cache != nil {
+		var cached StatementResponse
+		if err := s.cache.Get(cacheKey, &cached); err == nil {
+			cached.CachedResult = true
+			return &cached, nil
+		}
+	}
+
+	if err := s.limiter.Wait(ctx); err != nil {
+		s.logger.Error("Rate limit exceeded for statement polishing", "error", err)
+		return nil, ErrRateLimitExceeded
+	}
+
+	var statement *StatementResponse
+	provider, _, err := s.withFallback(ctx, providerName, func(ctx context.Context, provider Provider) error {
+		var lastErr error
+		for retry := 0; retry <= s.maxRetries; retry++ {
+			if retry > 0 {
+				select {
+				case <-time.After(s.retryDelay * time.Duration(1<<uint(retry-1))):
+				case <-ctx.Done():
+					if ctx.Err() == context.Canceled {
+						return ErrCancelled
+					}
+					return ErrTimeout
+				}
+			}
+
+			statement, lastErr = provider.PolishStatement(ctx, request)
+			if lastErr == nil {
+				lastErr = checkStatement(request, statement)
+			}
+			if lastErr == nil {
+				return nil
+			}
+			if errors.Is(lastErr, context.Canceled) {
+				return ErrCancelled
+			} else if errors.Is(lastErr, context.DeadlineExceeded) {
+				return ErrTimeout
+			}
+
+			s.logger.Error("Statement polishing failed",
+				"error", lastErr,
+				"provider", provider.GetName(),
+				"retry", retry)
+		}
+		return fmt.Errorf("%w: %v", ErrAPIRequestFailed, lastErr)
+	})
+	if err != nil {
+		return nil, err
+	}
+	statement.ProviderUsed = provider.GetName()
+
+	if s.cache != nil {
+		if err := s.cache.Set(cacheKey, statement, s.cacheTTL); err != nil {
+			s.logger.Error("Failed to cache statement", "error", err)
+		}
+	}
+
+	s.logger.Info("Polished statement successfully", "provider", provider.GetName(), "url", request.URL)
+	return statement, nil
+}
@@ -32,6 +32,20 @@ var Models = map[string]ModelInfo{
 		Name:                  "gpt-3.5-turbo",
 		Provider:              "openai",
 	},
+	"gpt-4o": {
+		TokensPerPromptDollar: 1000.0 / 0.0025, // $0.0025 per 1K prompt tokens
+		TokensPerOutputDollar: 1000.0 / 0.01,   // $0.01 per 1K completion tokens
+		MaxContextTokens:      128000,
+		Name:                  "gpt-4o",
+		Provider:              "openai",
+	},
+	"gpt-4o-mini": {
+		TokensPerPromptDollar: 1000.0 / 0.00015, // $0.00015 per 1K prompt tokens
+		TokensPerOutputDollar: 1000.0 / 0.0006,  // $0.0006 per 1K completion tokens
+		MaxContextTokens:      128000,
+		Name:                  "gpt-4o-mini",
+		Provider:              "openai",
+	},
 	"gemini-1.5-flash": {
 		TokensPerPromptDollar: 1000.0 / 0.00035, // $0.00035 per 1K input tokens
 		TokensPerOutputDollar: 1000.0 / 0.00035, // $0.00035 per 1K output tokens
@@ -46,6 +60,13 @@ var Models = map[string]ModelInfo{
 		Name:                  "gemini-1.5-pro",
 		Provider:              "gemini",
 	},
+	"gemini-2.0-flash": {
+		TokensPerPromptDollar: 1000.0 / 0.0001, // $0.0001 per 1K input tokens
+		TokensPerOutputDollar: 1000.0 / 0.0004, // $0.0004 per 1K output tokens
+		MaxContextTokens:      1000000,
+		Name:                  "gemini-2.0-flash",
+		Provider:              "gemini",
+	},
 }
 
 // ModelInfo contains pricing information for a model
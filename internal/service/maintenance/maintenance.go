@@ -0,0 +1,118 @@
+// Package maintenance holds the maintenance mode shared by all API instances.
+// While it is enabled new analyses are rejected and running ones finish.
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// redisKey stores the current mode as JSON
+	redisKey = "maintenance:mode"
+	// cacheTTL is how long an instance trusts its copy of the mode
+	cacheTTL = 5 * time.Second
+	// defaultRetryAfter is suggested to clients when no end time is known
+	defaultRetryAfter = 15 * time.Minute
+)
+
+// DefaultMessage is shown when maintenance is enabled without a message
+const DefaultMessage = "The service is undergoing scheduled maintenance. Running analyses will finish; new analyses can be started again shortly."
+
+// Mode is the maintenance state
+type Mode struct {
+	Enabled   bool       `json:"enabled"`
+	Message   string     `json:"message,omitempty"`
+	StartedAt time.Time  `json:"started_at,omitempty"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"`
+	StartedBy string     `json:"started_by,omitempty"`
+}
+
+// UserMessage is the message shown to users
+func (m Mode) UserMessage() string {
+	if m.Message != "" {
+		return m.Message
+	}
+	return DefaultMessage
+}
+
+// RetryAfter is how long clients should wait before trying again
+func (m Mode) RetryAfter() time.Duration {
+	if m.EndsAt != nil {
+		if wait := time.Until(*m.EndsAt); wait > 0 {
+			return wait
+		}
+	}
+	return defaultRetryAfter
+}
+
+// Store keeps the mode in Redis so that toggling it on one instance applies
+// to all of them, with a short in-process cache for the hot path
+type Store struct {
+	client   *redis.Client
+	mu       sync.Mutex
+	cached   Mode
+	cachedAt time.Time
+}
+
+// NewStore creates a store. Without a Redis client the mode only applies to
+// this instance.
+func NewStore(client *redis.Client) *Store {
+	return &Store{client: client}
+}
+
+// Get returns the current mode. When Redis is unavailable the last known
+// mode is kept.
+func (s *Store) Get(ctx context.Context) Mode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client == nil || time.Since(s.cachedAt) < cacheTTL {
+		return s.cached
+	}
+
+	data, err := s.client.Get(ctx, redisKey).Bytes()
+	switch {
+	case err == redis.Nil:
+		s.cached = Mode{}
+	case err != nil:
+		log.Printf("Failed to read maintenance mode: %v", err)
+	default:
+		var mode Mode
+		if err := json.Unmarshal(data, &mode); err != nil {
+			log.Printf("Failed to decode maintenance mode: %v", err)
+		} else {
+			s.cached = mode
+		}
+	}
+	s.cachedAt = time.Now()
+	return s.cached
+}
+
+// Set replaces the mode
+func (s *Store) Set(ctx context.Context, mode Mode) error {
+	if s.client != nil {
+		if mode.Enabled {
+			data, err := json.Marshal(mode)
+			if err != nil {
+				return err
+			}
+			if err := s.client.Set(ctx, redisKey, data, 0).Err(); err != nil {
+				return err
+			}
+		} else if err := s.client.Del(ctx, redisKey).Err(); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.cached = mode
+	s.cachedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
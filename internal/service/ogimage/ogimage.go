@@ -0,0 +1,182 @@
+// Package ogimage renders branded Open Graph preview images for pages that
+// have none: the page title and domain next to a thumbnail of the page,
+// drawn as HTML and captured with the headless browser.
+package ogimage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// Size of the generated image, the size recommended for og:image
+const (
+	Width  = 1200
+	Height = 630
+)
+
+// DefaultTimeout bounds the rendering of one image
+const DefaultTimeout = 30 * time.Second
+
+// DefaultAccent is the brand color used when the page declares no theme-color
+const DefaultAccent = "#2563eb"
+
+// ErrNoTitle is returned for a card without a title
+var ErrNoTitle = errors.New("og image needs a title")
+
+// Card is the content of a preview image
+type Card struct {
+	Title       string
+	Description string
+	Domain      string
+	// Screenshot is a JPEG or PNG screenshot of the page, shown as a thumbnail
+	Screenshot []byte
+	// Accent is a CSS color, e.g. the page's theme-color
+	Accent string
+}
+
+// Options configure rendering
+type Options struct {
+	ChromePath string
+	Timeout    time.Duration
+}
+
+var cssColor = regexp.MustCompile(`^(#[0-9a-fA-F]{3,8}|[a-zA-Z]{3,20}|rgba?\([0-9., %]+\)|hsla?\([0-9., %deg]+\))$`)
+
+var cardTemplate = template.Must(template.New("card").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><style>
+* { margin: 0; padding: 0; box-sizing: border-box; }
+html, body { width: {{.Width}}px; height: {{.Height}}px; overflow: hidden; }
+body { font-family: "Inter", "Segoe UI", "Helvetica Neue", Arial, sans-serif; background: #0f172a; color: #f8fafc; display: flex; }
+.bar { width: 16px; background: {{.Accent}}; }
+.text { flex: 1; padding: 72px 56px 64px 64px; display: flex; flex-direction: column; }
+.domain { align-self: flex-start; font-size: 26px; font-weight: 600; color: #0f172a; background: {{.Accent}}; border-radius: 999px; padding: 8px 22px; }
+.title { margin-top: 40px; font-size: 58px; font-weight: 800; line-height: 1.12; display: -webkit-box; -webkit-line-clamp: 4; -webkit-box-orient: vertical; overflow: hidden; }
+.description { margin-top: auto; font-size: 26px; line-height: 1.35; color: #cbd5e1; display: -webkit-box; -webkit-line-clamp: 2; -webkit-box-orient: vertical; overflow: hidden; }
+.thumb { width: 420px; padding: 64px 56px 0 0; }
+.thumb img { width: 100%; border-radius: 14px 14px 0 0; box-shadow: 0 24px 60px rgba(0,0,0,.55); border: 2px solid rgba(255,255,255,.12); border-bottom: none; }
+</style></head><body>
+<div class="bar"></div>
+<div class="text">
+<div class="domain">{{.Domain}}</div>
+<div class="title">{{.Title}}</div>
+{{if .Description}}<div class="description">{{.Description}}</div>{{end}}
+</div>
+{{if .Screenshot}}<div class="thumb"><img src="{{.Screenshot}}" alt=""></div>{{end}}
+</body></html>`))
+
+// HTML returns the markup the image is rendered from
+func (c Card) HTML() (string, error) {
+	if strings.TrimSpace(c.Title) == "" {
+		return "", ErrNoTitle
+	}
+	accent := strings.TrimSpace(c.Accent)
+	if !cssColor.MatchString(accent) {
+		accent = DefaultAccent
+	}
+
+	var screenshot template.URL
+	if len(c.Screenshot) > 0 {
+		mime := "image/jpeg"
+		if bytes.HasPrefix(c.Screenshot, []byte("\x89PNG")) {
+			mime = "image/png"
+		}
+		screenshot = template.URL("data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(c.Screenshot))
+	}
+
+	var buf bytes.Buffer
+	err := cardTemplate.Execute(&buf, map[string]interface{}{
+		"Width":       Width,
+		"Height":      Height,
+		"Accent":      template.CSS(accent),
+		"Domain":      c.Domain,
+		"Title":       c.Title,
+		"Description": c.Description,
+		"Screenshot":  screenshot,
+	})
+	return buf.String(), err
+}
+
+// Render draws a card and returns it as a PNG image
+func Render(ctx context.Context, card Card, opts Options) ([]byte, error) {
+	markup, err := card.HTML()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	allocOpts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.DisableGPU,
+		chromedp.WindowSize(Width, Height),
+	)
+	if opts.ChromePath != "" {
+		allocOpts = append(allocOpts, chromedp.ExecPath(opts.ChromePath))
+	}
+	allocCtx, allocCancel := chromedp.NewExecAllocator(ctx, allocOpts...)
+	defer allocCancel()
+	taskCtx, taskCancel := chromedp.NewContext(allocCtx)
+	defer taskCancel()
+
+	var image []byte
+	err = chromedp.Run(taskCtx,
+		chromedp.EmulateViewport(Width, Height),
+		chromedp.Navigate("about:blank"),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			tree, err := page.GetFrameTree().Do(ctx)
+			if err != nil {
+				return err
+			}
+			return page.SetDocumentContent(tree.Frame.ID, markup).Do(ctx)
+		}),
+		// Wait for the thumbnail to be decoded before capturing
+		chromedp.Poll(`Array.from(document.images).every(img => img.complete)`, nil, chromedp.WithPollingTimeout(5*time.Second)),
+		chromedp.CaptureScreenshot(&image),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render og image: %w", err)
+	}
+	return image, nil
+}
+
+// SuggestedURL returns where on the site an image for a page could be hosted
+func SuggestedURL(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Host == "" {
+		return "/og/home.png"
+	}
+	slug := strings.Trim(path.Base(strings.TrimSuffix(u.Path, "/")), "/.")
+	if ext := path.Ext(slug); ext != "" {
+		slug = strings.TrimSuffix(slug, ext)
+	}
+	if slug == "" {
+		slug = "home"
+	}
+	return u.Scheme + "://" + u.Host + "/og/" + url.PathEscape(slug) + ".png"
+}
+
+// MetaTags returns the meta tags that reference an image at imageURL
+func MetaTags(imageURL, alt string) string {
+	esc := template.HTMLEscapeString
+	return fmt.Sprintf(`<meta property="og:image" content="%s">
+<meta property="og:image:width" content="%d">
+<meta property="og:image:height" content="%d">
+<meta property="og:image:alt" content="%s">
+<meta name="twitter:card" content="summary_large_image">
+<meta name="twitter:image" content="%s">`, esc(imageURL), Width, Height, esc(alt), esc(imageURL))
+}
@@ -0,0 +1,136 @@
+package originality
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+)
+
+const (
+	copyscapeDefaultURL = "https://www.copyscape.com/api/"
+	// copyscapeComparisons is the number of results Copyscape compares in
+	// full to compute the matched percentage
+	copyscapeComparisons = 5
+)
+
+// copyscape submits the sampled sentences to the Copyscape Premium text search
+type copyscape struct {
+	baseURL    string
+	user       string
+	apiKey     string
+	samples    int
+	httpClient *http.Client
+}
+
+func newCopyscape(cfg Config) (Checker, error) {
+	if cfg.APIUser == "" || cfg.APIKey == "" {
+		return nil, fmt.Errorf("%s requires an API user and key", ProviderCopyscape)
+	}
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = copyscapeDefaultURL
+	}
+	return &copyscape{
+		baseURL:    baseURL,
+		user:       cfg.APIUser,
+		apiKey:     cfg.APIKey,
+		samples:    cfg.Samples,
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+	}, nil
+}
+
+// Name returns the provider name
+func (c *copyscape) Name() string {
+	return ProviderCopyscape
+}
+
+// copyscapeResponse is the XML response of a Copyscape search
+type copyscapeResponse struct {
+	Error      string `xml:"error"`
+	QueryWords int    `xml:"querywords"`
+	Results    []struct {
+		URL             string  `xml:"url"`
+		Title           string  `xml:"title"`
+		MinWordsMatched int     `xml:"minwordsmatched"`
+		PercentMatched  float64 `xml:"percentmatched"`
+	} `xml:"result"`
+}
+
+// Check searches the sampled sentences as one text
+func (c *copyscape) Check(ctx context.Context, pageURL, text string) (*Report, error) {
+	host, err := urlnorm.Hostname(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page URL: %w", err)
+	}
+	sentences := Sample(text, c.samples)
+	if len(sentences) == 0 {
+		return nil, ErrNoText
+	}
+
+	form := url.Values{}
+	form.Set("u", c.user)
+	form.Set("k", c.apiKey)
+	form.Set("o", "csearch")
+	form.Set("e", "UTF-8")
+	form.Set("c", fmt.Sprint(copyscapeComparisons))
+	form.Set("t", strings.Join(sentences, ". "))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %w", ProviderCopyscape, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s response: %w", ProviderCopyscape, err)
+	}
+	var payload copyscapeResponse
+	if err := xml.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid %s response (status %d): %w", ProviderCopyscape, resp.StatusCode, err)
+	}
+	if payload.Error != "" {
+		return nil, fmt.Errorf("%s error: %s", ProviderCopyscape, payload.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s error: status %d", ProviderCopyscape, resp.StatusCode)
+	}
+
+	report := &Report{
+		Provider:         ProviderCopyscape,
+		SentencesChecked: len(sentences),
+		Sentences:        sentences,
+	}
+	for _, result := range payload.Results {
+		if !otherHost(result.URL, host) {
+			continue
+		}
+		similarity := result.PercentMatched
+		if similarity == 0 && payload.QueryWords > 0 {
+			similarity = float64(result.MinWordsMatched) / float64(payload.QueryWords) * 100
+		}
+		if similarity > 100 {
+			similarity = 100
+		}
+		report.Matches = append(report.Matches, Match{
+			URL:          result.URL,
+			Title:        result.Title,
+			MatchedWords: result.MinWordsMatched,
+			Similarity:   similarity,
+		})
+	}
+	report.finish()
+	return report, nil
+}
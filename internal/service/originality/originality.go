@@ -0,0 +1,146 @@
+// Package originality checks whether the text of a page is copied from other
+// sites by looking up sampled sentences with an external search or
+// plagiarism detection API.
+package originality
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/serp"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+)
+
+// Supported checkers. The SERP providers of package serp can be used too: the
+// sampled sentences are then searched as exact phrases.
+const (
+	ProviderCopyscape = "copyscape"
+)
+
+// Defaults of a check
+const (
+	DefaultSamples = 5
+	DefaultTimeout = 45 * time.Second
+	// Sentences shorter than this are too common to indicate copying
+	minSampleWords = 8
+	// Longer sentences are truncated so that they fit search queries
+	maxSampleWords = 32
+)
+
+// ErrNoText is returned when the page has no sentence long enough to sample
+var ErrNoText = errors.New("no sentences to check")
+
+// Match is another page that contains text of the checked page
+type Match struct {
+	URL              string  `json:"url"`
+	Title            string  `json:"title,omitempty"`
+	MatchedSentences int     `json:"matched_sentences,omitempty"`
+	MatchedWords     int     `json:"matched_words,omitempty"`
+	Similarity       float64 `json:"similarity"` // percent of the checked text found on the page
+}
+
+// Report is the result of an originality check
+type Report struct {
+	Provider         string   `json:"provider"`
+	SentencesChecked int      `json:"sentences_checked"`
+	Sentences        []string `json:"sentences,omitempty"`
+	Matches          []Match  `json:"matches"`
+	// MaxSimilarity is the similarity of the best matching source
+	MaxSimilarity float64 `json:"max_similarity"`
+}
+
+// Checker looks up copies of the text of a page. The page itself and other
+// pages of its host are not reported.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context, pageURL, text string) (*Report, error)
+}
+
+var sentenceSplitRegex = regexp.MustCompile(`[.!?]+\s+`)
+
+// Sample picks up to n sentences evenly spread across the text, skipping
+// sentences that are too short to identify the text
+func Sample(text string, n int) []string {
+	if n <= 0 {
+		n = DefaultSamples
+	}
+
+	var candidates []string
+	seen := make(map[string]bool)
+	for _, sentence := range sentenceSplitRegex.Split(text, -1) {
+		words := strings.Fields(sentence)
+		if len(words) < minSampleWords {
+			continue
+		}
+		if len(words) > maxSampleWords {
+			words = words[:maxSampleWords]
+		}
+		sentence = strings.Join(words, " ")
+		if seen[sentence] {
+			continue
+		}
+		seen[sentence] = true
+		candidates = append(candidates, sentence)
+	}
+
+	if len(candidates) <= n {
+		return candidates
+	}
+	samples := make([]string, 0, n)
+	step := float64(len(candidates)) / float64(n)
+	for i := 0; i < n; i++ {
+		samples = append(samples, candidates[int(float64(i)*step)])
+	}
+	return samples
+}
+
+// finish sorts the matches by similarity and sets the best similarity
+func (r *Report) finish() {
+	sort.SliceStable(r.Matches, func(i, j int) bool {
+		return r.Matches[i].Similarity > r.Matches[j].Similarity
+	})
+	if len(r.Matches) > 0 {
+		r.MaxSimilarity = r.Matches[0].Similarity
+	}
+	if r.Matches == nil {
+		r.Matches = []Match{}
+	}
+}
+
+// Config configures a checker
+type Config struct {
+	Provider string // copyscape or a SERP provider name
+	BaseURL  string
+	APIUser  string // account name, required by Copyscape
+	APIKey   string
+	Samples  int // number of sentences checked per page
+}
+
+// NewChecker creates the checker of the configured provider
+func NewChecker(cfg Config) (Checker, error) {
+	if cfg.Samples <= 0 {
+		cfg.Samples = DefaultSamples
+	}
+	if cfg.Provider == ProviderCopyscape {
+		return newCopyscape(cfg)
+	}
+	provider, err := serp.NewProvider(cfg.Provider, cfg.BaseURL, cfg.APIKey)
+	if err != nil {
+		return nil, fmt.Errorf("originality provider: %w", err)
+	}
+	return NewSearchChecker(provider, cfg.Samples), nil
+}
+
+// otherHost reports whether a URL belongs to another site than the host
+func otherHost(rawURL, host string) bool {
+	matchHost, err := urlnorm.Hostname(rawURL)
+	if err != nil {
+		return false
+	}
+	return strings.TrimPrefix(matchHost, "www.") != strings.TrimPrefix(host, "www.")
+}
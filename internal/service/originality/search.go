@@ -0,0 +1,109 @@
+package originality
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/serp"
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+)
+
+const (
+	// searchDepth is the number of results fetched per sentence; copies of
+	// an exact phrase rank at the top
+	searchDepth = 10
+	// searchConcurrency limits the parallel searches of one check
+	searchConcurrency = 3
+)
+
+// searchChecker searches the sampled sentences as exact phrases through a
+// SERP API. The similarity of a source is the share of the sampled sentences
+// it was found for.
+type searchChecker struct {
+	provider serp.Provider
+	samples  int
+}
+
+// NewSearchChecker creates a checker that searches sampled sentences as exact
+// phrases with a SERP provider
+func NewSearchChecker(provider serp.Provider, samples int) Checker {
+	if samples <= 0 {
+		samples = DefaultSamples
+	}
+	return &searchChecker{provider: provider, samples: samples}
+}
+
+// Name returns the name of the SERP provider
+func (c *searchChecker) Name() string {
+	return c.provider.Name()
+}
+
+// Check searches each sampled sentence and aggregates the results per URL
+func (c *searchChecker) Check(ctx context.Context, pageURL, text string) (*Report, error) {
+	host, err := urlnorm.Hostname(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page URL: %w", err)
+	}
+	sentences := Sample(text, c.samples)
+	if len(sentences) == 0 {
+		return nil, ErrNoText
+	}
+
+	type sentenceResult struct {
+		results []serp.Result
+		err     error
+	}
+	found := make([]sentenceResult, len(sentences))
+	sem := make(chan struct{}, searchConcurrency)
+	var wg sync.WaitGroup
+	for i, sentence := range sentences {
+		wg.Add(1)
+		go func(i int, sentence string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results, err := c.provider.Search(ctx, serp.Query{Keyword: `"` + sentence + `"`, Depth: searchDepth})
+			found[i] = sentenceResult{results: results, err: err}
+		}(i, sentence)
+	}
+	wg.Wait()
+
+	report := &Report{Provider: c.Name(), Sentences: sentences}
+	matches := make(map[string]*Match)
+	var order []string
+	for _, result := range found {
+		if result.err != nil {
+			continue
+		}
+		report.SentencesChecked++
+
+		// A source counts once per sentence even when it has several results
+		counted := make(map[string]bool)
+		for _, r := range result.results {
+			key, err := urlnorm.Normalize(r.URL)
+			if err != nil || counted[key] || !otherHost(r.URL, host) {
+				continue
+			}
+			counted[key] = true
+			match, ok := matches[key]
+			if !ok {
+				match = &Match{URL: r.URL, Title: r.Title}
+				matches[key] = match
+				order = append(order, key)
+			}
+			match.MatchedSentences++
+		}
+	}
+	if report.SentencesChecked == 0 {
+		return nil, fmt.Errorf("%s search failed: %w", c.Name(), found[0].err)
+	}
+
+	for _, key := range order {
+		match := matches[key]
+		match.Similarity = float64(match.MatchedSentences) / float64(report.SentencesChecked) * 100
+		report.Matches = append(report.Matches, *match)
+	}
+	report.finish()
+	return report, nil
+}
@@ -0,0 +1,301 @@
+// Package outbox delivers side effects through a transactional outbox.
+// Webhooks, emails and WebSocket notifications are written as messages in
+// the same transaction as the state change that causes them, so a crash
+// cannot lose them, and a dispatcher delivers them afterwards with retries.
+// A message that keeps failing becomes a dead letter administrators can
+// requeue. Delivery is at least once: a dispatcher that dies between
+// delivering a message and recording it delivers it again, so consumers
+// deduplicate by message ID.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+)
+
+// Kinds of side effects
+const (
+	KindStreamDelivery = "stream_delivery" // findings of an analysis to an event stream
+	KindReportEmail    = "report_email"    // a scheduled report to one recipient
+	KindWebSocket      = "websocket"       // an acknowledged notification to a user
+)
+
+// Defaults of the dispatcher options
+const (
+	defaultPollInterval  = 2 * time.Second
+	defaultBatchSize     = 50
+	defaultConcurrency   = 8
+	defaultMaxAttempts   = 8
+	defaultRetryDelay    = 10 * time.Second
+	defaultMaxRetryDelay = time.Hour
+	defaultLease         = 5 * time.Minute
+	defaultRetention     = 7 * 24 * time.Hour
+	pruneInterval        = time.Hour
+)
+
+// Store persists outbox messages
+type Store interface {
+	FindDue(kinds []string, now time.Time, limit int) ([]models.OutboxMessage, error)
+	Claim(id uuid.UUID, now, lockedUntil time.Time) (bool, error)
+	MarkDelivered(id uuid.UUID) error
+	MarkFailed(id uuid.UUID, lastError string, retryAt *time.Time) error
+	PruneDelivered(before time.Time) (int64, error)
+}
+
+// Handler delivers one message. The context ends when the claim on the
+// message expires.
+type Handler func(ctx context.Context, msg models.OutboxMessage) error
+
+// permanentError is a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a delivery failure that retrying cannot fix, such as a
+// deleted recipient, so the message becomes a dead letter right away
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Options tune the dispatcher. Zero values use the defaults.
+type Options struct {
+	PollInterval time.Duration
+	BatchSize    int
+	Concurrency  int // messages delivered at once
+	MaxAttempts  int // attempts before a message becomes a dead letter
+	// RetryDelay is the delay before the first retry, doubled for each next
+	// one up to MaxRetryDelay
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	Lease         time.Duration // how long a claimed message is locked
+	Retention     time.Duration // how long delivered messages are kept
+}
+
+func (o Options) withDefaults() Options {
+	if o.PollInterval <= 0 {
+		o.PollInterval = defaultPollInterval
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = defaultBatchSize
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = defaultConcurrency
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = defaultMaxAttempts
+	}
+	if o.RetryDelay <= 0 {
+		o.RetryDelay = defaultRetryDelay
+	}
+	if o.MaxRetryDelay <= 0 {
+		o.MaxRetryDelay = defaultMaxRetryDelay
+	}
+	if o.Lease <= 0 {
+		o.Lease = defaultLease
+	}
+	if o.Retention <= 0 {
+		o.Retention = defaultRetention
+	}
+	return o
+}
+
+// Dispatcher delivers due outbox messages to the handlers of their kind.
+// Several instances may run dispatchers against the same store; each
+// message is claimed by one of them per attempt.
+type Dispatcher struct {
+	store Store
+	opts  Options
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+
+	wake chan struct{}
+}
+
+// NewDispatcher creates a dispatcher over a store
+func NewDispatcher(store Store, opts Options) *Dispatcher {
+	return &Dispatcher{
+		store:    store,
+		opts:     opts.withDefaults(),
+		handlers: make(map[string]Handler),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Handle registers the handler of a kind. Messages of kinds without a
+// handler stay queued for a dispatcher that has one.
+func (d *Dispatcher) Handle(kind string, handler Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[kind] = handler
+}
+
+// Notify wakes the dispatcher after messages were committed, so they are
+// delivered without waiting for the next poll. It is safe on a nil
+// dispatcher.
+func (d *Dispatcher) Notify() {
+	if d == nil {
+		return
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers messages until the context is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+	lastPrune := time.Time{}
+
+	for {
+		// Drain full batches before waiting again
+		for ctx.Err() == nil {
+			if d.DispatchDue(ctx) < d.opts.BatchSize {
+				break
+			}
+		}
+		if time.Since(lastPrune) >= pruneInterval {
+			lastPrune = time.Now()
+			if pruned, err := d.store.PruneDelivered(lastPrune.Add(-d.opts.Retention)); err != nil {
+				log.Printf("Failed to prune delivered outbox messages: %v", err)
+			} else if pruned > 0 {
+				log.Printf("Pruned %d delivered outbox messages", pruned)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// DispatchDue delivers one batch of due messages and returns how many were
+// found
+func (d *Dispatcher) DispatchDue(ctx context.Context) int {
+	d.mu.RLock()
+	kinds := make([]string, 0, len(d.handlers))
+	for kind := range d.handlers {
+		kinds = append(kinds, kind)
+	}
+	d.mu.RUnlock()
+	if len(kinds) == 0 {
+		return 0
+	}
+
+	messages, err := d.store.FindDue(kinds, time.Now(), d.opts.BatchSize)
+	if err != nil {
+		log.Printf("Failed to load due outbox messages: %v", err)
+		return 0
+	}
+
+	slots := make(chan struct{}, d.opts.Concurrency)
+	var wg sync.WaitGroup
+	for _, msg := range messages {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(msg models.OutboxMessage) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			d.deliver(ctx, msg)
+		}(msg)
+	}
+	wg.Wait()
+	return len(messages)
+}
+
+// deliver claims a message, runs its handler and records the outcome
+func (d *Dispatcher) deliver(ctx context.Context, msg models.OutboxMessage) {
+	d.mu.RLock()
+	handler, ok := d.handlers[msg.Kind]
+	d.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	lockedUntil := now.Add(d.opts.Lease)
+	claimed, err := d.store.Claim(msg.ID, now, lockedUntil)
+	if err != nil || !claimed {
+		return
+	}
+	msg.Attempts++
+
+	deliverCtx, cancel := context.WithDeadline(ctx, lockedUntil)
+	err = handler(deliverCtx, msg)
+	cancel()
+
+	if err == nil {
+		if err := d.store.MarkDelivered(msg.ID); err != nil {
+			log.Printf("Failed to record delivery of outbox message %s: %v", msg.ID, err)
+		}
+		return
+	}
+
+	var retryAt *time.Time
+	var permanent *permanentError
+	if !errors.As(err, &permanent) && msg.Attempts < d.opts.MaxAttempts {
+		next := time.Now().Add(d.retryDelay(msg.Attempts))
+		retryAt = &next
+	}
+	if retryAt == nil {
+		log.Printf("Outbox message %s (%s) is a dead letter after %d attempts: %v", msg.ID, msg.Kind, msg.Attempts, err)
+	}
+	if markErr := d.store.MarkFailed(msg.ID, err.Error(), retryAt); markErr != nil {
+		log.Printf("Failed to record failure of outbox message %s: %v", msg.ID, markErr)
+	}
+}
+
+// retryDelay returns the delay after the given number of failed attempts
+func (d *Dispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.opts.RetryDelay
+	for i := 1; i < attempts && delay < d.opts.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > d.opts.MaxRetryDelay {
+		delay = d.opts.MaxRetryDelay
+	}
+	return delay
+}
+
+// NewMessage builds a message of a kind. The key identifies the side effect:
+// a message with the key of a queued one is not queued again.
+func NewMessage(kind, key string, payload interface{}) (*models.OutboxMessage, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s message: %w", kind, err)
+	}
+	return &models.OutboxMessage{
+		Kind:    kind,
+		Key:     kind + ":" + key,
+		Payload: datatypes.JSON(data),
+	}, nil
+}
+
+// Decode reads the payload of a message. A payload that cannot be read is a
+// permanent failure.
+func Decode(msg models.OutboxMessage, payload interface{}) error {
+	if err := json.Unmarshal(msg.Payload, payload); err != nil {
+		return Permanent(fmt.Errorf("invalid %s payload: %w", msg.Kind, err))
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Challenge describes an anti-bot or CAPTCHA page served instead of the site
+type Challenge struct {
+	// Provider is the protection service, e.g. Cloudflare, or empty if unknown
+	Provider string `json:"provider,omitempty"`
+	// Kind is "challenge" for an interstitial check, "captcha" for a CAPTCHA
+	// wall and "block" for an access denied page
+	Kind       string `json:"kind"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Evidence   string `json:"evidence"`
+}
+
+// Challenge kinds
+const (
+	ChallengeKindInterstitial = "challenge"
+	ChallengeKindCaptcha      = "captcha"
+	ChallengeKindBlock        = "block"
+)
+
+// challengeMarkers only appear on challenge pages. Cloudflare also injects
+// /cdn-cgi/challenge-platform/scripts/ into ordinary pages, so only the
+// challenge orchestration path and options count.
+var challengeMarkers = []struct {
+	fragment string
+	provider string
+	kind     string
+}{
+	{"/cdn-cgi/challenge-platform/h/", "Cloudflare", ChallengeKindInterstitial},
+	{"window._cf_chl_opt", "Cloudflare", ChallengeKindInterstitial},
+	{"attention required! | cloudflare", "Cloudflare", ChallengeKindBlock},
+	{"_incapsula_resource", "Imperva", ChallengeKindInterstitial},
+	{"px-captcha", "PerimeterX", ChallengeKindCaptcha},
+	{"captcha-delivery.com", "DataDome", ChallengeKindCaptcha},
+	{"awswaf-captcha", "AWS WAF", ChallengeKindCaptcha},
+	{"sgcaptcha", "SiteGround", ChallengeKindCaptcha},
+	{"check.ddos-guard.net", "DDoS-Guard", ChallengeKindInterstitial},
+	{"showcaptcha", "Yandex SmartCaptcha", ChallengeKindCaptcha},
+}
+
+// captchaWidgets are also embedded in ordinary forms, so they only count
+// on pages that have little else
+var captchaWidgets = []struct {
+	fragment string
+	provider string
+}{
+	{"g-recaptcha", "reCAPTCHA"},
+	{"www.google.com/recaptcha", "reCAPTCHA"},
+	{"h-captcha", "hCaptcha"},
+	{"cf-turnstile", "Cloudflare Turnstile"},
+	{"smartcaptcha", "Yandex SmartCaptcha"},
+}
+
+// challengeTitles are titles of interstitial and access denied pages
+var challengeTitles = []string{
+	"just a moment",
+	"attention required",
+	"access denied",
+	"are you a robot",
+	"verify you are human",
+	"security check",
+	"ddos-guard",
+	"проверка браузера",
+	"вы не робот",
+	"доступ запрещен",
+}
+
+var (
+	titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	tagPattern   = regexp.MustCompile(`(?s)<script.*?</script>|<style.*?</style>|<[^>]+>`)
+)
+
+// maxChallengeWords is the most visible words a page may have to be taken
+// for a CAPTCHA wall rather than a page with a CAPTCHA-protected form
+const maxChallengeWords = 150
+
+// DetectChallenge reports whether a response is an anti-bot challenge,
+// CAPTCHA wall or WAF block page rather than the site itself
+func DetectChallenge(statusCode int, headers http.Header, body string) *Challenge {
+	lower := strings.ToLower(body)
+
+	if headers != nil && strings.EqualFold(headers.Get("cf-mitigated"), "challenge") {
+		return &Challenge{Provider: "Cloudflare", Kind: ChallengeKindInterstitial, StatusCode: statusCode, Evidence: "header:cf-mitigated"}
+	}
+	for _, marker := range challengeMarkers {
+		if strings.Contains(lower, marker.fragment) {
+			return &Challenge{Provider: marker.provider, Kind: marker.kind, StatusCode: statusCode, Evidence: "markup:" + marker.fragment}
+		}
+	}
+
+	title := ""
+	if match := titlePattern.FindStringSubmatch(body); match != nil {
+		title = strings.ToLower(normalizeWhitespace(match[1]))
+	}
+	words := len(strings.Fields(tagPattern.ReplaceAllString(body, " ")))
+	blocked := statusCode == http.StatusForbidden || statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+
+	for _, widget := range captchaWidgets {
+		if strings.Contains(lower, widget.fragment) && (blocked || words <= maxChallengeWords) {
+			return &Challenge{Provider: widget.provider, Kind: ChallengeKindCaptcha, StatusCode: statusCode, Evidence: "markup:" + widget.fragment}
+		}
+	}
+	if title != "" && (blocked || words <= maxChallengeWords) {
+		for _, fragment := range challengeTitles {
+			if strings.Contains(title, fragment) {
+				kind := ChallengeKindInterstitial
+				if statusCode == http.StatusForbidden || strings.Contains(fragment, "denied") || strings.Contains(fragment, "запрещен") {
+					kind = ChallengeKindBlock
+				}
+				return &Challenge{Provider: providerFromHeaders(headers), Kind: kind, StatusCode: statusCode, Evidence: "title:" + title}
+			}
+		}
+	}
+	return nil
+}
+
+// providerFromHeaders names the CDN or WAF that served a response
+func providerFromHeaders(headers http.Header) string {
+	if headers == nil {
+		return ""
+	}
+	server := strings.ToLower(headers.Get("Server"))
+	switch {
+	case headers.Get("cf-ray") != "" || strings.Contains(server, "cloudflare"):
+		return "Cloudflare"
+	case strings.Contains(server, "ddos-guard"):
+		return "DDoS-Guard"
+	case strings.Contains(server, "akamaighost") || headers.Get("akamai-grn") != "":
+		return "Akamai"
+	case headers.Get("x-sucuri-id") != "":
+		return "Sucuri"
+	case headers.Get("x-iinfo") != "":
+		return "Imperva"
+	case strings.Contains(server, "qrator"):
+		return "Qrator"
+	}
+	return ""
+}
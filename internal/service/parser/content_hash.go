@@ -0,0 +1,31 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// volatileMarkup matches parts of a page that change on every request
+// without changing its content: comments, CSP nonces and CSRF tokens are left
+// out of the hash
+var volatileMarkup = []*regexp.Regexp{
+	regexp.MustCompile(`(?s)<!--.*?-->`),
+	regexp.MustCompile(`(?i)\snonce="[^"]*"`),
+	regexp.MustCompile(`(?i)(<meta[^>]+name="csrf[-_]?token"[^>]+content=)"[^"]*"`),
+	regexp.MustCompile(`(?i)(<input[^>]+name="(?:_token|csrf[-_]?token|csrfmiddlewaretoken|authenticity_token)"[^>]+value=)"[^"]*"`),
+}
+
+// ContentHash returns the SHA-256 of the normalized HTML of a page: volatile
+// markup is removed and whitespace is collapsed, so two fetches of unchanged
+// content produce the same hash
+func ContentHash(html string) string {
+	for _, pattern := range volatileMarkup {
+		html = pattern.ReplaceAllString(html, "$1")
+	}
+	normalized := strings.Join(strings.Fields(html), " ")
+
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
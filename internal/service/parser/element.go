@@ -0,0 +1,212 @@
+package parser
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ElementRef identifies an element of a page by its structural selector and a
+// hash of its markup. The selector locates the element; the hash recognizes
+// it again when the page structure has shifted.
+type ElementRef struct {
+	Selector    string `json:"selector"`
+	ContentHash string `json:"content_hash"`
+}
+
+// ID returns the stable identifier of the element: the first 16 characters of
+// the content hash and the selector
+func (r ElementRef) ID() string {
+	hash := r.ContentHash
+	if len(hash) > 16 {
+		hash = hash[:16]
+	}
+	return hash + "@" + r.Selector
+}
+
+// Ways an element was found by ElementLocator.Resolve
+const (
+	ElementMatchExact    = "exact"    // selector and content hash match
+	ElementMatchMoved    = "moved"    // content hash matches at a different place
+	ElementMatchSelector = "selector" // selector matches but the element changed
+)
+
+// urlAttributes lists the elements and attributes that reference resources,
+// used to find the element an issue about a URL belongs to
+var urlAttributes = []struct{ selector, attr string }{
+	{"img[src]", "src"},
+	{"script[src]", "src"},
+	{"link[href]", "href"},
+	{"a[href]", "href"},
+	{"source[src]", "src"},
+	{"iframe[src]", "src"},
+	{"video[src]", "src"},
+	{"audio[src]", "src"},
+}
+
+// ElementLocator finds elements in the markup of a page
+type ElementLocator struct {
+	doc  *goquery.Document
+	base *url.URL
+}
+
+// NewElementLocator parses the markup of a page. It returns nil when the
+// markup cannot be parsed; a nil locator finds nothing.
+func NewElementLocator(html, pageURL string) *ElementLocator {
+	if html == "" {
+		return nil
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil
+	}
+	base, _ := url.Parse(pageURL)
+	return &ElementLocator{doc: doc, base: base}
+}
+
+// BySelector returns the reference of the first element matching a CSS selector
+func (l *ElementLocator) BySelector(selector string) (ElementRef, bool) {
+	if l == nil || selector == "" {
+		return ElementRef{}, false
+	}
+	sel := l.find(selector)
+	if sel.Length() == 0 {
+		return ElementRef{}, false
+	}
+	return l.ref(sel.First()), true
+}
+
+// ByURL returns the reference of the first element that loads or links to
+// the given URL, comparing URLs after resolving them against the page URL
+func (l *ElementLocator) ByURL(target string) (ElementRef, bool) {
+	if l == nil || target == "" {
+		return ElementRef{}, false
+	}
+	target = l.absolute(target)
+
+	for _, candidate := range urlAttributes {
+		var found *goquery.Selection
+		l.doc.Find(candidate.selector).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			if value, _ := s.Attr(candidate.attr); l.absolute(value) == target {
+				found = s
+				return false
+			}
+			return true
+		})
+		if found != nil {
+			return l.ref(found), true
+		}
+	}
+	return ElementRef{}, false
+}
+
+// Resolve finds a referenced element: at its selector when the content hash
+// still matches, otherwise by content hash anywhere in the page, otherwise
+// at its selector even though the element changed
+func (l *ElementLocator) Resolve(ref ElementRef) (*goquery.Selection, string, bool) {
+	if l == nil {
+		return nil, "", false
+	}
+
+	atSelector := l.find(ref.Selector)
+	if atSelector.Length() > 0 && elementHash(atSelector.First()) == ref.ContentHash {
+		return atSelector.First(), ElementMatchExact, true
+	}
+
+	if ref.ContentHash != "" {
+		tag := selectorTag(ref.Selector)
+		if tag == "" {
+			tag = "*"
+		}
+		var moved *goquery.Selection
+		l.doc.Find(tag).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			if elementHash(s) == ref.ContentHash {
+				moved = s
+				return false
+			}
+			return true
+		})
+		if moved != nil {
+			return moved, ElementMatchMoved, true
+		}
+	}
+
+	if atSelector.Length() > 0 {
+		return atSelector.First(), ElementMatchSelector, true
+	}
+	return nil, "", false
+}
+
+// find matches a selector; goquery treats an invalid selector as matching nothing
+func (l *ElementLocator) find(selector string) *goquery.Selection {
+	return l.doc.Find(selector)
+}
+
+func (l *ElementLocator) ref(sel *goquery.Selection) ElementRef {
+	return ElementRef{Selector: ElementSelector(sel), ContentHash: elementHash(sel)}
+}
+
+func (l *ElementLocator) absolute(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if l.base == nil || raw == "" {
+		return raw
+	}
+	u, err := l.base.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	u.Fragment = ""
+	return u.String()
+}
+
+// ElementSelector builds a structural CSS selector of an element: the path of
+// tag names with :nth-of-type from the nearest ancestor with an id
+func ElementSelector(sel *goquery.Selection) string {
+	var parts []string
+	for node := sel.First(); node.Length() > 0; node = node.Parent() {
+		tag := goquery.NodeName(node)
+		if tag == "" || strings.HasPrefix(tag, "#") {
+			break
+		}
+		if id, ok := node.Attr("id"); ok && id != "" {
+			parts = append(parts, fmt.Sprintf(`%s[id="%s"]`, tag, strings.ReplaceAll(id, `"`, `\"`)))
+			break
+		}
+		if tag == "html" {
+			parts = append(parts, tag)
+			break
+		}
+
+		part := tag
+		if siblings := node.Parent().ChildrenFiltered(tag); siblings.Length() > 1 {
+			part += fmt.Sprintf(":nth-of-type(%d)", siblings.IndexOfSelection(node)+1)
+		}
+		parts = append(parts, part)
+	}
+
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, " > ")
+}
+
+// elementHash hashes the normalized markup of an element
+func elementHash(sel *goquery.Selection) string {
+	html, err := goquery.OuterHtml(sel)
+	if err != nil {
+		return ""
+	}
+	return ContentHash(html)
+}
+
+// selectorTag returns the tag name of the last compound of a selector
+func selectorTag(selector string) string {
+	parts := strings.Split(selector, ">")
+	last := strings.TrimSpace(parts[len(parts)-1])
+	if i := strings.IndexAny(last, "[:.#"); i >= 0 {
+		last = last[:i]
+	}
+	return last
+}
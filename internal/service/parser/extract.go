@@ -0,0 +1,150 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+)
+
+// Limits of a structured extraction
+const (
+	MaxExtractSelectors = 50
+	MaxExtractMatches   = 100
+	maxExtractValueLen  = 2000
+)
+
+// Selector names a CSS selector whose matches should be extracted. Attr picks
+// an attribute instead of the text, and "html" picks the inner HTML. Without
+// All only the first match is returned.
+type Selector struct {
+	Name     string `json:"name"`
+	Selector string `json:"selector"`
+	Attr     string `json:"attr,omitempty"`
+	All      bool   `json:"all,omitempty"`
+}
+
+// SelectorResult holds the values a selector extracted from a page
+type SelectorResult struct {
+	Name      string   `json:"name"`
+	Values    []string `json:"values"`
+	Matches   int      `json:"matches"`
+	Truncated bool     `json:"truncated,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Extraction is the result of extracting selectors from a page
+type Extraction struct {
+	URL        string           `json:"url"`
+	FinalURL   string           `json:"final_url"`
+	StatusCode int              `json:"status_code"`
+	LoadTime   time.Duration    `json:"load_time"`
+	Results    []SelectorResult `json:"results"`
+}
+
+// ValidateSelectors checks that every selector has a unique name and
+// compiles
+func ValidateSelectors(selectors []Selector) error {
+	if len(selectors) == 0 {
+		return fmt.Errorf("at least one selector is required")
+	}
+	if len(selectors) > MaxExtractSelectors {
+		return fmt.Errorf("at most %d selectors are allowed", MaxExtractSelectors)
+	}
+	names := make(map[string]bool, len(selectors))
+	for _, s := range selectors {
+		if s.Name == "" || strings.TrimSpace(s.Selector) == "" {
+			return fmt.Errorf("every selector needs a name and a selector")
+		}
+		if names[s.Name] {
+			return fmt.Errorf("duplicate selector name %q", s.Name)
+		}
+		names[s.Name] = true
+		if _, err := cascadia.Compile(s.Selector); err != nil {
+			return fmt.Errorf("invalid selector %q: %v", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// ExtractURL fetches a page and extracts the values of each selector from its
+// markup as served, without running JavaScript
+func ExtractURL(ctx context.Context, client *http.Client, pageURL string, selectors []Selector) (*Extraction, error) {
+	start := time.Now()
+	resp, body, err := fetchDocument(ctx, client, pageURL, "", "")
+	if err != nil {
+		return nil, err
+	}
+	extraction := Extract(string(body), selectors)
+	extraction.URL = pageURL
+	extraction.FinalURL = resp.Request.URL.String()
+	extraction.StatusCode = resp.StatusCode
+	extraction.LoadTime = time.Since(start)
+	return extraction, nil
+}
+
+// Extract returns the values of each selector in the given markup
+func Extract(html string, selectors []Selector) *Extraction {
+	extraction := &Extraction{Results: make([]SelectorResult, 0, len(selectors))}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return extraction
+	}
+
+	for _, s := range selectors {
+		result := SelectorResult{Name: s.Name, Values: []string{}}
+		matcher, err := cascadia.Compile(s.Selector)
+		if err != nil {
+			result.Error = err.Error()
+			extraction.Results = append(extraction.Results, result)
+			continue
+		}
+
+		matches := doc.FindMatcher(matcher)
+		result.Matches = matches.Length()
+		matches.EachWithBreak(func(i int, el *goquery.Selection) bool {
+			if !s.All && i > 0 {
+				return false
+			}
+			if i >= MaxExtractMatches {
+				result.Truncated = true
+				return false
+			}
+			if value, ok := selectionValue(el, s.Attr); ok {
+				result.Values = append(result.Values, value)
+			}
+			return true
+		})
+		extraction.Results = append(extraction.Results, result)
+	}
+	return extraction
+}
+
+// selectionValue returns the text, inner HTML or an attribute of an element
+func selectionValue(el *goquery.Selection, attr string) (string, bool) {
+	var value string
+	switch attr {
+	case "":
+		value = strings.Join(strings.Fields(el.Text()), " ")
+	case "html":
+		html, err := el.Html()
+		if err != nil {
+			return "", false
+		}
+		value = strings.TrimSpace(html)
+	default:
+		attrValue, ok := el.Attr(attr)
+		if !ok {
+			return "", false
+		}
+		value = attrValue
+	}
+	if runes := []rune(value); len(runes) > maxExtractValueLen {
+		value = string(runes[:maxExtractValueLen])
+	}
+	return value, true
+}
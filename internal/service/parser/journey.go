@@ -0,0 +1,451 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// Limits on journeys supplied through the API
+const (
+	maxJourneys          = 5
+	maxJourneySteps      = 15
+	maxJourneyNameLength = 100
+	maxSelectorLength    = 512
+	maxConsoleErrors     = 10
+	// journeyStepTimeout bounds a single step including the page settling
+	journeyStepTimeout = 15 * time.Second
+	// maxJourneysDuration bounds all journeys of a parse together
+	maxJourneysDuration = 3 * time.Minute
+	// journeySettleDelay gives navigations started by a click or a submit
+	// time to begin before the page is considered settled
+	journeySettleDelay = 300 * time.Millisecond
+)
+
+// Journey step actions
+const (
+	JourneyActionVisit  = "visit"
+	JourneyActionClick  = "click"
+	JourneyActionFill   = "fill"
+	JourneyActionSubmit = "submit"
+	JourneyActionWait   = "wait"
+)
+
+// Journey is a scripted user path through the site, e.g. visit the home
+// page, click the pricing link and submit the signup form. Journeys start on
+// the analyzed page.
+type Journey struct {
+	Name  string        `json:"name"`
+	Steps []JourneyStep `json:"steps"`
+}
+
+// JourneyStep is one action of a journey. Visit loads URL, relative to the
+// analyzed page and on the same site, or the analyzed page when URL is
+// empty. Click, fill, submit and wait act on the first element matching the
+// CSS selector; wait only waits until it is visible.
+type JourneyStep struct {
+	Name     string `json:"name,omitempty"`
+	Action   string `json:"action"`
+	URL      string `json:"url,omitempty"`
+	Selector string `json:"selector,omitempty"`
+	// Value is typed into the element of a fill step. It is never reported.
+	Value string `json:"value,omitempty"`
+}
+
+// JourneyResult is the outcome of running a journey
+type JourneyResult struct {
+	Name       string `json:"name"`
+	Completed  bool   `json:"completed"`
+	FailedStep int    `json:"failed_step,omitempty"` // 1-based, zero when completed
+	// DurationMs and LayoutShift add up the steps that ran
+	DurationMs  int64               `json:"duration_ms"`
+	LayoutShift float64             `json:"layout_shift"`
+	Steps       []JourneyStepResult `json:"steps"`
+}
+
+// JourneyStepResult is the outcome of one journey step. Steps after a failed
+// step are reported as skipped.
+type JourneyStepResult struct {
+	Step   int    `json:"step"`
+	Name   string `json:"name,omitempty"`
+	Action string `json:"action"`
+	// URL is the page the step ended on
+	URL string `json:"url,omitempty"`
+	// DurationMs is the time until the page settled after the action
+	DurationMs int64 `json:"duration_ms"`
+	// LayoutShift is the unexpected layout shift score accumulated during the
+	// step, computed like Cumulative Layout Shift
+	LayoutShift   float64  `json:"layout_shift"`
+	StatusCode    int      `json:"status_code,omitempty"`
+	Error         string   `json:"error,omitempty"`
+	ConsoleErrors []string `json:"console_errors,omitempty"`
+	Skipped       bool     `json:"skipped,omitempty"`
+}
+
+// ValidateJourneys checks the structure and size of journeys
+func ValidateJourneys(journeys []Journey) error {
+	if len(journeys) > maxJourneys {
+		return fmt.Errorf("%w: at most %d journeys are allowed", ErrInvalidOverride, maxJourneys)
+	}
+	for i, journey := range journeys {
+		label := fmt.Sprintf("journey %d", i+1)
+		if len(journey.Name) > maxJourneyNameLength {
+			return fmt.Errorf("%w: name of %s exceeds %d characters", ErrInvalidOverride, label, maxJourneyNameLength)
+		}
+		if len(journey.Steps) == 0 || len(journey.Steps) > maxJourneySteps {
+			return fmt.Errorf("%w: %s must have between 1 and %d steps", ErrInvalidOverride, label, maxJourneySteps)
+		}
+		for j, step := range journey.Steps {
+			field := fmt.Sprintf("step %d of %s", j+1, label)
+			switch step.Action {
+			case JourneyActionVisit:
+				if err := validateValue(field+" url", step.URL, maxOverrideValueLength); err != nil {
+					return err
+				}
+			case JourneyActionClick, JourneyActionFill, JourneyActionSubmit, JourneyActionWait:
+				if step.Selector == "" {
+					return fmt.Errorf("%w: %s needs a selector", ErrInvalidOverride, field)
+				}
+			default:
+				return fmt.Errorf("%w: %s has unknown action %q", ErrInvalidOverride, field, step.Action)
+			}
+			if err := validateValue(field+" selector", step.Selector, maxSelectorLength); err != nil {
+				return err
+			}
+			if err := validateValue(field+" value", step.Value, maxOverrideValueLength); err != nil {
+				return err
+			}
+			if len(step.Name) > maxJourneyNameLength {
+				return fmt.Errorf("%w: name of %s exceeds %d characters", ErrInvalidOverride, field, maxJourneyNameLength)
+			}
+		}
+	}
+	return nil
+}
+
+// journeyObserverScript accumulates unexpected layout shifts of each document
+// and tags the document so navigations can be told apart
+const journeyObserverScript = `(() => {
+	window.__journeyDoc = Math.random().toString(36).slice(2);
+	window.__journeyShift = 0;
+	try {
+		new PerformanceObserver((list) => {
+			for (const entry of list.getEntries()) {
+				if (!entry.hadRecentInput) window.__journeyShift += entry.value;
+			}
+		}).observe({type: "layout-shift", buffered: true});
+	} catch (e) {}
+})();`
+
+// journeyPageState is read from the page before and after each step
+type journeyPageState struct {
+	Doc   string  `json:"doc"`
+	Shift float64 `json:"shift"`
+	URL   string  `json:"url"`
+}
+
+// journeyEvents collects console errors and document responses of a journey
+type journeyEvents struct {
+	mu            sync.Mutex
+	consoleErrors []string
+	status        int
+}
+
+// RunJourneys runs each journey in a fresh browser tab, starting on the
+// analyzed page. Journeys never fail the parse; problems are reported in
+// their results.
+func RunJourneys(ctx context.Context, targetURL string, journeys []Journey, opts ParseOptions) []JourneyResult {
+	ctx, cancel := context.WithTimeout(ctx, maxJourneysDuration)
+	defer cancel()
+
+	chromeOpts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.WindowSize(1920, 1080))
+	if opts.UserAgent != "" {
+		chromeOpts = append(chromeOpts, chromedp.UserAgent(opts.UserAgent))
+	}
+	if opts.CustomChromePath != "" {
+		chromeOpts = append(chromeOpts, chromedp.ExecPath(opts.CustomChromePath))
+	}
+	if opts.ProxyURL != "" {
+		chromeOpts = append(chromeOpts, chromedp.ProxyServer(opts.ProxyURL))
+	}
+	allocCtx, allocCancel := chromedp.NewExecAllocator(ctx, chromeOpts...)
+	defer allocCancel()
+
+	results := make([]JourneyResult, 0, len(journeys))
+	for _, journey := range journeys {
+		results = append(results, runJourney(allocCtx, targetURL, journey, opts))
+	}
+	return results
+}
+
+// runJourney runs the steps of one journey until the first failure
+func runJourney(allocCtx context.Context, targetURL string, journey Journey, opts ParseOptions) JourneyResult {
+	result := JourneyResult{Name: journey.Name, Completed: true}
+
+	tabCtx, tabCancel := chromedp.NewContext(allocCtx)
+	defer tabCancel()
+
+	events := &journeyEvents{}
+	chromedp.ListenTarget(tabCtx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *runtime.EventExceptionThrown:
+			events.addConsoleError(exceptionText(ev.ExceptionDetails))
+		case *runtime.EventConsoleAPICalled:
+			if ev.Type == runtime.APITypeError {
+				events.addConsoleError(consoleText(ev.Args))
+			}
+		case *network.EventResponseReceived:
+			if ev.Type == network.ResourceTypeDocument && ev.Response != nil {
+				events.mu.Lock()
+				events.status = int(ev.Response.Status)
+				events.mu.Unlock()
+			}
+		}
+	})
+
+	setup := []chromedp.Action{
+		network.Enable(),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			_, err := page.AddScriptToEvaluateOnNewDocument(journeyObserverScript).Do(ctx)
+			return err
+		}),
+	}
+	for _, cookie := range opts.Cookies {
+		cookie := cookie
+		setup = append(setup, chromedp.ActionFunc(func(ctx context.Context) error {
+			return network.SetCookie(cookie.Name, cookie.Value).WithDomain(cookie.Domain).WithPath(cookie.Path).Do(ctx)
+		}))
+	}
+	if len(opts.Headers) > 0 {
+		headers := network.Headers{}
+		for name, value := range opts.Headers {
+			headers[name] = value
+		}
+		setup = append(setup, network.SetExtraHTTPHeaders(headers))
+	}
+
+	// The first run allocates the tab and must not carry a timeout, which
+	// would close the tab when it expires
+	err := chromedp.Run(tabCtx, setup...)
+	if err == nil && (len(journey.Steps) == 0 || journey.Steps[0].Action != JourneyActionVisit) {
+		// Journeys start on the analyzed page unless they begin with a visit
+		openCtx, openCancel := context.WithTimeout(tabCtx, journeyStepTimeout)
+		err = chromedp.Run(openCtx, chromedp.Navigate(targetURL), waitSettled())
+		openCancel()
+	}
+	if err != nil {
+		result.Completed = false
+		result.FailedStep = 1
+		for i, step := range journey.Steps {
+			stepResult := JourneyStepResult{Step: i + 1, Name: step.Name, Action: step.Action, Skipped: i > 0}
+			if i == 0 {
+				stepResult.Error = "failed to open the analyzed page: " + err.Error()
+			}
+			result.Steps = append(result.Steps, stepResult)
+		}
+		return result
+	}
+
+	for i, step := range journey.Steps {
+		stepResult := JourneyStepResult{Step: i + 1, Name: step.Name, Action: step.Action}
+		if !result.Completed {
+			stepResult.Skipped = true
+			result.Steps = append(result.Steps, stepResult)
+			continue
+		}
+
+		runJourneyStep(tabCtx, targetURL, step, events, &stepResult)
+		result.DurationMs += stepResult.DurationMs
+		result.LayoutShift += stepResult.LayoutShift
+		if stepResult.Error != "" {
+			result.Completed = false
+			result.FailedStep = i + 1
+		}
+		result.Steps = append(result.Steps, stepResult)
+	}
+	return result
+}
+
+// runJourneyStep performs one action and measures it until the page settled
+func runJourneyStep(tabCtx context.Context, targetURL string, step JourneyStep, events *journeyEvents, result *JourneyStepResult) {
+	action, err := journeyAction(targetURL, step)
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(tabCtx, journeyStepTimeout)
+	defer cancel()
+
+	before := readPageState(ctx)
+	events.reset()
+
+	start := time.Now()
+	err = chromedp.Run(ctx, action, chromedp.Sleep(journeySettleDelay), waitSettled())
+	result.DurationMs = (time.Since(start) - journeySettleDelay).Milliseconds()
+	if result.DurationMs < 0 {
+		result.DurationMs = 0
+	}
+
+	after := readPageState(tabCtx)
+	result.URL = after.URL
+	if after.Doc != "" && after.Doc == before.Doc {
+		result.LayoutShift = after.Shift - before.Shift
+	} else {
+		// A new document started its own accumulation
+		result.LayoutShift = after.Shift
+	}
+	if result.LayoutShift < 0 {
+		result.LayoutShift = 0
+	}
+
+	result.StatusCode, result.ConsoleErrors = events.collect()
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		result.Error = fmt.Sprintf("step did not finish within %s", journeyStepTimeout)
+	case err != nil:
+		result.Error = err.Error()
+	case result.StatusCode >= 400:
+		result.Error = fmt.Sprintf("page returned status %d", result.StatusCode)
+	}
+}
+
+// journeyAction converts a step into a browser action
+func journeyAction(targetURL string, step JourneyStep) (chromedp.Action, error) {
+	switch step.Action {
+	case JourneyActionVisit:
+		destination, err := journeyURL(targetURL, step.URL)
+		if err != nil {
+			return nil, err
+		}
+		return chromedp.Navigate(destination), nil
+	case JourneyActionClick:
+		return chromedp.Tasks{
+			chromedp.WaitVisible(step.Selector, chromedp.ByQuery),
+			chromedp.Click(step.Selector, chromedp.ByQuery),
+		}, nil
+	case JourneyActionFill:
+		return chromedp.Tasks{
+			chromedp.WaitVisible(step.Selector, chromedp.ByQuery),
+			chromedp.Clear(step.Selector, chromedp.ByQuery),
+			chromedp.SendKeys(step.Selector, step.Value, chromedp.ByQuery),
+		}, nil
+	case JourneyActionSubmit:
+		return chromedp.Tasks{
+			chromedp.WaitReady(step.Selector, chromedp.ByQuery),
+			chromedp.Submit(step.Selector, chromedp.ByQuery),
+		}, nil
+	case JourneyActionWait:
+		return chromedp.WaitVisible(step.Selector, chromedp.ByQuery), nil
+	default:
+		return nil, fmt.Errorf("unknown action %q", step.Action)
+	}
+}
+
+// journeyURL resolves the URL of a visit step and keeps journeys on the
+// analyzed site
+func journeyURL(targetURL, stepURL string) (string, error) {
+	base, err := url.Parse(targetURL)
+	if err != nil {
+		return "", err
+	}
+	if stepURL == "" {
+		return targetURL, nil
+	}
+	ref, err := url.Parse(stepURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	destination := base.ResolveReference(ref)
+	if destination.Scheme != "http" && destination.Scheme != "https" {
+		return "", fmt.Errorf("URL must use http or https")
+	}
+	if siteHost(destination.Hostname()) != siteHost(base.Hostname()) {
+		return "", fmt.Errorf("URL %s is outside the analyzed site", destination.Redacted())
+	}
+	return destination.String(), nil
+}
+
+// siteHost returns a lowercased host without "www." prefix
+func siteHost(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
+
+// waitSettled waits until the current document finished loading
+func waitSettled() chromedp.Action {
+	return chromedp.Poll(`document.readyState === "complete"`, nil, chromedp.WithPollingTimeout(journeyStepTimeout))
+}
+
+// readPageState returns the layout shift and identity of the current
+// document, or an empty state if it cannot be read
+func readPageState(ctx context.Context) journeyPageState {
+	var state journeyPageState
+	readCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	_ = chromedp.Run(readCtx, chromedp.Evaluate(
+		`({doc: window.__journeyDoc || "", shift: window.__journeyShift || 0, url: location.href})`, &state))
+	return state
+}
+
+// addConsoleError records a console error, keeping the first few of a step
+func (e *journeyEvents) addConsoleError(text string) {
+	if text == "" {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.consoleErrors) < maxConsoleErrors {
+		e.consoleErrors = append(e.consoleErrors, text)
+	}
+}
+
+// reset forgets the events of the previous step
+func (e *journeyEvents) reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.consoleErrors = nil
+	e.status = 0
+}
+
+// collect returns the document status and console errors of the step
+func (e *journeyEvents) collect() (int, []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.status, e.consoleErrors
+}
+
+// exceptionText describes an uncaught exception
+func exceptionText(details *runtime.ExceptionDetails) string {
+	if details == nil {
+		return ""
+	}
+	if details.Exception != nil && details.Exception.Description != "" {
+		// The first line holds the message, the rest is the stack
+		return strings.SplitN(details.Exception.Description, "\n", 2)[0]
+	}
+	return details.Text
+}
+
+// consoleText joins the arguments of a console.error call
+func consoleText(args []*runtime.RemoteObject) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch {
+		case arg == nil:
+		case arg.Description != "":
+			parts = append(parts, arg.Description)
+		case len(arg.Value) > 0:
+			parts = append(parts, strings.Trim(string(arg.Value), `"`))
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/chromedp/chromedp"
+	"golang.org/x/net/html"
+)
+
+// maxExperienceItems limits the headings and links kept per page version
+const maxExperienceItems = 200
+
+// PageExperience is what a visitor sees in one version of a page: with
+// JavaScript running or with JavaScript disabled
+type PageExperience struct {
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Canonical   string   `json:"canonical,omitempty"`
+	H1          []string `json:"h1,omitempty"`
+	Headings    []string `json:"headings,omitempty"`
+	WordCount   int      `json:"word_count"`
+	MainWords   int      `json:"main_words"`
+	Links       []string `json:"-"`
+	NavLinks    []string `json:"-"`
+	LinkCount   int      `json:"link_count"`
+	NavCount    int      `json:"nav_link_count"`
+	Images      int      `json:"images"`
+	Noscript    bool     `json:"noscript_fallback,omitempty"`
+}
+
+// ExtractExperience reads the visible content of a page. With scripting
+// disabled the content of <noscript> is parsed as markup and shown, as a
+// browser without JavaScript would; with scripting enabled it is dropped.
+func ExtractExperience(markup, baseURL string, scripting bool) PageExperience {
+	var experience PageExperience
+
+	root, err := html.ParseWithOptions(strings.NewReader(markup), html.ParseOptionEnableScripting(scripting))
+	if err != nil {
+		return experience
+	}
+	doc := goquery.NewDocumentFromNode(root)
+
+	experience.Title = normalizeWhitespace(doc.Find("head title").First().Text())
+	experience.Description, _ = doc.Find(`meta[name="description"]`).Attr("content")
+	experience.Description = normalizeWhitespace(experience.Description)
+	experience.Canonical, _ = doc.Find(`link[rel="canonical"]`).Attr("href")
+
+	body := doc.Find("body")
+	body.Find("script, style, template, [hidden], [aria-hidden='true']").Remove()
+	if scripting {
+		body.Find("noscript").Remove()
+	} else {
+		experience.Noscript = strings.TrimSpace(body.Find("noscript").Text()) != ""
+	}
+
+	body.Find("h1").Each(func(_ int, s *goquery.Selection) {
+		if text := normalizeWhitespace(s.Text()); text != "" {
+			experience.H1 = append(experience.H1, text)
+		}
+	})
+	body.Find("h1, h2, h3").Each(func(_ int, s *goquery.Selection) {
+		if text := normalizeWhitespace(s.Text()); text != "" && len(experience.Headings) < maxExperienceItems {
+			experience.Headings = append(experience.Headings, text)
+		}
+	})
+	experience.WordCount = len(strings.Fields(body.Text()))
+	experience.Images = body.Find("img[src], picture source[srcset]").Length()
+
+	base, _ := url.Parse(baseURL)
+	nav := body.Find("nav, header, [role='navigation']")
+	experience.Links = experienceLinks(body.Find("a[href]"), base)
+	experience.NavLinks = experienceLinks(nav.Find("a[href]"), base)
+	experience.LinkCount = len(experience.Links)
+	experience.NavCount = len(experience.NavLinks)
+
+	if rendered, err := goquery.OuterHtml(body); err == nil {
+		experience.MainWords = len(strings.Fields(ExtractMainContent(rendered).Text))
+	}
+
+	return experience
+}
+
+// experienceLinks returns the distinct absolute targets of links that
+// navigate, without fragments
+func experienceLinks(links *goquery.Selection, base *url.URL) []string {
+	seen := make(map[string]bool)
+	var targets []string
+	links.Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		href = strings.TrimSpace(href)
+		if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			return
+		}
+		u, err := url.Parse(href)
+		if err != nil {
+			return
+		}
+		if base != nil {
+			u = base.ResolveReference(u)
+		}
+		u.Fragment = ""
+		target := u.String()
+		if !seen[target] && len(targets) < maxExperienceItems {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	})
+	return targets
+}
+
+// FetchWithoutJavaScript fetches the HTML a client without JavaScript
+// receives
+func FetchWithoutJavaScript(ctx context.Context, targetURL, userAgent string, timeout time.Duration) (string, error) {
+	client := &http.Client{Timeout: timeout}
+	resp, body, err := fetchDocument(ctx, client, targetURL, userAgent, "")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return "", fmt.Errorf("page returned status %d", resp.StatusCode)
+	}
+	return string(body), nil
+}
+
+// RenderDOM loads a page in the headless browser and returns the DOM after
+// JavaScript ran
+func RenderDOM(ctx context.Context, targetURL, userAgent string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	chromeOpts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.WindowSize(1920, 1080))
+	if userAgent != "" {
+		chromeOpts = append(chromeOpts, chromedp.UserAgent(userAgent))
+	}
+	allocCtx, allocCancel := chromedp.NewExecAllocator(ctx, chromeOpts...)
+	defer allocCancel()
+	taskCtx, taskCancel := chromedp.NewContext(allocCtx)
+	defer taskCancel()
+
+	var dom string
+	if err := chromedp.Run(taskCtx,
+		chromedp.Navigate(targetURL),
+		chromedp.WaitReady("body", chromedp.ByQuery),
+		chromedp.OuterHTML("html", &dom, chromedp.ByQuery),
+	); err != nil {
+		return "", fmt.Errorf("failed to render page: %w", err)
+	}
+	return dom, nil
+}
@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Issue types whose elements are located on screenshots. They match the
+// issue types reported by the accessibility and mobile analyzers.
+const (
+	OverlayMissingAlt     = "missing_alt_text"
+	OverlayLowContrast    = "potential_contrast_issues"
+	OverlaySmallTapTarget = "small_touch_targets"
+)
+
+// maxOverlayBoxes limits the number of boxes collected per issue type
+const maxOverlayBoxes = 50
+
+// ElementBox is the position of a problematic element on a full-page
+// screenshot, in CSS pixels relative to the top left corner of the page
+type ElementBox struct {
+	IssueType string  `json:"issue_type"`
+	Selector  string  `json:"selector"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	Width     float64 `json:"width"`
+	Height    float64 `json:"height"`
+	// Detail describes the problem, e.g. the image URL or the contrast ratio
+	Detail string `json:"detail,omitempty"`
+}
+
+// ScreenshotOverlay holds the element boxes found while rendering the page
+// for one device. Screenshot pixels are CSS pixels times DeviceScaleFactor.
+type ScreenshotOverlay struct {
+	Device            string       `json:"device"`
+	ViewportWidth     int          `json:"viewport_width"`
+	ViewportHeight    int          `json:"viewport_height"`
+	DeviceScaleFactor float64      `json:"device_scale_factor"`
+	Boxes             []ElementBox `json:"boxes"`
+}
+
+// overlayScript collects the bounding boxes of images without alt text, text
+// with insufficient contrast and, on touch devices, tap targets smaller than
+// 44x44 pixels
+const overlayScript = `
+(mobile, limit) => {
+	const boxes = [];
+	const counts = {};
+	const selectorOf = (el) => {
+		if (el.id) return '#' + CSS.escape(el.id);
+		const parts = [];
+		for (let node = el; node && node.nodeType === 1 && parts.length < 4; node = node.parentElement) {
+			let part = node.tagName.toLowerCase();
+			const parent = node.parentElement;
+			if (parent) {
+				const siblings = Array.from(parent.children).filter(c => c.tagName === node.tagName);
+				if (siblings.length > 1) part += ':nth-of-type(' + (siblings.indexOf(node) + 1) + ')';
+			}
+			parts.unshift(part);
+			if (node.id) { parts[0] = '#' + CSS.escape(node.id); break; }
+		}
+		return parts.join(' > ');
+	};
+	const add = (type, el, detail) => {
+		counts[type] = (counts[type] || 0) + 1;
+		if (counts[type] > limit) return;
+		const rect = el.getBoundingClientRect();
+		if (rect.width === 0 || rect.height === 0) return;
+		boxes.push({
+			issue_type: type,
+			selector: selectorOf(el),
+			x: rect.left + window.scrollX,
+			y: rect.top + window.scrollY,
+			width: rect.width,
+			height: rect.height,
+			detail: detail || ''
+		});
+	};
+
+	document.querySelectorAll('img:not([alt])').forEach(el => add('missing_alt_text', el, el.currentSrc || el.src));
+
+	const parseColor = (value) => {
+		const m = value.match(/rgba?\(([^)]+)\)/);
+		if (!m) return null;
+		const p = m[1].split(',').map(v => parseFloat(v));
+		return { r: p[0], g: p[1], b: p[2], a: p.length > 3 ? p[3] : 1 };
+	};
+	const background = (el) => {
+		for (let node = el; node && node.nodeType === 1; node = node.parentElement) {
+			const style = getComputedStyle(node);
+			if (style.backgroundImage !== 'none') return null;
+			const color = parseColor(style.backgroundColor);
+			if (color && color.a >= 1) return color;
+		}
+		return { r: 255, g: 255, b: 255, a: 1 };
+	};
+	const luminance = (c) => {
+		const channel = (v) => { v /= 255; return v <= 0.03928 ? v / 12.92 : Math.pow((v + 0.055) / 1.055, 2.4); };
+		return 0.2126 * channel(c.r) + 0.7152 * channel(c.g) + 0.0722 * channel(c.b);
+	};
+	document.querySelectorAll('body *').forEach(el => {
+		const hasText = Array.from(el.childNodes).some(n => n.nodeType === 3 && n.textContent.trim() !== '');
+		if (!hasText) return;
+		const style = getComputedStyle(el);
+		if (style.visibility === 'hidden' || style.display === 'none') return;
+		const fg = parseColor(style.color);
+		const bg = background(el);
+		if (!fg || !bg) return;
+		const l1 = luminance(fg), l2 = luminance(bg);
+		const ratio = (Math.max(l1, l2) + 0.05) / (Math.min(l1, l2) + 0.05);
+		const size = parseFloat(style.fontSize);
+		const large = size >= 24 || (size >= 18.66 && parseInt(style.fontWeight, 10) >= 700);
+		if (ratio < (large ? 3 : 4.5)) add('potential_contrast_issues', el, ratio.toFixed(2) + ':1');
+	});
+
+	if (mobile) {
+		document.querySelectorAll('a[href], button, input:not([type=hidden]), select, textarea, [role=button]').forEach(el => {
+			const rect = el.getBoundingClientRect();
+			if (rect.width === 0 || rect.height === 0) return;
+			if (rect.width < 44 || rect.height < 44) add('small_touch_targets', el, Math.round(rect.width) + 'x' + Math.round(rect.height));
+		});
+	}
+	return boxes;
+}`
+
+// captureElementBoxes evaluates overlayScript on the rendered page
+func captureElementBoxes(device DeviceConfig, boxes *[]ElementBox) chromedp.Action {
+	return chromedp.Evaluate(fmt.Sprintf("(%s)(%t, %d)", overlayScript, device.Mobile, maxOverlayBoxes), boxes)
+}
@@ -0,0 +1,235 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Limits on request overrides supplied through the API
+const (
+	maxOverrideHeaders     = 20
+	maxOverrideCookies     = 20
+	maxOverrideValueLength = 1024
+	maxUserAgentLength     = 512
+)
+
+// ErrInvalidOverride is returned when a request override is not allowed
+var ErrInvalidOverride = errors.New("invalid request override")
+
+// allowedHeaders are the standard headers users may set. Any other header
+// must be an X- header that is not in blockedHeaders.
+var allowedHeaders = map[string]bool{
+	"Accept":          true,
+	"Accept-Language": true,
+	"Cache-Control":   true,
+	"Dnt":             true,
+	"Pragma":          true,
+	"Referer":         true,
+	"Save-Data":       true,
+}
+
+// blockedHeaders could spoof the client address, change routing or the
+// method of the request
+var blockedHeaders = map[string]bool{
+	"X-Forwarded-For":        true,
+	"X-Forwarded-Host":       true,
+	"X-Forwarded-Proto":      true,
+	"X-Forwarded-Port":       true,
+	"X-Real-Ip":              true,
+	"X-Client-Ip":            true,
+	"X-Original-Url":         true,
+	"X-Rewrite-Url":          true,
+	"X-Http-Method-Override": true,
+	"X-Http-Method":          true,
+	"X-Method-Override":      true,
+}
+
+// RequestOverrides are the parts of ParseOptions a user may change for a
+// single analysis, e.g. to reach a variant served behind an A/B test or a
+// feature flag header
+type RequestOverrides struct {
+	Headers   map[string]string `json:"headers,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Cookies   map[string]string `json:"cookies,omitempty"`
+	// Record captures a HAR and a screencast of the page load, which forces
+	// the headless browser
+	Record bool `json:"record,omitempty"`
+	// Journeys are user journeys run in the headless browser after the page
+	// was parsed
+	Journeys []Journey `json:"journeys,omitempty"`
+}
+
+// IsEmpty reports whether no override is set
+func (o RequestOverrides) IsEmpty() bool {
+	return len(o.Headers) == 0 && o.UserAgent == "" && len(o.Cookies) == 0 && !o.Record && len(o.Journeys) == 0
+}
+
+// Validate checks the overrides against the header allowlist and size limits
+func (o RequestOverrides) Validate() error {
+	if len(o.Headers) > maxOverrideHeaders {
+		return fmt.Errorf("%w: at most %d headers are allowed", ErrInvalidOverride, maxOverrideHeaders)
+	}
+	for name, value := range o.Headers {
+		canonical := http.CanonicalHeaderKey(name)
+		if !validHeaderName(name) {
+			return fmt.Errorf("%w: malformed header name %q", ErrInvalidOverride, name)
+		}
+		if !allowedHeaders[canonical] && (!strings.HasPrefix(canonical, "X-") || blockedHeaders[canonical]) {
+			return fmt.Errorf("%w: header %s is not allowed", ErrInvalidOverride, canonical)
+		}
+		if err := validateValue("header "+canonical, value, maxOverrideValueLength); err != nil {
+			return err
+		}
+	}
+
+	if err := validateValue("user agent", o.UserAgent, maxUserAgentLength); err != nil {
+		return err
+	}
+
+	if len(o.Cookies) > maxOverrideCookies {
+		return fmt.Errorf("%w: at most %d cookies are allowed", ErrInvalidOverride, maxOverrideCookies)
+	}
+	for name, value := range o.Cookies {
+		if !validHeaderName(name) {
+			return fmt.Errorf("%w: malformed cookie name %q", ErrInvalidOverride, name)
+		}
+		if strings.ContainsAny(value, ";,\" \\") {
+			return fmt.Errorf("%w: cookie %s contains a forbidden character", ErrInvalidOverride, name)
+		}
+		if err := validateValue("cookie "+name, value, maxOverrideValueLength); err != nil {
+			return err
+		}
+	}
+
+	return ValidateJourneys(o.Journeys)
+}
+
+// Apply copies the overrides into parse options for the target URL.
+// Cookies are scoped to the host of the target.
+func (o RequestOverrides) Apply(opts *ParseOptions, targetURL string) {
+	if o.Record {
+		opts.RecordSession = true
+		opts.UseHeadlessBrowser = true
+	}
+
+	if len(o.Journeys) > 0 {
+		opts.Journeys = o.Journeys
+	}
+
+	if o.UserAgent != "" {
+		opts.UserAgent = o.UserAgent
+	}
+
+	if len(o.Headers) > 0 {
+		if opts.Headers == nil {
+			opts.Headers = make(map[string]string, len(o.Headers))
+		}
+		for name, value := range o.Headers {
+			opts.Headers[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+
+	host := ""
+	if u, err := url.Parse(targetURL); err == nil {
+		host = u.Hostname()
+	}
+	for _, name := range sortedKeys(o.Cookies) {
+		opts.Cookies = append(opts.Cookies, &http.Cookie{
+			Name:   name,
+			Value:  o.Cookies[name],
+			Domain: host,
+			Path:   "/",
+		})
+	}
+}
+
+// Fingerprint identifies a set of overrides. It is empty when no override
+// is set, so that plain analyses of a URL share the same fingerprint.
+func (o RequestOverrides) Fingerprint() string {
+	if o.IsEmpty() {
+		return ""
+	}
+
+	headers := make(map[string]string, len(o.Headers))
+	for name, value := range o.Headers {
+		headers[http.CanonicalHeaderKey(name)] = value
+	}
+	// Maps are marshalled with sorted keys
+	data, _ := json.Marshal(RequestOverrides{Headers: headers, UserAgent: o.UserAgent, Cookies: o.Cookies, Record: o.Record, Journeys: o.Journeys})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Summary describes the overrides without cookie values, which may hold
+// session tokens, and without the values typed by journeys
+func (o RequestOverrides) Summary() map[string]interface{} {
+	summary := map[string]interface{}{
+		"fingerprint": o.Fingerprint(),
+	}
+	if len(o.Headers) > 0 {
+		summary["headers"] = o.Headers
+	}
+	if o.UserAgent != "" {
+		summary["user_agent"] = o.UserAgent
+	}
+	if len(o.Cookies) > 0 {
+		summary["cookie_names"] = sortedKeys(o.Cookies)
+	}
+	if o.Record {
+		summary["record"] = true
+	}
+	if len(o.Journeys) > 0 {
+		journeys := make([]map[string]interface{}, 0, len(o.Journeys))
+		for _, journey := range o.Journeys {
+			journeys = append(journeys, map[string]interface{}{
+				"name":  journey.Name,
+				"steps": len(journey.Steps),
+			})
+		}
+		summary["journeys"] = journeys
+	}
+	return summary
+}
+
+// validHeaderName reports whether a name is an HTTP token
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r > 127 || r <= 32 || strings.ContainsRune("()<>@,;:\\\"/[]?={}", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateValue rejects control characters, which would allow header injection
+func validateValue(field, value string, maxLength int) error {
+	if len(value) > maxLength {
+		return fmt.Errorf("%w: %s exceeds %d characters", ErrInvalidOverride, field, maxLength)
+	}
+	for _, r := range value {
+		if r < 32 && r != '\t' || r == 127 {
+			return fmt.Errorf("%w: %s contains control characters", ErrInvalidOverride, field)
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of a map in a stable order
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -41,6 +41,61 @@ type WebsiteData struct {
 	Screenshots     map[string][]byte `json:"screenshots,omitempty"`
 	Technologies    []Technology      `json:"technologies,omitempty"`
 	JavaScriptError string            `json:"javascript_error,omitempty"`
+	// MainContent is the page text with navigation and other boilerplate removed
+	MainContent MainContent `json:"main_content"`
+	// FinalURL is the URL the page was served from after following redirects
+	FinalURL string `json:"final_url,omitempty"`
+	// RawHTML is the response body as fetched, before any processing
+	RawHTML string `json:"raw_html,omitempty"`
+	// RenderedDOM is the serialized DOM after JavaScript ran in the headless browser
+	RenderedDOM string `json:"rendered_dom,omitempty"`
+	// ScreenshotOverlays locates problematic elements on the screenshot of each device
+	ScreenshotOverlays map[string]ScreenshotOverlay `json:"screenshot_overlays,omitempty"`
+	// PhaseTimings holds the wall time spent in each parsing phase
+	PhaseTimings map[string]time.Duration `json:"phase_timings,omitempty"`
+	// NetworkRequests are the requests the headless browser made while loading
+	// the page; empty when the page was parsed without a browser
+	NetworkRequests []NetworkRequest `json:"network_requests,omitempty"`
+	// Recording is the HAR and screencast of the page load, set only when
+	// ParseOptions.RecordSession is enabled
+	Recording *SessionRecording `json:"-"`
+	// Challenge is set when an anti-bot check or WAF block page was served
+	// instead of the site
+	Challenge *Challenge `json:"challenge,omitempty"`
+	// Journeys are the results of the user journeys in ParseOptions.Journeys
+	Journeys []JourneyResult `json:"journeys,omitempty"`
+}
+
+// maxNetworkRequests limits the network log of a page
+const maxNetworkRequests = 2000
+
+// NetworkRequest is a request made by the page in the headless browser
+type NetworkRequest struct {
+	URL string `json:"url"`
+	// Type is the Chrome resource type, e.g. Script, Stylesheet, Image, Font,
+	// XHR, Fetch, WebSocket or Document
+	Type string `json:"type"`
+	// Initiator is "parser" for resources in the markup and "script" for
+	// requests made by JavaScript
+	Initiator string `json:"initiator,omitempty"`
+}
+
+// Parsing phases tracked in WebsiteData.PhaseTimings
+const (
+	PhaseFetch         = "fetch"
+	PhaseJSRender      = "js_render"
+	PhaseLinkCheck     = "link_check"
+	PhaseImageSizing   = "image_sizing"
+	PhaseTechDetection = "tech_detection"
+	PhaseJourneys      = "journeys"
+)
+
+// recordPhase adds the time elapsed since start to the given phase
+func (d *WebsiteData) recordPhase(phase string, start time.Time) {
+	if d.PhaseTimings == nil {
+		d.PhaseTimings = make(map[string]time.Duration)
+	}
+	d.PhaseTimings[phase] += time.Since(start)
 }
 
 // Link represents a hyperlink on the page
@@ -121,6 +176,17 @@ type ParseOptions struct {
 	Headers            map[string]string
 	Cookies            []*http.Cookie
 	CustomChromePath   string
+	// Transport replaces the network transport used to fetch the page
+	Transport http.RoundTripper
+	// SkipResourceChecks leaves out the link status checks and image size
+	// estimates, so only the page itself is fetched
+	SkipResourceChecks bool
+	// RecordSession records a HAR and a screencast of the page load in the
+	// headless browser
+	RecordSession bool
+	// Journeys are user journeys run in the headless browser after the page
+	// was parsed
+	Journeys []Journey
 }
 
 // DefaultParseOptions returns the default parsing options
@@ -199,9 +265,10 @@ func ParseWebsite(targetURL string, options ...ParseOptions) (*WebsiteData, erro
 
 	// Initialize the data structure
 	websiteData := &WebsiteData{
-		URL:         targetURL,
-		MetaTags:    make(map[string]string),
-		Screenshots: make(map[string][]byte),
+		URL:          targetURL,
+		MetaTags:     make(map[string]string),
+		Screenshots:  make(map[string][]byte),
+		PhaseTimings: make(map[string]time.Duration),
 	}
 
 	// Create context with timeout
@@ -231,9 +298,28 @@ func ParseWebsite(targetURL string, options ...ParseOptions) (*WebsiteData, erro
 	// Calculate load time
 	websiteData.LoadTime = time.Since(startTime)
 
+	// Extract the main content from the richest markup available
+	switch {
+	case websiteData.RenderedDOM != "":
+		websiteData.MainContent = ExtractMainContent(websiteData.RenderedDOM)
+	case websiteData.RawHTML != "":
+		websiteData.MainContent = ExtractMainContent(websiteData.RawHTML)
+	case websiteData.HTML != "":
+		websiteData.MainContent = ExtractMainContent(websiteData.HTML)
+	}
+
 	// Detect technologies if requested
 	if opts.DetectTechnologies {
+		techStart := time.Now()
 		websiteData.Technologies = detectTechnologies(websiteData)
+		websiteData.recordPhase(PhaseTechDetection, techStart)
+	}
+
+	// Run user journeys on pages that were actually served
+	if len(opts.Journeys) > 0 && parseErr == nil && websiteData.Challenge == nil {
+		journeyStart := time.Now()
+		websiteData.Journeys = RunJourneys(context.Background(), targetURL, opts.Journeys, opts)
+		websiteData.recordPhase(PhaseJourneys, journeyStart)
 	}
 
 	return websiteData, parseErr
@@ -241,6 +327,8 @@ func ParseWebsite(targetURL string, options ...ParseOptions) (*WebsiteData, erro
 
 // parseWithColly uses the Colly crawler for basic scraping
 func parseWithColly(ctx context.Context, websiteData *WebsiteData, parsedURL *url.URL, opts ParseOptions) error {
+	fetchStart := time.Now()
+
 	// Set up the collector
 	c := colly.NewCollector(
 		colly.AllowedDomains(parsedURL.Hostname()),
@@ -291,6 +379,9 @@ func parseWithColly(ctx context.Context, websiteData *WebsiteData, parsedURL *ur
 	}
 
 	c.SetRequestTimeout(opts.Timeout)
+	if opts.Transport != nil {
+		c.WithTransport(opts.Transport)
+	}
 
 	// Process hyperlinks
 	c.OnHTML("a[href]", func(e *colly.HTMLElement) {
@@ -424,6 +515,14 @@ func parseWithColly(ctx context.Context, websiteData *WebsiteData, parsedURL *ur
 	// Handle response
 	c.OnResponse(func(r *colly.Response) {
 		websiteData.StatusCode = r.StatusCode
+		if websiteData.FinalURL == "" {
+			// The request URL is updated when redirects are followed
+			websiteData.FinalURL = r.Request.URL.String()
+		}
+		if websiteData.RawHTML == "" && strings.Contains(r.Headers.Get("Content-Type"), "html") {
+			websiteData.RawHTML = string(r.Body)
+		}
+		websiteData.Challenge = DetectChallenge(r.StatusCode, *r.Headers, string(r.Body))
 	})
 
 	// Advanced retry logic with exponential backoff
@@ -440,6 +539,9 @@ func parseWithColly(ctx context.Context, websiteData *WebsiteData, parsedURL *ur
 			websiteData.StatusCode = r.StatusCode
 			if r.StatusCode == 0 {
 				websiteData.StatusCode = http.StatusInternalServerError
+			} else if r.Headers != nil {
+				// WAFs answer crawlers with 403 or 503 challenge pages
+				websiteData.Challenge = DetectChallenge(r.StatusCode, *r.Headers, string(r.Body))
 			}
 			lastErr = err
 		})
@@ -471,16 +573,27 @@ func parseWithColly(ctx context.Context, websiteData *WebsiteData, parsedURL *ur
 		c.Wait()
 		break
 	}
+	websiteData.recordPhase(PhaseFetch, fetchStart)
+
+	if opts.SkipResourceChecks {
+		return nil
+	}
 
 	// Check link statuses after initial parsing with optimized parallel execution
 	if len(websiteData.Links) > 0 {
-		if err := checkLinksStatus(ctx, websiteData, opts); err != nil {
+		linkStart := time.Now()
+		err := checkLinksStatus(ctx, websiteData, opts)
+		websiteData.recordPhase(PhaseLinkCheck, linkStart)
+		if err != nil {
 			return fmt.Errorf("error checking links: %w", err)
 		}
 	}
 
 	// Estimate image file sizes
-	if err := estimateImageSizes(ctx, websiteData, opts); err != nil {
+	imageStart := time.Now()
+	err := estimateImageSizes(ctx, websiteData, opts)
+	websiteData.recordPhase(PhaseImageSizing, imageStart)
+	if err != nil {
 		return fmt.Errorf("error estimating image sizes: %w", err)
 	}
 
@@ -489,6 +602,8 @@ func parseWithColly(ctx context.Context, websiteData *WebsiteData, parsedURL *ur
 
 // parseWithHeadlessBrowser uses Chrome/Chromium through chromedp for JavaScript-heavy sites
 func parseWithHeadlessBrowser(ctx context.Context, websiteData *WebsiteData, targetURL string, opts ParseOptions) error {
+	renderStart := time.Now()
+
 	// Create timeout context
 	jsCtx, jsCancel := context.WithTimeout(ctx, opts.JavaScriptTimeout)
 	defer jsCancel()
@@ -581,6 +696,7 @@ func parseWithHeadlessBrowser(ctx context.Context, websiteData *WebsiteData, tar
 	tasks := []chromedp.Action{
 		network.Enable(),
 		chromedp.Navigate(targetURL),
+		chromedp.Location(&websiteData.FinalURL),
 	}
 
 	// Add wait actions
@@ -712,13 +828,57 @@ func parseWithHeadlessBrowser(ctx context.Context, websiteData *WebsiteData, tar
 		`, &extractedData),
 	)
 
+	// Record the requests the page makes for the network log
+	var networkMu sync.Mutex
+	var networkRequests []NetworkRequest
+	chromedp.ListenTarget(taskCtx, func(ev interface{}) {
+		var request NetworkRequest
+		switch e := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			request = NetworkRequest{URL: e.Request.URL, Type: string(e.Type)}
+			if e.Initiator != nil {
+				request.Initiator = string(e.Initiator.Type)
+			}
+		case *network.EventWebSocketCreated:
+			request = NetworkRequest{URL: e.URL, Type: string(network.ResourceTypeWebSocket)}
+		default:
+			return
+		}
+
+		networkMu.Lock()
+		defer networkMu.Unlock()
+		if len(networkRequests) < maxNetworkRequests {
+			networkRequests = append(networkRequests, request)
+		}
+	})
+
+	// Record the session from before navigation until the page is extracted
+	var recorder *sessionRecorder
+	if opts.RecordSession {
+		recorder = newSessionRecorder()
+		recorder.listen(taskCtx)
+		tasks = append(append([]chromedp.Action{recorder.startScreencast()}, tasks...), recorder.stopScreencast())
+	}
+
 	// Execute the tasks
 	if err := chromedp.Run(taskCtx, tasks...); err != nil {
 		return fmt.Errorf("failed to execute browser tasks: %w", err)
 	}
 
+	networkMu.Lock()
+	websiteData.NetworkRequests = append([]NetworkRequest(nil), networkRequests...)
+	networkMu.Unlock()
+
+	if recorder != nil {
+		websiteData.Recording = recorder.recording(websiteData.FinalURL, title)
+	}
+
+	// A challenge still shown after rendering was not passed by the browser
+	websiteData.Challenge = DetectChallenge(0, nil, html)
+
 	// Process parsed data
 	websiteData.HTML = html
+	websiteData.RenderedDOM = html
 	websiteData.Title = title
 	websiteData.TextContent = pageText
 	websiteData.H1 = extractedData.Headings.H1
@@ -835,6 +995,10 @@ func parseWithHeadlessBrowser(ctx context.Context, websiteData *WebsiteData, tar
 				)
 			}
 
+			// Положение проблемных элементов для разметки поверх скриншота
+			var boxes []ElementBox
+			deviceTasks = append(deviceTasks, captureElementBoxes(device, &boxes))
+
 			// Захват скриншота
 			var screenshot []byte
 			deviceTasks = append(deviceTasks,
@@ -852,18 +1016,40 @@ func parseWithHeadlessBrowser(ctx context.Context, websiteData *WebsiteData, tar
 
 			// Сохраняем скриншот
 			websiteData.Screenshots[device.Name] = screenshot
+			if websiteData.ScreenshotOverlays == nil {
+				websiteData.ScreenshotOverlays = make(map[string]ScreenshotOverlay)
+			}
+			websiteData.ScreenshotOverlays[device.Name] = ScreenshotOverlay{
+				Device:            device.Name,
+				ViewportWidth:     device.Width,
+				ViewportHeight:    device.Height,
+				DeviceScaleFactor: device.DeviceScaleFactor,
+				Boxes:             boxes,
+			}
 		}
 	}
 
+	websiteData.recordPhase(PhaseJSRender, renderStart)
+
+	if opts.SkipResourceChecks {
+		return nil
+	}
+
 	// Check link statuses after parsing
 	if len(websiteData.Links) > 0 {
-		if err := checkLinksStatus(ctx, websiteData, opts); err != nil {
+		linkStart := time.Now()
+		err := checkLinksStatus(ctx, websiteData, opts)
+		websiteData.recordPhase(PhaseLinkCheck, linkStart)
+		if err != nil {
 			return fmt.Errorf("error checking links: %w", err)
 		}
 	}
 
 	// Estimate image file sizes
-	if err := estimateImageSizes(ctx, websiteData, opts); err != nil {
+	imageStart := time.Now()
+	err := estimateImageSizes(ctx, websiteData, opts)
+	websiteData.recordPhase(PhaseImageSizing, imageStart)
+	if err != nil {
 		return fmt.Errorf("error estimating image sizes: %w", err)
 	}
 
@@ -917,64 +1103,21 @@ func checkLinksStatus(ctx context.Context, data *WebsiteData, opts ParseOptions)
 			defer func() { <-semaphore }() // Release semaphore when done
 
 			statusCode := 0
-
-			// Implement retry logic
-			for retryCount := 0; retryCount <= opts.MaxRetries; retryCount++ {
-				select {
-				case <-ctx.Done():
-					// Context cancelled/timed out
-					statusCode = http.StatusRequestTimeout
-					return
-				default:
-					// Continue with request
-				}
-
-				// Create request
-				req, err := http.NewRequestWithContext(ctx, "HEAD", link.URL, nil)
-				if err != nil {
-					if retryCount == opts.MaxRetries {
-						errMu.Lock()
-						errs = append(errs, fmt.Errorf("error creating request for %s: %w", link.URL, err))
-						errMu.Unlock()
-					}
-					continue
-				}
-
-				// Set headers
-				req.Header.Set("User-Agent", opts.UserAgent)
-				for key, value := range opts.Headers {
-					req.Header.Set(key, value)
-				}
-
-				// Make request
-				resp, err := client.Do(req)
-				if err != nil {
-					// If this is the last retry, log the error
-					if retryCount == opts.MaxRetries {
-						statusCode = http.StatusInternalServerError
-						errMu.Lock()
-						errs = append(errs, fmt.Errorf("error checking %s after %d retries: %w", link.URL, opts.MaxRetries, err))
-						errMu.Unlock()
-					}
-
-					// Wait before retrying with exponential backoff and jitter
-					if retryCount < opts.MaxRetries {
-						baseDelay := opts.RetryDelay * time.Duration(1<<uint(retryCount))
-						jitter := time.Duration(float64(baseDelay) * 0.2 * (0.5 + rand.Float64()))
-						delay := baseDelay + jitter
-
-						select {
-						case <-ctx.Done():
-							return
-						case <-time.After(delay):
-							continue
-						}
-					}
-				} else {
-					statusCode = resp.StatusCode
-					resp.Body.Close()
-					break
+			resp, err := headWithRetry(ctx, client, link.URL, opts)
+			switch {
+			case err == nil:
+				statusCode = resp.StatusCode
+				resp.Body.Close()
+			case ctx.Err() != nil:
+				// Context cancelled/timed out
+				return
+			default:
+				if !errors.Is(err, errInvalidRequest) {
+					statusCode = http.StatusInternalServerError
 				}
+				errMu.Lock()
+				errs = append(errs, err)
+				errMu.Unlock()
 			}
 
 			// Update status code with mutex protection
@@ -995,6 +1138,52 @@ func checkLinksStatus(ctx context.Context, data *WebsiteData, opts ParseOptions)
 	return nil
 }
 
+// errInvalidRequest marks a URL a request could not be created for
+var errInvalidRequest = errors.New("invalid request")
+
+// headWithRetry sends a HEAD request, retrying failed attempts with
+// exponential backoff and jitter
+func headWithRetry(ctx context.Context, client *http.Client, target string, opts ParseOptions) (*http.Response, error) {
+	var lastErr error
+	for retryCount := 0; retryCount <= opts.MaxRetries; retryCount++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Create request
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w for %s: %v", errInvalidRequest, target, err)
+		}
+
+		// Set headers
+		req.Header.Set("User-Agent", opts.UserAgent)
+		for key, value := range opts.Headers {
+			req.Header.Set(key, value)
+		}
+
+		// Make request
+		resp, err := client.Do(req)
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+
+		// Wait before retrying with exponential backoff and jitter
+		if retryCount < opts.MaxRetries {
+			baseDelay := opts.RetryDelay * time.Duration(1<<uint(retryCount))
+			jitter := time.Duration(float64(baseDelay) * 0.2 * (0.5 + rand.Float64()))
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(baseDelay + jitter):
+			}
+		}
+	}
+	return nil, fmt.Errorf("error checking %s after %d retries: %w", target, opts.MaxRetries, lastErr)
+}
+
 // estimateImageSizes tries to get the file size of images with improved error handling
 func estimateImageSizes(ctx context.Context, data *WebsiteData, opts ParseOptions) error {
 	var wg sync.WaitGroup
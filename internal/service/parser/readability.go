@@ -0,0 +1,223 @@
+package parser
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// wordsPerMinute is the reading speed used to estimate read time
+const wordsPerMinute = 200
+
+// MainContent is the primary text of a page with boilerplate removed
+type MainContent struct {
+	Text            string `json:"text"`
+	WordCount       int    `json:"word_count"`
+	Language        string `json:"language"`
+	ReadTimeSeconds int    `json:"read_time_seconds"`
+	// Selector describes the element the content was extracted from
+	Selector string `json:"selector,omitempty"`
+}
+
+// boilerplateTags are removed before scoring content candidates
+const boilerplateTags = "script, style, noscript, template, svg, iframe, form, nav, header, footer, aside, button, select"
+
+// boilerplatePattern matches class names and IDs of navigation, ads and
+// other non-content blocks
+var boilerplatePattern = regexp.MustCompile(`(?i)(^|[\s_-])(nav|navbar|navigation|menu|footer|header|sidebar|side-bar|widget|comment|cookie|consent|banner|share|social|breadcrumb|advert|ads?|promo|related|subscribe|newsletter|popup|modal|masthead|pagination|skip)($|[\s_-])`)
+
+// contentPattern matches class names and IDs that usually hold the main text
+var contentPattern = regexp.MustCompile(`(?i)(article|content|entry|main|post|story|text|body|blog)`)
+
+// languageStopWords holds frequent function words used to guess the language
+// of Latin-script text
+var languageStopWords = map[string][]string{
+	"en": {"the", "and", "of", "to", "in", "is", "that", "for", "with", "you", "this", "are"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "sie", "ein", "den", "auf", "für"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "pour", "dans", "que", "pas", "vous"},
+	"es": {"el", "la", "los", "las", "y", "que", "del", "por", "una", "para", "con", "es"},
+	"pt": {"o", "os", "e", "que", "do", "da", "em", "um", "uma", "para", "com", "não"},
+	"it": {"il", "di", "che", "e", "la", "per", "una", "sono", "con", "non", "gli", "del"},
+}
+
+// ExtractMainContent finds the main text of an HTML document by removing
+// boilerplate and picking the block with the highest paragraph text density
+func ExtractMainContent(html string) MainContent {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return MainContent{Language: "unknown"}
+	}
+
+	declaredLang, _ := doc.Find("html").Attr("lang")
+
+	body := doc.Find("body")
+	if body.Length() == 0 {
+		body = doc.Selection
+	}
+
+	body.Find(boilerplateTags).Remove()
+	body.Find("[role=navigation], [role=banner], [role=contentinfo], [role=complementary], [aria-hidden=true]").Remove()
+	body.Find("*").Each(func(_ int, s *goquery.Selection) {
+		class, _ := s.Attr("class")
+		id, _ := s.Attr("id")
+		attrs := class + " " + id
+		// Wrappers such as "content-sidebar-wrap" still hold the main content
+		if boilerplatePattern.MatchString(attrs) && !contentPattern.MatchString(attrs) {
+			s.Remove()
+		}
+	})
+
+	candidate, selector := bestContentCandidate(body)
+
+	text := collectBlockText(candidate)
+	if text == "" {
+		text = normalizeWhitespace(body.Text())
+		selector = "body"
+	}
+
+	wordCount := len(strings.Fields(text))
+	readTime := 0
+	if wordCount > 0 {
+		readTime = int(math.Ceil(float64(wordCount) / wordsPerMinute * 60))
+	}
+
+	return MainContent{
+		Text:            text,
+		WordCount:       wordCount,
+		Language:        detectLanguage(declaredLang, text),
+		ReadTimeSeconds: readTime,
+		Selector:        selector,
+	}
+}
+
+// bestContentCandidate scores containers by the text of their paragraphs,
+// penalising link-heavy blocks
+func bestContentCandidate(body *goquery.Selection) (*goquery.Selection, string) {
+	if main := body.Find("article, main, [role=main]"); main.Length() == 1 && len(main.Text()) > 200 {
+		return main, goquery.NodeName(main)
+	}
+
+	best := body
+	bestSelector := "body"
+	bestScore := 0.0
+
+	body.Find("article, main, section, div, td").Each(func(_ int, s *goquery.Selection) {
+		score := 0.0
+		s.ChildrenFiltered("p, pre, blockquote, ul, ol, h2, h3").Each(func(_ int, p *goquery.Selection) {
+			text := strings.TrimSpace(p.Text())
+			if len(text) < 25 {
+				return
+			}
+			score += 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+		})
+		if score == 0 {
+			return
+		}
+
+		class, _ := s.Attr("class")
+		id, _ := s.Attr("id")
+		if contentPattern.MatchString(class) || contentPattern.MatchString(id) {
+			score *= 1.25
+		}
+
+		score *= 1 - linkDensity(s)
+
+		if score > bestScore {
+			bestScore = score
+			best = s
+			bestSelector = goquery.NodeName(s)
+			if id != "" {
+				bestSelector += "#" + id
+			} else if class != "" {
+				bestSelector += "." + strings.Join(strings.Fields(class), ".")
+			}
+		}
+	})
+
+	return best, bestSelector
+}
+
+// linkDensity returns the share of a block's text that is inside links
+func linkDensity(s *goquery.Selection) float64 {
+	total := len(s.Text())
+	if total == 0 {
+		return 0
+	}
+
+	linkText := 0
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linkText += len(a.Text())
+	})
+	return math.Min(float64(linkText)/float64(total), 1)
+}
+
+// collectBlockText joins the text of block-level elements with newlines
+func collectBlockText(s *goquery.Selection) string {
+	var blocks []string
+	s.Find("h1, h2, h3, h4, h5, h6, p, li, pre, blockquote, td, figcaption").Each(func(_ int, block *goquery.Selection) {
+		// Nested blocks are collected on their own
+		if block.Find("p, li, pre, blockquote").Length() > 0 {
+			return
+		}
+		if text := normalizeWhitespace(block.Text()); text != "" {
+			blocks = append(blocks, text)
+		}
+	})
+	return strings.Join(blocks, "\n")
+}
+
+// normalizeWhitespace collapses runs of whitespace into single spaces
+func normalizeWhitespace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// detectLanguage prefers the declared document language and otherwise
+// guesses from the script and function words of the text
+func detectLanguage(declared, text string) string {
+	if declared != "" {
+		lang := strings.ToLower(strings.SplitN(strings.SplitN(declared, "-", 2)[0], "_", 2)[0])
+		if lang != "" {
+			return lang
+		}
+	}
+
+	var letters, cyrillic int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Cyrillic, r) {
+			cyrillic++
+		}
+	}
+	if letters == 0 {
+		return "unknown"
+	}
+	if float64(cyrillic)/float64(letters) > 0.5 {
+		return "ru"
+	}
+
+	counts := make(map[string]int)
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		word = strings.Trim(word, ".,?!:;()\"'«»")
+		counts[word]++
+	}
+
+	bestLang := "unknown"
+	bestHits := 0
+	for lang, stopWords := range languageStopWords {
+		hits := 0
+		for _, word := range stopWords {
+			hits += counts[word]
+		}
+		if hits > bestHits {
+			bestLang = lang
+			bestHits = hits
+		}
+	}
+	return bestLang
+}
@@ -0,0 +1,521 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// Limits of a session recording
+const (
+	maxScreencastFrames = 300
+	maxScreencastBytes  = 20 << 20
+	maxHAREntries       = 2000
+)
+
+// redactedHeaders are not written to the HAR, as request overrides may
+// carry session tokens
+var redactedHeaders = map[string]bool{
+	"cookie":        true,
+	"set-cookie":    true,
+	"authorization": true,
+}
+
+// SessionRecording is what the headless browser experienced while loading
+// the page: every request as a HAR and a screencast of the viewport
+type SessionRecording struct {
+	HAR    *HAR              `json:"har"`
+	Frames []ScreencastFrame `json:"-"`
+}
+
+// ScreencastFrame is one JPEG frame of the screencast
+type ScreencastFrame struct {
+	Offset time.Duration `json:"offset"` // since the recording started
+	Data   []byte        `json:"-"`
+}
+
+// HAR is an HTTP Archive 1.2 document
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the log of a HAR document
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Pages   []HARPage  `json:"pages"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator names the application that created the HAR
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HARPage is a page of a HAR document
+type HARPage struct {
+	StartedDateTime time.Time      `json:"startedDateTime"`
+	ID              string         `json:"id"`
+	Title           string         `json:"title"`
+	PageTimings     HARPageTimings `json:"pageTimings"`
+}
+
+// HARPageTimings are the load timings of a page in milliseconds, -1 if unknown
+type HARPageTimings struct {
+	OnContentLoad float64 `json:"onContentLoad"`
+	OnLoad        float64 `json:"onLoad"`
+}
+
+// HAREntry is one request and its response
+type HAREntry struct {
+	PageRef         string      `json:"pageref"`
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	ResourceType    string      `json:"_resourceType,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+
+	started *cdp.MonotonicTime
+}
+
+// HARRequest is the request of a HAR entry
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	Cookies     []HARNameValue `json:"cookies"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse is the response of a HAR entry
+type HARResponse struct {
+	Status      int64          `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []HARNameValue `json:"headers"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARContent describes a response body; the body itself is not recorded
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+// HARTimings are the phases of a request in milliseconds, -1 if unknown
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// HARNameValue is a header, query parameter or cookie
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// sessionRecorder collects the HAR and screencast of a page load from
+// browser events
+type sessionRecorder struct {
+	mu         sync.Mutex
+	started    time.Time
+	entries    map[network.RequestID]*HAREntry
+	finished   []*HAREntry
+	frames     []ScreencastFrame
+	frameBytes int
+}
+
+func newSessionRecorder() *sessionRecorder {
+	return &sessionRecorder{
+		started: time.Now(),
+		entries: make(map[network.RequestID]*HAREntry),
+	}
+}
+
+// listen subscribes the recorder to the events of a browser tab
+func (r *sessionRecorder) listen(ctx context.Context) {
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch e := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			r.requestWillBeSent(e)
+		case *network.EventResponseReceived:
+			r.mu.Lock()
+			if entry, ok := r.entries[e.RequestID]; ok {
+				setHARResponse(entry, e.Response)
+			}
+			r.mu.Unlock()
+		case *network.EventLoadingFinished:
+			r.finish(e.RequestID, e.Timestamp, e.EncodedDataLength, "")
+		case *network.EventLoadingFailed:
+			r.finish(e.RequestID, e.Timestamp, -1, e.ErrorText)
+		case *page.EventScreencastFrame:
+			r.frame(e)
+			// Frames stop until the previous one is acknowledged; the
+			// listener must not block, so acknowledge asynchronously
+			go func() {
+				if c := chromedp.FromContext(ctx); c != nil && c.Target != nil {
+					page.ScreencastFrameAck(e.SessionID).Do(cdp.WithExecutor(ctx, c.Target))
+				}
+			}()
+		}
+	})
+}
+
+// startScreencast starts capturing frames of the viewport
+func (r *sessionRecorder) startScreencast() chromedp.Action {
+	return page.StartScreencast().
+		WithFormat(page.ScreencastFormatJpeg).
+		WithQuality(60).
+		WithMaxWidth(1280).
+		WithMaxHeight(720).
+		WithEveryNthFrame(2)
+}
+
+// stopScreencast stops capturing frames
+func (r *sessionRecorder) stopScreencast() chromedp.Action {
+	return page.StopScreencast()
+}
+
+func (r *sessionRecorder) requestWillBeSent(e *network.EventRequestWillBeSent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// A redirect reuses the request ID: complete the redirected entry first
+	if previous, ok := r.entries[e.RequestID]; ok && e.RedirectResponse != nil {
+		setHARResponse(previous, e.RedirectResponse)
+		previous.Response.RedirectURL = e.Request.URL
+		r.complete(previous, e.Timestamp)
+		delete(r.entries, e.RequestID)
+	}
+	if len(r.entries)+len(r.finished) >= maxHAREntries {
+		return
+	}
+
+	started := time.Now()
+	if e.WallTime != nil {
+		started = e.WallTime.Time()
+	}
+	requestURL := e.Request.URL + e.Request.URLFragment
+	entry := &HAREntry{
+		PageRef:         "page_1",
+		StartedDateTime: started,
+		Request: HARRequest{
+			Method:      e.Request.Method,
+			URL:         requestURL,
+			HTTPVersion: "HTTP/1.1",
+			Headers:     harHeaders(e.Request.Headers),
+			QueryString: harQueryString(requestURL),
+			Cookies:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Response: HARResponse{
+			HTTPVersion: "HTTP/1.1",
+			Headers:     []HARNameValue{},
+			Cookies:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings:      HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Send: 0, Wait: -1, Receive: -1},
+		ResourceType: string(e.Type),
+		started:      e.Timestamp,
+	}
+	r.entries[e.RequestID] = entry
+}
+
+func (r *sessionRecorder) finish(id network.RequestID, at *cdp.MonotonicTime, size float64, errorText string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[id]
+	if !ok {
+		return
+	}
+	if size >= 0 {
+		entry.Response.BodySize = int64(size)
+		entry.Response.Content.Size = int64(size)
+	}
+	entry.Error = errorText
+	r.complete(entry, at)
+	delete(r.entries, id)
+}
+
+// complete sets the total time of an entry and moves it to the finished ones
+func (r *sessionRecorder) complete(entry *HAREntry, at *cdp.MonotonicTime) {
+	if entry.started != nil && at != nil {
+		entry.Time = float64(at.Time().Sub(entry.started.Time())) / float64(time.Millisecond)
+		if entry.Timings.Wait >= 0 {
+			entry.Timings.Receive = entry.Time - entry.Timings.Wait - nonNegative(entry.Timings.Blocked) -
+				nonNegative(entry.Timings.DNS) - nonNegative(entry.Timings.Connect) - entry.Timings.Send
+			if entry.Timings.Receive < 0 {
+				entry.Timings.Receive = 0
+			}
+		}
+	}
+	// Send, wait and receive are required: without response timing the
+	// whole request counts as waiting
+	if entry.Timings.Wait < 0 {
+		entry.Timings.Wait = entry.Time
+		entry.Timings.Receive = 0
+	}
+	r.finished = append(r.finished, entry)
+}
+
+func (r *sessionRecorder) frame(e *page.EventScreencastFrame) {
+	data, err := base64.StdEncoding.DecodeString(e.Data)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.frames) >= maxScreencastFrames || r.frameBytes+len(data) > maxScreencastBytes {
+		return
+	}
+	at := time.Now()
+	if e.Metadata != nil && e.Metadata.Timestamp != nil {
+		at = e.Metadata.Timestamp.Time()
+	}
+	offset := at.Sub(r.started)
+	if offset < 0 {
+		offset = 0
+	}
+	r.frames = append(r.frames, ScreencastFrame{Offset: offset, Data: data})
+	r.frameBytes += len(data)
+}
+
+// recording returns what was recorded so far. Requests still in flight are
+// included without a response.
+func (r *sessionRecorder) recording(pageURL, title string) *SessionRecording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]HAREntry, 0, len(r.finished)+len(r.entries))
+	for _, entry := range r.finished {
+		entries = append(entries, *entry)
+	}
+	for _, entry := range r.entries {
+		pending := *entry
+		pending.Error = "request did not complete while recording"
+		pending.Timings.Wait = nonNegative(pending.Timings.Wait)
+		pending.Timings.Receive = nonNegative(pending.Timings.Receive)
+		entries = append(entries, pending)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedDateTime.Before(entries[j].StartedDateTime)
+	})
+
+	if title == "" {
+		title = pageURL
+	}
+	return &SessionRecording{
+		HAR: &HAR{Log: HARLog{
+			Version: "1.2",
+			Creator: HARCreator{Name: "website_optimizer", Version: "1.0"},
+			Pages: []HARPage{{
+				StartedDateTime: r.started,
+				ID:              "page_1",
+				Title:           title,
+				PageTimings:     HARPageTimings{OnContentLoad: -1, OnLoad: -1},
+			}},
+			Entries: entries,
+		}},
+		Frames: append([]ScreencastFrame(nil), r.frames...),
+	}
+}
+
+// setHARResponse copies a browser response into a HAR entry
+func setHARResponse(entry *HAREntry, response *network.Response) {
+	if response == nil {
+		return
+	}
+	entry.Response.Status = response.Status
+	entry.Response.StatusText = response.StatusText
+	entry.Response.Headers = harHeaders(response.Headers)
+	entry.Response.Content.MimeType = response.MimeType
+	if location, ok := response.Headers["Location"].(string); ok {
+		entry.Response.RedirectURL = location
+	} else if location, ok := response.Headers["location"].(string); ok {
+		entry.Response.RedirectURL = location
+	}
+	if response.Protocol != "" {
+		version := strings.ToUpper(response.Protocol)
+		entry.Request.HTTPVersion = version
+		entry.Response.HTTPVersion = version
+	}
+	entry.ServerIPAddress = response.RemoteIPAddress
+
+	if t := response.Timing; t != nil {
+		entry.Timings.Blocked = nonNegative(t.DNSStart)
+		if t.DNSStart >= 0 {
+			entry.Timings.DNS = t.DNSEnd - t.DNSStart
+		}
+		if t.ConnectStart >= 0 {
+			entry.Timings.Connect = t.ConnectEnd - t.ConnectStart
+		}
+		if t.SslStart >= 0 {
+			entry.Timings.SSL = t.SslEnd - t.SslStart
+		}
+		entry.Timings.Send = nonNegative(t.SendEnd - t.SendStart)
+		entry.Timings.Wait = nonNegative(t.ReceiveHeadersEnd - t.SendEnd)
+	}
+}
+
+// harHeaders converts browser headers to sorted HAR headers, redacting
+// credentials
+func harHeaders(headers network.Headers) []HARNameValue {
+	values := make([]HARNameValue, 0, len(headers))
+	for name, value := range headers {
+		text := fmt.Sprint(value)
+		if redactedHeaders[strings.ToLower(name)] {
+			text = "[redacted]"
+		}
+		values = append(values, HARNameValue{Name: name, Value: text})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+	return values
+}
+
+// harQueryString returns the query parameters of a URL
+func harQueryString(rawURL string) []HARNameValue {
+	values := []HARNameValue{}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return values
+	}
+	query := u.Query()
+	for _, name := range sortedQueryKeys(query) {
+		for _, value := range query[name] {
+			values = append(values, HARNameValue{Name: name, Value: value})
+		}
+	}
+	return values
+}
+
+func sortedQueryKeys(query url.Values) []string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func nonNegative(value float64) float64 {
+	if value < 0 {
+		return 0
+	}
+	return value
+}
+
+// HARJSON returns the HAR document of a recording
+func (r *SessionRecording) HARJSON() ([]byte, error) {
+	return json.MarshalIndent(r.HAR, "", "  ")
+}
+
+// screencastPlayer plays the frames of a screencast archive in a browser
+const screencastPlayer = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Screencast</title>
+<style>body{margin:0;background:#111;color:#eee;font:14px sans-serif;text-align:center}img{max-width:100%;margin-top:12px}</style>
+</head><body><div id="time"></div><img id="frame" alt="">
+<script src="frames.js"></script>
+<script>
+const img = document.getElementById("frame"), time = document.getElementById("time");
+let i = 0;
+function show() {
+  if (i >= frames.length) { i = 0; setTimeout(show, 1500); return; }
+  img.src = frames[i].file;
+  time.textContent = (frames[i].offset_ms / 1000).toFixed(2) + " s";
+  const next = i + 1 < frames.length ? frames[i + 1].offset_ms - frames[i].offset_ms : 1500;
+  i++;
+  setTimeout(show, Math.max(next, 16));
+}
+show();
+</script></body></html>
+`
+
+// ScreencastZip packs the screencast frames into a ZIP archive with a
+// manifest of frame offsets and an HTML page that plays them back
+func (r *SessionRecording) ScreencastZip() ([]byte, error) {
+	if len(r.Frames) == 0 {
+		return nil, fmt.Errorf("recording has no screencast frames")
+	}
+
+	type manifestFrame struct {
+		File     string `json:"file"`
+		OffsetMS int64  `json:"offset_ms"`
+	}
+	manifest := make([]manifestFrame, len(r.Frames))
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for i, frame := range r.Frames {
+		name := fmt.Sprintf("frames/%04d.jpg", i)
+		// JPEG frames are already compressed
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(frame.Data); err != nil {
+			return nil, err
+		}
+		manifest[i] = manifestFrame{File: name, OffsetMS: frame.Offset.Milliseconds()}
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{"manifest.json", manifestJSON},
+		{"frames.js", append([]byte("const frames = "), append(manifestJSON, ';', '\n')...)},
+		{"index.html", []byte(screencastPlayer)},
+	}
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(file.content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,338 @@
+package parser
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	// sitemapCrawlDepth limits how many links away from the analyzed page the crawl goes
+	sitemapCrawlDepth = 3
+	// sitemapCrawlConcurrency is the number of pages fetched at the same time
+	sitemapCrawlConcurrency = 4
+	// maxSitemapPageSize limits the body read from each crawled page
+	maxSitemapPageSize = 2 << 20
+	// sitemapNamespace is the XML namespace of the sitemap protocol
+	sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+)
+
+// nonPageExtensions are file extensions of links that are not HTML pages
+var nonPageExtensions = map[string]bool{
+	".pdf": true, ".zip": true, ".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
+	".webp": true, ".svg": true, ".mp4": true, ".mp3": true, ".css": true, ".js": true,
+	".xml": true, ".json": true, ".doc": true, ".docx": true, ".xls": true, ".xlsx": true,
+}
+
+// SitemapURL is an entry of a generated sitemap
+type SitemapURL struct {
+	Loc     string `json:"loc" xml:"loc"`
+	LastMod string `json:"lastmod,omitempty" xml:"lastmod,omitempty"`
+}
+
+// SitemapCrawl is the outcome of crawling a site to propose a sitemap
+type SitemapCrawl struct {
+	// URLs are the indexable canonical pages found
+	URLs []SitemapURL `json:"urls"`
+	// Crawled is the number of pages fetched
+	Crawled int `json:"crawled"`
+	// Noindex lists pages left out because of a noindex directive
+	Noindex []string `json:"noindex,omitempty"`
+	// Canonicalized maps pages left out to the canonical URL they declare
+	Canonicalized map[string]string `json:"canonicalized,omitempty"`
+	// Failed is the number of pages that could not be fetched or were not HTML
+	Failed int `json:"failed"`
+	// Truncated is set when the page limit stopped the crawl
+	Truncated bool `json:"truncated"`
+	// Discovered lists every link to the site found on crawled pages
+	Discovered []string `json:"-"`
+}
+
+// crawledPage is what the crawl learns from fetching one page
+type crawledPage struct {
+	url       string
+	ok        bool
+	noindex   bool
+	canonical string
+	lastMod   string
+	links     []string
+}
+
+// FindSitemap returns the first sitemap declared in robots.txt or, failing
+// that, /sitemap.xml when it is served
+func FindSitemap(ctx context.Context, client *http.Client, pageURL string) (string, bool) {
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return "", false
+	}
+
+	robotsURL := page.ResolveReference(&url.URL{Path: "/robots.txt"})
+	if body, resp, err := fetchPage(ctx, client, robotsURL.String()); err == nil && resp.StatusCode == http.StatusOK {
+		scanner := bufio.NewScanner(strings.NewReader(string(body)))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if len(line) > 8 && strings.EqualFold(line[:8], "sitemap:") {
+				return strings.TrimSpace(line[8:]), true
+			}
+		}
+	}
+
+	fallback := page.ResolveReference(&url.URL{Path: "/sitemap.xml"})
+	if _, resp, err := fetchPage(ctx, client, fallback.String()); err == nil && resp.StatusCode == http.StatusOK {
+		return fallback.String(), true
+	}
+	return "", false
+}
+
+// CrawlForSitemap follows internal links from an analyzed page, breadth
+// first, and collects the indexable pages of the site. Pages with a noindex
+// directive are left out, and pages declaring another canonical URL are
+// replaced by that URL. At most maxPages pages are fetched.
+func CrawlForSitemap(ctx context.Context, client *http.Client, data *WebsiteData, maxPages int) *SitemapCrawl {
+	startURL := data.FinalURL
+	if startURL == "" {
+		startURL = data.URL
+	}
+	start, err := url.Parse(startURL)
+	if err != nil || start.Host == "" {
+		return &SitemapCrawl{Canonicalized: make(map[string]string)}
+	}
+
+	markup := data.RawHTML
+	if markup == "" {
+		markup = data.HTML
+	}
+	first := inspectPage(start, markup)
+	first.ok = data.StatusCode == 0 || data.StatusCode == http.StatusOK
+	return crawlSite(ctx, client, start, first, maxPages)
+}
+
+// CrawlURL crawls a site like CrawlForSitemap, starting with a fetch of pageURL
+func CrawlURL(ctx context.Context, client *http.Client, pageURL string, maxPages int) (*SitemapCrawl, error) {
+	start, err := url.Parse(pageURL)
+	if err != nil || start.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q", pageURL)
+	}
+	first := crawlPage(ctx, client, normalizePageURL(start))
+	if !first.ok {
+		return nil, fmt.Errorf("failed to fetch %s", pageURL)
+	}
+	if final, err := url.Parse(first.url); err == nil {
+		start = final
+	}
+	return crawlSite(ctx, client, start, first, maxPages), nil
+}
+
+// crawlSite continues a crawl from an inspected first page
+func crawlSite(ctx context.Context, client *http.Client, start *url.URL, first crawledPage, maxPages int) *SitemapCrawl {
+	crawl := &SitemapCrawl{
+		Canonicalized: make(map[string]string),
+		Crawled:       1,
+		Discovered:    []string{first.url},
+	}
+
+	seen := map[string]bool{first.url: true}
+	indexable := make(map[string]string) // URL -> lastmod
+	var mu sync.Mutex
+
+	// record applies the result of a page and returns the unseen links to crawl
+	record := func(page crawledPage) []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case !page.ok:
+			crawl.Failed++
+		case page.noindex:
+			crawl.Noindex = append(crawl.Noindex, page.url)
+		case page.canonical != "" && page.canonical != page.url:
+			crawl.Canonicalized[page.url] = page.canonical
+		default:
+			indexable[page.url] = page.lastMod
+		}
+
+		var next []string
+		candidates := page.links
+		if page.canonical != "" && page.canonical != page.url {
+			candidates = append([]string{page.canonical}, candidates...)
+		}
+		for _, link := range candidates {
+			if !seen[link] && sameSite(start, link) {
+				seen[link] = true
+				crawl.Discovered = append(crawl.Discovered, link)
+				next = append(next, link)
+			}
+		}
+		return next
+	}
+
+	frontier := record(first)
+	for depth := 1; depth <= sitemapCrawlDepth && len(frontier) > 0; depth++ {
+		if remaining := maxPages - crawl.Crawled; len(frontier) > remaining {
+			frontier = frontier[:remaining]
+			crawl.Truncated = true
+		}
+		crawl.Crawled += len(frontier)
+
+		var next []string
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, sitemapCrawlConcurrency)
+		for _, link := range frontier {
+			if ctx.Err() != nil {
+				break
+			}
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(link string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				found := record(crawlPage(ctx, client, link))
+				mu.Lock()
+				next = append(next, found...)
+				mu.Unlock()
+			}(link)
+		}
+		wg.Wait()
+
+		sort.Strings(next)
+		frontier = next
+		if crawl.Crawled >= maxPages && len(frontier) > 0 {
+			crawl.Truncated = true
+			break
+		}
+	}
+
+	for loc, lastMod := range indexable {
+		crawl.URLs = append(crawl.URLs, SitemapURL{Loc: loc, LastMod: lastMod})
+	}
+	sort.Slice(crawl.URLs, func(i, j int) bool { return crawl.URLs[i].Loc < crawl.URLs[j].Loc })
+	sort.Strings(crawl.Noindex)
+	sort.Strings(crawl.Discovered)
+	return crawl
+}
+
+// XML renders the indexable pages as a sitemap.xml document
+func (c *SitemapCrawl) XML() ([]byte, error) {
+	urlset := struct {
+		XMLName xml.Name     `xml:"urlset"`
+		Xmlns   string       `xml:"xmlns,attr"`
+		URLs    []SitemapURL `xml:"url"`
+	}{Xmlns: sitemapNamespace, URLs: c.URLs}
+
+	body, err := xml.MarshalIndent(urlset, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(body, '\n')...), nil
+}
+
+// crawlPage fetches a page and inspects it. Redirects are followed; the page
+// is recorded under the URL it was finally served from.
+func crawlPage(ctx context.Context, client *http.Client, pageURL string) crawledPage {
+	body, resp, err := fetchPage(ctx, client, pageURL)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return crawledPage{url: pageURL}
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" {
+		return crawledPage{url: pageURL}
+	}
+
+	page := inspectPage(resp.Request.URL, string(body))
+	page.ok = true
+	if strings.Contains(strings.ToLower(resp.Header.Get("X-Robots-Tag")), "noindex") {
+		page.noindex = true
+	}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		page.lastMod = modified.UTC().Format("2006-01-02")
+	}
+	return page
+}
+
+// inspectPage reads the robots directives, canonical URL and links of a page
+func inspectPage(pageURL *url.URL, markup string) crawledPage {
+	page := crawledPage{url: normalizePageURL(pageURL)}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(markup))
+	if err != nil {
+		return page
+	}
+
+	doc.Find("meta[name]").Each(func(_ int, s *goquery.Selection) {
+		name, _ := s.Attr("name")
+		name = strings.ToLower(name)
+		if name != "robots" && name != "googlebot" {
+			return
+		}
+		if content, _ := s.Attr("content"); strings.Contains(strings.ToLower(content), "noindex") {
+			page.noindex = true
+		}
+	})
+
+	if href, ok := doc.Find(`link[rel="canonical"]`).First().Attr("href"); ok && strings.TrimSpace(href) != "" {
+		if canonical, err := pageURL.Parse(strings.TrimSpace(href)); err == nil {
+			page.canonical = normalizePageURL(canonical)
+		}
+	}
+
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		link, err := pageURL.Parse(strings.TrimSpace(href))
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+			return
+		}
+		if nonPageExtensions[strings.ToLower(path.Ext(link.Path))] {
+			return
+		}
+		page.links = append(page.links, normalizePageURL(link))
+	})
+	return page
+}
+
+// fetchPage GETs a URL with the desktop user agent
+func fetchPage(ctx context.Context, client *http.Client, target string) ([]byte, *http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", DesktopDevice.UserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSitemapPageSize))
+	return body, resp, err
+}
+
+// normalizePageURL drops the fragment and lowercases scheme and host, so
+// links to the same page compare equal
+func normalizePageURL(u *url.URL) string {
+	normalized := *u
+	normalized.Fragment = ""
+	normalized.RawFragment = ""
+	normalized.Scheme = strings.ToLower(normalized.Scheme)
+	normalized.Host = strings.ToLower(normalized.Host)
+	if normalized.Path == "" {
+		normalized.Path = "/"
+	}
+	return normalized.String()
+}
+
+// sameSite reports whether a link points to the host of the start page
+func sameSite(start *url.URL, link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && strings.EqualFold(u.Host, start.Host)
+}
@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// snapshotTransport answers requests for the page with stored markup
+// instead of fetching it again
+type snapshotTransport struct {
+	pageURL string
+	html    string
+}
+
+func (t *snapshotTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.TrimSuffix(req.URL.String(), "/") != strings.TrimSuffix(t.pageURL, "/") {
+		return nil, fmt.Errorf("snapshot only contains %s", t.pageURL)
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"text/html; charset=utf-8"}},
+		Body:          io.NopCloser(strings.NewReader(t.html)),
+		ContentLength: int64(len(t.html)),
+		Request:       req,
+	}, nil
+}
+
+// ParseSnapshot parses previously fetched markup of a page as if it had been
+// fetched from targetURL. Only the page itself comes from the snapshot: link
+// statuses and image sizes are still checked over the network. The load time
+// of the result does not reflect the site.
+func ParseSnapshot(targetURL, html string, options ...ParseOptions) (*WebsiteData, error) {
+	opts := DefaultParseOptions()
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if !strings.HasPrefix(targetURL, "http://") && !strings.HasPrefix(targetURL, "https://") {
+		targetURL = "https://" + targetURL
+	}
+
+	opts.UseHeadlessBrowser = false
+	opts.ExecuteJavaScript = false
+	opts.CaptureScreenshots = false
+	opts.MaxRetries = 0
+	opts.Transport = &snapshotTransport{pageURL: targetURL, html: html}
+
+	return ParseWebsite(targetURL, opts)
+}
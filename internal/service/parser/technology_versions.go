@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// VersionSupport is the oldest version of a technology that still receives
+// security fixes
+type VersionSupport struct {
+	Name           string `json:"name"`
+	MinimumVersion string `json:"minimum_version"`
+	Reason         string `json:"reason"`
+}
+
+// supportedVersions lists the technologies detected with a version whose
+// older releases are end of life or have known vulnerabilities
+var supportedVersions = map[string]VersionSupport{
+	"jQuery": {
+		Name:           "jQuery",
+		MinimumVersion: "3.5.0",
+		Reason:         "Versions before 3.5.0 are affected by XSS vulnerabilities in htmlPrefilter (CVE-2020-11022, CVE-2020-11023)",
+	},
+	"Bootstrap": {
+		Name:           "Bootstrap",
+		MinimumVersion: "5.0.0",
+		Reason:         "Bootstrap 3 and 4 reached end of life and no longer receive fixes",
+	},
+	"WordPress": {
+		Name:           "WordPress",
+		MinimumVersion: "6.0",
+		Reason:         "Only recent WordPress branches receive security releases",
+	},
+	"Drupal": {
+		Name:           "Drupal",
+		MinimumVersion: "10.0",
+		Reason:         "Drupal 7 and 9 reached end of life",
+	},
+	"Joomla": {
+		Name:           "Joomla",
+		MinimumVersion: "4.0",
+		Reason:         "Joomla 3 reached end of life",
+	},
+	"Gatsby": {
+		Name:           "Gatsby",
+		MinimumVersion: "5.0.0",
+		Reason:         "Only the latest Gatsby major version is maintained",
+	},
+}
+
+// OutdatedVersion reports whether a detected version of a technology is older
+// than its oldest supported version. Technologies without a known support
+// policy and versions that cannot be compared are never outdated.
+func OutdatedVersion(name, version string) (VersionSupport, bool) {
+	support, ok := supportedVersions[name]
+	if !ok || version == "" {
+		return VersionSupport{}, false
+	}
+	cmp, ok := CompareVersions(version, support.MinimumVersion)
+	if !ok {
+		return VersionSupport{}, false
+	}
+	return support, cmp < 0
+}
+
+// CompareVersions compares dotted numeric versions such as 3.4.1 and 3.5,
+// where missing components count as zero. It returns -1, 0 or 1, and false
+// if either version is not numeric.
+func CompareVersions(a, b string) (int, bool) {
+	left, ok := versionParts(a)
+	if !ok {
+		return 0, false
+	}
+	right, ok := versionParts(b)
+	if !ok {
+		return 0, false
+	}
+
+	for i := 0; i < len(left) || i < len(right); i++ {
+		var l, r int
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		switch {
+		case l < r:
+			return -1, true
+		case l > r:
+			return 1, true
+		}
+	}
+	return 0, true
+}
+
+// versionParts splits a version into its numeric components, ignoring a
+// leading "v" and pre-release or build suffixes
+func versionParts(version string) ([]int, bool) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+ "); i >= 0 {
+		version = version[:i]
+	}
+	version = strings.TrimSuffix(version, ".")
+	if version == "" {
+		return nil, false
+	}
+
+	fields := strings.Split(version, ".")
+	parts := make([]int, 0, len(fields))
+	for _, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
@@ -0,0 +1,191 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	// maxTopicPhrases limits how many phrases are kept per page
+	maxTopicPhrases = 80
+	// headingPhraseWeight counts a phrase in a heading as this many mentions
+	headingPhraseWeight = 3
+)
+
+// topicStopWords are function and filler words that never start or end a
+// topic phrase. The language detection words are added in init.
+var topicStopWords = map[string]bool{
+	"a": true, "an": true, "are": true, "as": true, "at": true, "be": true, "by": true, "can": true,
+	"do": true, "from": true, "has": true, "have": true, "how": true, "if": true, "it": true, "its": true,
+	"more": true, "most": true, "my": true, "no": true, "not": true, "on": true, "or": true, "our": true,
+	"so": true, "than": true, "their": true, "them": true, "then": true, "there": true, "these": true,
+	"they": true, "was": true, "we": true, "what": true, "when": true, "where": true, "which": true,
+	"who": true, "why": true, "will": true, "would": true, "your": true, "all": true, "also": true,
+	"about": true, "into": true, "just": true, "only": true, "other": true, "out": true, "some": true,
+	"such": true, "up": true, "use": true, "very": true, "get": true, "one": true, "two": true,
+	"и": true, "в": true, "во": true, "не": true, "на": true, "с": true, "со": true, "что": true,
+	"как": true, "а": true, "по": true, "к": true, "у": true, "из": true, "за": true, "от": true,
+	"для": true, "о": true, "об": true, "или": true, "но": true, "это": true, "так": true, "же": true,
+	"вы": true, "мы": true, "он": true, "она": true, "они": true, "все": true, "еще": true, "уже": true,
+	"при": true, "до": true, "без": true, "если": true, "чтобы": true, "вам": true, "ваш": true, "наш": true,
+}
+
+func init() {
+	for _, words := range languageStopWords {
+		for _, word := range words {
+			topicStopWords[word] = true
+		}
+	}
+}
+
+// PageHeading is an h1-h4 heading of a page
+type PageHeading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+}
+
+// PageTopics holds the headings and most frequent phrases of a page, used to
+// compare the topical coverage of pages targeting the same keyword
+type PageTopics struct {
+	URL       string         `json:"url"`
+	Title     string         `json:"title"`
+	Headings  []PageHeading  `json:"headings"`
+	Phrases   map[string]int `json:"phrases"`
+	WordCount int            `json:"word_count"`
+	// Text is the normalized lowercase main text, used for containment checks
+	Text string `json:"-"`
+}
+
+// Covers reports whether the page mentions a phrase in its title, headings
+// or main text
+func (t *PageTopics) Covers(phrase string) bool {
+	if _, ok := t.Phrases[phrase]; ok {
+		return true
+	}
+	return strings.Contains(" "+t.Text+" ", " "+phrase+" ")
+}
+
+// ExtractTopics extracts the headings and the most frequent one- and two-word
+// phrases of the main content of an HTML document
+func ExtractTopics(html string) PageTopics {
+	topics := PageTopics{Phrases: make(map[string]int)}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return topics
+	}
+
+	topics.Title = normalizeWhitespace(doc.Find("head title").First().Text())
+	doc.Find("h1, h2, h3, h4").Each(func(_ int, s *goquery.Selection) {
+		text := normalizeWhitespace(s.Text())
+		if text == "" {
+			return
+		}
+		topics.Headings = append(topics.Headings, PageHeading{
+			Level: int(goquery.NodeName(s)[1] - '0'),
+			Text:  text,
+		})
+	})
+
+	content := ExtractMainContent(html)
+	topics.WordCount = content.WordCount
+
+	counts := make(map[string]int)
+	words := TopicWords(content.Text)
+	topics.Text = strings.Join(words, " ")
+	countPhrases(counts, words, 1)
+	countPhrases(counts, TopicWords(topics.Title), headingPhraseWeight)
+	for _, heading := range topics.Headings {
+		headingWords := TopicWords(heading.Text)
+		countPhrases(counts, headingWords, headingPhraseWeight)
+		topics.Text += " " + strings.Join(headingWords, " ")
+	}
+
+	phrases := make([]string, 0, len(counts))
+	for phrase, count := range counts {
+		// Single mentions in body text are noise
+		if count > 1 {
+			phrases = append(phrases, phrase)
+		}
+	}
+	sort.Slice(phrases, func(i, j int) bool {
+		if counts[phrases[i]] != counts[phrases[j]] {
+			return counts[phrases[i]] > counts[phrases[j]]
+		}
+		return phrases[i] < phrases[j]
+	})
+	if len(phrases) > maxTopicPhrases {
+		phrases = phrases[:maxTopicPhrases]
+	}
+	for _, phrase := range phrases {
+		topics.Phrases[phrase] = counts[phrase]
+	}
+
+	return topics
+}
+
+// TopicWords splits text into lowercase words, dropping punctuation
+func TopicWords(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+	words := fields[:0]
+	for _, field := range fields {
+		if field = strings.Trim(field, "-"); field != "" {
+			words = append(words, field)
+		}
+	}
+	return words
+}
+
+// countPhrases adds single words and word pairs that do not start or end with
+// a stop word
+func countPhrases(counts map[string]int, words []string, weight int) {
+	for i, word := range words {
+		if !isTopicWord(word) {
+			continue
+		}
+		counts[word] += weight
+		if i+1 < len(words) && isTopicWord(words[i+1]) {
+			counts[word+" "+words[i+1]] += weight
+		}
+	}
+}
+
+// isTopicWord reports whether a word can be part of a topic phrase
+func isTopicWord(word string) bool {
+	if len([]rune(word)) < 3 || topicStopWords[word] {
+		return false
+	}
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// FetchTopics fetches a page and extracts its topics. The page is fetched
+// through the public-only transport since its URL is user supplied.
+func FetchTopics(ctx context.Context, targetURL string, timeout time.Duration) (*PageTopics, error) {
+	client := &http.Client{Timeout: timeout, Transport: PublicTransport()}
+
+	resp, body, err := fetchDocument(ctx, client, targetURL, "", "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("page returned status %d", resp.StatusCode)
+	}
+
+	topics := ExtractTopics(string(body))
+	topics.URL = resp.Request.URL.String()
+	return &topics, nil
+}
@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// URLStatus is how a URL answered a HEAD request. Redirects are not followed:
+// RedirectTo is the absolute target of a 3xx response.
+type URLStatus struct {
+	URL          string        `json:"url"`
+	StatusCode   int           `json:"status_code"`
+	RedirectTo   string        `json:"redirect_to,omitempty"`
+	ResponseTime time.Duration `json:"response_time"`
+	Error        string        `json:"error,omitempty"`
+	Cached       bool          `json:"cached,omitempty"`
+}
+
+// URLCheckOptions tunes a bulk URL check
+type URLCheckOptions struct {
+	Concurrency int
+	// PerHostRate limits requests per second sent to a single host
+	PerHostRate float64
+	Timeout     time.Duration
+	// Transport replaces the network transport, e.g. with PublicTransport
+	Transport http.RoundTripper
+	// ParseOptions supplies the user agent, headers and retry policy
+	ParseOptions ParseOptions
+}
+
+// CheckURLs sends a HEAD request to every URL concurrently and returns their
+// statuses in the order given. Servers refusing HEAD are asked with GET.
+func CheckURLs(ctx context.Context, urls []string, opts URLCheckOptions) []URLStatus {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	client := &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: opts.Transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	var limitersMu sync.Mutex
+	limiters := make(map[string]*rate.Limiter)
+	limiterFor := func(host string) *rate.Limiter {
+		limitersMu.Lock()
+		defer limitersMu.Unlock()
+		limiter, ok := limiters[host]
+		if !ok {
+			limiter = rate.NewLimiter(rate.Limit(opts.PerHostRate), 1)
+			limiters[host] = limiter
+		}
+		return limiter
+	}
+
+	results := make([]URLStatus, len(urls))
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, opts.Concurrency)
+	for i, target := range urls {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, target string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			result := URLStatus{URL: target}
+			if u, err := url.Parse(target); err == nil && opts.PerHostRate > 0 {
+				if err := limiterFor(u.Host).Wait(ctx); err != nil {
+					result.Error = err.Error()
+					results[i] = result
+					return
+				}
+			}
+			results[i] = checkURL(ctx, client, target, opts.ParseOptions)
+		}(i, target)
+	}
+	wg.Wait()
+	return results
+}
+
+// checkURL requests a single URL, falling back to GET when HEAD is refused
+func checkURL(ctx context.Context, client *http.Client, target string, opts ParseOptions) URLStatus {
+	result := URLStatus{URL: target}
+	start := time.Now()
+	resp, err := headWithRetry(ctx, client, target, opts)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		var req *http.Request
+		if req, err = http.NewRequestWithContext(ctx, http.MethodGet, target, nil); err == nil {
+			req.Header.Set("User-Agent", opts.UserAgent)
+			resp, err = client.Do(req)
+		}
+	}
+	result.ResponseTime = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	if location := resp.Header.Get("Location"); location != "" && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		if redirect, err := resp.Request.URL.Parse(location); err == nil {
+			result.RedirectTo = redirect.String()
+		}
+	}
+	return result
+}
@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxVariantBodySize limits how much of a locale variant is read
+const maxVariantBodySize = 5 << 20
+
+// HreflangLink is an alternate language version declared by a page
+type HreflangLink struct {
+	Lang string `json:"hreflang"`
+	URL  string `json:"href"`
+}
+
+// LocaleVariant holds the locale-dependent signals of a page as served for
+// one Accept-Language value
+type LocaleVariant struct {
+	Locale          string         `json:"locale,omitempty"`
+	URL             string         `json:"url"`
+	FinalURL        string         `json:"final_url,omitempty"`
+	StatusCode      int            `json:"status_code,omitempty"`
+	ContentLanguage string         `json:"content_language,omitempty"`
+	Vary            string         `json:"vary,omitempty"`
+	Lang            string         `json:"lang,omitempty"`
+	Title           string         `json:"title,omitempty"`
+	Description     string         `json:"description,omitempty"`
+	Canonical       string         `json:"canonical,omitempty"`
+	Hreflang        []HreflangLink `json:"hreflang,omitempty"`
+	ContentHash     string         `json:"content_hash,omitempty"`
+	Error           string         `json:"error,omitempty"`
+}
+
+// ParseLocaleSignals extracts the language, title, description, canonical,
+// hreflang annotations and a hash of the main content from an HTML document
+func ParseLocaleSignals(html string) LocaleVariant {
+	var variant LocaleVariant
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return variant
+	}
+
+	variant.Lang, _ = doc.Find("html").Attr("lang")
+	variant.Title = normalizeWhitespace(doc.Find("head title").First().Text())
+	variant.Description, _ = doc.Find(`meta[name="description"]`).Attr("content")
+	variant.Description = normalizeWhitespace(variant.Description)
+	variant.Canonical, _ = doc.Find(`link[rel="canonical"]`).Attr("href")
+
+	doc.Find(`link[rel="alternate"][hreflang]`).Each(func(_ int, s *goquery.Selection) {
+		lang, _ := s.Attr("hreflang")
+		href, _ := s.Attr("href")
+		if lang != "" && href != "" {
+			variant.Hreflang = append(variant.Hreflang, HreflangLink{
+				Lang: strings.ToLower(strings.TrimSpace(lang)),
+				URL:  strings.TrimSpace(href),
+			})
+		}
+	})
+
+	sum := sha256.Sum256([]byte(ExtractMainContent(html).Text))
+	variant.ContentHash = hex.EncodeToString(sum[:8])
+
+	return variant
+}
+
+// FetchLocaleVariant fetches a page with the given Accept-Language and,
+// when proxyURL is set, through a proxy in the matching region
+func FetchLocaleVariant(ctx context.Context, targetURL, locale, proxyURL, userAgent string, timeout time.Duration) (*LocaleVariant, error) {
+	transport := &http.Transport{}
+	if proxyURL != "" {
+		proxy, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL for %s: %w", locale, err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+	defer transport.CloseIdleConnections()
+
+	resp, body, err := fetchDocument(ctx, client, targetURL, userAgent, locale)
+	if err != nil {
+		return nil, err
+	}
+
+	variant := ParseLocaleSignals(string(body))
+	variant.Locale = locale
+	variant.URL = targetURL
+	variant.FinalURL = resp.Request.URL.String()
+	variant.StatusCode = resp.StatusCode
+	variant.ContentLanguage = resp.Header.Get("Content-Language")
+	variant.Vary = resp.Header.Get("Vary")
+
+	return &variant, nil
+}
+
+// fetchDocument GETs an HTML document and reads at most maxVariantBodySize
+// bytes of it. The response body is closed.
+func fetchDocument(ctx context.Context, client *http.Client, targetURL, userAgent, acceptLanguage string) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if userAgent == "" {
+		userAgent = DesktopDevice.UserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	if acceptLanguage != "" {
+		req.Header.Set("Accept-Language", acceptLanguage)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxVariantBodySize))
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
@@ -0,0 +1,397 @@
+// Package priority ranks the issues of an analysis into a single list of
+// what to fix first. Each issue's impact (its severity, weighted by the
+// importance of its category, the search traffic of the page and the budgets
+// the analysis missed) is divided by the estimated effort of the fix. Every
+// ranking comes with the factors that produced it and a justification.
+package priority
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/analyzer"
+)
+
+// Effort levels of a fix
+const (
+	EffortLow    = "low"    // a markup, meta tag or header change
+	EffortMedium = "medium" // a template, asset or configuration change
+	EffortHigh   = "high"   // a change to the architecture, hosting or content
+)
+
+// severityPoints is the impact of an issue before weighting
+var severityPoints = map[string]float64{
+	"critical": 4,
+	"high":     3,
+	"medium":   2,
+	"low":      1,
+}
+
+// effortFactors divide the impact of an issue. They grow slower than the
+// effort itself, so severe issues stay above trivial ones.
+var effortFactors = map[string]float64{
+	EffortLow:    1,
+	EffortMedium: 1.5,
+	EffortHigh:   2.5,
+}
+
+// effortHours are rough estimates of the time a fix takes, shown to users
+var effortHours = map[string]string{
+	EffortLow:    "under an hour",
+	EffortMedium: "a few hours",
+	EffortHigh:   "a day or more",
+}
+
+// issueEfforts are the efforts of the issue types whose fix is not of
+// medium effort
+var issueEfforts = map[string]string{
+	"missing_title":                          EffortLow,
+	"title_too_long":                         EffortLow,
+	"title_too_short":                        EffortLow,
+	"no_keywords_in_title":                   EffortLow,
+	"missing_description":                    EffortLow,
+	"description_too_long":                   EffortLow,
+	"description_too_short":                  EffortLow,
+	"missing_canonical":                      EffortLow,
+	"relative_canonical":                     EffortLow,
+	"canonical_mismatch":                     EffortLow,
+	"missing_viewport":                       EffortLow,
+	"incomplete_viewport":                    EffortLow,
+	"missing_language":                       EffortLow,
+	"missing_doctype":                        EffortLow,
+	"missing_alt":                            EffortLow,
+	"missing_alt_text":                       EffortLow,
+	"too_short_alt":                          EffortLow,
+	"suspicious_alt":                         EffortLow,
+	"missing_form_labels":                    EffortLow,
+	"forms_without_labels":                   EffortLow,
+	"no_skip_links":                          EffortLow,
+	"tabindex_issue":                         EffortLow,
+	"duplicated_ids":                         EffortLow,
+	"heading_order":                          EffortLow,
+	"skipped_heading_levels":                 EffortLow,
+	"long_headings":                          EffortLow,
+	"broken_contact_links":                   EffortLow,
+	"missing_hsts":                           EffortLow,
+	"missing_x_frame_options":                EffortLow,
+	"missing_xss_protection":                 EffortLow,
+	"server_software_disclosed":              EffortLow,
+	"missing_hreflang_x_default":             EffortLow,
+	"missing_vary_accept_language":           EffortLow,
+	"tracking_params_in_links":               EffortLow,
+	"internal_tracking_params":               EffortLow,
+	"unminified_css":                         EffortLow,
+	"unminified_js":                          EffortLow,
+	"subdomain_takeover_risk":                EffortLow,
+	"lighthouse_document-title":              EffortLow,
+	"lighthouse_html-has-lang":               EffortLow,
+	"lighthouse_image-alt":                   EffortLow,
+	"lighthouse_meta-viewport":               EffortLow,
+	"no_https":                               EffortHigh,
+	"content_requires_javascript":            EffortHigh,
+	"navigation_requires_javascript":         EffortHigh,
+	"nav_links_require_javascript":           EffortHigh,
+	"headings_require_javascript":            EffortHigh,
+	"seo_elements_require_javascript":        EffortHigh,
+	"no_mobile_template":                     EffortHigh,
+	"fixed_width_content":                    EffortHigh,
+	"no_media_queries":                       EffortHigh,
+	"core_web_vital_lcp":                     EffortHigh,
+	"core_web_vital_tbt":                     EffortHigh,
+	"slow_lcp":                               EffortHigh,
+	"slow_load_time":                         EffortHigh,
+	"too_many_requests":                      EffortHigh,
+	"large_page_size":                        EffortHigh,
+	"no_cdn":                                 EffortHigh,
+	"no_cdn_global_audience":                 EffortHigh,
+	"server_far_from_audience":               EffortHigh,
+	"duplicate_content":                      EffortHigh,
+	"copied_content":                         EffortHigh,
+	"low_word_count":                         EffortHigh,
+	"no_text_content":                        EffortHigh,
+	"robots_directives_differ_by_user_agent": EffortHigh,
+}
+
+// Effort returns the estimated effort of fixing an issue type
+func Effort(issueType string) string {
+	if effort, ok := issueEfforts[issueType]; ok {
+		return effort
+	}
+	return EffortMedium
+}
+
+// searchCategories are the categories whose issues cost a page search
+// visitors, and so weigh more on pages with search traffic
+var searchCategories = map[string]bool{
+	string(analyzer.SEOType):         true,
+	string(analyzer.ContentType):     true,
+	string(analyzer.PerformanceType): true,
+	string(analyzer.MobileType):      true,
+	string(analyzer.LighthouseType):  true,
+	string(analyzer.NoScriptType):    true,
+	string(analyzer.GeoType):         true,
+}
+
+// Budget boosts: issues of an analyzer that missed its score budget weigh
+// more, and issues of every category when the overall score missed its
+// budget
+const (
+	analyzerBudgetBoost = 1.5
+	overallBudgetBoost  = 1.2
+)
+
+// loadTimeCategories are the categories boosted when the page missed its
+// load time budget
+var loadTimeCategories = []string{string(analyzer.PerformanceType), string(analyzer.LighthouseType)}
+
+// Issue is an issue found by the analysis
+type Issue struct {
+	ID       string `json:"id"`
+	Category string `json:"category"`
+	Severity string `json:"severity"`
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title"`
+	Location string `json:"location,omitempty"`
+}
+
+// Traffic is the search traffic of the analyzed page, estimated from the
+// positions of the tracked keywords it ranks for
+type Traffic struct {
+	Keywords     int `json:"keywords"`
+	BestPosition int `json:"best_position,omitempty"`
+	// ExpectedCTR is the sum of the expected click-through rates of the
+	// positions, capped at 1
+	ExpectedCTR float64 `json:"expected_ctr"`
+}
+
+// expectedCTR is the share of searchers clicking a result at a position
+func expectedCTR(position int) float64 {
+	switch {
+	case position <= 0:
+		return 0
+	case position == 1:
+		return 0.28
+	case position == 2:
+		return 0.15
+	case position == 3:
+		return 0.11
+	case position == 4:
+		return 0.08
+	case position == 5:
+		return 0.07
+	case position <= 10:
+		return 0.04
+	case position <= 20:
+		return 0.01
+	default:
+		return 0
+	}
+}
+
+// TrafficFromPositions estimates the search traffic of a page from the
+// positions of its tracked keywords
+func TrafficFromPositions(positions []int) Traffic {
+	var traffic Traffic
+	for _, position := range positions {
+		if position <= 0 {
+			continue
+		}
+		traffic.Keywords++
+		if traffic.BestPosition == 0 || position < traffic.BestPosition {
+			traffic.BestPosition = position
+		}
+		traffic.ExpectedCTR += expectedCTR(position)
+	}
+	traffic.ExpectedCTR = math.Min(round(traffic.ExpectedCTR), 1)
+	return traffic
+}
+
+// Weight returns the weight of search-facing issues on the page: 1 without
+// search traffic, up to 2 for a page that takes most clicks of its keywords
+func (t Traffic) Weight() float64 {
+	return round(1 + t.ExpectedCTR)
+}
+
+// Input is what issues are ranked from
+type Input struct {
+	Issues []Issue
+	// CategoryWeights scale the issues of a category; categories not
+	// listed weigh 1 and a weight of 0 leaves them out
+	CategoryWeights map[string]float64
+	Traffic         Traffic
+	// Violations are the budgets the analysis did not meet
+	Violations []analyzer.BudgetViolation
+}
+
+// Factor is one signal in the ranking of an issue
+type Factor struct {
+	Name   string  `json:"name"`
+	Value  float64 `json:"value"`
+	Reason string  `json:"reason"`
+}
+
+// Item is a ranked issue
+type Item struct {
+	Rank  int   `json:"rank"`
+	Issue Issue `json:"issue"`
+	// Score is the weighted impact divided by the effort factor
+	Score   float64  `json:"score"`
+	Effort  string   `json:"effort"`
+	Factors []Factor `json:"factors"`
+	// Justification explains the rank in one sentence
+	Justification string `json:"justification"`
+}
+
+// Rank orders issues by priority, highest first. Ties keep the more severe
+// issue first, then the order of the input.
+func Rank(in Input) []Item {
+	boosts := budgetBoosts(in.Violations)
+	trafficWeight := in.Traffic.Weight()
+
+	items := make([]Item, 0, len(in.Issues))
+	for _, issue := range in.Issues {
+		categoryWeight := 1.0
+		if w, ok := in.CategoryWeights[issue.Category]; ok {
+			categoryWeight = w
+		}
+		if categoryWeight <= 0 {
+			continue
+		}
+
+		points, ok := severityPoints[issue.Severity]
+		if !ok {
+			points = severityPoints["low"]
+		}
+		effort := Effort(issue.Type)
+
+		factors := []Factor{{
+			Name:   "severity",
+			Value:  points,
+			Reason: fmt.Sprintf("%s severity", capitalize(issue.Severity)),
+		}}
+		score := points
+
+		if categoryWeight != 1 {
+			factors = append(factors, Factor{
+				Name:   "category_weight",
+				Value:  categoryWeight,
+				Reason: fmt.Sprintf("%s issues are weighted ×%s", issue.Category, formatFactor(categoryWeight)),
+			})
+			score *= categoryWeight
+		}
+
+		if searchCategories[issue.Category] && trafficWeight > 1 {
+			factors = append(factors, Factor{
+				Name:  "traffic",
+				Value: trafficWeight,
+				Reason: fmt.Sprintf("the page ranks for %d tracked %s (best position %d), so %s issues cost search visitors (×%s)",
+					in.Traffic.Keywords, plural(in.Traffic.Keywords, "keyword", "keywords"), in.Traffic.BestPosition, issue.Category, formatFactor(trafficWeight)),
+			})
+			score *= trafficWeight
+		}
+
+		for _, boost := range boosts[issue.Category] {
+			factors = append(factors, boost)
+			score *= boost.Value
+		}
+		for _, boost := range boosts[""] {
+			factors = append(factors, boost)
+			score *= boost.Value
+		}
+
+		factors = append(factors, Factor{
+			Name:   "effort",
+			Value:  effortFactors[effort],
+			Reason: fmt.Sprintf("%s effort, %s (÷%s)", effort, effortHours[effort], formatFactor(effortFactors[effort])),
+		})
+		score /= effortFactors[effort]
+
+		items = append(items, Item{
+			Issue:   issue,
+			Score:   round(score),
+			Effort:  effort,
+			Factors: factors,
+		})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Score != items[j].Score {
+			return items[i].Score > items[j].Score
+		}
+		return severityPoints[items[i].Issue.Severity] > severityPoints[items[j].Issue.Severity]
+	})
+	for i := range items {
+		items[i].Rank = i + 1
+		items[i].Justification = justify(items[i])
+	}
+	return items
+}
+
+// budgetBoosts returns the budget factors by category; the factors of the
+// empty category apply to all issues
+func budgetBoosts(violations []analyzer.BudgetViolation) map[string][]Factor {
+	boosts := make(map[string][]Factor)
+	for _, violation := range violations {
+		switch violation.Budget {
+		case "min_score":
+			boosts[violation.Analyzer] = append(boosts[violation.Analyzer], Factor{
+				Name:  "budget",
+				Value: analyzerBudgetBoost,
+				Reason: fmt.Sprintf("the %s score %.0f is below its budget of %.0f (×%s)",
+					violation.Analyzer, violation.Actual, violation.Limit, formatFactor(analyzerBudgetBoost)),
+			})
+		case "max_load_time_ms":
+			for _, category := range loadTimeCategories {
+				boosts[category] = append(boosts[category], Factor{
+					Name:  "budget",
+					Value: analyzerBudgetBoost,
+					Reason: fmt.Sprintf("the load time of %.0f ms exceeds its budget of %.0f ms (×%s)",
+						violation.Actual, violation.Limit, formatFactor(analyzerBudgetBoost)),
+				})
+			}
+		case "min_overall_score":
+			boosts[""] = append(boosts[""], Factor{
+				Name:  "budget",
+				Value: overallBudgetBoost,
+				Reason: fmt.Sprintf("the overall score %.0f is below its budget of %.0f (×%s)",
+					violation.Actual, violation.Limit, formatFactor(overallBudgetBoost)),
+			})
+		}
+	}
+	return boosts
+}
+
+// justify explains the rank of an item from its factors
+func justify(item Item) string {
+	reasons := make([]string, 0, len(item.Factors))
+	for _, factor := range item.Factors {
+		reasons = append(reasons, factor.Reason)
+	}
+	return fmt.Sprintf("Ranked #%d with a score of %s: %s.", item.Rank, formatFactor(item.Score), strings.Join(reasons, "; "))
+}
+
+// round rounds to two decimals
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// formatFactor formats a factor without trailing zeros
+func formatFactor(value float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", value), "0"), ".")
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,388 @@
+package queue
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Priority determines the order in which queued jobs are started
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+)
+
+// String returns the name of the priority
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// ParsePriority converts a priority name to a Priority. An empty name is normal.
+func ParsePriority(name string) (Priority, error) {
+	switch name {
+	case "low":
+		return PriorityLow, nil
+	case "", "normal":
+		return PriorityNormal, nil
+	case "high":
+		return PriorityHigh, nil
+	default:
+		return PriorityNormal, fmt.Errorf("invalid priority: %s", name)
+	}
+}
+
+// Reasons a job is still waiting, reported by WaitReason
+const (
+	WaitReasonWorkerLimit = "worker_limit"
+	WaitReasonKeyLimit    = "key_limit"
+)
+
+// Stats describes the current state of the scheduler
+type Stats struct {
+	MaxWorkers int            `json:"max_workers"`
+	MaxPerKey  int            `json:"max_per_key"`
+	Running    int            `json:"running"`
+	Queued     map[string]int `json:"queued"`
+	Paused     int            `json:"paused"`
+	Preemption bool           `json:"preemption"`
+	// Throttled is the number of waiting jobs held back by the per-key limit
+	Throttled int `json:"throttled"`
+	// RunningByKey counts running jobs per key, only keys with running jobs
+	RunningByKey map[string]int `json:"running_by_key"`
+}
+
+// Scheduler runs jobs with a bounded number of workers, starting higher
+// priority jobs first. With preemption enabled, running low-priority jobs give
+// up their slot at checkpoints while higher-priority jobs are waiting. Jobs
+// sharing a key, such as the target domain, can additionally be limited to a
+// number of concurrent runs; jobs over that limit wait without blocking jobs
+// for other keys.
+type Scheduler struct {
+	maxWorkers   int
+	maxPerKey    int
+	preemption   bool
+	running      int
+	runningByKey map[string]int
+	paused       int
+	seq          uint64
+	waiting      jobHeap
+	mu           sync.Mutex
+}
+
+// Ticket is handed to a running job and used to cooperate with the scheduler
+type Ticket struct {
+	ID       string
+	Key      string
+	Priority Priority
+
+	scheduler *Scheduler
+	holding   bool
+	item      *queuedItem
+}
+
+// queuedItem is a ticket waiting for a worker slot
+type queuedItem struct {
+	ticket     *Ticket
+	seq        uint64
+	enqueuedAt time.Time
+	ready      chan struct{}
+	index      int
+}
+
+// NewScheduler creates a scheduler with the given number of worker slots
+func NewScheduler(maxWorkers int, preemption bool) *Scheduler {
+	if maxWorkers <= 0 {
+		maxWorkers = 1
+	}
+	return &Scheduler{
+		maxWorkers:   maxWorkers,
+		preemption:   preemption,
+		runningByKey: make(map[string]int),
+	}
+}
+
+// SetMaxPerKey limits the number of jobs with the same key running at once.
+// Zero or less disables the limit.
+func (s *Scheduler) SetMaxPerKey(max int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if max < 0 {
+		max = 0
+	}
+	s.maxPerKey = max
+	s.dispatchLocked()
+}
+
+// Submit queues a job. It runs in its own goroutine once a worker slot is free
+// and fewer than the per-key limit of jobs with the same key are running. An
+// empty key is never limited.
+func (s *Scheduler) Submit(id, key string, priority Priority, run func(ticket *Ticket)) {
+	ticket := &Ticket{ID: id, Key: key, Priority: priority, scheduler: s}
+
+	s.mu.Lock()
+	item := s.enqueueLocked(ticket)
+	s.dispatchLocked()
+	s.mu.Unlock()
+
+	go func() {
+		<-item.ready
+		defer s.release(ticket)
+		run(ticket)
+	}()
+}
+
+// Yield gives up the ticket's worker slot if it is a low-priority job and
+// higher-priority jobs are waiting, and blocks until it is scheduled again.
+// onPause, if set, is called after the slot was released. It returns true if
+// the job was paused.
+func (t *Ticket) Yield(ctx context.Context, onPause func()) (bool, error) {
+	s := t.scheduler
+
+	s.mu.Lock()
+	if !s.preemption || t.Priority != PriorityLow || !t.holding || !s.higherWaitingLocked(t) {
+		s.mu.Unlock()
+		return false, nil
+	}
+
+	s.releaseSlotLocked(t)
+	s.paused++
+	item := s.enqueueLocked(t)
+	s.dispatchLocked()
+	s.mu.Unlock()
+
+	if onPause != nil {
+		onPause()
+	}
+
+	select {
+	case <-item.ready:
+		s.mu.Lock()
+		s.paused--
+		s.mu.Unlock()
+		return true, nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		s.paused--
+		if item.index >= 0 {
+			heap.Remove(&s.waiting, item.index)
+			t.item = nil
+		}
+		s.mu.Unlock()
+		return true, ctx.Err()
+	}
+}
+
+// Position returns the 1-based queue position of a waiting job, or 0 if it is
+// not waiting
+func (s *Scheduler) Position(id string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var target *queuedItem
+	for _, item := range s.waiting {
+		if item.ticket.ID == id {
+			target = item
+			break
+		}
+	}
+	if target == nil {
+		return 0
+	}
+
+	position := 1
+	for _, item := range s.waiting {
+		if item != target && s.waiting.before(item, target) {
+			position++
+		}
+	}
+	return position
+}
+
+// WaitReason returns why a waiting job has not started: WaitReasonKeyLimit if
+// jobs with its key already use the per-key limit, WaitReasonWorkerLimit if
+// all workers are busy. It is empty if the job is not waiting.
+func (s *Scheduler) WaitReason(id string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range s.waiting {
+		if item.ticket.ID != id {
+			continue
+		}
+		if s.keyFullLocked(item.ticket.Key) {
+			return WaitReasonKeyLimit
+		}
+		return WaitReasonWorkerLimit
+	}
+	return ""
+}
+
+// Stats returns a snapshot of the scheduler state
+func (s *Scheduler) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queued := map[string]int{
+		PriorityHigh.String():   0,
+		PriorityNormal.String(): 0,
+		PriorityLow.String():    0,
+	}
+	throttled := 0
+	for _, item := range s.waiting {
+		queued[item.ticket.Priority.String()]++
+		if s.keyFullLocked(item.ticket.Key) {
+			throttled++
+		}
+	}
+	runningByKey := make(map[string]int, len(s.runningByKey))
+	for key, count := range s.runningByKey {
+		runningByKey[key] = count
+	}
+
+	return Stats{
+		MaxWorkers:   s.maxWorkers,
+		MaxPerKey:    s.maxPerKey,
+		Running:      s.running,
+		Queued:       queued,
+		Paused:       s.paused,
+		Preemption:   s.preemption,
+		Throttled:    throttled,
+		RunningByKey: runningByKey,
+	}
+}
+
+// release frees the ticket's worker slot and starts the next job
+func (s *Scheduler) release(ticket *Ticket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ticket.holding {
+		s.releaseSlotLocked(ticket)
+	} else if ticket.item != nil && ticket.item.index >= 0 {
+		// The job finished while waiting to resume
+		heap.Remove(&s.waiting, ticket.item.index)
+	}
+	ticket.item = nil
+	s.dispatchLocked()
+}
+
+// enqueueLocked adds a ticket to the waiting heap. The caller must hold s.mu.
+func (s *Scheduler) enqueueLocked(ticket *Ticket) *queuedItem {
+	s.seq++
+	item := &queuedItem{
+		ticket:     ticket,
+		seq:        s.seq,
+		enqueuedAt: time.Now(),
+		ready:      make(chan struct{}),
+	}
+	ticket.item = item
+	heap.Push(&s.waiting, item)
+	return item
+}
+
+// dispatchLocked starts waiting jobs while worker slots are free. Jobs whose
+// key is at its limit are skipped and keep their place. The caller must hold s.mu.
+func (s *Scheduler) dispatchLocked() {
+	var held []*queuedItem
+	for s.running < s.maxWorkers && s.waiting.Len() > 0 {
+		item := heap.Pop(&s.waiting).(*queuedItem)
+		if s.keyFullLocked(item.ticket.Key) {
+			held = append(held, item)
+			continue
+		}
+		item.ticket.holding = true
+		item.ticket.item = nil
+		s.running++
+		if item.ticket.Key != "" {
+			s.runningByKey[item.ticket.Key]++
+		}
+		close(item.ready)
+	}
+	for _, item := range held {
+		heap.Push(&s.waiting, item)
+	}
+}
+
+// releaseSlotLocked frees the worker slot held by a ticket. The caller must hold s.mu.
+func (s *Scheduler) releaseSlotLocked(ticket *Ticket) {
+	ticket.holding = false
+	s.running--
+	if ticket.Key == "" {
+		return
+	}
+	if s.runningByKey[ticket.Key] <= 1 {
+		delete(s.runningByKey, ticket.Key)
+	} else {
+		s.runningByKey[ticket.Key]--
+	}
+}
+
+// keyFullLocked reports whether jobs with the key use the per-key limit.
+// The caller must hold s.mu.
+func (s *Scheduler) keyFullLocked(key string) bool {
+	return key != "" && s.maxPerKey > 0 && s.runningByKey[key] >= s.maxPerKey
+}
+
+// higherWaitingLocked reports whether a job with a higher priority than the
+// ticket is waiting and could take over its slot. Jobs held back by the
+// per-key limit only count if they share the ticket's key. The caller must
+// hold s.mu.
+func (s *Scheduler) higherWaitingLocked(ticket *Ticket) bool {
+	for _, item := range s.waiting {
+		if item.ticket.Priority <= ticket.Priority {
+			continue
+		}
+		if item.ticket.Key == ticket.Key || !s.keyFullLocked(item.ticket.Key) {
+			return true
+		}
+	}
+	return false
+}
+
+// jobHeap orders waiting jobs by priority, then by arrival
+type jobHeap []*queuedItem
+
+func (h jobHeap) before(a, b *queuedItem) bool {
+	if a.ticket.Priority != b.ticket.Priority {
+		return a.ticket.Priority > b.ticket.Priority
+	}
+	return a.seq < b.seq
+}
+
+func (h jobHeap) Len() int           { return len(h) }
+func (h jobHeap) Less(i, j int) bool { return h.before(h[i], h[j]) }
+
+func (h jobHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *jobHeap) Push(x interface{}) {
+	item := x.(*queuedItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*h = old[:n-1]
+	return item
+}
@@ -0,0 +1,184 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// A4 page layout of PDF reports in points
+const (
+	pdfPageWidth   = 595
+	pdfPageHeight  = 842
+	pdfMargin      = 50
+	pdfBodySize    = 10
+	pdfLineSpacing = 1.4
+	// pdfWrapColumn is the number of characters per body line; Helvetica
+	// averages about half the font size per character
+	pdfWrapColumn = 95
+)
+
+// pdfLine is a line of text with its font size
+type pdfLine struct {
+	text string
+	size float64
+	bold bool
+}
+
+// RenderPDF renders the sections of a report chosen by a recipient as a PDF
+// document. The built-in Helvetica font covers Latin-1; other characters are
+// replaced by question marks.
+func RenderPDF(r Report, recipient Recipient) ([]byte, error) {
+	return TextPDF(textLines(r, sectionsOf(recipient))), nil
+}
+
+// TextPDF lays out lines of text as an A4 PDF document. Lines starting with
+// "# " and "## " are headings; longer lines are wrapped.
+func TextPDF(text []string) []byte {
+	var lines []pdfLine
+	for _, line := range text {
+		switch {
+		case strings.HasPrefix(line, "# "):
+			lines = append(lines, pdfLine{text: line[2:], size: 18, bold: true})
+		case strings.HasPrefix(line, "## "):
+			lines = append(lines, pdfLine{text: "", size: pdfBodySize}, pdfLine{text: line[3:], size: 13, bold: true})
+		default:
+			for _, wrapped := range wrap(line, pdfWrapColumn) {
+				lines = append(lines, pdfLine{text: wrapped, size: pdfBodySize})
+			}
+		}
+	}
+	return writePDF(paginate(lines))
+}
+
+// paginate splits lines into pages
+func paginate(lines []pdfLine) [][]pdfLine {
+	var pages [][]pdfLine
+	var page []pdfLine
+	used := 0.0
+	for _, line := range lines {
+		height := line.size * pdfLineSpacing
+		if used+height > pdfPageHeight-2*pdfMargin && len(page) > 0 {
+			pages = append(pages, page)
+			page, used = nil, 0
+		}
+		page = append(page, line)
+		used += height
+	}
+	return append(pages, page)
+}
+
+// writePDF writes a PDF document with one content stream per page
+func writePDF(pages [][]pdfLine) []byte {
+	// Objects: 1 catalog, 2 page tree, 3 regular font, 4 bold font, then a
+	// page and a content stream per page
+	var objects []string
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	objects = append(objects,
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	)
+
+	for i, page := range pages {
+		var content bytes.Buffer
+		content.WriteString("BT\n")
+		y := float64(pdfPageHeight - pdfMargin)
+		for _, line := range page {
+			y -= line.size * pdfLineSpacing
+			font := "F1"
+			if line.bold {
+				font = "F2"
+			}
+			fmt.Fprintf(&content, "/%s %.0f Tf 1 0 0 1 %d %.1f Tm (%s) Tj\n", font, line.size, pdfMargin, y, pdfString(line.text))
+		}
+		content.WriteString("ET\n")
+
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, 6+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// pdfString escapes text for a PDF string literal in WinAnsi encoding
+func pdfString(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '–':
+			// The en dash is at 0x96 in WinAnsi
+			b.WriteString("\\226")
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 128:
+			b.WriteRune(r)
+		case r < 256:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// wrap splits a line at spaces so that no part exceeds width characters,
+// keeping the indentation of the line
+func wrap(line string, width int) []string {
+	if utf8.RuneCountInString(line) <= width {
+		return []string{line}
+	}
+	indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(line) {
+		for utf8.RuneCountInString(word) > width-len(indent) {
+			// Words longer than a line, such as URLs, are split
+			runes := []rune(word)
+			if current != "" {
+				lines = append(lines, current)
+				current = ""
+			}
+			lines = append(lines, indent+string(runes[:width-len(indent)]))
+			word = string(runes[width-len(indent):])
+		}
+		switch {
+		case current == "":
+			current = indent + word
+		case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) > width:
+			lines = append(lines, current)
+			current = indent + "  " + word
+		default:
+			current += " " + word
+		}
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	return lines
+}
@@ -0,0 +1,157 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// dateLayout formats the dates of a report period
+const dateLayout = "2 Jan 2006"
+
+// view is the data passed to the templates
+type view struct {
+	Report
+	Sections map[string]bool
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"date":   func(t time.Time) string { return t.Format(dateLayout) },
+	"score":  formatScore,
+	"change": formatChange,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body style="font-family: Arial, Helvetica, sans-serif; color: #222; max-width: 720px; margin: 0 auto;">
+<h1 style="font-size: 22px;">{{.Title}}</h1>
+<p style="color: #666;">{{date .From}} – {{date .To}}</p>
+{{if not .Sites}}<p>No monitored sites are included in this report.</p>{{end}}
+{{range .Sites}}
+<h2 style="font-size: 18px; border-bottom: 1px solid #ddd; padding-bottom: 4px;">{{.URL}}</h2>
+{{if $.Sections.trend}}
+{{if .LastScore}}
+<p>Overall score <strong>{{score .LastScore}}</strong> ({{change .Change}} since {{score .FirstScore}}), {{len .Scores}} analyses, {{.OpenIssues}} open issue types.</p>
+<table style="border-collapse: collapse; font-size: 13px;">
+<tr><th style="text-align: left; padding: 2px 12px 2px 0;">Date</th><th style="text-align: right;">Score</th></tr>
+{{range .Scores}}<tr><td style="padding: 2px 12px 2px 0;">{{date .At}}</td><td style="text-align: right;">{{printf "%.0f" .Score}}</td></tr>
+{{end}}</table>
+{{else}}
+<p>The site was not analyzed in this period.</p>
+{{end}}
+{{end}}
+{{if $.Sections.new_issues}}
+<h3 style="font-size: 15px;">New issues</h3>
+{{if .NewIssues}}<ul>
+{{range .NewIssues}}<li><strong>{{.Severity}}</strong> · {{.Category}}: {{.Title}}{{with .Docs}} · <a href="{{.URL}}">{{.Title}}</a>{{end}}</li>
+{{end}}</ul>{{if .MoreNewIssues}}<p>and {{.MoreNewIssues}} more</p>{{end}}
+{{else}}<p>No new issues.</p>{{end}}
+{{end}}
+{{if $.Sections.resolved_issues}}
+<h3 style="font-size: 15px;">Resolved issues</h3>
+{{if .ResolvedIssues}}<ul>
+{{range .ResolvedIssues}}<li>{{.Category}}: {{.Title}}</li>
+{{end}}</ul>{{if .MoreResolvedIssues}}<p>and {{.MoreResolvedIssues}} more</p>{{end}}
+{{else}}<p>No issues were resolved.</p>{{end}}
+{{end}}
+{{end}}
+<p style="color: #999; font-size: 12px;">Generated {{date .GeneratedAt}}. You receive this report because your address is a recipient of a scheduled report.</p>
+</body>
+</html>
+`))
+
+// Subject returns the email subject of a report
+func Subject(r Report) string {
+	return fmt.Sprintf("%s: %s – %s", r.Title, r.From.Format(dateLayout), r.To.Format(dateLayout))
+}
+
+// RenderHTML renders the sections of a report chosen by a recipient as HTML
+func RenderHTML(r Report, recipient Recipient) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, view{Report: r, Sections: sectionsOf(recipient)}); err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderText renders the sections of a report chosen by a recipient as plain
+// text. It is also the content of the PDF document.
+func RenderText(r Report, recipient Recipient) string {
+	return strings.Join(textLines(r, sectionsOf(recipient)), "\n")
+}
+
+// textLines lays out a report as lines of text. Lines starting with "# " and
+// "## " are headings.
+func textLines(r Report, sections map[string]bool) []string {
+	lines := []string{
+		"# " + r.Title,
+		r.From.Format(dateLayout) + " – " + r.To.Format(dateLayout),
+		"",
+	}
+	if len(r.Sites) == 0 {
+		lines = append(lines, "No monitored sites are included in this report.")
+	}
+
+	for _, site := range r.Sites {
+		lines = append(lines, "## "+site.URL)
+		if sections[SectionTrend] {
+			if site.LastScore != nil {
+				lines = append(lines, fmt.Sprintf("Overall score %s (%s since %s), %d analyses, %d open issue types",
+					formatScore(site.LastScore), formatChange(site.Change), formatScore(site.FirstScore), len(site.Scores), site.OpenIssues))
+				for _, point := range site.Scores {
+					lines = append(lines, fmt.Sprintf("  %s  %.0f", point.At.Format(dateLayout), point.Score))
+				}
+			} else {
+				lines = append(lines, "The site was not analyzed in this period.")
+			}
+		}
+		if sections[SectionNewIssues] {
+			lines = append(lines, "", "New issues:")
+			if len(site.NewIssues) == 0 {
+				lines = append(lines, "  none")
+			}
+			for _, issue := range site.NewIssues {
+				lines = append(lines, fmt.Sprintf("  - [%s] %s: %s", issue.Severity, issue.Category, issue.Title))
+				if issue.Docs != nil {
+					lines = append(lines, fmt.Sprintf("    How to fix: %s (%s)", issue.Docs.Title, issue.Docs.URL))
+				}
+			}
+			if site.MoreNewIssues > 0 {
+				lines = append(lines, fmt.Sprintf("  and %d more", site.MoreNewIssues))
+			}
+		}
+		if sections[SectionResolvedIssues] {
+			lines = append(lines, "", "Resolved issues:")
+			if len(site.ResolvedIssues) == 0 {
+				lines = append(lines, "  none")
+			}
+			for _, issue := range site.ResolvedIssues {
+				lines = append(lines, fmt.Sprintf("  - %s: %s", issue.Category, issue.Title))
+			}
+			if site.MoreResolvedIssues > 0 {
+				lines = append(lines, fmt.Sprintf("  and %d more", site.MoreResolvedIssues))
+			}
+		}
+		lines = append(lines, "")
+	}
+
+	lines = append(lines, "Generated "+r.GeneratedAt.Format(dateLayout))
+	return lines
+}
+
+// formatScore formats an optional score
+func formatScore(score *float64) string {
+	if score == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.0f", *score)
+}
+
+// formatChange formats a score change with its sign
+func formatChange(change float64) string {
+	return fmt.Sprintf("%+.0f", change)
+}
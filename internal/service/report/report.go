@@ -0,0 +1,226 @@
+// Package report builds periodic summaries of monitored sites and renders
+// them as HTML, plain text or PDF documents.
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/issuedocs"
+)
+
+// Report frequencies
+const (
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+)
+
+// Document formats
+const (
+	FormatHTML = "html"
+	FormatPDF  = "pdf"
+)
+
+// Report sections a recipient can choose
+const (
+	SectionTrend          = "trend"
+	SectionNewIssues      = "new_issues"
+	SectionResolvedIssues = "resolved_issues"
+)
+
+// AllSections are the sections included when a recipient chooses none
+var AllSections = []string{SectionTrend, SectionNewIssues, SectionResolvedIssues}
+
+// maxListedIssues limits the issues listed per site and section
+const maxListedIssues = 25
+
+// Recipient is an address a report is delivered to with its preferences
+type Recipient struct {
+	Email    string   `json:"email"`
+	Format   string   `json:"format"`             // html (default) or pdf
+	Sections []string `json:"sections,omitempty"` // all sections when empty
+}
+
+// Validate checks the format and sections of a recipient
+func (r Recipient) Validate() error {
+	if r.Format != "" && r.Format != FormatHTML && r.Format != FormatPDF {
+		return fmt.Errorf("format of %s must be html or pdf", r.Email)
+	}
+	for _, section := range r.Sections {
+		if !contains(AllSections, section) {
+			return fmt.Errorf("unknown section %q for %s, expected one of %s", section, r.Email, strings.Join(AllSections, ", "))
+		}
+	}
+	return nil
+}
+
+// Period returns the time range a report of the given frequency covers when
+// it is sent at the given time
+func Period(frequency string, at time.Time) (time.Time, time.Time) {
+	if frequency == FrequencyMonthly {
+		return at.AddDate(0, -1, 0), at
+	}
+	return at.AddDate(0, 0, -7), at
+}
+
+// NextRun returns when a report of the given frequency is due after a run
+func NextRun(frequency string, after time.Time) time.Time {
+	if frequency == FrequencyMonthly {
+		return after.AddDate(0, 1, 0)
+	}
+	return after.AddDate(0, 0, 7)
+}
+
+// ValidFrequency reports whether a frequency is supported
+func ValidFrequency(frequency string) bool {
+	return frequency == FrequencyWeekly || frequency == FrequencyMonthly
+}
+
+// ScorePoint is the overall score of one analysis
+type ScorePoint struct {
+	At    time.Time `json:"at"`
+	Score float64   `json:"score"`
+}
+
+// Issue is an issue type found on a site
+type Issue struct {
+	Category string `json:"category"`
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Severity string `json:"severity"`
+	// Docs explains the fix on the site's platform, nil without documentation
+	Docs *issuedocs.Link `json:"docs,omitempty"`
+}
+
+// Snapshot is the issues found by one analysis of a site
+type Snapshot struct {
+	At     time.Time
+	Issues []Issue
+}
+
+// SiteReport summarizes one monitored site over the report period
+type SiteReport struct {
+	URL    string       `json:"url"`
+	Scores []ScorePoint `json:"scores"`
+	// FirstScore and LastScore are nil when the site was not analyzed
+	FirstScore     *float64 `json:"first_score"`
+	LastScore      *float64 `json:"last_score"`
+	Change         float64  `json:"change"`
+	OpenIssues     int      `json:"open_issues"`
+	NewIssues      []Issue  `json:"new_issues"`
+	ResolvedIssues []Issue  `json:"resolved_issues"`
+	// MoreNewIssues and MoreResolvedIssues count issues left out of the lists
+	MoreNewIssues      int `json:"more_new_issues,omitempty"`
+	MoreResolvedIssues int `json:"more_resolved_issues,omitempty"`
+}
+
+// Report is the summary of all sites of a schedule over one period
+type Report struct {
+	Title       string       `json:"title"`
+	Frequency   string       `json:"frequency"`
+	From        time.Time    `json:"from"`
+	To          time.Time    `json:"to"`
+	GeneratedAt time.Time    `json:"generated_at"`
+	Sites       []SiteReport `json:"sites"`
+}
+
+// BuildSiteReport summarizes a site from the scores of its analyses in the
+// period and two snapshots of its issues: the baseline is the analysis before
+// or at the start of the period, current is the latest one. Either snapshot
+// may be nil when the site was not analyzed.
+func BuildSiteReport(url string, scores []ScorePoint, baseline, current *Snapshot) SiteReport {
+	site := SiteReport{URL: url, Scores: scores, NewIssues: []Issue{}, ResolvedIssues: []Issue{}}
+	if site.Scores == nil {
+		site.Scores = []ScorePoint{}
+	}
+	if len(scores) > 0 {
+		first, last := scores[0].Score, scores[len(scores)-1].Score
+		site.FirstScore, site.LastScore = &first, &last
+		site.Change = last - first
+	}
+	if current == nil {
+		return site
+	}
+	site.OpenIssues = len(current.Issues)
+	if baseline == nil {
+		return site
+	}
+
+	site.NewIssues = difference(current.Issues, baseline.Issues)
+	site.ResolvedIssues = difference(baseline.Issues, current.Issues)
+	if len(site.NewIssues) > maxListedIssues {
+		site.MoreNewIssues = len(site.NewIssues) - maxListedIssues
+		site.NewIssues = site.NewIssues[:maxListedIssues]
+	}
+	if len(site.ResolvedIssues) > maxListedIssues {
+		site.MoreResolvedIssues = len(site.ResolvedIssues) - maxListedIssues
+		site.ResolvedIssues = site.ResolvedIssues[:maxListedIssues]
+	}
+	return site
+}
+
+// difference returns the issue types of a that are not in b, most severe first
+func difference(a, b []Issue) []Issue {
+	known := make(map[string]bool, len(b))
+	for _, issue := range b {
+		known[issue.Category+"/"+issue.Type] = true
+	}
+	result := []Issue{}
+	seen := make(map[string]bool)
+	for _, issue := range a {
+		key := issue.Category + "/" + issue.Type
+		if known[key] || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, issue)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if severityRank(result[i].Severity) != severityRank(result[j].Severity) {
+			return severityRank(result[i].Severity) < severityRank(result[j].Severity)
+		}
+		return result[i].Category+result[i].Type < result[j].Category+result[j].Type
+	})
+	return result
+}
+
+// severityRank orders severities from high to low
+func severityRank(severity string) int {
+	switch severity {
+	case "critical":
+		return 0
+	case "high":
+		return 1
+	case "medium":
+		return 2
+	case "low":
+		return 3
+	default:
+		return 4
+	}
+}
+
+// sectionsOf returns the sections chosen by a recipient
+func sectionsOf(recipient Recipient) map[string]bool {
+	sections := recipient.Sections
+	if len(sections) == 0 {
+		sections = AllSections
+	}
+	chosen := make(map[string]bool, len(sections))
+	for _, section := range sections {
+		chosen[section] = true
+	}
+	return chosen
+}
+
+// contains reports whether values holds value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
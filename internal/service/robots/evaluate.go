@@ -0,0 +1,115 @@
+package robots
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Decision is whether a user agent may crawl a URL and why
+type Decision struct {
+	UserAgent string `json:"user_agent"`
+	URL       string `json:"url"`
+	Allowed   bool   `json:"allowed"`
+	// Group lists the user agents of the groups whose rules applied; empty
+	// when no group matched the agent
+	Group []string `json:"group"`
+	// Rule is the rule that decided; nil when no rule matched
+	Rule *Rule `json:"rule,omitempty"`
+}
+
+// Evaluate decides whether a user agent may crawl a URL. The group with the
+// most specific matching user agent applies, falling back to "*"; within it
+// the longest matching rule wins and Allow wins ties.
+func (f *File) Evaluate(userAgent, rawURL string) Decision {
+	decision := Decision{UserAgent: userAgent, URL: rawURL, Allowed: true, Group: []string{}}
+
+	target := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		target = u.EscapedPath()
+		if target == "" {
+			target = "/"
+		}
+		if u.RawQuery != "" {
+			target += "?" + u.RawQuery
+		}
+	}
+	if target == "/robots.txt" {
+		return decision
+	}
+
+	var rules []Rule
+	for _, group := range f.groupsFor(userAgent) {
+		decision.Group = append(decision.Group, group.UserAgents...)
+		rules = append(rules, group.Rules...)
+	}
+
+	var best *Rule
+	for i := range rules {
+		rule := rules[i]
+		if !pathMatches(rule.Path, target) {
+			continue
+		}
+		if best == nil || len(rule.Path) > len(best.Path) || (len(rule.Path) == len(best.Path) && rule.Allow && !best.Allow) {
+			best = &rule
+		}
+	}
+	if best != nil {
+		decision.Allowed = best.Allow
+		decision.Rule = best
+	}
+	return decision
+}
+
+// groupsFor returns the groups that apply to a user agent: those naming the
+// longest product token the agent starts with, or the "*" groups. Groups
+// naming the same agent are merged, as crawlers do.
+func (f *File) groupsFor(userAgent string) []Group {
+	agent := strings.ToLower(userAgent)
+	if i := strings.IndexAny(agent, "/ "); i >= 0 {
+		agent = agent[:i]
+	}
+
+	bestLen := -1
+	var matched []Group
+	for _, group := range f.Groups {
+		groupLen := -1
+		for _, token := range group.UserAgents {
+			switch {
+			case token == "*":
+				if groupLen < 0 {
+					groupLen = 0
+				}
+			case agent == token || strings.HasPrefix(agent, token+"-"):
+				if len(token) > groupLen {
+					groupLen = len(token)
+				}
+			}
+		}
+		switch {
+		case groupLen < 0 || groupLen < bestLen:
+		case groupLen > bestLen:
+			bestLen = groupLen
+			matched = []Group{group}
+		default:
+			matched = append(matched, group)
+		}
+	}
+	return matched
+}
+
+// pathMatches matches a robots.txt path pattern, where * matches any
+// characters and a trailing $ anchors the end of the URL
+func pathMatches(pattern, target string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	parts := strings.Split(strings.TrimSuffix(pattern, "$"), "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	re, err := regexp.Compile(expr)
+	return err == nil && re.MatchString(target)
+}
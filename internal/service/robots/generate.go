@@ -0,0 +1,206 @@
+package robots
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// maxExamples limits the example URLs listed per proposed directive
+const maxExamples = 3
+
+// privateSections are first path segments of back-office, account and
+// transactional pages that search engines should not crawl
+var privateSections = map[string]string{
+	"wp-admin":      "WordPress admin area",
+	"wp-login.php":  "WordPress login page",
+	"admin":         "Admin area",
+	"administrator": "Admin area",
+	"bitrix":        "Bitrix admin area",
+	"cgi-bin":       "CGI scripts",
+	"login":         "Login page",
+	"signin":        "Login page",
+	"sign-in":       "Login page",
+	"logout":        "Logout page",
+	"register":      "Registration page",
+	"account":       "User account pages",
+	"my-account":    "User account pages",
+	"cart":          "Shopping cart",
+	"basket":        "Shopping cart",
+	"checkout":      "Checkout",
+	"search":        "Internal search results",
+}
+
+// duplicateParams are query parameters that only track, sort or restyle a
+// page and so produce duplicate URLs of the same content. Names ending in
+// "_" are prefixes.
+var duplicateParams = map[string]string{
+	"utm_":        "Campaign tracking parameters",
+	"gclid":       "Google Ads click ID",
+	"fbclid":      "Facebook click ID",
+	"yclid":       "Yandex click ID",
+	"sessionid":   "Session ID",
+	"sid":         "Session ID",
+	"phpsessid":   "Session ID",
+	"jsessionid":  "Session ID",
+	"ref":         "Referral tracking",
+	"sort":        "Sort order",
+	"order":       "Sort order",
+	"orderby":     "Sort order",
+	"replytocom":  "Comment reply links",
+	"print":       "Print version",
+	"add-to-cart": "Add to cart action",
+}
+
+// Directive is a proposed robots.txt rule and why it is proposed
+type Directive struct {
+	Allow    bool     `json:"allow"`
+	Path     string   `json:"path"`
+	Reason   string   `json:"reason"`
+	Examples []string `json:"examples,omitempty"`
+}
+
+// Proposal is a recommended robots.txt for a site
+type Proposal struct {
+	Body       string      `json:"robots_txt"`
+	Directives []Directive `json:"directives"`
+}
+
+// Generate proposes a robots.txt from the URLs found by crawling a site: it
+// disallows the admin, account and checkout sections that were found and the
+// query parameters that create duplicate URLs, either known tracking and sort
+// parameters or parameters of pages whose canonical URL drops them
+func Generate(discovered []string, canonicalized map[string]string, sitemaps []string) Proposal {
+	sections := make(map[string][]string)
+	bareSections := make(map[string]bool)
+	params := make(map[string][]string)
+	paramReasons := make(map[string]string)
+
+	for _, raw := range discovered {
+		u, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		segments := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)
+		if first := strings.ToLower(segments[0]); privateSections[first] != "" {
+			sections[first] = append(sections[first], raw)
+			if len(segments) == 1 {
+				bareSections[first] = true
+			}
+		}
+		for name := range u.Query() {
+			if key, reason := duplicateParam(name); key != "" {
+				params[key] = append(params[key], raw)
+				paramReasons[key] = reason
+			}
+		}
+	}
+
+	// Parameters that the canonical URL of a page drops create duplicates too
+	for page, canonical := range canonicalized {
+		pageURL, err := url.Parse(page)
+		if err != nil {
+			continue
+		}
+		canonicalURL, err := url.Parse(canonical)
+		if err != nil || pageURL.Path != canonicalURL.Path {
+			continue
+		}
+		kept := canonicalURL.Query()
+		for name := range pageURL.Query() {
+			key := strings.ToLower(name)
+			if _, ok := kept[name]; ok || key == "page" || key == "p" {
+				continue
+			}
+			params[key] = append(params[key], page)
+			if paramReasons[key] == "" {
+				paramReasons[key] = "Canonical URL drops this parameter"
+			}
+		}
+	}
+
+	var directives []Directive
+	for _, section := range sortedKeys(sections) {
+		examples := examplesOf(sections[section])
+		directives = append(directives, Directive{Path: "/" + section + "/", Reason: privateSections[section], Examples: examples})
+		if bareSections[section] {
+			directives = append(directives, Directive{Path: "/" + section + "$", Reason: privateSections[section], Examples: examples})
+		}
+		if section == "wp-admin" {
+			directives = append(directives, Directive{Allow: true, Path: "/wp-admin/admin-ajax.php", Reason: "Themes and plugins load content through admin-ajax.php"})
+		}
+	}
+	for _, param := range sortedKeys(params) {
+		examples := examplesOf(params[param])
+		name := param
+		if !strings.HasSuffix(param, "_") {
+			name += "="
+		}
+		directives = append(directives,
+			Directive{Path: "/*?" + name, Reason: paramReasons[param], Examples: examples},
+			Directive{Path: "/*&" + name, Reason: paramReasons[param], Examples: examples},
+		)
+	}
+
+	var body strings.Builder
+	body.WriteString("User-agent: *\n")
+	if len(directives) == 0 {
+		body.WriteString("Disallow:\n")
+	}
+	for _, d := range directives {
+		if d.Allow {
+			body.WriteString("Allow: " + d.Path + "\n")
+		} else {
+			body.WriteString("Disallow: " + d.Path + "\n")
+		}
+	}
+	if len(sitemaps) > 0 {
+		body.WriteString("\n")
+		for _, sitemap := range sitemaps {
+			body.WriteString("Sitemap: " + sitemap + "\n")
+		}
+	}
+
+	return Proposal{Body: body.String(), Directives: directives}
+}
+
+// duplicateParam returns the key and reason of a parameter that creates
+// duplicate URLs, or an empty key
+func duplicateParam(name string) (string, string) {
+	name = strings.ToLower(name)
+	if reason, ok := duplicateParams[name]; ok && !strings.HasSuffix(name, "_") {
+		return name, reason
+	}
+	for key, reason := range duplicateParams {
+		if strings.HasSuffix(key, "_") && strings.HasPrefix(name, key) {
+			return key, reason
+		}
+	}
+	return "", ""
+}
+
+// examplesOf returns the first few distinct URLs in sorted order
+func examplesOf(urls []string) []string {
+	sort.Strings(urls)
+	var examples []string
+	for i, u := range urls {
+		if i > 0 && u == urls[i-1] {
+			continue
+		}
+		examples = append(examples, u)
+		if len(examples) == maxExamples {
+			break
+		}
+	}
+	return examples
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
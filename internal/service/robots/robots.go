@@ -0,0 +1,153 @@
+// Package robots parses, validates and evaluates robots.txt files and
+// proposes robots.txt rules for a crawled site.
+package robots
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// MaxSize is the part of a robots.txt file that Google reads
+const MaxSize = 500 << 10
+
+// Problem severities
+const (
+	SeverityError   = "error"   // the line is ignored by crawlers
+	SeverityWarning = "warning" // the line works differently than it likely intends
+)
+
+// Rule is an Allow or Disallow line of a group
+type Rule struct {
+	Allow bool   `json:"allow"`
+	Path  string `json:"path"`
+	Line  int    `json:"line"`
+}
+
+// Group holds the rules for a set of user agents
+type Group struct {
+	UserAgents []string `json:"user_agents"`
+	Rules      []Rule   `json:"rules"`
+	CrawlDelay float64  `json:"crawl_delay,omitempty"`
+}
+
+// Problem is a line of a robots.txt file that crawlers ignore or may misread
+type Problem struct {
+	Line     int    `json:"line"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// File is a parsed robots.txt file
+type File struct {
+	Groups   []Group   `json:"groups"`
+	Sitemaps []string  `json:"sitemaps"`
+	Problems []Problem `json:"problems"`
+}
+
+// Valid reports whether the file has no errors
+func (f *File) Valid() bool {
+	for _, p := range f.Problems {
+		if p.Severity == SeverityError {
+			return false
+		}
+	}
+	return true
+}
+
+// Parse reads a robots.txt file the way Google does and records the lines
+// crawlers would ignore or misread
+func Parse(body string) *File {
+	file := &File{}
+	if len(body) > MaxSize {
+		file.problem(0, SeverityWarning, fmt.Sprintf("File is larger than %d KiB; crawlers ignore the rest", MaxSize>>10))
+		body = body[:MaxSize]
+	}
+
+	var current *Group
+	groupHasRules := false
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64<<10), MaxSize)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			file.problem(lineNo, SeverityError, "Line is not a \"field: value\" pair")
+			continue
+		}
+		field = strings.ToLower(strings.TrimSpace(field))
+		value = strings.TrimSpace(value)
+
+		switch field {
+		case "user-agent":
+			if value == "" {
+				file.problem(lineNo, SeverityError, "User-agent without a value")
+				continue
+			}
+			if current == nil || groupHasRules {
+				file.Groups = append(file.Groups, Group{})
+				current = &file.Groups[len(file.Groups)-1]
+				groupHasRules = false
+			}
+			current.UserAgents = append(current.UserAgents, strings.ToLower(value))
+
+		case "allow", "disallow":
+			if current == nil {
+				file.problem(lineNo, SeverityError, fmt.Sprintf("%s before any User-agent line is ignored", field))
+				continue
+			}
+			groupHasRules = true
+			if value == "" {
+				// An empty Disallow allows everything and adds no rule
+				continue
+			}
+			if !strings.HasPrefix(value, "/") && !strings.HasPrefix(value, "*") {
+				file.problem(lineNo, SeverityWarning, fmt.Sprintf("Path %q does not start with / or *", value))
+			}
+			current.Rules = append(current.Rules, Rule{Allow: field == "allow", Path: value, Line: lineNo})
+
+		case "crawl-delay":
+			if current == nil {
+				file.problem(lineNo, SeverityError, "Crawl-delay before any User-agent line is ignored")
+				continue
+			}
+			groupHasRules = true
+			delay, err := strconv.ParseFloat(value, 64)
+			if err != nil || delay < 0 {
+				file.problem(lineNo, SeverityError, fmt.Sprintf("Crawl-delay %q is not a number of seconds", value))
+				continue
+			}
+			current.CrawlDelay = delay
+			file.problem(lineNo, SeverityWarning, "Crawl-delay is ignored by Google")
+
+		case "sitemap":
+			if u, err := url.Parse(value); err != nil || !u.IsAbs() {
+				file.problem(lineNo, SeverityError, fmt.Sprintf("Sitemap %q is not an absolute URL", value))
+				continue
+			}
+			file.Sitemaps = append(file.Sitemaps, value)
+
+		case "host", "clean-param":
+			file.problem(lineNo, SeverityWarning, fmt.Sprintf("%s is only read by Yandex", field))
+
+		default:
+			file.problem(lineNo, SeverityError, fmt.Sprintf("Unknown field %q", field))
+		}
+	}
+	return file
+}
+
+// problem records a problem of a line
+func (f *File) problem(line int, severity, message string) {
+	f.Problems = append(f.Problems, Problem{Line: line, Severity: severity, Message: message})
+}
@@ -0,0 +1,246 @@
+// Package schedule computes the run times of recurring jobs in the time zone
+// of a customer, keeping runs out of blackout windows.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	// Time zones resolve in images without a zoneinfo database
+	_ "time/tzdata"
+)
+
+const (
+	// MaxBlackouts limits the blackout windows of a schedule
+	MaxBlackouts = 10
+	// maxPostpones bounds the chain of adjacent windows a run is moved
+	// through; ten windows a day cover at most 70 windows a week
+	maxPostpones = 100
+	day          = 24 * time.Hour
+)
+
+// weekdays maps the day names accepted in blackout windows
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ErrNoRunTime is returned when blackout windows cover the whole week
+var ErrNoRunTime = errors.New("blackout windows leave no time to run")
+
+// Window is a recurring period in local time during which nothing runs. A
+// window whose end is before its start ends on the next day.
+type Window struct {
+	Days  []string `json:"days,omitempty"` // mon..sun the window starts on; every day when empty
+	Start string   `json:"start"`          // HH:MM
+	End   string   `json:"end"`            // HH:MM
+}
+
+// window is a parsed blackout window in minutes after local midnight
+type window struct {
+	days       map[time.Weekday]bool
+	start, end int
+}
+
+// Schedule computes the runs of a recurring job
+type Schedule struct {
+	interval  time.Duration
+	location  *time.Location
+	runAt     int // minutes after local midnight; -1 when runs are not pinned
+	blackouts []window
+}
+
+// New creates a schedule repeating at the given interval in an IANA time
+// zone (UTC when empty). runAt pins runs of schedules counted in whole days
+// to a local time of day (HH:MM); those schedules keep their local time
+// across daylight saving changes.
+func New(interval time.Duration, timeZone, runAt string, blackouts []Window) (*Schedule, error) {
+	if interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	s := &Schedule{interval: interval, location: time.UTC, runAt: -1}
+
+	if timeZone != "" {
+		location, err := time.LoadLocation(timeZone)
+		if err != nil || timeZone == "Local" {
+			return nil, fmt.Errorf("unknown time zone %q", timeZone)
+		}
+		s.location = location
+	}
+
+	if runAt != "" {
+		if !s.calendar() {
+			return nil, errors.New("run_at requires a schedule of whole days such as daily or weekly")
+		}
+		minutes, err := parseClock(runAt)
+		if err != nil {
+			return nil, fmt.Errorf("run_at: %w", err)
+		}
+		s.runAt = minutes
+	}
+
+	if len(blackouts) > MaxBlackouts {
+		return nil, fmt.Errorf("at most %d blackout windows are allowed", MaxBlackouts)
+	}
+	for i, blackout := range blackouts {
+		parsed, err := parseWindow(blackout)
+		if err != nil {
+			return nil, fmt.Errorf("blackouts[%d]: %w", i, err)
+		}
+		s.blackouts = append(s.blackouts, parsed)
+	}
+
+	// Windows repeat weekly, so a week without a gap never has one
+	probe := time.Date(2024, time.January, 1, 0, 0, 0, 0, s.location)
+	if _, ok := s.postpone(probe); !ok {
+		return nil, ErrNoRunTime
+	}
+	return s, nil
+}
+
+// Location returns the time zone of the schedule
+func (s *Schedule) Location() *time.Location {
+	return s.location
+}
+
+// First returns the first run of a new schedule: now, or the next pinned
+// local time, moved out of blackout windows
+func (s *Schedule) First(now time.Time) time.Time {
+	next := now
+	if s.runAt >= 0 {
+		next = s.atClock(now)
+		if next.Before(now) {
+			next = s.atClock(now.In(s.location).AddDate(0, 0, 1))
+		}
+	}
+	postponed, _ := s.postpone(next)
+	return postponed
+}
+
+// Next returns the run following a run at the given time
+func (s *Schedule) Next(after time.Time) time.Time {
+	var next time.Time
+	if s.calendar() {
+		next = after.In(s.location).AddDate(0, 0, int(s.interval/day))
+		if s.runAt >= 0 {
+			next = s.atClock(next)
+		}
+	} else {
+		next = after.Add(s.interval)
+	}
+	postponed, _ := s.postpone(next)
+	return postponed
+}
+
+// Preview returns the next count runs of a schedule starting at the given
+// pending run
+func (s *Schedule) Preview(pending time.Time, count int) []time.Time {
+	runs := make([]time.Time, 0, count)
+	next, _ := s.postpone(pending)
+	for len(runs) < count {
+		runs = append(runs, next.In(s.location))
+		next = s.Next(next)
+	}
+	return runs
+}
+
+// BlackoutEnd reports whether t falls into a blackout window and returns
+// when the blackout, including adjacent windows, ends
+func (s *Schedule) BlackoutEnd(t time.Time) (time.Time, bool) {
+	end, ok := s.postpone(t)
+	return end, ok && end.After(t)
+}
+
+// postpone moves t to the end of the blackout windows covering it. It
+// reports false when the windows do not end.
+func (s *Schedule) postpone(t time.Time) (time.Time, bool) {
+	for i := 0; i < maxPostpones; i++ {
+		end, ok := s.covering(t)
+		if !ok {
+			return t, true
+		}
+		t = end
+	}
+	return t, false
+}
+
+// covering returns the end of a blackout window covering t
+func (s *Schedule) covering(t time.Time) (time.Time, bool) {
+	local := t.In(s.location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, s.location)
+
+	// A window started on the previous day may still be open
+	for _, startDay := range []time.Time{midnight.AddDate(0, 0, -1), midnight} {
+		for _, w := range s.blackouts {
+			if len(w.days) > 0 && !w.days[startDay.Weekday()] {
+				continue
+			}
+			start := clockOn(startDay, w.start)
+			end := clockOn(startDay, w.end)
+			if w.end <= w.start {
+				end = clockOn(startDay.AddDate(0, 0, 1), w.end)
+			}
+			if !t.Before(start) && t.Before(end) {
+				return end, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// calendar reports whether the interval is counted in whole days
+func (s *Schedule) calendar() bool {
+	return s.interval%day == 0
+}
+
+// atClock returns the pinned local time on the day of t
+func (s *Schedule) atClock(t time.Time) time.Time {
+	local := t.In(s.location)
+	return clockOn(time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, s.location), s.runAt)
+}
+
+// clockOn returns the local time the given minutes after midnight of a day
+func clockOn(midnight time.Time, minutes int) time.Time {
+	return time.Date(midnight.Year(), midnight.Month(), midnight.Day(), minutes/60, minutes%60, 0, 0, midnight.Location())
+}
+
+// parseWindow validates a blackout window
+func parseWindow(w Window) (window, error) {
+	parsed := window{}
+	var err error
+	if parsed.start, err = parseClock(w.Start); err != nil {
+		return parsed, fmt.Errorf("start: %w", err)
+	}
+	if parsed.end, err = parseClock(w.End); err != nil {
+		return parsed, fmt.Errorf("end: %w", err)
+	}
+	if parsed.start == parsed.end {
+		return parsed, errors.New("start and end must differ")
+	}
+	if len(w.Days) > 0 {
+		parsed.days = make(map[time.Weekday]bool, len(w.Days))
+		for _, name := range w.Days {
+			weekday, ok := weekdays[strings.ToLower(name)]
+			if !ok {
+				return parsed, fmt.Errorf("unknown day %q, expected mon, tue, wed, thu, fri, sat or sun", name)
+			}
+			parsed.days[weekday] = true
+		}
+	}
+	return parsed, nil
+}
+
+// parseClock returns the minutes after midnight of an HH:MM time
+func parseClock(value string) (int, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("time must be in HH:MM format, got %q", value)
+	}
+	return clock.Hour()*60 + clock.Minute(), nil
+}
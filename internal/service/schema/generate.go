@@ -0,0 +1,187 @@
+package schema
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// For generates the schema of the JSON encoding of a Go value's type. Named
+// struct types become $defs so that recursive types terminate. Types with
+// their own JSON encoding, such as UUIDs and raw JSON columns, are
+// described by their usual form.
+func For(value interface{}, title, description string) *Schema {
+	g := &generator{defs: map[string]*Schema{}, types: map[string]reflect.Type{}}
+	root := g.schema(reflect.TypeOf(value))
+
+	// The root type is described inline rather than by reference
+	if root.Ref != "" {
+		name := strings.TrimPrefix(root.Ref, "#/$defs/")
+		root = g.defs[name]
+		if !g.referenced(name) {
+			delete(g.defs, name)
+		}
+	}
+	described := *root
+	described.Schema = Draft
+	described.Title = title
+	described.Description = description
+	if len(g.defs) > 0 {
+		described.Defs = g.defs
+	}
+	return &described
+}
+
+// generator collects the definitions of named struct types
+type generator struct {
+	defs  map[string]*Schema
+	types map[string]reflect.Type
+}
+
+// defName names the definition of a struct type. Types of different
+// packages with the same name are told apart by their package.
+func (g *generator) defName(t reflect.Type) string {
+	name := t.Name()
+	if existing, ok := g.types[name]; ok && existing != t {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	g.types[name] = t
+	return name
+}
+
+// referenced reports whether any definition refers to the named one
+func (g *generator) referenced(name string) bool {
+	encoded, _ := json.Marshal(g.defs)
+	return strings.Contains(string(encoded), `"#/$defs/`+name+`"`)
+}
+
+// schema describes a Go type
+func (g *generator) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	if t.Kind() == reflect.Ptr {
+		inner := g.schema(t.Elem())
+		return nullable(inner)
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Array && t.Len() == 16 && t.Elem().Kind() == reflect.Uint8:
+		return &Schema{Type: "string", Format: "uuid"}
+	case t.Implements(marshalerType):
+		// Custom encodings, such as raw JSON columns and gorm.DeletedAt,
+		// are not introspected
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: []string{"array", "null"}, Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: []string{"object", "null"}, AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := g.defName(t)
+		if _, ok := g.defs[name]; !ok {
+			// Reserve the name before descending for recursive types
+			g.defs[name] = &Schema{}
+			*g.defs[name] = *g.object(t)
+		}
+		return &Schema{Ref: "#/$defs/" + name}
+	}
+	return &Schema{}
+}
+
+// object describes the fields of a struct as encoding/json encodes them
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		name, options := field.Name, ""
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			name, options, _ = strings.Cut(tag, ",")
+			if name == "" {
+				name = field.Name
+			}
+		}
+
+		// Fields of embedded structs are promoted
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				promoted := g.object(embedded)
+				for propertyName, property := range promoted.Properties {
+					s.Properties[propertyName] = property
+				}
+				s.Required = append(s.Required, promoted.Required...)
+				continue
+			}
+		}
+
+		property := g.schema(field.Type)
+		if strings.Contains(options, "string") {
+			property = &Schema{Type: "string"}
+		}
+		s.Properties[name] = property
+		if !strings.Contains(options, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+// nullable allows null besides the values of a schema
+func nullable(s *Schema) *Schema {
+	switch t := s.Type.(type) {
+	case string:
+		copied := *s
+		copied.Type = []string{t, "null"}
+		return &copied
+	case []string:
+		for _, name := range t {
+			if name == "null" {
+				return s
+			}
+		}
+		copied := *s
+		copied.Type = append(append([]string{}, t...), "null")
+		return &copied
+	}
+	if s.Ref != "" {
+		return &Schema{AnyOf: []*Schema{s, {Type: "null"}}}
+	}
+	// Untyped schemas already allow null
+	return s
+}
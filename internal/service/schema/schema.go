@@ -0,0 +1,195 @@
+// Package schema generates JSON Schemas (draft 2020-12) from Go types and
+// validates JSON values against them. Only the keywords the generator emits
+// are supported by the validator.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Draft is the JSON Schema dialect of generated schemas
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema
+type Schema struct {
+	Schema      string `json:"$schema,omitempty"`
+	ID          string `json:"$id,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Type is a type name or a list of type names
+	Type       interface{}        `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Enum       []interface{}      `json:"enum,omitempty"`
+	Minimum    *float64           `json:"minimum,omitempty"`
+	Maximum    *float64           `json:"maximum,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	// AdditionalProperties is the schema of undeclared properties; nil
+	// allows any
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// Violation is a place where a value does not match its schema
+type Violation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// String formats a violation for logs and errors
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// Range returns a copy of a numeric schema limited to [min, max]
+func Range(s *Schema, min, max float64) *Schema {
+	limited := *s
+	limited.Minimum, limited.Maximum = &min, &max
+	return &limited
+}
+
+// Validate checks a value against the schema. Go values are compared in
+// their JSON form.
+func (s *Schema) Validate(value interface{}) []Violation {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return []Violation{{Path: "$", Message: "not encodable as JSON: " + err.Error()}}
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return []Violation{{Path: "$", Message: err.Error()}}
+	}
+
+	var violations []Violation
+	s.validate(s, decoded, "$", &violations)
+	return violations
+}
+
+// validate checks a decoded JSON value; root holds the $defs of references
+func (s *Schema) validate(root *Schema, value interface{}, path string, violations *[]Violation) {
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/$defs/")
+		def, ok := root.Defs[name]
+		if !ok {
+			*violations = append(*violations, Violation{Path: path, Message: "unresolved reference " + s.Ref})
+			return
+		}
+		def.validate(root, value, path, violations)
+		return
+	}
+
+	if len(s.AnyOf) > 0 {
+		for _, option := range s.AnyOf {
+			var optionViolations []Violation
+			option.validate(root, value, path, &optionViolations)
+			if len(optionViolations) == 0 {
+				return
+			}
+		}
+		*violations = append(*violations, Violation{Path: path, Message: "matches none of the allowed schemas"})
+		return
+	}
+
+	if types := typeNames(s.Type); len(types) > 0 && !matchesType(types, value) {
+		*violations = append(*violations, Violation{Path: path, Message: fmt.Sprintf("expected %s, got %s", strings.Join(types, " or "), jsonType(value))})
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		allowed := false
+		for _, option := range s.Enum {
+			if fmt.Sprint(option) == fmt.Sprint(value) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			*violations = append(*violations, Violation{Path: path, Message: fmt.Sprintf("%v is not one of %v", value, s.Enum)})
+		}
+	}
+
+	switch v := value.(type) {
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			*violations = append(*violations, Violation{Path: path, Message: fmt.Sprintf("%g is less than %g", v, *s.Minimum)})
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			*violations = append(*violations, Violation{Path: path, Message: fmt.Sprintf("%g is greater than %g", v, *s.Maximum)})
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(root, item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*violations = append(*violations, Violation{Path: path, Message: "missing required property " + name})
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := s.Properties[name]; ok {
+				property.validate(root, v[name], path+"."+name, violations)
+			} else if s.AdditionalProperties != nil {
+				s.AdditionalProperties.validate(root, v[name], path+"."+name, violations)
+			}
+		}
+	}
+}
+
+// typeNames returns the type names of a type keyword
+func typeNames(t interface{}) []string {
+	switch v := t.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// matchesType reports whether a decoded JSON value has one of the types
+func matchesType(types []string, value interface{}) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON type of a decoded value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
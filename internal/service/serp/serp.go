@@ -0,0 +1,175 @@
+// Package serp queries search engine result page APIs and finds the position
+// of a page for a keyword.
+package serp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/utils/urlnorm"
+)
+
+// Supported providers
+const (
+	ProviderSerpAPI   = "serpapi"
+	ProviderValueSERP = "valueserp"
+)
+
+// Default settings
+const (
+	DefaultTimeout = 60 * time.Second
+	DefaultDepth   = 100
+	maxBodySize    = 10 << 20
+)
+
+// defaultURLs are the search endpoints of the supported providers
+var defaultURLs = map[string]string{
+	ProviderSerpAPI:   "https://serpapi.com/search.json",
+	ProviderValueSERP: "https://api.valueserp.com/search",
+}
+
+// ErrUnknownProvider is returned for a provider name that is not supported
+var ErrUnknownProvider = errors.New("unknown SERP provider")
+
+// Query is a keyword search in one market
+type Query struct {
+	Keyword  string
+	Country  string // ISO 3166-1 alpha-2 code, e.g. us
+	Language string // ISO 639-1 code, e.g. en
+	Device   string // desktop or mobile
+	Depth    int    // number of results to fetch
+}
+
+// Result is an organic search result
+type Result struct {
+	Position int    `json:"position"`
+	URL      string `json:"link"`
+	Title    string `json:"title"`
+}
+
+// Provider runs keyword searches through a SERP API
+type Provider interface {
+	Name() string
+	Search(ctx context.Context, query Query) ([]Result, error)
+}
+
+// apiProvider queries a SERP API that takes the keyword and market as query
+// parameters and returns organic results as "organic_results". SerpAPI and
+// ValueSERP share this format.
+type apiProvider struct {
+	name       string
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewProvider creates the named provider. An empty baseURL selects the
+// public endpoint of the provider.
+func NewProvider(name, baseURL, apiKey string) (Provider, error) {
+	defaultURL, ok := defaultURLs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("%s requires an API key", name)
+	}
+	if baseURL == "" {
+		baseURL = defaultURL
+	}
+	return &apiProvider{
+		name:       name,
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+	}, nil
+}
+
+// Name returns the provider name
+func (p *apiProvider) Name() string {
+	return p.name
+}
+
+// Search returns the organic results for a query ordered by position
+func (p *apiProvider) Search(ctx context.Context, query Query) ([]Result, error) {
+	if query.Depth <= 0 {
+		query.Depth = DefaultDepth
+	}
+
+	params := url.Values{}
+	params.Set("api_key", p.apiKey)
+	params.Set("q", query.Keyword)
+	params.Set("num", strconv.Itoa(query.Depth))
+	if query.Country != "" {
+		params.Set("gl", query.Country)
+	}
+	if query.Language != "" {
+		params.Set("hl", query.Language)
+	}
+	if query.Device != "" {
+		params.Set("device", query.Device)
+	}
+	if p.name == ProviderSerpAPI {
+		params.Set("engine", "google")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %w", p.name, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s response: %w", p.name, err)
+	}
+
+	var payload struct {
+		Error          string   `json:"error"`
+		OrganicResults []Result `json:"organic_results"`
+		RequestInfo    struct {
+			Success bool   `json:"success"`
+			Message string `json:"message"`
+		} `json:"request_info"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid %s response (status %d): %w", p.name, resp.StatusCode, err)
+	}
+	switch {
+	case payload.Error != "":
+		return nil, fmt.Errorf("%s error: %s", p.name, payload.Error)
+	case resp.StatusCode != http.StatusOK:
+		message := payload.RequestInfo.Message
+		if message == "" {
+			message = http.StatusText(resp.StatusCode)
+		}
+		return nil, fmt.Errorf("%s error: %s", p.name, message)
+	}
+	return payload.OrganicResults, nil
+}
+
+// Rank returns the position of a page in the results and the result URL
+// that matched, compared in normalized form. The position is zero when the
+// page is not among the results.
+func Rank(results []Result, pageURL string) (int, string) {
+	target, err := urlnorm.Normalize(pageURL)
+	if err != nil {
+		return 0, ""
+	}
+	for _, result := range results {
+		if normalized, err := urlnorm.Normalize(result.URL); err == nil && normalized == target {
+			return result.Position, result.URL
+		}
+	}
+	return 0, ""
+}
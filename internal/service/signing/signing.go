@@ -0,0 +1,224 @@
+// Package signing signs exported reports and shared analysis results with
+// the server's Ed25519 key as JSON Web Signatures (RFC 7515, compact
+// serialization, alg EdDSA), so that recipients can prove a document was
+// produced by the platform and not modified. Documents delivered as files
+// get a detached signature; JSON payloads are embedded in the signature.
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrNotConfigured is returned when no signing key is configured
+var ErrNotConfigured = errors.New("signing is not configured")
+
+// ErrInvalidSignature is returned for signatures that do not verify
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// algorithm is the JWS algorithm of Ed25519 signatures (RFC 8037)
+const algorithm = "EdDSA"
+
+// Header is the protected header of a signature
+type Header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	// ContentType is the media type of the signed document
+	ContentType string `json:"cty,omitempty"`
+	// Subject names what was signed, e.g. the report or the scan
+	Subject  string `json:"sub,omitempty"`
+	IssuedAt int64  `json:"iat"`
+}
+
+// JWK is a public key in JSON Web Key format (RFC 8037)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+// Verified is a signature that verified
+type Verified struct {
+	Header   Header    `json:"header"`
+	SignedAt time.Time `json:"signed_at"`
+	// Payload is the signed document; for detached signatures it is the
+	// document that was checked
+	Payload []byte `json:"-"`
+}
+
+// Signer signs documents with the current key and verifies signatures made
+// with it or with earlier keys
+type Signer struct {
+	key     ed25519.PrivateKey
+	keyID   string
+	publics map[string]ed25519.PublicKey // by key ID, including the current key
+}
+
+// NewSigner creates a signer from a base64-encoded 32-byte Ed25519 seed.
+// verifyKeys are base64-encoded public keys of earlier signing keys, kept
+// so that documents signed before a key rotation still verify. It returns
+// ErrNotConfigured when key is empty.
+func NewSigner(key string, verifyKeys []string) (*Signer, error) {
+	if key == "" {
+		return nil, ErrNotConfigured
+	}
+	seed, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("signing key must be a base64-encoded %d-byte Ed25519 seed", ed25519.SeedSize)
+	}
+
+	private := ed25519.NewKeyFromSeed(seed)
+	public := private.Public().(ed25519.PublicKey)
+	s := &Signer{
+		key:     private,
+		keyID:   KeyID(public),
+		publics: map[string]ed25519.PublicKey{KeyID(public): public},
+	}
+	for _, value := range verifyKeys {
+		raw, err := base64.StdEncoding.DecodeString(value)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("verification key must be a base64-encoded %d-byte Ed25519 public key", ed25519.PublicKeySize)
+		}
+		s.publics[KeyID(raw)] = ed25519.PublicKey(raw)
+	}
+	return s, nil
+}
+
+// KeyID derives the ID of a public key from its SHA-256 hash
+func KeyID(public ed25519.PublicKey) string {
+	sum := sha256.Sum256(public)
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// KeyID returns the ID of the current key
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+// Keys returns the public keys signatures are verified with, the current
+// one first
+func (s *Signer) Keys() []JWK {
+	keys := []JWK{jwk(s.keyID, s.publics[s.keyID])}
+	for id, public := range s.publics {
+		if id != s.keyID {
+			keys = append(keys, jwk(id, public))
+		}
+	}
+	return keys
+}
+
+func jwk(id string, public ed25519.PublicKey) JWK {
+	return JWK{
+		KeyType:   "OKP",
+		Curve:     "Ed25519",
+		X:         base64.RawURLEncoding.EncodeToString(public),
+		KeyID:     id,
+		Use:       "sig",
+		Algorithm: algorithm,
+	}
+}
+
+// Sign returns a compact JWS embedding the document
+func (s *Signer) Sign(payload []byte, contentType, subject string) (string, error) {
+	return s.sign(payload, contentType, subject, false)
+}
+
+// SignDetached returns a compact JWS with a detached payload (RFC 7515,
+// appendix F): the document travels separately and is needed to verify it
+func (s *Signer) SignDetached(payload []byte, contentType, subject string) (string, error) {
+	return s.sign(payload, contentType, subject, true)
+}
+
+// SignJSON signs the JSON encoding of a value
+func (s *Signer) SignJSON(value interface{}, subject string) (string, error) {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode signed payload: %w", err)
+	}
+	return s.Sign(payload, "application/json", subject)
+}
+
+func (s *Signer) sign(payload []byte, contentType, subject string, detached bool) (string, error) {
+	header, err := json.Marshal(Header{
+		Algorithm:   algorithm,
+		KeyID:       s.keyID,
+		ContentType: contentType,
+		Subject:     subject,
+		IssuedAt:    time.Now().Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode signature header: %w", err)
+	}
+
+	encodedHeader := base64.RawURLEncoding.EncodeToString(header)
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+	signature := ed25519.Sign(s.key, []byte(encodedHeader+"."+encodedPayload))
+
+	if detached {
+		encodedPayload = ""
+	}
+	return encodedHeader + "." + encodedPayload + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify checks a compact JWS. The document is required for detached
+// signatures and must match the embedded one otherwise, if given.
+func (s *Signer) Verify(jws string, document []byte) (*Verified, error) {
+	parts := strings.Split(strings.TrimSpace(jws), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: expected three dot-separated parts", ErrInvalidSignature)
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	var header Header
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	if header.Algorithm != algorithm {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidSignature, header.Algorithm)
+	}
+	public, ok := s.publics[header.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidSignature, header.KeyID)
+	}
+
+	payload := document
+	if parts[1] != "" {
+		embedded, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed payload", ErrInvalidSignature)
+		}
+		if document != nil && string(document) != string(embedded) {
+			return nil, fmt.Errorf("%w: document differs from the signed one", ErrInvalidSignature)
+		}
+		payload = embedded
+	} else if document == nil {
+		return nil, fmt.Errorf("%w: the signature is detached, the document is required", ErrInvalidSignature)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	signingInput := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload)
+	if !ed25519.Verify(public, []byte(signingInput), signature) {
+		return nil, fmt.Errorf("%w: the document was modified or not signed by this server", ErrInvalidSignature)
+	}
+
+	return &Verified{
+		Header:   header,
+		SignedAt: time.Unix(header.IssuedAt, 0).UTC(),
+		Payload:  payload,
+	}, nil
+}
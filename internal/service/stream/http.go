@@ -0,0 +1,90 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+// sinkTimeout limits a single request to a sink
+const sinkTimeout = 15 * time.Second
+
+func init() {
+	RegisterSink(models.EventStreamSinkHTTP, func(cfg SinkConfig) (Sink, error) {
+		return &HTTPSink{
+			URL:    cfg.URL,
+			Secret: cfg.Secret,
+			Client: &http.Client{Timeout: sinkTimeout, Transport: parser.PublicTransport()},
+		}, nil
+	})
+}
+
+// HTTPSink posts events as newline-delimited JSON. Each request carries
+// X-Stream-Timestamp and X-Stream-Signature headers; the signature is
+// "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>".
+type HTTPSink struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+// Send posts a batch of events
+func (s *HTTPSink) Send(ctx context.Context, events []Event) error {
+	body, err := EncodeNDJSON(events)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("X-Stream-Timestamp", timestamp)
+	req.Header.Set("X-Stream-Signature", "sha256="+Sign(s.Secret, timestamp, body))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// EncodeNDJSON encodes events as one JSON object per line
+func EncodeNDJSON(events []Event) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Sign returns the hex HMAC-SHA256 of a timestamped payload
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
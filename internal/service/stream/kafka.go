@@ -0,0 +1,89 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/chynybekuuludastan/website_optimizer/internal/models"
+	"github.com/chynybekuuludastan/website_optimizer/internal/service/parser"
+)
+
+func init() {
+	RegisterSink(models.EventStreamSinkKafka, func(cfg SinkConfig) (Sink, error) {
+		if cfg.Topic == "" {
+			return nil, errors.New("kafka sink requires a topic")
+		}
+		return &KafkaRESTSink{
+			ProxyURL: strings.TrimRight(cfg.URL, "/"),
+			Topic:    cfg.Topic,
+			Client:   &http.Client{Timeout: sinkTimeout, Transport: parser.PublicTransport()},
+		}, nil
+	})
+}
+
+// KafkaRESTSink produces events to a Kafka topic through the Confluent REST
+// Proxy v2 API. Records are keyed by analysis ID so that the events of one
+// analysis land in the same partition in order.
+type KafkaRESTSink struct {
+	ProxyURL string
+	Topic    string
+	Client   *http.Client
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key"`
+	Value Event  `json:"value"`
+}
+
+// Send produces a batch of events
+func (s *KafkaRESTSink) Send(ctx context.Context, events []Event) error {
+	records := make([]kafkaRecord, len(events))
+	for i, event := range events {
+		records[i] = kafkaRecord{Key: event.AnalysisID.String(), Value: event}
+	}
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return err
+	}
+
+	endpoint := s.ProxyURL + "/topics/" + url.PathEscape(s.Topic)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The proxy reports per-record failures in a 200 response
+	var result struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+		Message string `json:"message"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("kafka proxy returned status %d: %s", resp.StatusCode, result.Message)
+	}
+	for _, offset := range result.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("kafka proxy rejected record: %s", offset.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// Package stream forwards persisted analysis findings to external sinks so
+// customers can load them into their own data pipelines
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types
+const (
+	EventMetric = "metric"
+	EventIssue  = "issue"
+	EventTest   = "test"
+)
+
+const (
+	// maxBatchSize is the number of events sent in one request to a sink
+	maxBatchSize = 500
+	// maxAttempts is how many times a batch is sent before it is dropped
+	maxAttempts = 3
+	// retryDelay is the delay before the first retry, doubled for each next one
+	retryDelay = time.Second
+)
+
+// ErrUnknownSink is returned for sink types without a registered factory
+var ErrUnknownSink = errors.New("unknown sink type")
+
+// Event is one persisted metric or issue. The ID is the ID of the stored
+// record, so consumers can deduplicate redelivered events.
+type Event struct {
+	ID         uuid.UUID       `json:"id"`
+	Type       string          `json:"type"`
+	AnalysisID uuid.UUID       `json:"analysis_id"`
+	URL        string          `json:"url"`
+	Category   string          `json:"category"`
+	Timestamp  time.Time       `json:"timestamp"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// Sink delivers a batch of events to an external system
+type Sink interface {
+	Send(ctx context.Context, events []Event) error
+}
+
+// SinkConfig describes where a sink delivers events
+type SinkConfig struct {
+	Type   string
+	URL    string
+	Topic  string
+	Secret string
+}
+
+// SinkFactory creates a sink from its configuration
+type SinkFactory func(cfg SinkConfig) (Sink, error)
+
+var (
+	sinksMu   sync.RWMutex
+	factories = map[string]SinkFactory{}
+)
+
+// RegisterSink makes a sink type available to NewSink
+func RegisterSink(sinkType string, factory SinkFactory) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	factories[sinkType] = factory
+}
+
+// NewSink creates a sink of the configured type
+func NewSink(cfg SinkConfig) (Sink, error) {
+	sinksMu.RLock()
+	factory, ok := factories[cfg.Type]
+	sinksMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSink, cfg.Type)
+	}
+	return factory(cfg)
+}
+
+// Deliver sends events to a sink in batches, retrying failed batches with
+// exponential backoff. It returns the last error of a dropped batch.
+func Deliver(ctx context.Context, sink Sink, events []Event) error {
+	var lastErr error
+	for start := 0; start < len(events); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(events) {
+			end = len(events)
+		}
+		if err := sendWithRetry(ctx, sink, events[start:end]); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// sendWithRetry sends one batch, retrying on failure
+func sendWithRetry(ctx context.Context, sink Sink, batch []Event) error {
+	delay := retryDelay
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = sink.Send(ctx, batch); err == nil {
+			return nil
+		}
+		if attempt == maxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return fmt.Errorf("batch dropped after %d attempts: %w", maxAttempts, err)
+}
@@ -0,0 +1,172 @@
+package templates
+
+import "sort"
+
+// TemplateIssueShare is the share of the pages of a template an issue must
+// occur on to be attributed to the template rather than to single pages
+const TemplateIssueShare = 0.5
+
+// Issue is an issue found on a page
+type Issue struct {
+	Category string `json:"category"`
+	Type     string `json:"type,omitempty"`
+	Severity string `json:"severity"`
+	Title    string `json:"title"`
+}
+
+// PageResult holds the analysis results of a clustered page
+type PageResult struct {
+	URL            string             `json:"url"`
+	AnalysisID     string             `json:"analysis_id"`
+	Score          *float64           `json:"score"`
+	CategoryScores map[string]float64 `json:"-"`
+	Issues         []Issue            `json:"-"`
+}
+
+// TemplateIssue is an issue with the number of pages of a template it
+// occurs on
+type TemplateIssue struct {
+	Issue
+	Pages int     `json:"pages"`
+	Share float64 `json:"share"` // share of the pages of the template
+}
+
+// TemplateReport holds the scores and issues of a template
+type TemplateReport struct {
+	Pattern        string             `json:"pattern"`
+	Kind           string             `json:"kind"`
+	PagesCount     int                `json:"pages_count"`
+	AvgScore       *float64           `json:"avg_score"`
+	CategoryScores map[string]float64 `json:"category_scores"`
+	Issues         []TemplateIssue    `json:"issues"`
+	Pages          []PageResult       `json:"pages"`
+}
+
+// Fix is a template-level issue: fixing it in the template fixes it on all
+// the pages it occurs on
+type Fix struct {
+	Pattern string `json:"pattern"`
+	Kind    string `json:"kind"`
+	TemplateIssue
+}
+
+// Summarize computes the scores and issues of each template from the results
+// of its pages and lists the template-level fixes, the ones affecting the
+// most pages first
+func Summarize(templates []Template, results map[string]PageResult) ([]TemplateReport, []Fix) {
+	reports := make([]TemplateReport, 0, len(templates))
+	fixes := []Fix{}
+
+	for _, template := range templates {
+		report := TemplateReport{
+			Pattern:        template.Pattern,
+			Kind:           template.Kind,
+			CategoryScores: make(map[string]float64),
+			Issues:         []TemplateIssue{},
+			Pages:          make([]PageResult, 0, len(template.PageIDs)),
+		}
+
+		var scoreSum float64
+		var scored int
+		categorySums := make(map[string]float64)
+		categoryCounts := make(map[string]int)
+		issues := make(map[string]*TemplateIssue)
+		var issueOrder []string
+
+		for _, id := range template.PageIDs {
+			result, ok := results[id]
+			if !ok {
+				continue
+			}
+			report.Pages = append(report.Pages, result)
+			if result.Score != nil {
+				scoreSum += *result.Score
+				scored++
+			}
+			for category, score := range result.CategoryScores {
+				categorySums[category] += score
+				categoryCounts[category]++
+			}
+
+			// An issue counts once per page
+			seen := make(map[string]bool)
+			for _, issue := range result.Issues {
+				key := issueKey(issue)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				if existing, ok := issues[key]; ok {
+					existing.Pages++
+					if severityRank(issue.Severity) > severityRank(existing.Severity) {
+						existing.Severity = issue.Severity
+					}
+					continue
+				}
+				issues[key] = &TemplateIssue{Issue: issue, Pages: 1}
+				issueOrder = append(issueOrder, key)
+			}
+		}
+
+		report.PagesCount = len(report.Pages)
+		if report.PagesCount == 0 {
+			continue
+		}
+		if scored > 0 {
+			avg := scoreSum / float64(scored)
+			report.AvgScore = &avg
+		}
+		for category, sum := range categorySums {
+			report.CategoryScores[category] = sum / float64(categoryCounts[category])
+		}
+		for _, key := range issueOrder {
+			issue := issues[key]
+			issue.Share = float64(issue.Pages) / float64(report.PagesCount)
+			report.Issues = append(report.Issues, *issue)
+			if report.PagesCount > 1 && issue.Share >= TemplateIssueShare {
+				fixes = append(fixes, Fix{Pattern: report.Pattern, Kind: report.Kind, TemplateIssue: *issue})
+			}
+		}
+		sort.SliceStable(report.Issues, func(i, j int) bool {
+			return lessImpact(report.Issues[i], report.Issues[j])
+		})
+		reports = append(reports, report)
+	}
+
+	sort.SliceStable(fixes, func(i, j int) bool {
+		return lessImpact(fixes[i].TemplateIssue, fixes[j].TemplateIssue)
+	})
+	return reports, fixes
+}
+
+// lessImpact orders issues by affected pages, then by severity
+func lessImpact(a, b TemplateIssue) bool {
+	if a.Pages != b.Pages {
+		return a.Pages > b.Pages
+	}
+	return severityRank(a.Severity) > severityRank(b.Severity)
+}
+
+// issueKey identifies the same issue on different pages
+func issueKey(issue Issue) string {
+	if issue.Type != "" {
+		return issue.Category + "/" + issue.Type
+	}
+	return issue.Category + "/" + issue.Title
+}
+
+// severityRank orders severities
+func severityRank(severity string) int {
+	switch severity {
+	case "critical":
+		return 4
+	case "high":
+		return 3
+	case "medium":
+		return 2
+	case "low":
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,254 @@
+// Package templates groups the pages of a site into templates, e.g. product
+// pages, blog posts and category listings, by URL pattern and by the
+// similarity of their DOM structure.
+package templates
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Default clustering settings
+const (
+	// DefaultSimilarity is the structural similarity from which two pages
+	// are considered to share a template
+	DefaultSimilarity = 0.6
+	// maxSignatureDepth limits the depth of the tag paths of a signature
+	maxSignatureDepth = 8
+)
+
+// Template kinds guessed from the URL pattern
+const (
+	KindHome     = "home"
+	KindProduct  = "product"
+	KindArticle  = "article"
+	KindCategory = "category"
+	KindPage     = "page"
+)
+
+var (
+	numericSegment = regexp.MustCompile(`^\d+$`)
+	idSegment      = regexp.MustCompile(`^(?i)[0-9a-f]{8,}$|^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	dateSegment    = regexp.MustCompile(`^(19|20)\d{2}(-\d{2}){0,2}$`)
+	slugSegment    = regexp.MustCompile(`^[\p{L}\d]+(?:[-_][\p{L}\d]+){2,}(?:\.\w+)?$|^[\p{L}-]*\d[\p{L}\d-]*(?:\.\w+)?$`)
+)
+
+// kindHints maps path segments to the template kind they indicate
+var kindHints = map[string]string{
+	"product": KindProduct, "products": KindProduct, "item": KindProduct, "items": KindProduct,
+	"p": KindProduct, "shop": KindProduct, "dp": KindProduct,
+	"blog": KindArticle, "news": KindArticle, "article": KindArticle, "articles": KindArticle,
+	"post": KindArticle, "posts": KindArticle, "stories": KindArticle,
+	"category": KindCategory, "categories": KindCategory, "catalog": KindCategory,
+	"collection": KindCategory, "collections": KindCategory, "tag": KindCategory, "tags": KindCategory,
+	"c": KindCategory,
+}
+
+// Page is a page of a site to cluster
+type Page struct {
+	ID        string // identifies the page to the caller
+	URL       string
+	Signature Signature // structure of the page, see NewSignature
+}
+
+// Template is a group of pages sharing a URL pattern and a DOM structure
+type Template struct {
+	Pattern string   `json:"pattern"` // e.g. /blog/{slug}
+	Kind    string   `json:"kind"`
+	PageIDs []string `json:"page_ids"`
+}
+
+// Signature is the set of tag paths of a document, e.g. "body>main>article>h1"
+type Signature map[string]struct{}
+
+// NewSignature computes the structure signature of an HTML document. Classes
+// and text are ignored so that pages of one template with different content
+// share a signature.
+func NewSignature(html string) Signature {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil
+	}
+	signature := make(Signature)
+	var walk func(sel *goquery.Selection, path string, depth int)
+	walk = func(sel *goquery.Selection, path string, depth int) {
+		if depth > maxSignatureDepth {
+			return
+		}
+		sel.Children().Each(func(_ int, child *goquery.Selection) {
+			tag := goquery.NodeName(child)
+			switch tag {
+			case "script", "style", "noscript", "svg", "template":
+				return
+			}
+			childPath := path + ">" + tag
+			signature[childPath] = struct{}{}
+			walk(child, childPath, depth+1)
+		})
+	}
+	walk(doc.Find("body").First(), "body", 1)
+	return signature
+}
+
+// Similarity is the Jaccard similarity of two signatures, 0-1
+func Similarity(a, b Signature) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	shared := 0
+	for path := range a {
+		if _, ok := b[path]; ok {
+			shared++
+		}
+	}
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// URLPattern generalizes the path of a URL by replacing segments that look
+// like identifiers: numbers become {id}, dates {date} and slugs {slug}
+func URLPattern(rawURL string) string {
+	segments := pathSegments(rawURL)
+	if len(segments) == 0 {
+		return "/"
+	}
+	for i, segment := range segments {
+		switch {
+		case dateSegment.MatchString(segment):
+			segments[i] = "{date}"
+		case numericSegment.MatchString(segment), idSegment.MatchString(segment):
+			segments[i] = "{id}"
+		case slugSegment.MatchString(segment):
+			segments[i] = "{slug}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// Cluster groups pages into templates. Pages are first grouped by section,
+// the first path segment and the path depth, and then split into groups of
+// similar structure. Each group gets the URL pattern its pages have in
+// common. Templates are ordered by page count.
+func Cluster(pages []Page, similarity float64) []Template {
+	if similarity <= 0 {
+		similarity = DefaultSimilarity
+	}
+
+	sections := make(map[string][]int)
+	var sectionOrder []string
+	for i, page := range pages {
+		key := sectionKey(page.URL)
+		if _, ok := sections[key]; !ok {
+			sectionOrder = append(sectionOrder, key)
+		}
+		sections[key] = append(sections[key], i)
+	}
+
+	var templates []Template
+	for _, key := range sectionOrder {
+		// Each group is compared through its first page
+		var groups [][]int
+		for _, i := range sections[key] {
+			placed := false
+			for g, group := range groups {
+				if Similarity(pages[group[0]].Signature, pages[i].Signature) >= similarity {
+					groups[g] = append(groups[g], i)
+					placed = true
+					break
+				}
+			}
+			if !placed {
+				groups = append(groups, []int{i})
+			}
+		}
+
+		for _, group := range groups {
+			template := Template{PageIDs: make([]string, 0, len(group))}
+			patterns := make([]string, 0, len(group))
+			for _, i := range group {
+				template.PageIDs = append(template.PageIDs, pages[i].ID)
+				patterns = append(patterns, URLPattern(pages[i].URL))
+			}
+			template.Pattern = mergePatterns(patterns)
+			template.Kind = guessKind(template.Pattern)
+			templates = append(templates, template)
+		}
+	}
+
+	sort.SliceStable(templates, func(i, j int) bool {
+		return len(templates[i].PageIDs) > len(templates[j].PageIDs)
+	})
+	return templates
+}
+
+// sectionKey groups URLs by their first path segment and depth
+func sectionKey(rawURL string) string {
+	segments := pathSegments(rawURL)
+	if len(segments) == 0 {
+		return "/"
+	}
+	first := segments[0]
+	if len(segments) == 1 {
+		// Top-level pages share a section regardless of their name
+		first = "*"
+	}
+	return first + "/" + strings.Repeat("*/", len(segments)-1)
+}
+
+// mergePatterns keeps the segments the patterns share and replaces the
+// others by {slug}
+func mergePatterns(patterns []string) string {
+	if len(patterns) == 0 {
+		return "/"
+	}
+	merged := strings.Split(patterns[0], "/")
+	for _, pattern := range patterns[1:] {
+		segments := strings.Split(pattern, "/")
+		if len(segments) != len(merged) {
+			continue
+		}
+		for i := range merged {
+			if merged[i] != segments[i] {
+				merged[i] = "{slug}"
+			}
+		}
+	}
+	return strings.Join(merged, "/")
+}
+
+// guessKind guesses the kind of a template from its URL pattern
+func guessKind(pattern string) string {
+	if pattern == "/" {
+		return KindHome
+	}
+	// The last hint is the most specific, e.g. /blog/category/{slug}
+	segments := strings.Split(strings.Trim(pattern, "/"), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if kind, ok := kindHints[strings.ToLower(segments[i])]; ok {
+			return kind
+		}
+	}
+	return KindPage
+}
+
+// pathSegments returns the non-empty path segments of a URL
+func pathSegments(rawURL string) []string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	var segments []string
+	for _, segment := range strings.Split(parsed.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
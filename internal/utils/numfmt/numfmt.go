@@ -0,0 +1,188 @@
+// internal/utils/numfmt/numfmt.go
+package numfmt
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale is used when neither the request nor the user names a supported locale
+const DefaultLocale = "en"
+
+// Format describes how numbers, durations and sizes are written in a locale.
+// It is returned with API responses so clients format values the same way.
+type Format struct {
+	Locale           string `json:"locale"`
+	DecimalSeparator string `json:"decimal_separator"`
+	GroupSeparator   string `json:"group_separator"`
+	// SecondsThresholdMs is the duration from which seconds are shown instead of milliseconds
+	SecondsThresholdMs int64  `json:"seconds_threshold_ms"`
+	MillisecondUnit    string `json:"millisecond_unit"`
+	SecondUnit         string `json:"second_unit"`
+	// SizeBase is the factor between size units
+	SizeBase  int64    `json:"size_base"`
+	SizeUnits []string `json:"size_units"`
+	// UnitSeparator is written between a value and its unit
+	UnitSeparator string `json:"unit_separator"`
+}
+
+var (
+	latinSizes    = []string{"B", "KB", "MB", "GB"}
+	frenchSizes   = []string{"o", "Ko", "Mo", "Go"}
+	cyrillicSizes = []string{"Б", "КБ", "МБ", "ГБ"}
+)
+
+// formats lists the supported locales by language or language-region tag
+var formats = map[string]Format{
+	"en":    {DecimalSeparator: ".", GroupSeparator: ",", MillisecondUnit: "ms", SecondUnit: "s", SizeUnits: latinSizes, UnitSeparator: " "},
+	"de":    {DecimalSeparator: ",", GroupSeparator: ".", MillisecondUnit: "ms", SecondUnit: "s", SizeUnits: latinSizes, UnitSeparator: " "},
+	"de-ch": {DecimalSeparator: ".", GroupSeparator: "’", MillisecondUnit: "ms", SecondUnit: "s", SizeUnits: latinSizes, UnitSeparator: " "},
+	"fr":    {DecimalSeparator: ",", GroupSeparator: " ", MillisecondUnit: "ms", SecondUnit: "s", SizeUnits: frenchSizes, UnitSeparator: " "},
+	"es":    {DecimalSeparator: ",", GroupSeparator: ".", MillisecondUnit: "ms", SecondUnit: "s", SizeUnits: latinSizes, UnitSeparator: " "},
+	"it":    {DecimalSeparator: ",", GroupSeparator: ".", MillisecondUnit: "ms", SecondUnit: "s", SizeUnits: latinSizes, UnitSeparator: " "},
+	"pt":    {DecimalSeparator: ",", GroupSeparator: ".", MillisecondUnit: "ms", SecondUnit: "s", SizeUnits: latinSizes, UnitSeparator: " "},
+	"nl":    {DecimalSeparator: ",", GroupSeparator: ".", MillisecondUnit: "ms", SecondUnit: "s", SizeUnits: latinSizes, UnitSeparator: " "},
+	"pl":    {DecimalSeparator: ",", GroupSeparator: " ", MillisecondUnit: "ms", SecondUnit: "s", SizeUnits: latinSizes, UnitSeparator: " "},
+	"tr":    {DecimalSeparator: ",", GroupSeparator: ".", MillisecondUnit: "ms", SecondUnit: "sn", SizeUnits: latinSizes, UnitSeparator: " "},
+	"ru":    {DecimalSeparator: ",", GroupSeparator: " ", MillisecondUnit: "мс", SecondUnit: "с", SizeUnits: cyrillicSizes, UnitSeparator: " "},
+	"uk":    {DecimalSeparator: ",", GroupSeparator: " ", MillisecondUnit: "мс", SecondUnit: "с", SizeUnits: cyrillicSizes, UnitSeparator: " "},
+	"ky":    {DecimalSeparator: ",", GroupSeparator: " ", MillisecondUnit: "мс", SecondUnit: "с", SizeUnits: cyrillicSizes, UnitSeparator: " "},
+	"kk":    {DecimalSeparator: ",", GroupSeparator: " ", MillisecondUnit: "мс", SecondUnit: "с", SizeUnits: cyrillicSizes, UnitSeparator: " "},
+	"ja":    {DecimalSeparator: ".", GroupSeparator: ",", MillisecondUnit: "ms", SecondUnit: "秒", SizeUnits: latinSizes, UnitSeparator: " "},
+	"zh":    {DecimalSeparator: ".", GroupSeparator: ",", MillisecondUnit: "毫秒", SecondUnit: "秒", SizeUnits: latinSizes, UnitSeparator: " "},
+}
+
+// Supported returns the supported locale tags
+func Supported() []string {
+	tags := make([]string, 0, len(formats))
+	for tag := range formats {
+		tags = append(tags, canonicalTag(tag))
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Lookup returns the format of a locale tag such as "de-AT" or "ru_RU",
+// falling back from the region to the language
+func Lookup(tag string) (Format, bool) {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	if tag == "" {
+		return Format{}, false
+	}
+	if format, ok := formats[tag]; ok {
+		return withDefaults(tag, format), true
+	}
+	language := strings.SplitN(tag, "-", 2)[0]
+	if format, ok := formats[language]; ok {
+		return withDefaults(language, format), true
+	}
+	return Format{}, false
+}
+
+// FromAcceptLanguage returns the format of the most preferred supported
+// locale of an Accept-Language header
+func FromAcceptLanguage(header string) (Format, bool) {
+	type candidate struct {
+		tag     string
+		quality float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		quality := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if fields[0] != "" && fields[0] != "*" && quality > 0 {
+			candidates = append(candidates, candidate{tag: fields[0], quality: quality})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+
+	for _, c := range candidates {
+		if format, ok := Lookup(c.tag); ok {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+// Default returns the format of DefaultLocale
+func Default() Format {
+	format, _ := Lookup(DefaultLocale)
+	return format
+}
+
+// Number formats a value with the given number of decimals and grouped thousands
+func (f Format) Number(value float64, decimals int) string {
+	text := strconv.FormatFloat(math.Abs(value), 'f', decimals, 64)
+	integer, fraction, _ := strings.Cut(text, ".")
+
+	var grouped strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			grouped.WriteString(f.GroupSeparator)
+		}
+		grouped.WriteRune(digit)
+	}
+
+	result := grouped.String()
+	if fraction != "" {
+		result += f.DecimalSeparator + fraction
+	}
+	if value < 0 && strings.Trim(text, "0.") != "" {
+		result = "-" + result
+	}
+	return result
+}
+
+// Duration formats milliseconds, switching to seconds from SecondsThresholdMs
+func (f Format) Duration(ms int64) string {
+	if ms < f.SecondsThresholdMs {
+		return f.Number(float64(ms), 0) + f.UnitSeparator + f.MillisecondUnit
+	}
+	seconds := float64(ms) / 1000
+	decimals := 1
+	if seconds >= 10 {
+		decimals = 0
+	}
+	return f.Number(seconds, decimals) + f.UnitSeparator + f.SecondUnit
+}
+
+// Bytes formats a size with the largest unit that keeps the value at least 1
+func (f Format) Bytes(size int64) string {
+	value := float64(size)
+	unit := 0
+	for value >= float64(f.SizeBase) && unit < len(f.SizeUnits)-1 {
+		value /= float64(f.SizeBase)
+		unit++
+	}
+	decimals := 1
+	if unit == 0 || value >= 10 {
+		decimals = 0
+	}
+	return f.Number(value, decimals) + f.UnitSeparator + f.SizeUnits[unit]
+}
+
+// withDefaults fills the parts shared by all locales
+func withDefaults(tag string, format Format) Format {
+	format.Locale = canonicalTag(tag)
+	format.SecondsThresholdMs = 1000
+	format.SizeBase = 1024
+	return format
+}
+
+// canonicalTag writes the region of a tag in upper case, e.g. "de-CH"
+func canonicalTag(tag string) string {
+	language, region, ok := strings.Cut(tag, "-")
+	if !ok {
+		return language
+	}
+	return language + "-" + strings.ToUpper(region)
+}
@@ -0,0 +1,139 @@
+// internal/utils/urlnorm/urlnorm.go
+package urlnorm
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidURL is returned when a URL has no host
+var ErrInvalidURL = errors.New("invalid URL")
+
+// trackingParams are query parameters that identify a campaign or click
+// rather than a resource
+var trackingParams = map[string]bool{
+	"gclid":   true,
+	"dclid":   true,
+	"fbclid":  true,
+	"msclkid": true,
+	"yclid":   true,
+	"twclid":  true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_ga":     true,
+	"_gl":     true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+	"ref_src": true,
+}
+
+// defaultPorts maps schemes to the port that is implied when none is given
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Normalize returns the canonical form of a URL: https is assumed when no
+// scheme is given, scheme and host are lowercased, default ports, fragments,
+// tracking parameters and trailing slashes are removed and the remaining
+// query parameters are sorted
+func Normalize(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", ErrInvalidURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if port := u.Port(); port != "" && port != defaultPorts[u.Scheme] {
+		host += ":" + port
+	}
+	u.Host = host
+	u.User = nil
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+
+	query := u.Query()
+	for key := range query {
+		if IsTrackingParam(key) {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = encodeSorted(query)
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+// IsTrackingParam reports whether a query parameter identifies a campaign or
+// click rather than a resource
+func IsTrackingParam(key string) bool {
+	lower := strings.ToLower(key)
+	return strings.HasPrefix(lower, "utm_") || trackingParams[lower]
+}
+
+// SchemeVariants returns the https and http forms of a normalized URL.
+// Sites are usually reachable over both, so lookups should match either.
+func SchemeVariants(normalized string) []string {
+	rest := normalized
+	if i := strings.Index(normalized, "://"); i >= 0 {
+		rest = normalized[i+3:]
+	}
+	return []string{"https://" + rest, "http://" + rest}
+}
+
+// Key returns a scheme-independent identifier of a URL. URLs that only
+// differ in scheme or in the parts removed by Normalize share a key.
+func Key(raw string) (string, error) {
+	normalized, err := Normalize(raw)
+	if err != nil {
+		return "", err
+	}
+	return SchemeVariants(normalized)[0], nil
+}
+
+// Hostname returns the lowercased host of a URL without port or "www." prefix
+func Hostname(raw string) (string, error) {
+	normalized, err := Normalize(raw)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(normalized)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(u.Hostname(), "www."), nil
+}
+
+// InDomain reports whether a host belongs to a domain. Without subdomains
+// only the domain itself and its "www." host match.
+func InDomain(host, domain string, includeSubdomains bool) bool {
+	host = strings.TrimPrefix(host, "www.")
+	if host == domain {
+		return true
+	}
+	return includeSubdomains && strings.HasSuffix(host, "."+domain)
+}
+
+// encodeSorted encodes query values with keys and values in a stable order
+func encodeSorted(query url.Values) string {
+	for key := range query {
+		sort.Strings(query[key])
+	}
+	// url.Values.Encode sorts by key
+	return query.Encode()
+}
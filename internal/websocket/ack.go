@@ -0,0 +1,214 @@
+package websocket
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Delivery statuses of messages that require acknowledgment
+const (
+	AckStatusPending   = "pending"
+	AckStatusDelivered = "delivered"
+	AckStatusFailed    = "failed"
+)
+
+// AckPolicy controls retry and cleanup of unacknowledged messages
+type AckPolicy struct {
+	RetryInterval     time.Duration `json:"retry_interval"`
+	TTL               time.Duration `json:"ttl"`
+	MaxAttempts       int           `json:"max_attempts"`
+	MaxPendingPerUser int           `json:"max_pending_per_user"`
+}
+
+// DefaultAckPolicy returns the default acknowledgment policy
+func DefaultAckPolicy() AckPolicy {
+	return AckPolicy{
+		RetryInterval:     30 * time.Second,
+		TTL:               24 * time.Hour,
+		MaxAttempts:       10,
+		MaxPendingPerUser: 100,
+	}
+}
+
+// AckPayload is sent by clients to acknowledge a message
+type AckPayload struct {
+	MessageID string `json:"message_id"`
+}
+
+// PendingMessage is a critical message tracked until the user acknowledges it
+type PendingMessage struct {
+	Message       *Message  `json:"message"`
+	UserID        string    `json:"user_id"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastAttemptAt time.Time `json:"last_attempt_at"`
+	DeliveredAt   time.Time `json:"delivered_at,omitempty"`
+}
+
+// AckStore persists messages awaiting acknowledgment
+type AckStore interface {
+	// Save creates or updates a pending message
+	Save(ctx context.Context, pending *PendingMessage) error
+	// UpdatePending updates a message only while it is still pending and
+	// reports whether it was updated
+	UpdatePending(ctx context.Context, pending *PendingMessage) (bool, error)
+	// MarkDelivered records that the user acknowledged the message
+	MarkDelivered(ctx context.Context, userID, messageID string) error
+	// Pending returns undelivered messages for a user, oldest first
+	Pending(ctx context.Context, userID string) ([]*PendingMessage, error)
+	// Cleanup removes delivered, expired and overflowing messages and
+	// returns the number of removed entries
+	Cleanup(ctx context.Context, policy AckPolicy) (int, error)
+	// Stats returns message counts grouped by status
+	Stats(ctx context.Context) (map[string]int, error)
+}
+
+// memoryAckStore keeps pending messages in process memory. It is used when
+// Redis is not available, so messages do not survive a restart.
+type memoryAckStore struct {
+	messages map[string]map[string]*PendingMessage
+	mu       sync.Mutex
+}
+
+// NewMemoryAckStore creates an in-memory acknowledgment store
+func NewMemoryAckStore() AckStore {
+	return &memoryAckStore{
+		messages: make(map[string]map[string]*PendingMessage),
+	}
+}
+
+// Save creates or updates a pending message
+func (s *memoryAckStore) Save(ctx context.Context, pending *PendingMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userMessages, ok := s.messages[pending.UserID]
+	if !ok {
+		userMessages = make(map[string]*PendingMessage)
+		s.messages[pending.UserID] = userMessages
+	}
+
+	stored := *pending
+	userMessages[pending.Message.ID] = &stored
+	return nil
+}
+
+// UpdatePending updates a message only while it is still pending
+func (s *memoryAckStore) UpdatePending(ctx context.Context, pending *PendingMessage) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.messages[pending.UserID][pending.Message.ID]
+	if !ok || current.Status != AckStatusPending {
+		return false, nil
+	}
+
+	stored := *pending
+	s.messages[pending.UserID][pending.Message.ID] = &stored
+	return true, nil
+}
+
+// MarkDelivered records that the user acknowledged the message
+func (s *memoryAckStore) MarkDelivered(ctx context.Context, userID, messageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if pending, ok := s.messages[userID][messageID]; ok {
+		pending.Status = AckStatusDelivered
+		pending.DeliveredAt = time.Now()
+	}
+	return nil
+}
+
+// Pending returns undelivered messages for a user, oldest first
+func (s *memoryAckStore) Pending(ctx context.Context, userID string) ([]*PendingMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []*PendingMessage
+	for _, pending := range s.messages[userID] {
+		if pending.Status == AckStatusPending {
+			copied := *pending
+			result = append(result, &copied)
+		}
+	}
+
+	sortPendingMessages(result)
+	return result, nil
+}
+
+// Cleanup removes delivered, expired and overflowing messages
+func (s *memoryAckStore) Cleanup(ctx context.Context, policy AckPolicy) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for userID, userMessages := range s.messages {
+		var kept []*PendingMessage
+		for id, pending := range userMessages {
+			if shouldRemovePending(pending, policy) {
+				delete(userMessages, id)
+				removed++
+				continue
+			}
+			kept = append(kept, pending)
+		}
+
+		for _, pending := range overflowPending(kept, policy) {
+			delete(userMessages, pending.Message.ID)
+			removed++
+		}
+
+		if len(userMessages) == 0 {
+			delete(s.messages, userID)
+		}
+	}
+
+	return removed, nil
+}
+
+// Stats returns message counts grouped by status
+func (s *memoryAckStore) Stats(ctx context.Context) (map[string]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := map[string]int{
+		AckStatusPending:   0,
+		AckStatusDelivered: 0,
+		AckStatusFailed:    0,
+	}
+	for _, userMessages := range s.messages {
+		for _, pending := range userMessages {
+			stats[pending.Status]++
+		}
+	}
+	return stats, nil
+}
+
+// shouldRemovePending reports whether a message is delivered or expired
+func shouldRemovePending(pending *PendingMessage, policy AckPolicy) bool {
+	if pending.Status == AckStatusDelivered {
+		return true
+	}
+	return policy.TTL > 0 && time.Since(pending.CreatedAt) > policy.TTL
+}
+
+// overflowPending returns the oldest messages exceeding the per-user limit
+func overflowPending(messages []*PendingMessage, policy AckPolicy) []*PendingMessage {
+	if policy.MaxPendingPerUser <= 0 || len(messages) <= policy.MaxPendingPerUser {
+		return nil
+	}
+
+	sortPendingMessages(messages)
+	return messages[:len(messages)-policy.MaxPendingPerUser]
+}
+
+// sortPendingMessages orders messages by creation time, oldest first
+func sortPendingMessages(messages []*PendingMessage) {
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+}
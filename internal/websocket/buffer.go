@@ -0,0 +1,142 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// queuedMessage is an outbound message waiting in a client's send buffer.
+// Large payloads are kept deflate-compressed until they are written.
+type queuedMessage struct {
+	data       []byte
+	compressed bool
+}
+
+// BufferStats counts what happened to messages queued for slow clients
+type BufferStats struct {
+	Enqueued        int64 `json:"enqueued"`
+	Evicted         int64 `json:"evicted"`
+	EvictedBytes    int64 `json:"evicted_bytes"`
+	Dropped         int64 `json:"dropped"`
+	Compressed      int64 `json:"compressed"`
+	CompressedSaved int64 `json:"compressed_bytes_saved"`
+	// BufferedBytes and BufferedMessages are currently held by all clients
+	BufferedBytes    int64 `json:"buffered_bytes"`
+	BufferedMessages int   `json:"buffered_messages"`
+}
+
+// bufferCounters are the hub-wide counters behind BufferStats
+type bufferCounters struct {
+	enqueued        atomic.Int64
+	evicted         atomic.Int64
+	evictedBytes    atomic.Int64
+	dropped         atomic.Int64
+	compressed      atomic.Int64
+	compressedSaved atomic.Int64
+}
+
+// flateWriters reuses compressors, which allocate several hundred KB each
+var flateWriters = sync.Pool{
+	New: func() interface{} {
+		w, _ := flate.NewWriter(nil, flate.BestSpeed)
+		return w
+	},
+}
+
+// compressPayload deflates a payload, returning false if that does not
+// make it smaller
+func compressPayload(data []byte) ([]byte, bool) {
+	var buf bytes.Buffer
+	w := flateWriters.Get().(*flate.Writer)
+	defer flateWriters.Put(w)
+
+	w.Reset(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, false
+	}
+	if err := w.Close(); err != nil || buf.Len() >= len(data) {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+// payload returns the message as it is sent on the wire
+func (m queuedMessage) payload() ([]byte, error) {
+	if !m.compressed {
+		return m.data, nil
+	}
+	r := flate.NewReader(bytes.NewReader(m.data))
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// enqueue adds a message to the send buffer, evicting the oldest queued
+// messages while the buffer is over its message or byte limit. Progress
+// updates supersede each other and critical messages are redelivered from
+// the ack store, so the newest message is the one worth keeping. The
+// caller must hold c.closeMu.
+func (c *Client) enqueue(data []byte) bool {
+	stats := &c.hub.buffers
+	item := queuedMessage{data: data}
+	if c.limits.CompressThreshold > 0 && len(data) >= c.limits.CompressThreshold {
+		if compressed, ok := compressPayload(data); ok {
+			stats.compressed.Add(1)
+			stats.compressedSaved.Add(int64(len(data) - len(compressed)))
+			item = queuedMessage{data: compressed, compressed: true}
+		}
+	}
+
+	size := int64(len(item.data))
+	maxBytes := c.limits.SendBufferBytes
+	if maxBytes > 0 && size > maxBytes {
+		stats.dropped.Add(1)
+		return false
+	}
+
+	for len(c.send) == cap(c.send) || (maxBytes > 0 && c.bufferedBytes.Load()+size > maxBytes) {
+		select {
+		case oldest := <-c.send:
+			c.bufferedBytes.Add(-int64(len(oldest.data)))
+			stats.evicted.Add(1)
+			stats.evictedBytes.Add(int64(len(oldest.data)))
+		default:
+			// The write pump drained the buffer in the meantime
+		}
+		if len(c.send) == 0 {
+			break
+		}
+	}
+
+	select {
+	case c.send <- item:
+		c.bufferedBytes.Add(size)
+		stats.enqueued.Add(1)
+		return true
+	default:
+		stats.dropped.Add(1)
+		return false
+	}
+}
+
+// BufferStats returns the send buffer counters and what is buffered now
+func (h *Hub) BufferStats() BufferStats {
+	stats := BufferStats{
+		Enqueued:        h.buffers.enqueued.Load(),
+		Evicted:         h.buffers.evicted.Load(),
+		EvictedBytes:    h.buffers.evictedBytes.Load(),
+		Dropped:         h.buffers.dropped.Load(),
+		Compressed:      h.buffers.compressed.Load(),
+		CompressedSaved: h.buffers.compressedSaved.Load(),
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.clients {
+		stats.BufferedBytes += client.bufferedBytes.Load()
+		stats.BufferedMessages += len(client.send)
+	}
+	return stats
+}
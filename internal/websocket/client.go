@@ -0,0 +1,276 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	fastws "github.com/fasthttp/websocket"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/google/uuid"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// Time allowed to write a message to the peer
+	writeWait = 10 * time.Second
+
+	// Time allowed to read the next pong message from the peer
+	pongWait = 60 * time.Second
+
+	// Send pings to peer with this period. Must be less than pongWait
+	pingPeriod = (pongWait * 9) / 10
+)
+
+// Client is a single WebSocket connection registered in the hub
+type Client struct {
+	hub  *Hub
+	conn *websocket.Conn
+	send chan queuedMessage
+	// bufferedBytes is the size of the messages queued in send
+	bufferedBytes atomic.Int64
+	closed        bool
+	closeMu       sync.Mutex
+	limits        ClientLimits
+	limiter       *rate.Limiter
+	violations    int
+	UserID        uuid.UUID
+	Username      string
+	Role          string
+	ConnectedAt   time.Time
+}
+
+// NewClient creates a client for an upgraded connection
+func NewClient(hub *Hub, conn *websocket.Conn, userID uuid.UUID, username, role string) *Client {
+	limits := hub.ClientLimits()
+
+	limiter := rate.NewLimiter(rate.Inf, 0)
+	if limits.MessagesPerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(limits.MessagesPerSecond), limits.Burst)
+	}
+
+	return &Client{
+		hub:         hub,
+		conn:        conn,
+		send:        make(chan queuedMessage, sendBufferMessages(limits)),
+		limits:      limits,
+		limiter:     limiter,
+		UserID:      userID,
+		Username:    username,
+		Role:        role,
+		ConnectedAt: time.Now(),
+	}
+}
+
+// Serve registers the client and pumps messages until the connection closes.
+// It blocks, as required by the fiber websocket handler.
+func (c *Client) Serve() {
+	c.hub.Register(c)
+
+	// The connection is released once the handler returns, so wait for the
+	// writer to finish before giving it back
+	done := make(chan struct{})
+	go func() {
+		c.writePump()
+		close(done)
+	}()
+
+	c.readPump()
+	<-done
+}
+
+// Send queues a message for delivery. When the client's buffer is full the
+// oldest queued messages are evicted; messages are dropped if the
+// connection is already closed.
+func (c *Client) Send(msg *Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Failed to marshal WebSocket message: %v", err)
+		return
+	}
+
+	c.closeMu.Lock()
+	defer c.closeMu.Unlock()
+
+	if c.closed {
+		return
+	}
+
+	if !c.enqueue(data) {
+		log.Printf("WebSocket message too large for the send buffer of user %s, dropping %s message", c.UserID, msg.Type)
+	}
+}
+
+// SendError sends an error message to the client
+func (c *Client) SendError(room, message string) {
+	if msg, err := NewMessage(MessageTypeError, room, map[string]string{"error": message}); err == nil {
+		c.Send(msg)
+	}
+}
+
+// close closes the outbound channel exactly once
+func (c *Client) close() {
+	c.closeMu.Lock()
+	defer c.closeMu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
+}
+
+// readPump reads messages from the connection and dispatches them to the hub
+func (c *Client) readPump() {
+	// Unregistering closes the send channel, which stops the write pump
+	defer c.hub.Unregister(c)
+
+	if c.limits.MaxMessageSize > 0 {
+		c.conn.SetReadLimit(c.limits.MaxMessageSize)
+	}
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.hub.Touch(c)
+		return nil
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if errors.Is(err, fastws.ErrReadLimit) {
+				c.hub.reportAbuse(c, AbuseEvent{
+					Reason:       CloseReasonPayloadTooLarge,
+					Violations:   c.violations + 1,
+					Disconnected: true,
+					RemoteAddr:   c.remoteAddr(),
+					Details:      map[string]interface{}{"max_message_size": c.limits.MaxMessageSize},
+				})
+				c.disconnect(websocket.CloseMessageTooBig, CloseReasonPayloadTooLarge)
+				return
+			}
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("WebSocket read error for user %s: %v", c.UserID, err)
+			}
+			return
+		}
+
+		if !c.limiter.Allow() {
+			details := map[string]interface{}{"messages_per_second": c.limits.MessagesPerSecond, "burst": c.limits.Burst}
+			if c.violation(CloseReasonRateLimited, details) {
+				return
+			}
+			continue
+		}
+
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.SendError("", "Invalid message format")
+			continue
+		}
+
+		if c.handleMessage(&msg) {
+			return
+		}
+	}
+}
+
+// handleMessage dispatches an inbound client message and reports whether the
+// client was disconnected for a policy violation
+func (c *Client) handleMessage(msg *Message) bool {
+	switch msg.Type {
+	case MessageTypeJoin:
+		if msg.Room == "" {
+			c.SendError("", "Room is required")
+			return false
+		}
+		if err := c.hub.Join(c, msg.Room); err != nil {
+			if errors.Is(err, ErrRoomLimit) {
+				return c.violation(CloseReasonTooManyRooms, map[string]interface{}{"room": msg.Room, "max_rooms": c.limits.MaxRooms})
+			}
+			c.SendError(msg.Room, err.Error())
+		}
+
+	case MessageTypeLeave:
+		c.hub.Leave(c, msg.Room)
+
+	case MessageTypeTyping:
+		var payload TypingPayload
+		if len(msg.Data) > 0 {
+			if err := json.Unmarshal(msg.Data, &payload); err != nil {
+				c.SendError(msg.Room, "Invalid typing payload")
+				return false
+			}
+		}
+		c.hub.SetTyping(c, msg.Room, payload.IsTyping)
+
+	case MessageTypeAck:
+		var payload AckPayload
+		if err := json.Unmarshal(msg.Data, &payload); err != nil || payload.MessageID == "" {
+			c.SendError(msg.Room, "Invalid ack payload")
+			return false
+		}
+		if err := c.hub.Acknowledge(context.Background(), c, payload.MessageID); err != nil {
+			log.Printf("Failed to acknowledge message %s for user %s: %v", payload.MessageID, c.UserID, err)
+		}
+
+	case MessageTypePing:
+		c.hub.Touch(c)
+		if pong, err := NewMessage(MessageTypePong, "", nil); err == nil {
+			c.Send(pong)
+		}
+
+	default:
+		handler := c.hub.handler(msg.Type)
+		if handler == nil {
+			c.SendError(msg.Room, "Unknown message type: "+msg.Type)
+			return false
+		}
+		if !c.hub.InRoom(c, msg.Room) {
+			c.SendError(msg.Room, "Join the room first")
+			return false
+		}
+		handler(c, msg)
+	}
+	return false
+}
+
+// writePump writes queued messages and periodic pings to the connection
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case item, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				// The hub closed the channel
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			c.bufferedBytes.Add(-int64(len(item.data)))
+
+			data, err := item.payload()
+			if err != nil {
+				log.Printf("Failed to decompress WebSocket message for user %s: %v", c.UserID, err)
+				continue
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
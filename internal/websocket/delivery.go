@@ -0,0 +1,219 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SetAckStore replaces the store and policy used for critical messages
+func (h *Hub) SetAckStore(store AckStore, policy AckPolicy) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.ackStore = store
+	h.ackPolicy = policy
+}
+
+// AckPolicy returns the active acknowledgment policy
+func (h *Hub) AckPolicy() AckPolicy {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.ackPolicy
+}
+
+// SendToUser sends a message to every connection of a user
+func (h *Hub) SendToUser(userID string, msg *Message) int {
+	recipients := h.userClients(userID)
+	for _, client := range recipients {
+		client.Send(msg)
+	}
+	return len(recipients)
+}
+
+// Broadcast sends a message to every connected client and returns the
+// number of connections it was queued for
+func (h *Hub) Broadcast(msg *Message) int {
+	h.mu.RLock()
+	recipients := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		recipients = append(recipients, client)
+	}
+	h.mu.RUnlock()
+
+	for _, client := range recipients {
+		client.Send(msg)
+	}
+	return len(recipients)
+}
+
+// SendCritical persists a message until the user acknowledges it and delivers
+// it to the user's open connections. Offline users receive it on reconnect.
+func (h *Hub) SendCritical(ctx context.Context, userID string, msg *Message) error {
+	if msg.ID == "" {
+		msg.ID = uuid.New().String()
+	}
+	msg.RequiresAck = true
+
+	now := time.Now()
+	pending := &PendingMessage{
+		Message:   msg,
+		UserID:    userID,
+		Status:    AckStatusPending,
+		CreatedAt: now,
+	}
+
+	if h.SendToUser(userID, msg) > 0 {
+		pending.Attempts = 1
+		pending.LastAttemptAt = now
+	}
+
+	return h.store().Save(ctx, pending)
+}
+
+// Acknowledge marks a critical message as delivered for the client's user
+func (h *Hub) Acknowledge(ctx context.Context, client *Client, messageID string) error {
+	return h.store().MarkDelivered(ctx, client.UserID.String(), messageID)
+}
+
+// CleanupUndelivered applies the cleanup policy to stored messages
+func (h *Hub) CleanupUndelivered(ctx context.Context) (int, error) {
+	return h.store().Cleanup(ctx, h.AckPolicy())
+}
+
+// UndeliveredStats returns stored message counts grouped by delivery status
+func (h *Hub) UndeliveredStats(ctx context.Context) (map[string]int, error) {
+	return h.store().Stats(ctx)
+}
+
+// RunAckRetry periodically resends unacknowledged messages to connected users
+// and cleans up expired ones until the context is cancelled
+func (h *Hub) RunAckRetry(ctx context.Context) {
+	policy := h.AckPolicy()
+	if policy.RetryInterval <= 0 {
+		return
+	}
+
+	retryTicker := time.NewTicker(policy.RetryInterval)
+	defer retryTicker.Stop()
+
+	cleanupTicker := time.NewTicker(time.Hour)
+	defer cleanupTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-retryTicker.C:
+			h.retryPending(ctx)
+		case <-cleanupTicker.C:
+			if removed, err := h.CleanupUndelivered(ctx); err != nil {
+				log.Printf("Failed to clean up undelivered WebSocket messages: %v", err)
+			} else if removed > 0 {
+				log.Printf("Cleaned up %d undelivered WebSocket messages", removed)
+			}
+		}
+	}
+}
+
+// retryPending resends messages whose last attempt is older than the retry interval
+func (h *Hub) retryPending(ctx context.Context) {
+	policy := h.AckPolicy()
+
+	for _, userID := range h.connectedUsers() {
+		pendingMessages, err := h.store().Pending(ctx, userID)
+		if err != nil {
+			log.Printf("Failed to load pending messages for user %s: %v", userID, err)
+			continue
+		}
+
+		recipients := h.userClients(userID)
+		for _, pending := range pendingMessages {
+			if time.Since(pending.LastAttemptAt) < policy.RetryInterval {
+				continue
+			}
+			h.deliverPending(ctx, pending, policy, recipients)
+		}
+	}
+}
+
+// redeliverPending sends every unacknowledged message to a newly connected
+// client. The user's other connections already received them.
+func (h *Hub) redeliverPending(client *Client) {
+	ctx := context.Background()
+	policy := h.AckPolicy()
+
+	pendingMessages, err := h.store().Pending(ctx, client.UserID.String())
+	if err != nil {
+		log.Printf("Failed to load pending messages for user %s: %v", client.UserID, err)
+		return
+	}
+
+	for _, pending := range pendingMessages {
+		h.deliverPending(ctx, pending, policy, []*Client{client})
+	}
+}
+
+// deliverPending sends a stored message to the given connections and records
+// the attempt, giving up once the maximum number of attempts is reached. The
+// attempt is only recorded while the message is still pending, so an
+// acknowledgment that arrives meanwhile is kept.
+func (h *Hub) deliverPending(ctx context.Context, pending *PendingMessage, policy AckPolicy, recipients []*Client) {
+	if policy.MaxAttempts > 0 && pending.Attempts >= policy.MaxAttempts {
+		pending.Status = AckStatusFailed
+	} else if len(recipients) > 0 {
+		for _, client := range recipients {
+			client.Send(pending.Message)
+		}
+		pending.Attempts++
+		pending.LastAttemptAt = time.Now()
+	} else {
+		return
+	}
+
+	if _, err := h.store().UpdatePending(ctx, pending); err != nil {
+		log.Printf("Failed to update pending message %s: %v", pending.Message.ID, err)
+	}
+}
+
+// store returns the active acknowledgment store
+func (h *Hub) store() AckStore {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.ackStore
+}
+
+// userClients returns all connections of a user
+func (h *Hub) userClients(userID string) []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var result []*Client
+	for client := range h.clients {
+		if client.UserID.String() == userID {
+			result = append(result, client)
+		}
+	}
+	return result
+}
+
+// connectedUsers returns the distinct IDs of connected users
+func (h *Hub) connectedUsers() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var result []string
+	for client := range h.clients {
+		userID := client.UserID.String()
+		if !seen[userID] {
+			seen[userID] = true
+			result = append(result, userID)
+		}
+	}
+	return result
+}
@@ -0,0 +1,353 @@
+package websocket
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// typingTimeout is how long a typing indicator stays active without a refresh
+const typingTimeout = 5 * time.Second
+
+// membership tracks a single client connection inside a room
+type membership struct {
+	joinedAt    time.Time
+	lastSeenAt  time.Time
+	typingUntil time.Time
+}
+
+// Hub keeps track of connected clients and the rooms they have joined
+type Hub struct {
+	clients   map[*Client]bool
+	rooms     map[string]map[*Client]*membership
+	authorize RoomAuthorizer
+	ackStore  AckStore
+	ackPolicy AckPolicy
+	limits    ClientLimits
+	onAbuse   AbuseHandler
+	buffers   bufferCounters
+	handlers  map[string]MessageHandler
+	mu        sync.RWMutex
+}
+
+// RoomAuthorizer decides whether a client may join a room
+type RoomAuthorizer func(client *Client, room string) error
+
+// MessageHandler handles client messages of a type registered with Handle.
+// The client has joined the room of the message.
+type MessageHandler func(client *Client, msg *Message)
+
+// NewHub creates a new hub
+func NewHub() *Hub {
+	return &Hub{
+		clients:   make(map[*Client]bool),
+		rooms:     make(map[string]map[*Client]*membership),
+		ackStore:  NewMemoryAckStore(),
+		ackPolicy: DefaultAckPolicy(),
+		limits:    DefaultClientLimits(),
+		handlers:  make(map[string]MessageHandler),
+	}
+}
+
+// SetRoomAuthorizer installs the check run before a client joins a room
+func (h *Hub) SetRoomAuthorizer(authorize RoomAuthorizer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.authorize = authorize
+}
+
+// Handle routes client messages of a type to a handler, for features built
+// on top of rooms
+func (h *Hub) Handle(messageType string, handler MessageHandler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.handlers[messageType] = handler
+}
+
+// handler returns the handler of a message type
+func (h *Hub) handler(messageType string) MessageHandler {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.handlers[messageType]
+}
+
+// InRoom reports whether a client has joined a room
+func (h *Hub) InRoom(client *Client, room string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	_, ok := h.rooms[room][client]
+	return ok
+}
+
+// Register adds a client to the hub and redelivers its unacknowledged messages
+func (h *Hub) Register(client *Client) {
+	h.mu.Lock()
+	h.clients[client] = true
+	h.mu.Unlock()
+
+	go h.redeliverPending(client)
+}
+
+// Unregister removes a client from the hub and from every room it joined
+func (h *Hub) Unregister(client *Client) {
+	h.mu.Lock()
+	if _, ok := h.clients[client]; !ok {
+		h.mu.Unlock()
+		return
+	}
+	delete(h.clients, client)
+
+	var leftRooms []string
+	for room, members := range h.rooms {
+		if _, ok := members[client]; ok {
+			delete(members, client)
+			if len(members) == 0 {
+				delete(h.rooms, room)
+			}
+			leftRooms = append(leftRooms, room)
+		}
+	}
+	h.mu.Unlock()
+
+	client.close()
+
+	for _, room := range leftRooms {
+		h.announceLeave(client, room)
+	}
+}
+
+// Join adds a client to a room and notifies the other members
+func (h *Hub) Join(client *Client, room string) error {
+	h.mu.RLock()
+	authorize := h.authorize
+	h.mu.RUnlock()
+
+	if authorize != nil {
+		if err := authorize(client, room); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+
+	h.mu.Lock()
+	members, ok := h.rooms[room]
+	if _, joined := members[client]; joined {
+		h.mu.Unlock()
+		return nil
+	}
+	if h.limits.MaxRooms > 0 && h.roomCountLocked(client) >= h.limits.MaxRooms {
+		h.mu.Unlock()
+		return ErrRoomLimit
+	}
+	if !ok {
+		members = make(map[*Client]*membership)
+		h.rooms[room] = members
+	}
+	firstConnection := !h.userInRoomLocked(room, client.UserID.String())
+	members[client] = &membership{joinedAt: now, lastSeenAt: now}
+	h.mu.Unlock()
+
+	// Send the full presence list to the joining client
+	if msg, err := NewMessage(MessageTypePresenceState, room, h.Presence(room)); err == nil {
+		client.Send(msg)
+	}
+
+	// Only announce users that were not already present through another connection
+	if firstConnection {
+		if entry, ok := h.presenceEntry(room, client.UserID.String()); ok {
+			if msg, err := NewMessage(MessageTypePresenceJoin, room, entry); err == nil {
+				h.BroadcastToRoom(room, msg, client)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Leave removes a client from a room and notifies the remaining members
+func (h *Hub) Leave(client *Client, room string) {
+	h.mu.Lock()
+	members, ok := h.rooms[room]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	if _, joined := members[client]; !joined {
+		h.mu.Unlock()
+		return
+	}
+	delete(members, client)
+	if len(members) == 0 {
+		delete(h.rooms, room)
+	}
+	h.mu.Unlock()
+
+	h.announceLeave(client, room)
+}
+
+// SetTyping updates the typing indicator of a client and relays it to the room
+func (h *Hub) SetTyping(client *Client, room string, isTyping bool) {
+	now := time.Now()
+
+	h.mu.Lock()
+	member, ok := h.rooms[room][client]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	member.lastSeenAt = now
+	if isTyping {
+		member.typingUntil = now.Add(typingTimeout)
+	} else {
+		member.typingUntil = time.Time{}
+	}
+	h.mu.Unlock()
+
+	msg, err := NewMessage(MessageTypeTyping, room, map[string]interface{}{
+		"user_id":   client.UserID.String(),
+		"username":  client.Username,
+		"is_typing": isTyping,
+	})
+	if err != nil {
+		return
+	}
+	h.BroadcastToRoom(room, msg, client)
+}
+
+// Touch refreshes the last seen timestamp of a client in all its rooms
+func (h *Hub) Touch(client *Client) {
+	now := time.Now()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, members := range h.rooms {
+		if member, ok := members[client]; ok {
+			member.lastSeenAt = now
+		}
+	}
+}
+
+// Presence returns the users currently viewing a room, one entry per user
+func (h *Hub) Presence(room string) []PresenceEntry {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.presenceLocked(room)
+}
+
+// BroadcastToRoom sends a message to every client in a room except the given one
+func (h *Hub) BroadcastToRoom(room string, msg *Message, except *Client) {
+	h.mu.RLock()
+	recipients := make([]*Client, 0, len(h.rooms[room]))
+	for client := range h.rooms[room] {
+		if client != except {
+			recipients = append(recipients, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range recipients {
+		client.Send(msg)
+	}
+}
+
+// ClientCount returns the number of connected clients
+func (h *Hub) ClientCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.clients)
+}
+
+// announceLeave broadcasts a presence_leave event if the user has no other
+// connection left in the room
+func (h *Hub) announceLeave(client *Client, room string) {
+	h.mu.RLock()
+	stillPresent := h.userInRoomLocked(room, client.UserID.String())
+	h.mu.RUnlock()
+
+	if stillPresent {
+		return
+	}
+
+	msg, err := NewMessage(MessageTypePresenceLeave, room, map[string]interface{}{
+		"user_id":  client.UserID.String(),
+		"username": client.Username,
+	})
+	if err != nil {
+		log.Printf("Failed to build presence_leave message: %v", err)
+		return
+	}
+	h.BroadcastToRoom(room, msg, client)
+}
+
+// presenceEntry returns the aggregated presence of a single user in a room
+func (h *Hub) presenceEntry(room, userID string) (PresenceEntry, bool) {
+	for _, entry := range h.Presence(room) {
+		if entry.UserID == userID {
+			return entry, true
+		}
+	}
+	return PresenceEntry{}, false
+}
+
+// userInRoomLocked reports whether any connection of the user is in the room.
+// The caller must hold h.mu.
+func (h *Hub) userInRoomLocked(room, userID string) bool {
+	for client := range h.rooms[room] {
+		if client.UserID.String() == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// presenceLocked builds the presence list for a room. The caller must hold h.mu.
+func (h *Hub) presenceLocked(room string) []PresenceEntry {
+	now := time.Now()
+	byUser := make(map[string]*PresenceEntry)
+
+	for client, member := range h.rooms[room] {
+		userID := client.UserID.String()
+		entry, ok := byUser[userID]
+		if !ok {
+			entry = &PresenceEntry{
+				UserID:     userID,
+				Username:   client.Username,
+				JoinedAt:   member.joinedAt,
+				LastSeenAt: member.lastSeenAt,
+			}
+			byUser[userID] = entry
+		}
+
+		entry.Connections++
+		if member.joinedAt.Before(entry.JoinedAt) {
+			entry.JoinedAt = member.joinedAt
+		}
+		if member.lastSeenAt.After(entry.LastSeenAt) {
+			entry.LastSeenAt = member.lastSeenAt
+		}
+		if member.typingUntil.After(now) {
+			entry.IsTyping = true
+		}
+	}
+
+	presence := make([]PresenceEntry, 0, len(byUser))
+	for _, entry := range byUser {
+		presence = append(presence, *entry)
+	}
+
+	// Stable order: earliest viewer first
+	sort.Slice(presence, func(i, j int) bool {
+		return presence[i].JoinedAt.Before(presence[j].JoinedAt)
+	})
+
+	return presence
+}
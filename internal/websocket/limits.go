@@ -0,0 +1,163 @@
+package websocket
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+)
+
+// Close reasons sent to clients disconnected for abuse
+const (
+	CloseReasonRateLimited     = "rate_limit_exceeded"
+	CloseReasonPayloadTooLarge = "payload_too_large"
+	CloseReasonTooManyRooms    = "room_limit_exceeded"
+)
+
+// ErrRoomLimit is returned when a client tries to join more rooms than allowed
+var ErrRoomLimit = errors.New("room limit reached")
+
+// ClientLimits bounds what a single connection may do
+type ClientLimits struct {
+	MessagesPerSecond float64 `json:"messages_per_second"`
+	Burst             int     `json:"burst"`
+	MaxRooms          int     `json:"max_rooms"`
+	MaxMessageSize    int64   `json:"max_message_size"`
+	MaxViolations     int     `json:"max_violations"`
+	// SendBufferMessages and SendBufferBytes bound the outbound messages
+	// queued for a client that reads slower than messages arrive
+	SendBufferMessages int   `json:"send_buffer_messages"`
+	SendBufferBytes    int64 `json:"send_buffer_bytes"`
+	// CompressThreshold is the payload size from which queued messages are
+	// kept compressed; zero disables compression
+	CompressThreshold int `json:"compress_threshold"`
+}
+
+// DefaultClientLimits returns the default per-client limits
+func DefaultClientLimits() ClientLimits {
+	return ClientLimits{
+		MessagesPerSecond: 10,
+		Burst:             20,
+		MaxRooms:          20,
+		MaxMessageSize:    4096,
+		MaxViolations:     5,
+
+		SendBufferMessages: defaultSendBufferMessages,
+		SendBufferBytes:    1 << 20,
+		CompressThreshold:  1024,
+	}
+}
+
+// defaultSendBufferMessages is used when no message limit is configured
+const defaultSendBufferMessages = 256
+
+// sendBufferMessages returns the capacity of a client's send buffer
+func sendBufferMessages(limits ClientLimits) int {
+	if limits.SendBufferMessages > 0 {
+		return limits.SendBufferMessages
+	}
+	return defaultSendBufferMessages
+}
+
+// AbuseEvent describes a limit violation by a client
+type AbuseEvent struct {
+	Reason       string                 `json:"reason"`
+	Violations   int                    `json:"violations"`
+	Disconnected bool                   `json:"disconnected"`
+	RemoteAddr   string                 `json:"remote_addr"`
+	Details      map[string]interface{} `json:"details,omitempty"`
+	OccurredAt   time.Time              `json:"occurred_at"`
+}
+
+// AbuseHandler receives abuse events, e.g. to write them to the audit log
+type AbuseHandler func(client *Client, event AbuseEvent)
+
+// SetClientLimits replaces the limits applied to new connections
+func (h *Hub) SetClientLimits(limits ClientLimits) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.limits = limits
+}
+
+// ClientLimits returns the limits applied to new connections
+func (h *Hub) ClientLimits() ClientLimits {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.limits
+}
+
+// SetAbuseHandler installs the callback invoked for every limit violation
+func (h *Hub) SetAbuseHandler(handler AbuseHandler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.onAbuse = handler
+}
+
+// reportAbuse logs a violation and forwards it to the abuse handler
+func (h *Hub) reportAbuse(client *Client, event AbuseEvent) {
+	event.OccurredAt = time.Now()
+	log.Printf("WebSocket abuse by user %s (%s): %s, violations=%d, disconnected=%t",
+		client.UserID, event.RemoteAddr, event.Reason, event.Violations, event.Disconnected)
+
+	h.mu.RLock()
+	handler := h.onAbuse
+	h.mu.RUnlock()
+
+	if handler != nil {
+		handler(client, event)
+	}
+}
+
+// roomCountLocked returns the number of rooms a client has joined. The caller must hold h.mu.
+func (h *Hub) roomCountLocked(client *Client) int {
+	count := 0
+	for _, members := range h.rooms {
+		if _, ok := members[client]; ok {
+			count++
+		}
+	}
+	return count
+}
+
+// violation records a limit violation and disconnects the client once it
+// exceeds the allowed number. It returns true if the client was disconnected.
+func (c *Client) violation(reason string, details map[string]interface{}) bool {
+	c.violations++
+	disconnect := c.limits.MaxViolations > 0 && c.violations >= c.limits.MaxViolations
+
+	c.hub.reportAbuse(c, AbuseEvent{
+		Reason:       reason,
+		Violations:   c.violations,
+		Disconnected: disconnect,
+		RemoteAddr:   c.remoteAddr(),
+		Details:      details,
+	})
+
+	if disconnect {
+		c.disconnect(websocket.ClosePolicyViolation, reason)
+		return true
+	}
+
+	c.SendError("", reason)
+	return false
+}
+
+// disconnect sends a close frame with a structured reason
+func (c *Client) disconnect(code int, reason string) {
+	payload := websocket.FormatCloseMessage(code, `{"reason":"`+reason+`"}`)
+	if err := c.conn.WriteControl(websocket.CloseMessage, payload, time.Now().Add(writeWait)); err != nil {
+		log.Printf("Failed to send close frame to user %s: %v", c.UserID, err)
+	}
+}
+
+// remoteAddr returns the client's remote address if known
+func (c *Client) remoteAddr() string {
+	if c.conn == nil {
+		return ""
+	}
+	return c.conn.IP()
+}
@@ -0,0 +1,109 @@
+package websocket
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Message types exchanged between the hub and connected clients
+const (
+	// Client -> server
+	MessageTypeJoin   = "join"
+	MessageTypeLeave  = "leave"
+	MessageTypeTyping = "typing"
+	MessageTypePing   = "ping"
+	MessageTypeAck    = "ack"
+	// Collaborative editing of improved content
+	MessageTypeEdit     = "edit"
+	MessageTypeEditSync = "edit_sync"
+
+	// Server -> client
+	MessageTypePong             = "pong"
+	MessageTypeError            = "error"
+	MessageTypePresenceState    = "presence_state"
+	MessageTypePresenceJoin     = "presence_join"
+	MessageTypePresenceLeave    = "presence_leave"
+	MessageTypeAnalysisProgress = "analysis_progress"
+	// Sent to every connected client by administrators, e.g. maintenance announcements
+	MessageTypeSystemNotification = "system_notification"
+	// Collaborative editing: the document state, the confirmation of an
+	// edit to its author and a saved version
+	MessageTypeEditState   = "edit_state"
+	MessageTypeEditAck     = "edit_ack"
+	MessageTypeEditVersion = "edit_version"
+
+	// Critical server -> client messages that must be acknowledged
+	MessageTypeAnalysisCompleted = "analysis_completed"
+	MessageTypeAlert             = "alert"
+)
+
+// Message is the envelope for every WebSocket frame
+type Message struct {
+	ID          string          `json:"id,omitempty"`
+	Type        string          `json:"type"`
+	Room        string          `json:"room,omitempty"`
+	Data        json.RawMessage `json:"data,omitempty"`
+	RequiresAck bool            `json:"requires_ack,omitempty"`
+	Timestamp   time.Time       `json:"timestamp"`
+}
+
+// NewMessage builds a message with the payload serialized as JSON
+func NewMessage(messageType, room string, data interface{}) (*Message, error) {
+	msg := &Message{
+		Type:      messageType,
+		Room:      room,
+		Timestamp: time.Now(),
+	}
+
+	if data != nil {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		msg.Data = payload
+	}
+
+	return msg, nil
+}
+
+// TypingPayload is sent by clients to signal they are typing in a room
+type TypingPayload struct {
+	IsTyping bool `json:"is_typing"`
+}
+
+// PresenceEntry describes a single user viewing a room
+type PresenceEntry struct {
+	UserID      string    `json:"user_id"`
+	Username    string    `json:"username"`
+	Connections int       `json:"connections"`
+	IsTyping    bool      `json:"is_typing"`
+	JoinedAt    time.Time `json:"joined_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// Levels of system notifications
+const (
+	NotificationLevelInfo     = "info"
+	NotificationLevelWarning  = "warning"
+	NotificationLevelCritical = "critical"
+)
+
+// SystemNotification is the payload of a system_notification message
+type SystemNotification struct {
+	Level   string `json:"level"`
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message"`
+	// Maintenance is set on announcements of the maintenance mode
+	Maintenance *bool      `json:"maintenance,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// ImprovementRoom returns the room name used to edit a content improvement
+func ImprovementRoom(improvementID string) string {
+	return "improvement:" + improvementID
+}
+
+// AnalysisRoom returns the room name used for an analysis
+func AnalysisRoom(analysisID string) string {
+	return "analysis:" + analysisID
+}
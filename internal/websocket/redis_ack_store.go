@@ -0,0 +1,194 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// Hash of pending messages per user, keyed by message ID
+	keyPrefixPendingAcks = "ws:acks:"
+	// Set of users that have at least one tracked message
+	keyPendingAckUsers = "ws:acks:users"
+)
+
+// redisAckStore persists messages awaiting acknowledgment in Redis so they
+// survive reconnects and server restarts
+type redisAckStore struct {
+	client *redis.Client
+}
+
+// NewRedisAckStore creates a Redis-backed acknowledgment store
+func NewRedisAckStore(client *redis.Client) AckStore {
+	return &redisAckStore{client: client}
+}
+
+// Save creates or updates a pending message
+func (s *redisAckStore) Save(ctx context.Context, pending *PendingMessage) error {
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pending message: %w", err)
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, keyPrefixPendingAcks+pending.UserID, pending.Message.ID, data)
+	pipe.SAdd(ctx, keyPendingAckUsers, pending.UserID)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// updatePendingScript replaces a stored message only if its status is still
+// pending
+var updatePendingScript = redis.NewScript(`
+local current = redis.call("HGET", KEYS[1], ARGV[1])
+if not current or cjson.decode(current).status ~= ARGV[3] then
+	return 0
+end
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+// UpdatePending updates a message only while it is still pending
+func (s *redisAckStore) UpdatePending(ctx context.Context, pending *PendingMessage) (bool, error) {
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal pending message: %w", err)
+	}
+
+	updated, err := updatePendingScript.Run(ctx, s.client, []string{keyPrefixPendingAcks + pending.UserID},
+		pending.Message.ID, data, AckStatusPending).Int()
+	if err != nil {
+		return false, err
+	}
+	return updated == 1, nil
+}
+
+// MarkDelivered records that the user acknowledged the message
+func (s *redisAckStore) MarkDelivered(ctx context.Context, userID, messageID string) error {
+	data, err := s.client.HGet(ctx, keyPrefixPendingAcks+userID, messageID).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil // Unknown or already cleaned up
+		}
+		return err
+	}
+
+	var pending PendingMessage
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return fmt.Errorf("failed to unmarshal pending message: %w", err)
+	}
+
+	pending.Status = AckStatusDelivered
+	pending.DeliveredAt = time.Now()
+	return s.Save(ctx, &pending)
+}
+
+// Pending returns undelivered messages for a user, oldest first
+func (s *redisAckStore) Pending(ctx context.Context, userID string) ([]*PendingMessage, error) {
+	all, err := s.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*PendingMessage
+	for _, pending := range all {
+		if pending.Status == AckStatusPending {
+			result = append(result, pending)
+		}
+	}
+
+	sortPendingMessages(result)
+	return result, nil
+}
+
+// Cleanup removes delivered, expired and overflowing messages
+func (s *redisAckStore) Cleanup(ctx context.Context, policy AckPolicy) (int, error) {
+	userIDs, err := s.client.SMembers(ctx, keyPendingAckUsers).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, userID := range userIDs {
+		all, err := s.load(ctx, userID)
+		if err != nil {
+			return removed, err
+		}
+
+		var toRemove []string
+		var kept []*PendingMessage
+		for _, pending := range all {
+			if shouldRemovePending(pending, policy) {
+				toRemove = append(toRemove, pending.Message.ID)
+				continue
+			}
+			kept = append(kept, pending)
+		}
+		for _, pending := range overflowPending(kept, policy) {
+			toRemove = append(toRemove, pending.Message.ID)
+		}
+
+		key := keyPrefixPendingAcks + userID
+		if len(toRemove) > 0 {
+			if err := s.client.HDel(ctx, key, toRemove...).Err(); err != nil {
+				return removed, err
+			}
+			removed += len(toRemove)
+		}
+
+		if len(toRemove) == len(all) {
+			s.client.SRem(ctx, keyPendingAckUsers, userID)
+		}
+	}
+
+	return removed, nil
+}
+
+// Stats returns message counts grouped by status
+func (s *redisAckStore) Stats(ctx context.Context) (map[string]int, error) {
+	stats := map[string]int{
+		AckStatusPending:   0,
+		AckStatusDelivered: 0,
+		AckStatusFailed:    0,
+	}
+
+	userIDs, err := s.client.SMembers(ctx, keyPendingAckUsers).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, userID := range userIDs {
+		all, err := s.load(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, pending := range all {
+			stats[pending.Status]++
+		}
+	}
+
+	return stats, nil
+}
+
+// load reads every tracked message of a user
+func (s *redisAckStore) load(ctx context.Context, userID string) ([]*PendingMessage, error) {
+	values, err := s.client.HGetAll(ctx, keyPrefixPendingAcks+userID).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*PendingMessage, 0, len(values))
+	for _, value := range values {
+		var pending PendingMessage
+		if err := json.Unmarshal([]byte(value), &pending); err != nil {
+			continue // Skip corrupted entries
+		}
+		result = append(result, &pending)
+	}
+
+	return result, nil
+}